@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/audit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditTailLines  int
+	auditTailOutput string
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Work with the audit log of the commands the agent ran",
+}
+
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Show the most recent commands in the audit log",
+	Long: `Read the audit log at audit.path and print its last --lines records oldest first: when each command started,
+the failover id of the transition it ran in, what ran it, the command and args with secret args hashed, its exit code
+and duration and whether it was a dry run. The whole log is checked to still be the chain the agent appended, so a
+record edited or removed since is reported. Exits 1 if the audit log can't be read or was modified.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if auditTailOutput != "text" && auditTailOutput != "json" {
+			log.Fatal("invalid --output, must be one of text, json", "output", auditTailOutput)
+		}
+		if !loadedConfig.Audit.Enabled() {
+			log.Fatal("audit.path is not set and there is no run.state_dir to default it to")
+		}
+
+		records, err := audit.Read(loadedConfig.Audit.Path)
+		if err != nil && records == nil {
+			log.Fatal("failed to read audit log", "path", loadedConfig.Audit.Path, "error", err)
+		}
+		if auditTailLines > 0 && len(records) > auditTailLines {
+			records = records[len(records)-auditTailLines:]
+		}
+
+		switch auditTailOutput {
+		case "json":
+			for _, record := range records {
+				raw, err := json.Marshal(record)
+				if err != nil {
+					log.Fatal("failed to encode audit record", "error", err)
+				}
+				os.Stdout.Write(append(raw, '\n'))
+			}
+		default:
+			printAuditRecords(records)
+		}
+
+		if err != nil {
+			log.Error("audit log is not intact", "path", loadedConfig.Audit.Path, "error", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// printAuditRecords prints the audit records oldest first, one per line
+func printAuditRecords(records []audit.Record) {
+	if len(records) == 0 {
+		fmt.Println("no commands audited")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "STARTED\tFAILOVER ID\tNAME\tCOMMAND\tEXIT CODE\tDURATION\tDRY RUN\tERROR")
+	for _, record := range records {
+		failoverID := "-"
+		if record.FailoverID != "" {
+			failoverID = record.FailoverID
+		}
+		errMessage := "-"
+		if record.Error != "" {
+			errMessage = record.Error
+		}
+		duration := time.Duration(record.DurationSeconds * float64(time.Second)).Round(time.Millisecond)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%t\t%s\n",
+			record.Timestamp.Local().Format(time.RFC3339), failoverID, record.Name,
+			strings.TrimSpace(record.Command+" "+strings.Join(record.Args, " ")), record.ExitCode, duration, record.DryRun, errMessage)
+	}
+}
+
+func init() {
+	auditTailCmd.Flags().IntVarP(&auditTailLines, "lines", "n", 20, "Number of most recent records to show, 0 shows all")
+	auditTailCmd.Flags().StringVarP(&auditTailOutput, "output", "o", "text", "Output format (text, json)")
+	auditCmd.AddCommand(auditTailCmd)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+	// print loads the configuration itself, without reading identity keypair files or validating
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the fully-resolved configuration with secrets redacted",
+	Long: `Load the configuration file, apply defaults and print the YAML the agent will actually use, followed by the
+effective prometheus and health check ports and cluster rpc_urls. Identity keypair file paths are printed but the files
+are never read. String values matching redact.patterns or the built-in patterns for tokens, passwords, url credentials
+and webhook urls - and role env values whose names look secret - are printed as ***, so the output is safe to paste when
+asking for help. The configuration is not validated, use validate for that.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.NewResolvedFromConfigFile(configFile)
+		if err != nil {
+			log.Fatal("failed to load configuration", "error", err)
+		}
+
+		out, err := cfg.ResolvedYAML()
+		if err != nil {
+			log.Fatal("failed to print configuration", "error", err)
+		}
+
+		os.Stdout.Write(out)
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configPrintCmd)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/ha"
+	"github.com/spf13/cobra"
+)
+
+// explainExitWouldTransition is the exit code when the validator would become active or passive
+const explainExitWouldTransition = 2
+
+var (
+	explainJSON     bool
+	explainPublicIP string
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Explain what the agent would decide right now without acting",
+	Long: `Take one read-only gossip snapshot from the cluster RPC and evaluate the failover decision for the configured
+validator as its agent would, printing every gate's result and the values that fed it. Nothing is run - no role
+commands, no hooks - and the agent doesn't need to be running. Gates that need the local validator RPC are skipped
+and assumed to pass. Pass --public-ip to explain a validator other than this host. Exits 2 if the decision would
+become active or passive.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := log.WithPrefix("[explain]")
+
+		publicIP := explainPublicIP
+		if publicIP == "" {
+			if offline && loadedConfig.Validator.PublicIPSource() != config.PublicIPSourceConfig {
+				log.Fatal("--public-ip is required with --offline unless validator.public_ip is set")
+			}
+			var err error
+			publicIP, err = loadedConfig.Validator.ResolvePublicIP()
+			if err != nil {
+				log.Fatal("failed to resolve public ip - pass --public-ip", "error", err)
+			}
+			logger.Info("explaining for this host's public ip - pass --public-ip to explain another validator", "public_ip", publicIP, "public_ip_source", loadedConfig.Validator.PublicIPSource())
+		}
+
+		explanation, err := ha.Explain(ha.ExplainOptions{
+			Cfg:        loadedConfig,
+			ClusterRPC: newRPCClient(loadedConfig.Validator.Name, loadedConfig.Cluster.RPCURLs...),
+			PublicIP:   publicIP,
+			NewPeerRPC: newPeerRPCClient,
+		})
+		if err != nil {
+			log.Fatal("failed to explain decision", "error", err)
+		}
+
+		if explainJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(explanation)
+		} else {
+			printExplanation(explanation)
+		}
+
+		if explanation.WouldTransition() {
+			os.Exit(explainExitWouldTransition)
+		}
+	},
+}
+
+// printExplanation prints the decision and its gates as tables on stdout
+func printExplanation(explanation *ha.Explanation) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "validator\t%s\n", explanation.ValidatorName)
+	fmt.Fprintf(w, "public ip\t%s\n", explanation.PublicIP)
+	fmt.Fprintf(w, "action\t%s\n", explanation.Decision.Action)
+	fmt.Fprintf(w, "reason\t%s\n", explanation.Decision.Reason)
+	fmt.Fprintf(w, "dry run\t%t\n", explanation.Decision.DryRun)
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "GATE\tRESULT\tVALUES\tDETAIL")
+	for _, gate := range explanation.Gates {
+		keys := make([]string, 0, len(gate.Values))
+		for key := range gate.Values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]string, 0, len(keys))
+		for _, key := range keys {
+			values = append(values, key+"="+gate.Values[key])
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", gate.Name, gate.Result, strings.Join(values, " "), gate.Detail)
+	}
+}
+
+func init() {
+	explainCmd.Flags().BoolVar(&explainJSON, "json", false, "Print the explanation as JSON")
+	explainCmd.Flags().StringVar(&explainPublicIP, "public-ip", "", "Public IP of the validator to explain (default: validator.public_ip or resolved from validator.public_ip_service_urls)")
+	explainCmd.Flags().BoolVar(&offline, "offline", false, "Answer RPC calls with canned data and make no network requests")
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/ha"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+	"github.com/spf13/cobra"
+)
+
+var historyOutput string
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the transitions the running agent has made",
+	Long: `Query the running agent's /history endpoint on the health check server (prometheus.port + 1) and print the
+most recent history.size transitions oldest first: when each started, the roles it went from and to, why, how it went
+and how long it took, the role command's exit code and the hooks that failed. The history is kept in run.state_dir so
+it survives agent restarts. Exits 1 if the agent is unreachable.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if historyOutput != "text" && historyOutput != "json" {
+			log.Fatal("invalid --output, must be one of text, json", "output", historyOutput)
+		}
+
+		client, err := statusClient(loadedConfig.HealthCheck.TLS)
+		if err != nil {
+			log.Error("failed to query agent history", "error", err)
+			os.Exit(statusExitUnreachable)
+		}
+
+		url := agentURL("/history")
+		entries, err := fetchHistory(client, url, loadedConfig.HealthCheck.Auth.Token())
+		if err != nil {
+			log.Error("failed to query agent history", "url", url, "error", err)
+			os.Exit(statusExitUnreachable)
+		}
+
+		switch historyOutput {
+		case "json":
+			raw, err := json.MarshalIndent(entries, "", "  ")
+			if err != nil {
+				log.Fatal("failed to encode history", "error", err)
+			}
+			os.Stdout.Write(append(raw, '\n'))
+		default:
+			printHistory(entries)
+		}
+	},
+}
+
+// fetchHistory gets and decodes the agent's failover history with the healthcheck.auth bearer token, if any
+func fetchHistory(client *http.Client, url string, token string) (entries []ha.HistoryEntry, err error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpauth.SetBearerToken(req, token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to decode history: %w", err)
+	}
+	return entries, nil
+}
+
+// printHistory prints the history entries oldest first, one per line
+func printHistory(entries []ha.HistoryEntry) {
+	if len(entries) == 0 {
+		fmt.Println("no transitions recorded")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "STARTED\tFROM\tTO\tREASON\tOUTCOME\tDURATION\tEXIT CODE\tFAILED HOOKS\tERROR")
+	for _, entry := range entries {
+		exitCode := "-"
+		if entry.Command != nil {
+			exitCode = fmt.Sprintf("%d", entry.Command.ExitCode)
+		}
+		failedHooks := 0
+		for _, hook := range entry.Hooks {
+			if hook.Error != "" {
+				failedHooks++
+			}
+		}
+		errMessage := "-"
+		if entry.Error != "" {
+			errMessage = entry.Error
+		}
+		duration := time.Duration(entry.DurationSeconds * float64(time.Second)).Round(time.Millisecond)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			entry.StartedAt.Local().Format(time.RFC3339), entry.From, entry.To, entry.Reason, entry.Outcome, duration, exitCode, failedHooks, errMessage)
+	}
+}
+
+func init() {
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", "text", "Output format (text, json)")
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/spf13/cobra"
+)
+
+// hooksTestReason is the SVHA_REASON hooks see when run by hooks test
+const hooksTestReason = "hooks_test"
+
+var (
+	hooksTestRole   string
+	hooksTestPhase  string
+	hooksTestDryRun bool
+)
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Work with the configured role hooks",
+}
+
+var hooksTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Run the configured hooks for a role and phase out of band",
+	Long: `Run the pre or post hooks of failover.active or failover.passive the way a failover would - rendered with the
+loaded identities' pubkeys and keypair paths, in order and with their must_succeed semantics through the same code the
+agent runs them with - printing each hook's exit code, attempts, duration and captured output. Retries are waited for,
+post hooks included. The role command itself is never run. Hooks are run regardless of failover.dry_run, --dry-run only
+prints the rendered commands. Exits 1 if any hook failed.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		var role config.Role
+		switch hooksTestRole {
+		case constants.RoleActive.String():
+			role = loadedConfig.Failover.Active
+		case constants.RolePassive.String():
+			role = loadedConfig.Failover.Passive
+		default:
+			log.Fatal("--role must be one of active, passive", "role", hooksTestRole)
+		}
+
+		var hooks []config.Hook
+		switch hooksTestPhase {
+		case constants.HookTypePre:
+			hooks = role.Hooks.Pre
+		case constants.HookTypePost:
+			hooks = role.Hooks.Post
+		default:
+			log.Fatal("--phase must be one of pre, post", "phase", hooksTestPhase)
+		}
+
+		stage := hooksTestPhase + "-" + hooksTestRole
+		if len(hooks) == 0 {
+			fmt.Printf("no %s hooks configured\n", stage)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "testing %s hooks - dry run %t\n\n", stage, hooksTestDryRun)
+		checks := make([]commandCheck, 0, len(hooks))
+		for _, hook := range hooks {
+			checks = append(checks, newHookCheck(stage, hook))
+		}
+		printCommandChecks(w, checks)
+		w.Flush()
+		if hooksTestDryRun {
+			return
+		}
+
+		results := []config.HookResult{}
+		opts := config.HooksRunOptions{
+			LoggerPrefix: loadedConfig.Validator.Name,
+			WaitRetries:  true,
+			LoggerArgs:   []any{"failover_stage", stage},
+			OnResult: func(result config.HookResult) {
+				results = append(results, result)
+				printHookResult(result)
+			},
+			Context: &config.HookContext{
+				Role:          hooksTestRole,
+				Reason:        hooksTestReason,
+				ValidatorName: loadedConfig.Validator.Name,
+				ActivePubkey:  loadedConfig.Validator.Identities.ActiveKeyPair.PublicKey().String(),
+				PassivePubkey: loadedConfig.Validator.Identities.PassiveKeyPair.PublicKey().String(),
+			},
+		}
+
+		var err error
+		if hooksTestPhase == constants.HookTypePre {
+			err = role.Hooks.RunPre(opts)
+		} else {
+			role.Hooks.RunPost(opts)
+		}
+
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+			}
+		}
+
+		fmt.Println()
+		if err != nil {
+			fmt.Printf("a must_succeed hook failed - a failover would stop here, %d of %d %s hooks were not run\n", len(hooks)-len(results), len(hooks), stage)
+		}
+		fmt.Printf("%d of %d %s hooks succeeded\n", len(results)-failed, len(hooks), stage)
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// printHookResult prints a hook's outcome and captured output
+func printHookResult(result config.HookResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	status := "ok"
+	if result.Err != nil {
+		status = "failed: " + result.Err.Error()
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "hook\t%s\n", result.Hook.Name)
+	fmt.Fprintf(w, "result\t%s\n", status)
+	fmt.Fprintf(w, "exit code\t%d\n", result.ExitCode)
+	fmt.Fprintf(w, "attempts\t%d of %d\n", result.Attempts, result.Hook.Retries+1)
+	fmt.Fprintf(w, "duration\t%s\n", result.Duration.Round(time.Millisecond))
+	fmt.Fprintf(w, "must succeed\t%t\n", result.Hook.MustSucceed)
+	if result.Output == "" {
+		fmt.Fprintln(w, "output\t(none)")
+		return
+	}
+	fmt.Fprintln(w, "output")
+	for _, line := range strings.Split(strings.TrimRight(result.Output, "\n"), "\n") {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
+
+func init() {
+	hooksTestCmd.Flags().StringVar(&hooksTestRole, "role", "", "Role whose hooks to run - active or passive")
+	hooksTestCmd.Flags().StringVar(&hooksTestPhase, "phase", "", "Hooks to run - pre or post")
+	hooksTestCmd.Flags().BoolVar(&hooksTestDryRun, "dry-run", false, "Print the rendered hook commands without running them")
+	hooksTestCmd.MarkFlagRequired("role")
+	hooksTestCmd.MarkFlagRequired("phase")
+	hooksCmd.AddCommand(hooksTestCmd)
+}
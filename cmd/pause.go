@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/ha"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+	"github.com/spf13/cobra"
+)
+
+var pauseMaxDuration time.Duration
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause failover on the running agent for maintenance",
+	Long: `Pause failover on the running agent through its /admin/pause endpoint on the health check server
+(prometheus.port + 1). Gossip, metrics and decisions carry on but no role transition runs - each one skipped is logged
+with what would have happened. Failover resumes on its own after --max-duration, failover.max_pause_duration by
+default and at most, or with solana-validator-ha resume.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		query := url.Values{}
+		if pauseMaxDuration != 0 {
+			query.Set("max_duration", pauseMaxDuration.String())
+		}
+		status := postAdmin("/admin/pause", query)
+		fmt.Printf("failover paused until %s\n", status.ResumesAt.Local().Format(time.RFC3339))
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume failover on the running agent after a pause",
+	Long: `Resume failover on the running agent through its /admin/resume endpoint on the health check server
+(prometheus.port + 1), ending a pause started with solana-validator-ha pause. Does nothing if failover isn't paused.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		postAdmin("/admin/resume", nil)
+		fmt.Println("failover resumed")
+	},
+}
+
+// postAdmin posts to the agent's admin endpoint at path with the healthcheck.auth bearer token, if any, exiting 1
+// unless it answers with the pause status
+func postAdmin(path string, query url.Values) ha.PauseStatus {
+	client, err := statusClient(loadedConfig.HealthCheck.TLS)
+	if err != nil {
+		log.Error("failed to reach agent", "error", err)
+		os.Exit(statusExitUnreachable)
+	}
+
+	adminURL := agentURL(path)
+	if len(query) > 0 {
+		adminURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(http.MethodPost, adminURL, nil)
+	if err != nil {
+		log.Error("failed to reach agent", "url", adminURL, "error", err)
+		os.Exit(statusExitUnreachable)
+	}
+	httpauth.SetBearerToken(req, loadedConfig.HealthCheck.Auth.Token())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Error("failed to reach agent", "url", adminURL, "error", err)
+		os.Exit(statusExitUnreachable)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		log.Error(fmt.Sprintf("POST %s returned status %d", adminURL, resp.StatusCode), "error", strings.TrimSpace(string(body)))
+		os.Exit(statusExitUnreachable)
+	}
+
+	var status ha.PauseStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		log.Error("failed to decode pause status", "url", adminURL, "error", err)
+		os.Exit(statusExitUnreachable)
+	}
+	return status
+}
+
+func init() {
+	pauseCmd.Flags().DurationVar(&pauseMaxDuration, "max-duration", 0, "Resume failover on its own after this long, failover.max_pause_duration if not set")
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/spf13/cobra"
+)
+
+// clearScreen moves the cursor home and clears the terminal between --watch renders
+const clearScreen = "\033[H\033[2J"
+
+var peersWatch bool
+
+var peersCmd = &cobra.Command{
+	Use:   "peers",
+	Short: "Show the configured peers as gossip sees them right now",
+	Long: `Refresh the gossip state from cluster.rpc_urls once, exactly as the agent does every poll, and print each
+configured peer: whether it was found in gossip, the pubkey it was seen with, whether that is the active identity and
+when it was last seen. A peer found in cluster nodes is only counted when its gossip address can be dialed and, if it
+is the active identity, while it is voting - the same rules the agent fails over on. Peers with an rpc_url are also
+asked directly for their identity and health. Pass --watch to refresh every failover.poll_interval_duration, last seen
+is then kept for peers that drop out of gossip. --offline uses the same canned RPC stub as validate.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		state := gossip.NewState(gossip.Options{
+			ClusterRPC:   newRPCClient(loadedConfig.Validator.Name, loadedConfig.Cluster.RPCURLs...),
+			ActivePubkey: loadedConfig.Validator.Identities.ActiveKeyPair.PublicKey().String(),
+			ConfigPeers:  loadedConfig.Failover.Peers,
+			LogPrefix:    loadedConfig.Validator.Name,
+			NewPeerRPC:   newPeerRPCClient,
+		})
+
+		lastSeen := map[string]time.Time{}
+		for {
+			state.Refresh()
+			for name, peerState := range state.GetPeerStates() {
+				lastSeen[name] = peerState.LastSeenAtUTC
+			}
+
+			if peersWatch {
+				fmt.Print(clearScreen)
+			}
+			printPeers(state, lastSeen)
+
+			if !peersWatch {
+				return
+			}
+			time.Sleep(loadedConfig.Failover.PollIntervalDuration)
+		}
+	},
+}
+
+// printPeers prints the configured peers ordered by name with what the latest gossip refresh saw of them
+func printPeers(state *gossip.State, lastSeen map[string]time.Time) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "refreshed at\t%s\n", state.PeerStatesRefreshedAt.Time().UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "active pubkey\t%s\n", loadedConfig.Validator.Identities.ActiveKeyPair.PublicKey())
+	fmt.Fprintf(w, "leaderless samples\t%d\n", state.LeaderlessSamplesCount)
+	fmt.Fprintf(w, "cluster rpc errors\t%d\n", state.ConsecutiveRPCErrors)
+	fmt.Fprintln(w)
+
+	names := make([]string, 0, len(loadedConfig.Failover.Peers))
+	for name := range loadedConfig.Failover.Peers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	peerStates := state.GetPeerStates()
+	peerRPCStates := state.GetPeerRPCStates()
+	fmt.Fprintln(w, "NAME\tIP\tIN GOSSIP\tPUBKEY\tACTIVE\tLAST SEEN\tPEER RPC")
+	for _, name := range names {
+		pubkey, active, seen := "-", "-", "never"
+		peerState, inGossip := peerStates[name]
+		if inGossip {
+			pubkey = peerState.Pubkey
+			active = fmt.Sprintf("%t", peerState.LastSeenActive)
+			if peerState.Stale {
+				active += " (stale)"
+			}
+		}
+		if at, ok := lastSeen[name]; ok {
+			seen = at.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\t%s\t%s\n", name, loadedConfig.Failover.Peers[name].IP, inGossip, pubkey, active, seen, peerRPCSummary(peerRPCStates, name))
+	}
+}
+
+// peerRPCSummary describes what a peer's own rpc reported, - if it has no rpc_url
+func peerRPCSummary(peerRPCStates map[string]gossip.PeerRPCState, name string) string {
+	rpcState, ok := peerRPCStates[name]
+	if !ok {
+		return "-"
+	}
+	if !rpcState.Reachable {
+		return "unreachable"
+	}
+	health := "healthy"
+	if !rpcState.Healthy {
+		health = "unhealthy"
+	}
+	return fmt.Sprintf("%s active=%t %s", rpcState.Identity, rpcState.IsActive, health)
+}
+
+func init() {
+	peersCmd.Flags().BoolVar(&offline, "offline", false, "Answer RPC calls with canned data and make no network requests")
+	peersCmd.Flags().BoolVar(&peersWatch, "watch", false, "Refresh and re-render every failover.poll_interval_duration")
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/buildinfo"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -12,12 +13,15 @@ import (
 //go:embed version.txt
 var versionFile string
 
-var version = strings.TrimSpace(strings.Split(versionFile, "\n")[0])
+// version is from version.txt unless overridden at build time with -X .../internal/buildinfo.Version
+var version = buildinfo.WithDefaultVersion(strings.TrimSpace(strings.Split(versionFile, "\n")[0]))
 
 var (
-	configFile   string
-	logLevel     string
-	loadedConfig *config.Config
+	configFile    string
+	logLevel      string
+	logFormat     string
+	lenientConfig bool
+	loadedConfig  *config.Config
 )
 
 var rootCmd = &cobra.Command{
@@ -31,11 +35,16 @@ It monitors peers and manages failover decisions to ensure continuous validator
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		// Load configuration
 		var err error
-		loadedConfig, err = config.NewFromConfigFile(configFile)
+		loadedConfig, err = config.NewFromConfigFileWithParams(configFile, config.NewConfigParams{LenientConfig: lenientConfig})
 		if err != nil {
 			log.Fatal("failed to load configuration", "error", err)
 		}
 
+		if logFormat != "" {
+			if err := loadedConfig.Log.SetFormatString(logFormat); err != nil {
+				log.Fatal("invalid --log-format", "error", err)
+			}
+		}
 		loadedConfig.Log.ConfigureWithLevelString(logLevel)
 	},
 }
@@ -49,7 +58,22 @@ func init() {
 	// Add global flags here
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "~/solana-validator-ha/config.yaml", "Path to configuration file (default: ~/solana-validator-ha/config.yaml)")
 	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "", "Log level (debug, info, warn, error, fatal) - overrides config.yaml log.level if specified")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "Log format (text, json, logfmt) - overrides config.yaml log.format if specified")
+	rootCmd.PersistentFlags().BoolVar(&lenientConfig, "lenient-config", false, "Warn about unknown keys in the config file instead of failing to load it")
 
 	// Add subcommands here
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(explainCmd)
+	rootCmd.AddCommand(promoteCmd)
+	rootCmd.AddCommand(demoteCmd)
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(hooksCmd)
+	rootCmd.AddCommand(peersCmd)
 }
@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"errors"
+	"os"
+
 	"github.com/charmbracelet/log"
 	"github.com/sol-strategies/solana-validator-ha/internal/ha"
 	"github.com/spf13/cobra"
 )
 
+var (
+	runForceDryRun           bool
+	runSkipPassiveStakeCheck bool
+)
+
 var runCmd = &cobra.Command{
 	Use:           "run",
 	Short:         "Start the Solana validator HA manager",
@@ -13,13 +21,34 @@ var runCmd = &cobra.Command{
 	SilenceUsage:  true,
 	SilenceErrors: true,
 	Run: func(cmd *cobra.Command, args []string) {
+		// --dry-run can only force dry run on, a typo can never arm a node
+		if runForceDryRun {
+			loadedConfig.ForceDryRun()
+		}
+		loadedConfig.Validator.Identities.SkipPassiveStakeCheck = runSkipPassiveStakeCheck
+
 		// Start the HA manager with the loaded config
 		manager := ha.NewManager(ha.NewManagerOptions{
 			Cfg: loadedConfig,
 		})
 		err := manager.Run()
 		if err != nil {
-			log.Fatal("failed to run manager", "error", err)
+			log.Error("failed to run manager", "error", err)
+			os.Exit(exitCode(err))
 		}
 	},
 }
+
+func init() {
+	runCmd.PersistentFlags().BoolVar(&runForceDryRun, "dry-run", false, "Force failover.dry_run on regardless of the config, it can't be forced off")
+	runCmd.PersistentFlags().BoolVar(&runSkipPassiveStakeCheck, "skip-passive-stake-check", false, "Start even if a passive identity is the node identity of a vote account or has stake delegated to it")
+}
+
+// exitCode returns the exit code carried by err if it has one, 1 otherwise
+func exitCode(err error) int {
+	var exitCoder interface{ ExitCode() int }
+	if errors.As(err, &exitCoder) {
+		return exitCoder.ExitCode()
+	}
+	return 1
+}
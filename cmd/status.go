@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/gates"
+	"github.com/sol-strategies/solana-validator-ha/internal/ha"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+	"github.com/sol-strategies/solana-validator-ha/internal/tlscert"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// statusExitUnreachable is the exit code when the agent can't be queried
+	statusExitUnreachable = 1
+	// statusExitUnhealthy is the exit code when the agent reports itself unhealthy
+	statusExitUnhealthy = 2
+)
+
+var statusOutput string
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show what the running agent thinks it is doing",
+	Long: `Query the running agent's /status endpoint on the health check server (prometheus.port + 1) and print its
+role, failover status, peers and their gossip visibility, dry run, version, timers and when its state was last updated. Exits 1 if the agent is unreachable and 2 if it
+reports itself unhealthy.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		if statusOutput != "text" && statusOutput != "json" {
+			log.Fatal("invalid --output, must be one of text, json", "output", statusOutput)
+		}
+
+		client, err := statusClient(loadedConfig.HealthCheck.TLS)
+		if err != nil {
+			log.Error("failed to query agent status", "error", err)
+			os.Exit(statusExitUnreachable)
+		}
+
+		url := agentURL("/status")
+		status, raw, err := fetchStatus(client, url, loadedConfig.HealthCheck.Auth.Token())
+		if err != nil {
+			log.Error("failed to query agent status", "url", url, "error", err)
+			os.Exit(statusExitUnreachable)
+		}
+
+		switch statusOutput {
+		case "json":
+			os.Stdout.Write(raw)
+		default:
+			fmt.Printf("validator:       %s\n", status.ValidatorName)
+			fmt.Printf("public ip:       %s\n", status.PublicIP)
+			fmt.Printf("role:            %s\n", status.Role)
+			fmt.Printf("status:          %s\n", status.Status)
+			fmt.Printf("failover status: %s\n", status.FailoverStatus)
+			fmt.Printf("peer count:      %d\n", status.PeerCount)
+			fmt.Printf("self in gossip:  %t\n", status.SelfInGossip)
+			fmt.Printf("leaderless:      %d samples\n", status.LeaderlessSamples)
+			fmt.Printf("dry run:         %t\n", status.DryRun)
+			if status.PausedUntil != nil {
+				fmt.Printf("paused:          until %s\n", status.PausedUntil.Format(time.RFC3339))
+			} else {
+				fmt.Printf("paused:          %t\n", status.Paused)
+			}
+			fmt.Printf("version:         %s\n", status.Version)
+			fmt.Printf("last observed:   %s\n", formatStatusTime(status.LastObserved))
+			fmt.Printf("last changed:    %s\n", formatStatusTime(status.LastChanged))
+			if len(status.Peers) > 0 {
+				fmt.Println("peers:")
+				for _, peer := range status.Peers {
+					fmt.Printf("  %-26s %s\n", peer.Name+":", formatStatusPeer(peer))
+				}
+			}
+			if len(status.Timers) > 0 {
+				fmt.Println("timers:")
+				for _, timer := range status.Timers {
+					fmt.Printf("  %-26s %s\n", timer.Name+":", formatStatusTimer(timer))
+				}
+			}
+			if status.Coordination != nil {
+				fmt.Printf("coordination:    %s\n", formatStatusCoordination(*status.Coordination))
+			}
+			if status.Gates != nil {
+				fmt.Printf("safety gates:    checked %s\n", formatStatusTime(status.Gates.Time))
+				for _, result := range status.Gates.Results {
+					fmt.Printf("  %-26s %s\n", result.Name+":", formatStatusGate(result))
+				}
+			}
+		}
+
+		if status.Status != constants.StatusHealthy.String() {
+			os.Exit(statusExitUnhealthy)
+		}
+	},
+}
+
+// agentURL returns the url of path on the agent's health check server, reached at healthcheck.bind_address or
+// loopback when it listens on every address
+func agentURL(path string) string {
+	host := net.JoinHostPort(loadedConfig.HealthCheck.DialHost(), strconv.Itoa(loadedConfig.Prometheus.HealthCheckPort()))
+	return fmt.Sprintf("%s://%s%s", loadedConfig.HealthCheck.TLS.Scheme(), host, path)
+}
+
+// statusClient returns the client the agent status is fetched with. Over https it trusts only the agent's own
+// healthcheck.tls certificate, which needn't be valid for the address it is reached on.
+func statusClient(tlsCfg *config.TLS) (*http.Client, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	if tlsCfg != nil {
+		tlsConfig, err := tlscert.PinnedClientConfig(tlsCfg.CertFile)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	return client, nil
+}
+
+// fetchStatus gets and decodes the agent status with the healthcheck.auth bearer token, if any, also returning the
+// raw response for --output json
+func fetchStatus(client *http.Client, url string, token string) (status ha.Status, raw []byte, err error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return status, nil, err
+	}
+	httpauth.SetBearerToken(req, token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return status, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return status, nil, fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return status, nil, fmt.Errorf("failed to decode status: %w", err)
+	}
+
+	raw, err = json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return status, nil, err
+	}
+	return status, append(raw, '\n'), nil
+}
+
+// formatStatusTime formats t with how long ago it was, never if zero
+func formatStatusTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return fmt.Sprintf("%s (%s ago)", t.Format(time.RFC3339), time.Since(t).Round(time.Second))
+}
+
+// formatStatusPeer formats a peer's ip, whether it is in gossip and when it was last seen
+func formatStatusPeer(peer ha.PeerStatus) string {
+	inGossip := "not in gossip"
+	if peer.InGossip {
+		inGossip = "in gossip"
+	}
+	lastSeen := "never seen"
+	if peer.LastSeenAt != nil {
+		lastSeen = "last seen " + formatStatusTime(*peer.LastSeenAt)
+	}
+	return fmt.Sprintf("%s %s, %s", peer.IP, inGossip, lastSeen)
+}
+
+// formatStatusCoordination formats the coordination key, who holds it and whether its backend is reachable
+func formatStatusCoordination(coordination ha.CoordinationStatus) string {
+	holder := "held by no one"
+	if coordination.Holder != "" {
+		holder = "held by " + coordination.Holder
+	}
+	switch {
+	case coordination.Degraded:
+		return fmt.Sprintf("%s %s unreachable, DEGRADED to gossip alone - %s", coordination.Backend, coordination.Key, coordination.Error)
+	case !coordination.Reachable:
+		return fmt.Sprintf("%s %s unreachable, last %s - %s", coordination.Backend, coordination.Key, holder, coordination.Error)
+	}
+	return fmt.Sprintf("%s %s %s", coordination.Backend, coordination.Key, holder)
+}
+
+// formatStatusTimer formats a timer's remaining time and expiry, not running if it has no expiry
+func formatStatusTimer(timer ha.TimerStatus) string {
+	if timer.ExpiresAt == nil {
+		return "not running - " + timer.Purpose
+	}
+	remaining := time.Duration(timer.RemainingSeconds * float64(time.Second)).Round(time.Second)
+	return fmt.Sprintf("%s remaining, expires %s - %s", remaining, timer.ExpiresAt.Format(time.RFC3339), timer.Purpose)
+}
+
+// formatStatusGate formats a safety gate result with its mode and what it was based on
+func formatStatusGate(result gates.Result) string {
+	outcome := "passed"
+	switch {
+	case !result.Checked:
+		return "off"
+	case result.Blocked:
+		outcome = "BLOCKED"
+	case result.Violated():
+		outcome = "would have blocked"
+	}
+	return fmt.Sprintf("%s (%s) - %s", outcome, result.Mode, result.Detail)
+}
+
+func init() {
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "text", "Output format (text, json)")
+}
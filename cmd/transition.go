@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/ha"
+	"github.com/spf13/cobra"
+)
+
+var (
+	transitionDryRun bool
+	promoteForce     bool
+)
+
+var promoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Make this node active now through the configured hooks and active command",
+	Long: `Run the same transition to active as a failover - pre-active hooks, failover.active.command, post-active hooks
+and confirmation by local RPC - without waiting for gossip to declare the active node dead. The agent must not be
+running as the single instance lock is taken. Refuses while gossip shows another active peer unless --force is passed.
+Honours failover.dry_run, which --dry-run forces on.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		runManualTransition("active", loadedConfig.Failover.Active, func(manager *ha.Manager) error {
+			return manager.Promote(ha.ManualTransitionOptions{Force: promoteForce})
+		})
+	},
+}
+
+var demoteCmd = &cobra.Command{
+	Use:   "demote",
+	Short: "Make this node passive now through the configured hooks and passive command",
+	Long: `Run the same transition to passive as a failover - pre-passive hooks, failover.passive.command, post-passive
+hooks and confirmation by local RPC. The agent must not be running as the single instance lock is taken.
+Honours failover.dry_run, which --dry-run forces on.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		runManualTransition("passive", loadedConfig.Failover.Passive, func(manager *ha.Manager) error {
+			return manager.Demote()
+		})
+	},
+}
+
+// runManualTransition prints the rendered commands for role then runs transition with a manager for the loaded config
+func runManualTransition(name string, role config.Role, transition func(manager *ha.Manager) error) {
+	if transitionDryRun {
+		loadedConfig.Failover.DryRun = true
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "becoming %s - dry run %t\n\n", name, loadedConfig.Failover.DryRun)
+	printCommandChecks(w, roleCommandChecks(name, role))
+	fmt.Fprintln(w)
+	w.Flush()
+
+	manager := ha.NewManager(ha.NewManagerOptions{
+		Cfg: loadedConfig,
+	})
+	if err := transition(manager); err != nil {
+		log.Error("failed to become "+name, "error", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+func init() {
+	promoteCmd.Flags().BoolVar(&promoteForce, "force", false, "Promote even though gossip shows another active peer")
+	promoteCmd.Flags().BoolVar(&transitionDryRun, "dry-run", false, "Log the commands and hooks without running them, overriding failover.dry_run")
+	demoteCmd.Flags().BoolVar(&transitionDryRun, "dry-run", false, "Log the commands and hooks without running them, overriding failover.dry_run")
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var offline bool
+
+// commandCheck is a role command or hook and whether its binary was found
+type commandCheck struct {
+	stage   string
+	name    string
+	command string
+	args    []string
+	found   bool
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration and RPC connectivity",
+	Long: `Validate the configuration file - loading identities and rendering the role command templates - then resolve our
+public IP, check the cluster and local validator RPC endpoints respond and that every role command and hook is on PATH.
+Unreachable endpoints and missing commands are warnings, a summary is printed and the exit code is 1 only if the agent
+would refuse to start. With --offline RPC calls are answered with canned data and no network access is made.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		// the config has been loaded, defaulted, validated and its role commands rendered by the root command
+		logger := log.WithPrefix("[validate]")
+		logger.Info("configuration is valid", "file", configFile, "warnings", len(loadedConfig.Warnings))
+		for _, warning := range loadedConfig.Warnings {
+			logger.Warn(warning.Message, "warning_code", warning.Code)
+		}
+		failed := false
+
+		clusterRPC := newRPCClient(loadedConfig.Validator.Name, loadedConfig.Cluster.RPCURLs...)
+		localRPC := newRPCClient(loadedConfig.Validator.Name, loadedConfig.Validator.RPCURL)
+		if offline {
+			logger.Warn("offline mode - RPC responses are canned and do not reflect the cluster")
+		}
+
+		// resolve our public ip as the agent does on startup - it refuses to start if a peer has it
+		// a static validator.public_ip needs no network so is checked offline too
+		publicIP := "skipped (offline)"
+		if !offline || loadedConfig.Validator.PublicIPSource() == config.PublicIPSourceConfig {
+			ip, err := loadedConfig.Validator.ResolvePublicIP()
+			if err != nil {
+				publicIP = "unresolved"
+				logger.Warn("failed to resolve public ip", "error", err)
+			} else {
+				publicIP = ip
+				if loadedConfig.Failover.Peers.HasIP(ip) {
+					logger.Error("failover.peers must not reference ourselves", "public_ip", ip)
+					failed = true
+				}
+			}
+		}
+
+		// cluster rpc should respond and we report which configured peers are visible in gossip
+		gossipIPs := map[string]bool{}
+		nodes, err := clusterRPC.GetClusterNodes(context.Background())
+		if err != nil {
+			logger.Warn("failed to get cluster nodes - cluster rpc unreachable", "cluster_rpc_urls", loadedConfig.Cluster.RPCURLs, "error", err)
+		} else {
+			for _, node := range nodes {
+				if node.Gossip == nil {
+					continue
+				}
+				gossipIPs[gossip.AddressIP(*node.Gossip)] = true
+			}
+			logger.Info("cluster rpc ok", "cluster_nodes", len(nodes))
+		}
+
+		// local validator rpc should respond with one of our identities
+		role := "unreachable"
+		identity, err := localRPC.GetIdentity(context.Background())
+		if err != nil {
+			logger.Warn("failed to get local validator identity - validator rpc unreachable", "validator_rpc_url", loadedConfig.Validator.RPCURL, "error", err)
+		} else {
+			role = "unknown"
+			switch {
+			case identity.Identity.String() == loadedConfig.Validator.Identities.ActiveKeyPair.PublicKey().String():
+				role = "active"
+			case loadedConfig.Validator.Identities.IsPassivePubkey(identity.Identity.String()):
+				role = "passive"
+			}
+			logger.Info("validator rpc ok", "identity", identity.Identity.String(), "role", role)
+		}
+
+		// every command and hook should be runnable - catch typos before a real failover does
+		checks := commandChecks(loadedConfig.Failover)
+		for _, check := range checks {
+			if !check.found {
+				logger.Warn("command not found on PATH", "stage", check.stage, "name", check.name, "command", check.command)
+			}
+		}
+
+		printValidateSummary(publicIP, role, gossipIPs, nodes != nil, checks)
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// commandChecks looks up every rendered role command and hook, and sample hook on PATH
+func commandChecks(failover config.Failover) (checks []commandCheck) {
+	checks = append(checks, roleCommandChecks("active", failover.Active)...)
+	checks = append(checks, roleCommandChecks("passive", failover.Passive)...)
+	for _, hook := range failover.SampleHooks {
+		checks = append(checks, newHookCheck("sample", hook))
+	}
+	return checks
+}
+
+// roleCommandChecks looks up the role's pre hooks, command and post hooks on PATH in the order they run
+func roleCommandChecks(name string, role config.Role) (checks []commandCheck) {
+	for _, hook := range role.Hooks.Pre {
+		checks = append(checks, newHookCheck("pre-"+name, hook))
+	}
+	checks = append(checks, newRoleMethodCheck(name, role))
+	for _, hook := range role.Hooks.Post {
+		checks = append(checks, newHookCheck("post-"+name, hook))
+	}
+	return checks
+}
+
+// newRoleMethodCheck looks up the role command on PATH or, with method agave_admin_rpc, checks the admin rpc
+// socket exists
+func newRoleMethodCheck(name string, role config.Role) commandCheck {
+	if !role.UsesAdminRPC() {
+		return newCommandCheck(name, "command", role.Command, role.Args)
+	}
+	socket := role.AdminRPC.Socket()
+	_, err := os.Stat(socket)
+	return commandCheck{stage: name, name: "command", command: "agave_admin_rpc (built in)", args: []string{socket}, found: err == nil}
+}
+
+// newHookCheck looks up a hook's command on PATH - slack and webhook hooks are built in and always found, showing
+// their channel or method and redacted url
+func newHookCheck(stage string, hook config.Hook) commandCheck {
+	if hook.Slack != nil {
+		return commandCheck{stage: stage, name: hook.Name, command: "slack (built in)", args: []string{hook.Slack.Channel}, found: true}
+	}
+	if hook.Webhook != nil {
+		return commandCheck{stage: stage, name: hook.Name, command: "webhook (built in)", args: []string{hook.Webhook.Method, loadedConfig.Redact.String(hook.Webhook.URL)}, found: true}
+	}
+	return newCommandCheck(stage, hook.Name, hook.Command, hook.Args)
+}
+
+// newCommandCheck looks up command on PATH
+func newCommandCheck(stage string, name string, command string, args []string) commandCheck {
+	_, err := exec.LookPath(command)
+	return commandCheck{stage: stage, name: name, command: command, args: args, found: err == nil}
+}
+
+// printValidateSummary prints the resolved configuration as tables on stdout
+func printValidateSummary(publicIP string, role string, gossipIPs map[string]bool, gossipKnown bool, checks []commandCheck) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "validator\t%s\n", loadedConfig.Validator.Name)
+	fmt.Fprintf(w, "cluster\t%s\n", loadedConfig.Cluster.Name)
+	fmt.Fprintf(w, "public ip\t%s\n", publicIP)
+	fmt.Fprintf(w, "role\t%s\n", role)
+	fmt.Fprintf(w, "active pubkey\t%s\n", loadedConfig.Validator.Identities.ActiveKeyPair.PublicKey())
+	for i, passivePubkey := range loadedConfig.Validator.Identities.PassivePubkeys() {
+		label := "passive pubkey"
+		if i > 0 {
+			label = "passive pubkey (rotation)"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", label, passivePubkey)
+	}
+	fmt.Fprintf(w, "dry run\t%t\n", loadedConfig.Failover.DryRun)
+
+	// peers in the static rank order used for takeover
+	rankedIPs := loadedConfig.Failover.Peers.GetRankedIPs()
+	names := make([]string, 0, len(loadedConfig.Failover.Peers))
+	for name := range loadedConfig.Failover.Peers {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return rankedIPs[loadedConfig.Failover.Peers[names[i]].IP] < rankedIPs[loadedConfig.Failover.Peers[names[j]].IP]
+	})
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "PEER\tIP\tRANK\tIN GOSSIP")
+	for _, name := range names {
+		peer := loadedConfig.Failover.Peers[name]
+		inGossip := "unknown"
+		if gossipKnown {
+			inGossip = fmt.Sprintf("%t", gossipIPs[peer.IP])
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", name, peer.IP, rankedIPs[peer.IP], inGossip)
+	}
+
+	fmt.Fprintln(w)
+	printCommandChecks(w, checks)
+}
+
+// printCommandChecks prints the commands as a table
+func printCommandChecks(w io.Writer, checks []commandCheck) {
+	fmt.Fprintln(w, "STAGE\tNAME\tON PATH\tCOMMAND")
+	for _, check := range checks {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", check.stage, check.name, check.found, strings.TrimSpace(check.command+" "+strings.Join(check.args, " ")))
+	}
+}
+
+// newRPCClient returns an offline stub client when --offline is set, otherwise a real one
+func newRPCClient(logPrefix string, urls ...string) *rpc.Client {
+	if offline {
+		return rpc.NewOfflineClient(logPrefix, urls...)
+	}
+	return rpc.NewClient(logPrefix, urls...)
+}
+
+// newPeerRPCClient returns the client for a peer's rpc_url, honouring --offline like newRPCClient
+func newPeerRPCClient(logPrefix string, url string) rpc.SolanaClient {
+	return newRPCClient(logPrefix, url)
+}
+
+func init() {
+	validateCmd.Flags().BoolVar(&offline, "offline", false, "Answer RPC calls with canned data and make no network requests")
+}
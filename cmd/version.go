@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/buildinfo"
+	"github.com/spf13/cobra"
+)
+
+var versionOutput string
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show the version, commit, build date and go version of this binary",
+	Long: `Print the build details of this binary. The same details are exported by the running agent as
+solana_validator_ha_build_info so peers can be checked to run the same release.`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	// no configuration needed
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		info := buildinfo.Get()
+
+		switch versionOutput {
+		case "json":
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(info); err != nil {
+				log.Fatal("failed to encode version", "error", err)
+			}
+		case "text":
+			fmt.Printf("solana-validator-ha version %s\n", info.Version)
+			fmt.Printf("commit:     %s\n", info.Commit)
+			fmt.Printf("build date: %s\n", info.BuildDate)
+			fmt.Printf("go version: %s\n", info.GoVersion)
+		default:
+			log.Fatal("invalid --output, must be one of text, json", "output", versionOutput)
+		}
+	},
+}
+
+func init() {
+	versionCmd.Flags().StringVarP(&versionOutput, "output", "o", "text", "Output format (text, json)")
+}
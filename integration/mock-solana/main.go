@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -14,11 +15,39 @@ import (
 )
 
 type MockSolanaServer struct {
-	activeValidator  string
-	validators       map[string]string
-	disconnected     map[string]bool
-	mu               sync.RWMutex
-	callingValidator string // Added to track which validator is calling the RPC endpoint
+	activeValidator string
+	validators      map[string]string
+	disconnected    map[string]bool
+	mu              sync.RWMutex
+	activePubkey    string
+	passivePubkeys  map[string]string
+	controlChanges  []ControlChange
+	hookRecords     []HookRecord
+	slotLags        map[string]int64
+	// splitBrainValidator's local RPC also reports the active identity while the active validator holds it
+	splitBrainValidator string
+	// clients are the validator client each validator's local RPC emulates, agave when not set
+	clients map[string]string
+}
+
+// ControlChange records a change of the active validator and who made it
+type ControlChange struct {
+	Time            time.Time `json:"time"`
+	ActiveValidator string    `json:"active_validator"`
+	ChangedBy       string    `json:"changed_by"`
+}
+
+// HookRecord records a command or hook executed by a validator
+type HookRecord struct {
+	Time      time.Time `json:"time"`
+	Validator string    `json:"validator"`
+	Name      string    `json:"name"`
+}
+
+// MockSolanaControlView is the GET /control response
+type MockSolanaControlView struct {
+	ActiveValidator string          `json:"active_validator"`
+	Changes         []ControlChange `json:"changes"`
 }
 
 type RPCRequest struct {
@@ -44,6 +73,17 @@ type MockSolanaControl struct {
 	ActiveValidator string `json:"active_validator"`
 }
 
+// SlotLagControl sets how many slots a validator's local RPC reports behind the cluster
+type SlotLagControl struct {
+	Validator string `json:"validator"`
+	Lag       int64  `json:"lag"`
+}
+
+// SplitBrainControl sets the validator whose local RPC also reports the active identity, empty clears it
+type SplitBrainControl struct {
+	Validator string `json:"validator"`
+}
+
 type NetworkControl struct {
 	DisconnectValidator string `json:"disconnect_validator"`
 	ReconnectValidator  string `json:"reconnect_validator"`
@@ -77,7 +117,33 @@ func NewMockSolanaServer() *MockSolanaServer {
 			"validator-3": os.Getenv("VALIDATOR_3_IP"),
 		},
 		disconnected: make(map[string]bool),
+		slotLags:     make(map[string]int64),
+		clients: map[string]string{
+			"validator-1": os.Getenv("VALIDATOR_1_CLIENT"),
+			"validator-2": os.Getenv("VALIDATOR_2_CLIENT"),
+			"validator-3": os.Getenv("VALIDATOR_3_CLIENT"),
+		},
+		activePubkey: loadPubkey(os.Getenv("ACTIVE_IDENTITY_FILE"), "ArkzFExXXHaA6izkNhTJJ5zpXdQpynffjfRMJu4Yq6H"),
+		passivePubkeys: map[string]string{
+			"validator-1": loadPubkey(os.Getenv("VALIDATOR_1_PASSIVE_IDENTITY_FILE"), "AP4JyZq2vuN4u64FGFHTwdG11xHu1vZWVYQj21MPLrnw"),
+			"validator-2": loadPubkey(os.Getenv("VALIDATOR_2_PASSIVE_IDENTITY_FILE"), "DJ7w4p8Ve7qdSAmkpA3sviSbsd1HPUxd43x7MTH72JHT"),
+			"validator-3": loadPubkey(os.Getenv("VALIDATOR_3_PASSIVE_IDENTITY_FILE"), "5dXttfrjFEEExmZhVmVAdw2LzepNAhFYJTUgPCDk8CYD"),
+		},
+	}
+}
+
+// loadPubkey returns the pubkey of the keygen file at path so the mock reports the same identities
+// the validators are configured with, falling back to a fixed pubkey when there is no file
+func loadPubkey(path string, fallback string) string {
+	if path == "" {
+		return fallback
+	}
+	privateKey, err := solana.PrivateKeyFromSolanaKeygenFile(path)
+	if err != nil {
+		log.Printf("Failed to load identity %s, using %s: %v", path, fallback, err)
+		return fallback
 	}
+	return privateKey.PublicKey().String()
 }
 
 func (s *MockSolanaServer) handleRPC(w http.ResponseWriter, r *http.Request) {
@@ -94,22 +160,21 @@ func (s *MockSolanaServer) handleRPC(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Track which validator is calling based on query parameter
+	// Which validator is calling is passed per request as a query parameter
 	validatorName := r.URL.Query().Get("validator")
-	if validatorName != "" {
-		s.mu.Lock()
-		s.callingValidator = validatorName
-		s.mu.Unlock()
-	}
 
 	var response interface{}
 	switch method {
 	case "getClusterNodes":
 		response = s.getClusterNodes()
 	case "getIdentity":
-		response = s.getIdentity()
+		response = s.getIdentity(validatorName)
 	case "getHealth":
 		response = s.getHealth()
+	case "getSlot":
+		response = s.getSlot(validatorName)
+	case "getVoteAccounts":
+		response = s.getVoteAccounts()
 	default:
 		response = map[string]interface{}{
 			"error": map[string]interface{}{
@@ -131,6 +196,19 @@ func (s *MockSolanaServer) handleRPC(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *MockSolanaServer) handleControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" {
+		s.mu.RLock()
+		view := MockSolanaControlView{
+			ActiveValidator: s.activeValidator,
+			Changes:         append([]ControlChange{}, s.controlChanges...),
+		}
+		s.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(view)
+		return
+	}
+
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -142,7 +220,7 @@ func (s *MockSolanaServer) handleControl(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	s.SetActiveValidator(control.ActiveValidator)
+	s.SetActiveValidator(control.ActiveValidator, s.callerName(r))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
@@ -170,6 +248,126 @@ func (s *MockSolanaServer) handleNetwork(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+// handleSlotLag sets a validator's slot lag (POST) and lists them (GET)
+func (s *MockSolanaServer) handleSlotLag(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		s.mu.RLock()
+		lags := make(map[string]int64, len(s.slotLags))
+		for validator, lag := range s.slotLags {
+			lags[validator] = lag
+		}
+		s.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(lags)
+	case "POST":
+		var control SlotLagControl
+		if err := json.NewDecoder(r.Body).Decode(&control); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		if control.Lag > 0 {
+			s.slotLags[control.Validator] = control.Lag
+		} else {
+			delete(s.slotLags, control.Validator)
+		}
+		s.mu.Unlock()
+		log.Printf("Slot lag for %s set to %d", control.Validator, control.Lag)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSplitBrain sets the split brain validator (POST) and returns it (GET)
+func (s *MockSolanaServer) handleSplitBrain(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		s.mu.RLock()
+		control := SplitBrainControl{Validator: s.splitBrainValidator}
+		s.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(control)
+	case "POST":
+		var control SplitBrainControl
+		if err := json.NewDecoder(r.Body).Decode(&control); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		s.splitBrainValidator = control.Validator
+		s.mu.Unlock()
+		log.Printf("Split brain validator set to %q by %s", control.Validator, s.callerName(r))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleHooks records hooks and commands run by validators (POST) and lists them (GET)
+func (s *MockSolanaServer) handleHooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		s.mu.RLock()
+		records := append([]HookRecord{}, s.hookRecords...)
+		s.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(records)
+	case "POST":
+		// attribute by caller address rather than trusting the request so a misconfigured node can't hide
+		record := HookRecord{
+			Time:      time.Now(),
+			Validator: s.callerName(r),
+			Name:      r.URL.Query().Get("name"),
+		}
+		s.mu.Lock()
+		s.hookRecords = append(s.hookRecords, record)
+		s.mu.Unlock()
+		log.Printf("Hook recorded: %s ran %s", record.Validator, record.Name)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	case "DELETE":
+		s.mu.Lock()
+		s.hookRecords = nil
+		s.controlChanges = nil
+		s.mu.Unlock()
+		log.Printf("Hook and control records cleared")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// callerName returns the validator name for the request's source address, or the address itself
+func (s *MockSolanaServer) callerName(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for name, ip := range s.validators {
+		if ip == host {
+			return name
+		}
+	}
+	return host
+}
+
 func (s *MockSolanaServer) handlePublicIP(w http.ResponseWriter, r *http.Request) {
 	// Get the client's IP address
 	clientIP := r.RemoteAddr
@@ -182,24 +380,11 @@ func (s *MockSolanaServer) handlePublicIP(w http.ResponseWriter, r *http.Request
 		clientIP = clientIP[:colonIndex]
 	}
 
-	// Map the client IP to a different public IP for testing
-	// This prevents the "must not reference ourselves" validation error
-	// Return IPs that are NOT in the peers configuration
-	var validatorIP string
-	switch clientIP {
-	case "172.20.0.10":
-		validatorIP = "10.0.0.100" // Different from peers configuration
-	case "172.20.0.11":
-		validatorIP = "10.0.0.101" // Different from peers configuration
-	case "172.20.0.12":
-		validatorIP = "10.0.0.102" // Different from peers configuration
-	default:
-		// Fallback to a different IP
-		validatorIP = "10.0.0.103"
-	}
+	// Return the caller's network IP - it matches its gossip address and each
+	// validator config only lists its two peers, never itself
 
 	w.Header().Set("Content-Type", "text/plain")
-	w.Write([]byte(validatorIP))
+	w.Write([]byte(clientIP))
 }
 
 func (s *MockSolanaServer) getClusterNodes() []ClusterNode {
@@ -213,20 +398,9 @@ func (s *MockSolanaServer) getClusterNodes() []ClusterNode {
 			continue
 		}
 
-		// Use the actual active pubkey for the active validator
-		var pubkey string
+		pubkey := s.passivePubkeys[name]
 		if name == s.activeValidator {
-			pubkey = "ArkzFExXXHaA6izkNhTJJ5zpXdQpynffjfRMJu4Yq6H"
-		} else {
-			// Use valid Solana public keys for passive validators
-			switch name {
-			case "validator-2":
-				pubkey = "AP4JyZq2vuN4u64FGFHTwdG11xHu1vZWVYQj21MPLrnw"
-			case "validator-3":
-				pubkey = "DJ7w4p8Ve7qdSAmkpA3sviSbsd1HPUxd43x7MTH72JHT"
-			default:
-				pubkey = "11111111111111111111111111111111"
-			}
+			pubkey = s.activePubkey
 		}
 
 		node := ClusterNode{
@@ -258,44 +432,51 @@ func (s *MockSolanaServer) getBlock() *BlockInfo {
 	}
 }
 
-func (s *MockSolanaServer) getSlot() int64 {
-	return 1000
+// getSlot returns the cluster slot, less the validator's slot lag when called on a validator's local RPC
+func (s *MockSolanaServer) getSlot(validator string) int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return 1000 - s.slotLags[validator]
 }
 
-func (s *MockSolanaServer) getIdentity() map[string]interface{} {
+// getVoteAccounts reports the active identity as current and voting
+func (s *MockSolanaServer) getVoteAccounts() map[string]interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Return the appropriate identity based on which validator is calling
-	// Only the active validator should return the active pubkey
-	if s.callingValidator == s.activeValidator {
-		// This validator is the active one, return active pubkey
-		return map[string]interface{}{
-			"identity": "ArkzFExXXHaA6izkNhTJJ5zpXdQpynffjfRMJu4Yq6H",
-		}
-	} else {
-		// This validator is passive, return passive pubkey
-		// Use different passive pubkeys for different validators
-		switch s.callingValidator {
-		case "validator-1":
-			return map[string]interface{}{
-				"identity": "AP4JyZq2vuN4u64FGFHTwdG11xHu1vZWVYQj21MPLrnw",
-			}
-		case "validator-2":
-			return map[string]interface{}{
-				"identity": "DJ7w4p8Ve7qdSAmkpA3sviSbsd1HPUxd43x7MTH72JHT",
-			}
-		case "validator-3":
-			return map[string]interface{}{
-				"identity": "5dXttfrjFEEExmZhVmVAdw2LzepNAhFYJTUgPCDk8CYD",
-			}
-		default:
-			// Fallback - return passive pubkey
-			return map[string]interface{}{
-				"identity": "AP4JyZq2vuN4u64FGFHTwdG11xHu1vZWVYQj21MPLrnw",
-			}
-		}
+	return map[string]interface{}{
+		"current": []map[string]interface{}{
+			{
+				"votePubkey":       solana.PublicKeyFromBytes(make([]byte, 32)).String(),
+				"nodePubkey":       s.activePubkey,
+				"activatedStake":   1000000000,
+				"epochVoteAccount": true,
+				"commission":       0,
+				"lastVote":         1000,
+				"rootSlot":         968,
+				"epochCredits":     []interface{}{},
+			},
+		},
+		"delinquent": []interface{}{},
+	}
+}
+
+func (s *MockSolanaServer) getIdentity(validator string) interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Only the active validator, and a split brain validator, return the active pubkey, the rest their own passive pubkey
+	identity := s.passivePubkeys[validator]
+	if validator == s.activeValidator || validator == s.splitBrainValidator {
+		identity = s.activePubkey
+	}
+
+	// a firedancer validator reports its identity as the bare pubkey, agave as {"identity": <pubkey>}
+	if s.clients[validator] == "firedancer" {
+		return identity
 	}
+	return map[string]interface{}{"identity": identity}
 }
 
 func (s *MockSolanaServer) getHealth() string {
@@ -303,11 +484,16 @@ func (s *MockSolanaServer) getHealth() string {
 	return "ok"
 }
 
-func (s *MockSolanaServer) SetActiveValidator(validator string) {
+func (s *MockSolanaServer) SetActiveValidator(validator string, changedBy string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.activeValidator = validator
-	log.Printf("Active validator changed to: %s", validator)
+	s.controlChanges = append(s.controlChanges, ControlChange{
+		Time:            time.Now(),
+		ActiveValidator: validator,
+		ChangedBy:       changedBy,
+	})
+	log.Printf("Active validator changed to: %s by %s", validator, changedBy)
 }
 
 func (s *MockSolanaServer) DisconnectValidator(validator string) {
@@ -331,6 +517,9 @@ func main() {
 	http.HandleFunc("/control", server.handleControl)
 	http.HandleFunc("/network", server.handleNetwork)
 	http.HandleFunc("/public-ip", server.handlePublicIP)
+	http.HandleFunc("/hooks", server.handleHooks)
+	http.HandleFunc("/slot-lag", server.handleSlotLag)
+	http.HandleFunc("/split-brain", server.handleSplitBrain)
 
 	port := ":8899"
 	log.Printf("Mock Solana RPC server starting on port %s", port)
@@ -99,6 +99,9 @@ while true; do
         echo "  ✅ Scenario 1: One active and two passive peers"
         echo "  ✅ Scenario 2: Active peer disconnection"
         echo "  ✅ Scenario 3: Multiple passive peers compete"
+        echo "  ✅ Scenario 4: Unhealthy validators don't become active"
+        echo "  ✅ Scenario 5: dry_run validator never changes the mock's world"
+        echo "  ✅ Scenario 6: Healthy passive out-claims a lagging passive by fitness"
         echo ""
         print_status "You can view logs with: docker compose logs -f"
         print_status "Stop the environment with: docker compose down"
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,6 +16,8 @@ import (
 type TestOrchestrator struct {
 	mockSolanaURL string
 	validatorURLs map[string]string
+	// statusURLs are the validators' /status endpoints on the health check server
+	statusURLs map[string]string
 }
 
 type ValidatorStatus struct {
@@ -33,6 +36,37 @@ type NetworkControl struct {
 	ReconnectValidator  string `json:"reconnect_validator"`
 }
 
+// SlotLagControl sets how many slots a validator's local RPC reports behind the cluster
+type SlotLagControl struct {
+	Validator string `json:"validator"`
+	Lag       int64  `json:"lag"`
+}
+
+// ControlChange is a change of the mock's active validator and who made it
+type ControlChange struct {
+	Time            time.Time `json:"time"`
+	ActiveValidator string    `json:"active_validator"`
+	ChangedBy       string    `json:"changed_by"`
+}
+
+// MockSolanaControlView is the mock's GET /control response
+type MockSolanaControlView struct {
+	ActiveValidator string          `json:"active_validator"`
+	Changes         []ControlChange `json:"changes"`
+}
+
+// SplitBrainControl sets the validator whose local RPC also reports the active identity, empty clears it
+type SplitBrainControl struct {
+	Validator string `json:"validator"`
+}
+
+// HookRecord is a command or hook a validator reported running to the mock's hook recorder
+type HookRecord struct {
+	Time      time.Time `json:"time"`
+	Validator string    `json:"validator"`
+	Name      string    `json:"name"`
+}
+
 func NewTestOrchestrator() *TestOrchestrator {
 	return &TestOrchestrator{
 		mockSolanaURL: os.Getenv("MOCK_SOLANA_URL"),
@@ -41,6 +75,11 @@ func NewTestOrchestrator() *TestOrchestrator {
 			"validator-2": os.Getenv("VALIDATOR_2_URL"),
 			"validator-3": os.Getenv("VALIDATOR_3_URL"),
 		},
+		statusURLs: map[string]string{
+			"validator-1": os.Getenv("VALIDATOR_1_STATUS_URL"),
+			"validator-2": os.Getenv("VALIDATOR_2_STATUS_URL"),
+			"validator-3": os.Getenv("VALIDATOR_3_STATUS_URL"),
+		},
 	}
 }
 
@@ -110,50 +149,180 @@ func (t *TestOrchestrator) reconnectValidator(validator string) error {
 	return nil
 }
 
-func (t *TestOrchestrator) getValidatorStatus(validator string) (*ValidatorStatus, error) {
-	url := t.validatorURLs[validator] + "/metrics"
+func (t *TestOrchestrator) setSlotLag(validator string, lag int64) error {
+	jsonData, err := json.Marshal(SlotLagControl{Validator: validator, Lag: lag})
+	if err != nil {
+		return fmt.Errorf("failed to marshal slot lag data: %w", err)
+	}
+
+	resp, err := http.Post(t.mockSolanaURL+"/slot-lag", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to set slot lag: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to set slot lag, status: %d", resp.StatusCode)
+	}
+
+	log.Printf("Set slot lag for %s to %d", validator, lag)
+	return nil
+}
+
+func (t *TestOrchestrator) setSplitBrainValidator(validator string) error {
+	jsonData, err := json.Marshal(SplitBrainControl{Validator: validator})
+	if err != nil {
+		return fmt.Errorf("failed to marshal split brain data: %w", err)
+	}
 
-	resp, err := http.Get(url)
+	resp, err := http.Post(t.mockSolanaURL+"/split-brain", "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get metrics for %s: %w", validator, err)
+		return fmt.Errorf("failed to set split brain validator: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get metrics for %s, status: %d", validator, resp.StatusCode)
+		return fmt.Errorf("failed to set split brain validator, status: %d", resp.StatusCode)
+	}
+
+	log.Printf("Set split brain validator to: %q", validator)
+	return nil
+}
+
+func (t *TestOrchestrator) getSplitBrainValidator() (string, error) {
+	resp, err := http.Get(t.mockSolanaURL + "/split-brain")
+	if err != nil {
+		return "", fmt.Errorf("failed to get split brain validator: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get split brain validator, status: %d", resp.StatusCode)
+	}
+
+	var control SplitBrainControl
+	if err := json.NewDecoder(resp.Body).Decode(&control); err != nil {
+		return "", fmt.Errorf("failed to decode split brain validator: %w", err)
+	}
+	return control.Validator, nil
+}
+
+func (t *TestOrchestrator) clearMockRecords() error {
+	req, err := http.NewRequest(http.MethodDelete, t.mockSolanaURL+"/hooks", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create clear records request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to clear mock records: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to clear mock records, status: %d", resp.StatusCode)
+	}
+
+	log.Println("Cleared mock hook and control records")
+	return nil
+}
+
+func (t *TestOrchestrator) getControlView() (*MockSolanaControlView, error) {
+	resp, err := http.Get(t.mockSolanaURL + "/control")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mock control view: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get mock control view, status: %d", resp.StatusCode)
+	}
+
+	var view MockSolanaControlView
+	if err := json.NewDecoder(resp.Body).Decode(&view); err != nil {
+		return nil, fmt.Errorf("failed to decode mock control view: %w", err)
+	}
+	return &view, nil
+}
+
+func (t *TestOrchestrator) getHookRecords() ([]HookRecord, error) {
+	resp, err := http.Get(t.mockSolanaURL + "/hooks")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hook records: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get hook records, status: %d", resp.StatusCode)
+	}
+
+	var records []HookRecord
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return nil, fmt.Errorf("failed to decode hook records: %w", err)
+	}
+	return records, nil
+}
+
+// getWouldHaveCount sums the validator's would_have_total series for the given decision action
+func (t *TestOrchestrator) getWouldHaveCount(validator, action string) (float64, error) {
+	resp, err := http.Get(t.validatorURLs[validator] + "/metrics")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get metrics for %s: %w", validator, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to get metrics for %s, status: %d", validator, resp.StatusCode)
 	}
 
-	// Parse metrics to extract role and status
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return 0, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	metrics := string(body)
+	var count float64
+	for _, line := range strings.Split(string(body), "\n") {
+		if !strings.HasPrefix(line, "solana_validator_ha_would_have_total{") ||
+			!strings.Contains(line, fmt.Sprintf(`action="%s"`, action)) {
+			continue
+		}
+		fields := strings.Fields(line)
+		value, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse would_have_total value %q: %w", line, err)
+		}
+		count += value
+	}
+	return count, nil
+}
+
+// agentStatus is the part of a validator's /status response the tests check
+type agentStatus struct {
+	Role   string `json:"role"`
+	Status string `json:"status"`
+}
 
-	// Parse role from metadata metric
-	var role string
-	if strings.Contains(metrics, `validator_role="active"`) {
-		role = "active"
-	} else if strings.Contains(metrics, `validator_role="passive"`) {
-		role = "passive"
-	} else {
-		role = "unknown"
+func (t *TestOrchestrator) getValidatorStatus(validator string) (*ValidatorStatus, error) {
+	resp, err := http.Get(t.statusURLs[validator])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status for %s: %w", validator, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get status for %s, status: %d", validator, resp.StatusCode)
 	}
 
-	// Parse status from metadata metric
-	var status string
-	if strings.Contains(metrics, `validator_status="healthy"`) {
-		status = "healthy"
-	} else {
-		status = "unhealthy"
+	var status agentStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode status for %s: %w", validator, err)
 	}
 
 	return &ValidatorStatus{
-		Role:    role,
-		Healthy: status == "healthy",
-		Active:  role == "active",
-		Passive: role == "passive",
+		Role:    status.Role,
+		Healthy: status.Status == "healthy",
+		Active:  status.Role == "active",
+		Passive: status.Role == "passive",
 	}, nil
 }
 
@@ -448,6 +617,248 @@ func (t *TestOrchestrator) runScenario4() error {
 	return nil
 }
 
+func (t *TestOrchestrator) runScenario5() error {
+	log.Println("=== Scenario 5: dry_run validator never changes the mock's world ===")
+
+	const dryRunValidator = "validator-3"
+	liveValidators := map[string]bool{"validator-1": true, "validator-2": true}
+
+	// start clean - everyone in gossip, validator-1 active and no records from earlier scenarios
+	for validator := range t.validatorURLs {
+		if err := t.reconnectValidator(validator); err != nil {
+			return fmt.Errorf("failed to reconnect %s: %w", validator, err)
+		}
+	}
+	if err := t.setActiveValidator("validator-1"); err != nil {
+		return fmt.Errorf("failed to set validator-1 as active: %w", err)
+	}
+	if err := t.waitForValidatorRole("validator-1", "active", 30*time.Second); err != nil {
+		return fmt.Errorf("validator-1 should be active: %w", err)
+	}
+	if err := t.clearMockRecords(); err != nil {
+		return err
+	}
+
+	// disconnect the active validator and the other live one so the dry_run validator is the only candidate
+	if err := t.disconnectValidator("validator-1"); err != nil {
+		return fmt.Errorf("failed to disconnect validator-1: %w", err)
+	}
+	if err := t.disconnectValidator("validator-2"); err != nil {
+		return fmt.Errorf("failed to disconnect validator-2: %w", err)
+	}
+
+	// the dry_run validator must decide it would promote while its role never becomes active
+	deadline := time.Now().Add(45 * time.Second)
+	for {
+		status, err := t.getValidatorStatus(dryRunValidator)
+		if err == nil && status.Role == "active" {
+			return fmt.Errorf("%s (dry_run) reported role active", dryRunValidator)
+		}
+
+		wouldPromote, err := t.getWouldHaveCount(dryRunValidator, "become_active")
+		if err != nil {
+			log.Printf("Error getting would_have_total for %s: %v", dryRunValidator, err)
+		}
+		if wouldPromote > 0 {
+			log.Printf("%s (dry_run) would have promoted %.0f time(s)", dryRunValidator, wouldPromote)
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for %s (dry_run) to record a would promote decision", dryRunValidator)
+		}
+		time.Sleep(2 * time.Second)
+	}
+
+	// keep watching for a few more cycles - dry_run must hold, not just be slow
+	for i := 0; i < 5; i++ {
+		status, err := t.getValidatorStatus(dryRunValidator)
+		if err == nil && status.Role == "active" {
+			return fmt.Errorf("%s (dry_run) reported role active", dryRunValidator)
+		}
+		time.Sleep(3 * time.Second)
+	}
+
+	// reconnect validator-2 so a live validator takes over - this proves the recorders see real side effects
+	if err := t.reconnectValidator("validator-2"); err != nil {
+		return fmt.Errorf("failed to reconnect validator-2: %w", err)
+	}
+	if err := t.waitForValidatorRole("validator-2", "active", 45*time.Second); err != nil {
+		return fmt.Errorf("validator-2 should take over as active: %w", err)
+	}
+
+	// no commands or hooks may be attributed to the dry_run validator
+	records, err := t.getHookRecords()
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if record.Validator == dryRunValidator {
+			return fmt.Errorf("%s (dry_run) executed %s at %s", dryRunValidator, record.Name, record.Time)
+		}
+	}
+
+	// the mock's active validator may only have been changed by live validators
+	view, err := t.getControlView()
+	if err != nil {
+		return err
+	}
+	changedByLive := false
+	for _, change := range view.Changes {
+		if !liveValidators[change.ChangedBy] {
+			return fmt.Errorf("mock active validator changed to %s by %s, only live validators may change it", change.ActiveValidator, change.ChangedBy)
+		}
+		changedByLive = true
+	}
+	if !changedByLive {
+		return fmt.Errorf("expected validator-2 to change the mock's active validator, got no changes")
+	}
+
+	log.Printf("✅ Scenario 5 passed: %s (dry_run) would have promoted but never changed the mock's world", dryRunValidator)
+	return nil
+}
+
+func (t *TestOrchestrator) runScenario6() error {
+	log.Println("=== Scenario 6: Healthy passive out-claims a lagging passive by fitness ===")
+
+	// validator-1 has the lowest IP so would claim first by static priority - lag it so fitness must override that
+	const laggingValidator = "validator-1"
+	const healthyValidator = "validator-2"
+
+	// start clean - everyone in gossip with validator-3 active, it is dry_run so taking it away leaves
+	// validator-1 and validator-2 as the only live passives competing
+	for validator := range t.validatorURLs {
+		if err := t.reconnectValidator(validator); err != nil {
+			return fmt.Errorf("failed to reconnect %s: %w", validator, err)
+		}
+	}
+	if err := t.setActiveValidator("validator-3"); err != nil {
+		return fmt.Errorf("failed to set validator-3 as active: %w", err)
+	}
+	if err := t.waitForValidatorRole("validator-3", "active", 30*time.Second); err != nil {
+		return fmt.Errorf("validator-3 should be active: %w", err)
+	}
+	if err := t.setSlotLag(laggingValidator, 1000); err != nil {
+		return err
+	}
+	defer func() {
+		if err := t.setSlotLag(laggingValidator, 0); err != nil {
+			log.Printf("Failed to reset slot lag for %s: %v", laggingValidator, err)
+		}
+	}()
+	if err := t.clearMockRecords(); err != nil {
+		return err
+	}
+
+	// give the lagging validator a cycle to advertise its lower fitness before the active goes away
+	time.Sleep(5 * time.Second)
+
+	if err := t.disconnectValidator("validator-3"); err != nil {
+		return fmt.Errorf("failed to disconnect validator-3: %w", err)
+	}
+
+	if err := t.waitForValidatorRole(healthyValidator, "active", 45*time.Second); err != nil {
+		return fmt.Errorf("%s should out-claim %s by fitness: %w", healthyValidator, laggingValidator, err)
+	}
+
+	// the lagging validator must not have claimed at any point
+	view, err := t.getControlView()
+	if err != nil {
+		return err
+	}
+	for _, change := range view.Changes {
+		if change.ChangedBy == laggingValidator {
+			return fmt.Errorf("%s (lagging) claimed active at %s", laggingValidator, change.Time)
+		}
+	}
+	status, err := t.getValidatorStatus(laggingValidator)
+	if err == nil && status.Role == "active" {
+		return fmt.Errorf("%s (lagging) reported role active", laggingValidator)
+	}
+
+	log.Printf("✅ Scenario 6 passed: %s out-claimed %s (lagging) by fitness", healthyValidator, laggingValidator)
+	return nil
+}
+
+func (t *TestOrchestrator) runScenario7() error {
+	log.Println("=== Scenario 7: Lower priority of two actives demotes itself ===")
+
+	// no peer sets a priority so validator-1 outranks validator-2 by name, validator-2 runs
+	// demote_self_if_lower_priority and its passive command clears the split brain in the mock
+	const outrankedValidator = "validator-2"
+
+	// start clean - everyone in gossip, validator-1 active and no records from earlier scenarios
+	for validator := range t.validatorURLs {
+		if err := t.reconnectValidator(validator); err != nil {
+			return fmt.Errorf("failed to reconnect %s: %w", validator, err)
+		}
+	}
+	if err := t.setActiveValidator("validator-1"); err != nil {
+		return fmt.Errorf("failed to set validator-1 as active: %w", err)
+	}
+	if err := t.waitForValidatorRole("validator-1", "active", 30*time.Second); err != nil {
+		return fmt.Errorf("validator-1 should be active: %w", err)
+	}
+	if err := t.clearMockRecords(); err != nil {
+		return err
+	}
+
+	// validator-2 comes up with the active identity while validator-1 still holds it
+	if err := t.setSplitBrainValidator(outrankedValidator); err != nil {
+		return err
+	}
+	defer func() {
+		if err := t.setSplitBrainValidator(""); err != nil {
+			log.Printf("Failed to clear split brain validator: %v", err)
+		}
+	}()
+
+	// validator-2 must demote itself through its passive command
+	deadline := time.Now().Add(45 * time.Second)
+	for {
+		splitBrainValidator, err := t.getSplitBrainValidator()
+		if err != nil {
+			log.Printf("Error getting split brain validator: %v", err)
+		}
+		if err == nil && splitBrainValidator == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for %s to demote itself from the split brain", outrankedValidator)
+		}
+		time.Sleep(2 * time.Second)
+	}
+	if err := t.waitForValidatorRole(outrankedValidator, "passive", 30*time.Second); err != nil {
+		return fmt.Errorf("%s should be passive after the split brain: %w", outrankedValidator, err)
+	}
+
+	// only validator-2 ran its passive role, validator-1 stayed active throughout
+	records, err := t.getHookRecords()
+	if err != nil {
+		return err
+	}
+	demoted := false
+	for _, record := range records {
+		if record.Validator != outrankedValidator {
+			return fmt.Errorf("%s ran %s during the split brain, only %s may demote", record.Validator, record.Name, outrankedValidator)
+		}
+		demoted = demoted || record.Name == "passive-command"
+	}
+	if !demoted {
+		return fmt.Errorf("expected %s to run its passive command, got %v", outrankedValidator, records)
+	}
+	status, err := t.getValidatorStatus("validator-1")
+	if err != nil {
+		return err
+	}
+	if !status.Active {
+		return fmt.Errorf("validator-1 should have stayed active, got %s", status.Role)
+	}
+
+	log.Printf("✅ Scenario 7 passed: %s demoted itself from the split brain, validator-1 stayed active", outrankedValidator)
+	return nil
+}
+
 func (t *TestOrchestrator) runAllScenarios() error {
 	log.Println("Starting integration test scenarios...")
 
@@ -464,6 +875,9 @@ func (t *TestOrchestrator) runAllScenarios() error {
 		{"Scenario 2", t.runScenario2},
 		{"Scenario 3", t.runScenario3},
 		{"Scenario 4", t.runScenario4},
+		{"Scenario 5", t.runScenario5},
+		{"Scenario 6", t.runScenario6},
+		{"Scenario 7", t.runScenario7},
 	}
 
 	for _, scenario := range scenarios {
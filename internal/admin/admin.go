@@ -0,0 +1,113 @@
+// Package admin is a client of the agave validator's admin RPC, the JSON-RPC 2.0 server agave-validator subcommands
+// such as set-identity talk to over a unix socket in the ledger directory
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// SocketName is the name of the admin RPC socket agave-validator creates in its ledger directory
+const SocketName = "admin.rpc"
+
+// DefaultTimeout bounds a call when the client is created without one
+const DefaultTimeout = 10 * time.Second
+
+// SocketPath returns the admin RPC socket path of the validator using ledgerPath
+func SocketPath(ledgerPath string) string {
+	return filepath.Join(ledgerPath, SocketName)
+}
+
+// Error is an error the admin RPC answered a call with
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	return fmt.Sprintf("admin rpc error %d: %s", e.Code, e.Message)
+}
+
+// Client calls the admin RPC on a unix socket, dialing it for every call as agave-validator does
+type Client struct {
+	socketPath string
+	timeout    time.Duration
+	nextID     atomic.Uint64
+}
+
+// New returns a client of the admin RPC on socketPath whose calls time out after timeout, DefaultTimeout if zero
+func New(socketPath string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Client{socketPath: socketPath, timeout: timeout}
+}
+
+// SocketPath returns the socket the client calls
+func (c *Client) SocketPath() string {
+	return c.socketPath
+}
+
+// SetIdentity makes the validator switch to the identity in keypairFile, a path the validator process must be able
+// to read - with requireTower the validator refuses unless it has a saved tower for that identity, as
+// agave-validator set-identity --require-tower does
+func (c *Client) SetIdentity(ctx context.Context, keypairFile string, requireTower bool) error {
+	return c.call(ctx, "setIdentity", []any{keypairFile, requireTower})
+}
+
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      uint64 `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type response struct {
+	ID    uint64 `json:"id"`
+	Error *Error `json:"error"`
+}
+
+// call sends method with params, returning the error the admin rpc answered with if any
+func (c *Client) call(ctx context.Context, method string, params []any) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to admin rpc %s: %w", c.socketPath, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return fmt.Errorf("failed to set admin rpc deadline: %w", err)
+		}
+	}
+
+	req := request{JSONRPC: "2.0", ID: c.nextID.Add(1), Method: method, Params: params}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode admin rpc %s request: %w", method, err)
+	}
+	if _, err := conn.Write(append(body, '\n')); err != nil {
+		return fmt.Errorf("failed to send admin rpc %s request: %w", method, err)
+	}
+
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to read admin rpc %s response: %w", method, err)
+	}
+	if resp.ID != req.ID {
+		return fmt.Errorf("admin rpc %s response has id %d, expected %d", method, resp.ID, req.ID)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("admin rpc %s failed: %w", method, resp.Error)
+	}
+	return nil
+}
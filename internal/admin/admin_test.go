@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSocketPath(t *testing.T) {
+	assert.Equal(t, "/mnt/ledger/admin.rpc", SocketPath("/mnt/ledger"))
+}
+
+func TestClient_SetIdentity(t *testing.T) {
+	fake := testutil.NewFakeAdminRPC(t)
+	client := New(SocketPath(fake.LedgerPath), time.Second)
+
+	require.NoError(t, client.SetIdentity(context.Background(), "/keys/active.json", true))
+	require.NoError(t, client.SetIdentity(context.Background(), "/keys/passive.json", false))
+	assert.Equal(t, []testutil.AdminRPCCall{
+		{Method: "setIdentity", Params: []any{"/keys/active.json", true}},
+		{Method: "setIdentity", Params: []any{"/keys/passive.json", false}},
+	}, fake.Calls())
+}
+
+func TestClient_SetIdentity_Error(t *testing.T) {
+	fake := testutil.NewFakeAdminRPC(t)
+	fake.SetError(-32603, "Unable to set identity: tower not found")
+	client := New(fake.SocketPath, time.Second)
+
+	err := client.SetIdentity(context.Background(), "/keys/active.json", true)
+	var adminErr *Error
+	require.ErrorAs(t, err, &adminErr)
+	assert.Equal(t, -32603, adminErr.Code)
+	assert.Contains(t, err.Error(), "tower not found")
+}
+
+func TestClient_SetIdentity_NoSocket(t *testing.T) {
+	client := New(filepath.Join(t.TempDir(), SocketName), time.Second)
+
+	err := client.SetIdentity(context.Background(), "/keys/active.json", false)
+	assert.ErrorContains(t, err, "failed to connect to admin rpc")
+}
+
+func TestClient_SetIdentity_Timeout(t *testing.T) {
+	// the validator answers too late
+	fake := testutil.NewFakeAdminRPC(t)
+	fake.SetDelay(time.Second)
+	client := New(fake.SocketPath, 50*time.Millisecond)
+
+	startedAt := time.Now()
+	err := client.SetIdentity(context.Background(), "/keys/active.json", false)
+	assert.ErrorContains(t, err, "failed to read admin rpc setIdentity response")
+	assert.Less(t, time.Since(startedAt), 500*time.Millisecond)
+}
+
+func TestNew_DefaultTimeout(t *testing.T) {
+	client := New("/mnt/ledger/admin.rpc", 0)
+	assert.Equal(t, DefaultTimeout, client.timeout)
+	assert.Equal(t, "/mnt/ledger/admin.rpc", client.SocketPath())
+}
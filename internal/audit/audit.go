@@ -0,0 +1,183 @@
+// Package audit appends a record of every external command the agent runs to a tamper-evident, append-only file
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// hashPrefix marks an arg recorded as its sha256 rather than as is
+const hashPrefix = "sha256:"
+
+// secretFlagRegexp matches flags whose value follows them as the next arg, e.g. --token <token>
+var secretFlagRegexp = regexp.MustCompile(`(?i)^--?[\w-]*(token|secret|password|passwd|api[-_]?key|credential)[\w-]*$`)
+
+// Record is one command run, as appended to the audit log
+type Record struct {
+	// Timestamp is when the command was started
+	Timestamp time.Time `json:"ts"`
+	// FailoverID is the transition the command ran in, empty outside of one
+	FailoverID string `json:"failover_id,omitempty"`
+	// Name is what ran the command, e.g. active, pre-hook <name> or tower-sync
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	// Args are the args the command ran with, secret ones as sha256:<hex> of their value
+	Args []string `json:"args"`
+	// Dir is the working directory the command ran in
+	Dir string `json:"dir"`
+	// ExitCode is 0 on success and -1 if the command could not be started or was killed
+	ExitCode        int     `json:"exit_code"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	DryRun          bool    `json:"dry_run"`
+	TimedOut        bool    `json:"timed_out,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	// PrevHash is the sha256 of the previous line, empty for the first - editing or removing a line breaks the chain
+	PrevHash string `json:"prev_hash"`
+}
+
+// Log is an append-only audit log. Each record holds the hash of the one before it, so a record edited or removed
+// after the fact is detected by Read. Appends are serialized so it is safe for concurrent use.
+type Log struct {
+	mu       sync.Mutex
+	path     string
+	isSecret func(arg string) bool
+	file     *os.File
+	lastHash string
+}
+
+// Options are the options for opening an audit log
+type Options struct {
+	// Path is the audit log file
+	Path string
+	// IsSecret, if set, returns true for args to record hashed, e.g. those matching redact.patterns
+	IsSecret func(arg string) bool
+}
+
+// Open opens opts.Path for appending, creating it and its directory if needed and carrying on the hash chain of
+// the records already in it
+func Open(opts Options) (*Log, error) {
+	l := &Log{path: opts.Path, isSecret: opts.IsSecret}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create %s directory: %w", l.path, err)
+	}
+
+	raw, err := os.ReadFile(l.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read audit log %s: %w", l.path, err)
+	}
+	if lines := bytes.Split(bytes.TrimSpace(raw), []byte("\n")); len(lines[len(lines)-1]) > 0 {
+		l.lastHash = hash(lines[len(lines)-1])
+	}
+
+	l.file, err = os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log %s: %w", l.path, err)
+	}
+	return l, nil
+}
+
+// Path returns the audit log file
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Args returns args as recorded, each one that is secret as its sha256 - an arg is secret when IsSecret returns
+// true for it, it follows a flag like --token or it differs from its counterpart in logArgs, the args as
+// configured, i.e. it had a ${ENV_VAR} expanded into it
+func (l *Log) Args(args []string, logArgs []string) []string {
+	recorded := make([]string, len(args))
+	for i, arg := range args {
+		secret := (l.isSecret != nil && l.isSecret(arg)) ||
+			(i > 0 && secretFlagRegexp.MatchString(args[i-1])) ||
+			(logArgs != nil && (i >= len(logArgs) || logArgs[i] != arg))
+		recorded[i] = arg
+		if secret {
+			recorded[i] = HashArg(arg)
+		}
+	}
+	return recorded
+}
+
+// Append appends record to the audit log, chained to the record before it, and syncs it to disk
+func (l *Log) Append(record Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record.Args == nil {
+		record.Args = []string{}
+	}
+	record.PrevHash = l.lastHash
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to append to audit log %s: %w", l.path, err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log %s: %w", l.path, err)
+	}
+	l.lastHash = hash(line)
+	return nil
+}
+
+// Close closes the audit log
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// HashArg returns the value an arg is recorded as when it is secret
+func HashArg(arg string) string {
+	return hashPrefix + hash([]byte(arg))
+}
+
+// Read reads every record in the audit log at path, oldest first, returning them along with an error naming the
+// first line whose hash chain is broken - the records are returned in full either way
+func Read(path string) (records []Record, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var chainErr error
+	prevHash := ""
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return records, fmt.Errorf("line %d of %s is not an audit record: %w", lineNumber, path, err)
+		}
+		if record.PrevHash != prevHash && chainErr == nil {
+			chainErr = fmt.Errorf("line %d of %s does not follow the line before it - the audit log was modified", lineNumber, path)
+		}
+		prevHash = hash(line)
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return records, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return records, chainErr
+}
+
+// hash returns the hex sha256 of b
+func hash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
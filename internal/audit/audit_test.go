@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_AppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "audit.log")
+	auditLog, err := Open(Options{Path: path})
+	require.NoError(t, err)
+
+	startedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, auditLog.Append(Record{Timestamp: startedAt, FailoverID: "0a1b2c3d", Name: "active", Command: "systemctl", Args: []string{"restart", "validator"}}))
+	require.NoError(t, auditLog.Append(Record{Timestamp: startedAt, Name: "tower-sync", Command: "scp", ExitCode: 1, Error: "exit status 1"}))
+	require.NoError(t, auditLog.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// reopening carries on the chain
+	auditLog, err = Open(Options{Path: path})
+	require.NoError(t, err)
+	require.NoError(t, auditLog.Append(Record{Timestamp: startedAt, Name: "post-hook notify", Command: "notify", DryRun: true}))
+	require.NoError(t, auditLog.Close())
+
+	records, err := Read(path)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "0a1b2c3d", records[0].FailoverID)
+	assert.Empty(t, records[0].PrevHash)
+	assert.Equal(t, []string{}, records[1].Args)
+	assert.Equal(t, 1, records[1].ExitCode)
+	assert.True(t, records[2].DryRun)
+}
+
+func TestRead_DetectsModified(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := Open(Options{Path: path})
+	require.NoError(t, err)
+	for _, name := range []string{"pre-hook a", "active", "post-hook b"} {
+		require.NoError(t, auditLog.Append(Record{Name: name, Command: "true"}))
+	}
+	require.NoError(t, auditLog.Close())
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// an edited record
+	require.NoError(t, os.WriteFile(path, []byte(strings.Replace(string(raw), `"name":"active"`, `"name":"passive"`, 1)), 0o600))
+	records, err := Read(path)
+	assert.Len(t, records, 3)
+	assert.ErrorContains(t, err, "line 3")
+
+	// a removed record
+	lines := strings.SplitAfter(string(raw), "\n")
+	require.NoError(t, os.WriteFile(path, []byte(lines[0]+lines[2]), 0o600))
+	_, err = Read(path)
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestLog_Args(t *testing.T) {
+	auditLog := &Log{isSecret: func(arg string) bool { return strings.HasPrefix(arg, "--api-key=") }}
+
+	assert.Equal(t, []string{"restart", "validator"}, auditLog.Args([]string{"restart", "validator"}, nil))
+	assert.Equal(t,
+		[]string{HashArg("--api-key=abc"), "--token", HashArg("abc"), "-v"},
+		auditLog.Args([]string{"--api-key=abc", "--token", "abc", "-v"}, nil),
+	)
+	// args a ${ENV_VAR} was expanded into
+	assert.Equal(t,
+		[]string{"-H", HashArg("Authorization: Bearer abc")},
+		auditLog.Args([]string{"-H", "Authorization: Bearer abc"}, []string{"-H", "Authorization: Bearer ${TOKEN}"}),
+	)
+	assert.True(t, strings.HasPrefix(HashArg("abc"), "sha256:"))
+}
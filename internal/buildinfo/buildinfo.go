@@ -0,0 +1,61 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// unknown is reported for anything not set at build time and not recorded by the go toolchain
+const unknown = "unknown"
+
+// Set at build time with -ldflags "-X github.com/sol-strategies/solana-validator-ha/internal/buildinfo.Commit=..."
+var (
+	// Version is the release version, defaulting to cmd/version.txt
+	Version string
+	// Commit is the git commit the binary was built from
+	Commit string
+	// BuildDate is when the binary was built, in RFC3339
+	BuildDate string
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+// WithDefaultVersion sets Version to version unless it was set at build time and returns the result
+func WithDefaultVersion(version string) string {
+	if Version == "" {
+		Version = version
+	}
+	return Version
+}
+
+// Get returns the build info, falling back to the vcs revision the go toolchain records for the commit
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			if setting.Key == "vcs.revision" && info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+
+	for _, value := range []*string{&info.Version, &info.Commit, &info.BuildDate} {
+		if *value == "" {
+			*value = unknown
+		}
+	}
+
+	return info
+}
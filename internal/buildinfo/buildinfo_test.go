@@ -0,0 +1,48 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setBuildInfo sets the build time variables for the test, restoring them after
+func setBuildInfo(t *testing.T, version, commit, buildDate string) {
+	t.Helper()
+
+	previousVersion, previousCommit, previousBuildDate := Version, Commit, BuildDate
+	t.Cleanup(func() { Version, Commit, BuildDate = previousVersion, previousCommit, previousBuildDate })
+	Version, Commit, BuildDate = version, commit, buildDate
+}
+
+func TestGet(t *testing.T) {
+	setBuildInfo(t, "1.2.3", "abc123", "2025-01-01T00:00:00Z")
+
+	assert.Equal(t, Info{
+		Version:   "1.2.3",
+		Commit:    "abc123",
+		BuildDate: "2025-01-01T00:00:00Z",
+		GoVersion: runtime.Version(),
+	}, Get())
+}
+
+func TestGet_Unset(t *testing.T) {
+	setBuildInfo(t, "", "", "")
+
+	info := Get()
+	assert.Equal(t, unknown, info.Version)
+	assert.Equal(t, unknown, info.BuildDate)
+	// the vcs revision when the toolchain recorded one, unknown otherwise
+	assert.NotEmpty(t, info.Commit)
+}
+
+func TestWithDefaultVersion(t *testing.T) {
+	setBuildInfo(t, "", "", "")
+	assert.Equal(t, "0.1.7", WithDefaultVersion("0.1.7"))
+	assert.Equal(t, "0.1.7", Get().Version)
+
+	// set at build time - the default is ignored
+	setBuildInfo(t, "1.2.3", "", "")
+	assert.Equal(t, "1.2.3", WithDefaultVersion("0.1.7"))
+}
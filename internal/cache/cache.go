@@ -1,8 +1,11 @@
 package cache
 
 import (
+	"reflect"
 	"sync"
 	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
 )
 
 // State represents the current state of the HA manager
@@ -11,18 +14,104 @@ type State struct {
 	ValidatorName string
 	Hostname      string
 	PublicIP      string
-	Role          string // "active", "passive", "unknown"
-	Status        string // "healthy", "unhealthy", "unknown"
+	Role          constants.Role
+	Status        constants.Status
 
 	// Peer information
 	PeerCount    int
 	SelfInGossip bool
+	// Peers is the gossip visibility of every configured peer other than ourselves by name - it is replaced
+	// rather than modified in place as states share it
+	Peers map[string]PeerVisibility
+	// LeaderlessSamples is the number of consecutive gossip samples without an active peer
+	LeaderlessSamples int
+	// ClusterRPCErrors is the number of consecutive gossip refreshes that failed to reach the cluster rpc
+	ClusterRPCErrors int
+	// SplitBrainPeers are the names of us and the peers seen with the active identity when more than one is,
+	// empty otherwise
+	SplitBrainPeers []string
+	// VoteAccountMismatch is true while validator.vote_account's node identity is not the active identity
+	VoteAccountMismatch bool
+	// Paused is true while failover is paused for maintenance and role transitions are skipped
+	Paused bool
+	// PausedUntil is when a maintenance pause resumes on its own, zero when not paused
+	PausedUntil time.Time
 
 	// Failover status
-	FailoverStatus string // "idle", "becoming_active", "becoming_passive"
+	FailoverStatus constants.FailoverStatus
+	// FailoverCount is the number of role transitions completed since startup by direction and reason - it is
+	// replaced rather than modified in place as states share it
+	FailoverCount map[FailoverKey]uint64
+	// LastFailoverAt is when the last role transition completed, zero if none has since startup
+	LastFailoverAt time.Time
+
+	// Polling
+	// EffectivePollInterval is the poll interval in use, longer than configured while adapting to rpc rate limits
+	EffectivePollInterval time.Duration
+	// RPCRateLimitedTotal is the number of rate limited cluster rpc responses since startup
+	RPCRateLimitedTotal uint64
+	// ClusterRPCEndpoint is the host of the cluster rpc endpoint requests are sent to first
+	ClusterRPCEndpoint string
+
+	// Timers are the countdowns currently governing agent behaviour
+	Timers []Timer
 
 	// Timestamps
-	LastUpdated time.Time
+	// LastObserved is the last time the state was updated, changed or not - useful for liveness
+	LastObserved time.Time
+	// LastChanged is the last time an update actually changed the state
+	LastChanged time.Time
+}
+
+// PeerVisibility is what this node sees of a configured peer in gossip
+type PeerVisibility struct {
+	IP       string
+	InGossip bool
+	// LastSeenAt is when the peer was last in gossip since startup, zero if never
+	LastSeenAt time.Time
+	// Heartbeat is the peer's latest heartbeat, nil when heartbeats are not enabled
+	Heartbeat *PeerHeartbeat
+}
+
+// PeerHeartbeat is what a configured peer's agent last reported on its heartbeat
+type PeerHeartbeat struct {
+	Reachable bool
+	Role      constants.Role
+	Identity  string
+	Healthy   bool
+	Slot      uint64
+	// Error is why the peer's agent was unreachable
+	Error     string
+	CheckedAt time.Time
+}
+
+// FailoverKey identifies a kind of role transition - the role transitioned to and why
+type FailoverKey struct {
+	Direction constants.Role
+	Reason    string
+}
+
+// WithFailover returns a copy of the state with a completed transition to direction for reason counted
+func (s State) WithFailover(direction constants.Role, reason string, at time.Time) State {
+	failoverCount := make(map[FailoverKey]uint64, len(s.FailoverCount)+1)
+	for key, count := range s.FailoverCount {
+		failoverCount[key] = count
+	}
+	failoverCount[FailoverKey{Direction: direction, Reason: reason}]++
+
+	s.FailoverCount = failoverCount
+	s.LastFailoverAt = at
+	return s
+}
+
+// Timer is a countdown governing agent behaviour, such as a repeat interval or a window before relaxing
+type Timer struct {
+	Name    string
+	Purpose string
+	// ExpiresAt is zero when the timer is not running
+	ExpiresAt time.Time
+	// Remaining is how long until ExpiresAt when the state was built, never negative
+	Remaining time.Duration
 }
 
 // Cache provides thread-safe access to the HA manager state
@@ -36,13 +125,23 @@ func New() *Cache {
 	return &Cache{}
 }
 
-// UpdateState updates the cached state
-func (c *Cache) UpdateState(state State) {
+// UpdateState updates the cached state and returns true if anything other than the timestamps changed,
+// consumers that persist or emit the state should only act when it did
+func (c *Cache) UpdateState(state State) (changed bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	state.LastUpdated = time.Now()
+	now := time.Now()
+	changed = !c.state.equals(state)
+
+	state.LastObserved = now
+	state.LastChanged = c.state.LastChanged
+	if changed {
+		state.LastChanged = now
+	}
 	c.state = state
+
+	return changed
 }
 
 // GetState returns a copy of the current state
@@ -52,3 +151,24 @@ func (c *Cache) GetState() State {
 
 	return c.state
 }
+
+// equals returns true if the states are equal ignoring timestamps and timers counting down
+func (s State) equals(other State) bool {
+	s.LastObserved, other.LastObserved = time.Time{}, time.Time{}
+	s.LastChanged, other.LastChanged = time.Time{}, time.Time{}
+	s.Timers, other.Timers = withoutRemaining(s.Timers), withoutRemaining(other.Timers)
+	return reflect.DeepEqual(s, other)
+}
+
+// withoutRemaining returns a copy of timers with the remaining durations cleared
+func withoutRemaining(timers []Timer) []Timer {
+	if timers == nil {
+		return nil
+	}
+	cleared := make([]Timer, len(timers))
+	for i, timer := range timers {
+		timer.Remaining = 0
+		cleared[i] = timer
+	}
+	return cleared
+}
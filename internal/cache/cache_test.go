@@ -7,6 +7,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
 )
 
 func TestCache_New(t *testing.T) {
@@ -45,10 +47,10 @@ func TestCache_UpdateState(t *testing.T) {
 	assert.Equal(t, state.PeerCount, result.PeerCount)
 	assert.Equal(t, state.SelfInGossip, result.SelfInGossip)
 	assert.Equal(t, state.FailoverStatus, result.FailoverStatus)
-	assert.False(t, result.LastUpdated.IsZero(), "LastUpdated should be set")
+	assert.False(t, result.LastObserved.IsZero(), "LastObserved should be set")
 
-	// Verify LastUpdated is recent (within last second)
-	assert.True(t, time.Since(result.LastUpdated) < time.Second, "LastUpdated should be recent")
+	// Verify LastObserved is recent (within last second)
+	assert.True(t, time.Since(result.LastObserved) < time.Second, "LastObserved should be recent")
 }
 
 func TestCache_UpdateStateMultipleTimes(t *testing.T) {
@@ -66,7 +68,7 @@ func TestCache_UpdateStateMultipleTimes(t *testing.T) {
 	result1 := cache.GetState()
 	assert.Equal(t, "validator-1", result1.ValidatorName)
 	assert.Equal(t, 5, result1.PeerCount)
-	assert.Equal(t, "active", result1.Role)
+	assert.Equal(t, constants.RoleActive, result1.Role)
 
 	// Second update
 	state2 := State{
@@ -81,11 +83,11 @@ func TestCache_UpdateStateMultipleTimes(t *testing.T) {
 	result2 := cache.GetState()
 	assert.Equal(t, "validator-2", result2.ValidatorName)
 	assert.Equal(t, 10, result2.PeerCount)
-	assert.Equal(t, "passive", result2.Role)
-	assert.Equal(t, "healthy", result2.Status)
+	assert.Equal(t, constants.RolePassive, result2.Role)
+	assert.Equal(t, constants.StatusHealthy, result2.Status)
 
-	// Verify LastUpdated is more recent for second update
-	assert.True(t, result2.LastUpdated.After(result1.LastUpdated), "Second update should have more recent timestamp")
+	// Verify LastObserved is more recent for second update
+	assert.True(t, result2.LastObserved.After(result1.LastObserved), "Second update should have more recent timestamp")
 }
 
 func TestCache_ConcurrentAccess(t *testing.T) {
@@ -127,7 +129,7 @@ func TestCache_ConcurrentAccess(t *testing.T) {
 	// Verify we can still read after concurrent access
 	state := cache.GetState()
 	assert.NotEqual(t, "", state.ValidatorName, "expected state to be readable after concurrent access")
-	assert.False(t, state.LastUpdated.IsZero(), "LastUpdated should be set")
+	assert.False(t, state.LastObserved.IsZero(), "LastObserved should be set")
 }
 
 func TestCache_StateIsolation(t *testing.T) {
@@ -154,7 +156,7 @@ func TestCache_StateIsolation(t *testing.T) {
 	originalRetrieved := cache.GetState()
 	assert.Equal(t, "original", originalRetrieved.ValidatorName, "expected original state to be unchanged")
 	assert.Equal(t, 5, originalRetrieved.PeerCount, "expected original peer count to be unchanged")
-	assert.Equal(t, "active", originalRetrieved.Role, "expected original role to be unchanged")
+	assert.Equal(t, constants.RoleActive, originalRetrieved.Role, "expected original role to be unchanged")
 }
 
 func TestCache_ZeroValueState(t *testing.T) {
@@ -168,12 +170,12 @@ func TestCache_ZeroValueState(t *testing.T) {
 	assert.Equal(t, "", result.ValidatorName)
 	assert.Equal(t, "", result.Hostname)
 	assert.Equal(t, "", result.PublicIP)
-	assert.Equal(t, "", result.Role)
-	assert.Equal(t, "", result.Status)
+	assert.Empty(t, result.Role)
+	assert.Empty(t, result.Status)
 	assert.Equal(t, 0, result.PeerCount)
 	assert.False(t, result.SelfInGossip)
-	assert.Equal(t, "", result.FailoverStatus)
-	assert.False(t, result.LastUpdated.IsZero(), "LastUpdated should still be set even for zero state")
+	assert.Empty(t, result.FailoverStatus)
+	assert.False(t, result.LastObserved.IsZero(), "LastObserved should still be set even for zero state")
 }
 
 func TestCache_EdgeCases(t *testing.T) {
@@ -211,7 +213,7 @@ func TestCache_EdgeCases(t *testing.T) {
 func TestCache_AllRoles(t *testing.T) {
 	cache := New()
 
-	roles := []string{"active", "passive", "unknown"}
+	roles := constants.Roles()
 
 	for _, role := range roles {
 		state := State{
@@ -229,7 +231,7 @@ func TestCache_AllRoles(t *testing.T) {
 func TestCache_AllStatuses(t *testing.T) {
 	cache := New()
 
-	statuses := []string{"healthy", "unhealthy", "unknown"}
+	statuses := constants.Statuses()
 
 	for _, status := range statuses {
 		state := State{
@@ -247,7 +249,7 @@ func TestCache_AllStatuses(t *testing.T) {
 func TestCache_AllFailoverStatuses(t *testing.T) {
 	cache := New()
 
-	failoverStatuses := []string{"idle", "becoming_active", "becoming_passive"}
+	failoverStatuses := constants.FailoverStatuses()
 
 	for _, failoverStatus := range failoverStatuses {
 		state := State{
@@ -301,7 +303,7 @@ func TestCache_ConcurrentReadWrite(t *testing.T) {
 	// Verify final state is consistent
 	finalState := cache.GetState()
 	assert.NotNil(t, finalState)
-	assert.False(t, finalState.LastUpdated.IsZero())
+	assert.False(t, finalState.LastObserved.IsZero())
 }
 
 func TestCache_TimestampAccuracy(t *testing.T) {
@@ -320,11 +322,11 @@ func TestCache_TimestampAccuracy(t *testing.T) {
 
 	result := cache.GetState()
 
-	// Verify LastUpdated is between beforeUpdate and afterUpdate
-	assert.True(t, result.LastUpdated.After(beforeUpdate) || result.LastUpdated.Equal(beforeUpdate),
-		"LastUpdated should be after or equal to beforeUpdate")
-	assert.True(t, result.LastUpdated.Before(afterUpdate) || result.LastUpdated.Equal(afterUpdate),
-		"LastUpdated should be before or equal to afterUpdate")
+	// Verify LastObserved is between beforeUpdate and afterUpdate
+	assert.True(t, result.LastObserved.After(beforeUpdate) || result.LastObserved.Equal(beforeUpdate),
+		"LastObserved should be after or equal to beforeUpdate")
+	assert.True(t, result.LastObserved.Before(afterUpdate) || result.LastObserved.Equal(afterUpdate),
+		"LastObserved should be before or equal to afterUpdate")
 }
 
 func TestCache_MultipleInstances(t *testing.T) {
@@ -355,5 +357,92 @@ func TestCache_MultipleInstances(t *testing.T) {
 	assert.Equal(t, 10, result2.PeerCount)
 
 	// Verify they have different timestamps
-	assert.NotEqual(t, result1.LastUpdated, result2.LastUpdated)
+	assert.NotEqual(t, result1.LastObserved, result2.LastObserved)
+}
+
+func TestCache_UpdateStateChangeDetection(t *testing.T) {
+	cache := New()
+
+	state := State{
+		ValidatorName:  "test-validator",
+		Role:           constants.RoleActive,
+		Status:         constants.StatusHealthy,
+		FailoverStatus: constants.FailoverStatusIdle,
+	}
+
+	// first update is a change from the zero state
+	assert.True(t, cache.UpdateState(state))
+	first := cache.GetState()
+	assert.False(t, first.LastChanged.IsZero())
+	assert.Equal(t, first.LastObserved, first.LastChanged)
+
+	time.Sleep(time.Millisecond)
+
+	// identical update is not a change, but is still observed
+	assert.False(t, cache.UpdateState(state))
+	second := cache.GetState()
+	assert.True(t, second.LastObserved.After(first.LastObserved), "LastObserved should refresh on every update")
+	assert.Equal(t, first.LastChanged, second.LastChanged, "LastChanged should not move without a change")
+
+	// timestamps passed in by the caller are ignored for change detection
+	stale := second
+	stale.LastObserved = time.Time{}
+	stale.LastChanged = time.Time{}
+	assert.False(t, cache.UpdateState(stale))
+	assert.Equal(t, first.LastChanged, cache.GetState().LastChanged)
+
+	time.Sleep(time.Millisecond)
+
+	// a real change moves LastChanged
+	state.FailoverStatus = constants.FailoverStatusBecomingPassive
+	assert.True(t, cache.UpdateState(state))
+	third := cache.GetState()
+	assert.True(t, third.LastChanged.After(second.LastChanged))
+	assert.Equal(t, third.LastObserved, third.LastChanged)
+}
+
+func TestCache_UpdateStateZeroStateIsNotAChange(t *testing.T) {
+	cache := New()
+
+	assert.False(t, cache.UpdateState(State{}))
+	result := cache.GetState()
+	assert.False(t, result.LastObserved.IsZero())
+	assert.True(t, result.LastChanged.IsZero())
+}
+
+func TestCache_UpdateStateTimersCountingDownIsNotAChange(t *testing.T) {
+	cache := New()
+
+	expiresAt := time.Now().Add(time.Minute)
+	state := State{
+		ValidatorName: "test-validator",
+		Timers:        []Timer{{Name: "test", Purpose: "testing", ExpiresAt: expiresAt, Remaining: time.Minute}},
+	}
+	assert.True(t, cache.UpdateState(state))
+
+	// only the remaining time moved
+	state.Timers = []Timer{{Name: "test", Purpose: "testing", ExpiresAt: expiresAt, Remaining: 30 * time.Second}}
+	assert.False(t, cache.UpdateState(state))
+	assert.Equal(t, 30*time.Second, cache.GetState().Timers[0].Remaining)
+
+	// a timer restarting is a change
+	state.Timers = []Timer{{Name: "test", Purpose: "testing", ExpiresAt: expiresAt.Add(time.Minute), Remaining: 2 * time.Minute}}
+	assert.True(t, cache.UpdateState(state))
+}
+
+func TestState_WithFailover(t *testing.T) {
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := State{}.WithFailover(constants.RoleActive, "no_active_peer", at)
+	second := first.WithFailover(constants.RoleActive, "no_active_peer", at.Add(time.Minute))
+
+	assert.Equal(t, map[FailoverKey]uint64{{Direction: constants.RoleActive, Reason: "no_active_peer"}: 1}, first.FailoverCount)
+	assert.Equal(t, at, first.LastFailoverAt)
+	assert.Equal(t, map[FailoverKey]uint64{{Direction: constants.RoleActive, Reason: "no_active_peer"}: 2}, second.FailoverCount)
+	assert.Equal(t, at.Add(time.Minute), second.LastFailoverAt)
+
+	// counting a failover is a change
+	cache := New()
+	cache.UpdateState(first)
+	assert.True(t, cache.UpdateState(second))
+	assert.False(t, cache.UpdateState(second))
 }
@@ -0,0 +1,117 @@
+package clock
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// start is the process start, monotonic readings are offsets from it
+var start = time.Now()
+
+// Instant is a point in time as a UTC wall clock reading, for display and serialization only, and a monotonic
+// reading, for measuring durations. Wall clock steps such as NTP corrections move the former but never the
+// latter, so freshness and elapsed time must only be measured with the Sub, Add, Before and After methods.
+type Instant struct {
+	wall time.Time
+	mono time.Duration
+}
+
+// Time returns the UTC wall clock reading for display - never compare or subtract it
+func (i Instant) Time() time.Time {
+	return i.wall
+}
+
+// IsZero returns true if the instant was never set
+func (i Instant) IsZero() bool {
+	return i.wall.IsZero() && i.mono == 0
+}
+
+// Sub returns the monotonic duration i-u
+func (i Instant) Sub(u Instant) time.Duration {
+	return i.mono - u.mono
+}
+
+// Add returns the instant d after i
+func (i Instant) Add(d time.Duration) Instant {
+	return Instant{wall: i.wall.Add(d), mono: i.mono + d}
+}
+
+// Before returns true if i is monotonically before u
+func (i Instant) Before(u Instant) bool {
+	return i.mono < u.mono
+}
+
+// After returns true if i is monotonically after u
+func (i Instant) After(u Instant) bool {
+	return i.mono > u.mono
+}
+
+// String returns the wall clock reading in RFC3339
+func (i Instant) String() string {
+	return i.wall.Format(time.RFC3339)
+}
+
+// MarshalJSON serializes the wall clock reading as time.Time does
+func (i Instant) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.wall)
+}
+
+// UnmarshalJSON restores only the wall clock reading - a monotonic reading is meaningless outside the process
+// that took it, so an unmarshaled instant is for display only
+func (i *Instant) UnmarshalJSON(data []byte) error {
+	i.mono = 0
+	return json.Unmarshal(data, &i.wall)
+}
+
+// Clock reads the current instant
+type Clock interface {
+	Now() Instant
+}
+
+// systemClock reads the process clocks
+type systemClock struct{}
+
+// Now implements Clock
+func (systemClock) Now() Instant {
+	now := time.Now()
+	return Instant{wall: now.UTC(), mono: now.Sub(start)}
+}
+
+// System is the clock to use outside of tests
+var System Clock = systemClock{}
+
+// Fake is a clock for tests whose wall and monotonic readings are moved by hand
+type Fake struct {
+	mu  sync.Mutex
+	now Instant
+}
+
+// NewFake returns a fake clock reading wall, with a monotonic reading well clear of the zero instant
+func NewFake(wall time.Time) *Fake {
+	return &Fake{now: Instant{wall: wall.UTC(), mono: time.Hour}}
+}
+
+// Now implements Clock
+func (f *Fake) Now() Instant {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+// Advance moves both readings forward by d, as time passing does
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+}
+
+// Step moves only the wall clock reading by d, as an NTP correction or manual clock change does
+func (f *Fake) Step(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now.wall = f.now.wall.Add(d)
+}
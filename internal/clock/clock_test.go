@@ -0,0 +1,66 @@
+package clock
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystem_Now(t *testing.T) {
+	first := System.Now()
+	time.Sleep(time.Millisecond)
+	second := System.Now()
+
+	assert.False(t, first.IsZero())
+	assert.True(t, second.After(first))
+	assert.GreaterOrEqual(t, second.Sub(first), time.Millisecond)
+	assert.Equal(t, time.UTC, second.Time().Location())
+}
+
+func TestFake_AdvanceAndStep(t *testing.T) {
+	wall := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := NewFake(wall)
+	first := fake.Now()
+	assert.Equal(t, wall, first.Time())
+
+	fake.Advance(10 * time.Second)
+	assert.Equal(t, 10*time.Second, fake.Now().Sub(first))
+	assert.Equal(t, wall.Add(10*time.Second), fake.Now().Time())
+
+	// a 30s backwards step moves the display time but not elapsed time
+	fake.Step(-30 * time.Second)
+	stepped := fake.Now()
+	assert.Equal(t, wall.Add(-20*time.Second), stepped.Time())
+	assert.Equal(t, 10*time.Second, stepped.Sub(first))
+	assert.True(t, stepped.After(first))
+	assert.True(t, stepped.Time().Before(first.Time()), "the wall clock alone would say time went backwards")
+}
+
+func TestInstant_Zero(t *testing.T) {
+	var zero Instant
+	assert.True(t, zero.IsZero())
+	assert.False(t, NewFake(time.Now()).Now().IsZero())
+}
+
+func TestInstant_MarshalJSON(t *testing.T) {
+	wall := time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC)
+	data, err := json.Marshal(NewFake(wall).Now())
+	require.NoError(t, err)
+
+	expected, err := json.Marshal(wall)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(data))
+
+	var restored Instant
+	require.NoError(t, json.Unmarshal(data, &restored))
+	assert.Equal(t, wall, restored.Time())
+
+	// the zero instant round trips
+	data, err = json.Marshal(Instant{})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &restored))
+	assert.True(t, restored.IsZero())
+}
@@ -2,13 +2,21 @@ package command
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/audit"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
 )
 
 var (
@@ -22,50 +30,151 @@ type RunOptions struct {
 	Command      string
 	Args         []string
 	Env          map[string]string
+	InheritEnv   bool // start from the agent's own environment before applying Env
 	DryRun       bool
 	StreamOutput bool
+	// Output, if set, also receives every stdout and stderr line of a streamed command, e.g. to capture it
+	Output       io.Writer
 	LoggerPrefix string
 	LoggerArgs   []any
+	// LogCommand and LogArgs, if set, are logged in place of Command and Args, e.g. so expanded secrets never are
+	LogCommand string
+	LogArgs    []string
+	// Timeout, if set, kills the command and every process it started once it has run this long
+	Timeout time.Duration
+	// FailoverID, if set, is logged with every line as failover_id so a transition's commands can be stitched together
+	FailoverID string
+	// Audit, if set, has a record of the command appended once it is done however it went, dry runs included
+	Audit *audit.Log
 }
 
+// timeoutWaitDelay bounds how long output is still read after a timed out command is killed
+const timeoutWaitDelay = time.Second
+
 // Run runs a command with the given options.
-// Note: This function never times out - commands can take an indeterminate amount of time
+// Note: Without opts.Timeout this function never times out - commands can take an indeterminate amount of time
 // (e.g., failover commands that may need to wait for services to start/stop).
-func Run(opts RunOptions) error {
-	logger := log.WithPrefix(fmt.Sprintf("[%s command %s]", opts.LoggerPrefix, opts.Name))
+func Run(opts RunOptions) (err error) {
+	logger := loglevel.WithPrefix(loglevel.Command, fmt.Sprintf("[%s command %s]", opts.LoggerPrefix, opts.Name))
+	if opts.FailoverID != "" {
+		logger = logger.With("failover_id", opts.FailoverID)
+	}
 	envString := ""
 	for key, value := range opts.Env {
 		envString += fmt.Sprintf("%s=%s ", key, value)
 	}
-	runMsg := fmt.Sprintf("%s %s %s", envString, opts.Command, strings.Join(opts.Args, " "))
+	logCommand, logArgs := opts.Command, opts.Args
+	if opts.LogCommand != "" {
+		logCommand, logArgs = opts.LogCommand, opts.LogArgs
+	}
+	runMsg := fmt.Sprintf("%s %s %s", envString, logCommand, strings.Join(logArgs, " "))
 	runMsg = strings.TrimSpace(runMsg)
 
 	logger.Info(runMsg)
 
+	startedAt := time.Now()
+	timedOut := false
+	if opts.Audit != nil {
+		defer func() {
+			if auditErr := opts.Audit.Append(auditRecord(opts, startedAt, timedOut, err)); auditErr != nil {
+				logger.Warn("failed to append command to the audit log", "path", opts.Audit.Path(), "error", auditErr)
+			}
+		}()
+	}
+
 	if opts.DryRun {
 		logger.Debug("command completed successfully - dry run")
 		return nil
 	}
 
-	cmd := exec.Command(opts.Command, opts.Args...)
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, opts.Command, opts.Args...)
+	if opts.Timeout > 0 {
+		// run in its own process group so children holding the output pipes (e.g. a shell's curl) are killed too
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Cancel = func() error {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+		cmd.WaitDelay = timeoutWaitDelay
+	}
 
 	// Set environment variables if provided
 	if len(opts.Env) > 0 {
 		cmd.Env = make([]string, 0, len(opts.Env))
+		if opts.InheritEnv {
+			cmd.Env = append(cmd.Env, os.Environ()...)
+		}
 		for key, value := range opts.Env {
 			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", strings.TrimSpace(key), strings.TrimSpace(value)))
 		}
 	}
 
 	if opts.StreamOutput {
-		return runWithStreaming(cmd, logger)
+		err = runWithStreaming(cmd, logger, opts.Output)
+	} else {
+		err = runWithoutStreaming(cmd, logger)
 	}
 
-	return runWithoutStreaming(cmd, logger)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		timedOut = true
+		elapsed := time.Since(startedAt).Round(time.Millisecond)
+		logger.Error("command timed out and was killed", "timeout", opts.Timeout, "elapsed", elapsed)
+		return fmt.Errorf("timed out after %s (timeout %s): %w", elapsed, opts.Timeout, err)
+	}
+	return err
+}
+
+// ExitCode returns the exit code of a command run with Run, 0 if it succeeded and -1 if it never exited
+// e.g. because it could not be started
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
 }
 
-// runWithStreaming executes the command and streams stdout/stderr in real-time
-func runWithStreaming(cmd *exec.Cmd, logger *log.Logger) error {
+// AuditDryRun appends opts' command to opts.Audit, if set, as a dry run without running or logging it - for
+// callers skipping Run altogether on a dry run
+func AuditDryRun(opts RunOptions) error {
+	if opts.Audit == nil {
+		return nil
+	}
+	opts.DryRun = true
+	return opts.Audit.Append(auditRecord(opts, time.Now(), false, nil))
+}
+
+// auditRecord returns the audit record of a command started at startedAt that returned err
+func auditRecord(opts RunOptions, startedAt time.Time, timedOut bool, err error) audit.Record {
+	dir, _ := os.Getwd()
+	record := audit.Record{
+		Timestamp:       startedAt,
+		FailoverID:      opts.FailoverID,
+		Name:            opts.Name,
+		Command:         opts.Command,
+		Args:            opts.Audit.Args(opts.Args, opts.LogArgs),
+		Dir:             dir,
+		ExitCode:        ExitCode(err),
+		DurationSeconds: time.Since(startedAt).Seconds(),
+		DryRun:          opts.DryRun,
+		TimedOut:        timedOut,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	return record
+}
+
+// runWithStreaming executes the command and streams stdout/stderr in real-time, copying each line to output if set
+func runWithStreaming(cmd *exec.Cmd, logger *log.Logger, output io.Writer) error {
 	// Capture stdout and stderr
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -85,21 +194,25 @@ func runWithStreaming(cmd *exec.Cmd, logger *log.Logger) error {
 		return err
 	}
 
-	// Stream stdout
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		for scanner.Scan() {
-			logger.Info(styledStreamOutputString("stdout", scanner.Text()))
-		}
-	}()
-
-	// Stream stderr
-	go func() {
-		scanner := bufio.NewScanner(stderr)
+	// Stream stdout and stderr - both must be read to the end before waiting as wait closes the pipes
+	var outputMu sync.Mutex
+	var wg sync.WaitGroup
+	stream := func(name string, pipe io.Reader) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(pipe)
 		for scanner.Scan() {
-			logger.Info(styledStreamOutputString("stderr", scanner.Text()))
+			logger.Info(styledStreamOutputString(name, scanner.Text()))
+			if output != nil {
+				outputMu.Lock()
+				fmt.Fprintln(output, scanner.Text())
+				outputMu.Unlock()
+			}
 		}
-	}()
+	}
+	wg.Add(2)
+	go stream("stdout", stdout)
+	go stream("stderr", stderr)
+	wg.Wait()
 
 	// Wait for command to complete
 	err = cmd.Wait()
@@ -1,12 +1,14 @@
 package command
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/sol-strategies/solana-validator-ha/internal/audit"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -229,6 +231,32 @@ func TestRun_CommandWithExitCodes(t *testing.T) {
 			} else {
 				assert.Error(t, err, "expected command to fail with non-zero exit code")
 			}
+			assert.Equal(t, exitCode, ExitCode(err))
+		})
+	}
+}
+
+func TestExitCode_NotStarted(t *testing.T) {
+	err := Run(RunOptions{Command: "/nonexistent/command"})
+	assert.Error(t, err)
+	assert.Equal(t, -1, ExitCode(err))
+}
+
+func TestRun_Timeout(t *testing.T) {
+	for _, streamOutput := range []bool{true, false} {
+		t.Run(fmt.Sprintf("stream_output=%t", streamOutput), func(t *testing.T) {
+			// exits within the timeout
+			err := Run(RunOptions{Command: "sh", Args: []string{"-c", "sleep 0.1"}, StreamOutput: streamOutput, Timeout: 5 * time.Second})
+			assert.NoError(t, err)
+
+			// killed with the sleep it started on expiry
+			startedAt := time.Now()
+			err = Run(RunOptions{Command: "sh", Args: []string{"-c", "sleep 30"}, StreamOutput: streamOutput, Timeout: 200 * time.Millisecond})
+			assert.Less(t, time.Since(startedAt), 5*time.Second)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "timed out after")
+			assert.Contains(t, err.Error(), "(timeout 200ms)")
+			assert.Equal(t, -1, ExitCode(err))
 		})
 	}
 }
@@ -474,6 +502,24 @@ echo "This is stdout line 3"
 	assert.NoError(t, err, "expected streaming command to succeed")
 }
 
+func TestRun_WithStreamingOutput(t *testing.T) {
+	scriptPath := createTestScript(t, `echo "stdout line"
+echo "stderr line" >&2
+exit 3`, 0)
+	defer os.Remove(scriptPath)
+
+	var output bytes.Buffer
+	err := Run(RunOptions{
+		Command:      scriptPath,
+		StreamOutput: true,
+		Output:       &output,
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 3, ExitCode(err))
+	assert.Contains(t, output.String(), "stdout line\n")
+	assert.Contains(t, output.String(), "stderr line\n")
+}
+
 func TestRun_WithStreamingAndFailure(t *testing.T) {
 	// Create a test script that outputs to both stdout and stderr then fails
 	scriptContent := `#!/bin/sh
@@ -625,3 +671,36 @@ echo "PATH: $PATH"
 	err := Run(opts)
 	assert.NoError(t, err, "expected command with empty env vars to succeed")
 }
+
+func TestRun_Audit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := audit.Open(audit.Options{Path: path})
+	require.NoError(t, err)
+	defer auditLog.Close()
+
+	require.NoError(t, Run(RunOptions{Name: "active", Command: "true", FailoverID: "0a1b2c3d", Audit: auditLog}))
+	require.Error(t, Run(RunOptions{Name: "pre-hook fail", Command: "sh", Args: []string{"-c", "exit 3"}, Audit: auditLog}))
+	require.Error(t, Run(RunOptions{Name: "pre-hook slow", Command: "sh", Args: []string{"-c", "sleep 30"}, Timeout: 100 * time.Millisecond, Audit: auditLog}))
+	require.NoError(t, Run(RunOptions{Name: "post-hook dry", Command: "nonexistent-command", DryRun: true, Audit: auditLog}))
+	require.NoError(t, AuditDryRun(RunOptions{Name: "passive", Command: "systemctl", Audit: auditLog}))
+
+	records, err := audit.Read(path)
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+
+	assert.Equal(t, "0a1b2c3d", records[0].FailoverID)
+	assert.Equal(t, 0, records[0].ExitCode)
+	wd, _ := os.Getwd()
+	assert.Equal(t, wd, records[0].Dir)
+
+	assert.Equal(t, 3, records[1].ExitCode)
+	assert.Equal(t, []string{"-c", "exit 3"}, records[1].Args)
+	assert.NotEmpty(t, records[1].Error)
+
+	assert.True(t, records[2].TimedOut)
+	assert.Equal(t, -1, records[2].ExitCode)
+
+	assert.True(t, records[3].DryRun)
+	assert.True(t, records[4].DryRun)
+	assert.Equal(t, "passive", records[4].Name)
+}
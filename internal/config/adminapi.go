@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// DefaultAdminAPIBindAddress is the address the admin api listens on when no bind_address is set - loopback only,
+// as it can promote and demote the validator
+const DefaultAdminAPIBindAddress = "127.0.0.1"
+
+// AdminAPI represents the authenticated admin api external tooling orchestrates planned failovers through
+type AdminAPI struct {
+	// Enabled serves the admin api on port
+	Enabled bool `koanf:"enabled"`
+	Port    int  `koanf:"port"`
+	// BindAddress is the IP address the admin api listens on, DefaultAdminAPIBindAddress if unset
+	BindAddress string `koanf:"bind_address"`
+	// Auth is required when enabled, every endpoint needs its bearer token
+	Auth *Auth `koanf:"auth"`
+	// TLS, if set, serves the admin api over https, the certificate is reloaded on SIGHUP
+	TLS *TLS `koanf:"tls"`
+}
+
+// ListenAddress returns the host:port the admin api listens on
+func (a *AdminAPI) ListenAddress() string {
+	return net.JoinHostPort(a.BindAddress, strconv.Itoa(a.Port))
+}
+
+// Validate validates the admin api configuration
+func (a *AdminAPI) Validate() error {
+	if !a.Enabled {
+		return nil
+	}
+
+	// admin_api.port must be positive and non-zero
+	if a.Port <= 0 {
+		return fmt.Errorf("admin_api.port must be positive and non-zero")
+	}
+
+	// admin_api.bind_address must be an IP address
+	if err := validateBindAddress("admin_api.bind_address", a.BindAddress); err != nil {
+		return err
+	}
+
+	// admin_api.auth must define a bearer token - the admin api is never served unauthenticated
+	if a.Auth == nil {
+		return fmt.Errorf("admin_api.auth must be defined when admin_api.enabled is true")
+	}
+	if err := a.Auth.Validate("admin_api.auth"); err != nil {
+		return err
+	}
+
+	// admin_api.tls must define a loadable certificate and key
+	if a.TLS != nil {
+		if err := a.TLS.Validate("admin_api.tls"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the admin api configuration
+func (a *AdminAPI) SetDefaults() {
+	if a.BindAddress == "" {
+		a.BindAddress = DefaultAdminAPIBindAddress
+	}
+}
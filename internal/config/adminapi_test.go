@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAPI(t *testing.T) {
+	// disabled is never validated
+	adminAPI := &AdminAPI{}
+	adminAPI.SetDefaults()
+	assert.Equal(t, DefaultAdminAPIBindAddress, adminAPI.BindAddress)
+	assert.NoError(t, adminAPI.Validate())
+
+	adminAPI.Enabled = true
+	assert.EqualError(t, adminAPI.Validate(), "admin_api.port must be positive and non-zero")
+
+	adminAPI.Port = 9092
+	assert.EqualError(t, adminAPI.Validate(), "admin_api.auth must be defined when admin_api.enabled is true")
+
+	adminAPI.Auth = &Auth{}
+	assert.ErrorContains(t, adminAPI.Validate(), "admin_api.auth.bearer_token")
+
+	adminAPI.Auth = &Auth{BearerTokenFile: "/etc/solana-validator-ha/admin-token"}
+	assert.NoError(t, adminAPI.Validate())
+	assert.Equal(t, "127.0.0.1:9092", adminAPI.ListenAddress())
+
+	adminAPI.BindAddress = "garbage"
+	assert.EqualError(t, adminAPI.Validate(), "admin_api.bind_address must be a valid IP address, got garbage")
+}
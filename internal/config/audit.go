@@ -0,0 +1,25 @@
+package config
+
+import "path/filepath"
+
+// AuditFileName is the file in run.state_dir the audit log is appended to by default
+const AuditFileName = "audit.log"
+
+// Audit configures the audit log every role command, hook command and tower sync the agent runs is appended to
+type Audit struct {
+	// Path is the audit log file, defaults to audit.log in run.state_dir - empty without a state directory, when
+	// nothing is audited
+	Path string `koanf:"path"`
+}
+
+// Enabled returns true when commands are audited
+func (a *Audit) Enabled() bool {
+	return a.Path != ""
+}
+
+// SetDefaults sets default values for the audit configuration
+func (a *Audit) SetDefaults(stateDir string) {
+	if a.Path == "" && stateDir != "" {
+		a.Path = filepath.Join(stateDir, AuditFileName)
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Auth represents optional bearer token authentication of an http server's endpoints
+type Auth struct {
+	// BearerToken supports ${ENV_VAR} references so it needn't be inline
+	BearerToken string `koanf:"bearer_token"`
+	// BearerTokenFile is a file the bearer token is read from, surrounding whitespace trimmed
+	BearerTokenFile string `koanf:"bearer_token_file"`
+}
+
+// Validate validates the auth configuration at path
+func (a *Auth) Validate(path string) error {
+	// exactly one of bearer_token and bearer_token_file must be defined
+	if a.BearerToken == "" && a.BearerTokenFile == "" {
+		return fmt.Errorf("%s.bearer_token or %s.bearer_token_file must be defined", path, path)
+	}
+	if a.BearerToken != "" && a.BearerTokenFile != "" {
+		return fmt.Errorf("%s.bearer_token and %s.bearer_token_file are mutually exclusive", path, path)
+	}
+
+	return nil
+}
+
+// Resolve reads the bearer token from bearer_token_file or expands its ${ENV_VAR} references from the agent's
+// environment, each of which must be set. The token must not resolve empty. A nil auth resolves to no token.
+func (a *Auth) Resolve(path string) error {
+	if a == nil {
+		return nil
+	}
+
+	if a.BearerTokenFile != "" {
+		token, err := os.ReadFile(a.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read %s.bearer_token_file: %w", path, err)
+		}
+		a.BearerToken = strings.TrimSpace(string(token))
+	} else {
+		unset := []string{}
+		a.BearerToken = expandEnvReferences(a.BearerToken, nil, &unset)
+		if len(unset) > 0 {
+			return fmt.Errorf("%s.bearer_token references unset environment variables: %s", path, strings.Join(unset, ", "))
+		}
+	}
+
+	if a.BearerToken == "" {
+		return fmt.Errorf("%s bearer token must not be empty", path)
+	}
+
+	return nil
+}
+
+// Token returns the resolved bearer token, empty when auth is not configured
+func (a *Auth) Token() string {
+	if a == nil {
+		return ""
+	}
+	return a.BearerToken
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth_Validate(t *testing.T) {
+	assert.EqualError(t, (&Auth{}).Validate("prometheus.auth"), "prometheus.auth.bearer_token or prometheus.auth.bearer_token_file must be defined")
+	assert.EqualError(t, (&Auth{BearerToken: "a", BearerTokenFile: "/b"}).Validate("healthcheck.auth"), "healthcheck.auth.bearer_token and healthcheck.auth.bearer_token_file are mutually exclusive")
+	assert.NoError(t, (&Auth{BearerToken: "a"}).Validate("prometheus.auth"))
+	assert.NoError(t, (&Auth{BearerTokenFile: "/b"}).Validate("prometheus.auth"))
+
+	// validated as part of their servers' configuration
+	prometheus := &Prometheus{Port: 9090, Auth: &Auth{}}
+	assert.ErrorContains(t, prometheus.Validate(), "prometheus.auth.bearer_token")
+	healthCheck := &HealthCheck{Auth: &Auth{}}
+	assert.ErrorContains(t, healthCheck.Validate(), "healthcheck.auth.bearer_token")
+}
+
+func TestAuth_Resolve(t *testing.T) {
+	// nil is no auth
+	var auth *Auth
+	require.NoError(t, auth.Resolve("prometheus.auth"))
+	assert.Empty(t, auth.Token())
+
+	// inline
+	auth = &Auth{BearerToken: "s3cr3t"}
+	require.NoError(t, auth.Resolve("prometheus.auth"))
+	assert.Equal(t, "s3cr3t", auth.Token())
+
+	// env var
+	t.Setenv("SVHA_TEST_BEARER_TOKEN", "from-env")
+	auth = &Auth{BearerToken: "${SVHA_TEST_BEARER_TOKEN}"}
+	require.NoError(t, auth.Resolve("prometheus.auth"))
+	assert.Equal(t, "from-env", auth.Token())
+
+	auth = &Auth{BearerToken: "${SVHA_TEST_UNSET_BEARER_TOKEN}"}
+	assert.EqualError(t, auth.Resolve("prometheus.auth"), "prometheus.auth.bearer_token references unset environment variables: SVHA_TEST_UNSET_BEARER_TOKEN")
+
+	// file, trailing newline trimmed
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("from-file\n"), 0o600))
+	auth = &Auth{BearerTokenFile: tokenFile}
+	require.NoError(t, auth.Resolve("healthcheck.auth"))
+	assert.Equal(t, "from-file", auth.Token())
+
+	auth = &Auth{BearerTokenFile: filepath.Join(t.TempDir(), "missing")}
+	assert.ErrorContains(t, auth.Resolve("healthcheck.auth"), "failed to read healthcheck.auth.bearer_token_file")
+
+	require.NoError(t, os.WriteFile(tokenFile, []byte(" \n"), 0o600))
+	auth = &Auth{BearerTokenFile: tokenFile}
+	assert.EqualError(t, auth.Resolve("healthcheck.auth"), "healthcheck.auth bearer token must not be empty")
+}
+
+func TestResolvedYAML_RedactsBearerTokens(t *testing.T) {
+	cfg := Config{
+		Prometheus:  Prometheus{Auth: &Auth{BearerToken: "metrics-s3cr3t"}},
+		HealthCheck: HealthCheck{Auth: &Auth{BearerToken: "health-s3cr3t"}},
+	}
+
+	data, err := cfg.ResolvedYAML()
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cr3t")
+	assert.Contains(t, string(data), "bearer_token: '"+RedactedValue+"'")
+}
@@ -5,14 +5,40 @@ import (
 	"net/url"
 	"slices"
 	"strings"
+	"time"
 
 	solanagorpc "github.com/gagliardetto/solana-go/rpc"
 )
 
+const (
+	// DefaultClusterRPCRetries is how many more times an rpc request is sent while every url fails transiently
+	DefaultClusterRPCRetries = 2
+	// DefaultClusterRPCInitialBackoff is the delay before the first retry of an rpc request
+	DefaultClusterRPCInitialBackoff = 250 * time.Millisecond
+	// DefaultClusterRPCMaxBackoff caps the doubling delay between retries of an rpc request
+	DefaultClusterRPCMaxBackoff = 2 * time.Second
+	// DefaultClusterRPCJitter is the fraction of each backoff that is randomized
+	DefaultClusterRPCJitter = 0.2
+)
+
 // Cluster represents the Solana cluster configuration
 type Cluster struct {
-	Name    string   `koanf:"name"`
-	RPCURLs []string `koanf:"rpc_urls"`
+	Name    string     `koanf:"name"`
+	RPCURLs []string   `koanf:"rpc_urls"`
+	RPC     ClusterRPC `koanf:"rpc"`
+}
+
+// ClusterRPC represents how getClusterNodes, getIdentity and getHealth requests are retried
+type ClusterRPC struct {
+	// Retries is how many more times a request is sent while every url fails transiently, nil means
+	// DefaultClusterRPCRetries
+	Retries *int `koanf:"retries"`
+	// InitialBackoff is the delay before the first retry, doubling for each one after
+	InitialBackoff time.Duration `koanf:"initial_backoff"`
+	// MaxBackoff caps the doubling delay between retries
+	MaxBackoff time.Duration `koanf:"max_backoff"`
+	// Jitter is the fraction of each backoff randomized either way, between 0 and 1
+	Jitter float64 `koanf:"jitter"`
 }
 
 // Validate validates the cluster configuration
@@ -45,7 +71,7 @@ func (c *Cluster) Validate() error {
 		}
 	}
 
-	return nil
+	return c.RPC.Validate()
 }
 
 // SetDefaults sets default values for the cluster configuration
@@ -61,4 +87,50 @@ func (c *Cluster) SetDefaults() {
 			c.RPCURLs = []string{solanagorpc.DevNet.RPC}
 		}
 	}
+
+	c.RPC.SetDefaults()
+}
+
+// Validate validates the cluster rpc configuration
+func (r *ClusterRPC) Validate() error {
+	// cluster.rpc.retries must not be negative
+	if r.GetRetries() < 0 {
+		return fmt.Errorf("cluster.rpc.retries must not be negative, got %d", r.GetRetries())
+	}
+
+	// cluster.rpc.initial_backoff must not be negative, max_backoff must not be less than it
+	if r.InitialBackoff < 0 {
+		return fmt.Errorf("cluster.rpc.initial_backoff must not be negative, got %s", r.InitialBackoff)
+	}
+	if r.MaxBackoff < r.InitialBackoff {
+		return fmt.Errorf("cluster.rpc.max_backoff (%s) must not be less than cluster.rpc.initial_backoff (%s)", r.MaxBackoff, r.InitialBackoff)
+	}
+
+	// cluster.rpc.jitter must be between 0 and 1
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return fmt.Errorf("cluster.rpc.jitter must be between 0 and 1, got %g", r.Jitter)
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the cluster rpc configuration
+func (r *ClusterRPC) SetDefaults() {
+	if r.InitialBackoff == 0 {
+		r.InitialBackoff = DefaultClusterRPCInitialBackoff
+	}
+	if r.MaxBackoff == 0 {
+		r.MaxBackoff = max(DefaultClusterRPCMaxBackoff, r.InitialBackoff)
+	}
+	if r.Jitter == 0 {
+		r.Jitter = DefaultClusterRPCJitter
+	}
+}
+
+// GetRetries returns how many more times a request is sent while every url fails transiently
+func (r *ClusterRPC) GetRetries() int {
+	if r.Retries == nil {
+		return DefaultClusterRPCRetries
+	}
+	return *r.Retries
 }
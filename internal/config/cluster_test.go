@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	solanagorpc "github.com/gagliardetto/solana-go/rpc"
 	"github.com/stretchr/testify/assert"
@@ -71,3 +72,42 @@ func TestCluster_Validate(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "cluster.rpc_urls must be a list of valid RPC URLs")
 }
+
+func TestClusterRPC_SetDefaults(t *testing.T) {
+	r := &ClusterRPC{}
+	r.SetDefaults()
+	assert.Equal(t, DefaultClusterRPCRetries, r.GetRetries())
+	assert.Equal(t, DefaultClusterRPCInitialBackoff, r.InitialBackoff)
+	assert.Equal(t, DefaultClusterRPCMaxBackoff, r.MaxBackoff)
+	assert.Equal(t, DefaultClusterRPCJitter, r.Jitter)
+
+	// an explicit zero disables retries, a long initial backoff raises the default cap
+	retries := 0
+	r = &ClusterRPC{Retries: &retries, InitialBackoff: 5 * time.Second}
+	r.SetDefaults()
+	assert.Equal(t, 0, r.GetRetries())
+	assert.Equal(t, 5*time.Second, r.MaxBackoff)
+}
+
+func TestClusterRPC_Validate(t *testing.T) {
+	r := &ClusterRPC{}
+	r.SetDefaults()
+	assert.NoError(t, r.Validate())
+
+	retries := -1
+	r = &ClusterRPC{Retries: &retries}
+	assert.EqualError(t, r.Validate(), "cluster.rpc.retries must not be negative, got -1")
+
+	r = &ClusterRPC{InitialBackoff: -time.Second}
+	assert.EqualError(t, r.Validate(), "cluster.rpc.initial_backoff must not be negative, got -1s")
+
+	r = &ClusterRPC{InitialBackoff: 2 * time.Second, MaxBackoff: time.Second}
+	assert.EqualError(t, r.Validate(), "cluster.rpc.max_backoff (1s) must not be less than cluster.rpc.initial_backoff (2s)")
+
+	r = &ClusterRPC{Jitter: 1.5}
+	assert.EqualError(t, r.Validate(), "cluster.rpc.jitter must be between 0 and 1, got 1.5")
+
+	// cluster validation covers the rpc block
+	cluster := &Cluster{Name: solanagorpc.TestNet.Name, RPCURLs: []string{"https://api.testnet.solana.com"}, RPC: ClusterRPC{Jitter: -0.1}}
+	assert.ErrorContains(t, cluster.Validate(), "cluster.rpc.jitter")
+}
@@ -2,15 +2,14 @@ package config
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
-	"strings"
-	"time"
+	"reflect"
 
 	"github.com/charmbracelet/log"
 	"github.com/knadh/koanf"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/file"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
 )
 
 const (
@@ -27,27 +26,61 @@ type Config struct {
 	Cluster Cluster `koanf:"cluster"`
 	// Prometheus is the Prometheus metrics configuration
 	Prometheus Prometheus `koanf:"prometheus"`
+	// HealthCheck is the health check server configuration
+	HealthCheck HealthCheck `koanf:"healthcheck"`
+	// AdminAPI is the authenticated admin api for external orchestration
+	AdminAPI AdminAPI `koanf:"admin_api"`
 	// Failover is the failover decision parameters
 	Failover Failover `koanf:"failover"`
+	// Events is the event log configuration
+	Events Events `koanf:"events"`
+	// History is the failover history configuration
+	History History `koanf:"history"`
+	// Audit is the audit log configuration
+	Audit Audit `koanf:"audit"`
+	// Run is the run command process configuration
+	Run Run `koanf:"run"`
+	// State is persisting the agent state across restarts
+	State State `koanf:"state"`
+	// Fitness is the dynamic takeover fitness configuration
+	Fitness Fitness `koanf:"fitness"`
+	// Gates are the safety gates checked before taking over as active
+	Gates Gates `koanf:"gates"`
+	// Redact is how secrets are redacted when the configuration is printed
+	Redact Redact `koanf:"redact"`
+	// Notifications are the built-in notification integrations
+	Notifications Notifications `koanf:"notifications"`
+	// Debug is the debug server profiling the running agent
+	Debug Debug `koanf:"debug"`
 	// File is the file that the config was loaded from
 	File string `koanf:"-"`
+	// Warnings are the non-fatal warnings found when the config was last validated
+	Warnings []Warning `koanf:"-"`
 	// GetPublicIPFunc is a function that returns the public IP address of the current validator
 	// it defaults to using external services to get the public IP address, useful for testing to set to
 	// something else
 	GetPublicIPFunc func() (string, error)
 
+	// lenient warns about unknown keys in the config file instead of failing to load it
+	lenient bool
+	// unknownKeys are the keys in the config file no setting is read from, only kept when lenient
+	unknownKeys []unknownKey
+
 	logger *log.Logger
 }
 
 // NewConfigParams represents parameters for creating a new Config
 type NewConfigParams struct {
 	GetPublicIPFunc func() (string, error)
+	// LenientConfig warns about keys in the config file no setting is read from instead of failing to load it
+	LenientConfig bool
 }
 
 // New creates a new Config
 func New(params NewConfigParams) (config *Config, err error) {
 	config = &Config{
-		logger: log.WithPrefix("config"),
+		logger:  loglevel.WithPrefix(loglevel.Config, "config"),
+		lenient: params.LenientConfig,
 	}
 
 	if params.GetPublicIPFunc != nil {
@@ -59,8 +92,13 @@ func New(params NewConfigParams) (config *Config, err error) {
 
 // NewFromConfigFile creates a new Config from a config file path
 func NewFromConfigFile(configFile string) (*Config, error) {
+	return NewFromConfigFileWithParams(configFile, NewConfigParams{})
+}
+
+// NewFromConfigFileWithParams creates a new Config from a config file path with params
+func NewFromConfigFileWithParams(configFile string, params NewConfigParams) (*Config, error) {
 	// Create new config
-	cfg, err := New(NewConfigParams{})
+	cfg, err := New(params)
 	if err != nil {
 		return nil, err
 	}
@@ -78,24 +116,40 @@ func NewFromConfigFile(configFile string) (*Config, error) {
 	return cfg, nil
 }
 
+// NewResolvedFromConfigFile creates a new Config from a config file path with defaults applied but without
+// loading the identity keypair files, validating it or rendering role commands - it is for printing what
+// the agent would use, so a config that fails validation can still be shown - unknown keys are only warned about
+func NewResolvedFromConfigFile(configFile string) (*Config, error) {
+	cfg, err := New(NewConfigParams{LenientConfig: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.LoadFromFile(configFile); err != nil {
+		return nil, err
+	}
+
+	cfg.setDefaults()
+	if len(cfg.unknownKeys) > 0 {
+		cfg.logger.Warn("unknown keys in config file are ignored", "keys", formatUnknownKeys(cfg.unknownKeys))
+	}
+
+	// patterns are compiled when printing, a bad one would silently not redact
+	if err := cfg.Redact.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
 // LoadFromFile loads configuration from file into the struct
 func (c *Config) LoadFromFile(filePath string) error {
-	// Expand ~ to home directory
-	if strings.HasPrefix(filePath, "~/") || filePath == "~" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("error getting home directory: %w", err)
-		}
-		if filePath == "~" {
-			filePath = homeDir
-		} else {
-			filePath = strings.Replace(filePath, "~", homeDir, 1)
-		}
-	}
-
-	// Resolve to absolute path
-	absPath, err := filepath.Abs(filePath)
+	// Expand ~ and resolve to an absolute path
+	absPath, err := expandPath(filePath, ".")
 	if err != nil {
+		return err
+	}
+	if absPath, err = filepath.Abs(absPath); err != nil {
 		return fmt.Errorf("error resolving absolute path: %w", err)
 	}
 
@@ -112,7 +166,21 @@ func (c *Config) LoadFromFile(filePath string) error {
 
 	// Load YAML config file
 	if err := k.Load(file.Provider(c.File), yaml.Parser()); err != nil {
-		return fmt.Errorf("error loading config file: %w", err)
+		return fmt.Errorf("error loading config file %s: %w", c.File, err)
+	}
+
+	// koanf ignores keys no field is read from, a misspelt or misplaced setting would silently never apply
+	c.unknownKeys = findUnknownKeys("", reflect.TypeOf(*c), k.Raw())
+	if len(c.unknownKeys) > 0 && !c.lenient {
+		return fmt.Errorf("unknown keys in config file %s: %s - use --lenient-config to only warn", c.File, formatUnknownKeys(c.unknownKeys))
+	}
+
+	// checked as loaded as a bare number unmarshals to a nanosecond duration and defaults replace zero values
+	if err := validateLoadedDurations("", reflect.TypeOf(*c), k.Raw()); err != nil {
+		return err
+	}
+	if err := validateLoadedFailover(k); err != nil {
+		return err
 	}
 
 	// Unmarshal into this config struct
@@ -120,7 +188,8 @@ func (c *Config) LoadFromFile(filePath string) error {
 		return fmt.Errorf("error unmarshaling config: %w", err)
 	}
 
-	return nil
+	// resolve ~ and relative paths against the config file's directory, not wherever the agent was started
+	return c.resolvePaths()
 }
 
 // Initialize processes and validates the loaded configuration
@@ -132,6 +201,13 @@ func (c *Config) Initialize() error {
 	if err := c.Validator.Identities.Load(); err != nil {
 		return err
 	}
+	// operators confirm the right keypair files are in place from these
+	if c.logger != nil {
+		c.logger.Info("loaded validator identities",
+			"active_pubkey", c.Validator.Identities.ActiveKeyPair.PublicKey().String(),
+			"passive_pubkeys", c.Validator.Identities.PassivePubkeys(),
+		)
+	}
 
 	// validate configuration (after identity files are loaded)
 	if err := c.validate(); err != nil {
@@ -139,13 +215,45 @@ func (c *Config) Initialize() error {
 	}
 
 	// render failover commands, args and hooks
-	err := c.Failover.RenderRoleCommands(RoleCommandTemplateData{
-		ActiveIdentityKeypairFile:  c.Validator.Identities.ActiveKeyPairFile,
-		ActiveIdentityPubkey:       c.Validator.Identities.ActiveKeyPair.PublicKey().String(),
-		PassiveIdentityKeypairFile: c.Validator.Identities.PassiveKeyPairFile,
-		PassiveIdentityPubkey:      c.Validator.Identities.PassiveKeyPair.PublicKey().String(),
-		SelfName:                   c.Validator.Name,
-	})
+	identities := &c.Validator.Identities
+	passiveKeyPairFiles := make([]string, len(identities.PassiveKeyPairFiles))
+	for i, passiveKeyPairFile := range identities.PassiveKeyPairFiles {
+		passiveKeyPairFiles[i] = identities.KeyPairFile(passiveKeyPairFile)
+	}
+	templateData := RoleCommandTemplateData{
+		ActiveIdentityKeypairFile:   identities.KeyPairFile(identities.ActiveKeyPairFile),
+		ActiveIdentityPubkey:        identities.ActiveKeyPair.PublicKey().String(),
+		PassiveIdentityKeypairFile:  identities.KeyPairFile(identities.PassiveKeyPairFile),
+		PassiveIdentityPubkey:       identities.PassiveKeyPair.PublicKey().String(),
+		PassiveIdentityKeypairFiles: passiveKeyPairFiles,
+		PassiveIdentityPubkeys:      identities.PassivePubkeys(),
+		SelfName:                    c.Validator.Name,
+	}
+	err := c.Failover.RenderRoleCommands(templateData)
+	if err != nil {
+		return err
+	}
+
+	// render notification templates and secrets
+	err = c.Notifications.Render(templateData)
+	if err != nil {
+		return err
+	}
+
+	// read the http servers' bearer tokens from their files or environment
+	err = c.Prometheus.Auth.Resolve("prometheus.auth")
+	if err != nil {
+		return err
+	}
+	err = c.HealthCheck.Auth.Resolve("healthcheck.auth")
+	if err != nil {
+		return err
+	}
+	err = c.AdminAPI.Auth.Resolve("admin_api.auth")
+	if err != nil {
+		return err
+	}
+	err = c.Failover.Heartbeat.Auth.Resolve("failover.heartbeat.auth")
 	if err != nil {
 		return err
 	}
@@ -153,6 +261,14 @@ func (c *Config) Initialize() error {
 	return nil
 }
 
+// ForceDryRun turns failover.dry_run on regardless of the loaded configuration - there is deliberately no way
+// to force it off
+func (c *Config) ForceDryRun() {
+	c.Failover.DryRun = true
+	c.Failover.ForcedDryRun = true
+	c.refreshWarnings()
+}
+
 // validate validates the configuration
 func (c *Config) validate() error {
 	err := c.Log.Validate()
@@ -175,21 +291,88 @@ func (c *Config) validate() error {
 		return err
 	}
 
+	err = c.HealthCheck.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.AdminAPI.Validate()
+	if err != nil {
+		return err
+	}
+
+	// admin_api.port must not clash with the metrics or health check server
+	if c.AdminAPI.Enabled && (c.AdminAPI.Port == c.Prometheus.Port || c.AdminAPI.Port == c.Prometheus.HealthCheckPort()) {
+		return fmt.Errorf("admin_api.port (%d) must differ from prometheus.port (%d) and the health check port (%d)",
+			c.AdminAPI.Port, c.Prometheus.Port, c.Prometheus.HealthCheckPort())
+	}
+
 	err = c.Failover.Validate()
 	if err != nil {
 		return err
 	}
 
-	// failover.dry_run if true print warning
-	if c.Failover.DryRun {
-		c.logger.Warn("failover.dry_run is true - failovers will dry-run commands only and be no-op")
+	// failover.min_rpc_confirmations can't need more confirmations than there are cluster.rpc_urls to give them
+	if c.Failover.MinRPCConfirmations > len(c.Cluster.RPCURLs) {
+		return fmt.Errorf("failover.min_rpc_confirmations (%d) must not exceed the number of cluster.rpc_urls (%d)",
+			c.Failover.MinRPCConfirmations, len(c.Cluster.RPCURLs))
 	}
 
-	// failover.takeover_jitter_duration if below 1s print warning
-	if c.Failover.TakeoverJitterDuration > 0 && c.Failover.TakeoverJitterDuration < time.Second {
-		c.logger.Warn("failover.takeover_jitter_duration is below 1s - this may void the usefulness of jitter in preventing race conditions")
+	err = c.Events.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.History.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.Fitness.Validate()
+	if err != nil {
+		return err
 	}
 
+	err = c.Gates.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.Run.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.State.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.Redact.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.Notifications.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = c.Debug.Validate()
+	if err != nil {
+		return err
+	}
+
+	// debug.pprof_port must not clash with the metrics, health check or admin api server
+	if c.Debug.Enabled() && (c.Debug.PprofPort == c.Prometheus.Port || c.Debug.PprofPort == c.Prometheus.HealthCheckPort() ||
+		(c.AdminAPI.Enabled && c.Debug.PprofPort == c.AdminAPI.Port)) {
+		return fmt.Errorf("debug.pprof_port (%d) must differ from prometheus.port (%d), the health check port (%d) and admin_api.port",
+			c.Debug.PprofPort, c.Prometheus.Port, c.Prometheus.HealthCheckPort())
+	}
+
+	// non-fatal warnings are collected so they can be exported as well as logged
+	c.EvaluateWarnings()
+
 	return nil
 }
 
@@ -199,5 +382,16 @@ func (c *Config) setDefaults() {
 	c.Validator.SetDefaults()
 	c.Cluster.SetDefaults()
 	c.Prometheus.SetDefaults()
+	c.HealthCheck.SetDefaults()
+	c.AdminAPI.SetDefaults()
 	c.Failover.SetDefaults()
+	c.Events.SetDefaults()
+	c.History.SetDefaults()
+	c.Run.SetDefaults(c.File)
+	c.State.SetDefaults(c.Run.StateDir)
+	c.Audit.SetDefaults(c.Run.StateDir)
+	c.Fitness.SetDefaults()
+	c.Gates.SetDefaults()
+	c.Notifications.SetDefaults()
+	c.Debug.SetDefaults()
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -123,6 +124,25 @@ func TestValidate(t *testing.T) {
 	err = cfg.validate()
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "validator.rpc_url must be a valid URL")
+
+	// Test with more rpc confirmations than cluster rpc urls
+	cfg.Validator.RPCURL = "http://localhost:8899"
+	cfg.Failover.MinRPCConfirmations = 2
+	err = cfg.validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.min_rpc_confirmations (2) must not exceed the number of cluster.rpc_urls (1)")
+
+	cfg.Cluster.RPCURLs = append(cfg.Cluster.RPCURLs, "https://rpc.example.com")
+	assert.NoError(t, cfg.validate())
+
+	// Test with the admin api on the health check port
+	cfg.AdminAPI = AdminAPI{Enabled: true, Port: 9091, BindAddress: DefaultAdminAPIBindAddress, Auth: &Auth{BearerToken: "s3cr3t"}}
+	err = cfg.validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "admin_api.port (9091) must differ from prometheus.port (9090) and the health check port (9091)")
+
+	cfg.AdminAPI.Port = 9092
+	assert.NoError(t, cfg.validate())
 }
 
 func createTempConfigFile(t *testing.T) string {
@@ -157,7 +177,7 @@ prometheus:
 failover:
   dry_run: true
   poll_interval_duration: "30s"
-  leaderless_threshold_duration: "5m"
+  leaderless_samples_threshold: 10
   takeover_jitter_duration: "10s"
   active:
     command: "systemctl start solana"
@@ -214,7 +234,7 @@ prometheus:
 failover:
   dry_run: true
   poll_interval_duration: "30s"
-  leaderless_threshold_duration: "5m"
+  leaderless_samples_threshold: 10
   takeover_jitter_duration: "10s"
   active:
     command: "systemctl start solana"
@@ -238,3 +258,78 @@ failover:
 
 	return tempFile.Name()
 }
+
+func TestConfig_ForceDryRun(t *testing.T) {
+	cfg := newWarningFreeConfig()
+	require.Empty(t, cfg.EvaluateWarnings())
+
+	cfg.ForceDryRun()
+	assert.True(t, cfg.Failover.DryRun)
+	assert.True(t, cfg.Failover.ForcedDryRun)
+	require.Len(t, cfg.Warnings, 1)
+	assert.Equal(t, WarningDryRunEnabled, cfg.Warnings[0].Code)
+
+	// already dry run in the config - still forced, warnings unchanged
+	cfg = newWarningFreeConfig()
+	cfg.Failover.DryRun = true
+	cfg.EvaluateWarnings()
+	cfg.ForceDryRun()
+	assert.True(t, cfg.Failover.DryRun)
+	assert.True(t, cfg.Failover.ForcedDryRun)
+	assert.Len(t, cfg.Warnings, 1)
+}
+
+func TestNewFromConfigFile_PassiveIdentityList(t *testing.T) {
+	activeIdentityFile := createTempIdentityFile(t)
+	primaryPassiveIdentityFile := createTempIdentityFile(t)
+	rotatedPassiveIdentityFile := createTempIdentityFile(t)
+	t.Cleanup(func() {
+		os.Remove(activeIdentityFile)
+		os.Remove(primaryPassiveIdentityFile)
+		os.Remove(rotatedPassiveIdentityFile)
+	})
+
+	content := `
+validator:
+  name: "test-validator"
+  identities:
+    active: "` + activeIdentityFile + `"
+    passive:
+      - "` + primaryPassiveIdentityFile + `"
+      - "` + rotatedPassiveIdentityFile + `"
+cluster:
+  name: "testnet"
+failover:
+  active:
+    command: "set-identity"
+    args: ["{{ .PassiveIdentityPubkey }}", "{{ range $i, $pubkey := .PassiveIdentityPubkeys }}{{ if $i }},{{ end }}{{ $pubkey }}{{ end }}"]
+  passive:
+    command: "set-identity"
+    args: ["{{ .PassiveIdentityKeypairFile }}", "{{ index .PassiveIdentityKeypairFiles 1 }}"]
+  peers:
+    validator-1:
+      ip: "192.168.1.10"
+`
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(content), 0o600))
+
+	cfg, err := NewFromConfigFile(configFile)
+	require.NoError(t, err)
+
+	pubkeys := cfg.Validator.Identities.PassivePubkeys()
+	require.Len(t, pubkeys, 2)
+	assert.Equal(t, []string{pubkeys[0], pubkeys[0] + "," + pubkeys[1]}, cfg.Failover.Active.Args)
+	assert.Equal(t, []string{primaryPassiveIdentityFile, rotatedPassiveIdentityFile}, cfg.Failover.Passive.Args)
+}
+
+func TestNewFromConfigFile_SinglePassiveIdentity(t *testing.T) {
+	configFile := createTempConfigFile(t)
+	t.Cleanup(func() { os.Remove(configFile) })
+
+	cfg, err := NewFromConfigFile(configFile)
+	require.NoError(t, err)
+
+	assert.Len(t, cfg.Validator.Identities.PassiveKeyPairFiles, 1)
+	assert.Equal(t, cfg.Validator.Identities.PassiveKeyPairFiles[0], cfg.Validator.Identities.PassiveKeyPairFile)
+	assert.Len(t, cfg.Validator.Identities.PassivePubkeys(), 1)
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultEtcdKeyPrefix is the default prefix of the key the active agent holds in etcd
+	DefaultEtcdKeyPrefix = "/solana-validator-ha"
+	// DefaultEtcdLeaseTTL is the default ttl of the lease the active agent holds its key on
+	DefaultEtcdLeaseTTL = 15 * time.Second
+	// DefaultEtcdUnreachableGrace is how long etcd may be unreachable by default before falling back to gossip alone
+	DefaultEtcdUnreachableGrace = time.Minute
+	// MinEtcdLeaseTTL is the shortest lease ttl, the lease being kept alive every third of it by requests of up to
+	// two seconds each
+	MinEtcdLeaseTTL = 6 * time.Second
+)
+
+// Coordination configures an election backend agents coordinate failovers through on top of gossip
+type Coordination struct {
+	// Etcd elects the active agent by a lease-backed key in etcd, nil coordinates by gossip alone
+	Etcd *Etcd `koanf:"etcd"`
+}
+
+// Validate validates the coordination configuration
+func (c *Coordination) Validate() error {
+	if c.Etcd == nil {
+		return nil
+	}
+	return c.Etcd.Validate()
+}
+
+// SetDefaults sets default values for the coordination configuration
+func (c *Coordination) SetDefaults() {
+	if c.Etcd != nil {
+		c.Etcd.SetDefaults()
+	}
+}
+
+// Etcd configures the etcd key the active agent holds on a lease while active - passives only take over once it
+// has expired
+type Etcd struct {
+	// Endpoints are the etcd members' client urls, tried in order
+	Endpoints []string `koanf:"endpoints"`
+	// TLS is how etcd is reached over https, nil for the system roots without a client certificate
+	TLS *ClientTLS `koanf:"tls"`
+	// KeyPrefix prefixes the key the active agent holds, shared by the agents of one validator
+	KeyPrefix string `koanf:"key_prefix"`
+	// LeaseTTL is how long after the active agent last kept its lease alive the key expires
+	LeaseTTL time.Duration `koanf:"lease_ttl"`
+	// UnreachableGrace is how long etcd may be unreachable before failovers are coordinated by gossip alone
+	UnreachableGrace time.Duration `koanf:"unreachable_grace"`
+}
+
+// Key returns the key the active agent holds
+func (e *Etcd) Key() string {
+	return strings.TrimRight(e.KeyPrefix, "/") + "/active"
+}
+
+// Validate validates the etcd configuration
+func (e *Etcd) Validate() error {
+	// failover.coordination.etcd.endpoints must be at least one http or https url - https when tls is set
+	if len(e.Endpoints) == 0 {
+		return fmt.Errorf("failover.coordination.etcd.endpoints must have at least one endpoint")
+	}
+	for i, endpoint := range e.Endpoints {
+		u, err := url.ParseRequestURI(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("failover.coordination.etcd.endpoints[%d] must be an http or https url, got %q", i, endpoint)
+		}
+		if e.TLS != nil && u.Scheme != "https" {
+			return fmt.Errorf("failover.coordination.etcd.endpoints[%d] must be https when failover.coordination.etcd.tls is set, got %q", i, endpoint)
+		}
+	}
+
+	// failover.coordination.etcd.tls must load - fail fast rather than on the first failover
+	if e.TLS != nil {
+		if _, err := e.TLS.Config(); err != nil {
+			return fmt.Errorf("failover.coordination.etcd.tls: %w", err)
+		}
+	}
+
+	// failover.coordination.etcd.key_prefix must be defined
+	if strings.Trim(e.KeyPrefix, "/") == "" {
+		return fmt.Errorf("failover.coordination.etcd.key_prefix must be defined")
+	}
+
+	// failover.coordination.etcd.lease_ttl must be whole seconds and outlast a keepalive - the active agent keeps
+	// its lease alive every third of it, however long its polls take
+	if e.LeaseTTL%time.Second != 0 || e.LeaseTTL < MinEtcdLeaseTTL {
+		return fmt.Errorf("failover.coordination.etcd.lease_ttl must be whole seconds of at least %s, got %s", MinEtcdLeaseTTL, e.LeaseTTL)
+	}
+
+	// failover.coordination.etcd.unreachable_grace must be positive
+	if e.UnreachableGrace <= 0 {
+		return fmt.Errorf("failover.coordination.etcd.unreachable_grace must be positive, got %s", e.UnreachableGrace)
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the etcd configuration
+func (e *Etcd) SetDefaults() {
+	if e.KeyPrefix == "" {
+		e.KeyPrefix = DefaultEtcdKeyPrefix
+	}
+	if e.LeaseTTL == 0 {
+		e.LeaseTTL = DefaultEtcdLeaseTTL
+	}
+	if e.UnreachableGrace == 0 {
+		e.UnreachableGrace = DefaultEtcdUnreachableGrace
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtcd_SetDefaults(t *testing.T) {
+	// no etcd is coordinated with unless configured
+	coordination := &Coordination{}
+	coordination.SetDefaults()
+	assert.Nil(t, coordination.Etcd)
+	assert.NoError(t, coordination.Validate())
+
+	coordination.Etcd = &Etcd{Endpoints: []string{"http://10.0.0.1:2379"}}
+	coordination.SetDefaults()
+	assert.Equal(t, DefaultEtcdKeyPrefix, coordination.Etcd.KeyPrefix)
+	assert.Equal(t, 15*time.Second, coordination.Etcd.LeaseTTL)
+	assert.Equal(t, time.Minute, coordination.Etcd.UnreachableGrace)
+	assert.Equal(t, "/solana-validator-ha/active", coordination.Etcd.Key())
+}
+
+func TestEtcd_Validate(t *testing.T) {
+	certFile, keyFile := createTempCertificate(t)
+
+	tests := []struct {
+		name   string
+		modify func(e *Etcd)
+		errMsg string
+	}{
+		{name: "valid", modify: func(e *Etcd) {}},
+		{name: "valid tls", modify: func(e *Etcd) {
+			e.Endpoints = []string{"https://10.0.0.1:2379"}
+			e.TLS = &ClientTLS{CAFile: certFile, CertFile: certFile, KeyFile: keyFile}
+		}},
+		{name: "no endpoints", modify: func(e *Etcd) { e.Endpoints = nil }, errMsg: "failover.coordination.etcd.endpoints must have at least one endpoint"},
+		{name: "invalid endpoint", modify: func(e *Etcd) { e.Endpoints = []string{"http://10.0.0.1:2379", "10.0.0.2:2379"} }, errMsg: `failover.coordination.etcd.endpoints[1] must be an http or https url, got "10.0.0.2:2379"`},
+		{name: "http endpoint with tls", modify: func(e *Etcd) { e.TLS = &ClientTLS{CAFile: certFile} }, errMsg: "failover.coordination.etcd.endpoints[0] must be https when failover.coordination.etcd.tls is set"},
+		{name: "missing ca file", modify: func(e *Etcd) {
+			e.Endpoints = []string{"https://10.0.0.1:2379"}
+			e.TLS = &ClientTLS{CAFile: filepath.Join(t.TempDir(), "missing.crt")}
+		}, errMsg: "failover.coordination.etcd.tls: failed to read ca_file"},
+		{name: "ca file without certificates", modify: func(e *Etcd) {
+			e.Endpoints = []string{"https://10.0.0.1:2379"}
+			e.TLS = &ClientTLS{CAFile: keyFile}
+		}, errMsg: "failover.coordination.etcd.tls: no certificate found in ca_file"},
+		{name: "cert without key", modify: func(e *Etcd) {
+			e.Endpoints = []string{"https://10.0.0.1:2379"}
+			e.TLS = &ClientTLS{CertFile: certFile}
+		}, errMsg: "failover.coordination.etcd.tls: cert_file and key_file must both be defined"},
+		{name: "empty key prefix", modify: func(e *Etcd) { e.KeyPrefix = "/" }, errMsg: "failover.coordination.etcd.key_prefix must be defined"},
+		{name: "fractional lease ttl", modify: func(e *Etcd) { e.LeaseTTL = 15500 * time.Millisecond }, errMsg: "failover.coordination.etcd.lease_ttl must be whole seconds of at least 6s, got 15.5s"},
+		{name: "shortest lease ttl", modify: func(e *Etcd) { e.LeaseTTL = 6 * time.Second }},
+		{name: "lease ttl too short", modify: func(e *Etcd) { e.LeaseTTL = 5 * time.Second }, errMsg: "failover.coordination.etcd.lease_ttl must be whole seconds of at least 6s, got 5s"},
+		{name: "negative unreachable grace", modify: func(e *Etcd) { e.UnreachableGrace = -time.Second }, errMsg: "failover.coordination.etcd.unreachable_grace must be positive, got -1s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			etcd := &Etcd{Endpoints: []string{"http://10.0.0.1:2379"}}
+			etcd.SetDefaults()
+			tt.modify(etcd)
+
+			err := etcd.Validate()
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// DefaultDebugBindAddress is the address the debug server listens on when no bind_address is set - loopback only,
+// as profiles expose the agent's memory
+const DefaultDebugBindAddress = "127.0.0.1"
+
+// Debug configures the debug server profiling the running agent, off unless pprof_port is set
+type Debug struct {
+	// PprofPort serves net/http/pprof on /debug/pprof/, 0 disables the debug server
+	PprofPort int `koanf:"pprof_port"`
+	// Expvar also serves the agent's counters, memstats and cmdline as JSON on /debug/vars
+	Expvar bool `koanf:"expvar"`
+	// BindAddress is the IP address the debug server listens on, DefaultDebugBindAddress if unset
+	BindAddress string `koanf:"bind_address"`
+	// AllowNonLoopback allows a bind_address other than a loopback address
+	AllowNonLoopback bool `koanf:"allow_non_loopback"`
+}
+
+// Enabled returns true when the debug server is served
+func (d *Debug) Enabled() bool {
+	return d.PprofPort > 0
+}
+
+// ListenAddress returns the host:port the debug server listens on
+func (d *Debug) ListenAddress() string {
+	return net.JoinHostPort(d.BindAddress, strconv.Itoa(d.PprofPort))
+}
+
+// Validate validates the debug configuration
+func (d *Debug) Validate() error {
+	// debug.pprof_port must not be negative
+	if d.PprofPort < 0 {
+		return fmt.Errorf("debug.pprof_port must not be negative, got %d", d.PprofPort)
+	}
+
+	// debug.expvar is served by the debug server so needs debug.pprof_port
+	if d.Expvar && !d.Enabled() {
+		return fmt.Errorf("debug.pprof_port must be set when debug.expvar is true - /debug/vars is served on it")
+	}
+
+	if !d.Enabled() {
+		return nil
+	}
+
+	// debug.bind_address must be an IP address
+	if err := validateBindAddress("debug.bind_address", d.BindAddress); err != nil {
+		return err
+	}
+
+	// debug.bind_address must be loopback unless debug.allow_non_loopback is set
+	if ip := net.ParseIP(d.BindAddress); ip != nil && !ip.IsLoopback() && !d.AllowNonLoopback {
+		return fmt.Errorf("debug.bind_address must be a loopback address unless debug.allow_non_loopback is true, got %s", d.BindAddress)
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the debug configuration
+func (d *Debug) SetDefaults() {
+	if d.BindAddress == "" {
+		d.BindAddress = DefaultDebugBindAddress
+	}
+}
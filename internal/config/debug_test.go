@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebug(t *testing.T) {
+	// off by default
+	debug := &Debug{}
+	debug.SetDefaults()
+	assert.Equal(t, DefaultDebugBindAddress, debug.BindAddress)
+	assert.False(t, debug.Enabled())
+	assert.NoError(t, debug.Validate())
+
+	debug.Expvar = true
+	assert.EqualError(t, debug.Validate(), "debug.pprof_port must be set when debug.expvar is true - /debug/vars is served on it")
+
+	debug.PprofPort = 6060
+	assert.NoError(t, debug.Validate())
+	assert.Equal(t, "127.0.0.1:6060", debug.ListenAddress())
+
+	debug.BindAddress = "::1"
+	assert.NoError(t, debug.Validate())
+
+	// non-loopback addresses need the override
+	debug.BindAddress = "0.0.0.0"
+	assert.EqualError(t, debug.Validate(), "debug.bind_address must be a loopback address unless debug.allow_non_loopback is true, got 0.0.0.0")
+	debug.AllowNonLoopback = true
+	assert.NoError(t, debug.Validate())
+
+	debug.BindAddress = "garbage"
+	assert.EqualError(t, debug.Validate(), "debug.bind_address must be a valid IP address, got garbage")
+
+	debug.PprofPort = -1
+	assert.EqualError(t, debug.Validate(), "debug.pprof_port must not be negative, got -1")
+}
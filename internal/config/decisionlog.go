@@ -0,0 +1,54 @@
+package config
+
+import "fmt"
+
+const (
+	// DefaultDecisionLogMaxFileBytes is the size the decisions file may grow to by default before it is rotated
+	DefaultDecisionLogMaxFileBytes = 10 * 1024 * 1024
+	// DefaultDecisionLogMaxBackups is the number of rotated decisions files kept by default
+	DefaultDecisionLogMaxBackups = 3
+)
+
+// DecisionLog configures appending every poll cycle's decision as a JSON record to a size rotated file
+type DecisionLog struct {
+	// Path is the decisions file, empty only logs the decision
+	Path string `koanf:"path"`
+	// MaxFileBytes is the size the decisions file may grow to before it is rotated to <path>.1
+	MaxFileBytes int64 `koanf:"max_file_bytes"`
+	// MaxBackups is the number of rotated decisions files kept, the oldest is removed beyond it
+	MaxBackups int `koanf:"max_backups"`
+}
+
+// Enabled returns true when decisions are appended to a file
+func (d *DecisionLog) Enabled() bool {
+	return d.Path != ""
+}
+
+// Validate validates the decision log configuration
+func (d *DecisionLog) Validate() error {
+	if !d.Enabled() {
+		return nil
+	}
+
+	// failover.decision_log.max_file_bytes must be positive
+	if d.MaxFileBytes <= 0 {
+		return fmt.Errorf("failover.decision_log.max_file_bytes must be positive and non-zero")
+	}
+
+	// failover.decision_log.max_backups must not be negative
+	if d.MaxBackups < 0 {
+		return fmt.Errorf("failover.decision_log.max_backups must not be negative, got %d", d.MaxBackups)
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the decision log configuration
+func (d *DecisionLog) SetDefaults() {
+	if d.MaxFileBytes == 0 {
+		d.MaxFileBytes = DefaultDecisionLogMaxFileBytes
+	}
+	if d.MaxBackups == 0 {
+		d.MaxBackups = DefaultDecisionLogMaxBackups
+	}
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecisionLog_SetDefaults(t *testing.T) {
+	decisionLog := &DecisionLog{}
+	decisionLog.SetDefaults()
+	assert.False(t, decisionLog.Enabled())
+	assert.Equal(t, int64(10*1024*1024), decisionLog.MaxFileBytes)
+	assert.Equal(t, 3, decisionLog.MaxBackups)
+
+	// explicit values are kept
+	decisionLog = &DecisionLog{Path: "/var/log/solana-validator-ha/decisions.jsonl", MaxFileBytes: 1024, MaxBackups: 1}
+	decisionLog.SetDefaults()
+	assert.True(t, decisionLog.Enabled())
+	assert.Equal(t, int64(1024), decisionLog.MaxFileBytes)
+	assert.Equal(t, 1, decisionLog.MaxBackups)
+}
+
+func TestDecisionLog_Validate(t *testing.T) {
+	// disabled needs nothing
+	assert.NoError(t, (&DecisionLog{MaxFileBytes: -1}).Validate())
+
+	assert.NoError(t, (&DecisionLog{Path: "/tmp/decisions.jsonl", MaxFileBytes: 1024}).Validate())
+	assert.ErrorContains(t, (&DecisionLog{Path: "/tmp/decisions.jsonl"}).Validate(), "failover.decision_log.max_file_bytes must be positive")
+	assert.ErrorContains(t, (&DecisionLog{Path: "/tmp/decisions.jsonl", MaxFileBytes: 1024, MaxBackups: -1}).Validate(), "failover.decision_log.max_backups must not be negative")
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// validateLoadedDurations validates every duration setting of typ, the type raw is unmarshaled into, as loaded
+// from the config file at path. A duration must be a duration string with a unit - koanf reads a bare number as
+// nanoseconds, so cooldown: 300 or timeout: 30 would silently be 300ns or 30ns - only a bare 0 is unambiguous.
+func validateLoadedDurations(path string, typ reflect.Type, raw any) error {
+	if raw == nil {
+		return nil
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch {
+	case typ == durationType:
+		return validateLoadedDuration(path, raw)
+	case typ.Kind() == reflect.Struct:
+		values, ok := raw.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			key := field.Tag.Get("koanf")
+			if !field.IsExported() || key == "" || key == "-" {
+				continue
+			}
+			if err := validateLoadedDurations(joinKey(path, key), field.Type, values[key]); err != nil {
+				return err
+			}
+		}
+	case typ.Kind() == reflect.Slice:
+		values, ok := raw.([]any)
+		if !ok {
+			return nil
+		}
+		for i, value := range values {
+			if err := validateLoadedDurations(fmt.Sprintf("%s[%d]", path, i), typ.Elem(), value); err != nil {
+				return err
+			}
+		}
+	case typ.Kind() == reflect.Map:
+		values, ok := raw.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for key, value := range values {
+			if err := validateLoadedDurations(joinKey(path, key), typ.Elem(), value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateLoadedDuration validates the duration setting at path as loaded from the config file
+func validateLoadedDuration(path string, raw any) error {
+	switch value := raw.(type) {
+	case string:
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%s must be a duration like 30s, 5m or 1h30m, got %q", path, value)
+		}
+	case int, int64, float64:
+		if fmt.Sprint(value) != "0" {
+			return fmt.Errorf("%s must be a duration with a unit like %vs or %vm, got %v - a bare number is nanoseconds", path, value, value, value)
+		}
+	default:
+		return fmt.Errorf("%s must be a duration like 30s, 5m or 1h30m, got %v", path, value)
+	}
+	return nil
+}
+
+// joinKey returns key under path, key itself at the top level
+func joinKey(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLoadedDurations(t *testing.T) {
+	load := func(t *testing.T, yaml string) error {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(yaml), 0o600))
+		return (&Config{}).LoadFromFile(path)
+	}
+
+	assert.NoError(t, load(t, `
+run:
+  startup_timeout: 2m
+failover:
+  takeover_jitter_duration: 15s
+  active:
+    hooks:
+      pre:
+        - name: notify
+          timeout: 30s
+`))
+
+	// durations nested in lists and maps are found by their full path
+	assert.EqualError(t, load(t, `
+failover:
+  active:
+    hooks:
+      pre:
+        - name: notify
+          timeout: 30
+`), "failover.active.hooks.pre[0].timeout must be a duration with a unit like 30s or 30m, got 30 - a bare number is nanoseconds")
+	assert.EqualError(t, load(t, "failover:\n  takeover_jitter_duration: 1.5\n"),
+		"failover.takeover_jitter_duration must be a duration with a unit like 1.5s or 1.5m, got 1.5 - a bare number is nanoseconds")
+	assert.EqualError(t, load(t, "run:\n  shutdown_timeout: thirty seconds\n"),
+		`run.shutdown_timeout must be a duration like 30s, 5m or 1h30m, got "thirty seconds"`)
+	assert.EqualError(t, load(t, "run:\n  shutdown_timeout: true\n"),
+		"run.shutdown_timeout must be a duration like 30s, 5m or 1h30m, got true")
+
+	// a duration where a number is expected isn't silently zero either
+	assert.ErrorContains(t, load(t, "failover:\n  leaderless_samples_threshold: 30s\n"), "leaderless_samples_threshold")
+}
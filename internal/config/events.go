@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+)
+
+// Events represents the event log configuration
+type Events struct {
+	// Size is the number of most recent events kept in memory and served on /events
+	Size int `koanf:"size"`
+	// File is an optional path to persist events to so they survive restarts
+	File string `koanf:"file"`
+	// MaxFileBytes is the size the events file may grow to before it is truncated to the most recent events
+	MaxFileBytes int64 `koanf:"max_file_bytes"`
+}
+
+// Validate validates the events configuration
+func (e *Events) Validate() error {
+	// events.size must be positive
+	if e.Size <= 0 {
+		return fmt.Errorf("events.size must be positive and non-zero")
+	}
+
+	// events.max_file_bytes must be positive
+	if e.MaxFileBytes <= 0 {
+		return fmt.Errorf("events.max_file_bytes must be positive and non-zero")
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the events configuration
+func (e *Events) SetDefaults() {
+	if e.Size == 0 {
+		e.Size = events.DefaultSize
+	}
+	if e.MaxFileBytes == 0 {
+		e.MaxFileBytes = events.DefaultMaxFileBytes
+	}
+}
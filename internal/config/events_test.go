@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents_SetDefaults(t *testing.T) {
+	events := &Events{}
+	events.SetDefaults()
+
+	assert.Equal(t, 100, events.Size)
+	assert.Equal(t, int64(1024*1024), events.MaxFileBytes)
+	assert.Empty(t, events.File)
+}
+
+func TestEvents_Validate(t *testing.T) {
+	events := &Events{Size: 10, MaxFileBytes: 1024}
+	assert.NoError(t, events.Validate())
+
+	events.Size = -1
+	err := events.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "events.size must be positive and non-zero")
+
+	events.Size = 10
+	events.MaxFileBytes = -1
+	err = events.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "events.max_file_bytes must be positive and non-zero")
+}
@@ -2,8 +2,29 @@ package config
 
 import (
 	"fmt"
-	"net"
 	"time"
+
+	"github.com/knadh/koanf"
+)
+
+const (
+	// MinPollInterval is the shortest failover.poll_interval_duration allowed
+	MinPollInterval = time.Second
+	// PollIntervalWarnThreshold is the failover.poll_interval_duration below which a warning is logged
+	PollIntervalWarnThreshold = 5 * time.Second
+	// MaxTakeoverJitter is the longest failover.takeover_jitter_duration allowed
+	MaxTakeoverJitter = 300 * time.Second
+
+	// SplitBrainPolicyAlertOnly logs and alerts when more than one peer is seen with the active identity
+	SplitBrainPolicyAlertOnly = "alert_only"
+	// SplitBrainPolicyDemoteSelfIfLowerPriority also demotes us when we are active and a peer also seen with the
+	// active identity outranks us by failover.peers priority
+	SplitBrainPolicyDemoteSelfIfLowerPriority = "demote_self_if_lower_priority"
+
+	// RPCOutagePolicyHold makes no failover decisions while the cluster rpc can't be reached
+	RPCOutagePolicyHold = "hold"
+	// RPCOutagePolicyProceed counts a refresh that can't reach the cluster rpc as a sample without an active peer
+	RPCOutagePolicyProceed = "proceed"
 )
 
 // Failover represents failover decision parameters
@@ -15,21 +36,95 @@ type Failover struct {
 	Active                     Role          `koanf:"active"`
 	Passive                    Role          `koanf:"passive"`
 	Peers                      Peers         `koanf:"peers"`
+	SampleHooks                []Hook        `koanf:"sample_hooks"`
+	SampleHookInterval         time.Duration `koanf:"sample_hook_interval"`
+	AdaptivePoll               bool          `koanf:"adaptive_poll"`
+	AdaptivePollMaxMultiplier  int           `koanf:"adaptive_poll_max_multiplier"`
+	DecisionLagWarnThreshold   time.Duration `koanf:"decision_lag_warn_threshold"`
+	ActionLagWarnThreshold     time.Duration `koanf:"action_lag_warn_threshold"`
+	// PostDemotionWatch is how long to watch the active identity's votes after a demotion, negative disables it
+	PostDemotionWatch time.Duration `koanf:"post_demotion_watch"`
+	// ConfirmWithPeerRPC requires every peer with an rpc_url to be unreachable or report a passive identity before taking over
+	ConfirmWithPeerRPC bool `koanf:"confirm_with_peer_rpc"`
+	// Heartbeat is the heartbeat agents serve and poll each other on
+	Heartbeat Heartbeat `koanf:"heartbeat"`
+	// RequireHeartbeatConfirmation requires every peer's heartbeat to be unreachable or report a role other than active
+	// before taking over
+	RequireHeartbeatConfirmation bool `koanf:"require_heartbeat_confirmation"`
+	// Coordination is an election backend a takeover must also win, on top of gossip
+	Coordination Coordination `koanf:"coordination"`
+	// MinRPCConfirmations is how many cluster.rpc_urls, queried in parallel, must agree the active peer is gone before
+	// a sample counts as leaderless - 1 queries one url at a time, failing over between them
+	MinRPCConfirmations int `koanf:"min_rpc_confirmations"`
+	// Cooldown is how long after completing a transition the agent refuses to start another, 0 never holds one back -
+	// demoting an active node that dropped out of gossip is never held back
+	Cooldown time.Duration `koanf:"cooldown"`
+	// TakeoverPriorityStagger is added to the takeover delay per rank below the highest priority passive in gossip,
+	// only when peers set a priority
+	TakeoverPriorityStagger time.Duration `koanf:"takeover_priority_stagger"`
+	// StartupGracePeriod is how long after startup the agent observes without becoming active, negative disables it -
+	// demoting to passive is never held back
+	StartupGracePeriod time.Duration `koanf:"startup_grace_period"`
+	// AllowUnknownIdentity lets the agent make failover decisions while the local validator reports an identity that
+	// is neither the active nor any passive one
+	AllowUnknownIdentity bool `koanf:"allow_unknown_identity"`
+	// IdentityVerifyTimeout is how long after a role command the agent polls the local validator for the expected
+	// identity before failing the transition, 0 checks once
+	IdentityVerifyTimeout time.Duration `koanf:"identity_verify_timeout"`
+	// IdentityVerifyInterval is how often the local validator's identity is polled while verifying a transition
+	IdentityVerifyInterval time.Duration `koanf:"identity_verify_interval"`
+	// TowerSync copies the tower file from the last known active peer before the active command runs
+	TowerSync TowerSync `koanf:"tower_sync"`
+	// DecisionLog appends every poll cycle's decision to a size rotated file
+	DecisionLog DecisionLog `koanf:"decision_log"`
+	// SplitBrainPolicy is what to do when more than one peer is seen with the active identity, one of alert_only or
+	// demote_self_if_lower_priority
+	SplitBrainPolicy string `koanf:"split_brain_policy"`
+	// OnRPCOutage is what to do while gossip can't be refreshed from the cluster rpc, one of hold or proceed
+	OnRPCOutage string `koanf:"on_rpc_outage"`
+	// MaxPauseDuration is the longest failover may be paused for maintenance before resuming on its own, also the
+	// duration of a pause that doesn't ask for one
+	MaxPauseDuration time.Duration `koanf:"max_pause_duration"`
+	// PostHookWorkers is how many post hooks not set async: false run at once in the background after a transition
+	PostHookWorkers int `koanf:"post_hook_workers"`
+	// PostHookDrainTimeout is how long shutdown waits for async post hooks still running
+	PostHookDrainTimeout time.Duration `koanf:"post_hook_drain_timeout"`
+	// AlertHooks run when the agent raises a critical alarm such as possible duplicate signing
+	AlertHooks []Hook `koanf:"alert_hooks"`
+	// ForcedDryRun is true when dry run was forced on from the command line, see Config.ForceDryRun
+	ForcedDryRun bool `koanf:"-"`
 }
 
 func (f *Failover) Validate() error {
-	// failover.poll_interval must be greater than zero
-	if f.PollIntervalDuration == 0 {
-		return fmt.Errorf("failover.poll_interval_duration must be greater than zero")
+	// failover.poll_interval_duration must be at least MinPollInterval
+	if f.PollIntervalDuration < MinPollInterval {
+		return fmt.Errorf("failover.poll_interval_duration must be at least %s, got %s - rpc endpoints rate limit faster polling, use e.g. 5s",
+			MinPollInterval, f.PollIntervalDuration)
 	}
 
-	// failover.leaderless_samples_threshold must be greater than zero
-	if f.LeaderlessSamplesThreshold <= 0 {
-		return fmt.Errorf("failover.leaderless_samples_threshold must be positive and non-zero")
+	// failover.leaderless_samples_threshold must be at least 1
+	if f.LeaderlessSamplesThreshold < 1 {
+		return fmt.Errorf("failover.leaderless_samples_threshold must be at least 1, got %d - it is how many consecutive leaderless samples trigger a failover",
+			f.LeaderlessSamplesThreshold)
 	}
 
-	// failover.active.command must be defined
-	if f.Active.Command == "" {
+	// failover.takeover_jitter_duration must be between 0 and MaxTakeoverJitter
+	if f.TakeoverJitterDuration < 0 || f.TakeoverJitterDuration > MaxTakeoverJitter {
+		return fmt.Errorf("failover.takeover_jitter_duration must be between 0s and %s, got %s", MaxTakeoverJitter, f.TakeoverJitterDuration)
+	}
+
+	// failover.min_rpc_confirmations must not be negative
+	if f.MinRPCConfirmations < 0 {
+		return fmt.Errorf("failover.min_rpc_confirmations must not be negative, got %d", f.MinRPCConfirmations)
+	}
+
+	// failover.active.method must be valid and agave_admin_rpc must know where the admin rpc socket is
+	if err := f.Active.validateMethod(); err != nil {
+		return fmt.Errorf("failover.active.%w", err)
+	}
+
+	// failover.active.command must be defined unless failover.active.method is agave_admin_rpc
+	if f.Active.Command == "" && !f.Active.UsesAdminRPC() {
 		return fmt.Errorf("failover.active.command must be defined")
 	}
 
@@ -53,8 +148,13 @@ func (f *Failover) Validate() error {
 		}
 	}
 
-	// failover.passive.command must be defined
-	if f.Passive.Command == "" {
+	// failover.passive.method must be valid and agave_admin_rpc must know where the admin rpc socket is
+	if err := f.Passive.validateMethod(); err != nil {
+		return fmt.Errorf("failover.passive.%w", err)
+	}
+
+	// failover.passive.command must be defined unless failover.passive.method is agave_admin_rpc
+	if f.Passive.Command == "" && !f.Passive.UsesAdminRPC() {
 		return fmt.Errorf("failover.passive.command must be defined")
 	}
 
@@ -78,21 +178,159 @@ func (f *Failover) Validate() error {
 		}
 	}
 
+	// failover.adaptive_poll_max_multiplier must be at least 1 when adaptive polling is enabled - 1 effectively disables stretching
+	if f.AdaptivePoll && f.AdaptivePollMaxMultiplier < 1 {
+		return fmt.Errorf("failover.adaptive_poll_max_multiplier must be at least 1 - got %d", f.AdaptivePollMaxMultiplier)
+	}
+
+	// failover.decision_lag_warn_threshold and failover.action_lag_warn_threshold must not be negative
+	if f.DecisionLagWarnThreshold < 0 {
+		return fmt.Errorf("failover.decision_lag_warn_threshold must not be negative")
+	}
+	if f.ActionLagWarnThreshold < 0 {
+		return fmt.Errorf("failover.action_lag_warn_threshold must not be negative")
+	}
+
+	// failover.takeover_priority_stagger must not be negative
+	if f.TakeoverPriorityStagger < 0 {
+		return fmt.Errorf("failover.takeover_priority_stagger must not be negative, got %s", f.TakeoverPriorityStagger)
+	}
+
+	// failover.cooldown must not be negative
+	if f.Cooldown < 0 {
+		return fmt.Errorf("failover.cooldown must not be negative, got %s", f.Cooldown)
+	}
+
+	// failover.identity_verify_timeout must not be negative
+	if f.IdentityVerifyTimeout < 0 {
+		return fmt.Errorf("failover.identity_verify_timeout must not be negative, got %s", f.IdentityVerifyTimeout)
+	}
+
+	// failover.identity_verify_interval must be positive when verifying for longer than one check
+	if f.IdentityVerifyInterval < 0 || (f.IdentityVerifyTimeout > 0 && f.IdentityVerifyInterval == 0) {
+		return fmt.Errorf("failover.identity_verify_interval must be positive, got %s", f.IdentityVerifyInterval)
+	}
+
+	// failover.tower_sync must be valid if enabled
+	if err := f.TowerSync.Validate(); err != nil {
+		return err
+	}
+
+	// failover.decision_log must be valid if enabled
+	if err := f.DecisionLog.Validate(); err != nil {
+		return err
+	}
+
+	// failover.heartbeat must be valid if enabled, and enabled to require heartbeat confirmation
+	if err := f.Heartbeat.Validate(f.PollIntervalDuration); err != nil {
+		return err
+	}
+	if f.RequireHeartbeatConfirmation && !f.Heartbeat.Enabled {
+		return fmt.Errorf("failover.require_heartbeat_confirmation requires failover.heartbeat.enabled")
+	}
+
+	// failover.coordination must be valid if configured
+	if err := f.Coordination.Validate(); err != nil {
+		return err
+	}
+
+	// failover.max_pause_duration must be positive
+	if f.MaxPauseDuration < 0 {
+		return fmt.Errorf("failover.max_pause_duration must be positive, got %s", f.MaxPauseDuration)
+	}
+
+	// failover.post_hook_workers and failover.post_hook_drain_timeout must be positive
+	if f.PostHookWorkers < 0 {
+		return fmt.Errorf("failover.post_hook_workers must be positive, got %d", f.PostHookWorkers)
+	}
+	if f.PostHookDrainTimeout < 0 {
+		return fmt.Errorf("failover.post_hook_drain_timeout must be positive, got %s", f.PostHookDrainTimeout)
+	}
+
+	// failover.split_brain_policy must be alert_only or demote_self_if_lower_priority
+	switch f.SplitBrainPolicy {
+	case "", SplitBrainPolicyAlertOnly, SplitBrainPolicyDemoteSelfIfLowerPriority:
+	default:
+		return fmt.Errorf("failover.split_brain_policy must be one of %s or %s, got %q",
+			SplitBrainPolicyAlertOnly, SplitBrainPolicyDemoteSelfIfLowerPriority, f.SplitBrainPolicy)
+	}
+
+	// failover.on_rpc_outage must be hold or proceed
+	switch f.OnRPCOutage {
+	case "", RPCOutagePolicyHold, RPCOutagePolicyProceed:
+	default:
+		return fmt.Errorf("failover.on_rpc_outage must be one of %s or %s, got %q", RPCOutagePolicyHold, RPCOutagePolicyProceed, f.OnRPCOutage)
+	}
+
+	// failover.sample_hooks must be valid if defined
+	if err := f.validateSampleHooks(); err != nil {
+		return err
+	}
+
+	// failover.alert_hooks must all be valid if defined
+	for i, hook := range f.AlertHooks {
+		if hook.MustSucceed {
+			return fmt.Errorf("failover.alert_hooks[%d]: must_succeed is not allowed for alert hooks", i)
+		}
+		if err := hook.Validate(false); err != nil {
+			return fmt.Errorf("failover.alert_hooks[%d]: %w", i, err)
+		}
+	}
+
 	// failover.peers must be at least 1
 	if len(f.Peers) == 0 {
 		return fmt.Errorf("failover.peers - at least one peer must be defined")
 	}
 
-	// failover.peers must have unique valid IP addresses
-	ips := make(map[string]bool)
-	for name, peer := range f.Peers {
-		if net.ParseIP(peer.IP) == nil || net.ParseIP(peer.IP).To4() == nil {
-			return fmt.Errorf("failover.peers - invalid IP address %s for peer %s", peer.IP, name)
-		}
-		if ips[peer.IP] {
-			return fmt.Errorf("failover.peers - duplicate IP address %s found for peer %s", peer.IP, name)
-		}
-		ips[peer.IP] = true
+	// failover.peers must have unique valid names and IP addresses
+	return f.Peers.Validate()
+}
+
+// validateLoadedFailover validates the failover values as loaded from the config file, before defaults replace
+// their zero values - poll_interval_duration and leaderless_samples_threshold must not be set to zero, which would
+// otherwise silently be the default
+func validateLoadedFailover(k *koanf.Koanf) error {
+	// failover.poll_interval_duration must not be set to zero
+	if k.Exists("failover.poll_interval_duration") && k.Duration("failover.poll_interval_duration") == 0 {
+		return fmt.Errorf("failover.poll_interval_duration must be at least %s, got 0s - remove it to poll every 5s", MinPollInterval)
+	}
+
+	// failover.leaderless_samples_threshold must not be set to zero
+	if k.Exists("failover.leaderless_samples_threshold") && k.Int("failover.leaderless_samples_threshold") == 0 {
+		return fmt.Errorf("failover.leaderless_samples_threshold must be at least 1, got 0 - remove it to fail over after 3 leaderless samples")
+	}
+
+	return nil
+}
+
+// validateSampleHooks validates the per-cycle observational hooks - these run every sample_hook_interval
+// in the background so the rules are strict to keep them from becoming a foot-gun
+func (f *Failover) validateSampleHooks() error {
+	if len(f.SampleHooks) == 0 {
+		return nil
+	}
+
+	// failover.sample_hooks allows at most one hook
+	if len(f.SampleHooks) > 1 {
+		return fmt.Errorf("failover.sample_hooks allows at most one hook - got %d", len(f.SampleHooks))
+	}
+
+	// failover.sample_hooks[0] must have a name and command and must not set must_succeed
+	hook := f.SampleHooks[0]
+	if hook.MustSucceed {
+		return fmt.Errorf("failover.sample_hooks[0]: must_succeed is not allowed for sample hooks")
+	}
+	if err := hook.Validate(false); err != nil {
+		return fmt.Errorf("failover.sample_hooks[0]: %w", err)
+	}
+
+	// failover.sample_hook_interval is mandatory and must not be shorter than the poll interval
+	if f.SampleHookInterval == 0 {
+		return fmt.Errorf("failover.sample_hook_interval must be set when failover.sample_hooks is defined")
+	}
+	if f.SampleHookInterval < f.PollIntervalDuration {
+		return fmt.Errorf("failover.sample_hook_interval (%s) must be greater than or equal to failover.poll_interval_duration (%s)",
+			f.SampleHookInterval, f.PollIntervalDuration)
 	}
 
 	return nil
@@ -100,6 +338,10 @@ func (f *Failover) Validate() error {
 
 // RenderRoleCommands renders the failover commands for a given role if they have templated strings
 func (f *Failover) RenderRoleCommands(data RoleCommandTemplateData) (err error) {
+	// the admin rpc sets each role's identity from its primary keypair file
+	f.Active.IdentityKeypairFile = data.ActiveIdentityKeypairFile
+	f.Passive.IdentityKeypairFile = data.PassiveIdentityKeypairFile
+
 	err = f.Active.RenderCommands(data)
 	if err != nil {
 		return fmt.Errorf("failed to render command template strings for failover.active.command: %w", err)
@@ -110,6 +352,25 @@ func (f *Failover) RenderRoleCommands(data RoleCommandTemplateData) (err error)
 		return fmt.Errorf("failed to render command template strings for failover.passive.command: %w", err)
 	}
 
+	err = f.TowerSync.Render(data)
+	if err != nil {
+		return err
+	}
+
+	for i := range f.SampleHooks {
+		err = renderHook(data, &f.SampleHooks[i])
+		if err != nil {
+			return fmt.Errorf("failed to render failover.sample_hooks[%d]: %w", i, err)
+		}
+	}
+
+	for i := range f.AlertHooks {
+		err = renderHook(data, &f.AlertHooks[i])
+		if err != nil {
+			return fmt.Errorf("failed to render failover.alert_hooks[%d]: %w", i, err)
+		}
+	}
+
 	return nil
 }
 
@@ -125,8 +386,68 @@ func (f *Failover) SetDefaults() {
 	if f.TakeoverJitterDuration == 0 {
 		f.TakeoverJitterDuration = 3 * time.Second
 	}
+	if f.TakeoverPriorityStagger == 0 {
+		f.TakeoverPriorityStagger = 5 * time.Second // longer than the default jitter so claims never overlap
+	}
+	if f.DecisionLagWarnThreshold == 0 {
+		f.DecisionLagWarnThreshold = 2 * time.Second
+	}
+	f.Peers.SetDefaults()
+	if f.ActionLagWarnThreshold == 0 {
+		f.ActionLagWarnThreshold = time.Second
+	}
+	if f.AdaptivePollMaxMultiplier == 0 {
+		f.AdaptivePollMaxMultiplier = 4 // 4 x poll interval = 20 seconds at most by default
+	}
+	if f.PostDemotionWatch == 0 {
+		f.PostDemotionWatch = 5 * time.Minute
+	}
+	if f.MinRPCConfirmations == 0 {
+		f.MinRPCConfirmations = 1
+	}
+	if f.StartupGracePeriod == 0 {
+		// long enough to have seen the threshold of samples ourselves
+		f.StartupGracePeriod = time.Duration(f.LeaderlessSamplesThreshold) * f.PollIntervalDuration
+	}
+	if f.IdentityVerifyTimeout == 0 {
+		f.IdentityVerifyTimeout = 30 * time.Second // a restarting validator can take a while to answer with its new identity
+	}
+	if f.IdentityVerifyInterval == 0 {
+		f.IdentityVerifyInterval = time.Second
+	}
+	if f.MaxPauseDuration == 0 {
+		f.MaxPauseDuration = time.Hour
+	}
+	if f.PostHookWorkers == 0 {
+		f.PostHookWorkers = DefaultPostHookWorkers
+	}
+	if f.PostHookDrainTimeout == 0 {
+		f.PostHookDrainTimeout = 10 * time.Second
+	}
+	if f.SplitBrainPolicy == "" {
+		f.SplitBrainPolicy = SplitBrainPolicyAlertOnly
+	}
+	if f.OnRPCOutage == "" {
+		f.OnRPCOutage = RPCOutagePolicyHold
+	}
+	f.Heartbeat.SetDefaults()
+	f.Coordination.SetDefaults()
 
-	// Set role names
+	// Set role names - the built-in clients' ready-made args depend on them
 	f.Active.Name = "active"
 	f.Passive.Name = "passive"
+
+	// hooks are killed after their timeout
+	f.Active.Hooks.SetDefaults()
+	f.Passive.Hooks.SetDefaults()
+	f.Active.SetDefaults()
+	f.Passive.SetDefaults()
+	f.TowerSync.SetDefaults()
+	f.DecisionLog.SetDefaults()
+	for i := range f.SampleHooks {
+		f.SampleHooks[i].SetDefaults()
+	}
+	for i := range f.AlertHooks {
+		f.AlertHooks[i].SetDefaults()
+	}
 }
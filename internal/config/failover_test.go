@@ -1,10 +1,13 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFailover_SetDefaults(t *testing.T) {
@@ -15,6 +18,215 @@ func TestFailover_SetDefaults(t *testing.T) {
 	assert.Equal(t, 5*time.Second, failover.PollIntervalDuration)
 	assert.Equal(t, 3, failover.LeaderlessSamplesThreshold)
 	assert.Equal(t, 3*time.Second, failover.TakeoverJitterDuration)
+	assert.Equal(t, 2*time.Second, failover.DecisionLagWarnThreshold)
+	assert.Equal(t, time.Second, failover.ActionLagWarnThreshold)
+	assert.Equal(t, 5*time.Minute, failover.PostDemotionWatch)
+	assert.Equal(t, 1, failover.MinRPCConfirmations)
+	assert.Zero(t, failover.Cooldown)
+	assert.Equal(t, 5*time.Second, failover.TakeoverPriorityStagger)
+	assert.Equal(t, 15*time.Second, failover.StartupGracePeriod)
+	assert.Equal(t, 30*time.Second, failover.IdentityVerifyTimeout)
+	assert.Equal(t, time.Second, failover.IdentityVerifyInterval)
+	assert.Equal(t, SplitBrainPolicyAlertOnly, failover.SplitBrainPolicy)
+	assert.Equal(t, RPCOutagePolicyHold, failover.OnRPCOutage)
+	assert.Equal(t, time.Hour, failover.MaxPauseDuration)
+	assert.Equal(t, DefaultPostHookWorkers, failover.PostHookWorkers)
+	assert.Equal(t, 10*time.Second, failover.PostHookDrainTimeout)
+
+	// the grace period follows a configured threshold and poll interval, an explicit one is kept
+	failover = &Failover{PollIntervalDuration: 2 * time.Second, LeaderlessSamplesThreshold: 5}
+	failover.SetDefaults()
+	assert.Equal(t, 10*time.Second, failover.StartupGracePeriod)
+
+	failover = &Failover{StartupGracePeriod: -time.Second}
+	failover.SetDefaults()
+	assert.Equal(t, -time.Second, failover.StartupGracePeriod)
+}
+
+func TestFailover_Validate_Cooldown(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       5 * time.Second,
+		LeaderlessSamplesThreshold: 3,
+		Active:                     Role{Command: "true"},
+		Passive:                    Role{Command: "true"},
+		Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+		Cooldown:                   -time.Minute,
+	}
+	assert.EqualError(t, failover.Validate(), "failover.cooldown must not be negative, got -1m0s")
+
+	failover.Cooldown = 10 * time.Minute
+	assert.NoError(t, failover.Validate())
+}
+
+func TestFailover_Validate_MaxPauseDuration(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       5 * time.Second,
+		LeaderlessSamplesThreshold: 3,
+		Active:                     Role{Command: "true"},
+		Passive:                    Role{Command: "true"},
+		Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+		MaxPauseDuration:           -time.Minute,
+	}
+	assert.EqualError(t, failover.Validate(), "failover.max_pause_duration must be positive, got -1m0s")
+
+	failover.MaxPauseDuration = 30 * time.Minute
+	assert.NoError(t, failover.Validate())
+}
+
+func TestFailover_Validate_PostHookPool(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       5 * time.Second,
+		LeaderlessSamplesThreshold: 3,
+		Active:                     Role{Command: "true"},
+		Passive:                    Role{Command: "true"},
+		Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+		PostHookWorkers:            -1,
+	}
+	assert.EqualError(t, failover.Validate(), "failover.post_hook_workers must be positive, got -1")
+
+	failover.PostHookWorkers = 2
+	failover.PostHookDrainTimeout = -time.Second
+	assert.EqualError(t, failover.Validate(), "failover.post_hook_drain_timeout must be positive, got -1s")
+
+	failover.PostHookDrainTimeout = 5 * time.Second
+	assert.NoError(t, failover.Validate())
+}
+
+func TestFailover_Validate_TakeoverPriorityStagger(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       5 * time.Second,
+		LeaderlessSamplesThreshold: 3,
+		Active:                     Role{Command: "true"},
+		Passive:                    Role{Command: "true"},
+		Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+		TakeoverPriorityStagger:    -time.Second,
+	}
+	assert.EqualError(t, failover.Validate(), "failover.takeover_priority_stagger must not be negative, got -1s")
+
+	failover.TakeoverPriorityStagger = 5 * time.Second
+	assert.NoError(t, failover.Validate())
+}
+
+func TestFailover_Validate_IdentityVerify(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       5 * time.Second,
+		LeaderlessSamplesThreshold: 3,
+		Active:                     Role{Command: "true"},
+		Passive:                    Role{Command: "true"},
+		Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+		IdentityVerifyTimeout:      -time.Second,
+	}
+	assert.EqualError(t, failover.Validate(), "failover.identity_verify_timeout must not be negative, got -1s")
+
+	// a single check needs no interval
+	failover.IdentityVerifyTimeout = 0
+	assert.NoError(t, failover.Validate())
+
+	failover.IdentityVerifyTimeout = 30 * time.Second
+	assert.EqualError(t, failover.Validate(), "failover.identity_verify_interval must be positive, got 0s")
+
+	failover.IdentityVerifyInterval = time.Second
+	assert.NoError(t, failover.Validate())
+}
+
+func TestFailover_Validate_SplitBrainPolicy(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       5 * time.Second,
+		LeaderlessSamplesThreshold: 3,
+		Active:                     Role{Command: "true"},
+		Passive:                    Role{Command: "true"},
+		Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+		SplitBrainPolicy:           "demote_both",
+	}
+	assert.EqualError(t, failover.Validate(), `failover.split_brain_policy must be one of alert_only or demote_self_if_lower_priority, got "demote_both"`)
+
+	failover.SplitBrainPolicy = SplitBrainPolicyDemoteSelfIfLowerPriority
+	assert.NoError(t, failover.Validate())
+}
+
+func TestFailover_Validate_OnRPCOutage(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       5 * time.Second,
+		LeaderlessSamplesThreshold: 3,
+		Active:                     Role{Command: "true"},
+		Passive:                    Role{Command: "true"},
+		Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+		OnRPCOutage:                "failover",
+	}
+	assert.EqualError(t, failover.Validate(), `failover.on_rpc_outage must be one of hold or proceed, got "failover"`)
+
+	failover.OnRPCOutage = RPCOutagePolicyProceed
+	assert.NoError(t, failover.Validate())
+}
+
+func TestFailover_Validate_RequireHeartbeatConfirmation(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:         5 * time.Second,
+		LeaderlessSamplesThreshold:   3,
+		Active:                       Role{Command: "true"},
+		Passive:                      Role{Command: "true"},
+		Peers:                        Peers{"validator-1": {IP: "192.168.1.10"}},
+		RequireHeartbeatConfirmation: true,
+	}
+	assert.EqualError(t, failover.Validate(), "failover.require_heartbeat_confirmation requires failover.heartbeat.enabled")
+
+	failover.Heartbeat = Heartbeat{Enabled: true, Auth: &Auth{BearerToken: "secret"}, Timeout: time.Second}
+	assert.NoError(t, failover.Validate())
+}
+
+func TestFailover_Validate_AdminRPCMethod(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       5 * time.Second,
+		LeaderlessSamplesThreshold: 3,
+		Active:                     Role{Method: RoleMethodAgaveAdminRPC},
+		Passive:                    Role{Command: "true"},
+		Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+	}
+	assert.EqualError(t, failover.Validate(), "failover.active.admin_rpc.ledger_path or admin_rpc.socket_path must be defined when method is agave_admin_rpc")
+
+	// no command is needed
+	failover.Active.AdminRPC.LedgerPath = "/mnt/ledger"
+	assert.NoError(t, failover.Validate())
+
+	failover.Passive.Method = "ssh"
+	assert.EqualError(t, failover.Validate(), `failover.passive.method must be one of command or agave_admin_rpc, got "ssh"`)
+}
+
+func TestFailover_Validate_LagWarnThresholds(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       5 * time.Second,
+		LeaderlessSamplesThreshold: 3,
+		Active:                     Role{Command: "true"},
+		Passive:                    Role{Command: "true"},
+		Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+		DecisionLagWarnThreshold:   -time.Second,
+	}
+	err := failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.decision_lag_warn_threshold must not be negative")
+
+	failover.DecisionLagWarnThreshold = time.Second
+	failover.ActionLagWarnThreshold = -time.Second
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.action_lag_warn_threshold must not be negative")
+}
+
+func TestFailover_Validate_MinRPCConfirmations(t *testing.T) {
+	failover := &Failover{
+		PollIntervalDuration:       5 * time.Second,
+		LeaderlessSamplesThreshold: 3,
+		Active:                     Role{Command: "true"},
+		Passive:                    Role{Command: "true"},
+		Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+		MinRPCConfirmations:        -1,
+	}
+	err := failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.min_rpc_confirmations must not be negative, got -1")
+
+	failover.MinRPCConfirmations = 2
+	assert.NoError(t, failover.Validate())
 }
 
 func TestFailover_Validate(t *testing.T) {
@@ -43,14 +255,14 @@ func TestFailover_Validate(t *testing.T) {
 	failover.PollIntervalDuration = 0
 	err = failover.Validate()
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "failover.poll_interval_duration must be greater than zero")
+	assert.Contains(t, err.Error(), "failover.poll_interval_duration must be at least 1s, got 0s")
 
 	// Test with zero leaderless samples threshold
 	failover.PollIntervalDuration = 30 * time.Second
 	failover.LeaderlessSamplesThreshold = 0
 	err = failover.Validate()
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "failover.leaderless_samples_threshold must be positive and non-zero")
+	assert.Contains(t, err.Error(), "failover.leaderless_samples_threshold must be at least 1, got 0")
 
 	// Test with empty active command
 	failover.LeaderlessSamplesThreshold = 10
@@ -139,3 +351,172 @@ func TestFailover_ValidateWithHooks(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failover.active.hooks.pre must have a command")
 }
+
+func TestFailover_ValidateSampleHooks(t *testing.T) {
+	newFailover := func() *Failover {
+		return &Failover{
+			PollIntervalDuration:       5 * time.Second,
+			LeaderlessSamplesThreshold: 3,
+			Active:                     Role{Command: "true"},
+			Passive:                    Role{Command: "true"},
+			Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+			SampleHooks:                []Hook{{Name: "record-decision", Command: "/usr/local/bin/record"}},
+			SampleHookInterval:         time.Minute,
+		}
+	}
+
+	// valid
+	assert.NoError(t, newFailover().Validate())
+
+	// interval equal to poll interval is allowed
+	failover := newFailover()
+	failover.SampleHookInterval = failover.PollIntervalDuration
+	assert.NoError(t, failover.Validate())
+
+	// more than one hook
+	failover = newFailover()
+	failover.SampleHooks = append(failover.SampleHooks, Hook{Name: "second", Command: "true"})
+	err := failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.sample_hooks allows at most one hook - got 2")
+
+	// must_succeed is not allowed
+	failover = newFailover()
+	failover.SampleHooks[0].MustSucceed = true
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must_succeed is not allowed for sample hooks")
+
+	// name and command required
+	failover = newFailover()
+	failover.SampleHooks[0].Command = ""
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.sample_hooks[0]: must have a command")
+
+	// interval is mandatory
+	failover = newFailover()
+	failover.SampleHookInterval = 0
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.sample_hook_interval must be set")
+
+	// interval must not be shorter than the poll interval
+	failover = newFailover()
+	failover.SampleHookInterval = time.Second
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must be greater than or equal to failover.poll_interval_duration")
+
+	// no sample hooks - no interval required
+	failover = newFailover()
+	failover.SampleHooks = nil
+	failover.SampleHookInterval = 0
+	assert.NoError(t, failover.Validate())
+}
+
+func TestFailover_ValidateAlertHooks(t *testing.T) {
+	newFailover := func() *Failover {
+		return &Failover{
+			PollIntervalDuration:       5 * time.Second,
+			LeaderlessSamplesThreshold: 3,
+			Active:                     Role{Command: "true"},
+			Passive:                    Role{Command: "true"},
+			Peers:                      Peers{"validator-1": {IP: "192.168.1.10"}},
+			AlertHooks: []Hook{
+				{Name: "page-oncall", Command: "/usr/local/bin/page"},
+				{Name: "notify-slack", Command: "/usr/local/bin/notify"},
+			},
+		}
+	}
+
+	// valid - more than one hook is allowed
+	assert.NoError(t, newFailover().Validate())
+
+	// must_succeed is not allowed
+	failover := newFailover()
+	failover.AlertHooks[1].MustSucceed = true
+	err := failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.alert_hooks[1]: must_succeed is not allowed for alert hooks")
+
+	// name and command required
+	failover = newFailover()
+	failover.AlertHooks[0].Command = ""
+	err = failover.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failover.alert_hooks[0]: must have a command")
+}
+
+func TestFailover_Validate_TimingBounds(t *testing.T) {
+	newFailover := func() *Failover {
+		failover := &Failover{
+			Active:  Role{Command: "systemctl start solana"},
+			Passive: Role{Command: "systemctl stop solana"},
+			Peers:   Peers{"validator-1": {IP: "192.168.1.10"}},
+		}
+		failover.SetDefaults()
+		return failover
+	}
+	require.NoError(t, newFailover().Validate())
+
+	t.Run("poll_interval_duration", func(t *testing.T) {
+		failover := newFailover()
+		failover.PollIntervalDuration = 50 * time.Millisecond
+		assert.EqualError(t, failover.Validate(), "failover.poll_interval_duration must be at least 1s, got 50ms - rpc endpoints rate limit faster polling, use e.g. 5s")
+		failover.PollIntervalDuration = -time.Second
+		assert.ErrorContains(t, failover.Validate(), "failover.poll_interval_duration must be at least 1s, got -1s")
+		failover.PollIntervalDuration = time.Second
+		assert.NoError(t, failover.Validate())
+	})
+
+	t.Run("leaderless_samples_threshold", func(t *testing.T) {
+		failover := newFailover()
+		failover.LeaderlessSamplesThreshold = -1
+		assert.ErrorContains(t, failover.Validate(), "failover.leaderless_samples_threshold must be at least 1, got -1")
+		failover.LeaderlessSamplesThreshold = 1
+		assert.NoError(t, failover.Validate())
+	})
+
+	t.Run("takeover_jitter_duration", func(t *testing.T) {
+		failover := newFailover()
+		failover.TakeoverJitterDuration = -time.Second
+		assert.EqualError(t, failover.Validate(), "failover.takeover_jitter_duration must be between 0s and 5m0s, got -1s")
+		failover.TakeoverJitterDuration = 301 * time.Second
+		assert.EqualError(t, failover.Validate(), "failover.takeover_jitter_duration must be between 0s and 5m0s, got 5m1s")
+		failover.TakeoverJitterDuration = 300 * time.Second
+		failover.TakeoverPriorityStagger = time.Hour
+		assert.NoError(t, failover.Validate())
+	})
+
+	t.Run("cooldown", func(t *testing.T) {
+		failover := newFailover()
+		failover.Cooldown = -time.Minute
+		assert.EqualError(t, failover.Validate(), "failover.cooldown must not be negative, got -1m0s")
+	})
+}
+
+func TestValidateLoadedFailover(t *testing.T) {
+	load := func(t *testing.T, yaml string) error {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(yaml), 0o600))
+		return (&Config{}).LoadFromFile(path)
+	}
+
+	assert.NoError(t, load(t, "failover:\n  poll_interval_duration: 5s\n  cooldown: 0\n  startup_grace_period: -1s\n"))
+
+	// bare numbers are nanoseconds
+	assert.EqualError(t, load(t, "failover:\n  cooldown: 300\n"),
+		"failover.cooldown must be a duration with a unit like 300s or 300m, got 300 - a bare number is nanoseconds")
+	assert.EqualError(t, load(t, "failover:\n  startup_grace_period: 5 minutes\n"),
+		`failover.startup_grace_period must be a duration like 30s, 5m or 1h30m, got "5 minutes"`)
+
+	// zero would otherwise be the default
+	assert.EqualError(t, load(t, "failover:\n  poll_interval_duration: 0s\n"),
+		"failover.poll_interval_duration must be at least 1s, got 0s - remove it to poll every 5s")
+	assert.EqualError(t, load(t, "failover:\n  poll_interval_duration: 0\n"),
+		"failover.poll_interval_duration must be at least 1s, got 0s - remove it to poll every 5s")
+	assert.EqualError(t, load(t, "failover:\n  leaderless_samples_threshold: 0\n"),
+		"failover.leaderless_samples_threshold must be at least 1, got 0 - remove it to fail over after 3 leaderless samples")
+}
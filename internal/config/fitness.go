@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultFitnessPeerTimeout is the default time to wait for a peer's advertised fitness
+	DefaultFitnessPeerTimeout = 500 * time.Millisecond
+	// DefaultFitnessMaxSlotLag is the default slot lag at which the full slot lag penalty applies
+	DefaultFitnessMaxSlotLag = 150
+	// DefaultFitnessMinDiskFreePercent is the default free disk percent below which a penalty applies
+	DefaultFitnessMinDiskFreePercent = 10
+	// DefaultFitnessRestartWindow is the default window agent restarts are counted in
+	DefaultFitnessRestartWindow = time.Hour
+)
+
+// Fitness represents the dynamic takeover fitness configuration - when enabled each agent advertises a
+// 0-100 fitness score to its peers and passives claim in score order during takeover
+type Fitness struct {
+	// Enabled turns on fitness scoring and score ordered takeover arbitration
+	Enabled bool `koanf:"enabled"`
+	// PeerTimeout is how long to wait for a peer's advertised fitness before falling back to static priority
+	PeerTimeout time.Duration `koanf:"peer_timeout"`
+	// MaxSlotLag is the local validator slot lag at which the full slot lag penalty applies
+	MaxSlotLag uint64 `koanf:"max_slot_lag"`
+	// DiskPath is an optional path whose filesystem free space counts towards the score, e.g. the ledger
+	DiskPath string `koanf:"disk_path"`
+	// MinDiskFreePercent is the free space on disk_path below which a penalty applies
+	MinDiskFreePercent float64 `koanf:"min_disk_free_percent"`
+	// RestartWindow is how far back agent restarts are counted
+	RestartWindow time.Duration `koanf:"restart_window"`
+}
+
+// Validate validates the fitness configuration
+func (f *Fitness) Validate() error {
+	if !f.Enabled {
+		return nil
+	}
+
+	// fitness.peer_timeout must be positive
+	if f.PeerTimeout <= 0 {
+		return fmt.Errorf("fitness.peer_timeout must be positive and non-zero")
+	}
+
+	// fitness.max_slot_lag must be positive
+	if f.MaxSlotLag == 0 {
+		return fmt.Errorf("fitness.max_slot_lag must be positive and non-zero")
+	}
+
+	// fitness.min_disk_free_percent must be a percentage
+	if f.MinDiskFreePercent <= 0 || f.MinDiskFreePercent > 100 {
+		return fmt.Errorf("fitness.min_disk_free_percent must be greater than 0 and at most 100 - got: %v", f.MinDiskFreePercent)
+	}
+
+	// fitness.restart_window must be positive
+	if f.RestartWindow <= 0 {
+		return fmt.Errorf("fitness.restart_window must be positive and non-zero")
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the fitness configuration
+func (f *Fitness) SetDefaults() {
+	if f.PeerTimeout == 0 {
+		f.PeerTimeout = DefaultFitnessPeerTimeout
+	}
+	if f.MaxSlotLag == 0 {
+		f.MaxSlotLag = DefaultFitnessMaxSlotLag
+	}
+	if f.MinDiskFreePercent == 0 {
+		f.MinDiskFreePercent = DefaultFitnessMinDiskFreePercent
+	}
+	if f.RestartWindow == 0 {
+		f.RestartWindow = DefaultFitnessRestartWindow
+	}
+}
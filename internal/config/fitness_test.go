@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFitness_SetDefaults(t *testing.T) {
+	fitness := &Fitness{}
+	fitness.SetDefaults()
+
+	assert.False(t, fitness.Enabled)
+	assert.Equal(t, 500*time.Millisecond, fitness.PeerTimeout)
+	assert.Equal(t, uint64(150), fitness.MaxSlotLag)
+	assert.Equal(t, float64(10), fitness.MinDiskFreePercent)
+	assert.Equal(t, time.Hour, fitness.RestartWindow)
+	assert.Empty(t, fitness.DiskPath)
+}
+
+func TestFitness_Validate(t *testing.T) {
+	// disabled fitness is not validated
+	fitness := &Fitness{}
+	assert.NoError(t, fitness.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(f *Fitness)
+		errMsg string
+	}{
+		{name: "valid", modify: func(f *Fitness) {}},
+		{name: "zero peer timeout", modify: func(f *Fitness) { f.PeerTimeout = 0 }, errMsg: "fitness.peer_timeout must be positive"},
+		{name: "zero max slot lag", modify: func(f *Fitness) { f.MaxSlotLag = 0 }, errMsg: "fitness.max_slot_lag must be positive"},
+		{name: "disk free above 100", modify: func(f *Fitness) { f.MinDiskFreePercent = 101 }, errMsg: "fitness.min_disk_free_percent must be greater than 0 and at most 100"},
+		{name: "negative restart window", modify: func(f *Fitness) { f.RestartWindow = -time.Second }, errMsg: "fitness.restart_window must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fitness := &Fitness{Enabled: true}
+			fitness.SetDefaults()
+			tt.modify(fitness)
+
+			err := fitness.Validate()
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
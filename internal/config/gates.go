@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/gates"
+)
+
+const (
+	// GateHealth is the name of the local validator health safety gate
+	GateHealth = "health"
+	// GateSlotLag is the name of the slot lag safety gate
+	GateSlotLag = "slot_lag"
+	// GateDiskSpace is the name of the disk space safety gate
+	GateDiskSpace = "disk_space"
+	// GateVoteAccount is the name of the vote account identity safety gate
+	GateVoteAccount = "vote_account"
+)
+
+// Gates represents the safety gates checked before taking over as active - each is off, warn or enforce so a
+// new gate can be run in warn mode to see what it would have blocked before enforcing it
+type Gates struct {
+	// Health blocks promotion while the local validator's getHealth is not ok
+	Health HealthGate `koanf:"health"`
+	// SlotLag blocks promotion while the local validator lags the cluster by more than max_slot_lag
+	SlotLag SlotLagGate `koanf:"slot_lag"`
+	// DiskSpace blocks promotion while free space on path is below min_free_percent
+	DiskSpace DiskSpaceGate `koanf:"disk_space"`
+	// VoteAccount blocks promotion while validator.vote_account's node identity is not the active identity
+	VoteAccount VoteAccountGate `koanf:"vote_account"`
+}
+
+// HealthGate represents the local validator health safety gate
+type HealthGate struct {
+	Mode gates.Mode `koanf:"mode"`
+}
+
+// SlotLagGate represents the slot lag safety gate
+type SlotLagGate struct {
+	Mode gates.Mode `koanf:"mode"`
+	// MaxSlotLag is the most slots the local validator may lag the cluster by
+	MaxSlotLag uint64 `koanf:"max_slot_lag"`
+}
+
+// DiskSpaceGate represents the disk space safety gate
+type DiskSpaceGate struct {
+	Mode gates.Mode `koanf:"mode"`
+	// Path is the path whose filesystem free space is checked, e.g. the ledger
+	Path string `koanf:"path"`
+	// MinFreePercent is the least free space percentage allowed
+	MinFreePercent float64 `koanf:"min_free_percent"`
+}
+
+// VoteAccountGate represents the vote account identity safety gate, only checked with validator.vote_account set
+type VoteAccountGate struct {
+	Mode gates.Mode `koanf:"mode"`
+}
+
+// Modes returns the mode of every gate by name
+func (g *Gates) Modes() map[string]gates.Mode {
+	return map[string]gates.Mode{
+		GateHealth:      g.Health.Mode,
+		GateSlotLag:     g.SlotLag.Mode,
+		GateDiskSpace:   g.DiskSpace.Mode,
+		GateVoteAccount: g.VoteAccount.Mode,
+	}
+}
+
+// Validate validates the gates configuration
+func (g *Gates) Validate() error {
+	// gates.health.mode must be a valid mode
+	if err := g.Health.Mode.Validate("gates.health.mode"); err != nil {
+		return err
+	}
+
+	// gates.slot_lag.mode must be a valid mode
+	if err := g.SlotLag.Mode.Validate("gates.slot_lag.mode"); err != nil {
+		return err
+	}
+
+	// gates.slot_lag.max_slot_lag must be positive
+	if g.SlotLag.MaxSlotLag == 0 {
+		return fmt.Errorf("gates.slot_lag.max_slot_lag must be positive and non-zero")
+	}
+
+	// gates.disk_space.mode must be a valid mode
+	if err := g.DiskSpace.Mode.Validate("gates.disk_space.mode"); err != nil {
+		return err
+	}
+
+	// gates.disk_space.path must be set to check it
+	if g.DiskSpace.Mode != gates.ModeOff && g.DiskSpace.Path == "" {
+		return fmt.Errorf("gates.disk_space.path must be defined when gates.disk_space.mode is %s", g.DiskSpace.Mode)
+	}
+
+	// gates.disk_space.min_free_percent must be a percentage
+	if g.DiskSpace.MinFreePercent <= 0 || g.DiskSpace.MinFreePercent > 100 {
+		return fmt.Errorf("gates.disk_space.min_free_percent must be greater than 0 and at most 100 - got: %v", g.DiskSpace.MinFreePercent)
+	}
+
+	// gates.vote_account.mode must be a valid mode
+	if err := g.VoteAccount.Mode.Validate("gates.vote_account.mode"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the gates configuration
+func (g *Gates) SetDefaults() {
+	if g.Health.Mode == "" {
+		g.Health.Mode = gates.ModeEnforce
+	}
+	if g.SlotLag.Mode == "" {
+		g.SlotLag.Mode = gates.ModeOff
+	}
+	if g.SlotLag.MaxSlotLag == 0 {
+		g.SlotLag.MaxSlotLag = DefaultFitnessMaxSlotLag
+	}
+	if g.DiskSpace.Mode == "" {
+		g.DiskSpace.Mode = gates.ModeOff
+	}
+	if g.DiskSpace.MinFreePercent == 0 {
+		g.DiskSpace.MinFreePercent = DefaultFitnessMinDiskFreePercent
+	}
+	if g.VoteAccount.Mode == "" {
+		g.VoteAccount.Mode = gates.ModeEnforce
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/gates"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGates_SetDefaults(t *testing.T) {
+	g := Gates{}
+	g.SetDefaults()
+
+	assert.Equal(t, gates.ModeEnforce, g.Health.Mode)
+	assert.Equal(t, gates.ModeOff, g.SlotLag.Mode)
+	assert.Equal(t, uint64(DefaultFitnessMaxSlotLag), g.SlotLag.MaxSlotLag)
+	assert.Equal(t, gates.ModeOff, g.DiskSpace.Mode)
+	assert.Equal(t, float64(DefaultFitnessMinDiskFreePercent), g.DiskSpace.MinFreePercent)
+	assert.NoError(t, g.Validate())
+}
+
+func TestGates_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(g *Gates)
+		wantErr string
+	}{
+		{
+			name: "warn and enforce",
+			modify: func(g *Gates) {
+				g.SlotLag.Mode = gates.ModeWarn
+				g.DiskSpace.Mode = gates.ModeEnforce
+				g.DiskSpace.Path = "/mnt/ledger"
+			},
+		},
+		{
+			name:    "invalid health mode",
+			modify:  func(g *Gates) { g.Health.Mode = "yes" },
+			wantErr: `gates.health.mode must be one of off, warn, enforce - got "yes"`,
+		},
+		{
+			name:    "invalid slot lag mode",
+			modify:  func(g *Gates) { g.SlotLag.Mode = "on" },
+			wantErr: `gates.slot_lag.mode must be one of off, warn, enforce - got "on"`,
+		},
+		{
+			name:    "invalid disk space mode",
+			modify:  func(g *Gates) { g.DiskSpace.Mode = "true" },
+			wantErr: `gates.disk_space.mode must be one of off, warn, enforce - got "true"`,
+		},
+		{
+			name:    "disk space without path",
+			modify:  func(g *Gates) { g.DiskSpace.Mode = gates.ModeWarn },
+			wantErr: "gates.disk_space.path must be defined when gates.disk_space.mode is warn",
+		},
+		{
+			name:    "disk space percent out of range",
+			modify:  func(g *Gates) { g.DiskSpace.MinFreePercent = 101 },
+			wantErr: "gates.disk_space.min_free_percent must be greater than 0 and at most 100 - got: 101",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := Gates{}
+			g.SetDefaults()
+			tt.modify(&g)
+
+			err := g.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
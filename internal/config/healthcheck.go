@@ -0,0 +1,68 @@
+package config
+
+import (
+	"net"
+	"strconv"
+)
+
+// HealthCheck represents the health check server configuration, it listens on prometheus.port + 1
+type HealthCheck struct {
+	// BindAddress is the IP address the health check server listens on, DefaultBindAddress if unset. Peers fetch
+	// /fitness from it during takeover arbitration so it must stay reachable from them when fitness is enabled.
+	BindAddress string `koanf:"bind_address"`
+	// Auth, if set, requires a bearer token on every endpoint but the /health, /livez and /readyz probes. Peers
+	// present their own healthcheck.auth token fetching /fitness, so with fitness enabled it must match across peers.
+	Auth *Auth `koanf:"auth"`
+	// TLS, if set, serves every endpoint over https, the certificate is reloaded on SIGHUP. Peers fetch /fitness
+	// over https, so the certificate must be trusted for their IP when fitness is enabled.
+	TLS *TLS `koanf:"tls"`
+}
+
+// ListenAddress returns the host:port the health check server listens on
+func (h *HealthCheck) ListenAddress(port int) string {
+	return net.JoinHostPort(h.BindAddress, strconv.Itoa(port))
+}
+
+// DialHost returns the host a client on this host reaches the health check server at - BindAddress, or loopback
+// when it listens on every address
+func (h *HealthCheck) DialHost() string {
+	ip := net.ParseIP(h.BindAddress)
+	switch {
+	case ip == nil || ip.Equal(net.IPv4zero):
+		return "127.0.0.1"
+	case ip.Equal(net.IPv6unspecified):
+		return "::1"
+	}
+	return h.BindAddress
+}
+
+// Validate validates the health check configuration
+func (h *HealthCheck) Validate() error {
+	// healthcheck.bind_address must be an IP address
+	if err := validateBindAddress("healthcheck.bind_address", h.BindAddress); err != nil {
+		return err
+	}
+
+	// healthcheck.auth must define a bearer token
+	if h.Auth != nil {
+		if err := h.Auth.Validate("healthcheck.auth"); err != nil {
+			return err
+		}
+	}
+
+	// healthcheck.tls must define a loadable certificate and key
+	if h.TLS != nil {
+		if err := h.TLS.Validate("healthcheck.tls"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the health check configuration
+func (h *HealthCheck) SetDefaults() {
+	if h.BindAddress == "" {
+		h.BindAddress = DefaultBindAddress
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultHeartbeatTimeout is the default time to wait for a peer's heartbeat
+const DefaultHeartbeatTimeout = time.Second
+
+// Heartbeat configures the heartbeat agents serve each other on /ha/heartbeat and poll every poll interval - it
+// reports an agent's role, identity, health and slot directly, without gossip's lag
+type Heartbeat struct {
+	// Enabled turns on serving our heartbeat and polling the peers'
+	Enabled bool `koanf:"enabled"`
+	// Auth is the secret peers share to authenticate heartbeats
+	Auth *Auth `koanf:"auth"`
+	// Timeout is how long to wait for a peer's heartbeat before it counts as unreachable
+	Timeout time.Duration `koanf:"timeout"`
+}
+
+// Validate validates the heartbeat configuration, pollInterval being failover.poll_interval_duration
+func (h *Heartbeat) Validate(pollInterval time.Duration) error {
+	if !h.Enabled {
+		return nil
+	}
+
+	// failover.heartbeat.auth must be defined - heartbeats decide failovers so they must not be spoofable
+	if h.Auth == nil {
+		return fmt.Errorf("failover.heartbeat.auth must be defined when failover.heartbeat.enabled is true")
+	}
+	if err := h.Auth.Validate("failover.heartbeat.auth"); err != nil {
+		return err
+	}
+
+	// failover.heartbeat.timeout must be positive and shorter than the poll interval
+	if h.Timeout <= 0 || h.Timeout >= pollInterval {
+		return fmt.Errorf("failover.heartbeat.timeout must be positive and shorter than failover.poll_interval_duration %s, got %s", pollInterval, h.Timeout)
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the heartbeat configuration
+func (h *Heartbeat) SetDefaults() {
+	if h.Timeout == 0 {
+		h.Timeout = DefaultHeartbeatTimeout
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeat_SetDefaults(t *testing.T) {
+	heartbeat := &Heartbeat{}
+	heartbeat.SetDefaults()
+
+	assert.False(t, heartbeat.Enabled)
+	assert.Nil(t, heartbeat.Auth)
+	assert.Equal(t, time.Second, heartbeat.Timeout)
+}
+
+func TestHeartbeat_Validate(t *testing.T) {
+	// a disabled heartbeat is not validated
+	heartbeat := &Heartbeat{}
+	assert.NoError(t, heartbeat.Validate(5*time.Second))
+
+	tests := []struct {
+		name   string
+		modify func(h *Heartbeat)
+		errMsg string
+	}{
+		{name: "valid", modify: func(h *Heartbeat) {}},
+		{name: "no auth", modify: func(h *Heartbeat) { h.Auth = nil }, errMsg: "failover.heartbeat.auth must be defined when failover.heartbeat.enabled is true"},
+		{name: "empty auth", modify: func(h *Heartbeat) { h.Auth = &Auth{} }, errMsg: "failover.heartbeat.auth.bearer_token or failover.heartbeat.auth.bearer_token_file must be defined"},
+		{name: "negative timeout", modify: func(h *Heartbeat) { h.Timeout = -time.Second }, errMsg: "failover.heartbeat.timeout must be positive and shorter than failover.poll_interval_duration 5s, got -1s"},
+		{name: "timeout of the poll interval", modify: func(h *Heartbeat) { h.Timeout = 5 * time.Second }, errMsg: "failover.heartbeat.timeout must be positive and shorter than failover.poll_interval_duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			heartbeat := &Heartbeat{Enabled: true, Auth: &Auth{BearerToken: "secret"}}
+			heartbeat.SetDefaults()
+			tt.modify(heartbeat)
+
+			err := heartbeat.Validate(5 * time.Second)
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
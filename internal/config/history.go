@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+const (
+	// DefaultHistorySize is the default number of transitions kept in the failover history
+	DefaultHistorySize = 50
+	// HistoryFileName is the file in run.state_dir the failover history is persisted to
+	HistoryFileName = "history.json"
+)
+
+// History represents the failover history configuration
+type History struct {
+	// Size is the number of most recent transitions kept and served on /history
+	Size int `koanf:"size"`
+}
+
+// Validate validates the history configuration
+func (h *History) Validate() error {
+	// history.size must be positive
+	if h.Size <= 0 {
+		return fmt.Errorf("history.size must be positive and non-zero")
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the history configuration
+func (h *History) SetDefaults() {
+	if h.Size == 0 {
+		h.Size = DefaultHistorySize
+	}
+}
+
+// HistoryFile returns the file the failover history is persisted to in run.state_dir, empty when there is no
+// state directory to persist it in
+func (r *Run) HistoryFile() string {
+	if r.StateDir == "" {
+		return ""
+	}
+	return filepath.Join(r.StateDir, HistoryFileName)
+}
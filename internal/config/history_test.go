@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory_SetDefaults(t *testing.T) {
+	history := &History{}
+	history.SetDefaults()
+	assert.Equal(t, 50, history.Size)
+
+	// explicit size is kept
+	history = &History{Size: 10}
+	history.SetDefaults()
+	assert.Equal(t, 10, history.Size)
+}
+
+func TestHistory_Validate(t *testing.T) {
+	assert.NoError(t, (&History{Size: 1}).Validate())
+	assert.ErrorContains(t, (&History{Size: -1}).Validate(), "history.size must be positive and non-zero")
+}
+
+func TestRun_HistoryFile(t *testing.T) {
+	assert.Equal(t, "/var/lib/solana-validator-ha/history.json", (&Run{StateDir: "/var/lib/solana-validator-ha"}).HistoryFile())
+	assert.Empty(t, (&Run{}).HistoryFile())
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultPostHookWorkers is the number of async post hooks run at once when failover.post_hook_workers isn't set
+const DefaultPostHookWorkers = 4
+
+// HookPool runs post hooks in the background on a bounded number of goroutines so slow notifications never hold up
+// the transition that ran them, tracking them so they can be drained on shutdown
+type HookPool struct {
+	slots    chan struct{}
+	wg       sync.WaitGroup
+	inFlight atomic.Int64
+}
+
+// NewHookPool creates a hook pool running at most workers hooks at once, DefaultPostHookWorkers if not positive
+func NewHookPool(workers int) *HookPool {
+	if workers <= 0 {
+		workers = DefaultPostHookWorkers
+	}
+	return &HookPool{slots: make(chan struct{}, workers)}
+}
+
+// Go runs fn on the pool once a worker is free, without waiting for it
+func (p *HookPool) Go(fn func()) {
+	p.wg.Add(1)
+	p.inFlight.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer p.inFlight.Add(-1)
+
+		p.slots <- struct{}{}
+		defer func() { <-p.slots }()
+		fn()
+	}()
+}
+
+// InFlight returns the number of hooks running or waiting for a worker
+func (p *HookPool) InFlight() int {
+	return int(p.inFlight.Load())
+}
+
+// Drain waits up to timeout for the hooks running or waiting for a worker, returning false if some still were
+func (p *HookPool) Drain(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHookPool_BoundsAndDrains(t *testing.T) {
+	pool := NewHookPool(2)
+
+	var running, peak atomic.Int64
+	release := make(chan struct{})
+	for range 5 {
+		pool.Go(func() {
+			now := running.Add(1)
+			for {
+				seen := peak.Load()
+				if now <= seen || peak.CompareAndSwap(seen, now) {
+					break
+				}
+			}
+			<-release
+			running.Add(-1)
+		})
+	}
+	assert.Equal(t, 5, pool.InFlight())
+
+	// still running when the wait runs out
+	assert.False(t, pool.Drain(50*time.Millisecond))
+
+	close(release)
+	assert.True(t, pool.Drain(5*time.Second))
+	assert.Equal(t, int64(2), peak.Load(), "no more than workers hooks run at once")
+	assert.Zero(t, pool.InFlight())
+}
+
+func TestNewHookPool_DefaultWorkers(t *testing.T) {
+	assert.Equal(t, DefaultPostHookWorkers, cap(NewHookPool(0).slots))
+	assert.Equal(t, 3, cap(NewHookPool(3).slots))
+}
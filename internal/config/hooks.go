@@ -1,14 +1,78 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/iancoleman/strcase"
+	"github.com/sol-strategies/solana-validator-ha/internal/audit"
 	"github.com/sol-strategies/solana-validator-ha/internal/command"
 	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
 )
 
+// hookEnvVarRegexp matches ${ENV_VAR} references in hook commands and args - $${ENV_VAR} is a literal ${ENV_VAR}
+var hookEnvVarRegexp = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+const (
+	// DefaultHookTimeout is how long a hook may run before it is killed when it doesn't set a timeout
+	DefaultHookTimeout = 30 * time.Second
+	// DefaultHookRetryDelay is the delay before a hook's first retry when it has retries but doesn't set retry_delay
+	DefaultHookRetryDelay = time.Second
+	// maxHookRetryDelay caps the doubling delay between a hook's retries
+	maxHookRetryDelay = time.Minute
+
+	// hookRoleEnvVar is the role being transitioned to
+	hookRoleEnvVar = "SVHA_ROLE"
+	// hookPhaseEnvVar is the hook type, pre or post
+	hookPhaseEnvVar = "SVHA_PHASE"
+	// hookReasonEnvVar is why the transition is happening
+	hookReasonEnvVar = "SVHA_REASON"
+	// hookPeerNameEnvVar is the peer active in gossip, or last seen active, empty if none
+	hookPeerNameEnvVar = "SVHA_PEER_NAME"
+	// hookValidatorNameEnvVar is validator.name
+	hookValidatorNameEnvVar = "SVHA_VALIDATOR_NAME"
+	// hookActivePubkeyEnvVar is the active identity pubkey
+	hookActivePubkeyEnvVar = "SVHA_ACTIVE_PUBKEY"
+	// hookPassivePubkeyEnvVar is the passive identity pubkey
+	hookPassivePubkeyEnvVar = "SVHA_PASSIVE_PUBKEY"
+	// hookFailoverIDEnvVar is the id correlating everything the transition logs, records and notifies
+	hookFailoverIDEnvVar = "SVHA_FAILOVER_ID"
+)
+
+// HookContext is the context of the transition a role hook runs in, passed to it as SVHA_* environment variables
+type HookContext struct {
+	Role          string
+	Reason        string
+	PeerName      string
+	ValidatorName string
+	ActivePubkey  string
+	PassivePubkey string
+	// FailoverID is the transition's failover id, logged with every line the hook logs
+	FailoverID string
+}
+
+// env returns the context as environment variables for a hook of hookType
+func (c *HookContext) env(hookType string) map[string]string {
+	return map[string]string{
+		hookRoleEnvVar:          c.Role,
+		hookPhaseEnvVar:         hookType,
+		hookReasonEnvVar:        c.Reason,
+		hookPeerNameEnvVar:      c.PeerName,
+		hookValidatorNameEnvVar: c.ValidatorName,
+		hookActivePubkeyEnvVar:  c.ActivePubkey,
+		hookPassivePubkeyEnvVar: c.PassivePubkey,
+		hookFailoverIDEnvVar:    c.FailoverID,
+	}
+}
+
 // Hooks represents a pre/post hook command
 type Hooks struct {
 	Pre  []Hook `koanf:"pre"`
@@ -21,30 +85,97 @@ type Hook struct {
 	Command     string   `koanf:"command"`
 	Args        []string `koanf:"args"`
 	MustSucceed bool     `koanf:"must_succeed"`
+	// Timeout is how long each attempt of the hook may run before it is killed and reported as failed
+	Timeout time.Duration `koanf:"timeout"`
+	// Retries is how many more times a failing hook is run
+	Retries int `koanf:"retries"`
+	// RetryDelay is the delay before the first retry, doubling for each one after
+	RetryDelay time.Duration `koanf:"retry_delay"`
+	// Slack, if set, posts to a Slack incoming webhook in place of Command
+	Slack *SlackHook `koanf:"slack"`
+	// Webhook, if set, calls an HTTP endpoint with a JSON body of the failover context in place of Command
+	Webhook *WebhookHook `koanf:"webhook"`
+	// Async false makes a post hook complete before the agent continues when post hooks run on a HookPool, unset
+	// or true dispatches it to the pool - pre hooks always run in turn
+	Async *bool `koanf:"async"`
+}
+
+// RunsAsync returns true unless the hook is set async: false
+func (h *Hook) RunsAsync() bool {
+	return h.Async == nil || *h.Async
 }
 
 // HookRunOptions represents options for running a hook
 type HookRunOptions struct {
-	HookType     string // "pre" or "post"
+	HookType     string // "pre", "post", "sample" or "alert"
+	Env          map[string]string
 	DryRun       bool
 	LoggerPrefix string
 	LoggerArgs   []any
+	// Output, if set, also receives the hook's stdout and stderr lines
+	Output io.Writer
+	// Context, if set, is passed to the hook as SVHA_* environment variables - Env takes precedence
+	Context *HookContext
+	// Audit, if set, records the hook's command each time it is run
+	Audit *audit.Log
+}
+
+// failoverID returns the failover id of the transition the hook runs in, empty outside of one
+func (o HookRunOptions) failoverID() string {
+	if o.Context == nil {
+		return ""
+	}
+	return o.Context.FailoverID
 }
 
 // HooksRunOptions represents options for running hooks
 type HooksRunOptions struct {
+	// Env is passed to every hook, e.g. to flag a rollback
+	Env          map[string]string
 	DryRun       bool
 	LoggerPrefix string
 	LoggerArgs   []any
+	// OnResult, if set, is called after each hook has run with its outcome and captured output - for post hooks
+	// retried in the background it is called again from that goroutine once they are done
+	OnResult func(result HookResult)
+	// WaitRetries retries failing post hooks before running the next one rather than in the background
+	WaitRetries bool
+	// Pool, if set, runs post hooks not set async: false on it, retries included, rather than in turn - along with
+	// the background retries of those that are
+	Pool *HookPool
+	// Context, if set, is passed to every hook as SVHA_* environment variables
+	Context *HookContext
+	// Audit, if set, records every hook command run
+	Audit *audit.Log
+}
+
+// HookResult is the outcome of running a hook
+type HookResult struct {
+	Hook     Hook
+	HookType string
+	// ExitCode is 0 on success and -1 if the hook could not be started
+	ExitCode int
+	// Attempts is how many times the hook has been run, including retries
+	Attempts int
+	// Duration is from the hook's first attempt until its last, background retries included
+	Duration time.Duration
+	Output   string
+	Err      error
+	DryRun   bool
+	// Retrying is true when a failed post hook is being retried in the background - its final result follows
+	Retrying bool
 }
 
 // Validate validates the hooks configuration
 func (h *Hooks) Validate() error {
-	// hooks.pre must all be valid if defined
+	// hooks.pre must all be valid if defined and never async - they must complete before the role command runs
 	for i, hook := range h.Pre {
 		if err := hook.Validate(true); err != nil {
 			return fmt.Errorf("hooks.%s[%d]: %w", constants.HookTypePre, i, err)
 		}
+		if hook.Async != nil && *hook.Async {
+			return fmt.Errorf("hooks.%s[%d]: async is only allowed for post hooks", constants.HookTypePre, i)
+		}
 	}
 
 	// hooks.post must all be valid if defined
@@ -64,42 +195,286 @@ func (h *Hook) Validate(allowMustSucceed bool) error {
 		return fmt.Errorf("must have a name")
 	}
 
-	// hook.command must be defined
-	if h.Command == "" {
-		return fmt.Errorf("must have a command")
+	// exactly one of hook.command, hook.slack or hook.webhook must be defined
+	kinds := 0
+	for _, defined := range []bool{h.Command != "", h.Slack != nil, h.Webhook != nil} {
+		if defined {
+			kinds++
+		}
+	}
+	if kinds == 0 {
+		return fmt.Errorf("must have a command, slack or webhook")
+	}
+	if kinds > 1 {
+		return fmt.Errorf("must have only one of command, slack or webhook")
+	}
+	if h.Slack != nil {
+		if err := h.Slack.Validate(); err != nil {
+			return err
+		}
+	}
+	if h.Webhook != nil {
+		if err := h.Webhook.Validate(); err != nil {
+			return err
+		}
 	}
 
 	if !allowMustSucceed && h.MustSucceed {
 		return fmt.Errorf("hook must_succeed not allowed for post hooks")
 	}
 
+	// hook.timeout must be positive once defaulted
+	if h.Timeout < 0 {
+		return fmt.Errorf("timeout must be positive, got %s", h.Timeout)
+	}
+
+	// hook.retries and hook.retry_delay must not be negative
+	if h.Retries < 0 {
+		return fmt.Errorf("retries must not be negative, got %d", h.Retries)
+	}
+	if h.RetryDelay < 0 {
+		return fmt.Errorf("retry_delay must not be negative, got %s", h.RetryDelay)
+	}
+
 	return nil
 }
 
-func (h *Hook) Run(opts HookRunOptions) error {
-	loggerArgs := []any{
-		"hook_name", strcase.ToSnake(h.Name),
-		"command", h.Command,
-		"args", h.Args,
-		"dry_run", opts.DryRun,
+// SetDefaults sets default values for every pre and post hook
+func (h *Hooks) SetDefaults() {
+	for i := range h.Pre {
+		h.Pre[i].SetDefaults()
+	}
+	for i := range h.Post {
+		h.Post[i].SetDefaults()
+	}
+}
+
+// SetDefaults sets default values for the hook
+func (h *Hook) SetDefaults() {
+	if h.Timeout == 0 {
+		h.Timeout = DefaultHookTimeout
+	}
+	if h.Retries > 0 && h.RetryDelay == 0 {
+		h.RetryDelay = DefaultHookRetryDelay
+	}
+	if h.Webhook != nil {
+		h.Webhook.SetDefaults()
+	}
+}
+
+// Run runs the hook, retrying it up to h.Retries times while it fails, returning its outcome along with any error.
+// The result's Output is empty, opts.Output receives it.
+func (h *Hook) Run(opts HookRunOptions) (HookResult, error) {
+	startedAt := time.Now()
+	attempt, err := h.runAttempts(opts, 1, h.Retries+1)
+	return HookResult{
+		Hook:     *h,
+		HookType: opts.HookType,
+		ExitCode: command.ExitCode(err),
+		Attempts: attempt,
+		Duration: time.Since(startedAt),
+		Err:      err,
+		DryRun:   opts.DryRun,
+	}, err
+}
+
+// runAttempts runs attempts first to last of the hook until one succeeds, returning the last attempt made. Each
+// attempt gets the full timeout and the delay before each retry doubles from h.RetryDelay.
+func (h *Hook) runAttempts(opts HookRunOptions, first int, last int) (attempt int, err error) {
+	loggerArgs := []any{"hook_name", strcase.ToSnake(h.Name)}
+	switch {
+	case h.Slack != nil:
+		loggerArgs = append(loggerArgs, "slack_channel", h.Slack.Channel, "message", h.Slack.Message)
+	case h.Webhook != nil:
+		loggerArgs = append(loggerArgs, "webhook_method", h.Webhook.Method, "webhook_url", (&Redact{}).String(h.Webhook.URL), "webhook_headers", h.Webhook.redactedHeaders())
+	default:
+		loggerArgs = append(loggerArgs, "command", h.Command, "args", h.Args)
+	}
+	loggerArgs = append(loggerArgs, "timeout", h.Timeout, "dry_run", opts.DryRun)
+	if failoverID := opts.failoverID(); failoverID != "" {
+		loggerArgs = append(loggerArgs, "failover_id", failoverID)
 	}
 	loggerArgs = append(loggerArgs, opts.LoggerArgs...)
+	logger := loglevel.WithPrefix(loglevel.Hooks, fmt.Sprintf("[%s %s-hook %s]", opts.LoggerPrefix, opts.HookType, h.Name))
 
 	if opts.DryRun {
-		return nil
+		if h.Slack != nil {
+			logger.Info("would post to slack - dry run", loggerArgs...)
+		}
+		if h.Webhook != nil {
+			body, err := h.Webhook.renderBody(newWebhookTemplateData(opts.HookType, opts.Context, time.Now()))
+			if err != nil {
+				return first, err
+			}
+			logger.Info("would call webhook - dry run", append(loggerArgs, "body", string(body))...)
+		}
+		if h.Slack == nil && h.Webhook == nil {
+			err := command.AuditDryRun(command.RunOptions{
+				Name:       h.commandName(opts.HookType),
+				Command:    h.Command,
+				Args:       h.Args,
+				FailoverID: opts.failoverID(),
+				Audit:      opts.Audit,
+			})
+			if err != nil {
+				logger.Warn("failed to append hook to the audit log", "error", err)
+			}
+		}
+		return first, nil
+	}
+
+	if opts.Context != nil {
+		env := opts.Context.env(opts.HookType)
+		for key, value := range opts.Env {
+			env[key] = value
+		}
+		opts.Env = env
+	}
+
+	// expanded when run so the agent's environment at the time is used - the expanded values are never logged
+	expandedCommand, expandedArgs, err := h.expandEnv(opts.Env)
+	if err != nil {
+		return first, err
+	}
+
+	for attempt = first; attempt <= last; attempt++ {
+		if attempt > 1 {
+			delay := h.retryDelay(attempt)
+			logger.Warn("hook failed - retrying", append(loggerArgs, "attempt", attempt, "attempts", h.Retries+1, "retry_delay", delay, "error", err)...)
+			time.Sleep(delay)
+		}
+
+		err = h.runAttempt(opts, expandedCommand, expandedArgs, loggerArgs)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("hook succeeded after retrying", append(loggerArgs, "attempt", attempt, "attempts", h.Retries+1)...)
+			}
+			return attempt, nil
+		}
+	}
+	return last, err
+}
+
+// retryDelay returns the delay before the given attempt, doubling from h.RetryDelay up to maxHookRetryDelay
+func (h *Hook) retryDelay(attempt int) time.Duration {
+	delay := h.RetryDelay
+	for i := 2; i < attempt && delay < maxHookRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxHookRetryDelay)
+}
+
+// runAttempt runs the expanded hook command once, posts its expanded slack webhook url, channel and message or
+// calls its expanded webhook url and headers with the body rendered now
+func (h *Hook) runAttempt(opts HookRunOptions, expandedCommand string, expandedArgs []string, loggerArgs []any) error {
+	logger := loglevel.WithPrefix(loglevel.Hooks, fmt.Sprintf("[%s %s-hook %s]", opts.LoggerPrefix, opts.HookType, h.Name))
+	if h.Slack != nil {
+		logger.Info("posting to slack", loggerArgs...)
+		return h.Slack.post(expandedArgs[0], expandedArgs[1], expandedArgs[2], h.Timeout, opts.Output)
+	}
+	if h.Webhook != nil {
+		body, err := h.Webhook.renderBody(newWebhookTemplateData(opts.HookType, opts.Context, time.Now()))
+		if err != nil {
+			return err
+		}
+		headers := make(map[string]string, len(h.Webhook.Headers))
+		for i, name := range h.Webhook.headerNames() {
+			headers[name] = expandedArgs[i+1]
+		}
+		logger.Info("calling webhook", loggerArgs...)
+		return h.Webhook.call(expandedArgs[0], headers, body, h.Timeout, opts.Output)
 	}
 
 	return command.Run(command.RunOptions{
-		Name:         fmt.Sprintf("%s-hook %s", opts.HookType, h.Name),
-		Command:      h.Command,
-		Args:         h.Args,
+		Name:         h.commandName(opts.HookType),
+		Command:      expandedCommand,
+		Args:         expandedArgs,
+		LogCommand:   h.Command,
+		LogArgs:      h.Args,
+		Env:          opts.Env,
+		InheritEnv:   true,
 		DryRun:       opts.DryRun,
 		LoggerPrefix: opts.LoggerPrefix,
 		LoggerArgs:   loggerArgs,
 		StreamOutput: true,
+		Output:       opts.Output,
+		Timeout:      h.Timeout,
+		FailoverID:   opts.failoverID(),
+		Audit:        opts.Audit,
 	})
 }
 
+// commandName returns the name the hook's command is run and audited as
+func (h *Hook) commandName(hookType string) string {
+	return fmt.Sprintf("%s-hook %s", hookType, h.Name)
+}
+
+// expandEnv returns the hook's command and args with ${ENV_VAR} references replaced by their values from env,
+// falling back to the agent's environment. Every reference must be set, even to an empty string. Slack hooks
+// have no command, their args are the expanded webhook url, channel and message. Webhook hooks' args are the
+// expanded url followed by the header values in header name order.
+func (h *Hook) expandEnv(env map[string]string) (expandedCommand string, expandedArgs []string, err error) {
+	unset := []string{}
+	expand := func(s string) string {
+		return expandEnvReferences(s, env, &unset)
+	}
+
+	args := h.Args
+	if h.Slack != nil {
+		args = []string{h.Slack.WebhookURL, h.Slack.Channel, h.Slack.Message}
+	}
+	if h.Webhook != nil {
+		args = []string{h.Webhook.URL}
+		for _, name := range h.Webhook.headerNames() {
+			args = append(args, h.Webhook.Headers[name])
+		}
+	}
+
+	expandedCommand = expand(h.Command)
+	expandedArgs = make([]string, len(args))
+	for i, arg := range args {
+		expandedArgs[i] = expand(arg)
+	}
+
+	if len(unset) > 0 {
+		return "", nil, fmt.Errorf("hook %s references unset environment variables: %s", h.Name, strings.Join(unset, ", "))
+	}
+	return expandedCommand, expandedArgs, nil
+}
+
+// run runs attempts first to last of the hook as hookType with opts, reporting its outcome to opts.OnResult if set.
+// startedAt is when the hook's first attempt started, retrying is whether a failure will be retried in the background.
+func (h *Hook) run(hookType string, opts HooksRunOptions, loggerArgs []any, first int, last int, startedAt time.Time, retrying bool) (attempt int, err error) {
+	runOpts := HookRunOptions{
+		HookType:     hookType,
+		Env:          opts.Env,
+		DryRun:       opts.DryRun,
+		LoggerPrefix: opts.LoggerPrefix,
+		LoggerArgs:   loggerArgs,
+		Context:      opts.Context,
+		Audit:        opts.Audit,
+	}
+	if opts.OnResult == nil {
+		return h.runAttempts(runOpts, first, last)
+	}
+
+	var output bytes.Buffer
+	runOpts.Output = &output
+	attempt, err = h.runAttempts(runOpts, first, last)
+	opts.OnResult(HookResult{
+		Hook:     *h,
+		HookType: hookType,
+		ExitCode: command.ExitCode(err),
+		Attempts: attempt,
+		Duration: time.Since(startedAt),
+		Output:   output.String(),
+		Err:      err,
+		DryRun:   opts.DryRun,
+		Retrying: err != nil && retrying,
+	})
+	return attempt, err
+}
+
 // RunPre runs the pre hooks
 func (h *Hooks) RunPre(opts HooksRunOptions) error {
 	loggerArgs := []any{
@@ -107,19 +482,14 @@ func (h *Hooks) RunPre(opts HooksRunOptions) error {
 	}
 	loggerArgs = append(loggerArgs, opts.LoggerArgs...)
 
-	// run pre hooks
+	// run pre hooks - retries are waited for, only the final failure of a must_succeed hook aborts
 	for _, hook := range h.Pre {
-		err := hook.Run(HookRunOptions{
-			HookType:     constants.HookTypePre,
-			DryRun:       opts.DryRun,
-			LoggerPrefix: opts.LoggerPrefix,
-			LoggerArgs:   loggerArgs,
-		})
+		attempts, err := hook.run(constants.HookTypePre, opts, loggerArgs, 1, hook.Retries+1, time.Now(), false)
 		if err != nil && hook.MustSucceed {
 			return err
 		}
 		if err != nil && !hook.MustSucceed {
-			log.Error("hook failed", loggerArgs...)
+			log.Error("hook failed", append(loggerArgs, "hook_name", hook.Name, "attempts", attempts, "error", err)...)
 		}
 	}
 
@@ -131,18 +501,69 @@ func (h *Hooks) RunPost(opts HooksRunOptions) {
 	loggerArgs := []any{
 		"hook_type", constants.HookTypePost,
 	}
-	loggerArgs = append(loggerArgs, opts.LoggerArgs...)
+	// clipped so appends in background retries never share its backing array
+	loggerArgs = slices.Clip(append(loggerArgs, opts.LoggerArgs...))
 
 	// run post hooks - failures are logged but not returned
 	for _, hook := range h.Post {
-		err := hook.Run(HookRunOptions{
-			HookType:     constants.HookTypePost,
-			DryRun:       opts.DryRun,
-			LoggerPrefix: opts.LoggerPrefix,
-			LoggerArgs:   loggerArgs,
-		})
-		if err != nil {
-			log.Error("hook failed", loggerArgs...)
+		// dispatched so a slow notification never holds up the agent, retries and all
+		if opts.Pool != nil && hook.RunsAsync() {
+			opts.Pool.Go(func() {
+				attempts, err := hook.run(constants.HookTypePost, opts, loggerArgs, 1, hook.Retries+1, time.Now(), false)
+				if err != nil {
+					log.Error("hook failed", append(loggerArgs, "hook_name", hook.Name, "attempts", attempts, "error", err)...)
+				}
+			})
+			continue
+		}
+
+		last := hook.Retries + 1
+		if !opts.WaitRetries {
+			last = 1
+		}
+		startedAt := time.Now()
+		attempts, err := hook.run(constants.HookTypePost, opts, loggerArgs, 1, last, startedAt, last <= hook.Retries)
+		if err == nil {
+			continue
+		}
+		if attempts > hook.Retries {
+			log.Error("hook failed", append(loggerArgs, "hook_name", hook.Name, "attempts", attempts, "error", err)...)
+			continue
+		}
+
+		// retried in the background so the next hooks and the agent aren't held up by a flaky hook
+		log.Warn("hook failed - retrying in the background", append(loggerArgs, "hook_name", hook.Name, "retries", hook.Retries, "error", err)...)
+		retry := func() {
+			attempts, err := hook.run(constants.HookTypePost, opts, loggerArgs, 2, hook.Retries+1, startedAt, false)
+			if err != nil {
+				log.Error("hook failed", append(loggerArgs, "hook_name", hook.Name, "attempts", attempts, "error", err)...)
+			}
+		}
+		if opts.Pool != nil {
+			opts.Pool.Go(retry)
+		} else {
+			go retry()
 		}
 	}
 }
+
+// expandEnvReferences returns s with ${ENV_VAR} references replaced by their values from env, falling back to the
+// agent's environment. References to unset variables are left as is and their names added to unset.
+func expandEnvReferences(s string, env map[string]string, unset *[]string) string {
+	return hookEnvVarRegexp.ReplaceAllStringFunc(s, func(reference string) string {
+		if strings.HasPrefix(reference, "$$") {
+			return reference[1:]
+		}
+		name := hookEnvVarRegexp.FindStringSubmatch(reference)[1]
+		if value, ok := env[name]; ok {
+			return value
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		if !slices.Contains(*unset, name) {
+			*unset = append(*unset, name)
+		}
+		return reference
+	})
+}
@@ -2,10 +2,20 @@ package config
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
+	"github.com/sol-strategies/solana-validator-ha/internal/logwriter"
+	"github.com/sol-strategies/solana-validator-ha/internal/rotatefile"
 )
 
 var (
@@ -20,12 +30,21 @@ var (
 type Log struct {
 	// Level is the log level - one of "debug", "info", "warn", "error", "fatal", defaults to "info", overwritable by --log-level command line flag
 	Level string `koanf:"level"`
-	// Format is the log format - one of "text" or "json" or "logfmt", defaults to txt
+	// Format is the log format - one of "text" or "json" or "logfmt", defaults to text, overwritable by --log-format
+	// command line flag
 	Format string `koanf:"format"`
+	// File optionally writes the logs to a rotated file alongside stderr
+	File LogFile `koanf:"file"`
+	// Levels are the levels of modules logging at other than Level, keyed by module - one of loglevel.Modules
+	Levels map[string]string `koanf:"levels"`
 	// ParsedLevel is the parsed log level
 	ParsedLevel log.Level `koanf:"-"`
+	// ParsedLevels are the parsed Levels
+	ParsedLevels map[string]log.Level `koanf:"-"`
 	// ParsedFormat is the parsed log format
 	ParsedFormatter log.Formatter `koanf:"-"`
+	// Sinks are the log outputs, each wrapped so a failing sink never blocks or crashes the caller
+	Sinks []*logwriter.Writer `koanf:"-"`
 }
 
 // SetDefaults sets default values for the log configuration
@@ -36,6 +55,7 @@ func (l *Log) SetDefaults() {
 	if l.Format == "" {
 		l.Format = "text"
 	}
+	l.File.SetDefaults()
 }
 
 // Validate validates the log configuration
@@ -46,6 +66,18 @@ func (l *Log) Validate() (err error) {
 		return fmt.Errorf("log.level must be one of debug, info, warn, error, fatal - got: %s", l.Level)
 	}
 
+	// log.levels must be known modules at valid levels
+	l.ParsedLevels = make(map[string]log.Level, len(l.Levels))
+	for module, level := range l.Levels {
+		if !slices.Contains(loglevel.Modules, module) {
+			return fmt.Errorf("log.levels.%s is not a module - must be one of %s", module, strings.Join(loglevel.Modules, ", "))
+		}
+		l.ParsedLevels[module], err = log.ParseLevel(level)
+		if err != nil {
+			return fmt.Errorf("log.levels.%s must be one of debug, info, warn, error, fatal - got: %s", module, level)
+		}
+	}
+
 	// try to parse the supplied format
 	var ok bool
 	l.ParsedFormatter, ok = logFormatters[l.Format]
@@ -53,6 +85,11 @@ func (l *Log) Validate() (err error) {
 		return fmt.Errorf("log.format must be one of text, json, logfmt - got: %s", l.Format)
 	}
 
+	// log.file must be valid if enabled
+	if err := l.File.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -67,6 +104,17 @@ func (l *Log) SetLevelString(logLevel string) {
 	}
 }
 
+// SetFormatString sets the log format from a string, the --log-format command line flag
+func (l *Log) SetFormatString(logFormat string) error {
+	formatter, ok := logFormatters[logFormat]
+	if !ok {
+		return fmt.Errorf("--log-format must be one of text, json, logfmt - got: %s", logFormat)
+	}
+	l.Format = logFormat
+	l.ParsedFormatter = formatter
+	return nil
+}
+
 // ConfigureWithLevelString configures the logger with the supplied settings
 // If logLevel is provided and different from the config level, it overrides the config
 func (l *Log) ConfigureWithLevelString(logLevel string) {
@@ -81,8 +129,43 @@ func (l *Log) ConfigureWithLevelString(logLevel string) {
 		}
 	}
 
-	// Set the global log level
-	log.SetLevel(l.ParsedLevel)
+	// Set the global log level and the module levels on top
+	if err := loglevel.Set(l.ParsedLevel, l.ParsedLevels); err != nil {
+		log.SetLevel(l.ParsedLevel)
+		log.Error("invalid log.levels - logging every module at "+l.Level, "error", err)
+	}
+
+	// wrap stderr so a broken log pipe (journald restart, container log driver hiccup) never blocks or
+	// kills the poll loop - ignoring SIGPIPE makes writes to a closed stderr fail rather than exit us
+	var fileErr error
+	if len(l.Sinks) == 0 {
+		signal.Ignore(syscall.SIGPIPE)
+		l.Sinks = append(l.Sinks, logwriter.New(logwriter.Options{Name: "stderr", Out: l.formatOutput(os.Stderr)}))
+
+		// the log file gets every line stderr does, wrapped in its own writer so a full disk never holds up stderr
+		if l.File.Enabled() {
+			var file *rotatefile.File
+			file, fileErr = rotatefile.Open(rotatefile.Options{
+				Path:       l.File.Path,
+				MaxBytes:   l.File.MaxBytes(),
+				MaxBackups: l.File.MaxBackups,
+				MaxAge:     l.File.MaxAge(),
+				Compress:   l.File.Compress,
+			})
+			if fileErr == nil {
+				file.ReopenOnSIGHUP(func(err error) {
+					log.Error("failed to reopen log file", "path", l.File.Path, "error", err)
+				})
+				l.Sinks = append(l.Sinks, logwriter.New(logwriter.Options{Name: "file", Out: l.formatOutput(file)}))
+			}
+		}
+
+		outputs := make([]io.Writer, 0, len(l.Sinks))
+		for _, sink := range l.Sinks {
+			outputs = append(outputs, sink)
+		}
+		log.SetOutput(io.MultiWriter(outputs...))
+	}
 
 	// set the time function to ensure all logs are in UTC and in nanos
 	log.SetTimeFunction(func() time.Time {
@@ -107,4 +190,17 @@ func (l *Log) ConfigureWithLevelString(logLevel string) {
 	styles.Levels[log.FatalLevel] = styles.Levels[log.FatalLevel].Foreground(lipgloss.Color("208"))
 
 	log.SetStyles(styles)
+
+	// logging to a file is never a reason not to run
+	if fileErr != nil {
+		log.Error("failed to open log file - logging to stderr only", "path", l.File.Path, "error", fileErr)
+	}
+}
+
+// formatOutput returns out rewritten for log.format json's keys, out as is for the other formats
+func (l *Log) formatOutput(out io.Writer) io.Writer {
+	if l.Format == "json" {
+		return newJSONLogWriter(out)
+	}
+	return out
 }
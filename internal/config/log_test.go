@@ -0,0 +1,87 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONLogWriter(t *testing.T) {
+	var out bytes.Buffer
+	logger := log.NewWithOptions(newJSONLogWriter(&out), log.Options{
+		Formatter:       log.JSONFormatter,
+		ReportTimestamp: true,
+		TimeFormat:      time.RFC3339,
+		Level:           log.DebugLevel,
+	})
+
+	// call sites keep using WithPrefix and key value pairs as they do with text
+	logger.WithPrefix("[primary ha_manager]").Info("monitoring HA state", "poll_interval", 5*time.Second, "slot", uint64(9007199254740993))
+	logger.WithPrefix("metrics").Warn("metrics server error", "error", errors.New("address in use"))
+	logger.Debug("no prefix")
+
+	lines := []map[string]any{}
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		entry := map[string]any{}
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+		require.NoError(t, decoder.Decode(&entry), "every line must be a JSON object: %s", scanner.Text())
+		lines = append(lines, entry)
+	}
+	require.Len(t, lines, 3)
+
+	for _, entry := range lines {
+		assert.NotEmpty(t, entry["ts"])
+		assert.NotContains(t, entry, "time")
+		assert.NotEmpty(t, entry["level"])
+		assert.NotEmpty(t, entry["msg"])
+	}
+
+	assert.Equal(t, "info", lines[0]["level"])
+	assert.Equal(t, "primary ha_manager", lines[0]["prefix"])
+	assert.Equal(t, "monitoring HA state", lines[0]["msg"])
+	assert.Equal(t, "5s", lines[0]["poll_interval"])
+	assert.Equal(t, json.Number("9007199254740993"), lines[0]["slot"], "numbers are kept exactly")
+	assert.Equal(t, "metrics", lines[1]["prefix"])
+	assert.Equal(t, "address in use", lines[1]["error"])
+	assert.NotContains(t, lines[2], "prefix")
+}
+
+func TestJSONLogWriter_PassesOnOtherLines(t *testing.T) {
+	var out bytes.Buffer
+	n, err := newJSONLogWriter(&out).Write([]byte("not json\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 9, n)
+	assert.Equal(t, "not json\n", out.String())
+}
+
+func TestLog_SetFormatString(t *testing.T) {
+	l := &Log{Format: "text"}
+	require.NoError(t, l.SetFormatString("json"))
+	assert.Equal(t, "json", l.Format)
+	assert.Equal(t, log.JSONFormatter, l.ParsedFormatter)
+
+	assert.EqualError(t, l.SetFormatString("yaml"), "--log-format must be one of text, json, logfmt - got: yaml")
+	assert.Equal(t, "json", l.Format)
+}
+
+func TestLog_Validate_Levels(t *testing.T) {
+	logCfg := &Log{Levels: map[string]string{"gossip_state": "debug", "ha_manager": "warn"}}
+	logCfg.SetDefaults()
+	require.NoError(t, logCfg.Validate())
+	assert.Equal(t, map[string]log.Level{"gossip_state": log.DebugLevel, "ha_manager": log.WarnLevel}, logCfg.ParsedLevels)
+
+	logCfg.Levels = map[string]string{"gossip": "debug"}
+	assert.ErrorContains(t, logCfg.Validate(), "log.levels.gossip is not a module - must be one of command, config")
+
+	logCfg.Levels = map[string]string{"rpc": "verbose"}
+	assert.EqualError(t, logCfg.Validate(), "log.levels.rpc must be one of debug, info, warn, error, fatal - got: verbose")
+}
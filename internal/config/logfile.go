@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultLogFileMaxSizeMB is the size in megabytes the log file may grow to by default before it is rotated
+	DefaultLogFileMaxSizeMB = 100
+	// DefaultLogFileMaxBackups is the number of rotated log files kept by default
+	DefaultLogFileMaxBackups = 5
+)
+
+// LogFile configures writing the logs to a size rotated file alongside stderr
+type LogFile struct {
+	// Path is the log file, empty only logs to stderr
+	Path string `koanf:"path"`
+	// MaxSizeMB is the size in megabytes the log file may grow to before it is rotated to <path>.1
+	MaxSizeMB int `koanf:"max_size_mb"`
+	// MaxBackups is the number of rotated log files kept, the oldest is removed beyond it
+	MaxBackups int `koanf:"max_backups"`
+	// MaxAgeDays removes rotated log files older than it in days, 0 keeps them however old
+	MaxAgeDays int `koanf:"max_age_days"`
+	// Compress gzips rotated log files
+	Compress bool `koanf:"compress"`
+}
+
+// Enabled returns true when logs are written to a file
+func (l *LogFile) Enabled() bool {
+	return l.Path != ""
+}
+
+// MaxBytes returns MaxSizeMB in bytes
+func (l *LogFile) MaxBytes() int64 {
+	return int64(l.MaxSizeMB) * 1024 * 1024
+}
+
+// MaxAge returns MaxAgeDays as a duration
+func (l *LogFile) MaxAge() time.Duration {
+	return time.Duration(l.MaxAgeDays) * 24 * time.Hour
+}
+
+// Validate validates the log file configuration
+func (l *LogFile) Validate() error {
+	if !l.Enabled() {
+		return nil
+	}
+
+	// log.file.max_size_mb must be positive
+	if l.MaxSizeMB <= 0 {
+		return fmt.Errorf("log.file.max_size_mb must be positive and non-zero")
+	}
+
+	// log.file.max_backups and log.file.max_age_days must not be negative
+	if l.MaxBackups < 0 {
+		return fmt.Errorf("log.file.max_backups must not be negative, got %d", l.MaxBackups)
+	}
+	if l.MaxAgeDays < 0 {
+		return fmt.Errorf("log.file.max_age_days must not be negative, got %d", l.MaxAgeDays)
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the log file configuration
+func (l *LogFile) SetDefaults() {
+	if l.MaxSizeMB == 0 {
+		l.MaxSizeMB = DefaultLogFileMaxSizeMB
+	}
+	if l.MaxBackups == 0 {
+		l.MaxBackups = DefaultLogFileMaxBackups
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogFile_SetDefaults(t *testing.T) {
+	logFile := &LogFile{}
+	logFile.SetDefaults()
+	assert.False(t, logFile.Enabled())
+	assert.Equal(t, 100, logFile.MaxSizeMB)
+	assert.Equal(t, 5, logFile.MaxBackups)
+	assert.Zero(t, logFile.MaxAgeDays)
+	assert.False(t, logFile.Compress)
+
+	// explicit values are kept
+	logFile = &LogFile{Path: "/var/log/solana-validator-ha/agent.log", MaxSizeMB: 10, MaxBackups: 2, MaxAgeDays: 7}
+	logFile.SetDefaults()
+	assert.True(t, logFile.Enabled())
+	assert.Equal(t, int64(10*1024*1024), logFile.MaxBytes())
+	assert.Equal(t, 2, logFile.MaxBackups)
+	assert.Equal(t, 7*24*time.Hour, logFile.MaxAge())
+}
+
+func TestLogFile_Validate(t *testing.T) {
+	// disabled needs nothing
+	assert.NoError(t, (&LogFile{MaxSizeMB: -1}).Validate())
+
+	assert.NoError(t, (&LogFile{Path: "/tmp/agent.log", MaxSizeMB: 1}).Validate())
+	assert.ErrorContains(t, (&LogFile{Path: "/tmp/agent.log"}).Validate(), "log.file.max_size_mb must be positive")
+	assert.ErrorContains(t, (&LogFile{Path: "/tmp/agent.log", MaxSizeMB: 1, MaxBackups: -1}).Validate(), "log.file.max_backups must not be negative")
+	assert.ErrorContains(t, (&LogFile{Path: "/tmp/agent.log", MaxSizeMB: 1, MaxAgeDays: -1}).Validate(), "log.file.max_age_days must not be negative")
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// jsonLogTimestampKey is the key log pipelines such as Loki expect the timestamp under
+const jsonLogTimestampKey = "ts"
+
+// jsonLogWriter rewrites each line of the charmbracelet JSON formatter so every entry has the ts, level, prefix and
+// msg keys, the prefix without the brackets log.WithPrefix call sites wrap it in
+type jsonLogWriter struct {
+	out io.Writer
+}
+
+// newJSONLogWriter returns a writer rewriting JSON log lines written to it onto out
+func newJSONLogWriter(out io.Writer) *jsonLogWriter {
+	return &jsonLogWriter{out: out}
+}
+
+// Write implements io.Writer - a line that isn't a JSON object is passed on as is
+func (w *jsonLogWriter) Write(p []byte) (int, error) {
+	var b bytes.Buffer
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		entry := map[string]any{}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		// numbers such as slots and lamports are kept exactly as written
+		decoder.UseNumber()
+		if err := decoder.Decode(&entry); err != nil {
+			b.Write(line)
+			b.WriteByte('\n')
+			continue
+		}
+
+		if ts, ok := entry[log.TimestampKey]; ok {
+			delete(entry, log.TimestampKey)
+			entry[jsonLogTimestampKey] = ts
+		}
+		if prefix, ok := entry[log.PrefixKey].(string); ok {
+			entry[log.PrefixKey] = strings.TrimSuffix(strings.TrimPrefix(prefix, "["), "]")
+		}
+
+		encoder := json.NewEncoder(&b)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(entry); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := w.out.Write(b.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
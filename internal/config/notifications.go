@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/notify"
+)
+
+const (
+	// DefaultPagerDutySeverity is the severity of triggered PagerDuty events when notifications.pagerduty.severity is not set
+	DefaultPagerDutySeverity = "critical"
+	// DefaultPagerDutyDedupKey groups every node's failover pages of the same active identity into one incident
+	DefaultPagerDutyDedupKey = "solana-validator-ha-{{ .ActiveIdentityPubkey }}"
+	// DefaultPagerDutyTimeout bounds each attempt to send a PagerDuty event
+	DefaultPagerDutyTimeout = 10 * time.Second
+	// DefaultPagerDutyRetries is how many more times a PagerDuty event is sent while PagerDuty fails
+	DefaultPagerDutyRetries = 3
+	// DefaultPagerDutyRetryDelay is the delay before the first retry of a PagerDuty event
+	DefaultPagerDutyRetryDelay = time.Second
+)
+
+// pagerDutySeverities are the severities the PagerDuty Events API v2 accepts
+var pagerDutySeverities = []string{"critical", "error", "warning", "info"}
+
+// Notifications represents the built-in notification integrations, distinct from hooks
+type Notifications struct {
+	// PagerDuty, if set, pages when this node fails over to active and resolves once there is an active peer again
+	PagerDuty *PagerDuty `koanf:"pagerduty"`
+}
+
+// PagerDuty represents the PagerDuty Events API v2 integration
+type PagerDuty struct {
+	// RoutingKey is the integration key of the PagerDuty service, supports ${ENV_VAR} references
+	RoutingKey string `koanf:"routing_key"`
+	// Severity of the triggered event - critical, error, warning or info
+	Severity string `koanf:"severity"`
+	// DedupKey supports the same template data as role commands, the resolve is sent with the same key
+	DedupKey string `koanf:"dedup_key"`
+	// URL is the Events API v2 enqueue endpoint, overridable for proxies
+	URL string `koanf:"url"`
+	// Timeout bounds each attempt to send an event
+	Timeout time.Duration `koanf:"timeout"`
+	// Retries is how many more times an event is sent while PagerDuty fails or can't be reached, nil means
+	// DefaultPagerDutyRetries
+	Retries *int `koanf:"retries"`
+	// RetryDelay is the delay before the first retry, doubling for each one after
+	RetryDelay time.Duration `koanf:"retry_delay"`
+}
+
+// Validate validates the notifications configuration
+func (n *Notifications) Validate() error {
+	if n.PagerDuty != nil {
+		if err := n.PagerDuty.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the notifications configuration
+func (n *Notifications) SetDefaults() {
+	if n.PagerDuty != nil {
+		n.PagerDuty.SetDefaults()
+	}
+}
+
+// Render renders the notifications' templates with data and expands their ${ENV_VAR} references
+func (n *Notifications) Render(data RoleCommandTemplateData) error {
+	if n.PagerDuty != nil {
+		if err := n.PagerDuty.Render(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the pagerduty configuration
+func (p *PagerDuty) Validate() error {
+	// notifications.pagerduty.routing_key must be defined
+	if p.RoutingKey == "" {
+		return fmt.Errorf("notifications.pagerduty.routing_key must be defined")
+	}
+
+	// notifications.pagerduty.severity must be one the events api accepts
+	if !slices.Contains(pagerDutySeverities, p.Severity) {
+		return fmt.Errorf("notifications.pagerduty.severity must be one of %s, got %s", strings.Join(pagerDutySeverities, ", "), p.Severity)
+	}
+
+	// notifications.pagerduty.dedup_key must be a valid template
+	if _, err := renderTemplateString(RoleCommandTemplateData{}, p.DedupKey); err != nil {
+		return fmt.Errorf("notifications.pagerduty.dedup_key: %w", err)
+	}
+
+	// notifications.pagerduty.url must be a valid http(s) url
+	if !isHTTPURL(p.URL) {
+		return fmt.Errorf("notifications.pagerduty.url must be a valid http(s) url")
+	}
+
+	// notifications.pagerduty.timeout must be positive
+	if p.Timeout <= 0 {
+		return fmt.Errorf("notifications.pagerduty.timeout must be positive, got %s", p.Timeout)
+	}
+
+	// notifications.pagerduty.retries and retry_delay must not be negative
+	if p.GetRetries() < 0 {
+		return fmt.Errorf("notifications.pagerduty.retries must not be negative, got %d", p.GetRetries())
+	}
+	if p.RetryDelay < 0 {
+		return fmt.Errorf("notifications.pagerduty.retry_delay must not be negative, got %s", p.RetryDelay)
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the pagerduty configuration
+func (p *PagerDuty) SetDefaults() {
+	if p.Severity == "" {
+		p.Severity = DefaultPagerDutySeverity
+	}
+	if p.DedupKey == "" {
+		p.DedupKey = DefaultPagerDutyDedupKey
+	}
+	if p.URL == "" {
+		p.URL = notify.DefaultPagerDutyURL
+	}
+	if p.Timeout == 0 {
+		p.Timeout = DefaultPagerDutyTimeout
+	}
+	if p.RetryDelay == 0 {
+		p.RetryDelay = DefaultPagerDutyRetryDelay
+	}
+}
+
+// GetRetries returns how many more times an event is sent while PagerDuty fails
+func (p *PagerDuty) GetRetries() int {
+	if p.Retries == nil {
+		return DefaultPagerDutyRetries
+	}
+	return *p.Retries
+}
+
+// Render renders the dedup key template with data and expands the routing key's ${ENV_VAR} references from the
+// agent's environment, each of which must be set
+func (p *PagerDuty) Render(data RoleCommandTemplateData) (err error) {
+	p.DedupKey, err = renderTemplateString(data, p.DedupKey)
+	if err != nil {
+		return fmt.Errorf("failed to render notifications.pagerduty.dedup_key: %w", err)
+	}
+	if p.DedupKey == "" {
+		return fmt.Errorf("notifications.pagerduty.dedup_key must not render empty")
+	}
+
+	unset := []string{}
+	p.RoutingKey = expandEnvReferences(p.RoutingKey, nil, &unset)
+	if len(unset) > 0 {
+		return fmt.Errorf("notifications.pagerduty.routing_key references unset environment variables: %s", strings.Join(unset, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/notify"
+)
+
+func TestPagerDuty_SetDefaults(t *testing.T) {
+	pagerDuty := PagerDuty{RoutingKey: "routing-key"}
+	pagerDuty.SetDefaults()
+
+	assert.Equal(t, DefaultPagerDutySeverity, pagerDuty.Severity)
+	assert.Equal(t, DefaultPagerDutyDedupKey, pagerDuty.DedupKey)
+	assert.Equal(t, notify.DefaultPagerDutyURL, pagerDuty.URL)
+	assert.Equal(t, DefaultPagerDutyTimeout, pagerDuty.Timeout)
+	assert.Equal(t, DefaultPagerDutyRetries, pagerDuty.GetRetries())
+	assert.Equal(t, DefaultPagerDutyRetryDelay, pagerDuty.RetryDelay)
+	assert.NoError(t, pagerDuty.Validate())
+
+	// retries may be turned off
+	retries := 0
+	pagerDuty.Retries = &retries
+	assert.Equal(t, 0, pagerDuty.GetRetries())
+}
+
+func TestPagerDuty_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(p *PagerDuty)
+		wantErr string
+	}{
+		{name: "missing routing key", modify: func(p *PagerDuty) { p.RoutingKey = "" }, wantErr: "notifications.pagerduty.routing_key must be defined"},
+		{name: "invalid severity", modify: func(p *PagerDuty) { p.Severity = "sev1" }, wantErr: "notifications.pagerduty.severity must be one of critical, error, warning, info, got sev1"},
+		{name: "invalid dedup key", modify: func(p *PagerDuty) { p.DedupKey = "{{ .SelfName" }, wantErr: "notifications.pagerduty.dedup_key: failed to parse command template"},
+		{name: "unknown dedup key field", modify: func(p *PagerDuty) { p.DedupKey = "{{ .Nope }}" }, wantErr: "notifications.pagerduty.dedup_key: failed to execute command template"},
+		{name: "invalid url", modify: func(p *PagerDuty) { p.URL = "events.pagerduty.com" }, wantErr: "notifications.pagerduty.url must be a valid http(s) url"},
+		{name: "negative timeout", modify: func(p *PagerDuty) { p.Timeout = -1 }, wantErr: "notifications.pagerduty.timeout must be positive"},
+		{name: "negative retries", modify: func(p *PagerDuty) { retries := -1; p.Retries = &retries }, wantErr: "notifications.pagerduty.retries must not be negative"},
+		{name: "negative retry delay", modify: func(p *PagerDuty) { p.RetryDelay = -1 }, wantErr: "notifications.pagerduty.retry_delay must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pagerDuty := PagerDuty{RoutingKey: "routing-key"}
+			pagerDuty.SetDefaults()
+			tt.modify(&pagerDuty)
+			assert.ErrorContains(t, pagerDuty.Validate(), tt.wantErr)
+		})
+	}
+
+	// nothing to validate when notifications.pagerduty is not set
+	assert.NoError(t, (&Notifications{}).Validate())
+}
+
+func TestPagerDuty_Render(t *testing.T) {
+	t.Setenv("TEST_PAGERDUTY_ROUTING_KEY", "routing-key")
+
+	pagerDuty := PagerDuty{RoutingKey: "${TEST_PAGERDUTY_ROUTING_KEY}", DedupKey: "failover-{{ .ActiveIdentityPubkey }}"}
+	require.NoError(t, pagerDuty.Render(RoleCommandTemplateData{ActiveIdentityPubkey: "active-pubkey"}))
+	assert.Equal(t, "routing-key", pagerDuty.RoutingKey)
+	assert.Equal(t, "failover-active-pubkey", pagerDuty.DedupKey)
+
+	pagerDuty = PagerDuty{RoutingKey: "${TEST_PAGERDUTY_UNSET}", DedupKey: "failover"}
+	assert.EqualError(t, pagerDuty.Render(RoleCommandTemplateData{}), "notifications.pagerduty.routing_key references unset environment variables: TEST_PAGERDUTY_UNSET")
+
+	pagerDuty = PagerDuty{RoutingKey: "routing-key", DedupKey: "{{ .SelfName }}"}
+	assert.EqualError(t, pagerDuty.Render(RoleCommandTemplateData{}), "notifications.pagerduty.dedup_key must not render empty")
+}
+
+func TestResolvedYAML_RedactsPagerDutyRoutingKey(t *testing.T) {
+	cfg := Config{Notifications: Notifications{PagerDuty: &PagerDuty{RoutingKey: "0123456789abcdef0123456789abcdef"}}}
+	cfg.Notifications.SetDefaults()
+
+	data, err := cfg.ResolvedYAML()
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "0123456789abcdef0123456789abcdef")
+	assert.Contains(t, string(data), "routing_key: '"+RedactedValue+"'")
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/vault"
+)
+
+// homeDir returns the current user's home directory, looked up in the user database when $HOME isn't set as is
+// often the case under systemd and cron
+func homeDir() (string, error) {
+	if home, err := os.UserHomeDir(); err == nil {
+		return home, nil
+	}
+	current, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	return current.HomeDir, nil
+}
+
+// expandPath returns path with a leading ~ expanded to the home directory and, if relative, resolved against
+// baseDir - empty stays empty
+func expandPath(path string, baseDir string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := homeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// resolvePaths expands ~ in every file path in the configuration and resolves relative ones against the config
+// file's directory, so the agent finds the same files wherever it is started from
+func (c *Config) resolvePaths() error {
+	baseDir := filepath.Dir(c.File)
+
+	paths := []*string{
+		&c.Validator.Identities.ActiveKeyPairFile,
+		&c.Validator.Identities.VaultKeyPairDir,
+		&c.Log.File.Path,
+		&c.Prometheus.TextfilePath,
+		&c.Failover.Active.AdminRPC.LedgerPath,
+		&c.Failover.Active.AdminRPC.SocketPath,
+		&c.Failover.Passive.AdminRPC.LedgerPath,
+		&c.Failover.Passive.AdminRPC.SocketPath,
+		&c.Failover.Active.ClientConfigPath,
+		&c.Failover.Passive.ClientConfigPath,
+		&c.Failover.TowerSync.DestinationDir,
+		&c.Failover.DecisionLog.Path,
+		&c.Events.File,
+		&c.Audit.Path,
+		&c.Run.LockFile,
+		&c.Run.StateDir,
+		&c.State.File,
+		&c.Fitness.DiskPath,
+		&c.Gates.DiskSpace.Path,
+	}
+	for i := range c.Validator.Identities.PassiveKeyPairFiles {
+		paths = append(paths, &c.Validator.Identities.PassiveKeyPairFiles[i])
+	}
+	for _, tls := range []*TLS{c.Prometheus.TLS, c.HealthCheck.TLS, c.AdminAPI.TLS} {
+		if tls != nil {
+			paths = append(paths, &tls.CertFile, &tls.KeyFile)
+		}
+	}
+	if etcd := c.Failover.Coordination.Etcd; etcd != nil && etcd.TLS != nil {
+		paths = append(paths, &etcd.TLS.CAFile, &etcd.TLS.CertFile, &etcd.TLS.KeyFile)
+	}
+	for _, auth := range []*Auth{c.Prometheus.Auth, c.HealthCheck.Auth, c.AdminAPI.Auth, c.Failover.Heartbeat.Auth} {
+		if auth != nil {
+			paths = append(paths, &auth.BearerTokenFile)
+		}
+	}
+
+	for _, path := range paths {
+		// identities read from vault are not files
+		if vault.IsRef(*path) {
+			continue
+		}
+		resolved, err := expandPath(*path, baseDir)
+		if err != nil {
+			return err
+		}
+		*path = resolved
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "empty stays empty", path: "", expected: ""},
+		{name: "absolute", path: "/etc/solana/identity.json", expected: "/etc/solana/identity.json"},
+		{name: "home", path: "~", expected: home},
+		{name: "under home", path: "~/solana/identity.json", expected: filepath.Join(home, "solana/identity.json")},
+		{name: "relative", path: "identity.json", expected: "/etc/solana-validator-ha/identity.json"},
+		{name: "relative parent", path: "../solana/identity.json", expected: "/etc/solana/identity.json"},
+		{name: "tilde not followed by slash is relative", path: "~solana/identity.json", expected: "/etc/solana-validator-ha/~solana/identity.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expanded, err := expandPath(tt.path, "/etc/solana-validator-ha")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, expanded)
+		})
+	}
+}
+
+func TestNewFromConfigFile_RelativePaths(t *testing.T) {
+	configDir := t.TempDir()
+	for _, name := range []string{"active.json", "passive.json"} {
+		identityFile := createTempIdentityFile(t)
+		t.Cleanup(func() { os.Remove(identityFile) })
+		raw, err := os.ReadFile(identityFile)
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Join(configDir, "keys"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(configDir, "keys", name), raw, 0o600))
+	}
+
+	content := `
+validator:
+  name: "test-validator"
+  identities:
+    active: "keys/active.json"
+    passive: "./keys/passive.json"
+cluster:
+  name: "testnet"
+failover:
+  active:
+    command: "set-identity"
+  passive:
+    command: "set-identity"
+  peers:
+    validator-1:
+      ip: "192.168.1.10"
+  decision_log:
+    path: "~/decisions.log"
+run:
+  state_dir: "state"
+`
+	configFile := filepath.Join(configDir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(content), 0o600))
+
+	// started from elsewhere, paths still resolve against the config file's directory
+	t.Chdir(t.TempDir())
+
+	cfg, err := NewFromConfigFile(configFile)
+	require.NoError(t, err)
+
+	resolvedDir := filepath.Dir(cfg.File)
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(resolvedDir, "keys/active.json"), cfg.Validator.Identities.ActiveKeyPairFile)
+	assert.Equal(t, []string{filepath.Join(resolvedDir, "keys/passive.json")}, cfg.Validator.Identities.PassiveKeyPairFiles)
+	assert.Equal(t, filepath.Join(resolvedDir, "state"), cfg.Run.StateDir)
+	assert.Equal(t, filepath.Join(home, "decisions.log"), cfg.Failover.DecisionLog.Path)
+}
+
+func TestNewFromConfigFile_MissingIdentityNamesResolvedPath(t *testing.T) {
+	configDir := t.TempDir()
+	content := `
+validator:
+  name: "test-validator"
+  identities:
+    active: "missing.json"
+    passive: "missing.json"
+`
+	configFile := filepath.Join(configDir, "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(content), 0o600))
+
+	cfg, err := New(NewConfigParams{})
+	require.NoError(t, err)
+	require.NoError(t, cfg.LoadFromFile(configFile))
+
+	err = cfg.Initialize()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), filepath.Join(filepath.Dir(cfg.File), "missing.json"))
+}
@@ -2,8 +2,21 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"slices"
 	"sort"
 	"strings"
+
+	solanago "github.com/gagliardetto/solana-go"
+)
+
+const (
+	// PeerMatchByIP finds a peer in gossip by its ip, falling back to its pubkeys when it lists any
+	PeerMatchByIP = "ip"
+	// PeerMatchByPubkey finds a peer in gossip only by its pubkeys, for peers behind NAT or a relayer whose gossip
+	// address isn't their ip
+	PeerMatchByPubkey = "pubkey"
 )
 
 // Peers is a map of peer names to their IP addresses
@@ -11,8 +24,150 @@ type Peers map[string]Peer
 
 // Peer represents a peer validator
 type Peer struct {
-	IP   string `koanf:"ip"`
-	Name string `koanf:"-"`
+	IP string `koanf:"ip"`
+	// RPCURL is the peer's own validator RPC, probed directly each poll when set
+	RPCURL string `koanf:"rpc_url"`
+	// Pubkeys are the identities the peer runs with, e.g. its active and passive, matched in gossip when its IP isn't
+	Pubkeys []string `koanf:"pubkeys"`
+	// MatchBy is how the peer is found in gossip, PeerMatchByIP when empty
+	MatchBy string `koanf:"match_by"`
+	// Priority orders takeover claims when any peer sets one, highest first - ties are broken by name
+	Priority int    `koanf:"priority"`
+	Name     string `koanf:"-"`
+}
+
+// Validate checks every peer has a valid name unique regardless of case and a valid IPv4 or IPv6 address no other
+// peer shares - gossip peers are looked up by IP so a shared IP would hide one of them - and that peers matched by
+// pubkey list valid pubkeys. Every offending entry is listed.
+func (p *Peers) Validate() error {
+	names := make([]string, 0, len(*p))
+	for name := range *p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := []string{}
+	namesByIP := map[string][]string{}
+	namesByLowerName := map[string][]string{}
+	ips := []string{}
+	for _, name := range names {
+		peer := (*p)[name]
+		if err := ValidateName(fmt.Sprintf("peer name %q", name), name); err != nil {
+			problems = append(problems, err.Error())
+		}
+
+		if peer.RPCURL != "" {
+			if parsedURL, err := url.Parse(peer.RPCURL); err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+				problems = append(problems, fmt.Sprintf("invalid rpc_url %q for peer %s", peer.RPCURL, name))
+			}
+		}
+
+		if peer.MatchBy != "" && peer.MatchBy != PeerMatchByIP && peer.MatchBy != PeerMatchByPubkey {
+			problems = append(problems, fmt.Sprintf("invalid match_by %q for peer %s, must be %s or %s", peer.MatchBy, name, PeerMatchByIP, PeerMatchByPubkey))
+		}
+		if peer.MatchBy == PeerMatchByPubkey && len(peer.Pubkeys) == 0 {
+			problems = append(problems, fmt.Sprintf("match_by %s for peer %s requires pubkeys", PeerMatchByPubkey, name))
+		}
+		for _, pubkey := range peer.Pubkeys {
+			if _, err := solanago.PublicKeyFromBase58(pubkey); err != nil {
+				problems = append(problems, fmt.Sprintf("invalid pubkey %q for peer %s", pubkey, name))
+			}
+		}
+
+		lowerName := strings.ToLower(name)
+		namesByLowerName[lowerName] = append(namesByLowerName[lowerName], name)
+
+		ip := net.ParseIP(peer.IP)
+		if ip == nil {
+			problems = append(problems, fmt.Sprintf("invalid IP address %q for peer %s", peer.IP, name))
+			continue
+		}
+		// compare the canonical form so differently written IPv6 addresses are still duplicates
+		if _, ok := namesByIP[ip.String()]; !ok {
+			ips = append(ips, ip.String())
+		}
+		namesByIP[ip.String()] = append(namesByIP[ip.String()], name)
+	}
+
+	for _, name := range names {
+		if duplicates := namesByLowerName[strings.ToLower(name)]; len(duplicates) > 1 && duplicates[0] == name {
+			problems = append(problems, fmt.Sprintf("duplicate peer name %s for peers %s", strings.ToLower(name), strings.Join(duplicates, ", ")))
+		}
+	}
+	for _, ip := range ips {
+		if duplicates := namesByIP[ip]; len(duplicates) > 1 {
+			problems = append(problems, fmt.Sprintf("duplicate IP address %s for peers %s", ip, strings.Join(duplicates, ", ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("failover.peers - %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// SetDefaults writes every peer's IP in canonical form so it compares equal to gossip addresses, and sets match_by to
+// ip when unset - an empty pubkeys list is no pubkeys so the printed config loads back the same
+func (p *Peers) SetDefaults() {
+	for name, peer := range *p {
+		if ip := net.ParseIP(peer.IP); ip != nil {
+			peer.IP = ip.String()
+		}
+		if peer.MatchBy == "" {
+			peer.MatchBy = PeerMatchByIP
+		}
+		if len(peer.Pubkeys) == 0 {
+			peer.Pubkeys = nil
+		}
+		(*p)[name] = peer
+	}
+}
+
+// HasRPCURLs returns true if any peer has an rpc_url
+func (p *Peers) HasRPCURLs() bool {
+	for _, peer := range *p {
+		if peer.RPCURL != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesByIP returns true if the peer is found in gossip by its IP
+func (p *Peer) MatchesByIP() bool {
+	return p.MatchBy != PeerMatchByPubkey
+}
+
+// HasPubkey returns true if pubkey is one of the peer's pubkeys
+func (p *Peer) HasPubkey(pubkey string) bool {
+	return slices.Contains(p.Pubkeys, pubkey)
+}
+
+// HasPriorities returns true if any peer sets a priority
+func (p *Peers) HasPriorities() bool {
+	for _, peer := range *p {
+		if peer.Priority != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPriorityRankedNames returns the peer names by descending priority, ties broken by ascending name so the
+// order is common across all nodes sharing the same priorities
+func (p *Peers) GetPriorityRankedNames() []string {
+	names := make([]string, 0, len(*p))
+	for name := range *p {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := (*p)[names[i]], (*p)[names[j]]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return names[i] < names[j]
+	})
+	return names
 }
 
 // Add adds a peer to the peers map
@@ -30,6 +185,16 @@ func (p *Peers) HasIP(ip string) bool {
 	return false
 }
 
+// NameFromIP returns the name of the peer with the given IP address
+func (p *Peers) NameFromIP(ip string) (string, bool) {
+	for name, peer := range *p {
+		if peer.IP == ip {
+			return name, true
+		}
+	}
+	return "", false
+}
+
 // String returns a string representation of the peers
 func (p *Peers) String() string {
 	peerStrings := []string{}
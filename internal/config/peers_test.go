@@ -61,3 +61,143 @@ func TestPeers_GetIPs(t *testing.T) {
 	ips = emptyPeers.GetIPs()
 	assert.Len(t, ips, 0)
 }
+
+func TestPeers_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		peers   Peers
+		wantErr string
+	}{
+		{
+			name:  "valid ipv4 and ipv6",
+			peers: Peers{"validator-1": {IP: "192.168.1.10"}, "validator-2": {IP: "2001:db8::1"}},
+		},
+		{
+			name:    "invalid IP address",
+			peers:   Peers{"validator-1": {IP: "192.168.1.300"}, "validator-2": {IP: "192.168.1.11"}},
+			wantErr: `failover.peers - invalid IP address "192.168.1.300" for peer validator-1`,
+		},
+		{
+			name:    "empty IP address",
+			peers:   Peers{"validator-1": {}},
+			wantErr: `failover.peers - invalid IP address "" for peer validator-1`,
+		},
+		{
+			name:    "duplicate IP address lists every peer sharing it",
+			peers:   Peers{"validator-1": {IP: "192.168.1.10"}, "validator-2": {IP: "192.168.1.10"}, "validator-3": {IP: "192.168.1.10"}},
+			wantErr: "failover.peers - duplicate IP address 192.168.1.10 for peers validator-1, validator-2, validator-3",
+		},
+		{
+			name:    "differently written ipv6 addresses are duplicates",
+			peers:   Peers{"validator-1": {IP: "2001:db8::1"}, "validator-2": {IP: "2001:0db8:0:0:0:0:0:1"}},
+			wantErr: "failover.peers - duplicate IP address 2001:db8::1 for peers validator-1, validator-2",
+		},
+		{
+			name:  "valid rpc_url",
+			peers: Peers{"validator-1": {IP: "192.168.1.10", RPCURL: "http://192.168.1.10:8899"}},
+		},
+		{
+			name:    "invalid rpc_url",
+			peers:   Peers{"validator-1": {IP: "192.168.1.10", RPCURL: "192.168.1.10:8899"}},
+			wantErr: `failover.peers - invalid rpc_url "192.168.1.10:8899" for peer validator-1`,
+		},
+		{
+			name:    "empty name",
+			peers:   Peers{"": {IP: "192.168.1.10"}},
+			wantErr: `failover.peers - peer name "" must be defined`,
+		},
+		{
+			name:    "names differing only in case",
+			peers:   Peers{"Validator-1": {IP: "192.168.1.10"}, "validator-1": {IP: "192.168.1.11"}},
+			wantErr: "failover.peers - duplicate peer name validator-1 for peers Validator-1, validator-1",
+		},
+		{
+			name:  "matched by pubkey",
+			peers: Peers{"validator-1": {IP: "192.168.1.10", MatchBy: PeerMatchByPubkey, Pubkeys: []string{"11111111111111111111111111111111"}}},
+		},
+		{
+			name:    "invalid match_by",
+			peers:   Peers{"validator-1": {IP: "192.168.1.10", MatchBy: "name"}},
+			wantErr: `failover.peers - invalid match_by "name" for peer validator-1, must be ip or pubkey`,
+		},
+		{
+			name:    "matched by pubkey without pubkeys",
+			peers:   Peers{"validator-1": {IP: "192.168.1.10", MatchBy: PeerMatchByPubkey}},
+			wantErr: "failover.peers - match_by pubkey for peer validator-1 requires pubkeys",
+		},
+		{
+			name:    "invalid pubkey",
+			peers:   Peers{"validator-1": {IP: "192.168.1.10", Pubkeys: []string{"not-a-pubkey"}}},
+			wantErr: `failover.peers - invalid pubkey "not-a-pubkey" for peer validator-1`,
+		},
+		{
+			name: "every offending entry is listed",
+			peers: Peers{
+				"validator-1": {IP: "192.168.1.10"},
+				"validator-2": {IP: "192.168.1.10"},
+				"validator-3": {IP: "not-an-ip"},
+				"validator-4": {IP: "192.168.1.11"},
+				"validator-5": {IP: "192.168.1.11"},
+			},
+			wantErr: `failover.peers - invalid IP address "not-an-ip" for peer validator-3; ` +
+				"duplicate IP address 192.168.1.10 for peers validator-1, validator-2; " +
+				"duplicate IP address 192.168.1.11 for peers validator-4, validator-5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.peers.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestPeers_SetDefaults(t *testing.T) {
+	peers := Peers{
+		"validator-1": {IP: "192.168.1.10", Pubkeys: []string{}},
+		"validator-2": {IP: "192.168.1.11", MatchBy: PeerMatchByPubkey, Pubkeys: []string{"11111111111111111111111111111111"}},
+	}
+	peers.SetDefaults()
+
+	assert.Equal(t, Peer{IP: "192.168.1.10", MatchBy: PeerMatchByIP}, peers["validator-1"])
+	assert.Equal(t, PeerMatchByPubkey, peers["validator-2"].MatchBy)
+	validator1, validator2 := peers["validator-1"], peers["validator-2"]
+	assert.True(t, validator1.MatchesByIP())
+	assert.False(t, validator2.MatchesByIP())
+
+	// IPv6 addresses are written in canonical form
+	peers = Peers{"validator-1": {IP: "2001:0DB8:0:0:0:0:0:1"}, "validator-2": {IP: "[2001:db8::2]"}}
+	peers.SetDefaults()
+	assert.Equal(t, "2001:db8::1", peers["validator-1"].IP)
+	assert.Equal(t, "[2001:db8::2]", peers["validator-2"].IP, "invalid IPs are left for Validate to report")
+}
+
+func TestPeers_HasRPCURLs(t *testing.T) {
+	peers := Peers{"validator-1": {IP: "192.168.1.10"}}
+	assert.False(t, peers.HasRPCURLs())
+
+	peers["validator-2"] = Peer{IP: "192.168.1.11", RPCURL: "http://192.168.1.11:8899"}
+	assert.True(t, peers.HasRPCURLs())
+}
+
+func TestPeers_GetPriorityRankedNames(t *testing.T) {
+	peers := Peers{
+		"validator-c": {IP: "192.168.1.12"},
+		"validator-b": {IP: "192.168.1.11"},
+		"validator-a": {IP: "192.168.1.10"},
+	}
+	assert.False(t, peers.HasPriorities())
+	assert.Equal(t, []string{"validator-a", "validator-b", "validator-c"}, peers.GetPriorityRankedNames())
+
+	// highest priority first, ties broken by name
+	peers["validator-c"] = Peer{IP: "192.168.1.12", Priority: 10}
+	peers["validator-b"] = Peer{IP: "192.168.1.11", Priority: 5}
+	peers["validator-a"] = Peer{IP: "192.168.1.10", Priority: 5}
+	assert.True(t, peers.HasPriorities())
+	assert.Equal(t, []string{"validator-c", "validator-a", "validator-b"}, peers.GetPriorityRankedNames())
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ResolvedYAML returns the configuration as YAML with defaults applied and secrets redacted, followed by an
+// effective section with the ports and cluster rpc urls the agent will actually use. Keypair file paths are
+// printed, their contents are never read.
+func (c *Config) ResolvedYAML() ([]byte, error) {
+	root, err := c.resolvedNode(reflect.ValueOf(*c), "")
+	if err != nil {
+		return nil, err
+	}
+
+	effective, err := c.resolvedNode(reflect.ValueOf(struct {
+		ConfigFile           string   `koanf:"config_file"`
+		MetricsServerEnabled bool     `koanf:"metrics_server_enabled"`
+		PrometheusPort       int      `koanf:"prometheus_port"`
+		HealthCheckPort      int      `koanf:"health_check_port"`
+		ClusterRPCURLs       []string `koanf:"cluster_rpc_urls"`
+	}{
+		ConfigFile:           c.File,
+		MetricsServerEnabled: c.Prometheus.IsEnabled(),
+		PrometheusPort:       c.Prometheus.Port,
+		HealthCheckPort:      c.Prometheus.HealthCheckPort(),
+		ClusterRPCURLs:       c.Cluster.RPCURLs,
+	}), "effective")
+	if err != nil {
+		return nil, err
+	}
+
+	key := &yaml.Node{
+		Kind:        yaml.ScalarNode,
+		Value:       "effective",
+		HeadComment: "not configuration - derived from the above, what the agent will actually use",
+	}
+	root.Content = append(root.Content, key, effective)
+
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("error encoding config: %w", err)
+	}
+
+	return []byte(b.String()), nil
+}
+
+// resolvedNode returns the yaml node for v, struct fields are keyed by their koanf tag in declaration order and
+// fields tagged "-" or untagged are internal and left out. path is the dotted koanf path used in errors.
+func (c *Config) resolvedNode(v reflect.Value, path string) (*yaml.Node, error) {
+	if v.Type() == durationType {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: time.Duration(v.Int()).String()}, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+		}
+		return c.resolvedNode(v.Elem(), path)
+
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			tag := field.Tag.Get("koanf")
+			if !field.IsExported() || tag == "" || tag == "-" {
+				continue
+			}
+
+			value, err := c.resolvedNode(v.Field(i), strings.TrimPrefix(path+"."+tag, "."))
+			if err != nil {
+				return nil, err
+			}
+			// secrets like notifications.pagerduty.routing_key have no recognisable shape
+			if value.Kind == yaml.ScalarNode && value.Tag != "!!null" && slices.Contains(secretConfigKeys, tag) {
+				value = &yaml.Node{Kind: yaml.ScalarNode, Value: RedactedValue}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: tag}, value)
+		}
+		return node, nil
+
+	case reflect.Map:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		if v.Len() == 0 {
+			node.Style = yaml.FlowStyle
+		}
+
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			entry := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+			value, err := c.resolvedNode(entry, path+"."+key)
+			if err != nil {
+				return nil, err
+			}
+			// env vars like SLACK_TOKEN and webhook headers like Authorization are secret whatever their value looks like
+			secretKey := secretEnvNameRegexp.MatchString(key) || (strings.HasSuffix(path, ".headers") && secretHeaderNameRegexp.MatchString(key))
+			if value.Kind == yaml.ScalarNode && secretKey {
+				value = &yaml.Node{Kind: yaml.ScalarNode, Value: RedactedValue}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+		}
+		return node, nil
+
+	case reflect.Slice, reflect.Array:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		if v.Len() == 0 {
+			node.Style = yaml.FlowStyle
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			value, err := c.resolvedNode(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+		}
+		return node, nil
+
+	case reflect.String:
+		value := v.String()
+		// redact.patterns are printed as configured, they would often match themselves
+		if path != "redact" && !strings.HasPrefix(path, "redact.") {
+			value = c.Redact.String(value)
+		}
+		node := &yaml.Node{}
+		if err := node.Encode(value); err != nil {
+			return nil, fmt.Errorf("error encoding %s: %w", path, err)
+		}
+		return node, nil
+
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(v.Interface()); err != nil {
+			return nil, fmt.Errorf("error encoding %s: %w", path, err)
+		}
+		return node, nil
+	}
+}
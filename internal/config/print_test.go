@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const printTestConfig = `
+validator:
+  name: "test-validator"
+  identities:
+    active: "/nonexistent/active-identity.json"
+    passive: "/nonexistent/passive-identity.json"
+cluster:
+  name: "testnet"
+prometheus:
+  port: 9190
+failover:
+  active:
+    command: "set-identity"
+    args: ["--token=s3cr3t", "{{ .ActiveIdentityKeypairFile }}"]
+    env:
+      PAGERDUTY_API_KEY: "0123456789"
+      REGION: "fra-1"
+    hooks:
+      post:
+        - name: notify
+          command: curl
+          args: ["https://hooks.slack.com/services/T000/B000/XXXX"]
+  passive:
+    command: "set-identity"
+  peers:
+    validator-1:
+      ip: "192.168.1.10"
+redact:
+  patterns: ["fra-[0-9]+"]
+`
+
+func writePrintTestConfig(t *testing.T) string {
+	t.Helper()
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(printTestConfig), 0o600))
+	return configFile
+}
+
+func TestNewResolvedFromConfigFile(t *testing.T) {
+	// identity files don't exist - they must never be read
+	cfg, err := NewResolvedFromConfigFile(writePrintTestConfig(t))
+	require.NoError(t, err)
+	assert.Nil(t, cfg.Validator.Identities.ActiveKeyPair)
+	assert.Nil(t, cfg.Validator.Identities.PassiveKeyPair)
+
+	// defaults applied
+	assert.Equal(t, []string{"https://api.testnet.solana.com"}, cfg.Cluster.RPCURLs)
+	assert.NotZero(t, cfg.Run.StartupTimeout)
+
+	// invalid redact pattern is an error rather than printing unredacted
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("redact:\n  patterns: [\"(unclosed\"]\n"), 0o600))
+	_, err = NewResolvedFromConfigFile(configFile)
+	assert.ErrorContains(t, err, "redact.patterns[0]")
+}
+
+func TestConfig_ResolvedYAML(t *testing.T) {
+	configFile := writePrintTestConfig(t)
+	cfg, err := NewResolvedFromConfigFile(configFile)
+	require.NoError(t, err)
+
+	out, err := cfg.ResolvedYAML()
+	require.NoError(t, err)
+	printed := string(out)
+
+	// keypair paths, defaults and templates as configured
+	assert.Contains(t, printed, "active: /nonexistent/active-identity.json")
+	assert.Contains(t, printed, "- /nonexistent/passive-identity.json")
+	assert.Contains(t, printed, "startup_timeout: 2m0s")
+	assert.Contains(t, printed, "'{{ .ActiveIdentityKeypairFile }}'")
+
+	// secrets redacted, by value, by env var name and by configured pattern
+	for _, secret := range []string{"s3cr3t", "0123456789", "hooks.slack.com", "fra-1"} {
+		assert.NotContains(t, printed, secret)
+	}
+	assert.Contains(t, printed, "PAGERDUTY_API_KEY: '***'")
+	assert.Contains(t, printed, "REGION: '***'")
+	// patterns are printed as configured
+	assert.Contains(t, printed, "patterns:\n    - fra-[0-9]+\n")
+
+	// effective section
+	assert.Contains(t, printed, "effective:\n"+
+		"  config_file: "+cfg.File+"\n"+
+		"  metrics_server_enabled: true\n"+
+		"  prometheus_port: 9190\n"+
+		"  health_check_port: 9191\n"+
+		"  cluster_rpc_urls:\n"+
+		"    - https://api.testnet.solana.com\n")
+
+	// the printed config loads back to the same resolved values
+	reloadedFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(reloadedFile, out, 0o600))
+	reloaded, err := NewResolvedFromConfigFile(reloadedFile)
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Failover.PollIntervalDuration, reloaded.Failover.PollIntervalDuration)
+	assert.Equal(t, cfg.Run.StartupTimeout, reloaded.Run.StartupTimeout)
+	assert.Equal(t, cfg.Failover.Peers, reloaded.Failover.Peers)
+	assert.Equal(t, cfg.Validator.Identities.PassiveKeyPairFiles, reloaded.Validator.Identities.PassiveKeyPairFiles)
+	assert.Equal(t, cfg.Prometheus.Port, reloaded.Prometheus.Port)
+}
@@ -1,11 +1,47 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultBindAddress is the address the metrics and health check servers listen on when no bind_address is set -
+// every interface
+const DefaultBindAddress = "0.0.0.0"
 
 // Prometheus represents Prometheus metrics configuration
 type Prometheus struct {
-	Port         int               `koanf:"port"`
+	// Enabled serves metrics over http on port, nil means enabled
+	Enabled *bool `koanf:"enabled"`
+	Port    int   `koanf:"port"`
+	// BindAddress is the IP address the metrics server listens on, DefaultBindAddress if unset
+	BindAddress  string            `koanf:"bind_address"`
 	StaticLabels map[string]string `koanf:"static_labels"`
+	// Auth, if set, requires a bearer token to scrape /metrics
+	Auth *Auth `koanf:"auth"`
+	// TLS, if set, serves metrics over https, the certificate is reloaded on SIGHUP
+	TLS *TLS `koanf:"tls"`
+	// TextfilePath is an optional node_exporter textfile collector file metrics are mirrored to on every refresh
+	TextfilePath string `koanf:"textfile_path"`
+}
+
+// IsEnabled returns true if the metrics http server should be started
+func (p *Prometheus) IsEnabled() bool {
+	return p.Enabled == nil || *p.Enabled
+}
+
+// ListenAddress returns the host:port the metrics server listens on
+func (p *Prometheus) ListenAddress() string {
+	return net.JoinHostPort(p.BindAddress, strconv.Itoa(p.Port))
+}
+
+// HealthCheckPort returns the port the health check server listens on - the one after port
+func (p *Prometheus) HealthCheckPort() int {
+	return p.Port + 1
 }
 
 // Validate validates the Prometheus configuration
@@ -15,6 +51,46 @@ func (p *Prometheus) Validate() error {
 		return fmt.Errorf("prometheus.port must be positive and non-zero")
 	}
 
+	// prometheus.bind_address must be an IP address
+	if err := validateBindAddress("prometheus.bind_address", p.BindAddress); err != nil {
+		return err
+	}
+
+	// prometheus.auth must define a bearer token
+	if p.Auth != nil {
+		if err := p.Auth.Validate("prometheus.auth"); err != nil {
+			return err
+		}
+	}
+
+	// prometheus.tls must define a loadable certificate and key
+	if p.TLS != nil {
+		if err := p.TLS.Validate("prometheus.tls"); err != nil {
+			return err
+		}
+	}
+
+	// prometheus.static_labels names must be valid label names, values are stripped of
+	// control characters and length-capped so they can't break the exposition format
+	for labelName, labelValue := range p.StaticLabels {
+		if err := ValidateLabelName(fmt.Sprintf("prometheus.static_labels.%s", labelName), labelName); err != nil {
+			return err
+		}
+		p.StaticLabels[labelName] = SanitizeLabelValue(labelValue)
+	}
+
+	if p.TextfilePath != "" {
+		// prometheus.textfile_path must end in .prom - the textfile collector ignores anything else
+		if !strings.HasSuffix(p.TextfilePath, ".prom") {
+			return fmt.Errorf("prometheus.textfile_path must end in .prom")
+		}
+
+		// prometheus.textfile_path must be in an existing directory
+		if info, err := os.Stat(filepath.Dir(p.TextfilePath)); err != nil || !info.IsDir() {
+			return fmt.Errorf("prometheus.textfile_path must be in an existing directory: %s", filepath.Dir(p.TextfilePath))
+		}
+	}
+
 	return nil
 }
 
@@ -24,4 +100,16 @@ func (p *Prometheus) SetDefaults() {
 	if p.Port == 0 {
 		p.Port = 9090
 	}
+	if p.BindAddress == "" {
+		p.BindAddress = DefaultBindAddress
+	}
+}
+
+// validateBindAddress returns an error if the bind address at path is set and not an IP address. Host names are
+// rejected so the interface listened on never depends on name resolution.
+func validateBindAddress(path string, bindAddress string) error {
+	if bindAddress != "" && net.ParseIP(bindAddress) == nil {
+		return fmt.Errorf("%s must be a valid IP address, got %s", path, bindAddress)
+	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,6 +12,12 @@ func TestPrometheus_SetDefaults(t *testing.T) {
 	prometheus.SetDefaults()
 
 	assert.Equal(t, 9090, prometheus.Port)
+	assert.Equal(t, DefaultBindAddress, prometheus.BindAddress)
+	assert.Equal(t, "0.0.0.0:9090", prometheus.ListenAddress())
+
+	prometheus = &Prometheus{BindAddress: "127.0.0.1"}
+	prometheus.SetDefaults()
+	assert.Equal(t, "127.0.0.1", prometheus.BindAddress)
 }
 
 func TestPrometheus_Validate(t *testing.T) {
@@ -42,3 +49,82 @@ func TestPrometheus_Validate(t *testing.T) {
 	err = prometheus.Validate()
 	assert.NoError(t, err)
 }
+
+func TestPrometheus_IsEnabled(t *testing.T) {
+	prometheus := &Prometheus{}
+	assert.True(t, prometheus.IsEnabled())
+
+	enabled := false
+	prometheus.Enabled = &enabled
+	assert.False(t, prometheus.IsEnabled())
+
+	enabled = true
+	assert.True(t, prometheus.IsEnabled())
+}
+
+func TestPrometheus_Validate_TextfilePath(t *testing.T) {
+	dir := t.TempDir()
+	prometheus := &Prometheus{Port: 9090}
+
+	prometheus.TextfilePath = filepath.Join(dir, "solana_validator_ha.prom")
+	assert.NoError(t, prometheus.Validate())
+
+	prometheus.TextfilePath = filepath.Join(dir, "solana_validator_ha.txt")
+	err := prometheus.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "prometheus.textfile_path must end in .prom")
+
+	prometheus.TextfilePath = filepath.Join(dir, "missing", "solana_validator_ha.prom")
+	err = prometheus.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "prometheus.textfile_path must be in an existing directory")
+}
+
+func TestPrometheus_Validate_BindAddress(t *testing.T) {
+	prometheus := &Prometheus{Port: 9090}
+
+	for _, bindAddress := range []string{"0.0.0.0", "127.0.0.1", "::1"} {
+		prometheus.BindAddress = bindAddress
+		assert.NoError(t, prometheus.Validate(), bindAddress)
+	}
+	assert.Equal(t, "[::1]:9090", prometheus.ListenAddress())
+
+	for _, bindAddress := range []string{"localhost", "not-an-ip", "127.0.0.1:9090", "256.0.0.1"} {
+		prometheus.BindAddress = bindAddress
+		err := prometheus.Validate()
+		assert.EqualError(t, err, "prometheus.bind_address must be a valid IP address, got "+bindAddress)
+	}
+}
+
+func TestHealthCheck_DialHost(t *testing.T) {
+	tests := []struct {
+		bindAddress string
+		want        string
+	}{
+		{bindAddress: "", want: "127.0.0.1"},
+		{bindAddress: "0.0.0.0", want: "127.0.0.1"},
+		{bindAddress: "::", want: "::1"},
+		{bindAddress: "127.0.0.1", want: "127.0.0.1"},
+		{bindAddress: "::1", want: "::1"},
+		{bindAddress: "10.0.0.5", want: "10.0.0.5"},
+	}
+
+	for _, tt := range tests {
+		healthCheck := &HealthCheck{BindAddress: tt.bindAddress}
+		assert.Equal(t, tt.want, healthCheck.DialHost(), tt.bindAddress)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	healthCheck := &HealthCheck{}
+	healthCheck.SetDefaults()
+	assert.Equal(t, DefaultBindAddress, healthCheck.BindAddress)
+	assert.NoError(t, healthCheck.Validate())
+
+	healthCheck.BindAddress = "127.0.0.1"
+	assert.NoError(t, healthCheck.Validate())
+	assert.Equal(t, "127.0.0.1:9091", healthCheck.ListenAddress(9091))
+
+	healthCheck.BindAddress = "garbage"
+	assert.EqualError(t, healthCheck.Validate(), "healthcheck.bind_address must be a valid IP address, got garbage")
+}
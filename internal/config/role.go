@@ -1,20 +1,52 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
 	"text/template"
+	"time"
 
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/admin"
+	"github.com/sol-strategies/solana-validator-ha/internal/audit"
 	"github.com/sol-strategies/solana-validator-ha/internal/command"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
 )
 
+const (
+	// RoleMethodCommand sets the role's identity by running role.command, the default
+	RoleMethodCommand = "command"
+	// RoleMethodAgaveAdminRPC sets the role's identity by calling setIdentity on the agave validator's admin rpc
+	RoleMethodAgaveAdminRPC = "agave_admin_rpc"
+
+	// RoleClientCustom runs role.command as configured, the default
+	RoleClientCustom = "custom"
+	// RoleClientAgave defaults role.command to agave-validator set-identity with the ledger at client_config_path
+	RoleClientAgave = "agave"
+	// RoleClientFiredancer defaults role.command to fdctl set-identity with the config.toml at client_config_path
+	RoleClientFiredancer = "firedancer"
+)
+
+// roleClientCommands are the binary each built-in client's identity is set with
+var roleClientCommands = map[string]string{
+	RoleClientAgave:      "agave-validator",
+	RoleClientFiredancer: "fdctl",
+}
+
 // RoleCommandTemplateData represents data available for command templates
 type RoleCommandTemplateData struct {
 	ActiveIdentityKeypairFile  string
 	ActiveIdentityPubkey       string
 	PassiveIdentityKeypairFile string
 	PassiveIdentityPubkey      string
-	SelfName                   string
+	// PassiveIdentityKeypairFiles and PassiveIdentityPubkeys list every passive identity, the primary first
+	PassiveIdentityKeypairFiles []string
+	PassiveIdentityPubkeys      []string
+	SelfName                    string
+	// ClientConfigPath is the role's client_config_path - the agave ledger or firedancer config.toml
+	ClientConfigPath string
 }
 
 // Role represents configuration for active/passive role transitions
@@ -24,26 +56,191 @@ type Role struct {
 	Args    []string          `koanf:"args"`
 	Env     map[string]string `koanf:"env"`
 	Hooks   Hooks             `koanf:"hooks"`
+	// RollbackOnFailure runs the opposite role's command and hooks once to return to the previous identity
+	// when this role's transition fails after its command ran
+	RollbackOnFailure bool `koanf:"rollback_on_failure"`
+	// SkipPostOnFailure doesn't run the post hooks when the role command fails
+	SkipPostOnFailure bool `koanf:"skip_post_on_failure"`
+	// Method is how the role's identity is set, one of RoleMethodCommand or RoleMethodAgaveAdminRPC
+	Method string `koanf:"method"`
+	// AdminRPC is where and how setIdentity is called when method is agave_admin_rpc
+	AdminRPC AdminRPC `koanf:"admin_rpc"`
+	// Client is the validator client whose ready-made command and args are used when they are not set, one of
+	// RoleClientCustom, RoleClientAgave or RoleClientFiredancer
+	Client string `koanf:"client"`
+	// ClientConfigPath is the agave ledger directory or the firedancer config.toml the ready-made args are given
+	ClientConfigPath string `koanf:"client_config_path"`
+	// IdentityKeypairFile is the keypair file the role's identity is set from - set automatically by system
+	IdentityKeypairFile string `koanf:"-"`
+}
+
+// AdminRPC configures calling the agave validator's admin rpc to set a role's identity
+type AdminRPC struct {
+	// LedgerPath is the validator's ledger directory, holding the admin.rpc socket
+	LedgerPath string `koanf:"ledger_path"`
+	// SocketPath is the admin rpc socket, overriding ledger_path
+	SocketPath string `koanf:"socket_path"`
+	// RequireTower makes the validator refuse the identity unless it has a saved tower for it, as
+	// agave-validator set-identity --require-tower does
+	RequireTower bool `koanf:"require_tower"`
+	// Timeout bounds the setIdentity call
+	Timeout time.Duration `koanf:"timeout"`
+}
+
+// Socket returns the admin rpc socket, socket_path if set else admin.rpc in ledger_path
+func (a *AdminRPC) Socket() string {
+	if a.SocketPath != "" {
+		return a.SocketPath
+	}
+	return admin.SocketPath(a.LedgerPath)
+}
+
+// SetDefaults sets default values for the admin rpc configuration
+func (a *AdminRPC) SetDefaults() {
+	if a.Timeout == 0 {
+		a.Timeout = admin.DefaultTimeout
+	}
 }
 
 type RoleCommandRunOptions struct {
+	// Env is merged over role.env, e.g. to flag a rollback
+	Env          map[string]string
 	DryRun       bool
 	LoggerPrefix string
 	LoggerArgs   []any
+	// FailoverID, if set, is logged with every line the role command logs
+	FailoverID string
+	// Audit, if set, records the role command or setIdentity call, dry runs included
+	Audit *audit.Log
+	// WriteKeypairFiles, if set, writes the identity keypair files held in memory before the role command runs -
+	// never in dry run
+	WriteKeypairFiles func() error
+	// RemoveKeypairFiles, if set, removes the files WriteKeypairFiles wrote once the role command has finished
+	RemoveKeypairFiles func() error
+}
+
+// removeKeypairFiles removes the keypair files written for the role command, logging to logger if it can't
+func (o RoleCommandRunOptions) removeKeypairFiles(logger *log.Logger) {
+	if o.RemoveKeypairFiles == nil {
+		return
+	}
+	if err := o.RemoveKeypairFiles(); err != nil {
+		logger.Warn("failed to remove identity keypair files", "error", err)
+	}
+}
+
+// RoleCommandResult is the outcome of running a role command
+type RoleCommandResult struct {
+	// ExitCode is 0 on success and -1 if the command could not be started
+	ExitCode int
+	Duration time.Duration
+	DryRun   bool
+}
+
+// SetDefaults sets default values for the role's method, client and admin rpc - with a built-in client an unset
+// command and args are its ready-made ones, setting the identity of the role by its name
+func (r *Role) SetDefaults() {
+	if r.Method == "" {
+		r.Method = RoleMethodCommand
+	}
+	if r.Client == "" {
+		r.Client = RoleClientCustom
+	}
+	r.AdminRPC.SetDefaults()
+
+	if r.Client == RoleClientCustom || r.UsesAdminRPC() {
+		return
+	}
+	if r.Command == "" {
+		r.Command = roleClientCommands[r.Client]
+	}
+	if len(r.Args) == 0 {
+		r.Args = r.clientArgs()
+	}
+}
+
+// clientArgs returns the built-in client's ready-made args setting the role's identity
+func (r *Role) clientArgs() []string {
+	keypairFile := "{{ .PassiveIdentityKeypairFile }}"
+	if r.Name == "active" {
+		keypairFile = "{{ .ActiveIdentityKeypairFile }}"
+	}
+
+	switch r.Client {
+	case RoleClientAgave:
+		// only take over the active identity with its tower, as agave-validator set-identity --require-tower does
+		if r.Name == "active" {
+			return []string{"--ledger", "{{ .ClientConfigPath }}", "set-identity", "--require-tower", keypairFile}
+		}
+		return []string{"--ledger", "{{ .ClientConfigPath }}", "set-identity", keypairFile}
+	case RoleClientFiredancer:
+		return []string{"set-identity", "--config", "{{ .ClientConfigPath }}", keypairFile}
+	}
+	return nil
+}
+
+// UsesAdminRPC returns true when the role's identity is set through the agave admin rpc rather than role.command
+func (r *Role) UsesAdminRPC() bool {
+	return r.Method == RoleMethodAgaveAdminRPC
 }
 
 // Validate validates the role configuration
 func (r *Role) Validate() error {
-	// role.command must be defined
-	if r.Command == "" {
+	// role.method must be valid
+	if err := r.validateMethod(); err != nil {
+		return fmt.Errorf("role.%w", err)
+	}
+
+	// role.command must be defined unless role.method is agave_admin_rpc
+	if r.Command == "" && !r.UsesAdminRPC() {
 		return fmt.Errorf("role.command must be defined")
 	}
 
 	return r.Hooks.Validate()
 }
 
+// validateMethod validates the method and, with agave_admin_rpc, the admin rpc configuration - errors are relative
+// to the role so callers prefix them with its path
+func (r *Role) validateMethod() error {
+	// method must be command or agave_admin_rpc
+	if r.Method != "" && r.Method != RoleMethodCommand && r.Method != RoleMethodAgaveAdminRPC {
+		return fmt.Errorf("method must be one of %s or %s, got %q", RoleMethodCommand, RoleMethodAgaveAdminRPC, r.Method)
+	}
+	// client must be custom, agave or firedancer
+	if r.Client != "" && r.Client != RoleClientCustom && r.Client != RoleClientAgave && r.Client != RoleClientFiredancer {
+		return fmt.Errorf("client must be one of %s, %s or %s, got %q", RoleClientCustom, RoleClientAgave, RoleClientFiredancer, r.Client)
+	}
+
+	// client_config_path must be defined for a built-in client's ready-made args
+	if roleClientCommands[r.Client] != "" && !r.UsesAdminRPC() && r.ClientConfigPath == "" {
+		return fmt.Errorf("client_config_path must be defined when client is %s", r.Client)
+	}
+
+	if !r.UsesAdminRPC() {
+		return nil
+	}
+
+	// method agave_admin_rpc talks to agave's admin rpc socket, firedancer has none
+	if r.Client == RoleClientFiredancer {
+		return fmt.Errorf("method must be %s when client is %s, got %s", RoleMethodCommand, RoleClientFiredancer, r.Method)
+	}
+
+	// admin_rpc.ledger_path or admin_rpc.socket_path must be defined with method agave_admin_rpc
+	if r.AdminRPC.LedgerPath == "" && r.AdminRPC.SocketPath == "" {
+		return fmt.Errorf("admin_rpc.ledger_path or admin_rpc.socket_path must be defined when method is %s", RoleMethodAgaveAdminRPC)
+	}
+
+	// admin_rpc.timeout must not be negative
+	if r.AdminRPC.Timeout < 0 {
+		return fmt.Errorf("admin_rpc.timeout must not be negative, got %s", r.AdminRPC.Timeout)
+	}
+	return nil
+}
+
 // RenderCommands renders the role commands
 func (r *Role) RenderCommands(data RoleCommandTemplateData) (err error) {
+	data.ClientConfigPath = r.ClientConfigPath
+
 	// render role.command, role.args, and role.env
 	err = r.renderCommandAndArgs(data)
 	if err != nil {
@@ -52,7 +249,7 @@ func (r *Role) RenderCommands(data RoleCommandTemplateData) (err error) {
 
 	// render role.hooks.pre
 	for i := range r.Hooks.Pre {
-		err = r.renderHook(data, &r.Hooks.Pre[i])
+		err = renderHook(data, &r.Hooks.Pre[i])
 		if err != nil {
 			return fmt.Errorf("failed to render role.hooks.pre[%d]: %w", i, err)
 		}
@@ -60,7 +257,7 @@ func (r *Role) RenderCommands(data RoleCommandTemplateData) (err error) {
 
 	// render role.hooks.post
 	for i := range r.Hooks.Post {
-		err = r.renderHook(data, &r.Hooks.Post[i])
+		err = renderHook(data, &r.Hooks.Post[i])
 		if err != nil {
 			return fmt.Errorf("failed to render role.hooks.post[%d]: %w", i, err)
 		}
@@ -71,14 +268,14 @@ func (r *Role) RenderCommands(data RoleCommandTemplateData) (err error) {
 
 func (r *Role) renderCommandAndArgs(data RoleCommandTemplateData) (err error) {
 	// render command
-	r.Command, err = r.renderTemplateString(data, r.Command)
+	r.Command, err = renderTemplateString(data, r.Command)
 	if err != nil {
 		return fmt.Errorf("failed to render command: %w", err)
 	}
 
 	// render args
 	for i, arg := range r.Args {
-		r.Args[i], err = r.renderTemplateString(data, arg)
+		r.Args[i], err = renderTemplateString(data, arg)
 		if err != nil {
 			return fmt.Errorf("failed to render args[%d]: %w", i, err)
 		}
@@ -86,7 +283,7 @@ func (r *Role) renderCommandAndArgs(data RoleCommandTemplateData) (err error) {
 
 	// render environment variables
 	for key, value := range r.Env {
-		r.Env[key], err = r.renderTemplateString(data, value)
+		r.Env[key], err = renderTemplateString(data, value)
 		if err != nil {
 			return fmt.Errorf("failed to render env[%s]: %w", key, err)
 		}
@@ -95,25 +292,39 @@ func (r *Role) renderCommandAndArgs(data RoleCommandTemplateData) (err error) {
 	return nil
 }
 
-func (r *Role) renderHook(data RoleCommandTemplateData, hook *Hook) (err error) {
+// renderHook renders a hook's command and args
+func renderHook(data RoleCommandTemplateData, hook *Hook) (err error) {
 	// render hook command
-	hook.Command, err = r.renderTemplateString(data, hook.Command)
+	hook.Command, err = renderTemplateString(data, hook.Command)
 	if err != nil {
 		return fmt.Errorf("failed to render hook command: %w", err)
 	}
 
 	// render hook args
 	for i, arg := range hook.Args {
-		hook.Args[i], err = r.renderTemplateString(data, arg)
+		hook.Args[i], err = renderTemplateString(data, arg)
 		if err != nil {
 			return fmt.Errorf("failed to render hook args[%d]: %w", i, err)
 		}
 	}
 
+	// render hook slack channel and message
+	if hook.Slack != nil {
+		hook.Slack.Channel, err = renderTemplateString(data, hook.Slack.Channel)
+		if err != nil {
+			return fmt.Errorf("failed to render hook slack.channel: %w", err)
+		}
+		hook.Slack.Message, err = renderTemplateString(data, hook.Slack.Message)
+		if err != nil {
+			return fmt.Errorf("failed to render hook slack.message: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func (r *Role) renderTemplateString(data RoleCommandTemplateData, templateStr string) (rendered string, err error) {
+// renderTemplateString renders a Go template string with the given data
+func renderTemplateString(data RoleCommandTemplateData, templateStr string) (rendered string, err error) {
 	// Parse and execute template
 	tmpl, err := template.New("command").Parse(templateStr)
 	if err != nil {
@@ -127,7 +338,13 @@ func (r *Role) renderTemplateString(data RoleCommandTemplateData, templateStr st
 	return buf.String(), nil
 }
 
-func (r *Role) RunCommand(opts RoleCommandRunOptions) error {
+// RunCommand runs the role command, or calls the admin rpc setIdentity with method agave_admin_rpc, returning how
+// long it took and its exit code along with any error
+func (r *Role) RunCommand(opts RoleCommandRunOptions) (RoleCommandResult, error) {
+	if r.UsesAdminRPC() {
+		return r.setIdentity(opts)
+	}
+
 	loggerArgs := []any{
 		"command", r.Command,
 		"args", r.Args,
@@ -136,23 +353,129 @@ func (r *Role) RunCommand(opts RoleCommandRunOptions) error {
 	}
 	loggerArgs = append(loggerArgs, opts.LoggerArgs...)
 
+	result := RoleCommandResult{DryRun: opts.DryRun}
 	if opts.DryRun {
-		return nil
+		err := command.AuditDryRun(command.RunOptions{
+			Name:       r.Name,
+			Command:    r.Command,
+			Args:       r.Args,
+			FailoverID: opts.FailoverID,
+			Audit:      opts.Audit,
+		})
+		if err != nil {
+			loglevel.WithPrefix(loglevel.Command, fmt.Sprintf("[%s command %s]", opts.LoggerPrefix, r.Name)).Warn("failed to append command to the audit log", "error", err)
+		}
+		return result, nil
 	}
 
+	if opts.WriteKeypairFiles != nil {
+		defer opts.removeKeypairFiles(loglevel.WithPrefix(loglevel.Command, fmt.Sprintf("[%s command %s]", opts.LoggerPrefix, r.Name)))
+		if err := opts.WriteKeypairFiles(); err != nil {
+			return RoleCommandResult{ExitCode: -1}, err
+		}
+	}
+
+	// extra env must not change whether the agent's environment is inherited - it only is without role.env
+	env := make(map[string]string, len(r.Env)+len(opts.Env))
+	for key, value := range r.Env {
+		env[key] = value
+	}
+	for key, value := range opts.Env {
+		env[key] = value
+	}
+
+	startedAt := time.Now()
 	err := command.Run(command.RunOptions{
 		Name:         r.Name,
 		Command:      r.Command,
 		Args:         r.Args,
-		Env:          r.Env,
+		Env:          env,
+		InheritEnv:   len(r.Env) == 0,
 		DryRun:       opts.DryRun,
 		LoggerPrefix: opts.LoggerPrefix,
 		LoggerArgs:   loggerArgs,
 		StreamOutput: true,
+		FailoverID:   opts.FailoverID,
+		Audit:        opts.Audit,
 	})
+	result.Duration = time.Since(startedAt)
+	result.ExitCode = command.ExitCode(err)
 	if err != nil {
-		return fmt.Errorf("failed to run command: %w", err)
+		return result, fmt.Errorf("failed to run command: %w", err)
 	}
 
-	return nil
+	return result, nil
+}
+
+// setIdentity calls the admin rpc setIdentity with the role's identity keypair file - the exit code is -1 when the
+// call fails
+func (r *Role) setIdentity(opts RoleCommandRunOptions) (RoleCommandResult, error) {
+	socket := r.AdminRPC.Socket()
+	logger := loglevel.WithPrefix(loglevel.Command, fmt.Sprintf("[%s admin-rpc %s]", opts.LoggerPrefix, r.Name)).With(opts.LoggerArgs...).With(
+		"method", RoleMethodAgaveAdminRPC,
+		"socket", socket,
+		"keypair_file", r.IdentityKeypairFile,
+		"require_tower", r.AdminRPC.RequireTower,
+		"dry_run", opts.DryRun,
+	)
+
+	if opts.FailoverID != "" {
+		logger = logger.With("failover_id", opts.FailoverID)
+	}
+
+	result := RoleCommandResult{DryRun: opts.DryRun}
+	startedAt := time.Now()
+	var err error
+	if opts.Audit != nil {
+		defer func() {
+			if auditErr := opts.Audit.Append(r.setIdentityAuditRecord(socket, opts, startedAt, result, err)); auditErr != nil {
+				logger.Warn("failed to append setIdentity to the audit log", "path", opts.Audit.Path(), "error", auditErr)
+			}
+		}()
+	}
+
+	if opts.DryRun {
+		logger.Info("dry_run - would have called admin rpc setIdentity")
+		return result, nil
+	}
+
+	if opts.WriteKeypairFiles != nil {
+		defer opts.removeKeypairFiles(logger)
+		if err = opts.WriteKeypairFiles(); err != nil {
+			result.ExitCode = -1
+			return result, err
+		}
+	}
+
+	logger.Info("calling admin rpc setIdentity")
+	err = admin.New(socket, r.AdminRPC.Timeout).SetIdentity(context.Background(), r.IdentityKeypairFile, r.AdminRPC.RequireTower)
+	result.Duration = time.Since(startedAt)
+	if err != nil {
+		result.ExitCode = -1
+		return result, fmt.Errorf("failed to set identity: %w", err)
+	}
+
+	logger.Info("admin rpc setIdentity succeeded", "duration", result.Duration)
+	return result, nil
+}
+
+// setIdentityAuditRecord returns the audit record of a setIdentity call on socket started at startedAt - it is
+// recorded as the admin-rpc setIdentity command with the keypair file and require tower as its args
+func (r *Role) setIdentityAuditRecord(socket string, opts RoleCommandRunOptions, startedAt time.Time, result RoleCommandResult, err error) audit.Record {
+	dir, _ := os.Getwd()
+	record := audit.Record{
+		Timestamp:       startedAt,
+		FailoverID:      opts.FailoverID,
+		Name:            r.Name,
+		Command:         "admin-rpc " + socket + " setIdentity",
+		Args:            []string{r.IdentityKeypairFile, fmt.Sprintf("require_tower=%t", r.AdminRPC.RequireTower)},
+		Dir:             dir,
+		ExitCode:        result.ExitCode,
+		DurationSeconds: result.Duration.Seconds(),
+		DryRun:          opts.DryRun,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	return record
 }
@@ -1,9 +1,15 @@
 package config
 
 import (
+	"errors"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/sol-strategies/solana-validator-ha/internal/audit"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRole_Validate(t *testing.T) {
@@ -106,24 +112,265 @@ func TestRole_RenderCommandsWithInvalidEnvTemplate(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to render env[SOLANA_IDENTITY]")
 }
 
-func TestRole_RenderTemplateString(t *testing.T) {
-	role := &Role{}
+func TestRenderTemplateString(t *testing.T) {
 	data := RoleCommandTemplateData{
 		ActiveIdentityPubkey: "test-pubkey",
 	}
 
 	// Test simple template
-	result, err := role.renderTemplateString(data, "echo {{.ActiveIdentityPubkey}}")
+	result, err := renderTemplateString(data, "echo {{.ActiveIdentityPubkey}}")
 	assert.NoError(t, err)
 	assert.Equal(t, "echo test-pubkey", result)
 
 	// Test template with multiple fields
-	result, err = role.renderTemplateString(data, "{{.ActiveIdentityPubkey}} {{.PassiveIdentityPubkey}}")
+	result, err = renderTemplateString(data, "{{.ActiveIdentityPubkey}} {{.PassiveIdentityPubkey}}")
 	assert.NoError(t, err)
 	assert.Equal(t, "test-pubkey ", result) // PassiveIdentityPubkey is empty
 
 	// Test invalid template
-	_, err = role.renderTemplateString(data, "{{.InvalidField}}")
+	_, err = renderTemplateString(data, "{{.InvalidField}}")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to execute command template")
 }
+
+func TestRole_RunCommand_EnvKeepsInheritance(t *testing.T) {
+	// without role.env the agent's environment is inherited, extra env must not change that
+	role := &Role{
+		Name:    "passive",
+		Command: "sh",
+		Args:    []string{"-c", `test -n "$PATH" && test "$SVHA_TRIGGER" = rollback`},
+	}
+	_, err := role.RunCommand(RoleCommandRunOptions{Env: map[string]string{"SVHA_TRIGGER": "rollback"}})
+	assert.NoError(t, err)
+
+	// with role.env only role.env and the extra env are set
+	role.Env = map[string]string{"ROLE_VAR": "set"}
+	role.Args = []string{"-c", `test "$ROLE_VAR" = set && test "$SVHA_TRIGGER" = rollback && test -z "$HOME"`}
+	_, err = role.RunCommand(RoleCommandRunOptions{Env: map[string]string{"SVHA_TRIGGER": "rollback"}})
+	assert.NoError(t, err)
+}
+
+func TestRole_RunCommand_Result(t *testing.T) {
+	role := &Role{Name: "active", Command: "sh", Args: []string{"-c", "sleep 0.05"}}
+	result, err := role.RunCommand(RoleCommandRunOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.GreaterOrEqual(t, result.Duration, 50*time.Millisecond)
+	assert.False(t, result.DryRun)
+
+	role.Args = []string{"-c", "exit 3"}
+	result, err = role.RunCommand(RoleCommandRunOptions{})
+	assert.Error(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+
+	// nothing is run in dry run
+	result, err = role.RunCommand(RoleCommandRunOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, RoleCommandResult{DryRun: true}, result)
+}
+
+func TestRole_RunCommand_WriteKeypairFiles(t *testing.T) {
+	writes, removes := 0, 0
+	writeKeypairFiles := func() error {
+		writes++
+		return nil
+	}
+	removeKeypairFiles := func() error {
+		removes++
+		return nil
+	}
+
+	// written before the command runs and removed once it has finished, never in dry run
+	file := filepath.Join(t.TempDir(), "removes")
+	role := &Role{Name: "active", Command: "sh", Args: []string{"-c", "echo " + file + " > " + file}}
+	_, err := role.RunCommand(RoleCommandRunOptions{
+		WriteKeypairFiles: writeKeypairFiles,
+		RemoveKeypairFiles: func() error {
+			assert.FileExists(t, file)
+			return removeKeypairFiles()
+		},
+	})
+	require.NoError(t, err)
+	_, err = role.RunCommand(RoleCommandRunOptions{DryRun: true, WriteKeypairFiles: writeKeypairFiles, RemoveKeypairFiles: removeKeypairFiles})
+	require.NoError(t, err)
+	assert.Equal(t, 1, writes)
+	assert.Equal(t, 1, removes)
+
+	// the command doesn't run without its keypair files, those written are removed
+	role = &Role{Name: "active", Command: "false"}
+	result, err := role.RunCommand(RoleCommandRunOptions{
+		WriteKeypairFiles:  func() error { return errors.New("tmpfs is full") },
+		RemoveKeypairFiles: removeKeypairFiles,
+	})
+	assert.EqualError(t, err, "tmpfs is full")
+	assert.Equal(t, -1, result.ExitCode)
+	assert.Equal(t, 2, removes)
+}
+
+func TestRole_Validate_Method(t *testing.T) {
+	role := &Role{Method: "ssh"}
+	assert.EqualError(t, role.Validate(), `role.method must be one of command or agave_admin_rpc, got "ssh"`)
+
+	// the admin rpc needs no command but does need its socket
+	role.Method = RoleMethodAgaveAdminRPC
+	assert.EqualError(t, role.Validate(), "role.admin_rpc.ledger_path or admin_rpc.socket_path must be defined when method is agave_admin_rpc")
+
+	role.AdminRPC.LedgerPath = "/mnt/ledger"
+	assert.NoError(t, role.Validate())
+	assert.Equal(t, "/mnt/ledger/admin.rpc", role.AdminRPC.Socket())
+
+	role.AdminRPC.SocketPath = "/run/agave/admin.rpc"
+	assert.Equal(t, "/run/agave/admin.rpc", role.AdminRPC.Socket())
+
+	role.AdminRPC.Timeout = -time.Second
+	assert.EqualError(t, role.Validate(), "role.admin_rpc.timeout must not be negative, got -1s")
+
+	// the command method still needs a command
+	role = &Role{Method: RoleMethodCommand}
+	assert.EqualError(t, role.Validate(), "role.command must be defined")
+}
+
+func TestRole_SetDefaults(t *testing.T) {
+	role := &Role{}
+	role.SetDefaults()
+	assert.Equal(t, RoleMethodCommand, role.Method)
+	assert.Equal(t, RoleClientCustom, role.Client)
+	assert.Equal(t, 10*time.Second, role.AdminRPC.Timeout)
+	// a custom client's command is as configured
+	assert.Empty(t, role.Command)
+	assert.Empty(t, role.Args)
+}
+
+func TestRole_Client_ReadyMadeCommands(t *testing.T) {
+	data := RoleCommandTemplateData{
+		ActiveIdentityKeypairFile:  "/path/to/active.json",
+		PassiveIdentityKeypairFile: "/path/to/passive.json",
+	}
+
+	tests := []struct {
+		name    string
+		role    Role
+		command string
+		args    []string
+	}{
+		{
+			name:    "agave active",
+			role:    Role{Name: "active", Client: RoleClientAgave, ClientConfigPath: "/mnt/ledger"},
+			command: "agave-validator",
+			args:    []string{"--ledger", "/mnt/ledger", "set-identity", "--require-tower", "/path/to/active.json"},
+		},
+		{
+			name:    "agave passive",
+			role:    Role{Name: "passive", Client: RoleClientAgave, ClientConfigPath: "/mnt/ledger"},
+			command: "agave-validator",
+			args:    []string{"--ledger", "/mnt/ledger", "set-identity", "/path/to/passive.json"},
+		},
+		{
+			name:    "firedancer active",
+			role:    Role{Name: "active", Client: RoleClientFiredancer, ClientConfigPath: "/home/firedancer/config.toml"},
+			command: "fdctl",
+			args:    []string{"set-identity", "--config", "/home/firedancer/config.toml", "/path/to/active.json"},
+		},
+		{
+			name:    "firedancer passive with its binary overridden",
+			role:    Role{Name: "passive", Client: RoleClientFiredancer, ClientConfigPath: "/home/firedancer/config.toml", Command: "/opt/firedancer/bin/fdctl"},
+			command: "/opt/firedancer/bin/fdctl",
+			args:    []string{"set-identity", "--config", "/home/firedancer/config.toml", "/path/to/passive.json"},
+		},
+		{
+			name:    "configured args are kept",
+			role:    Role{Name: "active", Client: RoleClientAgave, ClientConfigPath: "/mnt/ledger", Args: []string{"--ledger", "{{ .ClientConfigPath }}", "set-identity", "{{ .ActiveIdentityKeypairFile }}"}},
+			command: "agave-validator",
+			args:    []string{"--ledger", "/mnt/ledger", "set-identity", "/path/to/active.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role := tt.role
+			role.SetDefaults()
+			require.NoError(t, role.Validate())
+			require.NoError(t, role.RenderCommands(data))
+			assert.Equal(t, tt.command, role.Command)
+			assert.Equal(t, tt.args, role.Args)
+		})
+	}
+
+	// the admin rpc method doesn't need a command
+	role := Role{Name: "active", Client: RoleClientAgave, Method: RoleMethodAgaveAdminRPC}
+	role.SetDefaults()
+	assert.Empty(t, role.Command)
+}
+
+func TestRole_Validate_Client(t *testing.T) {
+	role := &Role{Client: "jito", Command: "true"}
+	assert.EqualError(t, role.Validate(), `role.client must be one of custom, agave or firedancer, got "jito"`)
+
+	role = &Role{Client: RoleClientFiredancer, Command: "fdctl"}
+	assert.EqualError(t, role.Validate(), "role.client_config_path must be defined when client is firedancer")
+
+	role.Method = RoleMethodAgaveAdminRPC
+	role.AdminRPC.LedgerPath = "/mnt/ledger"
+	assert.EqualError(t, role.Validate(), "role.method must be command when client is firedancer, got agave_admin_rpc")
+
+	role.Client = RoleClientAgave
+	assert.NoError(t, role.Validate())
+}
+
+func TestRole_RunCommand_AdminRPC(t *testing.T) {
+	fake := testutil.NewFakeAdminRPC(t)
+	role := &Role{
+		Name:                "active",
+		Method:              RoleMethodAgaveAdminRPC,
+		AdminRPC:            AdminRPC{LedgerPath: fake.LedgerPath, RequireTower: true, Timeout: time.Second},
+		IdentityKeypairFile: "/keys/active.json",
+	}
+
+	result, err := role.RunCommand(RoleCommandRunOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, []testutil.AdminRPCCall{{Method: "setIdentity", Params: []any{"/keys/active.json", true}}}, fake.Calls())
+
+	// nothing is called in dry run
+	result, err = role.RunCommand(RoleCommandRunOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, RoleCommandResult{DryRun: true}, result)
+	assert.Len(t, fake.Calls(), 1)
+
+	fake.SetError(-32603, "tower not found")
+	result, err = role.RunCommand(RoleCommandRunOptions{})
+	assert.ErrorContains(t, err, "tower not found")
+	assert.Equal(t, -1, result.ExitCode)
+}
+
+func TestRole_RunCommand_Audit(t *testing.T) {
+	auditLog, err := audit.Open(audit.Options{Path: filepath.Join(t.TempDir(), "audit.log")})
+	require.NoError(t, err)
+	defer auditLog.Close()
+
+	role := &Role{Name: "passive", Command: "sh", Args: []string{"-c", "exit 2"}}
+	_, err = role.RunCommand(RoleCommandRunOptions{FailoverID: "0a1b2c3d", Audit: auditLog})
+	require.Error(t, err)
+	_, err = role.RunCommand(RoleCommandRunOptions{DryRun: true, Audit: auditLog})
+	require.NoError(t, err)
+
+	fake := testutil.NewFakeAdminRPC(t)
+	fake.SetError(-32603, "tower not found")
+	adminRole := &Role{
+		Name:                "active",
+		Method:              RoleMethodAgaveAdminRPC,
+		AdminRPC:            AdminRPC{LedgerPath: fake.LedgerPath, Timeout: time.Second},
+		IdentityKeypairFile: "/keys/active.json",
+	}
+	_, err = adminRole.RunCommand(RoleCommandRunOptions{Audit: auditLog})
+	require.Error(t, err)
+
+	records, err := audit.Read(auditLog.Path())
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "0a1b2c3d", records[0].FailoverID)
+	assert.Equal(t, 2, records[0].ExitCode)
+	assert.True(t, records[1].DryRun)
+	assert.Equal(t, []string{"/keys/active.json", "require_tower=false"}, records[2].Args)
+	assert.Equal(t, -1, records[2].ExitCode)
+	assert.Contains(t, records[2].Error, "tower not found")
+}
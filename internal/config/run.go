@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// Run represents settings for the run command process
+type Run struct {
+	// LockFile is the file flocked for the life of the process so only one agent manages the validator,
+	// defaults to the config file's name with a .lock suffix in StateDir
+	LockFile string `koanf:"lock_file"`
+	// StateDir is the directory state kept across restarts is written to, such as the failover history,
+	// defaults to the config file's directory
+	StateDir string `koanf:"state_dir"`
+	// StartupTimeout bounds initialization, the first gossip refresh and binding the servers
+	StartupTimeout time.Duration `koanf:"startup_timeout"`
+	// ShutdownTimeout bounds how long SIGTERM or SIGINT waits for an in-progress transition to finish before
+	// cancelling it
+	ShutdownTimeout time.Duration `koanf:"shutdown_timeout"`
+}
+
+// Validate validates the run configuration
+func (r *Run) Validate() error {
+	// run.startup_timeout must be positive
+	if r.StartupTimeout <= 0 {
+		return fmt.Errorf("run.startup_timeout must be positive and non-zero")
+	}
+
+	// run.shutdown_timeout must be positive
+	if r.ShutdownTimeout <= 0 {
+		return fmt.Errorf("run.shutdown_timeout must be positive and non-zero")
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the run configuration
+func (r *Run) SetDefaults(configFile string) {
+	if r.StateDir == "" && configFile != "" {
+		r.StateDir = filepath.Dir(configFile)
+	}
+
+	if r.LockFile == "" && configFile != "" {
+		r.LockFile = filepath.Join(r.StateDir, filepath.Base(configFile)+".lock")
+	}
+
+	if r.StartupTimeout == 0 {
+		r.StartupTimeout = 2 * time.Minute
+	}
+
+	if r.ShutdownTimeout == 0 {
+		r.ShutdownTimeout = 30 * time.Second
+	}
+}
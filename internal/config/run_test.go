@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_SetDefaults(t *testing.T) {
+	run := &Run{}
+	run.SetDefaults("/home/solana/solana-validator-ha/config.yaml")
+	assert.Equal(t, "/home/solana/solana-validator-ha/config.yaml.lock", run.LockFile)
+	assert.Equal(t, "/home/solana/solana-validator-ha", run.StateDir)
+
+	// the lock file follows an explicit state dir
+	run = &Run{StateDir: "/var/lib/solana-validator-ha"}
+	run.SetDefaults("/home/solana/solana-validator-ha/config.yaml")
+	assert.Equal(t, "/var/lib/solana-validator-ha/config.yaml.lock", run.LockFile)
+
+	// explicit lock file and state dir are kept
+	run = &Run{LockFile: "/run/solana-validator-ha.lock", StateDir: "/var/lib/solana-validator-ha"}
+	run.SetDefaults("/home/solana/solana-validator-ha/config.yaml")
+	assert.Equal(t, "/run/solana-validator-ha.lock", run.LockFile)
+	assert.Equal(t, "/var/lib/solana-validator-ha", run.StateDir)
+
+	// no config file - no lock and no state dir
+	run = &Run{}
+	run.SetDefaults("")
+	assert.Empty(t, run.LockFile)
+	assert.Empty(t, run.StateDir)
+	assert.Equal(t, 2*time.Minute, run.StartupTimeout)
+	assert.Equal(t, 30*time.Second, run.ShutdownTimeout)
+
+	// explicit startup and shutdown timeouts are kept
+	run = &Run{StartupTimeout: 30 * time.Second, ShutdownTimeout: time.Minute}
+	run.SetDefaults("")
+	assert.Equal(t, 30*time.Second, run.StartupTimeout)
+	assert.Equal(t, time.Minute, run.ShutdownTimeout)
+}
+
+func TestRun_Validate(t *testing.T) {
+	assert.NoError(t, (&Run{StartupTimeout: time.Second, ShutdownTimeout: time.Second}).Validate())
+	assert.ErrorContains(t, (&Run{StartupTimeout: -time.Second, ShutdownTimeout: time.Second}).Validate(), "run.startup_timeout must be positive")
+	assert.ErrorContains(t, (&Run{StartupTimeout: time.Second}).Validate(), "run.shutdown_timeout must be positive")
+}
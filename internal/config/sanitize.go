@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// NameMaxLength is the maximum length of validator and peer names
+	NameMaxLength = 64
+	// LabelValueMaxLength is the maximum length of a prometheus static label value
+	LabelValueMaxLength = 128
+)
+
+var (
+	// nameRegexp is the safe pattern for names that flow into log prefixes, metric labels and file paths
+	nameRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	// labelNameRegexp is the prometheus label name pattern
+	labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// ValidateName validates a name against the safe name pattern, field is used to build a precise error
+func ValidateName(field string, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s must be defined", field)
+	}
+
+	if len(name) > NameMaxLength {
+		return fmt.Errorf("%s must be at most %d characters - got %d", field, NameMaxLength, len(name))
+	}
+
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("%s must only contain letters, digits, dashes, underscores and dots - got %q", field, name)
+	}
+
+	return nil
+}
+
+// ValidateLabelName validates a prometheus label name
+func ValidateLabelName(field string, name string) error {
+	if !labelNameRegexp.MatchString(name) {
+		return fmt.Errorf("%s must be a valid prometheus label name matching %s - got %q", field, labelNameRegexp.String(), name)
+	}
+	return nil
+}
+
+// SanitizeString strips control characters from s and caps it at maxLength bytes without splitting runes.
+// It is safe to use on untrusted values before they reach logs, metric labels or HTTP responses.
+func SanitizeString(s string, maxLength int) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return -1
+		}
+		return r
+	}, s)
+
+	if len(sanitized) <= maxLength {
+		return sanitized
+	}
+
+	// cut at the last rune boundary that fits
+	cut := 0
+	for i, r := range sanitized {
+		if i+utf8.RuneLen(r) > maxLength {
+			break
+		}
+		cut = i + utf8.RuneLen(r)
+	}
+
+	return sanitized[:cut]
+}
+
+// SanitizeLabelValue sanitizes a prometheus label value
+func SanitizeLabelValue(value string) string {
+	return SanitizeString(value, LabelValueMaxLength)
+}
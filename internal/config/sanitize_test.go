@@ -0,0 +1,186 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hostileStrings are inputs known to break log prefixes, label values, file paths or terminals
+var hostileStrings = []string{
+	"",
+	"validator\nname",
+	"validator\r\nINFO fake log line",
+	"\x1b[31mred\x1b[0m",
+	"tab\tseparated",
+	"null\x00byte",
+	"../../etc/passwd",
+	"name with spaces",
+	"emoji-🔥",
+	"quote\"name",
+	"back\\slash",
+	strings.Repeat("a", 10*1024),
+	"\xff\xfe invalid utf8",
+	"‮right-to-left-override",
+}
+
+func TestValidateName(t *testing.T) {
+	validNames := []string{"validator-1", "backup_validator.2", "A", strings.Repeat("a", NameMaxLength)}
+	for _, name := range validNames {
+		assert.NoError(t, ValidateName("validator.name", name), name)
+	}
+
+	for _, name := range hostileStrings {
+		err := ValidateName("validator.name", name)
+		assert.Error(t, err, "expected %q to be rejected", name)
+		if err != nil {
+			assert.Contains(t, err.Error(), "validator.name")
+		}
+	}
+
+	// precise errors
+	err := ValidateName("validator.name", "")
+	assert.Contains(t, err.Error(), "validator.name must be defined")
+
+	err = ValidateName("validator.name", strings.Repeat("a", NameMaxLength+1))
+	assert.Contains(t, err.Error(), "must be at most 64 characters - got 65")
+
+	err = ValidateName("validator.name", "bad\nname")
+	assert.Contains(t, err.Error(), `got "bad\nname"`)
+}
+
+func TestValidateLabelName(t *testing.T) {
+	assert.NoError(t, ValidateLabelName("label", "region"))
+	assert.NoError(t, ValidateLabelName("label", "_private_1"))
+	assert.Error(t, ValidateLabelName("label", "1region"))
+	assert.Error(t, ValidateLabelName("label", "re-gion"))
+	assert.Error(t, ValidateLabelName("label", ""))
+}
+
+func TestSanitizeString(t *testing.T) {
+	assert.Equal(t, "validatorname", SanitizeString("validator\nname", 64))
+	assert.Equal(t, "red[0m", SanitizeString("red\x1b[0m", 64))
+	assert.Equal(t, "abc", SanitizeString("abcdef", 3))
+	assert.Equal(t, "ok", SanitizeString("ok", 64))
+
+	// multi-byte runes are never split
+	assert.Equal(t, "🔥", SanitizeString("🔥🔥", 5))
+	assert.Equal(t, "", SanitizeString("🔥", 3))
+}
+
+func TestSanitizeString_HostileInputs(t *testing.T) {
+	for _, s := range hostileStrings {
+		assertSanitized(t, s, SanitizeLabelValue(s))
+	}
+}
+
+func FuzzSanitizeLabelValue(f *testing.F) {
+	for _, s := range hostileStrings {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		assertSanitized(t, s, SanitizeLabelValue(s))
+	})
+}
+
+func FuzzValidateName(f *testing.F) {
+	for _, s := range hostileStrings {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if ValidateName("validator.name", s) != nil {
+			return
+		}
+		// anything accepted must be short and free of anything but the safe charset
+		require.LessOrEqual(t, len(s), NameMaxLength)
+		for _, r := range s {
+			require.True(t, r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.", r)), "unexpected rune %q in %q", r, s)
+		}
+	})
+}
+
+func assertSanitized(t *testing.T, input string, sanitized string) {
+	t.Helper()
+	require.LessOrEqual(t, len(sanitized), LabelValueMaxLength, "input %q", input)
+	require.True(t, utf8.ValidString(sanitized), "input %q produced invalid utf8 %q", input, sanitized)
+	for _, r := range sanitized {
+		require.False(t, unicode.IsControl(r), "input %q left control rune %q", input, r)
+	}
+}
+
+func TestPrometheus_Validate_SanitizesStaticLabels(t *testing.T) {
+	prometheus := &Prometheus{
+		Port: 9090,
+		StaticLabels: map[string]string{
+			"region": "us-west\n-1",
+			"long":   strings.Repeat("x", 10*1024),
+		},
+	}
+
+	require.NoError(t, prometheus.Validate())
+	assert.Equal(t, "us-west-1", prometheus.StaticLabels["region"])
+	assert.Len(t, prometheus.StaticLabels["long"], LabelValueMaxLength)
+
+	prometheus.StaticLabels["bad-label"] = "value"
+	err := prometheus.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "prometheus.static_labels.bad-label must be a valid prometheus label name")
+}
+
+func TestFailover_Validate_RejectsHostilePeerNames(t *testing.T) {
+	for _, name := range hostileStrings {
+		failover := &Failover{
+			PollIntervalDuration:       5,
+			LeaderlessSamplesThreshold: 3,
+			Active:                     Role{Command: "true"},
+			Passive:                    Role{Command: "true"},
+			Peers: Peers{
+				name: {IP: "192.168.1.10"},
+			},
+		}
+		err := failover.Validate()
+		assert.Error(t, err, "expected peer name %q to be rejected", name)
+	}
+}
+
+func TestNewFromConfigFile_RejectsHostileValidatorName(t *testing.T) {
+	activeIdentityFile := createTempIdentityFile(t)
+	passiveIdentityFile := createTempIdentityFile(t)
+	t.Cleanup(func() {
+		os.Remove(activeIdentityFile)
+		os.Remove(passiveIdentityFile)
+	})
+
+	content := `
+validator:
+  name: "primary\ninjected log line"
+  identities:
+    active: "` + activeIdentityFile + `"
+    passive: "` + passiveIdentityFile + `"
+cluster:
+  name: "testnet"
+failover:
+  active:
+    command: "true"
+  passive:
+    command: "true"
+  peers:
+    backup:
+      ip: "192.168.1.10"
+`
+	tempFile, err := os.CreateTemp("", "config-*.yaml")
+	require.NoError(t, err)
+	defer os.Remove(tempFile.Name())
+	_, err = tempFile.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, tempFile.Close())
+
+	_, err = NewFromConfigFile(tempFile.Name())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "validator.name must only contain letters, digits, dashes, underscores and dots")
+}
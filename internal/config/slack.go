@@ -0,0 +1,53 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SlackHook posts a message to a Slack incoming webhook in place of a hook command
+type SlackHook struct {
+	// WebhookURL is the incoming webhook url, usually a ${ENV_VAR} reference as its path is the secret
+	WebhookURL string `koanf:"webhook_url"`
+	// Channel overrides the webhook's default channel if set
+	Channel string `koanf:"channel"`
+	// Message supports the same template data as role commands
+	Message string `koanf:"message"`
+}
+
+// slackPayload is the body posted to an incoming webhook
+type slackPayload struct {
+	Text    string `json:"text"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// Validate validates the slack hook configuration
+func (s *SlackHook) Validate() error {
+	// slack.webhook_url must be defined and, unless it references an env var only known when run, a valid url
+	if s.WebhookURL == "" {
+		return fmt.Errorf("slack.webhook_url must be defined")
+	}
+	if !hookEnvVarRegexp.MatchString(s.WebhookURL) && !isHTTPURL(s.WebhookURL) {
+		return fmt.Errorf("slack.webhook_url must be a valid http(s) url")
+	}
+
+	// slack.message must be defined
+	if s.Message == "" {
+		return fmt.Errorf("slack.message must be defined")
+	}
+
+	return nil
+}
+
+// post posts message to channel through webhookURL within timeout, failing on any non 2xx response. The
+// response body is copied to output if set. Errors never include the webhook url.
+func (s *SlackHook) post(webhookURL string, channel string, message string, timeout time.Duration, output io.Writer) error {
+	body, err := json.Marshal(slackPayload{Text: message, Channel: channel})
+	if err != nil {
+		return fmt.Errorf("failed to encode slack message: %w", err)
+	}
+	return postHook("slack", http.MethodPost, webhookURL, nil, body, timeout, output)
+}
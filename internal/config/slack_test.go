@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slackServer is a fake incoming webhook recording the payloads posted to it, failing the first failures posts
+func slackServer(t *testing.T, failures int32) (*httptest.Server, *[]slackPayload) {
+	t.Helper()
+
+	payloads := &[]slackPayload{}
+	var posts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload slackPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		if posts.Add(1) <= failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("no_service"))
+			return
+		}
+		*payloads = append(*payloads, payload)
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, payloads
+}
+
+func TestSlackHook_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		slack   SlackHook
+		wantErr string
+	}{
+		{name: "valid", slack: SlackHook{WebhookURL: "https://hooks.slack.com/services/T0/B0/x", Message: "hi"}},
+		{name: "env var webhook url", slack: SlackHook{WebhookURL: "${SLACK_WEBHOOK_URL}", Message: "hi"}},
+		{name: "missing webhook url", slack: SlackHook{Message: "hi"}, wantErr: "slack.webhook_url must be defined"},
+		{name: "invalid webhook url", slack: SlackHook{WebhookURL: "hooks.slack.com/services/T0", Message: "hi"}, wantErr: "slack.webhook_url must be a valid http(s) url"},
+		{name: "missing message", slack: SlackHook{WebhookURL: "https://hooks.slack.com/services/T0/B0/x"}, wantErr: "slack.message must be defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.slack.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHook_Validate_Slack(t *testing.T) {
+	slack := &SlackHook{WebhookURL: "https://hooks.slack.com/services/T0/B0/x", Message: "hi"}
+
+	hook := Hook{Name: "notify", Slack: slack}
+	assert.NoError(t, hook.Validate(true))
+
+	hook = Hook{Name: "notify", Command: "curl", Slack: slack}
+	assert.EqualError(t, hook.Validate(true), "must have only one of command, slack or webhook")
+
+	hook = Hook{Name: "notify", Slack: &SlackHook{Message: "hi"}}
+	assert.EqualError(t, hook.Validate(true), "slack.webhook_url must be defined")
+}
+
+func TestHook_Run_Slack(t *testing.T) {
+	server, payloads := slackServer(t, 0)
+	t.Setenv("TEST_SLACK_WEBHOOK_URL", server.URL)
+
+	hook := Hook{
+		Name:    "notify",
+		Timeout: time.Second,
+		Slack:   &SlackHook{WebhookURL: "${TEST_SLACK_WEBHOOK_URL}", Channel: "#ops", Message: "promoting {{ .SelfName }} - ${SVHA_REASON}"},
+	}
+	require.NoError(t, renderHook(RoleCommandTemplateData{SelfName: "primary"}, &hook))
+
+	var output bytes.Buffer
+	_, err := hook.Run(HookRunOptions{HookType: "pre", Env: map[string]string{"SVHA_REASON": "manual"}, Output: &output})
+	require.NoError(t, err)
+	assert.Equal(t, []slackPayload{{Text: "promoting primary - manual", Channel: "#ops"}}, *payloads)
+	assert.Equal(t, "ok\n", output.String())
+}
+
+func TestHook_Run_SlackRetriesAndFailures(t *testing.T) {
+	// fails once then succeeds on the retry
+	server, payloads := slackServer(t, 1)
+	hook := Hook{
+		Name:       "notify",
+		Timeout:    time.Second,
+		Retries:    1,
+		RetryDelay: 10 * time.Millisecond,
+		Slack:      &SlackHook{WebhookURL: server.URL + "/services/secret", Message: "hi"},
+	}
+	hooks := &Hooks{Post: []Hook{hook}}
+	results := []HookResult{}
+	hooks.RunPost(HooksRunOptions{WaitRetries: true, OnResult: func(result HookResult) { results = append(results, result) }})
+	require.Len(t, results, 1)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, 2, results[0].Attempts)
+	assert.Len(t, *payloads, 1)
+
+	// a non 2xx response fails the hook
+	server, _ = slackServer(t, 5)
+	hook.Retries = 0
+	hook.Slack = &SlackHook{WebhookURL: server.URL + "/services/secret", Message: "hi"}
+	_, err := hook.Run(HookRunOptions{})
+	assert.EqualError(t, err, "slack returned 500 Internal Server Error: no_service")
+
+	// errors never include the webhook url
+	hook.Slack = &SlackHook{WebhookURL: "http://127.0.0.1:1/services/secret", Message: "hi"}
+	_, err = hook.Run(HookRunOptions{})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+
+	// a slow webhook is timed out
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	t.Cleanup(slow.Close)
+	hook.Timeout = 100 * time.Millisecond
+	hook.Slack = &SlackHook{WebhookURL: slow.URL, Message: "hi"}
+	_, err = hook.Run(HookRunOptions{})
+	assert.ErrorContains(t, err, "failed to post to slack")
+}
+
+func TestHook_Run_SlackDryRun(t *testing.T) {
+	server, payloads := slackServer(t, 0)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	hook := Hook{Name: "notify", Slack: &SlackHook{WebhookURL: server.URL, Channel: "#ops", Message: "promoting primary"}}
+	_, err := hook.Run(HookRunOptions{DryRun: true})
+	require.NoError(t, err)
+
+	assert.Empty(t, *payloads)
+	assert.Contains(t, logs.String(), "would post to slack")
+	assert.Contains(t, logs.String(), "promoting primary")
+	assert.NotContains(t, logs.String(), server.URL)
+}
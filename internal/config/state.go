@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// DefaultStateMaxAge is how old a state file may be by default and still be loaded on startup
+	DefaultStateMaxAge = 5 * time.Minute
+	// StateFileName is the file in run.state_dir the agent state is persisted to by default
+	StateFileName = "state.json"
+)
+
+// State represents persisting the agent state across restarts
+type State struct {
+	// Enabled persists the role, last failover and counters to file on change and loads them on startup, off by
+	// default for a memoryless agent
+	Enabled bool `koanf:"enabled"`
+	// File is the state file, defaults to StateFileName in run.state_dir
+	File string `koanf:"file"`
+	// MaxAge is how old the state file may be and still be loaded on startup, older state is ignored
+	MaxAge time.Duration `koanf:"max_age"`
+}
+
+// Validate validates the state configuration
+func (s *State) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+
+	// state.file must be set - it defaults to run.state_dir which needs a config file to default from
+	if s.File == "" {
+		return fmt.Errorf("state.file must be set when state.enabled is true and run.state_dir is not")
+	}
+
+	// state.max_age must be positive
+	if s.MaxAge <= 0 {
+		return fmt.Errorf("state.max_age must be positive and non-zero")
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the state configuration, the file in stateDir
+func (s *State) SetDefaults(stateDir string) {
+	if s.File == "" && stateDir != "" {
+		s.File = filepath.Join(stateDir, StateFileName)
+	}
+
+	if s.MaxAge == 0 {
+		s.MaxAge = DefaultStateMaxAge
+	}
+}
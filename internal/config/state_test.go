@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestState_SetDefaults(t *testing.T) {
+	state := &State{}
+	state.SetDefaults("/home/solana/solana-validator-ha")
+	assert.False(t, state.Enabled)
+	assert.Equal(t, "/home/solana/solana-validator-ha/state.json", state.File)
+	assert.Equal(t, 5*time.Minute, state.MaxAge)
+
+	// explicit file and max age are kept
+	state = &State{File: "/var/lib/solana-validator-ha/state.json", MaxAge: time.Minute}
+	state.SetDefaults("/home/solana/solana-validator-ha")
+	assert.Equal(t, "/var/lib/solana-validator-ha/state.json", state.File)
+	assert.Equal(t, time.Minute, state.MaxAge)
+
+	// no state dir - no file
+	state = &State{}
+	state.SetDefaults("")
+	assert.Empty(t, state.File)
+}
+
+func TestState_Validate(t *testing.T) {
+	// disabled needs nothing
+	assert.NoError(t, (&State{}).Validate())
+
+	assert.NoError(t, (&State{Enabled: true, File: "/tmp/state.json", MaxAge: time.Minute}).Validate())
+	assert.ErrorContains(t, (&State{Enabled: true, MaxAge: time.Minute}).Validate(), "state.file must be set when state.enabled is true")
+	assert.ErrorContains(t, (&State{Enabled: true, File: "/tmp/state.json", MaxAge: -time.Minute}).Validate(), "state.max_age must be positive")
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/tlscert"
+)
+
+// TLS represents the certificate an http server is served over https with
+type TLS struct {
+	// CertFile is the PEM certificate, followed by any intermediates
+	CertFile string `koanf:"cert_file"`
+	// KeyFile is the PEM private key of the certificate
+	KeyFile string `koanf:"key_file"`
+}
+
+// Validate validates the tls configuration at path
+func (t *TLS) Validate(path string) error {
+	// cert_file and key_file must both be defined
+	if t.CertFile == "" || t.KeyFile == "" {
+		return fmt.Errorf("%s.cert_file and %s.key_file must both be defined", path, path)
+	}
+
+	// the certificate and key must load - fail fast rather than on the first scrape
+	if _, err := tlscert.Load(t.CertFile, t.KeyFile); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Scheme returns the url scheme of a server with this tls configuration, https unless t is nil
+func (t *TLS) Scheme() string {
+	if t == nil {
+		return "http"
+	}
+	return "https"
+}
+
+// ClientTLS configures the client side of tls to a server, e.g. etcd
+type ClientTLS struct {
+	// CAFile is the PEM bundle the server's certificate is verified against, the system roots when empty
+	CAFile string `koanf:"ca_file"`
+	// CertFile and KeyFile are the PEM client certificate and its key, for servers requiring client certificates
+	CertFile string `koanf:"cert_file"`
+	KeyFile  string `koanf:"key_file"`
+}
+
+// Config returns the client tls.Config of t, nil when t is nil
+func (t *ClientTLS) Config() (*tls.Config, error) {
+	if t == nil {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.CAFile != "" {
+		data, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_file %s: %w", t.CAFile, err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate found in ca_file %s", t.CAFile)
+		}
+	}
+
+	// cert_file and key_file must both be defined, or neither
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must both be defined")
+	}
+	if t.CertFile != "" {
+		cert, err := tlscert.Load(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
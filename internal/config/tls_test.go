@@ -0,0 +1,83 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTempCertificate writes a self signed certificate and its key to a temp dir, returning their paths
+func createTempCertificate(t *testing.T) (certFile string, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "solana-validator-ha"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "tls.crt")
+	keyFile = filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestTLS_Validate(t *testing.T) {
+	certFile, keyFile := createTempCertificate(t)
+
+	assert.NoError(t, (&TLS{CertFile: certFile, KeyFile: keyFile}).Validate("prometheus.tls"))
+
+	// only one of cert and key
+	assert.EqualError(t, (&TLS{CertFile: certFile}).Validate("prometheus.tls"), "prometheus.tls.cert_file and prometheus.tls.key_file must both be defined")
+	assert.EqualError(t, (&TLS{KeyFile: keyFile}).Validate("healthcheck.tls"), "healthcheck.tls.cert_file and healthcheck.tls.key_file must both be defined")
+
+	// unreadable, or not a certificate and key
+	err := (&TLS{CertFile: filepath.Join(t.TempDir(), "missing.crt"), KeyFile: keyFile}).Validate("prometheus.tls")
+	assert.ErrorContains(t, err, "prometheus.tls: failed to load certificate")
+	err = (&TLS{CertFile: keyFile, KeyFile: certFile}).Validate("healthcheck.tls")
+	assert.ErrorContains(t, err, "healthcheck.tls: failed to load certificate")
+
+	// validated as part of their servers' configuration
+	prometheus := &Prometheus{Port: 9090, TLS: &TLS{CertFile: certFile}}
+	assert.ErrorContains(t, prometheus.Validate(), "prometheus.tls.cert_file and prometheus.tls.key_file")
+	healthCheck := &HealthCheck{TLS: &TLS{KeyFile: keyFile}}
+	assert.ErrorContains(t, healthCheck.Validate(), "healthcheck.tls.cert_file and healthcheck.tls.key_file")
+}
+
+func TestTLS_Scheme(t *testing.T) {
+	var tls *TLS
+	assert.Equal(t, "http", tls.Scheme())
+	assert.Equal(t, "https", (&TLS{}).Scheme())
+}
+
+func TestClientTLS_Config(t *testing.T) {
+	config, err := (*ClientTLS)(nil).Config()
+	require.NoError(t, err)
+	assert.Nil(t, config)
+
+	certFile, keyFile := createTempCertificate(t)
+	config, err = (&ClientTLS{CAFile: certFile, CertFile: certFile, KeyFile: keyFile}).Config()
+	require.NoError(t, err)
+	assert.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TowerSyncMethodSSH copies the tower file with scp
+	TowerSyncMethodSSH = "ssh"
+	// TowerSyncMethodRsync copies the tower file with rsync over ssh
+	TowerSyncMethodRsync = "rsync"
+
+	// TowerSyncOnUnreachableAbort fails the promotion when the source host is unreachable
+	TowerSyncOnUnreachableAbort = "abort"
+	// TowerSyncOnUnreachableContinue promotes without the tower when the source host is unreachable
+	TowerSyncOnUnreachableContinue = "continue"
+	// TowerSyncOnUnreachableContinueAfterDelay waits tower_sync.unreachable_delay then promotes without the tower
+	TowerSyncOnUnreachableContinueAfterDelay = "continue_after_delay"
+)
+
+// TowerSync configures copying the tower file from the last known active peer before running the active command
+type TowerSync struct {
+	// Method is how the tower file is copied, empty disables tower sync
+	Method string `koanf:"method"`
+	// User is the remote user on the active peer
+	User string `koanf:"user"`
+	// Port is the active peer's ssh port
+	Port int `koanf:"port"`
+	// SSHArgs are extra ssh options, e.g. -i for the key to use
+	SSHArgs []string `koanf:"ssh_args"`
+	// Source is the tower file glob on the active peer, supporting role command templates
+	Source string `koanf:"source"`
+	// DestinationDir is the local directory the tower file is copied into
+	DestinationDir string `koanf:"destination_dir"`
+	// Timeout bounds reaching the source host and the copy
+	Timeout time.Duration `koanf:"timeout"`
+	// OnUnreachable is what to do when the source host can't be reached, one of abort, continue or
+	// continue_after_delay
+	OnUnreachable string `koanf:"on_unreachable"`
+	// UnreachableDelay is how long continue_after_delay waits before promoting without the tower
+	UnreachableDelay time.Duration `koanf:"unreachable_delay"`
+}
+
+// Enabled returns true when a tower sync method is configured
+func (t *TowerSync) Enabled() bool {
+	return t.Method != ""
+}
+
+// Validate validates the tower sync configuration
+func (t *TowerSync) Validate() error {
+	if !t.Enabled() {
+		return nil
+	}
+
+	// failover.tower_sync.method must be ssh or rsync
+	if t.Method != TowerSyncMethodSSH && t.Method != TowerSyncMethodRsync {
+		return fmt.Errorf("failover.tower_sync.method must be one of %s or %s, got %q", TowerSyncMethodSSH, TowerSyncMethodRsync, t.Method)
+	}
+
+	// failover.tower_sync.user must be defined
+	if t.User == "" {
+		return fmt.Errorf("failover.tower_sync.user must be defined")
+	}
+
+	// failover.tower_sync.source and failover.tower_sync.destination_dir must be absolute paths
+	if !path.IsAbs(t.Source) {
+		return fmt.Errorf("failover.tower_sync.source must be an absolute path, got %q", t.Source)
+	}
+	if !path.IsAbs(t.DestinationDir) {
+		return fmt.Errorf("failover.tower_sync.destination_dir must be an absolute path, got %q", t.DestinationDir)
+	}
+
+	// failover.tower_sync.port must be a valid port
+	if t.Port < 0 || t.Port > 65535 {
+		return fmt.Errorf("failover.tower_sync.port must be between 1 and 65535, got %d", t.Port)
+	}
+
+	// failover.tower_sync.timeout and failover.tower_sync.unreachable_delay must not be negative
+	if t.Timeout < 0 {
+		return fmt.Errorf("failover.tower_sync.timeout must not be negative, got %s", t.Timeout)
+	}
+	if t.UnreachableDelay < 0 {
+		return fmt.Errorf("failover.tower_sync.unreachable_delay must not be negative, got %s", t.UnreachableDelay)
+	}
+
+	// failover.tower_sync.on_unreachable must be abort, continue or continue_after_delay
+	switch t.OnUnreachable {
+	case "", TowerSyncOnUnreachableAbort, TowerSyncOnUnreachableContinue, TowerSyncOnUnreachableContinueAfterDelay:
+	default:
+		return fmt.Errorf("failover.tower_sync.on_unreachable must be one of %s, %s or %s, got %q",
+			TowerSyncOnUnreachableAbort, TowerSyncOnUnreachableContinue, TowerSyncOnUnreachableContinueAfterDelay, t.OnUnreachable)
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the tower sync configuration
+func (t *TowerSync) SetDefaults() {
+	if t.Port == 0 {
+		t.Port = 22
+	}
+	if t.Timeout == 0 {
+		t.Timeout = 30 * time.Second
+	}
+	if t.OnUnreachable == "" {
+		t.OnUnreachable = TowerSyncOnUnreachableAbort
+	}
+	if t.UnreachableDelay == 0 {
+		t.UnreachableDelay = 10 * time.Second
+	}
+}
+
+// Render renders the source glob's template strings
+func (t *TowerSync) Render(data RoleCommandTemplateData) (err error) {
+	t.Source, err = renderTemplateString(data, t.Source)
+	if err != nil {
+		return fmt.Errorf("failed to render failover.tower_sync.source: %w", err)
+	}
+	return nil
+}
+
+// Command returns the command and args copying the tower file from host - scp for ssh, rsync over ssh for rsync,
+// both never prompting so an unattended failover can't hang on a password or host key
+func (t *TowerSync) Command(host string) (command string, args []string) {
+	sshOptions := []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=" + strconv.Itoa(t.connectTimeoutSeconds())}
+	source := fmt.Sprintf("%s@%s:%s", t.User, host, t.Source)
+	destination := strings.TrimSuffix(t.DestinationDir, "/") + "/"
+
+	if t.Method == TowerSyncMethodRsync {
+		ssh := append([]string{"ssh", "-p", strconv.Itoa(t.Port)}, sshOptions...)
+		ssh = append(ssh, t.SSHArgs...)
+		return "rsync", []string{"--archive", "--timeout=" + strconv.Itoa(t.connectTimeoutSeconds()), "-e", strings.Join(ssh, " "), source, destination}
+	}
+
+	args = append([]string{"-P", strconv.Itoa(t.Port)}, sshOptions...)
+	args = append(args, t.SSHArgs...)
+	return "scp", append(args, source, destination)
+}
+
+// connectTimeoutSeconds is the timeout in whole seconds for ssh's ConnectTimeout, at least 1
+func (t *TowerSync) connectTimeoutSeconds() int {
+	seconds := int(t.Timeout / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
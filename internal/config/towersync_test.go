@@ -0,0 +1,95 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validTowerSync() TowerSync {
+	towerSync := TowerSync{
+		Method:         TowerSyncMethodSSH,
+		User:           "sol",
+		Source:         "/mnt/ledger/tower-1_9-*.bin",
+		DestinationDir: "/mnt/ledger",
+	}
+	towerSync.SetDefaults()
+	return towerSync
+}
+
+func TestTowerSync_Validate(t *testing.T) {
+	// disabled needs nothing else
+	disabled := TowerSync{}
+	assert.False(t, disabled.Enabled())
+	assert.NoError(t, disabled.Validate())
+
+	towerSync := validTowerSync()
+	assert.True(t, towerSync.Enabled())
+	assert.NoError(t, towerSync.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(*TowerSync)
+		err    string
+	}{
+		{"method", func(ts *TowerSync) { ts.Method = "ftp" }, `failover.tower_sync.method must be one of ssh or rsync, got "ftp"`},
+		{"user", func(ts *TowerSync) { ts.User = "" }, "failover.tower_sync.user must be defined"},
+		{"source", func(ts *TowerSync) { ts.Source = "tower.bin" }, `failover.tower_sync.source must be an absolute path, got "tower.bin"`},
+		{"destination_dir", func(ts *TowerSync) { ts.DestinationDir = "" }, `failover.tower_sync.destination_dir must be an absolute path, got ""`},
+		{"port", func(ts *TowerSync) { ts.Port = 70000 }, "failover.tower_sync.port must be between 1 and 65535, got 70000"},
+		{"timeout", func(ts *TowerSync) { ts.Timeout = -time.Second }, "failover.tower_sync.timeout must not be negative, got -1s"},
+		{"unreachable_delay", func(ts *TowerSync) { ts.UnreachableDelay = -time.Second }, "failover.tower_sync.unreachable_delay must not be negative, got -1s"},
+		{"on_unreachable", func(ts *TowerSync) { ts.OnUnreachable = "retry" }, `failover.tower_sync.on_unreachable must be one of abort, continue or continue_after_delay, got "retry"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			towerSync := validTowerSync()
+			tt.modify(&towerSync)
+			assert.EqualError(t, towerSync.Validate(), tt.err)
+		})
+	}
+}
+
+func TestTowerSync_SetDefaults(t *testing.T) {
+	towerSync := TowerSync{}
+	towerSync.SetDefaults()
+	assert.Equal(t, 22, towerSync.Port)
+	assert.Equal(t, 30*time.Second, towerSync.Timeout)
+	assert.Equal(t, TowerSyncOnUnreachableAbort, towerSync.OnUnreachable)
+	assert.Equal(t, 10*time.Second, towerSync.UnreachableDelay)
+}
+
+func TestTowerSync_Command(t *testing.T) {
+	towerSync := validTowerSync()
+	towerSync.SSHArgs = []string{"-i", "/home/sol/.ssh/tower"}
+
+	command, args := towerSync.Command("10.0.0.1")
+	assert.Equal(t, "scp", command)
+	assert.Equal(t, []string{
+		"-P", "22", "-o", "BatchMode=yes", "-o", "ConnectTimeout=30", "-i", "/home/sol/.ssh/tower",
+		"sol@10.0.0.1:/mnt/ledger/tower-1_9-*.bin", "/mnt/ledger/",
+	}, args)
+
+	towerSync.Method = TowerSyncMethodRsync
+	towerSync.Port = 2222
+	command, args = towerSync.Command("10.0.0.1")
+	assert.Equal(t, "rsync", command)
+	assert.Equal(t, []string{
+		"--archive", "--timeout=30",
+		"-e", "ssh -p 2222 -o BatchMode=yes -o ConnectTimeout=30 -i /home/sol/.ssh/tower",
+		"sol@10.0.0.1:/mnt/ledger/tower-1_9-*.bin", "/mnt/ledger/",
+	}, args)
+}
+
+func TestTowerSync_Render(t *testing.T) {
+	towerSync := validTowerSync()
+	towerSync.Source = "/mnt/ledger/tower-1_9-{{ .ActiveIdentityPubkey }}.bin"
+
+	require.NoError(t, towerSync.Render(RoleCommandTemplateData{ActiveIdentityPubkey: "active-pubkey"}))
+	assert.Equal(t, "/mnt/ledger/tower-1_9-active-pubkey.bin", towerSync.Source)
+
+	towerSync.Source = "{{ .Nope }}"
+	assert.ErrorContains(t, towerSync.Render(RoleCommandTemplateData{}), "failover.tower_sync.source")
+}
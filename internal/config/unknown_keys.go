@@ -0,0 +1,188 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// unknownKey is a key in the config file that no setting is read from
+type unknownKey struct {
+	// Path is the key's full path, e.g. failover.active.post_hooks
+	Path string
+	// Suggestion is the full path of the valid key nearest to it, empty if there is none
+	Suggestion string
+}
+
+// String returns the unknown key as reported
+func (u unknownKey) String() string {
+	if u.Suggestion == "" {
+		return u.Path
+	}
+	return fmt.Sprintf("%s (did you mean %s?)", u.Path, u.Suggestion)
+}
+
+// formatUnknownKeys returns the unknown keys as a comma separated list
+func formatUnknownKeys(unknown []unknownKey) string {
+	formatted := make([]string, len(unknown))
+	for i, u := range unknown {
+		formatted[i] = u.String()
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// findUnknownKeys returns every key in raw, as loaded from the config file at path, that typ has no koanf field
+// for - koanf silently ignores them, so a misspelt or misplaced setting would otherwise never take effect
+func findUnknownKeys(path string, typ reflect.Type, raw any) (unknown []unknownKey) {
+	if raw == nil {
+		return nil
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		values, ok := raw.(map[string]any)
+		if !ok {
+			return nil
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			field, ok := koanfField(typ, key)
+			if !ok {
+				unknown = append(unknown, unknownKey{Path: joinKey(path, key), Suggestion: nearestKey(path, typ, key)})
+				continue
+			}
+			unknown = append(unknown, findUnknownKeys(joinKey(path, key), field.Type, values[key])...)
+		}
+	case reflect.Slice:
+		values, ok := raw.([]any)
+		if !ok {
+			return nil
+		}
+		for i, value := range values {
+			unknown = append(unknown, findUnknownKeys(fmt.Sprintf("%s[%d]", path, i), typ.Elem(), value)...)
+		}
+	case reflect.Map:
+		values, ok := raw.(map[string]any)
+		if !ok {
+			return nil
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			unknown = append(unknown, findUnknownKeys(joinKey(path, key), typ.Elem(), values[key])...)
+		}
+	}
+	return unknown
+}
+
+// koanfField returns the field of the struct typ that key is unmarshaled into - keys match case insensitively
+// as they do when unmarshaling
+func koanfField(typ reflect.Type, key string) (reflect.StructField, bool) {
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if name := koanfKey(field); name != "" && strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// koanfKey returns the key field is read from, empty if it isn't read from the config file
+func koanfKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("koanf"), ",")
+	if !field.IsExported() || key == "-" {
+		return ""
+	}
+	return key
+}
+
+// nearestKey returns the full path of the valid key under path, the struct typ, nearest to the unknown key - the
+// one sharing the most words with it, then the fewest edits away. A key may be nested below, so post_hooks finds
+// hooks.post.
+func nearestKey(path string, typ reflect.Type, key string) string {
+	words := keyWords(key)
+	best, bestShared, bestDistance := "", 0, -1
+	for _, candidate := range validKeys("", typ) {
+		candidateWords := keyWords(candidate)
+		shared := sharedWords(words, candidateWords)
+		distance := levenshtein(strings.Join(words, "_"), strings.Join(candidateWords, "_"))
+		if bestDistance < 0 || shared > bestShared || (shared == bestShared && distance < bestDistance) {
+			best, bestShared, bestDistance = candidate, shared, distance
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return joinKey(path, best)
+}
+
+// validKeys returns the path of every key the struct typ reads under path, parents before their children - keys
+// of maps and slice items are left out as they depend on the config
+func validKeys(path string, typ reflect.Type) (keys []string) {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || typ == durationType {
+		return nil
+	}
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		key := koanfKey(field)
+		if key == "" {
+			continue
+		}
+		keys = append(keys, joinKey(path, key))
+		keys = append(keys, validKeys(joinKey(path, key), field.Type)...)
+	}
+	return keys
+}
+
+// keyWords returns key's words, split on . and _, sorted and lower cased so the same words in another order or
+// at another depth compare equal
+func keyWords(key string) []string {
+	words := strings.FieldsFunc(strings.ToLower(key), func(r rune) bool { return r == '.' || r == '_' })
+	slices.Sort(words)
+	return words
+}
+
+// sharedWords returns how many of words are in candidateWords, a word one edit away from one counting as a typo of it
+func sharedWords(words []string, candidateWords []string) (shared int) {
+	for _, word := range words {
+		if slices.ContainsFunc(candidateWords, func(candidate string) bool { return levenshtein(word, candidate) <= 1 }) {
+			shared++
+		}
+	}
+	return shared
+}
+
+// levenshtein returns the number of single character edits turning a into b
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			substitution := previous[j-1]
+			if a[i-1] != b[j-1] {
+				substitution++
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, substitution)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
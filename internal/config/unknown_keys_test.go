@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindUnknownKeys(t *testing.T) {
+	load := func(t *testing.T, lenient bool, yaml string) (*Config, error) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(yaml), 0o600))
+		cfg, err := New(NewConfigParams{LenientConfig: lenient})
+		require.NoError(t, err)
+		return cfg, cfg.LoadFromFile(path)
+	}
+
+	_, err := load(t, false, `
+failover:
+  poll_interval_duration: 5s
+  peers:
+    validator-1:
+      ip: 192.168.1.10
+  active:
+    env:
+      ANY_NAME: value
+    hooks:
+      post:
+        - name: notify
+          command: "true"
+`)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		yaml     string
+		expected []unknownKey
+	}{
+		{
+			name: "misplaced hooks",
+			yaml: `
+failover:
+  active:
+    post_hooks:
+      - name: notify
+`,
+			expected: []unknownKey{{Path: "failover.active.post_hooks", Suggestion: "failover.active.hooks.post"}},
+		},
+		{
+			name: "misspelt",
+			yaml: `
+failover:
+  leaderless_sample_threshold: 3
+`,
+			expected: []unknownKey{{Path: "failover.leaderless_sample_threshold", Suggestion: "failover.leaderless_samples_threshold"}},
+		},
+		{
+			name: "in list items and map values",
+			yaml: `
+failover:
+  peers:
+    validator-1:
+      addr: 192.168.1.10
+  active:
+    hooks:
+      pre:
+        - name: notify
+          comand: "true"
+`,
+			expected: []unknownKey{
+				{Path: "failover.active.hooks.pre[0].comand", Suggestion: "failover.active.hooks.pre[0].command"},
+				{Path: "failover.peers.validator-1.addr", Suggestion: "failover.peers.validator-1.ip"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, false, tt.yaml)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), formatUnknownKeys(tt.expected))
+
+			cfg, err := load(t, true, tt.yaml)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg.unknownKeys)
+		})
+	}
+}
@@ -1,17 +1,59 @@
 package config
 
 import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/log"
 	solanago "github.com/gagliardetto/solana-go"
+	"github.com/sol-strategies/solana-validator-ha/internal/vault"
 )
 
+const (
+	// PublicIPFamilyAny advertises the first public IP a service returns, of either family
+	PublicIPFamilyAny = "any"
+	// PublicIPFamilyIPv4 only asks public IP services over IPv4 and only accepts IPv4 addresses
+	PublicIPFamilyIPv4 = "ipv4"
+	// PublicIPFamilyIPv6 only asks public IP services over IPv6 and only accepts IPv6 addresses
+	PublicIPFamilyIPv6 = "ipv6"
+
+	// DefaultPublicIPRefreshInterval is how often a discovered public IP is resolved again when
+	// validator.public_ip_refresh_interval is not set
+	DefaultPublicIPRefreshInterval = time.Hour
+	// DefaultVoteAccountCheckInterval is how often the vote account's identity is checked when
+	// validator.vote_account_check_interval is not set - getVoteAccounts is a large response
+	DefaultVoteAccountCheckInterval = 5 * time.Minute
+	// DefaultVaultKeyPairDir is where identities read from vault are written when validator.identities.vault_keypair_dir
+	// is not set - tmpfs, so they never reach disk
+	DefaultVaultKeyPairDir = "/dev/shm/solana-validator-ha"
+	// tmpfsMagic is the statfs type of a tmpfs filesystem
+	tmpfsMagic = 0x01021994
+	// PublicIPTimeout bounds each request to a public IP service so an unresponsive one falls through to the next
+	PublicIPTimeout = 10 * time.Second
+)
+
+// publicIPFamilies are the valid validator.public_ip_family values
+var publicIPFamilies = []string{PublicIPFamilyAny, PublicIPFamilyIPv4, PublicIPFamilyIPv6}
+
+// publicIPv6Services answer with the IPv6 address they are reached over
+var publicIPv6Services = []string{
+	"https://api6.ipify.org",
+	"https://6.icanhazip.com",
+}
+
 var publicIPServices = []string{
 	"https://api.ipify.org",
 	"https://checkip.amazonaws.com",
@@ -21,50 +63,338 @@ var publicIPServices = []string{
 
 // Validator represents the local validator configuration
 type Validator struct {
-	Name                string              `koanf:"name"`
-	RPCURL              string              `koanf:"rpc_url"`
-	PublicIPServiceURLs []string            `koanf:"public_ip_service_urls"`
-	Identities          ValidatorIdentities `koanf:"identities"`
+	Name                string   `koanf:"name"`
+	RPCURL              string   `koanf:"rpc_url"`
+	PublicIPServiceURLs []string `koanf:"public_ip_service_urls"`
+	// PublicIP, when set, is the public IP used as is - the public IP services are never asked
+	PublicIP string `koanf:"public_ip"`
+	// PublicIPFamily pins the address family of the public IP advertised on dual-stack hosts - any, ipv4 or ipv6
+	PublicIPFamily string `koanf:"public_ip_family"`
+	// PublicIPRefreshInterval is how often the public IP services are asked again so a changed public IP is picked
+	// up without a restart, never with a static validator.public_ip
+	PublicIPRefreshInterval time.Duration       `koanf:"public_ip_refresh_interval"`
+	Identities              ValidatorIdentities `koanf:"identities"`
+	// GossipMatchBy is how this validator finds itself in gossip - ip, its public ip, or pubkey, its active and
+	// passive identities for hosts whose gossip address isn't their public ip
+	GossipMatchBy string `koanf:"gossip_match_by"`
+	// Priority is this validator's failover.peers priority as its peers should list it
+	Priority int `koanf:"priority"`
+	// VoteAccount, when set, is the vote account whose node identity must be the active identity - a takeover
+	// with any other identity votes for nothing
+	VoteAccount string `koanf:"vote_account"`
+	// VoteAccountCheckInterval is how often the vote account's node identity is checked after startup
+	VoteAccountCheckInterval time.Duration `koanf:"vote_account_check_interval"`
 }
 
 // ValidatorIdentities represents the identities for the validator
 type ValidatorIdentities struct {
-	ActiveKeyPairFile  string               `koanf:"active"`
-	ActiveKeyPair      *solanago.PrivateKey `koanf:"-"`
-	PassiveKeyPairFile string               `koanf:"passive"`
-	PassiveKeyPair     *solanago.PrivateKey `koanf:"-"`
+	ActiveKeyPairFile string               `koanf:"active"`
+	ActiveKeyPair     *solanago.PrivateKey `koanf:"-"`
+	// PassiveKeyPairFiles is a single passive keypair file or, while rotating keys, a list of them - the first is
+	// the primary passive identity
+	PassiveKeyPairFiles []string `koanf:"passive"`
+	// PassiveKeyPairFile is the primary passive keypair file, set by Load
+	PassiveKeyPairFile string `koanf:"-"`
+	// PassiveKeyPair is the primary passive identity, the first of PassiveKeyPairs
+	PassiveKeyPair *solanago.PrivateKey `koanf:"-"`
+	// PassiveKeyPairs are every acceptable passive identity in PassiveKeyPairFiles order
+	PassiveKeyPairs []*solanago.PrivateKey `koanf:"-"`
+	// AllowInsecurePermissions only warns about keypair files that aren't regular files owned by the running user
+	// and inaccessible to group and other, instead of failing to load them
+	AllowInsecurePermissions bool `koanf:"allow_insecure_permissions"`
+	// PassiveMaxStake is the most activated stake, in SOL, that may be delegated to a passive identity before the
+	// agent refuses to start with it
+	PassiveMaxStake float64 `koanf:"passive_max_stake"`
+	// SkipPassiveStakeCheck skips checking the passive identities are unstaked at startup, set by
+	// run --skip-passive-stake-check
+	SkipPassiveStakeCheck bool `koanf:"-"`
+
+	// VaultKeyPairDir is the directory, on tmpfs, identities read from vault:// references are written to for the
+	// validator to read when a role command runs
+	VaultKeyPairDir string `koanf:"vault_keypair_dir"`
+
+	// insecurePermissions are why each keypair file loaded with AllowInsecurePermissions is insecure
+	insecurePermissions []string
+	// vaultKeyPairs are the identities read from vault by the file they are written to, only ever held in memory
+	// until a role command runs
+	vaultKeyPairs map[string]solanago.PrivateKey
 }
 
-// Load loads the identities from the key pair files
+// Load loads the identities from the key pair files or, for vault://<path>#<field> references, from vault
 func (v *ValidatorIdentities) Load() error {
-	activeKeyPair, err := solanago.PrivateKeyFromSolanaKeygenFile(v.ActiveKeyPairFile)
+	v.insecurePermissions = nil
+	v.vaultKeyPairs = nil
+	var vaultClient *vault.Client
+
+	activeKeyPair, err := v.loadKeyPair("validator.identities.active", v.ActiveKeyPairFile, &vaultClient)
 	if err != nil {
-		return fmt.Errorf("failed to load active identity file: %w", err)
+		return fmt.Errorf("failed to load active identity %s: %w", v.ActiveKeyPairFile, err)
 	}
 	v.ActiveKeyPair = &activeKeyPair
 
-	passiveKeyPair, err := solanago.PrivateKeyFromSolanaKeygenFile(v.PassiveKeyPairFile)
+	// a single primary file set directly
+	if len(v.PassiveKeyPairFiles) == 0 && v.PassiveKeyPairFile != "" {
+		v.PassiveKeyPairFiles = []string{v.PassiveKeyPairFile}
+	}
+	if len(v.PassiveKeyPairFiles) == 0 {
+		return fmt.Errorf("failed to load passive identity file: validator.identities.passive must be defined")
+	}
+
+	v.PassiveKeyPairs = nil
+	for _, passiveKeyPairFile := range v.PassiveKeyPairFiles {
+		passiveKeyPair, err := v.loadKeyPair("validator.identities.passive", passiveKeyPairFile, &vaultClient)
+		if err != nil {
+			return fmt.Errorf("failed to load passive identity %s: %w", passiveKeyPairFile, err)
+		}
+		v.PassiveKeyPairs = append(v.PassiveKeyPairs, &passiveKeyPair)
+	}
+	v.PassiveKeyPairFile = v.PassiveKeyPairFiles[0]
+	v.PassiveKeyPair = v.PassiveKeyPairs[0]
+
+	// identities from vault can only ever be written somewhere safe for them
+	if len(v.vaultKeyPairs) > 0 {
+		if err := v.prepareVaultKeyPairDir(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadKeyPair loads the identity at source, the key it is configured at - a keypair file, or a vault reference
+// read with the client in vaultClient, logged in to from the environment on first use
+func (v *ValidatorIdentities) loadKeyPair(key string, source string, vaultClient **vault.Client) (solanago.PrivateKey, error) {
+	if !vault.IsRef(source) {
+		if err := v.checkPermissions(key, source); err != nil {
+			return nil, err
+		}
+		return solanago.PrivateKeyFromSolanaKeygenFile(source)
+	}
+
+	ref, err := vault.ParseRef(source)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*vault.RequestTimeout)
+	defer cancel()
+	if *vaultClient == nil {
+		if *vaultClient, err = vault.NewFromEnv(ctx); err != nil {
+			return nil, err
+		}
+	}
+	raw, err := (*vaultClient).Read(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// the keygen JSON array, as is or stored as a string
+	var encoded string
+	if json.Unmarshal(raw, &encoded) == nil {
+		raw = json.RawMessage(encoded)
+	}
+	var values []int
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, fmt.Errorf("%s must be a solana keygen JSON array of bytes: %w", ref, err)
+	}
+	keyPair := make(solanago.PrivateKey, len(values))
+	for i, value := range values {
+		if value < 0 || value > 255 {
+			return nil, fmt.Errorf("%s must be a solana keygen JSON array of bytes, got %d", ref, value)
+		}
+		keyPair[i] = byte(value)
+	}
+	if len(keyPair) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%s must be a %d byte keypair, got %d bytes", ref, ed25519.PrivateKeySize, len(keyPair))
+	}
+
+	if v.vaultKeyPairs == nil {
+		v.vaultKeyPairs = map[string]solanago.PrivateKey{}
+	}
+	v.vaultKeyPairs[v.KeyPairFile(source)] = keyPair
+	return keyPair, nil
+}
+
+// KeyPairFile returns the file the validator reads the identity at source from - source itself for a keypair
+// file, the file in VaultKeyPairDir it is written to for a vault reference
+func (v *ValidatorIdentities) KeyPairFile(source string) string {
+	ref, err := vault.ParseRef(source)
+	if err != nil {
+		return source
+	}
+	name := strings.NewReplacer("/", "_", "#", "_").Replace(ref.Path + "#" + ref.Field)
+	return filepath.Join(v.VaultKeyPairDir, name+".json")
+}
+
+// WriteVaultKeyPairFiles writes the identities read from vault to their files in VaultKeyPairDir, readable by
+// the running user only, so a role command can hand them to the validator - a no-op without vault references
+func (v *ValidatorIdentities) WriteVaultKeyPairFiles() error {
+	if len(v.vaultKeyPairs) == 0 {
+		return nil
+	}
+	if err := v.prepareVaultKeyPairDir(); err != nil {
+		return err
+	}
+
+	for file, keyPair := range v.vaultKeyPairs {
+		values := make([]int, len(keyPair))
+		for i, b := range keyPair {
+			values[i] = int(b)
+		}
+		content, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+
+		// written aside and renamed so the validator never reads a partial keypair, never through a link
+		tempFile := file + ".tmp"
+		if err := os.Remove(tempFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to write identity keypair file %s: %w", file, err)
+		}
+		f, err := os.OpenFile(tempFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY|syscall.O_NOFOLLOW, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to write identity keypair file %s: %w", file, err)
+		}
+		_, err = f.Write(content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tempFile, file)
+		}
+		if err != nil {
+			os.Remove(tempFile)
+			return fmt.Errorf("failed to write identity keypair file %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// RemoveVaultKeyPairFiles removes the files WriteVaultKeyPairFiles wrote, so the identities read from vault are
+// back to only being held in memory - a no-op without vault references
+func (v *ValidatorIdentities) RemoveVaultKeyPairFiles() error {
+	var errs []error
+	for file := range v.vaultKeyPairs {
+		for _, path := range []string{file, file + ".tmp"} {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("failed to remove identity keypair file %s: %w", path, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// prepareVaultKeyPairDir creates VaultKeyPairDir if it doesn't exist and checks it is a directory on tmpfs owned
+// by the running user that group and other can't access - in a shared directory like /dev/shm anyone could have
+// created it first to read the identities written to it
+func (v *ValidatorIdentities) prepareVaultKeyPairDir() error {
+	dir := v.VaultKeyPairDir
+	if err := os.Mkdir(dir, 0o700); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to create validator.identities.vault_keypair_dir %s: %w", dir, err)
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check validator.identities.vault_keypair_dir %s: %w", dir, err)
+	}
+	stat, _ := info.Sys().(*syscall.Stat_t)
+	switch {
+	case !info.IsDir():
+		return fmt.Errorf("validator.identities.vault_keypair_dir %s must be a directory, got %s", dir, info.Mode().Type())
+	case stat != nil && int(stat.Uid) != os.Getuid():
+		return fmt.Errorf("validator.identities.vault_keypair_dir %s must be owned by the running user (uid %d), owned by uid %d", dir, os.Getuid(), stat.Uid)
+	case info.Mode().Perm() != 0o700:
+		return fmt.Errorf("validator.identities.vault_keypair_dir %s must have mode 0700, got %04o", dir, info.Mode().Perm())
+	}
+
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(dir, &fs); err != nil {
+		return fmt.Errorf("failed to check validator.identities.vault_keypair_dir %s: %w", dir, err)
+	}
+	if int64(fs.Type) != tmpfsMagic {
+		return fmt.Errorf("validator.identities.vault_keypair_dir %s must be on tmpfs so identities never reach disk, got filesystem type %#x", dir, fs.Type)
+	}
+	return nil
+}
+
+// checkPermissions checks the keypair file at path, the key it is configured at, is a regular file owned by the
+// running user that group and other can't access - an identity anyone else can read can be used to vote as the
+// validator. With AllowInsecurePermissions the problem is kept to warn about instead.
+func (v *ValidatorIdentities) checkPermissions(key string, path string) error {
+	info, err := os.Stat(path)
 	if err != nil {
-		return fmt.Errorf("failed to load passive identity file: %w", err)
+		// reported naming the file when it is loaded
+		return nil
+	}
+
+	var problem string
+	stat, _ := info.Sys().(*syscall.Stat_t)
+	switch {
+	case !info.Mode().IsRegular():
+		problem = fmt.Sprintf("%s keypair file %s must be a regular file, got %s", key, path, info.Mode().Type())
+	case stat != nil && int(stat.Uid) != os.Getuid():
+		problem = fmt.Sprintf("%s keypair file %s must be owned by the running user (uid %d), owned by uid %d", key, path, os.Getuid(), stat.Uid)
+	case info.Mode().Perm()&0o077 != 0:
+		problem = fmt.Sprintf("%s keypair file %s must not be accessible by group or other, got mode %04o - chmod 600 it", key, path, info.Mode().Perm())
+	default:
+		return nil
 	}
-	v.PassiveKeyPair = &passiveKeyPair
 
+	if !v.AllowInsecurePermissions {
+		return fmt.Errorf("%s - set validator.identities.allow_insecure_permissions to only warn", problem)
+	}
+	v.insecurePermissions = append(v.insecurePermissions, problem)
 	return nil
 }
 
-// Validate validates the validator identities, returns an error if the identities are the same
+// PassivePubkeys returns the public keys of every acceptable passive identity, the primary first
+func (v *ValidatorIdentities) PassivePubkeys() []string {
+	pubkeys := []string{v.PassiveKeyPair.PublicKey().String()}
+	for i, passiveKeyPair := range v.PassiveKeyPairs {
+		// PassiveKeyPair is PassiveKeyPairs[0]
+		if i > 0 {
+			pubkeys = append(pubkeys, passiveKeyPair.PublicKey().String())
+		}
+	}
+	return pubkeys
+}
+
+// IsPassivePubkey returns true if pubkey is one of the acceptable passive identities
+func (v *ValidatorIdentities) IsPassivePubkey(pubkey string) bool {
+	for _, passivePubkey := range v.PassivePubkeys() {
+		if pubkey == passivePubkey {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates the validator identities, returns an error if a passive identity is the active identity or
+// the passive identities are not all different
 func (v *ValidatorIdentities) Validate() (err error) {
-	if v.ActiveKeyPair.PublicKey().String() == v.PassiveKeyPair.PublicKey().String() {
-		err = fmt.Errorf("validator.identities.active and validator.identities.passive must be different: %s", v.ActiveKeyPair.PublicKey().String())
+	// validator.identities.passive_max_stake must not be negative
+	if v.PassiveMaxStake < 0 {
+		return fmt.Errorf("validator.identities.passive_max_stake must not be negative, got %v", v.PassiveMaxStake)
+	}
+
+	activePubkey := v.ActiveKeyPair.PublicKey().String()
+	seen := map[string]bool{}
+	for _, passivePubkey := range v.PassivePubkeys() {
+		if passivePubkey == activePubkey {
+			return fmt.Errorf("validator.identities.active and validator.identities.passive must be different: %s", activePubkey)
+		}
+		if seen[passivePubkey] {
+			return fmt.Errorf("validator.identities.passive must all be different: %s is listed more than once", passivePubkey)
+		}
+		seen[passivePubkey] = true
 	}
-	return err
+	return nil
 }
 
 // Validate validates the validator configuration
 func (v *Validator) Validate() error {
-	// validator.name must be defined
-	if v.Name == "" {
-		return fmt.Errorf("validator.name must be defined")
+	// validator.name must be defined and safe to use in log prefixes, metric labels and file paths
+	if err := ValidateName("validator.name", v.Name); err != nil {
+		return err
 	}
 
 	// validator.rpc_url must be a valid URL
@@ -91,6 +421,40 @@ func (v *Validator) Validate() error {
 		}
 	}
 
+	// validator.public_ip_family must be any, ipv4 or ipv6
+	if v.PublicIPFamily != "" && !slices.Contains(publicIPFamilies, v.PublicIPFamily) {
+		return fmt.Errorf("validator.public_ip_family must be one of %s, got %s", strings.Join(publicIPFamilies, ", "), v.PublicIPFamily)
+	}
+
+	// validator.public_ip_refresh_interval must not be negative
+	if v.PublicIPRefreshInterval < 0 {
+		return fmt.Errorf("validator.public_ip_refresh_interval must not be negative, got %s", v.PublicIPRefreshInterval)
+	}
+
+	// validator.vote_account must be a pubkey
+	if v.VoteAccount != "" {
+		if _, err := solanago.PublicKeyFromBase58(v.VoteAccount); err != nil {
+			return fmt.Errorf("validator.vote_account must be a valid pubkey, got %s: %w", v.VoteAccount, err)
+		}
+	}
+
+	// validator.vote_account_check_interval must not be negative
+	if v.VoteAccountCheckInterval < 0 {
+		return fmt.Errorf("validator.vote_account_check_interval must not be negative, got %s", v.VoteAccountCheckInterval)
+	}
+
+	// validator.public_ip must be an IP address of validator.public_ip_family
+	if v.PublicIP != "" {
+		if _, err := parsePublicIP(v.PublicIP, v.PublicIPFamily); err != nil {
+			return fmt.Errorf("validator.public_ip must be a valid IP address: %w", err)
+		}
+	}
+
+	// validator.gossip_match_by must be ip or pubkey
+	if v.GossipMatchBy != "" && v.GossipMatchBy != PeerMatchByIP && v.GossipMatchBy != PeerMatchByPubkey {
+		return fmt.Errorf("validator.gossip_match_by must be %s or %s, got %s", PeerMatchByIP, PeerMatchByPubkey, v.GossipMatchBy)
+	}
+
 	// Only validate identities if they've been loaded
 	if v.Identities.ActiveKeyPair != nil && v.Identities.PassiveKeyPair != nil {
 		return v.Identities.Validate()
@@ -106,44 +470,158 @@ func (v *Validator) SetDefaults() {
 		v.RPCURL = "http://localhost:8899"
 	}
 
+	if v.PublicIPFamily == "" {
+		v.PublicIPFamily = PublicIPFamilyAny
+	}
+
+	// IPv6-only hosts fall through the IPv4 services to the IPv6 ones, a pinned family only asks its own
 	if len(v.PublicIPServiceURLs) == 0 {
-		v.PublicIPServiceURLs = publicIPServices
+		switch v.PublicIPFamily {
+		case PublicIPFamilyIPv4:
+			v.PublicIPServiceURLs = publicIPServices
+		case PublicIPFamilyIPv6:
+			v.PublicIPServiceURLs = publicIPv6Services
+		default:
+			v.PublicIPServiceURLs = append(slices.Clone(publicIPServices), publicIPv6Services...)
+		}
 	}
+
+	if v.PublicIPRefreshInterval == 0 {
+		v.PublicIPRefreshInterval = DefaultPublicIPRefreshInterval
+	}
+
+	if v.VoteAccountCheckInterval == 0 {
+		v.VoteAccountCheckInterval = DefaultVoteAccountCheckInterval
+	}
+
+	if v.Identities.VaultKeyPairDir == "" {
+		v.Identities.VaultKeyPairDir = DefaultVaultKeyPairDir
+	}
+
+	if v.GossipMatchBy == "" {
+		v.GossipMatchBy = PeerMatchByIP
+	}
+}
+
+// SelfPeer returns this validator as a failover peer at publicIP, matched in gossip by its identities with
+// validator.gossip_match_by pubkey
+func (v *Validator) SelfPeer(publicIP string) Peer {
+	peer := Peer{Name: v.Name, IP: publicIP, MatchBy: v.GossipMatchBy, Priority: v.Priority}
+	if v.GossipMatchBy == PeerMatchByPubkey {
+		peer.Pubkeys = append([]string{v.Identities.ActiveKeyPair.PublicKey().String()}, v.Identities.PassivePubkeys()...)
+	}
+	return peer
 }
 
-// PublicIP returns the public IP address of the validator using the public IP service URLs
-// returns the first successful response
-func (v *Validator) PublicIP() (string, error) {
+const (
+	// PublicIPSourceConfig is the source of a public IP set by validator.public_ip
+	PublicIPSourceConfig = "config"
+	// PublicIPSourceDiscovery is the source of a public IP returned by validator.public_ip_service_urls
+	PublicIPSourceDiscovery = "discovery"
+)
+
+// PublicIPSource returns where ResolvePublicIP gets the public IP from
+func (v *Validator) PublicIPSource() string {
+	if v.PublicIP != "" {
+		return PublicIPSourceConfig
+	}
+	return PublicIPSourceDiscovery
+}
+
+// ResolvePublicIP returns the public IP address of the validator in canonical form - validator.public_ip when set,
+// else the first successful response of validator.public_ip_family from the public IP service URLs. It creates a
+// client for the one lookup, use ResolvePublicIPWith to reuse one across lookups
+func (v *Validator) ResolvePublicIP() (string, error) {
+	return v.ResolvePublicIPWith(v.NewPublicIPHTTPClient())
+}
+
+// ResolvePublicIPWith is ResolvePublicIP asking the public IP services with client
+func (v *Validator) ResolvePublicIPWith(client *http.Client) (string, error) {
+	if v.PublicIP != "" {
+		ip, err := parsePublicIP(v.PublicIP, v.PublicIPFamily)
+		if err != nil {
+			return "", fmt.Errorf("validator.public_ip must be a valid IP address: %w", err)
+		}
+		return ip, nil
+	}
+
 	for _, publicIPServiceURL := range v.PublicIPServiceURLs {
-		response, err := http.Get(publicIPServiceURL)
+		body, err := fetchPublicIP(client, publicIPServiceURL)
 		if err != nil {
+			log.Warn("failed to get public IP from public IP service", "error", err, "service_url", publicIPServiceURL)
 			continue
 		}
-		defer response.Body.Close()
-		body, err := io.ReadAll(response.Body)
+
+		ip, err := parsePublicIP(body, v.PublicIPFamily)
 		if err != nil {
-			log.Warn("failed to read response body from public IP service", "error", err, "service_url", publicIPServiceURL)
+			log.Warn("invalid IP address returned from public IP service", "error", err, "service_url", publicIPServiceURL)
 			continue
 		}
+		return ip, nil
+	}
+	return "", fmt.Errorf("failed to get public IP from any public IP service URLs: %v", v.PublicIPServiceURLs)
+}
 
-		bodyStr := string(body)
-		var sanitizedIP string
+// fetchPublicIP returns the response body of a public IP service, read in full and closed so the client can
+// reuse the connection
+func fetchPublicIP(client *http.Client, publicIPServiceURL string) (string, error) {
+	response, err := client.Get(publicIPServiceURL)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
 
-		// select the first line of the response
-		sanitizedIP = strings.Split(bodyStr, "\n")[0]
-		// trim whitespaces
-		sanitizedIP = strings.TrimSpace(sanitizedIP)
-		// trim leading and trailing single or double quotes
-		sanitizedIP = strings.Trim(sanitizedIP, "\"")
-		sanitizedIP = strings.Trim(sanitizedIP, "'")
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	return string(body), nil
+}
 
-		// validate the IP address is a valid IPv4 address
-		ip := net.ParseIP(sanitizedIP)
-		if ip == nil || ip.To4() == nil {
-			log.Warn("invalid IPv4 address returned from public IP service", "ip", sanitizedIP, "service_url", publicIPServiceURL)
-			continue
-		}
-		return sanitizedIP, nil
+// NewPublicIPHTTPClient returns a keep-alive client for asking the public IP services, each request bounded by
+// PublicIPTimeout - create it once and pass it to ResolvePublicIPWith for every lookup
+func (v *Validator) NewPublicIPHTTPClient() *http.Client {
+	return publicIPHTTPClient(v.PublicIPFamily)
+}
+
+// publicIPHTTPClient returns the client asking public IP services, dialing only addresses of family when it is
+// pinned so dual-stack services answer with an address of that family
+func publicIPHTTPClient(family string) *http.Client {
+	network := "tcp"
+	switch family {
+	case PublicIPFamilyIPv4:
+		network = "tcp4"
+	case PublicIPFamilyIPv6:
+		network = "tcp6"
 	}
-	return "", fmt.Errorf("failed to get public IP from any public IP service URLs: %v", v.PublicIPServiceURLs)
+
+	dialer := &net.Dialer{}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, _ string, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+	return &http.Client{Transport: transport, Timeout: PublicIPTimeout}
+}
+
+// parsePublicIP returns the canonical form of the IP address on the first line of a public IP service's response,
+// bracketed IPv6 addresses included - it must be of family unless that is any
+func parsePublicIP(body string, family string) (string, error) {
+	// select the first line of the response
+	sanitizedIP := strings.Split(body, "\n")[0]
+	// trim whitespaces
+	sanitizedIP = strings.TrimSpace(sanitizedIP)
+	// trim leading and trailing single or double quotes, then IPv6 brackets
+	sanitizedIP = strings.Trim(sanitizedIP, "\"")
+	sanitizedIP = strings.Trim(sanitizedIP, "'")
+	sanitizedIP = strings.TrimSuffix(strings.TrimPrefix(sanitizedIP, "["), "]")
+
+	ip := net.ParseIP(sanitizedIP)
+	if ip == nil {
+		return "", fmt.Errorf("%q is not an IP address", sanitizedIP)
+	}
+	isIPv4 := ip.To4() != nil
+	if (family == PublicIPFamilyIPv4 && !isIPv4) || (family == PublicIPFamilyIPv6 && isIPv4) {
+		return "", fmt.Errorf("%s is not an %s address", ip, family)
+	}
+	return ip.String(), nil
 }
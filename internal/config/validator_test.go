@@ -1,9 +1,18 @@
 package config
 
 import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
+	solanago "github.com/gagliardetto/solana-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -13,6 +22,108 @@ func TestValidator_SetDefaults(t *testing.T) {
 	validator.SetDefaults()
 
 	assert.Equal(t, "http://localhost:8899", validator.RPCURL)
+	assert.Equal(t, PeerMatchByIP, validator.GossipMatchBy)
+	assert.Equal(t, PublicIPFamilyAny, validator.PublicIPFamily)
+	assert.Equal(t, DefaultPublicIPRefreshInterval, validator.PublicIPRefreshInterval)
+	// IPv6-only hosts fall through to the IPv6 services
+	assert.Equal(t, append(append([]string{}, publicIPServices...), publicIPv6Services...), validator.PublicIPServiceURLs)
+
+	// a pinned family only asks its own services
+	validator = &Validator{PublicIPFamily: PublicIPFamilyIPv6}
+	validator.SetDefaults()
+	assert.Equal(t, publicIPv6Services, validator.PublicIPServiceURLs)
+	validator = &Validator{PublicIPFamily: PublicIPFamilyIPv4}
+	validator.SetDefaults()
+	assert.Equal(t, publicIPServices, validator.PublicIPServiceURLs)
+}
+
+func TestParsePublicIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		family  string
+		want    string
+		wantErr string
+	}{
+		{name: "ipv4", body: "203.0.113.10\n", family: PublicIPFamilyAny, want: "203.0.113.10"},
+		{name: "ipv6", body: "2001:db8::1\n", family: PublicIPFamilyAny, want: "2001:db8::1"},
+		{name: "bracketed ipv6", body: "[2001:db8::1]", family: PublicIPFamilyIPv6, want: "2001:db8::1"},
+		{name: "quoted uncompressed ipv6", body: `"2001:0db8:0000:0000:0000:0000:0000:0001"`, family: PublicIPFamilyIPv6, want: "2001:db8::1"},
+		{name: "ipv6 pinned to ipv4", body: "2001:db8::1", family: PublicIPFamilyIPv4, wantErr: "2001:db8::1 is not an ipv4 address"},
+		{name: "ipv4 pinned to ipv6", body: "203.0.113.10", family: PublicIPFamilyIPv6, wantErr: "203.0.113.10 is not an ipv6 address"},
+		{name: "not an ip", body: "<html>", family: PublicIPFamilyAny, wantErr: `"<html>" is not an IP address`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, err := parsePublicIP(tt.body, tt.family)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ip)
+		})
+	}
+}
+
+func TestValidator_PublicIP(t *testing.T) {
+	ipv4Only := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("203.0.113.10\n"))
+	}))
+	t.Cleanup(ipv4Only.Close)
+	ipv6 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("2001:0db8::1\n"))
+	}))
+	t.Cleanup(ipv6.Close)
+
+	// the first service's address of any family
+	validator := &Validator{PublicIPServiceURLs: []string{ipv6.URL, ipv4Only.URL}, PublicIPFamily: PublicIPFamilyAny}
+	ip, err := validator.ResolvePublicIP()
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", ip)
+
+	// a service answering with the other family is skipped
+	validator.PublicIPFamily = PublicIPFamilyIPv4
+	ip, err = validator.ResolvePublicIP()
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.10", ip)
+
+	// a static public ip is returned without asking any service
+	static := &Validator{PublicIP: "2001:0db8::10", PublicIPServiceURLs: []string{ipv4Only.URL}}
+	ip, err = static.ResolvePublicIP()
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::10", ip)
+	assert.Equal(t, PublicIPSourceConfig, static.PublicIPSource())
+	assert.Equal(t, PublicIPSourceDiscovery, validator.PublicIPSource())
+
+	// services are only dialed over the pinned family - the test servers listen on IPv4 loopback
+	validator.PublicIPFamily = PublicIPFamilyIPv6
+	_, err = validator.ResolvePublicIP()
+	assert.Error(t, err)
+}
+
+func TestValidator_ResolvePublicIPWith_ReusesConnections(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("203.0.113.10\n"))
+	}))
+	var connections atomic.Int32
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	validator := &Validator{PublicIPServiceURLs: []string{server.URL}, PublicIPFamily: PublicIPFamilyIPv4}
+	client := validator.NewPublicIPHTTPClient()
+	for i := 0; i < 5; i++ {
+		ip, err := validator.ResolvePublicIPWith(client)
+		require.NoError(t, err)
+		assert.Equal(t, "203.0.113.10", ip)
+	}
+	assert.Equal(t, int32(1), connections.Load())
 }
 
 func TestValidator_Validate(t *testing.T) {
@@ -48,6 +159,59 @@ func TestValidator_Validate(t *testing.T) {
 	validator.RPCURL = "https://api.testnet.solana.com"
 	err = validator.Validate()
 	assert.NoError(t, err)
+
+	// Test with invalid public_ip_family
+	validator.PublicIPFamily = "ipv5"
+	err = validator.Validate()
+	assert.EqualError(t, err, "validator.public_ip_family must be one of any, ipv4, ipv6, got ipv5")
+	validator.PublicIPFamily = PublicIPFamilyIPv6
+
+	// Test with invalid public_ip, or one of the other family
+	validator.PublicIP = "not-an-ip"
+	err = validator.Validate()
+	assert.EqualError(t, err, `validator.public_ip must be a valid IP address: "not-an-ip" is not an IP address`)
+	validator.PublicIP = "203.0.113.10"
+	err = validator.Validate()
+	assert.EqualError(t, err, "validator.public_ip must be a valid IP address: 203.0.113.10 is not an ipv6 address")
+	validator.PublicIP = "2001:db8::1"
+	assert.NoError(t, validator.Validate())
+
+	// Test with negative public_ip_refresh_interval
+	validator.PublicIPRefreshInterval = -time.Minute
+	err = validator.Validate()
+	assert.EqualError(t, err, "validator.public_ip_refresh_interval must not be negative, got -1m0s")
+	validator.PublicIPRefreshInterval = time.Hour
+
+	// Test with invalid gossip_match_by
+	validator.GossipMatchBy = "name"
+	err = validator.Validate()
+	assert.EqualError(t, err, "validator.gossip_match_by must be ip or pubkey, got name")
+}
+
+func TestValidator_SelfPeer(t *testing.T) {
+	activeIdentityFile := createTempIdentityFile(t)
+	passiveIdentityFile := createTempIdentityFile(t)
+	t.Cleanup(func() {
+		os.Remove(activeIdentityFile)
+		os.Remove(passiveIdentityFile)
+	})
+	validator := &Validator{
+		Name:       "primary",
+		Identities: ValidatorIdentities{ActiveKeyPairFile: activeIdentityFile, PassiveKeyPairFiles: []string{passiveIdentityFile}},
+	}
+	require.NoError(t, validator.Identities.Load())
+	validator.SetDefaults()
+
+	// matched by ip the pubkeys aren't needed
+	assert.Equal(t, Peer{Name: "primary", IP: "192.168.1.10", MatchBy: PeerMatchByIP}, validator.SelfPeer("192.168.1.10"))
+
+	validator.GossipMatchBy = PeerMatchByPubkey
+	assert.Equal(t, Peer{
+		Name:    "primary",
+		IP:      "192.168.1.10",
+		MatchBy: PeerMatchByPubkey,
+		Pubkeys: []string{validator.Identities.ActiveKeyPair.PublicKey().String(), validator.Identities.PassiveKeyPair.PublicKey().String()},
+	}, validator.SelfPeer("192.168.1.10"))
 }
 
 func TestValidatorIdentities_Load(t *testing.T) {
@@ -75,6 +239,53 @@ func TestValidatorIdentities_Load(t *testing.T) {
 	assert.NotEqual(t, identities.ActiveKeyPair.PublicKey().String(), identities.PassiveKeyPair.PublicKey().String())
 }
 
+func TestValidatorIdentities_LoadInsecurePermissions(t *testing.T) {
+	activeIdentityFile := createTempIdentityFile(t)
+	passiveIdentityFile := createTempIdentityFile(t)
+	t.Cleanup(func() {
+		os.Remove(activeIdentityFile)
+		os.Remove(passiveIdentityFile)
+	})
+	require.NoError(t, os.Chmod(passiveIdentityFile, 0o640))
+
+	identities := &ValidatorIdentities{ActiveKeyPairFile: activeIdentityFile, PassiveKeyPairFiles: []string{passiveIdentityFile}}
+	err := identities.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "validator.identities.passive keypair file "+passiveIdentityFile+" must not be accessible by group or other, got mode 0640")
+	assert.Contains(t, err.Error(), "allow_insecure_permissions")
+
+	// downgraded to a warning
+	identities.AllowInsecurePermissions = true
+	require.NoError(t, identities.Load())
+	require.Len(t, identities.insecurePermissions, 1)
+	assert.Contains(t, identities.insecurePermissions[0], passiveIdentityFile)
+	assert.NotNil(t, identities.PassiveKeyPair)
+
+	// a directory is not a keypair file
+	identities = &ValidatorIdentities{ActiveKeyPairFile: t.TempDir(), PassiveKeyPairFiles: []string{passiveIdentityFile}}
+	err = identities.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be a regular file")
+}
+
+func TestValidatorIdentities_LoadNotOwned(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing a file's owner needs root")
+	}
+	activeIdentityFile := createTempIdentityFile(t)
+	passiveIdentityFile := createTempIdentityFile(t)
+	t.Cleanup(func() {
+		os.Remove(activeIdentityFile)
+		os.Remove(passiveIdentityFile)
+	})
+	require.NoError(t, os.Chown(activeIdentityFile, 65534, 65534))
+
+	identities := &ValidatorIdentities{ActiveKeyPairFile: activeIdentityFile, PassiveKeyPairFiles: []string{passiveIdentityFile}}
+	err := identities.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be owned by the running user (uid 0), owned by uid 65534")
+}
+
 func TestValidatorIdentities_Validate(t *testing.T) {
 	// Create temporary identity files
 	activeIdentityFile := createTempIdentityFile(t)
@@ -104,4 +315,175 @@ func TestValidatorIdentities_Validate(t *testing.T) {
 	err = identities.Validate()
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "validator.identities.active and validator.identities.passive must be different")
+
+	// Test with negative passive max stake
+	identities.PassiveMaxStake = -1
+	err = identities.Validate()
+	assert.EqualError(t, err, "validator.identities.passive_max_stake must not be negative, got -1")
+}
+
+func TestValidatorIdentities_LoadMultiplePassives(t *testing.T) {
+	activeIdentityFile := createTempIdentityFile(t)
+	primaryPassiveIdentityFile := createTempIdentityFile(t)
+	rotatedPassiveIdentityFile := createTempIdentityFile(t)
+	t.Cleanup(func() {
+		os.Remove(activeIdentityFile)
+		os.Remove(primaryPassiveIdentityFile)
+		os.Remove(rotatedPassiveIdentityFile)
+	})
+
+	identities := &ValidatorIdentities{
+		ActiveKeyPairFile:   activeIdentityFile,
+		PassiveKeyPairFiles: []string{primaryPassiveIdentityFile, rotatedPassiveIdentityFile},
+	}
+	require.NoError(t, identities.Load())
+	require.NoError(t, identities.Validate())
+
+	// the first is the primary
+	assert.Equal(t, primaryPassiveIdentityFile, identities.PassiveKeyPairFile)
+	require.Len(t, identities.PassiveKeyPairs, 2)
+	assert.Equal(t, identities.PassiveKeyPairs[0], identities.PassiveKeyPair)
+
+	pubkeys := identities.PassivePubkeys()
+	assert.Equal(t, []string{identities.PassiveKeyPairs[0].PublicKey().String(), identities.PassiveKeyPairs[1].PublicKey().String()}, pubkeys)
+	assert.True(t, identities.IsPassivePubkey(pubkeys[1]))
+	assert.False(t, identities.IsPassivePubkey(identities.ActiveKeyPair.PublicKey().String()))
+}
+
+func TestValidatorIdentities_ValidateMultiplePassives(t *testing.T) {
+	activeIdentityFile := createTempIdentityFile(t)
+	passiveIdentityFile := createTempIdentityFile(t)
+	t.Cleanup(func() {
+		os.Remove(activeIdentityFile)
+		os.Remove(passiveIdentityFile)
+	})
+
+	// listed twice
+	identities := &ValidatorIdentities{
+		ActiveKeyPairFile:   activeIdentityFile,
+		PassiveKeyPairFiles: []string{passiveIdentityFile, passiveIdentityFile},
+	}
+	require.NoError(t, identities.Load())
+	assert.ErrorContains(t, identities.Validate(), "validator.identities.passive must all be different")
+
+	// any passive being the active identity
+	identities = &ValidatorIdentities{
+		ActiveKeyPairFile:   activeIdentityFile,
+		PassiveKeyPairFiles: []string{passiveIdentityFile, activeIdentityFile},
+	}
+	require.NoError(t, identities.Load())
+	assert.ErrorContains(t, identities.Validate(), "validator.identities.active and validator.identities.passive must be different")
+
+	// none at all
+	identities = &ValidatorIdentities{ActiveKeyPairFile: activeIdentityFile}
+	assert.ErrorContains(t, identities.Load(), "validator.identities.passive must be defined")
+}
+
+// tmpfsTempDir returns a temporary directory on tmpfs, skipping the test without /dev/shm
+func tmpfsTempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("/dev/shm", "svha-test-")
+	if err != nil {
+		t.Skip("no /dev/shm to write identities to:", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestValidatorIdentities_PrepareVaultKeyPairDir(t *testing.T) {
+	shm := tmpfsTempDir(t)
+
+	// created owned by us with mode 0700
+	identities := &ValidatorIdentities{VaultKeyPairDir: filepath.Join(shm, "keys")}
+	require.NoError(t, identities.prepareVaultKeyPairDir())
+	info, err := os.Stat(identities.VaultKeyPairDir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+	require.NoError(t, identities.prepareVaultKeyPairDir())
+
+	// one someone could have created first for us to write to is refused
+	identities.VaultKeyPairDir = filepath.Join(shm, "open")
+	require.NoError(t, os.Mkdir(identities.VaultKeyPairDir, 0o700))
+	require.NoError(t, os.Chmod(identities.VaultKeyPairDir, 0o777))
+	assert.EqualError(t, identities.prepareVaultKeyPairDir(), "validator.identities.vault_keypair_dir "+identities.VaultKeyPairDir+" must have mode 0700, got 0777")
+
+	identities.VaultKeyPairDir = filepath.Join(shm, "link")
+	require.NoError(t, os.Symlink(filepath.Join(shm, "keys"), identities.VaultKeyPairDir))
+	assert.ErrorContains(t, identities.prepareVaultKeyPairDir(), "must be a directory")
+
+	if os.Getuid() == 0 {
+		identities.VaultKeyPairDir = filepath.Join(shm, "other")
+		require.NoError(t, os.Mkdir(identities.VaultKeyPairDir, 0o700))
+		require.NoError(t, os.Chown(identities.VaultKeyPairDir, 65534, 65534))
+		assert.ErrorContains(t, identities.prepareVaultKeyPairDir(), "must be owned by the running user (uid 0), owned by uid 65534")
+	}
+
+	// identities must never reach disk
+	var fs syscall.Statfs_t
+	require.NoError(t, syscall.Statfs(t.TempDir(), &fs))
+	if int64(fs.Type) != tmpfsMagic {
+		identities.VaultKeyPairDir = filepath.Join(t.TempDir(), "keys")
+		assert.ErrorContains(t, identities.prepareVaultKeyPairDir(), "must be on tmpfs so identities never reach disk")
+	}
+}
+
+func TestValidatorIdentities_LoadFromVault(t *testing.T) {
+	activeKeyPair := solanago.NewWallet().PrivateKey
+	passiveIdentityFile := createTempIdentityFile(t)
+	t.Cleanup(func() { os.Remove(passiveIdentityFile) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" || r.URL.Path != "/v1/secret/data/val12/active" {
+			http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+			return
+		}
+		values := make([]int, len(activeKeyPair))
+		for i, b := range activeKeyPair {
+			values[i] = int(b)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"data": map[string]any{"keypair": values}, "metadata": map[string]any{}}})
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "s.token")
+
+	identities := &ValidatorIdentities{
+		ActiveKeyPairFile:   "vault://secret/data/val12/active#keypair",
+		PassiveKeyPairFiles: []string{passiveIdentityFile},
+		VaultKeyPairDir:     filepath.Join(tmpfsTempDir(t), "keys"),
+	}
+	require.NoError(t, identities.Load())
+	assert.Equal(t, activeKeyPair.PublicKey(), identities.ActiveKeyPair.PublicKey())
+
+	// only written when asked, then readable by the validator and nobody else
+	activeKeyPairFile := identities.KeyPairFile(identities.ActiveKeyPairFile)
+	assert.Equal(t, filepath.Join(identities.VaultKeyPairDir, "secret_data_val12_active_keypair.json"), activeKeyPairFile)
+	assert.Equal(t, passiveIdentityFile, identities.KeyPairFile(passiveIdentityFile))
+	assert.NoFileExists(t, activeKeyPairFile)
+
+	require.NoError(t, identities.WriteVaultKeyPairFiles())
+	written, err := solanago.PrivateKeyFromSolanaKeygenFile(activeKeyPairFile)
+	require.NoError(t, err)
+	assert.Equal(t, activeKeyPair, written)
+	info, err := os.Stat(activeKeyPairFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// back to memory only once removed
+	require.NoError(t, identities.RemoveVaultKeyPairFiles())
+	assert.NoFileExists(t, activeKeyPairFile)
+	require.NoError(t, identities.RemoveVaultKeyPairFiles())
+
+	// vault errors fail loading
+	identities.ActiveKeyPairFile = "vault://secret/data/val12/missing#keypair"
+	err = identities.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load active identity vault://secret/data/val12/missing#keypair: failed to read vault://secret/data/val12/missing#keypair from vault: vault returned 404 Not Found")
+
+	t.Setenv("VAULT_ADDR", "")
+	identities.ActiveKeyPairFile = "vault://secret/data/val12/active#keypair"
+	err = identities.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "VAULT_ADDR must be set to read from vault")
 }
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WarningCode is a stable identifier for a non-fatal configuration warning, exported as a metric label
+type WarningCode string
+
+const (
+	// WarningDryRunEnabled - failover.dry_run is true so failovers are no-op
+	WarningDryRunEnabled WarningCode = "dry_run_enabled"
+	// WarningDryRunSampleHooks - failover.sample_hooks still run while dry_run is true
+	WarningDryRunSampleHooks WarningCode = "dry_run_sample_hooks"
+	// WarningTakeoverJitterLow - failover.takeover_jitter_duration is too low to be useful
+	WarningTakeoverJitterLow WarningCode = "takeover_jitter_low"
+	// WarningPollIntervalAggressive - failover.poll_interval_duration is likely to hit rpc rate limits
+	WarningPollIntervalAggressive WarningCode = "poll_interval_aggressive"
+	// WarningLeaderlessThresholdAggressive - a single leaderless sample triggers a failover
+	WarningLeaderlessThresholdAggressive WarningCode = "leaderless_threshold_aggressive"
+	// WarningMissingPostHooks - a role has pre hooks but no post hooks
+	WarningMissingPostHooks WarningCode = "missing_post_hooks"
+	// WarningPeerRPCConfirmWithoutURLs - failover.confirm_with_peer_rpc is set but no peer has an rpc_url
+	WarningPeerRPCConfirmWithoutURLs WarningCode = "peer_rpc_confirm_without_urls"
+	// WarningTakeoverPriorityOverlap - peers set priorities but the stagger between them is within the jitter
+	WarningTakeoverPriorityOverlap WarningCode = "takeover_priority_overlap"
+	// WarningInsecureKeypairPermissions - a keypair file is accessible to others, loaded with
+	// validator.identities.allow_insecure_permissions
+	WarningInsecureKeypairPermissions WarningCode = "insecure_keypair_permissions"
+	// WarningUnknownKeys - the config file has keys no setting is read from, loaded with --lenient-config
+	WarningUnknownKeys WarningCode = "unknown_keys"
+)
+
+// Warning is a non-fatal configuration warning
+type Warning struct {
+	Code    WarningCode `json:"code"`
+	Message string      `json:"message"`
+}
+
+// warningDefinition checks a config for a single warning code, returning the message and true if it applies
+type warningDefinition struct {
+	code  WarningCode
+	check func(c *Config) (message string, applies bool)
+}
+
+// warningDefinitions is the one table of every configuration warning, evaluated in order
+var warningDefinitions = []warningDefinition{
+	{
+		code: WarningDryRunEnabled,
+		check: func(c *Config) (string, bool) {
+			return "failover.dry_run is true - failovers will dry-run commands only and be no-op", c.Failover.DryRun
+		},
+	},
+	{
+		code: WarningDryRunSampleHooks,
+		check: func(c *Config) (string, bool) {
+			return "failover.dry_run is true - failover.sample_hooks are observational and will still run",
+				c.Failover.DryRun && len(c.Failover.SampleHooks) > 0
+		},
+	},
+	{
+		code: WarningTakeoverJitterLow,
+		check: func(c *Config) (string, bool) {
+			return "failover.takeover_jitter_duration is below 1s - this may void the usefulness of jitter in preventing race conditions",
+				c.Failover.TakeoverJitterDuration > 0 && c.Failover.TakeoverJitterDuration < time.Second
+		},
+	},
+	{
+		code: WarningPollIntervalAggressive,
+		check: func(c *Config) (string, bool) {
+			return fmt.Sprintf("failover.poll_interval_duration is %s - intervals below %s are likely to be rate limited by public rpc endpoints",
+					c.Failover.PollIntervalDuration, PollIntervalWarnThreshold),
+				c.Failover.PollIntervalDuration > 0 && c.Failover.PollIntervalDuration < PollIntervalWarnThreshold
+		},
+	},
+	{
+		code: WarningLeaderlessThresholdAggressive,
+		check: func(c *Config) (string, bool) {
+			return "failover.leaderless_samples_threshold is 1 - a single missed gossip sample will trigger a failover",
+				c.Failover.LeaderlessSamplesThreshold == 1
+		},
+	},
+	{
+		code: WarningMissingPostHooks,
+		check: func(c *Config) (string, bool) {
+			var roles []string
+			for _, role := range []*Role{&c.Failover.Active, &c.Failover.Passive} {
+				if len(role.Hooks.Pre) > 0 && len(role.Hooks.Post) == 0 {
+					roles = append(roles, role.Name)
+				}
+			}
+			return fmt.Sprintf("failover roles %v have pre hooks but no post hooks - nothing will report the outcome of a transition", roles),
+				len(roles) > 0
+		},
+	},
+	{
+		code: WarningPeerRPCConfirmWithoutURLs,
+		check: func(c *Config) (string, bool) {
+			return "failover.confirm_with_peer_rpc is true but no failover.peers have an rpc_url - failovers rely on gossip alone",
+				c.Failover.ConfirmWithPeerRPC && !c.Failover.Peers.HasRPCURLs()
+		},
+	},
+	{
+		code: WarningTakeoverPriorityOverlap,
+		check: func(c *Config) (string, bool) {
+			return fmt.Sprintf("failover.takeover_priority_stagger %s is not longer than failover.takeover_jitter_duration %s - a lower priority peer may claim before a higher one",
+					c.Failover.TakeoverPriorityStagger, c.Failover.TakeoverJitterDuration),
+				(c.Failover.Peers.HasPriorities() || c.Validator.Priority != 0) && c.Failover.TakeoverPriorityStagger <= c.Failover.TakeoverJitterDuration
+		},
+	},
+	{
+		code: WarningInsecureKeypairPermissions,
+		check: func(c *Config) (string, bool) {
+			return strings.Join(c.Validator.Identities.insecurePermissions, ", "),
+				len(c.Validator.Identities.insecurePermissions) > 0
+		},
+	},
+	{
+		code: WarningUnknownKeys,
+		check: func(c *Config) (string, bool) {
+			return fmt.Sprintf("unknown keys in config file are ignored: %s", formatUnknownKeys(c.unknownKeys)),
+				len(c.unknownKeys) > 0
+		},
+	},
+}
+
+// WarningCodes returns every known warning code in table order
+func WarningCodes() []WarningCode {
+	codes := make([]WarningCode, 0, len(warningDefinitions))
+	for _, definition := range warningDefinitions {
+		codes = append(codes, definition.code)
+	}
+	return codes
+}
+
+// EvaluateWarnings re-evaluates every warning against the current config, replacing c.Warnings and logging each one
+func (c *Config) EvaluateWarnings() []Warning {
+	return c.evaluateWarnings(nil)
+}
+
+// refreshWarnings re-evaluates every warning like EvaluateWarnings but only logs the ones not already in c.Warnings
+func (c *Config) refreshWarnings() []Warning {
+	logged := map[WarningCode]bool{}
+	for _, warning := range c.Warnings {
+		logged[warning.Code] = true
+	}
+	return c.evaluateWarnings(logged)
+}
+
+// evaluateWarnings replaces c.Warnings with the warnings that apply, logging those not in logged
+func (c *Config) evaluateWarnings(logged map[WarningCode]bool) []Warning {
+	c.Warnings = nil
+	for _, definition := range warningDefinitions {
+		message, applies := definition.check(c)
+		if !applies {
+			continue
+		}
+		c.Warnings = append(c.Warnings, Warning{Code: definition.code, Message: message})
+		if c.logger != nil && !logged[definition.code] {
+			c.logger.Warn(message, "warning_code", definition.code)
+		}
+	}
+	return c.Warnings
+}
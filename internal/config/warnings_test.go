@@ -0,0 +1,116 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWarningFreeConfig returns a config that produces no warnings
+func newWarningFreeConfig() *Config {
+	return &Config{
+		Failover: Failover{
+			PollIntervalDuration:       5 * time.Second,
+			LeaderlessSamplesThreshold: 3,
+			TakeoverJitterDuration:     3 * time.Second,
+			Active:                     Role{Name: "active", Command: "true"},
+			Passive:                    Role{Name: "passive", Command: "true"},
+		},
+	}
+}
+
+func TestEvaluateWarnings_NoWarnings(t *testing.T) {
+	assert.Empty(t, newWarningFreeConfig().EvaluateWarnings())
+}
+
+func TestEvaluateWarnings_EveryCodeCovered(t *testing.T) {
+	// every code in the table must have a config that triggers it - adding a warning without a case here fails
+	triggers := map[WarningCode]func(c *Config){
+		WarningDryRunEnabled: func(c *Config) {
+			c.Failover.DryRun = true
+		},
+		WarningDryRunSampleHooks: func(c *Config) {
+			c.Failover.DryRun = true
+			c.Failover.SampleHooks = []Hook{{Name: "sample", Command: "true"}}
+		},
+		WarningTakeoverJitterLow: func(c *Config) {
+			c.Failover.TakeoverJitterDuration = 500 * time.Millisecond
+		},
+		WarningPollIntervalAggressive: func(c *Config) {
+			c.Failover.PollIntervalDuration = time.Second
+		},
+		WarningLeaderlessThresholdAggressive: func(c *Config) {
+			c.Failover.LeaderlessSamplesThreshold = 1
+		},
+		WarningMissingPostHooks: func(c *Config) {
+			c.Failover.Active.Hooks.Pre = []Hook{{Name: "notify", Command: "true"}}
+		},
+		WarningPeerRPCConfirmWithoutURLs: func(c *Config) {
+			c.Failover.ConfirmWithPeerRPC = true
+		},
+		WarningTakeoverPriorityOverlap: func(c *Config) {
+			c.Validator.Priority = 10
+			c.Failover.TakeoverPriorityStagger = c.Failover.TakeoverJitterDuration
+		},
+		WarningInsecureKeypairPermissions: func(c *Config) {
+			c.Validator.Identities.insecurePermissions = []string{"validator.identities.active keypair file /keys/active.json must not be accessible by group or other, got mode 0640 - chmod 600 it"}
+		},
+		WarningUnknownKeys: func(c *Config) {
+			c.unknownKeys = []unknownKey{{Path: "failover.active.post_hooks", Suggestion: "failover.active.hooks.post"}}
+		},
+	}
+
+	assert.ElementsMatch(t, WarningCodes(), keys(triggers))
+
+	for _, code := range WarningCodes() {
+		t.Run(string(code), func(t *testing.T) {
+			cfg := newWarningFreeConfig()
+			triggers[code](cfg)
+
+			warnings := cfg.EvaluateWarnings()
+			codes := []WarningCode{}
+			for _, warning := range warnings {
+				assert.NotEmpty(t, warning.Message)
+				codes = append(codes, warning.Code)
+			}
+			assert.Contains(t, codes, code)
+		})
+	}
+}
+
+func TestWarningCodes_Unique(t *testing.T) {
+	seen := map[WarningCode]bool{}
+	for _, code := range WarningCodes() {
+		require.False(t, seen[code], "duplicate warning code %s", code)
+		seen[code] = true
+	}
+}
+
+func TestEvaluateWarnings_ReEvaluationReplaces(t *testing.T) {
+	cfg := newWarningFreeConfig()
+	cfg.Failover.DryRun = true
+	require.Len(t, cfg.EvaluateWarnings(), 1)
+	assert.Equal(t, WarningDryRunEnabled, cfg.Warnings[0].Code)
+
+	cfg.Failover.DryRun = false
+	assert.Empty(t, cfg.EvaluateWarnings())
+	assert.Empty(t, cfg.Warnings)
+}
+
+func TestEvaluateWarnings_MissingPostHooksMessage(t *testing.T) {
+	cfg := newWarningFreeConfig()
+	cfg.Failover.Passive.Hooks.Pre = []Hook{{Name: "notify", Command: "true"}}
+	warnings := cfg.EvaluateWarnings()
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0].Message, "[passive]")
+}
+
+func keys[K comparable, V any](m map[K]V) []K {
+	result := make([]K, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// DefaultWebhookMethod is the method a webhook hook is called with when it doesn't set one
+	DefaultWebhookMethod = http.MethodPost
+	// hookResponseBodyLimit bounds how much of a slack or webhook response is read into output and errors
+	hookResponseBodyLimit = 512
+)
+
+// webhookMethods are the methods a webhook hook may be called with - they all carry the body
+var webhookMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch}
+
+// secretHeaderNameRegexp matches header names whose values are redacted from logs and the printed configuration
+var secretHeaderNameRegexp = regexp.MustCompile(`(?i)(authorization|cookie|token|secret|password|passwd|api[-_]?key|credential|signature)`)
+
+// WebhookHook calls an HTTP endpoint with a JSON body in place of a hook command
+type WebhookHook struct {
+	// URL supports ${ENV_VAR} references for urls embedding a secret
+	URL string `koanf:"url"`
+	// Method defaults to POST
+	Method string `koanf:"method"`
+	// Headers values support ${ENV_VAR} references, secret ones are redacted from logs
+	Headers map[string]string `koanf:"headers"`
+	// Body is a JSON template rendered with WebhookTemplateData each time the hook runs, every field of it if unset
+	Body string `koanf:"body"`
+}
+
+// WebhookTemplateData is the failover context a webhook body is rendered with, and the default body
+type WebhookTemplateData struct {
+	Role          string `json:"role"`
+	Phase         string `json:"phase"`
+	Reason        string `json:"reason"`
+	PeerName      string `json:"peer_name"`
+	ValidatorName string `json:"validator_name"`
+	ActivePubkey  string `json:"active_pubkey"`
+	PassivePubkey string `json:"passive_pubkey"`
+	// FailoverID is the id correlating everything the transition logs, records and notifies
+	FailoverID string `json:"failover_id"`
+	// Timestamp is when the hook ran, RFC3339 in UTC
+	Timestamp string `json:"timestamp"`
+}
+
+// webhookTemplateFuncs are available to body templates - json quotes a value so it is always valid JSON
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// newWebhookTemplateData returns the template data of a hook of hookType run in hookContext, which may be nil
+func newWebhookTemplateData(hookType string, hookContext *HookContext, at time.Time) WebhookTemplateData {
+	data := WebhookTemplateData{Phase: hookType, Timestamp: at.UTC().Format(time.RFC3339)}
+	if hookContext != nil {
+		data.Role = hookContext.Role
+		data.Reason = hookContext.Reason
+		data.PeerName = hookContext.PeerName
+		data.ValidatorName = hookContext.ValidatorName
+		data.ActivePubkey = hookContext.ActivePubkey
+		data.PassivePubkey = hookContext.PassivePubkey
+		data.FailoverID = hookContext.FailoverID
+	}
+	return data
+}
+
+// Validate validates the webhook hook configuration
+func (w *WebhookHook) Validate() error {
+	// webhook.url must be defined and, unless it references an env var only known when run, a valid url
+	if w.URL == "" {
+		return fmt.Errorf("webhook.url must be defined")
+	}
+	if !hookEnvVarRegexp.MatchString(w.URL) && !isHTTPURL(w.URL) {
+		return fmt.Errorf("webhook.url must be a valid http(s) url")
+	}
+
+	// webhook.method must be one that carries a body
+	if !slices.Contains(webhookMethods, w.Method) {
+		return fmt.Errorf("webhook.method must be one of %s, got %s", strings.Join(webhookMethods, ", "), w.Method)
+	}
+
+	// webhook.body must be a template rendering valid JSON
+	body, err := w.renderBody(newWebhookTemplateData("pre", &HookContext{Role: "active", Reason: "validate"}, time.Now()))
+	if err != nil {
+		return err
+	}
+	if !json.Valid(body) {
+		return fmt.Errorf("webhook.body must render valid JSON, got %s", body)
+	}
+
+	return nil
+}
+
+// SetDefaults sets default values for the webhook hook
+func (w *WebhookHook) SetDefaults() {
+	if w.Method == "" {
+		w.Method = DefaultWebhookMethod
+	}
+	w.Method = strings.ToUpper(w.Method)
+}
+
+// renderBody renders the body template with data, or data itself as JSON if there is no body template
+func (w *WebhookHook) renderBody(data WebhookTemplateData) ([]byte, error) {
+	if w.Body == "" {
+		return json.Marshal(data)
+	}
+
+	tmpl, err := template.New("body").Funcs(webhookTemplateFuncs).Option("missingkey=error").Parse(w.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webhook.body: %w", err)
+	}
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render webhook.body: %w", err)
+	}
+	return body.Bytes(), nil
+}
+
+// headerNames returns the header names sorted, the order their values are expanded in
+func (w *WebhookHook) headerNames() []string {
+	names := make([]string, 0, len(w.Headers))
+	for name := range w.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// redactedHeaders returns the headers as they are logged, secret values replaced by ***
+func (w *WebhookHook) redactedHeaders() map[string]string {
+	redact := &Redact{}
+	headers := make(map[string]string, len(w.Headers))
+	for name, value := range w.Headers {
+		if secretHeaderNameRegexp.MatchString(name) || redact.IsSecret(value) {
+			value = RedactedValue
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// call sends body to webhookURL with headers within timeout, failing on any non 2xx response
+func (w *WebhookHook) call(webhookURL string, headers map[string]string, body []byte, timeout time.Duration, output io.Writer) error {
+	return postHook("webhook", w.Method, webhookURL, headers, body, timeout, output)
+}
+
+// postHook sends a JSON body to a slack or webhook hook's url within timeout, failing on any non 2xx response.
+// The response body is copied to output if set. Errors never include the url as it may embed a secret.
+func postHook(kind string, method string, hookURL string, headers map[string]string, body []byte, timeout time.Duration, output io.Writer) error {
+	req, err := http.NewRequest(method, hookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: invalid url", kind)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		// url errors embed the url and with it any secret
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to post to %s: %w", kind, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, hookResponseBodyLimit))
+	if output != nil && len(respBody) > 0 {
+		fmt.Fprintln(output, strings.TrimSpace(string(respBody)))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s: %s", kind, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
+
+// isHTTPURL returns true if s is an absolute http or https url
+func isHTTPURL(s string) bool {
+	parsedURL, err := url.Parse(s)
+	return err == nil && (parsedURL.Scheme == "http" || parsedURL.Scheme == "https") && parsedURL.Host != ""
+}
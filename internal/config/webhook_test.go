@@ -0,0 +1,232 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// webhookRequest is a request received by webhookServer
+type webhookRequest struct {
+	Method  string
+	Headers http.Header
+	Body    map[string]any
+}
+
+// webhookServer is a fake incident system endpoint recording requests and answering with status
+func webhookServer(t *testing.T, status int) (*httptest.Server, *[]webhookRequest) {
+	t.Helper()
+
+	requests := &[]webhookRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		request := webhookRequest{Method: r.Method, Headers: r.Header}
+		require.NoError(t, json.Unmarshal(data, &request.Body))
+		*requests = append(*requests, request)
+		w.WriteHeader(status)
+		w.Write([]byte(`{"incident":"INC-1"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+func TestWebhookHook_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook WebhookHook
+		wantErr string
+	}{
+		{name: "valid", webhook: WebhookHook{URL: "https://incidents.example.com/events"}},
+		{name: "env var url", webhook: WebhookHook{URL: "${INCIDENT_URL}"}},
+		{name: "body template", webhook: WebhookHook{URL: "https://incidents.example.com/events", Body: `{"summary": {{ json (printf "%s is %s" .ValidatorName .Role) }}}`}},
+		{name: "missing url", webhook: WebhookHook{}, wantErr: "webhook.url must be defined"},
+		{name: "invalid url", webhook: WebhookHook{URL: "incidents.example.com"}, wantErr: "webhook.url must be a valid http(s) url"},
+		{name: "invalid method", webhook: WebhookHook{URL: "https://incidents.example.com/events", Method: "get"}, wantErr: "webhook.method must be one of POST, PUT, PATCH, got GET"},
+		{name: "invalid template", webhook: WebhookHook{URL: "https://incidents.example.com/events", Body: `{"role": {{ .Role }`}, wantErr: "failed to parse webhook.body"},
+		{name: "unknown field", webhook: WebhookHook{URL: "https://incidents.example.com/events", Body: `{"role": {{ json .Nope }}}`}, wantErr: "failed to render webhook.body"},
+		{name: "invalid json", webhook: WebhookHook{URL: "https://incidents.example.com/events", Body: `{"role": {{ .Role }}}`}, wantErr: "webhook.body must render valid JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.webhook.SetDefaults()
+			err := tt.webhook.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHook_Validate_Webhook(t *testing.T) {
+	webhook := &WebhookHook{URL: "https://incidents.example.com/events", Method: DefaultWebhookMethod}
+
+	hook := Hook{Name: "incident", Webhook: webhook}
+	assert.NoError(t, hook.Validate(true))
+
+	hook = Hook{Name: "incident", Slack: &SlackHook{WebhookURL: "https://hooks.slack.com/services/T0/B0/x", Message: "hi"}, Webhook: webhook}
+	assert.EqualError(t, hook.Validate(true), "must have only one of command, slack or webhook")
+
+	hook = Hook{Name: "incident"}
+	assert.EqualError(t, hook.Validate(true), "must have a command, slack or webhook")
+}
+
+func TestHook_Run_WebhookDefaultBody(t *testing.T) {
+	server, requests := webhookServer(t, http.StatusAccepted)
+	t.Setenv("TEST_INCIDENT_TOKEN", "s3cr3t")
+
+	hook := Hook{
+		Name: "incident",
+		Webhook: &WebhookHook{
+			URL:     server.URL + "/events",
+			Headers: map[string]string{"Authorization": "Bearer ${TEST_INCIDENT_TOKEN}", "X-Source": "svha"},
+		},
+	}
+	hook.SetDefaults()
+
+	var output bytes.Buffer
+	_, err := hook.Run(HookRunOptions{
+		HookType: "post",
+		Output:   &output,
+		Context: &HookContext{
+			Role:          "active",
+			Reason:        "no_active_peer",
+			PeerName:      "backup",
+			ValidatorName: "primary",
+			ActivePubkey:  "active-pubkey",
+			PassivePubkey: "passive-pubkey",
+			FailoverID:    "3f9a0c1e",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "{\"incident\":\"INC-1\"}\n", output.String())
+
+	require.Len(t, *requests, 1)
+	request := (*requests)[0]
+	assert.Equal(t, http.MethodPost, request.Method)
+	assert.Equal(t, "Bearer s3cr3t", request.Headers.Get("Authorization"))
+	assert.Equal(t, "svha", request.Headers.Get("X-Source"))
+	assert.Equal(t, "application/json", request.Headers.Get("Content-Type"))
+
+	timestamp, err := time.Parse(time.RFC3339, request.Body["timestamp"].(string))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), timestamp, time.Minute)
+	delete(request.Body, "timestamp")
+	assert.Equal(t, map[string]any{
+		"role":           "active",
+		"phase":          "post",
+		"reason":         "no_active_peer",
+		"peer_name":      "backup",
+		"validator_name": "primary",
+		"active_pubkey":  "active-pubkey",
+		"passive_pubkey": "passive-pubkey",
+		"failover_id":    "3f9a0c1e",
+	}, request.Body)
+}
+
+func TestHook_Run_WebhookBodyTemplate(t *testing.T) {
+	server, requests := webhookServer(t, http.StatusOK)
+
+	hook := Hook{
+		Name: "incident",
+		Webhook: &WebhookHook{
+			URL:    server.URL,
+			Method: "put",
+			Body:   `{"summary": {{ json (printf "%s %s-%s" .ValidatorName .Phase .Role) }}, "severity": "critical"}`,
+		},
+	}
+	hook.SetDefaults()
+	require.NoError(t, hook.Validate(true))
+
+	_, err := hook.Run(HookRunOptions{HookType: "pre", Context: &HookContext{Role: "passive", ValidatorName: `primary "a"`}})
+	require.NoError(t, err)
+	require.Len(t, *requests, 1)
+	assert.Equal(t, http.MethodPut, (*requests)[0].Method)
+	assert.Equal(t, map[string]any{"summary": `primary "a" pre-passive`, "severity": "critical"}, (*requests)[0].Body)
+}
+
+func TestHook_Run_WebhookFailures(t *testing.T) {
+	// a non 2xx response fails the hook, aborting a must_succeed pre hook
+	server, requests := webhookServer(t, http.StatusServiceUnavailable)
+	hook := Hook{Name: "incident", MustSucceed: true, Webhook: &WebhookHook{URL: server.URL + "/events?token=s3cr3t"}}
+	hook.SetDefaults()
+
+	ran := Hook{Name: "after", Command: "true"}
+	results := []HookResult{}
+	err := (&Hooks{Pre: []Hook{hook, ran}}).RunPre(HooksRunOptions{OnResult: func(result HookResult) { results = append(results, result) }})
+	assert.EqualError(t, err, `webhook returned 503 Service Unavailable: {"incident":"INC-1"}`)
+	assert.Len(t, results, 1)
+	assert.Len(t, *requests, 1)
+
+	// errors never include the url
+	hook.Webhook.URL = "http://127.0.0.1:1/events?token=s3cr3t"
+	_, err = hook.Run(HookRunOptions{})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "s3cr3t")
+
+	// a slow endpoint is timed out
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	t.Cleanup(slow.Close)
+	hook.Timeout = 100 * time.Millisecond
+	hook.Webhook.URL = slow.URL
+	_, err = hook.Run(HookRunOptions{})
+	assert.ErrorContains(t, err, "failed to post to webhook")
+}
+
+func TestHook_Run_WebhookRedactsHeaders(t *testing.T) {
+	server, requests := webhookServer(t, http.StatusOK)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	hook := Hook{
+		Name: "incident",
+		Webhook: &WebhookHook{
+			URL:     server.URL,
+			Headers: map[string]string{"Authorization": "s3cr3t", "X-Api-Key": "s3cr3t", "X-Trace": "Bearer s3cr3t", "X-Source": "svha"},
+		},
+	}
+	hook.SetDefaults()
+
+	_, err := hook.Run(HookRunOptions{HookType: "post"})
+	require.NoError(t, err)
+	_, err = hook.Run(HookRunOptions{HookType: "post", DryRun: true})
+	require.NoError(t, err)
+	assert.Len(t, *requests, 1)
+	assert.Contains(t, logs.String(), "would call webhook")
+	assert.Contains(t, logs.String(), "svha")
+	assert.NotContains(t, logs.String(), "s3cr3t")
+}
+
+func TestResolvedYAML_RedactsWebhookHeaders(t *testing.T) {
+	cfg := Config{Failover: Failover{AlertHooks: []Hook{{
+		Name:    "incident",
+		Webhook: &WebhookHook{URL: "https://incidents.example.com", Headers: map[string]string{"Authorization": "s3cr3t", "X-Source": "svha"}},
+	}}}}
+
+	data, err := cfg.ResolvedYAML()
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "s3cr3t")
+
+	var resolved map[string]any
+	require.NoError(t, yaml.Unmarshal(data, &resolved))
+	webhook := resolved["failover"].(map[string]any)["alert_hooks"].([]any)[0].(map[string]any)["webhook"].(map[string]any)
+	assert.Equal(t, map[string]any{"Authorization": RedactedValue, "X-Source": "svha"}, webhook["headers"])
+}
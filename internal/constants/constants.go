@@ -1,25 +1,12 @@
 package constants
 
 const (
-	// RoleNameActive is the name of the active role
-	RoleNameActive = "active"
-	// RoleNamePassive is the name of the passive role
-	RoleNamePassive = "passive"
-	// RoleNameUnknown is the name of the unknown role
-	RoleNameUnknown = "unknown"
-
-	// StatusHealthy is the name of the healthy status
-	StatusHealthy = "healthy"
-	// StatusUnhealthy is the name of the unhealthy status
-	StatusUnhealthy = "unhealthy"
-	// StatusIdle is the name of the idle status
-	StatusIdle = "idle"
-	// StatusBecomingActive is the name of the becoming active status
-	StatusBecomingActive = "becoming_active"
-	// StatusBecomingPassive is the name of the becoming passive status
-	StatusBecomingPassive = "becoming_passive"
 	// HookTypePre is the name of the pre hook type
 	HookTypePre = "pre"
 	// HookTypePost is the name of the post hook type
 	HookTypePost = "post"
+	// HookTypeSample is the name of the per-cycle observational hook type
+	HookTypeSample = "sample"
+	// HookTypeAlert is the name of the critical alarm hook type
+	HookTypeAlert = "alert"
 )
@@ -0,0 +1,158 @@
+package constants
+
+import (
+	"fmt"
+)
+
+// Role is the role of a validator peer
+type Role string
+
+const (
+	// RoleActive is the active (voting) role
+	RoleActive Role = "active"
+	// RolePassive is the passive (non-voting) role
+	RolePassive Role = "passive"
+	// RoleUnknown is used when the role could not be determined
+	RoleUnknown Role = "unknown"
+)
+
+// Roles returns all valid roles
+func Roles() []Role {
+	return []Role{RoleActive, RolePassive, RoleUnknown}
+}
+
+// String returns the string value of the role
+func (r Role) String() string {
+	return string(r)
+}
+
+// Valid returns true if the role is one of the known roles
+func (r Role) Valid() bool {
+	return isOneOf(r, Roles())
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler rejecting unknown values
+func (r *Role) UnmarshalText(text []byte) error {
+	return unmarshalOneOf(r, text, Roles(), "role")
+}
+
+// Status is the health status of a validator
+type Status string
+
+const (
+	// StatusHealthy is the healthy status
+	StatusHealthy Status = "healthy"
+	// StatusUnhealthy is the unhealthy status
+	StatusUnhealthy Status = "unhealthy"
+	// StatusUnknown is used when the status could not be determined
+	StatusUnknown Status = "unknown"
+)
+
+// Statuses returns all valid statuses
+func Statuses() []Status {
+	return []Status{StatusHealthy, StatusUnhealthy, StatusUnknown}
+}
+
+// String returns the string value of the status
+func (s Status) String() string {
+	return string(s)
+}
+
+// Valid returns true if the status is one of the known statuses
+func (s Status) Valid() bool {
+	return isOneOf(s, Statuses())
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler rejecting unknown values
+func (s *Status) UnmarshalText(text []byte) error {
+	return unmarshalOneOf(s, text, Statuses(), "status")
+}
+
+// FailoverStatus is the failover status of the HA manager
+type FailoverStatus string
+
+const (
+	// FailoverStatusIdle is when no transition is in progress
+	FailoverStatusIdle FailoverStatus = "idle"
+	// FailoverStatusBecomingActive is when a transition to the active role is in progress
+	FailoverStatusBecomingActive FailoverStatus = "becoming_active"
+	// FailoverStatusBecomingPassive is when a transition to the passive role is in progress
+	FailoverStatusBecomingPassive FailoverStatus = "becoming_passive"
+	// FailoverStatusFailed is when the last transition failed
+	FailoverStatusFailed FailoverStatus = "failed"
+	// FailoverStatusBlocked is when a required transition is being prevented from starting
+	FailoverStatusBlocked FailoverStatus = "blocked"
+	// FailoverStatusDegraded is when the manager is running with reduced ability to make decisions
+	FailoverStatusDegraded FailoverStatus = "degraded"
+	// FailoverStatusRollbackFailed is when rolling back a failed transition also failed - no further transitions
+	// are attempted until the agent is restarted
+	FailoverStatusRollbackFailed FailoverStatus = "rollback_failed"
+)
+
+// FailoverStatuses returns all valid failover statuses
+func FailoverStatuses() []FailoverStatus {
+	return []FailoverStatus{
+		FailoverStatusIdle,
+		FailoverStatusBecomingActive,
+		FailoverStatusBecomingPassive,
+		FailoverStatusFailed,
+		FailoverStatusBlocked,
+		FailoverStatusDegraded,
+		FailoverStatusRollbackFailed,
+	}
+}
+
+// String returns the string value of the failover status
+func (f FailoverStatus) String() string {
+	return string(f)
+}
+
+// Valid returns true if the failover status is one of the known failover statuses
+func (f FailoverStatus) Valid() bool {
+	return isOneOf(f, FailoverStatuses())
+}
+
+// IsTerminal returns true if the failover status is not an in-progress transition
+func (f FailoverStatus) IsTerminal() bool {
+	return f.Valid() && f != FailoverStatusBecomingActive && f != FailoverStatusBecomingPassive
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (f FailoverStatus) MarshalText() ([]byte, error) {
+	return []byte(f), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler rejecting unknown values
+func (f *FailoverStatus) UnmarshalText(text []byte) error {
+	return unmarshalOneOf(f, text, FailoverStatuses(), "failover status")
+}
+
+// isOneOf returns true if value is in values
+func isOneOf[T ~string](value T, values []T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshalOneOf sets target from text if text is one of values
+func unmarshalOneOf[T ~string](target *T, text []byte, values []T, kind string) error {
+	value := T(text)
+	if !isOneOf(value, values) {
+		return fmt.Errorf("invalid %s %q - must be one of %v", kind, string(text), values)
+	}
+	*target = value
+	return nil
+}
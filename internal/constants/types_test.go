@@ -0,0 +1,64 @@
+package constants
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverStatus_Valid(t *testing.T) {
+	for _, failoverStatus := range FailoverStatuses() {
+		assert.True(t, failoverStatus.Valid(), failoverStatus.String())
+	}
+	assert.False(t, FailoverStatus("stable").Valid())
+	assert.False(t, FailoverStatus("").Valid())
+}
+
+func TestFailoverStatus_IsTerminal(t *testing.T) {
+	assert.True(t, FailoverStatusIdle.IsTerminal())
+	assert.True(t, FailoverStatusFailed.IsTerminal())
+	assert.True(t, FailoverStatusBlocked.IsTerminal())
+	assert.True(t, FailoverStatusDegraded.IsTerminal())
+	assert.False(t, FailoverStatusBecomingActive.IsTerminal())
+	assert.False(t, FailoverStatusBecomingPassive.IsTerminal())
+	assert.False(t, FailoverStatus("bogus").IsTerminal())
+}
+
+func TestRoleAndStatus_Valid(t *testing.T) {
+	for _, role := range Roles() {
+		assert.True(t, role.Valid(), role.String())
+	}
+	assert.False(t, Role("leader").Valid())
+
+	for _, status := range Statuses() {
+		assert.True(t, status.Valid(), status.String())
+	}
+	assert.False(t, Status("fine").Valid())
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	type payload struct {
+		Role           Role           `json:"role"`
+		Status         Status         `json:"status"`
+		FailoverStatus FailoverStatus `json:"failover_status"`
+	}
+
+	in := payload{Role: RoleActive, Status: StatusHealthy, FailoverStatus: FailoverStatusBecomingPassive}
+	data, err := json.Marshal(in)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"role":"active","status":"healthy","failover_status":"becoming_passive"}`, string(data))
+
+	var out payload
+	require.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+
+	// unknown values are rejected
+	err = json.Unmarshal([]byte(`{"role":"leader"}`), &out)
+	assert.ErrorContains(t, err, `invalid role "leader"`)
+	err = json.Unmarshal([]byte(`{"status":"fine"}`), &out)
+	assert.ErrorContains(t, err, `invalid status "fine"`)
+	err = json.Unmarshal([]byte(`{"failover_status":"stable"}`), &out)
+	assert.ErrorContains(t, err, `invalid failover status "stable"`)
+}
@@ -0,0 +1,49 @@
+// Package debugvars publishes the agent's counters as expvars, served on /debug/vars by the debug server when
+// debug.expvar is set alongside the memstats and cmdline expvar always publishes
+package debugvars
+
+import (
+	"expvar"
+	"runtime"
+	"time"
+)
+
+// startedAt is when the process started, for uptime
+var startedAt = time.Now()
+
+var (
+	// PollIterations is the number of HA monitor cycles run
+	PollIterations = new(expvar.Int)
+	// PollOverruns is the number of HA monitor cycles that took longer than the poll interval
+	PollOverruns = new(expvar.Int)
+	// RPCCalls is the number of rpc requests sent by method
+	RPCCalls = new(expvar.Map).Init()
+	// RPCErrors is the number of failed rpc requests by method
+	RPCErrors = new(expvar.Map).Init()
+)
+
+func init() {
+	vars := expvar.NewMap("solana_validator_ha")
+	vars.Set("poll_iterations", PollIterations)
+	vars.Set("poll_overruns", PollOverruns)
+	vars.Set("rpc_calls", RPCCalls)
+	vars.Set("rpc_errors", RPCErrors)
+	vars.Set("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+	vars.Set("uptime_seconds", expvar.Func(func() any { return int64(time.Since(startedAt).Seconds()) }))
+}
+
+// ObservePollIteration counts an HA monitor cycle, overran if it took longer than the poll interval
+func ObservePollIteration(overran bool) {
+	PollIterations.Add(1)
+	if overran {
+		PollOverruns.Add(1)
+	}
+}
+
+// ObserveRPCRequest counts an rpc request and whether it failed - it is an rpc.RequestObserver
+func ObserveRPCRequest(method string, endpoint string, retried bool, duration time.Duration, err error) {
+	RPCCalls.Add(method, 1)
+	if err != nil {
+		RPCErrors.Add(method, 1)
+	}
+}
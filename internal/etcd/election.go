@@ -0,0 +1,131 @@
+package etcd
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Election holds a key with an agent's name on a lease, which expires a lease ttl after the agent last
+// campaigned or kept it alive - so the key only ever names an agent that is still around to keep it. It is safe
+// for concurrent use so the lease can be kept alive apart from campaigning.
+type Election struct {
+	client *Client
+	key    string
+	name   string
+	ttl    time.Duration
+
+	mu sync.Mutex
+	// lease is the lease we hold the key on, 0 when we hold none
+	lease int64
+}
+
+// NewElection returns an election of key campaigning for name on leases of ttl
+func NewElection(client *Client, key string, name string, ttl time.Duration) *Election {
+	return &Election{client: client, key: key, name: name, ttl: ttl}
+}
+
+// Key returns the key elected on
+func (e *Election) Key() string {
+	return e.key
+}
+
+// Holding returns true while we hold a lease, whether or not the key is attached to it
+func (e *Election) Holding() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.lease != 0
+}
+
+// KeepAlive renews the lease we hold, if any, without campaigning - returning true when it had expired, the key
+// going with it
+func (e *Election) KeepAlive(ctx context.Context) (leaseExpired bool, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.lease == 0 {
+		return false, nil
+	}
+	alive, err := e.client.KeepAlive(ctx, e.lease)
+	if err != nil {
+		return false, err
+	}
+	if !alive {
+		e.lease = 0
+	}
+	return !alive, nil
+}
+
+// Campaign takes the key or keeps it, returning the name of who holds it - ours unless another agent's lease
+// still holds it. leaseExpired is true when the lease we held had expired, the key going with it.
+func (e *Election) Campaign(ctx context.Context) (holder string, leaseExpired bool, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.lease != 0 {
+		alive, err := e.client.KeepAlive(ctx, e.lease)
+		if err != nil {
+			return "", false, err
+		}
+		if !alive {
+			e.lease = 0
+			leaseExpired = true
+		}
+	}
+	if e.lease == 0 {
+		if e.lease, err = e.client.Grant(ctx, e.ttl); err != nil {
+			return "", leaseExpired, err
+		}
+	}
+
+	kv, found, err := e.client.Get(ctx, e.key)
+	if err != nil {
+		return "", leaseExpired, err
+	}
+	if found && (kv.Value != e.name || kv.Lease == e.lease) {
+		return kv.Value, leaseExpired, nil
+	}
+
+	// the key is free, or ours on a lease from before a restart
+	var put bool
+	if found {
+		put, err = e.client.PutIfValue(ctx, e.key, e.name, e.name, e.lease)
+	} else {
+		put, err = e.client.PutIfAbsent(ctx, e.key, e.name, e.lease)
+	}
+	if err != nil {
+		return "", leaseExpired, err
+	}
+	if put {
+		return e.name, leaseExpired, nil
+	}
+
+	// another agent took it in between
+	holder, err = e.Leader(ctx)
+	return holder, leaseExpired, err
+}
+
+// Leader returns the name of who holds the key, empty when no one does
+func (e *Election) Leader(ctx context.Context) (string, error) {
+	kv, found, err := e.client.Get(ctx, e.key)
+	if err != nil || !found {
+		return "", err
+	}
+	return kv.Value, nil
+}
+
+// Resign revokes our lease, releasing the key if it is attached to it
+func (e *Election) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.lease == 0 {
+		return nil
+	}
+	if err := e.client.Revoke(ctx, e.lease); err != nil {
+		return err
+	}
+	e.lease = 0
+	return nil
+}
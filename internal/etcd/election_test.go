@@ -0,0 +1,115 @@
+package etcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElection_Campaign(t *testing.T) {
+	fake, server := newFakeEtcd(t)
+	ctx := context.Background()
+	election := NewElection(NewClient([]string{server.URL}, nil), "/svha/active", "val1", 15*time.Second)
+
+	leader, err := election.Leader(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, leader)
+
+	// the free key is taken and kept on the same lease
+	holder, leaseExpired, err := election.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "val1", holder)
+	assert.False(t, leaseExpired)
+	assert.True(t, election.Holding())
+	kv, _ := fake.get("/svha/active")
+	lease := kv.lease
+
+	holder, _, err = election.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "val1", holder)
+	kv, _ = fake.get("/svha/active")
+	assert.Equal(t, lease, kv.lease)
+
+	// once the lease expires the key goes with it and is taken again on a new one
+	fake.expire(lease)
+	holder, leaseExpired, err = election.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "val1", holder)
+	assert.True(t, leaseExpired)
+	kv, _ = fake.get("/svha/active")
+	assert.NotEqual(t, lease, kv.lease)
+
+	// resigning releases the key
+	require.NoError(t, election.Resign(ctx))
+	assert.False(t, election.Holding())
+	_, found := fake.get("/svha/active")
+	assert.False(t, found)
+}
+
+func TestElection_KeepAlive(t *testing.T) {
+	fake, server := newFakeEtcd(t)
+	ctx := context.Background()
+	election := NewElection(NewClient([]string{server.URL}, nil), "/svha/active", "val1", 15*time.Second)
+
+	// nothing to keep alive before campaigning
+	leaseExpired, err := election.KeepAlive(ctx)
+	require.NoError(t, err)
+	assert.False(t, leaseExpired)
+
+	_, _, err = election.Campaign(ctx)
+	require.NoError(t, err)
+	leaseExpired, err = election.KeepAlive(ctx)
+	require.NoError(t, err)
+	assert.False(t, leaseExpired)
+	assert.True(t, election.Holding())
+
+	// an expired lease is let go of, the next campaign taking the key on a new one
+	kv, _ := fake.get("/svha/active")
+	fake.expire(kv.lease)
+	leaseExpired, err = election.KeepAlive(ctx)
+	require.NoError(t, err)
+	assert.True(t, leaseExpired)
+	assert.False(t, election.Holding())
+}
+
+func TestElection_CampaignHeldByPeer(t *testing.T) {
+	fake, server := newFakeEtcd(t)
+	ctx := context.Background()
+	election := NewElection(NewClient([]string{server.URL}, nil), "/svha/active", "val1", 15*time.Second)
+
+	// another agent's key is left alone
+	fake.put("/svha/active", "val2", 1)
+	holder, _, err := election.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "val2", holder)
+	kv, _ := fake.get("/svha/active")
+	assert.Equal(t, fakeKeyValue{value: "val2", lease: 1}, kv)
+
+	leader, err := election.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "val2", leader)
+}
+
+func TestElection_CampaignAfterRestart(t *testing.T) {
+	fake, server := newFakeEtcd(t)
+	ctx := context.Background()
+
+	// the key we held before a restart is moved to our new lease
+	fake.put("/svha/active", "val1", 1)
+	election := NewElection(NewClient([]string{server.URL}, nil), "/svha/active", "val1", 15*time.Second)
+	holder, _, err := election.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "val1", holder)
+	kv, _ := fake.get("/svha/active")
+	assert.NotEqual(t, int64(1), kv.lease)
+}
+
+func TestElection_Unreachable(t *testing.T) {
+	election := NewElection(NewClient([]string{"http://127.0.0.1:1"}, nil), "/svha/active", "val1", 15*time.Second)
+	_, _, err := election.Campaign(context.Background())
+	assert.Error(t, err)
+	assert.False(t, election.Holding())
+}
@@ -0,0 +1,202 @@
+// Package etcd holds a lease-backed key in etcd over its v3 JSON gateway, electing the one agent that may be active
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// RequestTimeout bounds each request to an etcd endpoint
+	RequestTimeout = 2 * time.Second
+
+	// responseBodyLimit bounds how much of an error response is read into errors
+	responseBodyLimit = 512
+	// leaseNotFound is the error etcd keeps a lease alive with once it has expired
+	leaseNotFound = "requested lease not found"
+)
+
+// KeyValue is a key's value and the lease it is attached to
+type KeyValue struct {
+	Value string
+	Lease int64
+}
+
+// Client talks to the first of its endpoints that answers
+type Client struct {
+	endpoints  []string
+	httpClient *http.Client
+}
+
+// NewClient returns a client of endpoints, tried in order, over tlsConfig when not nil
+func NewClient(endpoints []string, tlsConfig *tls.Config) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	trimmed := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		trimmed = append(trimmed, strings.TrimRight(endpoint, "/"))
+	}
+	return &Client{
+		endpoints:  trimmed,
+		httpClient: &http.Client{Timeout: RequestTimeout, Transport: transport},
+	}
+}
+
+// Grant grants a lease of ttl, returning its id
+func (c *Client) Grant(ctx context.Context, ttl time.Duration) (int64, error) {
+	var response struct {
+		ID  int64 `json:"ID,string"`
+		TTL int64 `json:"TTL,string"`
+	}
+	request := map[string]string{"TTL": seconds(ttl)}
+	if err := c.do(ctx, "/v3/lease/grant", request, &response); err != nil {
+		return 0, fmt.Errorf("failed to grant etcd lease: %w", err)
+	}
+	if response.ID == 0 {
+		return 0, fmt.Errorf("failed to grant etcd lease: no lease id in response")
+	}
+	return response.ID, nil
+}
+
+// KeepAlive renews lease, returning false once it has expired
+func (c *Client) KeepAlive(ctx context.Context, lease int64) (bool, error) {
+	var response struct {
+		Result struct {
+			TTL int64 `json:"TTL,string"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := c.do(ctx, "/v3/lease/keepalive", map[string]string{"ID": fmt.Sprint(lease)}, &response); err != nil {
+		return false, fmt.Errorf("failed to keep etcd lease %x alive: %w", lease, err)
+	}
+	if response.Error != nil {
+		if strings.Contains(response.Error.Message, leaseNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to keep etcd lease %x alive: %s", lease, response.Error.Message)
+	}
+	return response.Result.TTL > 0, nil
+}
+
+// Revoke revokes lease, deleting the keys attached to it
+func (c *Client) Revoke(ctx context.Context, lease int64) error {
+	var response struct{}
+	if err := c.do(ctx, "/v3/lease/revoke", map[string]string{"ID": fmt.Sprint(lease)}, &response); err != nil {
+		return fmt.Errorf("failed to revoke etcd lease %x: %w", lease, err)
+	}
+	return nil
+}
+
+// Get returns key's value, false when it does not exist
+func (c *Client) Get(ctx context.Context, key string) (KeyValue, bool, error) {
+	var response struct {
+		KVs []struct {
+			Value []byte `json:"value"`
+			Lease int64  `json:"lease,string"`
+		} `json:"kvs"`
+	}
+	if err := c.do(ctx, "/v3/kv/range", map[string]string{"key": encode(key)}, &response); err != nil {
+		return KeyValue{}, false, fmt.Errorf("failed to get etcd key %s: %w", key, err)
+	}
+	if len(response.KVs) == 0 {
+		return KeyValue{}, false, nil
+	}
+	return KeyValue{Value: string(response.KVs[0].Value), Lease: response.KVs[0].Lease}, true, nil
+}
+
+// PutIfAbsent puts value at key attached to lease unless key exists, returning whether it was put
+func (c *Client) PutIfAbsent(ctx context.Context, key string, value string, lease int64) (bool, error) {
+	compare := map[string]string{"key": encode(key), "target": "CREATE", "result": "EQUAL", "create_revision": "0"}
+	return c.putIf(ctx, key, compare, value, lease)
+}
+
+// PutIfValue puts value at key attached to lease if key's value is expected, returning whether it was put
+func (c *Client) PutIfValue(ctx context.Context, key string, expected string, value string, lease int64) (bool, error) {
+	compare := map[string]string{"key": encode(key), "target": "VALUE", "result": "EQUAL", "value": encode(expected)}
+	return c.putIf(ctx, key, compare, value, lease)
+}
+
+// putIf puts value at key attached to lease in a transaction on compare, returning whether it held
+func (c *Client) putIf(ctx context.Context, key string, compare map[string]string, value string, lease int64) (bool, error) {
+	request := map[string]any{
+		"compare": []map[string]string{compare},
+		"success": []map[string]any{{
+			"request_put": map[string]string{"key": encode(key), "value": encode(value), "lease": fmt.Sprint(lease)},
+		}},
+	}
+	var response struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	if err := c.do(ctx, "/v3/kv/txn", request, &response); err != nil {
+		return false, fmt.Errorf("failed to put etcd key %s: %w", key, err)
+	}
+	return response.Succeeded, nil
+}
+
+// do posts request to path on each endpoint in turn until one answers, decoding its JSON response into v
+func (c *Client) do(ctx context.Context, path string, request any, v any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, endpoint := range c.endpoints {
+		err := c.post(ctx, endpoint+path, body, v)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// post posts body to url, decoding the JSON response into v
+func (c *Client) post(ctx context.Context, url string, body []byte, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, responseBodyLimit))
+		return fmt.Errorf("etcd returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	// lease keep alives are streamed - the first message is the answer to ours
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode etcd response: %w", err)
+	}
+	return nil
+}
+
+// encode encodes s as the gateway expects bytes
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// seconds formats d in whole seconds, as etcd lease ttls are
+func seconds(d time.Duration) string {
+	return fmt.Sprint(int64(d / time.Second))
+}
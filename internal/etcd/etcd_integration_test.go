@@ -0,0 +1,112 @@
+//go:build etcd
+
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// etcdEndpointsEnvVar is the comma separated client urls of the etcd the integration tests run against
+const etcdEndpointsEnvVar = "SVHA_TEST_ETCD_ENDPOINTS"
+
+// newIntegrationClient returns a client of the etcd at SVHA_TEST_ETCD_ENDPOINTS and a key of its own for the
+// test, skipping the test when it is not set
+func newIntegrationClient(t *testing.T) (*Client, string) {
+	t.Helper()
+
+	endpoints := os.Getenv(etcdEndpointsEnvVar)
+	if endpoints == "" {
+		t.Skipf("%s not set", etcdEndpointsEnvVar)
+	}
+	return NewClient(strings.Split(endpoints, ","), nil), fmt.Sprintf("/svha-test/%s/%d/active", t.Name(), time.Now().UnixNano())
+}
+
+func TestIntegration_LeaseExpiry(t *testing.T) {
+	client, key := newIntegrationClient(t)
+	ctx := context.Background()
+
+	lease, err := client.Grant(ctx, 2*time.Second)
+	require.NoError(t, err)
+	put, err := client.PutIfAbsent(ctx, key, "val1", lease)
+	require.NoError(t, err)
+	require.True(t, put)
+
+	kv, found, err := client.Get(ctx, key)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "val1", kv.Value)
+
+	// the key goes with the lease once it expires, and keeping it alive reports it gone rather than failing
+	require.Eventually(t, func() bool {
+		_, found, err := client.Get(ctx, key)
+		return err == nil && !found
+	}, 10*time.Second, 100*time.Millisecond)
+	alive, err := client.KeepAlive(ctx, lease)
+	require.NoError(t, err)
+	assert.False(t, alive)
+}
+
+func TestIntegration_ElectionKeepAlive(t *testing.T) {
+	client, key := newIntegrationClient(t)
+	ctx := context.Background()
+	election := NewElection(client, key, "val1", 2*time.Second)
+
+	holder, _, err := election.Campaign(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "val1", holder)
+
+	// kept alive every third of its ttl the key outlasts it without campaigning again
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		leaseExpired, err := election.KeepAlive(ctx)
+		require.NoError(t, err)
+		require.False(t, leaseExpired)
+		time.Sleep(600 * time.Millisecond)
+	}
+	leader, err := election.Leader(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "val1", leader)
+
+	require.NoError(t, election.Resign(ctx))
+}
+
+func TestIntegration_ElectionBetweenPeers(t *testing.T) {
+	client, key := newIntegrationClient(t)
+	ctx := context.Background()
+	val1 := NewElection(client, key, "val1", 2*time.Second)
+	val2 := NewElection(client, key, "val2", 2*time.Second)
+
+	holder, _, err := val1.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "val1", holder)
+
+	// a peer's key is left alone while its lease lives
+	holder, _, err = val2.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "val1", holder)
+
+	// and taken once it is released
+	require.NoError(t, val1.Resign(ctx))
+	holder, _, err = val2.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "val2", holder)
+
+	// or once the holder stops keeping its lease alive and it expires
+	require.Eventually(t, func() bool {
+		leader, err := val1.Leader(ctx)
+		return err == nil && leader == ""
+	}, 10*time.Second, 100*time.Millisecond)
+	holder, _, err = val1.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "val1", holder)
+
+	require.NoError(t, val1.Resign(ctx))
+}
@@ -0,0 +1,216 @@
+package etcd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEtcd serves the part of etcd's v3 JSON gateway the client uses - int64s as strings, bytes base64 encoded
+// and zero values left out, like grpc-gateway
+type fakeEtcd struct {
+	t  *testing.T
+	mu sync.Mutex
+	// kvs are the values by key and leases the ttls by lease id
+	kvs       map[string]fakeKeyValue
+	leases    map[int64]int64
+	nextLease int64
+}
+
+type fakeKeyValue struct {
+	value string
+	lease int64
+}
+
+// newFakeEtcd returns a fake etcd and its server
+func newFakeEtcd(t *testing.T) (*fakeEtcd, *httptest.Server) {
+	t.Helper()
+	fake := &fakeEtcd{t: t, kvs: map[string]fakeKeyValue{}, leases: map[int64]int64{}, nextLease: 0x694d}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v3/lease/grant", fake.handle(func(request map[string]any) any {
+		fake.nextLease++
+		fake.leases[fake.nextLease] = fake.int64(request["TTL"])
+		return map[string]string{"ID": strconv.FormatInt(fake.nextLease, 10), "TTL": fmt.Sprint(request["TTL"])}
+	}))
+	mux.HandleFunc("POST /v3/lease/keepalive", fake.handle(func(request map[string]any) any {
+		id := fake.int64(request["ID"])
+		ttl, ok := fake.leases[id]
+		if !ok {
+			return map[string]any{"error": map[string]any{"grpc_code": 5, "http_code": 404, "message": "etcdserver: requested lease not found"}}
+		}
+		return map[string]any{"result": map[string]string{"ID": strconv.FormatInt(id, 10), "TTL": strconv.FormatInt(ttl, 10)}}
+	}))
+	mux.HandleFunc("POST /v3/lease/revoke", fake.handle(func(request map[string]any) any {
+		fake.expireLocked(fake.int64(request["ID"]))
+		return map[string]any{}
+	}))
+	mux.HandleFunc("POST /v3/kv/range", fake.handle(func(request map[string]any) any {
+		kv, ok := fake.kvs[fake.decode(request["key"])]
+		if !ok {
+			return map[string]any{}
+		}
+		return map[string]any{"kvs": []map[string]string{{
+			"key":   request["key"].(string),
+			"value": base64.StdEncoding.EncodeToString([]byte(kv.value)),
+			"lease": strconv.FormatInt(kv.lease, 10),
+		}}, "count": "1"}
+	}))
+	mux.HandleFunc("POST /v3/kv/txn", fake.handle(func(request map[string]any) any {
+		compare := request["compare"].([]any)[0].(map[string]any)
+		kv, exists := fake.kvs[fake.decode(compare["key"])]
+		var succeeded bool
+		switch compare["target"] {
+		case "CREATE":
+			succeeded = !exists && compare["create_revision"] == "0"
+		case "VALUE":
+			succeeded = exists && kv.value == fake.decode(compare["value"])
+		}
+		if succeeded {
+			put := request["success"].([]any)[0].(map[string]any)["request_put"].(map[string]any)
+			fake.kvs[fake.decode(put["key"])] = fakeKeyValue{value: fake.decode(put["value"]), lease: fake.int64(put["lease"])}
+		}
+		if !succeeded {
+			return map[string]any{}
+		}
+		return map[string]any{"succeeded": true}
+	}))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+// handle decodes the request and encodes what answer returns for it
+func (f *fakeEtcd) handle(answer func(request map[string]any) any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]any
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&request))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		json.NewEncoder(w).Encode(answer(request))
+	}
+}
+
+// expire expires lease, deleting the keys attached to it
+func (f *fakeEtcd) expire(lease int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expireLocked(lease)
+}
+
+func (f *fakeEtcd) expireLocked(lease int64) {
+	delete(f.leases, lease)
+	for key, kv := range f.kvs {
+		if kv.lease == lease {
+			delete(f.kvs, key)
+		}
+	}
+}
+
+// put puts value at key attached to lease, as another agent would
+func (f *fakeEtcd) put(key string, value string, lease int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.leases[lease] = 10
+	f.kvs[key] = fakeKeyValue{value: value, lease: lease}
+}
+
+// get returns key's value
+func (f *fakeEtcd) get(key string) (fakeKeyValue, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kv, ok := f.kvs[key]
+	return kv, ok
+}
+
+func (f *fakeEtcd) int64(v any) int64 {
+	n, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+	require.NoError(f.t, err)
+	return n
+}
+
+func (f *fakeEtcd) decode(v any) string {
+	b, err := base64.StdEncoding.DecodeString(v.(string))
+	require.NoError(f.t, err)
+	return string(b)
+}
+
+func TestClient_Lease(t *testing.T) {
+	fake, server := newFakeEtcd(t)
+	client := NewClient([]string{server.URL + "/"}, nil)
+	ctx := context.Background()
+
+	lease, err := client.Grant(ctx, 15*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), fake.leases[lease])
+
+	alive, err := client.KeepAlive(ctx, lease)
+	require.NoError(t, err)
+	assert.True(t, alive)
+
+	// an expired lease is not an error
+	fake.expire(lease)
+	alive, err = client.KeepAlive(ctx, lease)
+	require.NoError(t, err)
+	assert.False(t, alive)
+}
+
+func TestClient_Put(t *testing.T) {
+	fake, server := newFakeEtcd(t)
+	client := NewClient([]string{server.URL}, nil)
+	ctx := context.Background()
+
+	_, found, err := client.Get(ctx, "/svha/active")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	put, err := client.PutIfAbsent(ctx, "/svha/active", "val1", 7)
+	require.NoError(t, err)
+	assert.True(t, put)
+	kv, found, err := client.Get(ctx, "/svha/active")
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, KeyValue{Value: "val1", Lease: 7}, kv)
+
+	// the key exists now
+	put, err = client.PutIfAbsent(ctx, "/svha/active", "val2", 8)
+	require.NoError(t, err)
+	assert.False(t, put)
+
+	put, err = client.PutIfValue(ctx, "/svha/active", "val2", "val2", 8)
+	require.NoError(t, err)
+	assert.False(t, put)
+	put, err = client.PutIfValue(ctx, "/svha/active", "val1", "val1", 9)
+	require.NoError(t, err)
+	assert.True(t, put)
+	stored, _ := fake.get("/svha/active")
+	assert.Equal(t, fakeKeyValue{value: "val1", lease: 9}, stored)
+}
+
+func TestClient_Endpoints(t *testing.T) {
+	_, server := newFakeEtcd(t)
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"etcdserver: no leader"}`, http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(down.Close)
+
+	// the next endpoint is tried when one fails
+	_, err := NewClient([]string{down.URL, server.URL}, nil).Grant(context.Background(), 15*time.Second)
+	assert.NoError(t, err)
+
+	// every endpoint's error is returned when none answer
+	_, err = NewClient([]string{down.URL, "http://127.0.0.1:1"}, nil).Grant(context.Background(), 15*time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "etcd returned 503 Service Unavailable: {\"error\":\"etcdserver: no leader\"}")
+	assert.Contains(t, err.Error(), "http://127.0.0.1:1")
+}
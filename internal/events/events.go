@@ -0,0 +1,296 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
+)
+
+const (
+	// TypeAgentRestarted is the synthetic event inserted between persisted and new events on startup
+	TypeAgentRestarted = "agent_restarted"
+	// TypeBecomingActive is recorded when a transition to the active role starts
+	TypeBecomingActive = "becoming_active"
+	// TypeBecomingPassive is recorded when a transition to the passive role starts
+	TypeBecomingPassive = "becoming_passive"
+	// TypeActive is recorded when the node is confirmed active
+	TypeActive = "active"
+	// TypePassive is recorded when the node is confirmed passive
+	TypePassive = "passive"
+	// TypeTransitionFailed is recorded when a transition fails
+	TypeTransitionFailed = "transition_failed"
+	// TypeRollingBack is recorded when a failed transition is being rolled back to the previous role
+	TypeRollingBack = "rolling_back"
+	// TypeRolledBack is recorded when a rollback is confirmed by local rpc
+	TypeRolledBack = "rolled_back"
+	// TypeRollbackFailed is recorded when a rollback fails, leaving the node needing manual intervention
+	TypeRollbackFailed = "rollback_failed"
+	// TypeManualTransition is recorded when an operator starts a transition with promote or demote
+	TypeManualTransition = "manual_transition"
+	// TypePossibleDuplicateSigning is recorded when the active identity keeps voting from this host after a demotion
+	TypePossibleDuplicateSigning = "possible_duplicate_signing"
+	// TypeSplitBrain is recorded when more than one node is first seen with the active identity
+	TypeSplitBrain = "split_brain"
+	// TypeClusterRPCOutage is recorded when gossip has failed to refresh from the cluster rpc for the leaderless samples threshold
+	TypeClusterRPCOutage = "cluster_rpc_outage"
+	// TypeCoordinationDegraded is recorded when etcd has been unreachable for longer than its unreachable_grace
+	TypeCoordinationDegraded = "coordination_degraded"
+	// TypePaused is recorded when an operator pauses failover for maintenance
+	TypePaused = "paused"
+	// TypeResumed is recorded when a maintenance pause ends, by an operator or once its duration has elapsed
+	TypeResumed = "resumed"
+
+	// DefaultSize is the default number of events kept in memory
+	DefaultSize = 100
+	// DefaultMaxFileBytes is the default size the persistence file may grow to before being truncated
+	DefaultMaxFileBytes = 1024 * 1024
+
+	// maxRecordBytes bounds a single persisted record when reading the file back
+	maxRecordBytes = 64 * 1024
+)
+
+// Event is a single entry in the event log
+type Event struct {
+	Time    time.Time         `json:"time"`
+	Type    string            `json:"type"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Options are the options for creating an event log
+type Options struct {
+	// Size is the number of most recent events to keep
+	Size int
+	// File is an optional path to persist events to, empty disables persistence
+	File string
+	// MaxFileBytes is the size the file may grow to before it is truncated to the most recent events fitting in half
+	// of it
+	MaxFileBytes int64
+	// LogPrefix is the prefix for the logger
+	LogPrefix string
+}
+
+// Log is a fixed-size ring buffer of events with optional append-only file persistence
+type Log struct {
+	mu           sync.RWMutex
+	events       []Event
+	size         int
+	file         string
+	maxFileBytes int64
+	fileHandle   *os.File
+	logger       *log.Logger
+}
+
+// New creates a new event log, loading any persisted events from opts.File
+func New(opts Options) (*Log, error) {
+	l := &Log{
+		size:         opts.Size,
+		file:         opts.File,
+		maxFileBytes: opts.MaxFileBytes,
+		logger:       loglevel.WithPrefix(loglevel.Events, fmt.Sprintf("[%s events]", opts.LogPrefix)),
+	}
+	if l.size <= 0 {
+		l.size = DefaultSize
+	}
+	if l.maxFileBytes <= 0 {
+		l.maxFileBytes = DefaultMaxFileBytes
+	}
+
+	if l.file == "" {
+		return l, nil
+	}
+
+	loadedCount, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.openFile(); err != nil {
+		return nil, err
+	}
+
+	// mark the boundary between the previous run and this one
+	if loadedCount > 0 {
+		l.Record(Event{
+			Type:    TypeAgentRestarted,
+			Message: "agent restarted",
+			Fields: map[string]string{
+				"loaded_events": fmt.Sprintf("%d", loadedCount),
+			},
+		})
+	}
+
+	return l, nil
+}
+
+// Record adds an event, setting its time if unset, and persists it if a file is configured
+func (l *Log) Record(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.append(event)
+
+	if l.fileHandle == nil {
+		return
+	}
+
+	if err := l.persist(event); err != nil {
+		l.logger.Warn("failed to persist event", "error", err, "file", l.file)
+	}
+}
+
+// Events returns a copy of the events, oldest first
+func (l *Log) Events() []Event {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	events := make([]Event, len(l.events))
+	copy(events, l.events)
+	return events
+}
+
+// Close closes the persistence file if open
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.fileHandle == nil {
+		return nil
+	}
+	err := l.fileHandle.Close()
+	l.fileHandle = nil
+	return err
+}
+
+// append adds an event to the ring buffer dropping the oldest when full - caller must hold the lock
+func (l *Log) append(event Event) {
+	l.events = append(l.events, event)
+	if len(l.events) > l.size {
+		l.events = l.events[len(l.events)-l.size:]
+	}
+}
+
+// load reads persisted events from the file skipping records that fail to parse
+func (l *Log) load() (loadedCount int, err error) {
+	f, err := os.Open(l.file)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open events file %s: %w", l.file, err)
+	}
+	defer f.Close()
+
+	skipped := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 4096), maxRecordBytes)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Type == "" {
+			skipped++
+			continue
+		}
+		l.append(event)
+		loadedCount++
+	}
+
+	// a truncated or oversized trailing record is treated like any other corrupt record
+	if err := scanner.Err(); err != nil {
+		skipped++
+	}
+
+	if skipped > 0 {
+		l.logger.Warn("skipped corrupt records in events file", "file", l.file, "skipped", skipped, "loaded", loadedCount)
+	}
+
+	return loadedCount, nil
+}
+
+// openFile opens the persistence file for appending
+func (l *Log) openFile() (err error) {
+	if err := os.MkdirAll(filepath.Dir(l.file), 0o750); err != nil {
+		return fmt.Errorf("failed to create events file directory: %w", err)
+	}
+	l.fileHandle, err = os.OpenFile(l.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open events file %s: %w", l.file, err)
+	}
+	return nil
+}
+
+// persist appends an event to the file and truncates it when it grows too large - caller must hold the lock
+func (l *Log) persist(event Event) error {
+	record, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if _, err := l.fileHandle.Write(append(record, '\n')); err != nil {
+		return err
+	}
+
+	info, err := l.fileHandle.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() <= l.maxFileBytes {
+		return nil
+	}
+
+	return l.truncate()
+}
+
+// truncate rewrites the file with the most recent events of the ring buffer fitting in half of maxFileBytes, so
+// that a ring buffer larger than the file is rewritten once every half file of events rather than on every one -
+// caller must hold the lock
+func (l *Log) truncate() error {
+	records := [][]byte{}
+	var kept int64
+	for i := len(l.events) - 1; i >= 0; i-- {
+		record, err := json.Marshal(l.events[i])
+		if err != nil {
+			continue
+		}
+		if kept+int64(len(record))+1 > l.maxFileBytes/2 {
+			break
+		}
+		kept += int64(len(record)) + 1
+		records = append(records, record)
+	}
+
+	tmpFile := l.file + ".tmp"
+	f, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(f)
+	for i := len(records) - 1; i >= 0; i-- {
+		writer.Write(append(records[i], '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpFile, l.file); err != nil {
+		return err
+	}
+
+	l.fileHandle.Close()
+	return l.openFile()
+}
@@ -0,0 +1,165 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_InMemory(t *testing.T) {
+	l, err := New(Options{Size: 3})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		l.Record(Event{Type: TypeBecomingActive, Message: fmt.Sprintf("event-%d", i)})
+	}
+
+	events := l.Events()
+	require.Len(t, events, 3)
+	assert.Equal(t, "event-2", events[0].Message)
+	assert.Equal(t, "event-4", events[2].Message)
+	assert.False(t, events[0].Time.IsZero())
+	assert.NoError(t, l.Close())
+}
+
+func TestNew_Defaults(t *testing.T) {
+	l, err := New(Options{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSize, l.size)
+	assert.Equal(t, int64(DefaultMaxFileBytes), l.maxFileBytes)
+}
+
+func TestLog_RoundTrip(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events", "events.jsonl")
+
+	l, err := New(Options{Size: 10, File: file})
+	require.NoError(t, err)
+	assert.Empty(t, l.Events(), "no restart marker without previous events")
+
+	l.Record(Event{Type: TypeBecomingActive, Message: "becoming active", Fields: map[string]string{"pubkey": "abc"}})
+	l.Record(Event{Type: TypeActive, Message: "confirmed active"})
+	require.NoError(t, l.Close())
+
+	// reload - previous events come back followed by a restart marker
+	l, err = New(Options{Size: 10, File: file})
+	require.NoError(t, err)
+	defer l.Close()
+
+	events := l.Events()
+	require.Len(t, events, 3)
+	assert.Equal(t, TypeBecomingActive, events[0].Type)
+	assert.Equal(t, "abc", events[0].Fields["pubkey"])
+	assert.Equal(t, TypeActive, events[1].Type)
+	assert.Equal(t, TypeAgentRestarted, events[2].Type)
+	assert.Equal(t, "2", events[2].Fields["loaded_events"])
+
+	// new events land after the restart marker
+	l.Record(Event{Type: TypeBecomingPassive, Message: "becoming passive"})
+	events = l.Events()
+	assert.Equal(t, TypeBecomingPassive, events[3].Type)
+}
+
+func TestLog_Truncation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+
+	l, err := New(Options{Size: 5, File: file, MaxFileBytes: 1024})
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		l.Record(Event{Type: TypeBecomingActive, Message: fmt.Sprintf("event-%03d", i)})
+	}
+	require.NoError(t, l.Close())
+
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(1024))
+
+	// the most recent events are the ones preserved
+	l, err = New(Options{Size: 5, File: file, MaxFileBytes: 1024})
+	require.NoError(t, err)
+	defer l.Close()
+
+	events := l.Events()
+	require.Len(t, events, 5)
+	assert.Equal(t, "event-096", events[0].Message)
+	assert.Equal(t, "event-099", events[3].Message)
+	assert.Equal(t, TypeAgentRestarted, events[4].Type)
+}
+
+func TestLog_TruncationLowWatermark(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+
+	// a ring buffer far larger than the file is not rewritten on every event once it no longer fits
+	l, err := New(Options{Size: 1000, File: file, MaxFileBytes: 4096})
+	require.NoError(t, err)
+	defer l.Close()
+
+	rewrites := 0
+	previous, err := os.Stat(file)
+	require.NoError(t, err)
+	for i := 0; i < 500; i++ {
+		l.Record(Event{Type: TypeBecomingActive, Message: fmt.Sprintf("event-%03d", i)})
+		info, err := os.Stat(file)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(4096))
+		if !os.SameFile(previous, info) {
+			rewrites++
+		}
+		previous = info
+	}
+	assert.Less(t, rewrites, 40)
+	assert.Len(t, l.Events(), 500)
+}
+
+func TestLog_CorruptRecordsAreSkipped(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+
+	content := strings.Join([]string{
+		`{"time":"2025-01-01T00:00:00Z","type":"becoming_active","message":"first"}`,
+		`this is not json`,
+		`{"time":"2025-01-01T00:00:01Z","message":"missing type"}`,
+		``,
+		`{"time":"2025-01-01T00:00:02Z","type":"active","message":"second"}`,
+		`{"time":"2025-01-01T00:00:03Z","type":"act`, // torn write from a crash
+	}, "\n")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o640))
+
+	l, err := New(Options{Size: 10, File: file})
+	require.NoError(t, err)
+	defer l.Close()
+
+	events := l.Events()
+	require.Len(t, events, 3)
+	assert.Equal(t, "first", events[0].Message)
+	assert.Equal(t, "second", events[1].Message)
+	assert.Equal(t, TypeAgentRestarted, events[2].Type)
+}
+
+func TestLog_OversizedRecordIsSkipped(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+
+	content := `{"time":"2025-01-01T00:00:00Z","type":"active","message":"ok"}` + "\n" +
+		`{"type":"active","message":"` + strings.Repeat("x", maxRecordBytes) + `"}` + "\n"
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o640))
+
+	l, err := New(Options{Size: 10, File: file})
+	require.NoError(t, err)
+	defer l.Close()
+
+	events := l.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "ok", events[0].Message)
+	assert.Equal(t, TypeAgentRestarted, events[1].Type)
+}
+
+func TestNew_UnopenableFile(t *testing.T) {
+	dir := t.TempDir()
+	// a directory can't be opened as the events file
+	_, err := New(Options{File: dir})
+	assert.Error(t, err)
+}
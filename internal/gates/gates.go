@@ -0,0 +1,119 @@
+package gates
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Mode is how a safety gate is applied
+type Mode string
+
+const (
+	// ModeOff means the gate is not checked
+	ModeOff Mode = "off"
+	// ModeWarn means a violation is logged and counted but does not prevent promotion
+	ModeWarn Mode = "warn"
+	// ModeEnforce means a violation prevents promotion
+	ModeEnforce Mode = "enforce"
+)
+
+// Modes are the valid gate modes
+var Modes = []Mode{ModeOff, ModeWarn, ModeEnforce}
+
+// String returns the mode as a string
+func (m Mode) String() string {
+	return string(m)
+}
+
+// Effective returns the mode a gate is applied in, an unset mode is off
+func (m Mode) Effective() Mode {
+	if m == "" {
+		return ModeOff
+	}
+	return m
+}
+
+// Validate validates the mode, field is used to build a precise error
+func (m Mode) Validate(field string) error {
+	if !slices.Contains(Modes, m) {
+		modes := make([]string, 0, len(Modes))
+		for _, mode := range Modes {
+			modes = append(modes, mode.String())
+		}
+		return fmt.Errorf("%s must be one of %s - got %q", field, strings.Join(modes, ", "), m)
+	}
+	return nil
+}
+
+// Gate is a safety check that must pass before we take over as active
+type Gate struct {
+	// Name identifies the gate in logs, /status and metrics
+	Name string
+	// Mode is how the gate is applied
+	Mode Mode
+	// Check measures the gate, returning the values it was based on for display and an error describing the
+	// violation if it does not pass - a failure to measure is a violation so an enforced gate fails closed
+	Check func() (detail string, err error)
+}
+
+// Result is the outcome of checking a gate
+type Result struct {
+	Name string `json:"name"`
+	Mode Mode   `json:"mode"`
+	// Checked is false for gates that are off
+	Checked bool `json:"checked"`
+	Passed  bool `json:"passed"`
+	// Blocked is true when an enforced gate did not pass
+	Blocked bool `json:"blocked"`
+	// Detail is the values the check was based on, or why it was violated
+	Detail string `json:"detail,omitempty"`
+}
+
+// Violated returns true if the gate was checked and did not pass, whether or not it blocked
+func (r Result) Violated() bool {
+	return r.Checked && !r.Passed
+}
+
+// Evaluate checks every gate that isn't off in order, off gates are included unchecked so every gate is reported
+func Evaluate(gates []Gate) []Result {
+	results := make([]Result, 0, len(gates))
+	for _, gate := range gates {
+		result := Result{Name: gate.Name, Mode: gate.Mode.Effective(), Passed: true}
+		if result.Mode == ModeOff {
+			results = append(results, result)
+			continue
+		}
+
+		result.Checked = true
+		detail, err := gate.Check()
+		result.Detail = detail
+		if err != nil {
+			result.Passed = false
+			result.Blocked = result.Mode == ModeEnforce
+			result.Detail = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Blocked returns the results of the enforced gates that did not pass
+func Blocked(results []Result) []Result {
+	blocked := []Result{}
+	for _, result := range results {
+		if result.Blocked {
+			blocked = append(blocked, result)
+		}
+	}
+	return blocked
+}
+
+// Names returns the names of the gates of results
+func Names(results []Result) string {
+	names := make([]string, 0, len(results))
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	return strings.Join(names, ", ")
+}
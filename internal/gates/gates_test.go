@@ -0,0 +1,119 @@
+package gates
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleGate is a gate that passes while lag is at most max
+func sampleGate(mode Mode, lag int, max int, checks *int) Gate {
+	return Gate{
+		Name: "sample",
+		Mode: mode,
+		Check: func() (string, error) {
+			*checks++
+			if lag > max {
+				return "", errors.New("lag above max")
+			}
+			return "lag within max", nil
+		},
+	}
+}
+
+func TestEvaluate_SampleGateModes(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       Mode
+		lag        int
+		wantResult Result
+		wantChecks int
+	}{
+		{
+			name:       "off passing",
+			mode:       ModeOff,
+			lag:        1,
+			wantResult: Result{Name: "sample", Mode: ModeOff, Passed: true},
+		},
+		{
+			name:       "off violating is never checked",
+			mode:       ModeOff,
+			lag:        100,
+			wantResult: Result{Name: "sample", Mode: ModeOff, Passed: true},
+		},
+		{
+			name:       "unset is off",
+			mode:       "",
+			lag:        100,
+			wantResult: Result{Name: "sample", Mode: ModeOff, Passed: true},
+		},
+		{
+			name:       "warn passing",
+			mode:       ModeWarn,
+			lag:        1,
+			wantResult: Result{Name: "sample", Mode: ModeWarn, Checked: true, Passed: true, Detail: "lag within max"},
+			wantChecks: 1,
+		},
+		{
+			name:       "warn violating does not block",
+			mode:       ModeWarn,
+			lag:        100,
+			wantResult: Result{Name: "sample", Mode: ModeWarn, Checked: true, Detail: "lag above max"},
+			wantChecks: 1,
+		},
+		{
+			name:       "enforce passing",
+			mode:       ModeEnforce,
+			lag:        1,
+			wantResult: Result{Name: "sample", Mode: ModeEnforce, Checked: true, Passed: true, Detail: "lag within max"},
+			wantChecks: 1,
+		},
+		{
+			name:       "enforce violating blocks",
+			mode:       ModeEnforce,
+			lag:        100,
+			wantResult: Result{Name: "sample", Mode: ModeEnforce, Checked: true, Blocked: true, Detail: "lag above max"},
+			wantChecks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := 0
+			results := Evaluate([]Gate{sampleGate(tt.mode, tt.lag, 10, &checks)})
+
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.wantResult, results[0])
+			assert.Equal(t, tt.wantChecks, checks)
+			assert.Equal(t, tt.wantResult.Checked && !tt.wantResult.Passed, results[0].Violated())
+			assert.Equal(t, tt.wantResult.Blocked, len(Blocked(results)) == 1)
+		})
+	}
+}
+
+func TestEvaluate_Order(t *testing.T) {
+	checks := 0
+	results := Evaluate([]Gate{
+		{Name: "a", Mode: ModeEnforce, Check: func() (string, error) { return "", errors.New("a violated") }},
+		{Name: "b", Mode: ModeWarn, Check: func() (string, error) { return "", errors.New("b violated") }},
+		sampleGate(ModeEnforce, 100, 10, &checks),
+		sampleGate(ModeOff, 100, 10, &checks),
+	})
+
+	require.Len(t, results, 4)
+	assert.Equal(t, []string{"a", "b", "sample", "sample"}, []string{results[0].Name, results[1].Name, results[2].Name, results[3].Name})
+	assert.Equal(t, "a, sample", Names(Blocked(results)))
+	assert.Equal(t, 1, checks)
+}
+
+func TestMode_Validate(t *testing.T) {
+	for _, mode := range Modes {
+		assert.NoError(t, mode.Validate("gates.sample.mode"))
+	}
+
+	err := Mode("enforcing").Validate("gates.sample.mode")
+	require.Error(t, err)
+	assert.Equal(t, `gates.sample.mode must be one of off, warn, enforce - got "enforcing"`, err.Error())
+}
@@ -0,0 +1,192 @@
+package gossip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+)
+
+const (
+	// HeartbeatPath is where agents serve their heartbeat, on the health port
+	HeartbeatPath = "/ha/heartbeat"
+
+	// heartbeatResponseBodyLimit bounds how much of a peer's heartbeat response is read
+	heartbeatResponseBodyLimit = 4096
+)
+
+// Heartbeat is what an agent reports of itself to its peers on HeartbeatPath
+type Heartbeat struct {
+	Name string `json:"name"`
+	// Role is active or passive by the identity the local validator reports, unknown if it can't be told
+	Role constants.Role `json:"role"`
+	// Identity is the identity pubkey the local validator reports, empty if its rpc didn't answer
+	Identity string `json:"identity"`
+	Healthy  bool   `json:"healthy"`
+	// Slot is the local validator's processed slot, 0 if its rpc didn't answer
+	Slot   uint64    `json:"slot"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// sanitize strips the free text fields a peer reported of anything unfit for logs and bounds their length - its role
+// is already one of the known roles once decoded
+func (h *Heartbeat) sanitize() {
+	h.Name = config.SanitizeString(h.Name, config.NameMaxLength)
+	h.Identity = config.SanitizeString(h.Identity, config.NameMaxLength)
+}
+
+// FetchHeartbeatFunc fetches a peer's heartbeat
+type FetchHeartbeatFunc func(ctx context.Context, peer config.Peer) (Heartbeat, error)
+
+// PeerHeartbeatState is what a peer's agent last reported on its heartbeat, independent of the cluster RPC's gossip
+type PeerHeartbeatState struct {
+	Name string
+	IP   string
+	// Reachable is true if the peer's agent answered with its heartbeat
+	Reachable bool
+	// Heartbeat is what the peer's agent reported when reachable
+	Heartbeat Heartbeat
+	// IsActive is true if the peer's agent reported the active role
+	IsActive bool
+	// Error is why the peer's agent was unreachable
+	Error string
+	// CheckedAt is when the peer's heartbeat was fetched - compare its monotonic reading
+	CheckedAt clock.Instant
+	// CheckedAtUTC is the wall clock time CheckedAt, for display only
+	CheckedAtUTC time.Time
+}
+
+// NewHeartbeatFetcher returns a fetcher of peers' heartbeats from their health port - prometheus.port + 1, which
+// must be the same across peers - over https if healthcheck.tls is set, nil when failover.heartbeat is not enabled
+func NewHeartbeatFetcher(cfg *config.Config) FetchHeartbeatFunc {
+	heartbeat := cfg.Failover.Heartbeat
+	if !heartbeat.Enabled {
+		return nil
+	}
+
+	scheme := cfg.HealthCheck.TLS.Scheme()
+	port := cfg.Prometheus.HealthCheckPort()
+	client := &http.Client{Timeout: heartbeat.Timeout}
+	return func(ctx context.Context, peer config.Peer) (result Heartbeat, err error) {
+		ctx, cancel := context.WithTimeout(ctx, heartbeat.Timeout)
+		defer cancel()
+
+		url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(peer.IP, strconv.Itoa(port)), HeartbeatPath)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return result, err
+		}
+		// peers share failover.heartbeat.auth
+		httpauth.SetBearerToken(req, heartbeat.Auth.Token())
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return result, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return result, fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, heartbeatResponseBodyLimit)).Decode(&result); err != nil {
+			return result, fmt.Errorf("failed to decode heartbeat from %s: %w", url, err)
+		}
+		result.sanitize()
+		return result, nil
+	}
+}
+
+// refreshPeerHeartbeats fetches every peer's heartbeat but ours concurrently, when heartbeats are enabled
+func (p *State) refreshPeerHeartbeats(ctx context.Context) {
+	if p.fetchHeartbeat == nil {
+		return
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	latestPeerHeartbeatsByName := make(map[string]PeerHeartbeatState, len(p.configPeers))
+	for name, peer := range p.configPeers {
+		if peer.IP == p.selfIP {
+			continue
+		}
+		wg.Add(1)
+		go func(name string, peer config.Peer) {
+			defer wg.Done()
+			heartbeatState := p.fetchPeerHeartbeat(ctx, name, peer)
+			mu.Lock()
+			latestPeerHeartbeatsByName[name] = heartbeatState
+			mu.Unlock()
+		}(name, peer)
+	}
+	wg.Wait()
+
+	for name, heartbeatState := range latestPeerHeartbeatsByName {
+		previous, known := p.peerHeartbeatsByName[name]
+		if (!known || previous.Reachable) && !heartbeatState.Reachable {
+			p.logger.Warn("peer heartbeat unreachable", "name", name, "ip", heartbeatState.IP, "error", heartbeatState.Error)
+		}
+		if known && !previous.Reachable && heartbeatState.Reachable {
+			p.logger.Info("peer heartbeat reachable", "name", name, "ip", heartbeatState.IP)
+		}
+		if heartbeatState.IsActive && (!known || !previous.IsActive) {
+			p.logger.Info("peer heartbeat reports the active role", "name", name, "ip", heartbeatState.IP, "identity", heartbeatState.Heartbeat.Identity)
+		}
+	}
+	p.peerHeartbeatsByName = latestPeerHeartbeatsByName
+}
+
+// fetchPeerHeartbeat fetches a peer's heartbeat from its agent
+func (p *State) fetchPeerHeartbeat(ctx context.Context, name string, peer config.Peer) PeerHeartbeatState {
+	checkedAt := p.clock.Now()
+	heartbeatState := PeerHeartbeatState{
+		Name:         name,
+		IP:           peer.IP,
+		CheckedAt:    checkedAt,
+		CheckedAtUTC: checkedAt.Time(),
+	}
+
+	heartbeat, err := p.fetchHeartbeat(ctx, peer)
+	if err != nil {
+		heartbeatState.Error = err.Error()
+		p.logger.Debug("peer heartbeat failed", "name", name, "ip", peer.IP, "error", err)
+		return heartbeatState
+	}
+	heartbeatState.Reachable = true
+	heartbeatState.Heartbeat = heartbeat
+	heartbeatState.IsActive = heartbeat.Role == constants.RoleActive
+	return heartbeatState
+}
+
+// GetPeerHeartbeats returns the latest heartbeat of every peer but us, keyed by their name - empty when heartbeats
+// are not enabled
+func (p *State) GetPeerHeartbeats() map[string]PeerHeartbeatState {
+	return p.peerHeartbeatsByName
+}
+
+// ActivePeerHeartbeat returns the first peer by name, other than us, whose heartbeat reports the active role
+func (p *State) ActivePeerHeartbeat() (PeerHeartbeatState, bool) {
+	names := make([]string, 0, len(p.peerHeartbeatsByName))
+	for name := range p.peerHeartbeatsByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		heartbeatState := p.peerHeartbeatsByName[name]
+		if heartbeatState.Reachable && heartbeatState.IsActive && heartbeatState.IP != p.selfIP {
+			return heartbeatState, true
+		}
+	}
+	return PeerHeartbeatState{}, false
+}
@@ -0,0 +1,162 @@
+package gossip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHeartbeats returns a fetcher answering with the heartbeat by peer name, failing for peers without one
+func fakeHeartbeats(heartbeats map[string]Heartbeat) FetchHeartbeatFunc {
+	return func(ctx context.Context, peer config.Peer) (Heartbeat, error) {
+		heartbeat, ok := heartbeats[peer.Name]
+		if !ok {
+			return Heartbeat{}, errors.New("connection refused")
+		}
+		return heartbeat, nil
+	}
+}
+
+func TestNewHeartbeatFetcher(t *testing.T) {
+	// nothing is fetched when heartbeats are not enabled
+	assert.Nil(t, NewHeartbeatFetcher(&config.Config{}))
+
+	sent := Heartbeat{Name: "peer1", Role: constants.RoleActive, Identity: solana.NewWallet().PublicKey().String(), Healthy: true, Slot: 100}
+	server := httptest.NewServer(httpauth.RequireBearerToken("secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, HeartbeatPath, r.URL.Path)
+		json.NewEncoder(w).Encode(sent)
+	})))
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	require.NoError(t, err)
+
+	// heartbeats are served on the health port, prometheus.port + 1
+	cfg := &config.Config{}
+	cfg.Prometheus.Port = port - 1
+	cfg.Failover.Heartbeat = config.Heartbeat{Enabled: true, Auth: &config.Auth{BearerToken: "secret"}, Timeout: time.Second}
+	peer := config.Peer{Name: "peer1", IP: "127.0.0.1"}
+
+	heartbeat, err := NewHeartbeatFetcher(cfg)(context.Background(), peer)
+	require.NoError(t, err)
+	assert.Equal(t, sent, heartbeat)
+
+	// a peer with another secret is unreachable
+	cfg.Failover.Heartbeat.Auth.BearerToken = "other"
+	_, err = NewHeartbeatFetcher(cfg)(context.Background(), peer)
+	assert.ErrorContains(t, err, "returned status 401")
+}
+
+func TestNewHeartbeatFetcher_UntrustedResponse(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	require.NoError(t, err)
+
+	cfg := &config.Config{}
+	cfg.Prometheus.Port = port - 1
+	cfg.Failover.Heartbeat = config.Heartbeat{Enabled: true, Timeout: time.Second}
+	fetch := NewHeartbeatFetcher(cfg)
+	peer := config.Peer{Name: "peer1", IP: "127.0.0.1"}
+
+	// control characters are stripped and overlong fields capped before the heartbeat is logged or stored
+	body = `{"name":"peer1\nlevel=ERROR","role":"active","identity":"` + strings.Repeat("x", 200) + `"}`
+	heartbeat, err := fetch(context.Background(), peer)
+	require.NoError(t, err)
+	assert.Equal(t, "peer1level=ERROR", heartbeat.Name)
+	assert.Equal(t, constants.RoleActive, heartbeat.Role)
+	assert.Len(t, heartbeat.Identity, config.NameMaxLength)
+
+	// and no more than the limit of a response is read
+	body = `{"name":"` + strings.Repeat("x", heartbeatResponseBodyLimit) + `"}`
+	_, err = fetch(context.Background(), peer)
+	assert.ErrorContains(t, err, "failed to decode heartbeat")
+}
+
+func TestRefresh_PeerHeartbeats(t *testing.T) {
+	activePubkey := solana.NewWallet().PublicKey().String()
+
+	state := NewState(Options{
+		ClusterRPC:   rpc.NewClient("test", clusterRPCServer(t, activePubkey, true).URL),
+		ActivePubkey: activePubkey,
+		SelfIP:       "192.168.1.1",
+		ConfigPeers: map[string]config.Peer{
+			"a-self":  {IP: "192.168.1.1", Name: "a-self"},
+			"active":  {IP: "127.0.0.1", Name: "active"},
+			"passive": {IP: "192.168.1.3", Name: "passive"},
+			"down":    {IP: "192.168.1.4", Name: "down"},
+		},
+		Clock: clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+		FetchHeartbeat: fakeHeartbeats(map[string]Heartbeat{
+			"a-self":  {Name: "a-self", Role: constants.RoleActive},
+			"active":  {Name: "active", Role: constants.RoleActive, Identity: activePubkey, Healthy: true, Slot: 100},
+			"passive": {Name: "passive", Role: constants.RolePassive, Healthy: true, Slot: 99},
+		}),
+	})
+	state.Refresh()
+
+	// our own heartbeat is never fetched
+	heartbeats := state.GetPeerHeartbeats()
+	require.Len(t, heartbeats, 3)
+	assert.NotContains(t, heartbeats, "a-self")
+
+	active := heartbeats["active"]
+	assert.True(t, active.Reachable)
+	assert.True(t, active.IsActive)
+	assert.Equal(t, uint64(100), active.Heartbeat.Slot)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), active.CheckedAtUTC)
+
+	passive := heartbeats["passive"]
+	assert.True(t, passive.Reachable)
+	assert.False(t, passive.IsActive)
+
+	down := heartbeats["down"]
+	assert.False(t, down.Reachable)
+	assert.False(t, down.IsActive)
+	assert.Equal(t, "connection refused", down.Error)
+
+	// a peer in gossip carries its heartbeat
+	peerState := state.GetPeerStates()["active"]
+	require.NotNil(t, peerState.Heartbeat)
+	assert.Equal(t, active, *peerState.Heartbeat)
+
+	activePeerHeartbeat, ok := state.ActivePeerHeartbeat()
+	require.True(t, ok)
+	assert.Equal(t, "active", activePeerHeartbeat.Name)
+}
+
+func TestActivePeerHeartbeat_NoneActive(t *testing.T) {
+	state := NewState(Options{ClusterRPC: rpc.NewClient("test", "http://127.0.0.1:1"), SelfIP: "192.168.1.1"})
+	_, ok := state.ActivePeerHeartbeat()
+	assert.False(t, ok)
+
+	state.peerHeartbeatsByName = map[string]PeerHeartbeatState{
+		"down":    {Name: "down", IP: "192.168.1.2", IsActive: true},
+		"self":    {Name: "self", IP: "192.168.1.1", Reachable: true, IsActive: true},
+		"passive": {Name: "passive", IP: "192.168.1.3", Reachable: true},
+	}
+	_, ok = state.ActivePeerHeartbeat()
+	assert.False(t, ok)
+}
@@ -2,151 +2,204 @@ package gossip
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
 	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
+	"github.com/sol-strategies/solana-validator-ha/internal/logthrottle"
 	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
 )
 
 // State represents the state of the peers as seen by the solana network
 type State struct {
-	// PeerStatesRefreshedAt is the last time the peer states were refreshed
-	PeerStatesRefreshedAt time.Time
+	// PeerStatesRefreshedAt is the last time the peer states were refreshed - consumers measure how stale the
+	// snapshot is when they act on it with its monotonic reading
+	PeerStatesRefreshedAt clock.Instant
 	// peerStatesByName are the peers that are currently in the solana network, keyed by their name
 	peerStatesByName       map[string]PeerState // these are the peers that are currently in the solana network, keyed by their name
 	configPeers            config.Peers
 	activePubkey           string
 	selfIP                 string
-	clusterRPC             *rpc.Client
+	clusterRPC             rpc.SolanaClient
+	clock                  clock.Clock
 	logger                 *log.Logger
 	missingGossipIPs       []string
 	lastActivePeer         PeerState
-	activePeerLastSeenAt   time.Time
+	activePeerLastSeenAt   clock.Instant
 	LeaderlessSamplesCount int
+	// ConsecutiveRPCErrors is how many refreshes in a row failed to get cluster nodes, the peer states are stale
+	// while it is above 0
+	ConsecutiveRPCErrors int
+	// rpcErrorsCountAsLeaderless counts a failed refresh as a leaderless sample, see Options
+	rpcErrorsCountAsLeaderless bool
+	// peerRPCs are the clients for the peers with an rpc_url, keyed by their name
+	peerRPCs map[string]rpc.SolanaClient
+	// peerRPCStatesByName is the latest direct probe of each peer with an rpc_url, keyed by their name
+	peerRPCStatesByName map[string]PeerRPCState
+	// fetchHeartbeat fetches a peer's heartbeat, nil when heartbeats are not enabled
+	fetchHeartbeat FetchHeartbeatFunc
+	// peerHeartbeatsByName is the latest heartbeat of each peer but us, keyed by their name
+	peerHeartbeatsByName map[string]PeerHeartbeatState
+	// activePeers are the peers seen with the active identity in gossip or by their own rpc, ordered by name
+	activePeers []PeerState
+	// quorumRPCs are sampled in parallel when more than one must confirm the active peer is gone
+	quorumRPCs          []rpc.SolanaClient
+	minRPCConfirmations int
+	// throttle summarizes warnings repeated every refresh while their condition lasts
+	throttle *logthrottle.Throttle
+}
+
+// PeerRPCTimeout bounds each direct probe of a peer's rpc_url so an unreachable peer doesn't stall the poll
+const PeerRPCTimeout = 2 * time.Second
+
+// PeerRPCState is what a peer's own RPC reported when probed directly, independent of the cluster RPC's gossip
+type PeerRPCState struct {
+	Name   string
+	IP     string
+	RPCURL string
+	// Reachable is true if the peer's RPC answered getIdentity
+	Reachable bool
+	// Identity is the identity pubkey the peer's RPC reported
+	Identity string
+	// IsActive is true if Identity is the active identity
+	IsActive bool
+	// Healthy is true if the peer's RPC reported getHealth ok
+	Healthy bool
+	// Error is why the peer's RPC was unreachable or unhealthy
+	Error string
+	// CheckedAt is when the peer's RPC was probed - compare its monotonic reading
+	CheckedAt clock.Instant
+	// CheckedAtUTC is the wall clock time CheckedAt, for display only
+	CheckedAtUTC time.Time
 }
 
 // PeerState represents the state of a peer as seen by the solana network
 type PeerState struct {
 	// Name is the vanity name of the peer
 	Name string
-	// IP is the IP address of the peer
+	// IP is the configured IP address of the peer
 	IP string
+	// GossipIP is the IP of the peer's gossip address, not its IP when it was matched by pubkey
+	GossipIP string
 	// Pubkey is the public key of the peer
 	Pubkey string
-	// LastSeenAt is the last time the peer was seen by the solana network
+	// LastSeenAt is the last time the peer was seen by the solana network - compare its monotonic reading
+	LastSeenAt clock.Instant
+	// LastSeenAtUTC is the wall clock time LastSeenAt, for display only
 	LastSeenAtUTC time.Time
 	// LastSeenActive is true if the peer was the active validator when it was last seen
 	LastSeenActive bool
 	// IsRecentlyInGossip is true if the peer was recently in gossip
 	IsRecentlyInGossip bool
+	// RPC is the latest direct probe of the peer's rpc_url, nil if it has none
+	RPC *PeerRPCState
+	// Heartbeat is the latest heartbeat of the peer's agent, nil if heartbeats are not enabled
+	Heartbeat *PeerHeartbeatState
+	// SeenByEndpoints is how many of the cluster rpc endpoints queried showed the peer in gossip
+	SeenByEndpoints int
+	// Stale is true when the peer state is carried over from an earlier refresh as the cluster rpc could not be reached
+	Stale bool
 }
 
 // Options are the options for peers state
 type Options struct {
-	ClusterRPC   *rpc.Client
+	ClusterRPC   rpc.SolanaClient
 	ActivePubkey string
 	SelfIP       string
 	ConfigPeers  config.Peers
 	LogPrefix    string
+	// Clock defaults to clock.System
+	Clock clock.Clock
+	// NewPeerRPC returns the client for a peer's rpc_url, defaults to rpc.NewClient
+	NewPeerRPC func(logPrefix string, url string) rpc.SolanaClient
+	// FetchHeartbeat fetches a peer's heartbeat every refresh, nil disables heartbeats - see NewHeartbeatFetcher
+	FetchHeartbeat FetchHeartbeatFunc
+	// QuorumRPCs are a client per cluster rpc url, queried in parallel in place of ClusterRPC when
+	// MinRPCConfirmations is above 1
+	QuorumRPCs []rpc.SolanaClient
+	// MinRPCConfirmations is how many QuorumRPCs must answer without the active peer before it is marked absent
+	MinRPCConfirmations int
+	// RPCErrorsCountAsLeaderless counts a refresh that fails to get cluster nodes as a leaderless sample, by default
+	// it is only counted in ConsecutiveRPCErrors as an unreachable cluster rpc says nothing of the active peer
+	RPCErrorsCountAsLeaderless bool
 }
 
 // NewState creates a new gossip state
 func NewState(opts Options) *State {
+	if opts.Clock == nil {
+		opts.Clock = clock.System
+	}
+	if opts.NewPeerRPC == nil {
+		opts.NewPeerRPC = func(logPrefix string, url string) rpc.SolanaClient { return rpc.NewClient(logPrefix, url) }
+	}
+
+	peerRPCs := make(map[string]rpc.SolanaClient)
+	for name, peer := range opts.ConfigPeers {
+		if peer.RPCURL != "" {
+			peerRPCs[name] = opts.NewPeerRPC(fmt.Sprintf("%s peer %s", opts.LogPrefix, name), peer.RPCURL)
+		}
+	}
+
 	return &State{
-		logger:           log.WithPrefix(fmt.Sprintf("[%s gossip_state]", opts.LogPrefix)),
-		clusterRPC:       opts.ClusterRPC,
-		activePubkey:     opts.ActivePubkey,
-		selfIP:           opts.SelfIP,
-		configPeers:      opts.ConfigPeers,
-		peerStatesByName: make(map[string]PeerState),
+		clock:                opts.Clock,
+		logger:               loglevel.WithPrefix(loglevel.GossipState, fmt.Sprintf("[%s gossip_state]", opts.LogPrefix)),
+		clusterRPC:           opts.ClusterRPC,
+		activePubkey:         opts.ActivePubkey,
+		selfIP:               opts.SelfIP,
+		configPeers:          opts.ConfigPeers,
+		peerStatesByName:     make(map[string]PeerState),
+		peerRPCs:             peerRPCs,
+		peerRPCStatesByName:  make(map[string]PeerRPCState),
+		fetchHeartbeat:       opts.FetchHeartbeat,
+		peerHeartbeatsByName: make(map[string]PeerHeartbeatState),
+		quorumRPCs:           opts.QuorumRPCs,
+		minRPCConfirmations:  max(opts.MinRPCConfirmations, 1),
+		throttle:             logthrottle.New(logthrottle.Options{Clock: opts.Clock}),
+
+		rpcErrorsCountAsLeaderless: opts.RPCErrorsCountAsLeaderless,
 	}
 }
 
 // Refresh the state of peers as seen by the solana network
 func (p *State) Refresh() {
+	p.RefreshContext(context.Background())
+}
+
+// RefreshContext refreshes the state of peers like Refresh, its rpc calls cancelled with ctx - a refresh cut short
+// fails like an unreachable cluster rpc and is never counted as a leaderless sample
+func (p *State) RefreshContext(ctx context.Context) {
 	p.logger.Debug("refreshing peers state")
 	latestPeerStatesByName := make(map[string]PeerState)
 
-	// get cluster nodes - if this fails we return an empty state, which should cause its consumer
-	// to check for failovers
-	clusterNodes, err := p.clusterRPC.GetClusterNodes(context.Background())
+	// probe peers' own rpc first - it is a second signal that doesn't depend on the cluster rpc answering
+	p.refreshPeerRPCStates(ctx)
+	p.refreshPeerHeartbeats(ctx)
+
+	// get cluster nodes - if this fails the previous peer states are kept marked stale, an unreachable cluster rpc
+	// is not the active peer being gone
+	sample, err := p.sampleClusterNodes(ctx)
 	if err != nil {
-		p.peerStatesByName = latestPeerStatesByName
-		p.PeerStatesRefreshedAt = time.Now().UTC()
-		p.logger.Error("failed to get cluster nodes", "error", err)
+		p.markRPCError(err)
 		return
 	}
+	p.ConsecutiveRPCErrors = 0
+	p.throttle.Clear(p.logger, "cluster_rpc")
+	latestPeerStatesByName = sample.peerStatesByName
 
-	p.logger.Debug("looking for peers in gossip",
-		"cluster_nodes_count", len(clusterNodes),
-		"peers_count", len(p.configPeers),
-		"peers", p.configPeers.String(),
-		"active_pubkey", p.activePubkey,
-	)
-
-	// look through all the returned gossip nodes, looking for the ones that are in the config
-	isLeaderlessSample := true
-	for _, node := range clusterNodes {
-		nodeIP := strings.Split(*node.Gossip, ":")[0]
-
-		// if the peer is not the config, keep looking
-		if !p.hasConfigPeerWithIP(nodeIP) {
-			continue
-		}
-
-		// get the peer name from configPeers
-		peerName, ok := p.peerNameFromIP(nodeIP)
-		if !ok {
-			p.logger.Warn("peer not found in config", "ip", nodeIP)
-			continue
-		}
-
-		// if the node is not alive (can dial its gossip address) it's dead to us - gossip response is stale
-		if !p.isNodeGossipAlive(*node) {
-			p.logger.Debug("node gossip address not alive - excluding from state",
-				"peer_name", peerName,
-				"ip", nodeIP,
-				"gossip_address", *node.Gossip,
-				"pubkey", node.Pubkey.String(),
-			)
-			continue
-		}
-
-		// lastSeenActive
-		isActivePeer := node.Pubkey.String() == p.activePubkey
-
-		// a borked active peer might appear in gossip but not actually be voting
-		// so we need to check for that and only proceed to add it to the state if it is not voting still
-		if isActivePeer && !p.isNodeActiveAndVoting(*node) {
-			p.logger.Warn("active peer appears in gossip but is not voting - excluding from state", "ip", nodeIP, "pubkey", node.Pubkey.String())
-			continue
-		}
-
-		// now we know the peer is alive and voting (if it is an active node) - so we can add it to the state
-
-		// add the peer to the peerEntries
-		peerState := PeerState{
-			Name:               peerName,
-			IP:                 nodeIP,
-			LastSeenAtUTC:      time.Now().UTC(),
-			Pubkey:             node.Pubkey.String(),
-			LastSeenActive:     isActivePeer,
-			IsRecentlyInGossip: slices.Contains(p.missingGossipIPs, nodeIP),
-		}
-
-		// register the peer state
-		latestPeerStatesByName[peerName] = peerState
-
+	for _, peerState := range latestPeerStatesByName {
 		// update state's activePeerLastSeenAt
 		if peerState.LastSeenActive {
-			p.activePeerLastSeenAt = peerState.LastSeenAtUTC
-			isLeaderlessSample = false
+			p.activePeerLastSeenAt = peerState.LastSeenAt
 		}
 
 		// log if is change of active peer
@@ -168,6 +221,7 @@ func (p *State) Refresh() {
 				"pubkey", peerState.Pubkey,
 				"is_active", peerState.LastSeenActive,
 				"last_seen_at", peerState.LastSeenAtString(),
+				"seen_by_endpoints", peerState.SeenByEndpoints,
 			)
 		}
 
@@ -182,11 +236,6 @@ func (p *State) Refresh() {
 				"last_seen_at", peerState.LastSeenAtString(),
 			)
 		}
-
-		// if all peers from configPeers are in the peerEntries, we can stop looking
-		if len(p.configPeers) == len(latestPeerStatesByName) {
-			break
-		}
 	}
 
 	// warn if any of the config peers are not in the peerEntries
@@ -199,7 +248,7 @@ func (p *State) Refresh() {
 
 	// warn when peer transitions from present to missing (was in old state, now missing)
 	for _, ip := range latestMissingGossipIPs {
-		name, ok := p.peerNameFromIP(ip)
+		name, ok := p.configPeers.NameFromIP(ip)
 		if !ok {
 			continue
 		}
@@ -220,31 +269,463 @@ func (p *State) Refresh() {
 		p.logger.Debug("peer still missing from gossip", "name", name, "ip", ip)
 	}
 
-	// update state
-	if isLeaderlessSample {
+	// update state - a sample too few rpc endpoints answered to confirm the active peer is gone neither counts
+	// towards a failover nor resets the count
+	if !sample.inconclusive {
+		p.throttle.Clear(p.logger, "inconclusive")
+	}
+	switch {
+	case sample.inconclusive:
+		p.throttle.Warn(p.logger, "inconclusive", "no active peer found but too few rpc endpoints answered to confirm it is gone",
+			"answered", sample.answered,
+			"min_rpc_confirmations", p.minRPCConfirmations,
+			"leaderless_samples_count", p.LeaderlessSamplesCount)
+	case sample.leaderless:
 		p.LeaderlessSamplesCount++
-		p.logger.Warn("no active peer found",
+		p.throttle.Warn(p.logger, "leaderless", "no active peer found",
 			"leaderless_samples_count", p.LeaderlessSamplesCount)
-	} else {
+	default:
 		p.LeaderlessSamplesCount = 0
+		p.throttle.Clear(p.logger, "leaderless")
 	}
 	p.missingGossipIPs = latestMissingGossipIPs
 	p.peerStatesByName = latestPeerStatesByName
-	p.PeerStatesRefreshedAt = time.Now().UTC()
+	p.PeerStatesRefreshedAt = p.clock.Now()
+	p.refreshActivePeers()
 	p.logger.Debug("peers state refreshed", "peer_count", len(p.peerStatesByName))
 }
 
+// markRPCError keeps the previous peer states marked stale after a refresh failed to get cluster nodes, counting it
+// as a leaderless sample only when Options.RPCErrorsCountAsLeaderless is set
+func (p *State) markRPCError(err error) {
+	p.ConsecutiveRPCErrors++
+
+	stalePeerStatesByName := make(map[string]PeerState, len(p.peerStatesByName))
+	for name, peerState := range p.peerStatesByName {
+		peerState.Stale = true
+		stalePeerStatesByName[name] = peerState
+	}
+	p.peerStatesByName = stalePeerStatesByName
+	p.PeerStatesRefreshedAt = p.clock.Now()
+	p.throttle.Error(p.logger, "cluster_rpc", "failed to get cluster nodes - keeping stale peer states", "error", err, "consecutive_rpc_errors", p.ConsecutiveRPCErrors)
+
+	if p.rpcErrorsCountAsLeaderless {
+		p.LeaderlessSamplesCount++
+		p.logger.Warn("counting failed cluster rpc refresh as a leaderless sample", "leaderless_samples_count", p.LeaderlessSamplesCount)
+	}
+	p.refreshActivePeers()
+}
+
+// clusterSample is what a refresh found of the configured peers in gossip, merged across the cluster rpc endpoints
+// queried
+type clusterSample struct {
+	peerStatesByName map[string]PeerState
+	// answered is how many cluster rpc endpoints returned their cluster nodes
+	answered int
+	// leaderless is true when enough endpoints agree there is no active peer in gossip
+	leaderless bool
+	// inconclusive is true when no endpoint saw an active peer but too few answered to confirm it is gone
+	inconclusive bool
+}
+
+// sampleClusterNodes finds the configured peers in the cluster nodes of the cluster rpc, or of every quorum rpc in
+// parallel when failover.min_rpc_confirmations is above 1
+func (p *State) sampleClusterNodes(ctx context.Context) (clusterSample, error) {
+	if !p.quorumEnabled() {
+		peerStatesByName, err := p.peersInClusterNodes(ctx, p.clusterRPC)
+		if err != nil {
+			return clusterSample{}, err
+		}
+		return clusterSample{
+			peerStatesByName: peerStatesByName,
+			answered:         1,
+			leaderless:       !hasActivePeerState(peerStatesByName),
+		}, nil
+	}
+
+	views := make([]map[string]PeerState, len(p.quorumRPCs))
+	errs := make([]error, len(p.quorumRPCs))
+	var wg sync.WaitGroup
+	for i, client := range p.quorumRPCs {
+		wg.Add(1)
+		go func(i int, client rpc.SolanaClient) {
+			defer wg.Done()
+			views[i], errs[i] = p.peersInClusterNodes(ctx, client)
+		}(i, client)
+	}
+	wg.Wait()
+
+	// a peer is in gossip if any endpoint saw it, as active if any endpoint saw it active
+	sample := clusterSample{peerStatesByName: make(map[string]PeerState)}
+	activeSeenBy := 0
+	for i, view := range views {
+		endpoint := p.quorumRPCs[i].ActiveEndpoint()
+		if errs[i] != nil {
+			p.throttle.Warn(p.logger, "cluster_rpc_endpoint "+endpoint, "failed to get cluster nodes from rpc endpoint", "endpoint", endpoint, "error", errs[i])
+			continue
+		}
+		p.throttle.Clear(p.logger, "cluster_rpc_endpoint "+endpoint)
+		sample.answered++
+		if hasActivePeerState(view) {
+			activeSeenBy++
+		}
+		for name, peerState := range view {
+			merged, ok := sample.peerStatesByName[name]
+			if !ok || (peerState.LastSeenActive && !merged.LastSeenActive) {
+				peerState.SeenByEndpoints = merged.SeenByEndpoints
+				merged = peerState
+			}
+			merged.SeenByEndpoints++
+			sample.peerStatesByName[name] = merged
+		}
+	}
+	if sample.answered == 0 {
+		return clusterSample{}, errors.Join(errs...)
+	}
+
+	// the active peer is only gone once enough endpoints agree, then even those still listing it are outvoted
+	absentFrom := sample.answered - activeSeenBy
+	switch {
+	case absentFrom >= p.minRPCConfirmations:
+		sample.leaderless = true
+		for name, peerState := range sample.peerStatesByName {
+			if peerState.LastSeenActive {
+				delete(sample.peerStatesByName, name)
+			}
+		}
+	case activeSeenBy == 0:
+		sample.inconclusive = true
+	case absentFrom > 0:
+		p.logger.Warn("active peer missing from some rpc endpoints - too few to confirm it is gone",
+			"seen_by", activeSeenBy,
+			"missing_from", absentFrom,
+			"min_rpc_confirmations", p.minRPCConfirmations,
+		)
+	}
+
+	return sample, nil
+}
+
+// quorumEnabled returns true if the active peer's absence must be confirmed by more than one cluster rpc endpoint
+func (p *State) quorumEnabled() bool {
+	return p.minRPCConfirmations > 1 && len(p.quorumRPCs) >= p.minRPCConfirmations
+}
+
+// hasActivePeerState returns true if any of peerStatesByName was seen active
+func hasActivePeerState(peerStatesByName map[string]PeerState) bool {
+	for _, peerState := range peerStatesByName {
+		if peerState.LastSeenActive {
+			return true
+		}
+	}
+	return false
+}
+
+// peersInClusterNodes returns the configured peers client's cluster nodes show alive in gossip, keyed by their name -
+// an active peer only while it is voting. It only reads the state so quorum rpcs can be sampled in parallel.
+func (p *State) peersInClusterNodes(ctx context.Context, client rpc.SolanaClient) (map[string]PeerState, error) {
+	clusterNodes, err := client.GetClusterNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.logger.Debug("looking for peers in gossip",
+		"endpoint", client.ActiveEndpoint(),
+		"cluster_nodes_count", len(clusterNodes),
+		"peers_count", len(p.configPeers),
+		"peers", p.configPeers.String(),
+		"active_pubkey", p.activePubkey,
+	)
+
+	// look through all the returned gossip nodes that may be config peers, by ip first then by pubkey
+	peerStatesByName := make(map[string]PeerState)
+	for _, match := range p.matchConfigPeers(clusterNodes) {
+		node := match.node
+		nodeIP := AddressIP(*node.Gossip)
+
+		// the first of the peers it may be that no other node was already found as
+		peerName, ok := unmatchedPeerName(match.peerNames, peerStatesByName)
+		if !ok {
+			continue
+		}
+		peer := p.configPeers[peerName]
+
+		// if the node is not alive (can dial its gossip address) it's dead to us - gossip response is stale
+		if !p.isNodeGossipAlive(*node) {
+			p.logger.Debug("node gossip address not alive - excluding from state",
+				"peer_name", peerName,
+				"ip", nodeIP,
+				"gossip_address", *node.Gossip,
+				"pubkey", node.Pubkey.String(),
+			)
+			continue
+		}
+
+		// lastSeenActive
+		isActivePeer := node.Pubkey.String() == p.activePubkey
+
+		// a borked active peer might appear in gossip but not actually be voting
+		// so we need to check for that and only proceed to add it to the state if it is not voting still
+		if isActivePeer && !p.isNodeActiveAndVoting(ctx, client, *node) {
+			p.logger.Warn("active peer appears in gossip but is not voting - excluding from state", "ip", nodeIP, "pubkey", node.Pubkey.String())
+			continue
+		}
+		if match.byPubkey {
+			p.logger.Debug("peer matched in gossip by pubkey", "peer_name", peerName, "ip", peer.IP, "gossip_ip", nodeIP, "pubkey", node.Pubkey.String())
+		}
+
+		// now we know the peer is alive and voting (if it is an active node) - so we can add it to the state
+		seenAt := p.clock.Now()
+		peerState := PeerState{
+			Name:               peerName,
+			IP:                 peer.IP,
+			GossipIP:           nodeIP,
+			LastSeenAt:         seenAt,
+			LastSeenAtUTC:      seenAt.Time(),
+			Pubkey:             node.Pubkey.String(),
+			LastSeenActive:     isActivePeer,
+			IsRecentlyInGossip: slices.Contains(p.missingGossipIPs, peer.IP),
+			SeenByEndpoints:    1,
+		}
+		if rpcState, ok := p.peerRPCStatesByName[peerName]; ok {
+			peerState.RPC = &rpcState
+		}
+		if heartbeatState, ok := p.peerHeartbeatsByName[peerName]; ok {
+			peerState.Heartbeat = &heartbeatState
+		}
+		peerStatesByName[peerName] = peerState
+
+		// if all peers from configPeers are in the peerEntries, we can stop looking
+		if len(p.configPeers) == len(peerStatesByName) {
+			break
+		}
+	}
+
+	return peerStatesByName, nil
+}
+
+// gossipMatch is a cluster node and the names of the config peers it may be
+type gossipMatch struct {
+	node      *solanagorpc.GetClusterNodesResult
+	peerNames []string
+	byPubkey  bool
+}
+
+// matchConfigPeers returns the cluster nodes that may be config peers - a node whose gossip ip is a peer's ip is
+// that peer, unless it matches by pubkey only, otherwise it may be any peer listing its pubkey. Ip matches come first,
+// then pubkey matches by fewest candidates so a peer's own passive pubkey claims it before the active pubkey every
+// peer lists is handed out.
+func (p *State) matchConfigPeers(clusterNodes []*solanagorpc.GetClusterNodesResult) []gossipMatch {
+	names := make([]string, 0, len(p.configPeers))
+	for name := range p.configPeers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ipMatches := []gossipMatch{}
+	pubkeyMatches := []gossipMatch{}
+	for _, node := range clusterNodes {
+		if node.Gossip == nil {
+			continue
+		}
+		nodeIP := AddressIP(*node.Gossip)
+		if name, ok := p.peerNameFromIP(nodeIP); ok {
+			ipMatches = append(ipMatches, gossipMatch{node: node, peerNames: []string{name}})
+			continue
+		}
+
+		peerNames := []string{}
+		for _, name := range names {
+			peer := p.configPeers[name]
+			if peer.HasPubkey(node.Pubkey.String()) {
+				peerNames = append(peerNames, name)
+			}
+		}
+		if len(peerNames) > 0 {
+			pubkeyMatches = append(pubkeyMatches, gossipMatch{node: node, peerNames: peerNames, byPubkey: true})
+		}
+	}
+	sort.SliceStable(pubkeyMatches, func(i, j int) bool {
+		return len(pubkeyMatches[i].peerNames) < len(pubkeyMatches[j].peerNames)
+	})
+
+	return append(ipMatches, pubkeyMatches...)
+}
+
+// unmatchedPeerName returns the first of peerNames not in peerStatesByName
+func unmatchedPeerName(peerNames []string, peerStatesByName map[string]PeerState) (string, bool) {
+	for _, name := range peerNames {
+		if _, ok := peerStatesByName[name]; !ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// refreshPeerRPCStates probes every peer with an rpc_url concurrently for its identity and health
+func (p *State) refreshPeerRPCStates(ctx context.Context) {
+	if len(p.peerRPCs) == 0 {
+		return
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	latestPeerRPCStatesByName := make(map[string]PeerRPCState, len(p.peerRPCs))
+	for name, client := range p.peerRPCs {
+		wg.Add(1)
+		go func(name string, client rpc.SolanaClient) {
+			defer wg.Done()
+			rpcState := p.probePeerRPC(ctx, name, client)
+			mu.Lock()
+			latestPeerRPCStatesByName[name] = rpcState
+			mu.Unlock()
+		}(name, client)
+	}
+	wg.Wait()
+
+	for name, rpcState := range latestPeerRPCStatesByName {
+		previous, known := p.peerRPCStatesByName[name]
+		if known && previous.Reachable && !rpcState.Reachable {
+			p.logger.Warn("peer rpc unreachable", "name", name, "rpc_url", rpcState.RPCURL, "error", rpcState.Error)
+		}
+		if rpcState.IsActive && (!known || !previous.IsActive) {
+			p.logger.Info("peer rpc reports the active identity", "name", name, "rpc_url", rpcState.RPCURL, "identity", rpcState.Identity)
+		}
+	}
+	p.peerRPCStatesByName = latestPeerRPCStatesByName
+}
+
+// probePeerRPC asks a peer's own rpc for its identity and health
+func (p *State) probePeerRPC(ctx context.Context, name string, client rpc.SolanaClient) PeerRPCState {
+	peer := p.configPeers[name]
+	checkedAt := p.clock.Now()
+	rpcState := PeerRPCState{
+		Name:         name,
+		IP:           peer.IP,
+		RPCURL:       peer.RPCURL,
+		CheckedAt:    checkedAt,
+		CheckedAtUTC: checkedAt.Time(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, PeerRPCTimeout)
+	defer cancel()
+
+	identity, err := client.GetIdentity(ctx)
+	if err != nil {
+		rpcState.Error = err.Error()
+		p.logger.Debug("peer rpc getIdentity failed", "name", name, "rpc_url", peer.RPCURL, "error", err)
+		return rpcState
+	}
+	rpcState.Reachable = true
+	rpcState.Identity = identity.Identity.String()
+	rpcState.IsActive = rpcState.Identity == p.activePubkey
+
+	// an unhealthy node answers getHealth with an error, it is still reachable
+	if _, err := client.GetHealth(ctx); err != nil {
+		rpcState.Error = err.Error()
+		p.logger.Debug("peer rpc reports unhealthy", "name", name, "rpc_url", peer.RPCURL, "error", err)
+	} else {
+		rpcState.Healthy = true
+	}
+	return rpcState
+}
+
+// GetPeerRPCStates returns the latest direct probe of every peer with an rpc_url, keyed by their name
+func (p *State) GetPeerRPCStates() map[string]PeerRPCState {
+	return p.peerRPCStatesByName
+}
+
+// ActivePeerRPC returns the first peer by name, other than us, whose own rpc reports the active identity
+func (p *State) ActivePeerRPC() (PeerRPCState, bool) {
+	names := make([]string, 0, len(p.peerRPCStatesByName))
+	for name := range p.peerRPCStatesByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rpcState := p.peerRPCStatesByName[name]
+		if rpcState.Reachable && rpcState.IsActive && rpcState.IP != p.selfIP {
+			return rpcState, true
+		}
+	}
+	return PeerRPCState{}, false
+}
+
+// refreshActivePeers collects the peers seen with the active identity in gossip or reporting it from their own rpc,
+// logging an error when there is more than one as only one may ever vote with it
+func (p *State) refreshActivePeers() {
+	activePeersByName := make(map[string]PeerState)
+	for name, peerState := range p.peerStatesByName {
+		// a stale peer state says nothing of who holds the active identity now
+		if peerState.LastSeenActive && !peerState.Stale {
+			activePeersByName[name] = peerState
+		}
+	}
+
+	// a peer's own rpc sees an active identity gossip shows only once, or not at all while it is missing
+	for name, rpcState := range p.peerRPCStatesByName {
+		if _, ok := activePeersByName[name]; ok || !rpcState.Reachable || !rpcState.IsActive {
+			continue
+		}
+		activePeersByName[name] = PeerState{
+			Name:           name,
+			IP:             rpcState.IP,
+			Pubkey:         rpcState.Identity,
+			LastSeenAt:     rpcState.CheckedAt,
+			LastSeenAtUTC:  rpcState.CheckedAtUTC,
+			LastSeenActive: true,
+			RPC:            &rpcState,
+		}
+	}
+
+	activePeers := make([]PeerState, 0, len(activePeersByName))
+	for _, peerState := range activePeersByName {
+		activePeers = append(activePeers, peerState)
+	}
+	sort.Slice(activePeers, func(i, j int) bool { return activePeers[i].Name < activePeers[j].Name })
+	p.activePeers = activePeers
+
+	if len(activePeers) > 1 {
+		names := make([]string, 0, len(activePeers))
+		for _, peerState := range activePeers {
+			names = append(names, peerState.Name)
+		}
+		p.logger.Error("split brain - more than one peer seen with the active identity", "peers", strings.Join(names, ","))
+	}
+}
+
+// ActivePeers returns every peer seen with the active identity in gossip or by its own rpc in the last refresh,
+// ordered by name - more than one is a split brain
+func (p *State) ActivePeers() []PeerState {
+	return p.activePeers
+}
+
+// AddressIP returns the canonical IP of a gossip host:port address or bare IP, bracketed and unbracketed IPv6
+// addresses included, so it compares equal to configured IPs however they are written
+func AddressIP(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return host
+}
+
 // isNodeActiveAndVoting returns true if the node is active and voting
-func (p *State) isNodeActiveAndVoting(node solanagorpc.GetClusterNodesResult) bool {
+func (p *State) isNodeActiveAndVoting(ctx context.Context, client rpc.SolanaClient, node solanagorpc.GetClusterNodesResult) bool {
 	// get the current slot
-	currentSlot, err := p.clusterRPC.GetSlot(context.Background())
+	currentSlot, err := client.GetSlot(ctx)
 	if err != nil {
 		p.logger.Error("failed to get current slot", "error", err)
 		return true // forgive rpc error and assume innocence lest we trigger a false-positive failover
 	}
 
 	// get vote accounts to look for our node within
-	voteAccounts, err := p.clusterRPC.GetVoteAccounts(context.Background())
+	voteAccounts, err := client.GetVoteAccounts(ctx)
 	if err != nil {
 		p.logger.Error("failed to get vote accounts", "error", err)
 		return true // forgive rpc error and assume innocence lest we trigger a false-positive failover
@@ -259,7 +740,7 @@ func (p *State) isNodeActiveAndVoting(node solanagorpc.GetClusterNodesResult) bo
 		}
 
 		// ok we might be legit delinquent but let's check if the node's identity balance is below the rent-exempt balance
-		balance, err := p.clusterRPC.GetBalance(context.Background(), delinquentVoteAccount.NodePubkey)
+		balance, err := client.GetBalance(ctx, delinquentVoteAccount.NodePubkey)
 		if err != nil {
 			p.logger.Error("failed to get balance", "error", err)
 			return true // forgive rpc error and assume innocence lest we trigger a false-positive failover
@@ -364,6 +845,11 @@ func (p *State) LeaderlessSamplesBelowThreshold(n int) bool {
 	return p.LeaderlessSamplesCount < n
 }
 
+// SetSelfIP sets our own public IP after it has changed
+func (p *State) SetSelfIP(ip string) {
+	p.selfIP = ip
+}
+
 // HasIP returns true if the IP is in the peers gossip state
 func (p *State) HasIP(ip string) bool {
 	for _, peer := range p.peerStatesByName {
@@ -384,6 +870,11 @@ func (p *State) GetActivePeer() (state PeerState, err error) {
 	return PeerState{}, fmt.Errorf("no active peer found")
 }
 
+// LastActivePeer returns the last peer seen with the active identity, even if it has since left gossip
+func (p *State) LastActivePeer() (PeerState, bool) {
+	return p.lastActivePeer, p.lastActivePeer.IP != ""
+}
+
 // HasPeers returns true if the IP has any peers in the gossip state
 // that is, any peers in that state that are not the passed IP address
 func (p *State) HasPeers(ip string) bool {
@@ -401,29 +892,26 @@ func (p *State) GetPeerStates() map[string]PeerState {
 	return p.peerStatesByName
 }
 
+// SinceLastSeen returns how long before now the peer was last seen, measured monotonically
+func (p *PeerState) SinceLastSeen(now clock.Instant) time.Duration {
+	return now.Sub(p.LastSeenAt)
+}
+
 // LastSeenAtString returns the last seen at time as a string
 func (p *PeerState) LastSeenAtString() string {
 	return p.LastSeenAtUTC.Format(time.RFC3339)
 }
 
+// peerNameFromIP returns the name of the config peer matched in gossip by ip
 func (p *State) peerNameFromIP(ip string) (string, bool) {
 	for name, peer := range p.configPeers {
-		if peer.IP == ip {
+		if peer.IP == ip && peer.MatchesByIP() {
 			return name, true
 		}
 	}
 	return "", false
 }
 
-func (p *State) hasConfigPeerWithIP(ip string) bool {
-	for _, peer := range p.configPeers {
-		if peer.IP == ip {
-			return true
-		}
-	}
-	return false
-}
-
 // IPEquals returns true if the IP is equal to the peer's IP
 func (p *PeerState) IPEquals(ip string) bool {
 	return p.IP == ip
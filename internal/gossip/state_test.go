@@ -1,11 +1,23 @@
 package gossip
 
 import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/charmbracelet/log"
+	"github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/logthrottle"
 	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -239,6 +251,46 @@ func TestPeerState_LastSeenAtString(t *testing.T) {
 	assert.Equal(t, expected, peerState.LastSeenAtString())
 }
 
+func TestPeerState_SinceLastSeen_ClockStep(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	seenAt := fake.Now()
+	peerState := PeerState{IP: "192.168.1.2", LastSeenAt: seenAt, LastSeenAtUTC: seenAt.Time()}
+
+	// the wall clock steps back 30s while 10s pass - the peer was seen 10s ago, not in the future
+	fake.Advance(10 * time.Second)
+	fake.Step(-30 * time.Second)
+	assert.Equal(t, 10*time.Second, peerState.SinceLastSeen(fake.Now()))
+	assert.True(t, fake.Now().Time().Before(peerState.LastSeenAtUTC))
+}
+
+func TestRefresh_ClockStep(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	state := NewState(Options{
+		ClusterRPC:  rpc.NewClient("test", "http://127.0.0.1:1"),
+		SelfIP:      "192.168.1.1",
+		ConfigPeers: map[string]config.Peer{},
+		Clock:       fake,
+	})
+
+	state.Refresh()
+	first := state.PeerStatesRefreshedAt
+	assert.Equal(t, fake.Now(), first)
+
+	// a forward step between refreshes doesn't make the snapshot look stale
+	fake.Advance(5 * time.Second)
+	fake.Step(time.Hour)
+	state.Refresh()
+	assert.Equal(t, 5*time.Second, state.PeerStatesRefreshedAt.Sub(first))
+
+	// nor does a backwards one make it look older than the previous
+	fake.Advance(5 * time.Second)
+	fake.Step(-2 * time.Hour)
+	state.Refresh()
+	assert.True(t, state.PeerStatesRefreshedAt.After(first))
+	assert.Equal(t, 10*time.Second, state.PeerStatesRefreshedAt.Sub(first))
+	assert.Equal(t, time.Date(2024, 12, 31, 23, 0, 10, 0, time.UTC), state.PeerStatesRefreshedAt.Time())
+}
+
 func TestRefresh_WithRPCError(t *testing.T) {
 	// Test that Refresh handles RPC errors gracefully
 	// We'll use a real RPC client but with an invalid URL to simulate failure
@@ -260,12 +312,82 @@ func TestRefresh_WithRPCError(t *testing.T) {
 		"peer1": {IP: "192.168.1.2", Pubkey: "pubkey1", LastSeenAtUTC: time.Now().UTC(), LastSeenActive: false},
 	}
 
-	// Refresh should clear the state due to RPC error
+	// Refresh should keep the state marked stale due to RPC error
 	state.Refresh()
 
-	// Verify the state was cleared
 	assert.False(t, state.PeerStatesRefreshedAt.IsZero())
-	assert.Empty(t, state.GetPeerStates())
+	require.Len(t, state.GetPeerStates(), 1)
+	assert.True(t, state.GetPeerStates()["peer1"].Stale)
+	assert.Equal(t, "pubkey1", state.GetPeerStates()["peer1"].Pubkey)
+	assert.Equal(t, 1, state.ConsecutiveRPCErrors)
+
+	// an unreachable cluster rpc is not a leaderless sample
+	state.Refresh()
+	assert.Equal(t, 2, state.ConsecutiveRPCErrors)
+	assert.Equal(t, 0, state.LeaderlessSamplesCount)
+}
+
+func TestRefresh_RPCErrorsCountAsLeaderless(t *testing.T) {
+	activePubkey := solana.NewWallet().PublicKey().String()
+	state := NewState(Options{
+		ClusterRPC:   rpc.NewClient("test", clusterRPCServer(t, activePubkey, true).URL),
+		ActivePubkey: activePubkey,
+		SelfIP:       "192.168.1.1",
+		ConfigPeers: map[string]config.Peer{
+			"peer1": {IP: "127.0.0.1", Name: "peer1"},
+		},
+		Clock:                      clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+		RPCErrorsCountAsLeaderless: true,
+	})
+	state.Refresh()
+	require.Len(t, state.ActivePeers(), 1)
+	assert.Equal(t, 0, state.ConsecutiveRPCErrors)
+
+	state.clusterRPC = rpc.NewClient("test", "http://127.0.0.1:1")
+	state.Refresh()
+
+	assert.Equal(t, 1, state.ConsecutiveRPCErrors)
+	assert.Equal(t, 1, state.LeaderlessSamplesCount)
+	assert.True(t, state.GetPeerStates()["peer1"].Stale)
+	// the stale active peer is not counted as holding the active identity
+	assert.Empty(t, state.ActivePeers())
+
+	// reaching the cluster rpc again clears the errors
+	state.clusterRPC = rpc.NewClient("test", clusterRPCServer(t, activePubkey, true).URL)
+	state.Refresh()
+	assert.Equal(t, 0, state.ConsecutiveRPCErrors)
+	assert.Equal(t, 0, state.LeaderlessSamplesCount)
+	assert.False(t, state.GetPeerStates()["peer1"].Stale)
+}
+
+func TestRefresh_ThrottlesRepeatedRPCErrors(t *testing.T) {
+	fake := testutil.NewFakeRPC()
+	now := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	state := NewState(Options{
+		ClusterRPC:   fake,
+		ActivePubkey: solana.NewWallet().PublicKey().String(),
+		SelfIP:       "192.168.1.1",
+		ConfigPeers:  map[string]config.Peer{"peer1": {IP: "192.168.1.2", Name: "peer1"}},
+		Clock:        now,
+	})
+	var logged bytes.Buffer
+	state.logger = log.New(&logged)
+
+	// logged for the first few refreshes, then summarized
+	fake.SetError("getClusterNodes", assert.AnError)
+	for range 20 {
+		state.Refresh()
+		now.Advance(time.Minute)
+	}
+	assert.Equal(t, 20, state.ConsecutiveRPCErrors)
+	assert.Equal(t, logthrottle.DefaultBurst+1, strings.Count(logged.String(), "failed to get cluster nodes"))
+	assert.Contains(t, logged.String(), "identical warnings in the last 10m")
+
+	// the recovery is clear
+	fake.SetError("getClusterNodes", nil)
+	state.Refresh()
+	assert.Contains(t, logged.String(), "repeated warning cleared")
+	assert.Contains(t, logged.String(), "occurrences=20")
 }
 
 func TestRefresh_WithValidRPC(t *testing.T) {
@@ -275,7 +397,7 @@ func TestRefresh_WithValidRPC(t *testing.T) {
 
 	opts := Options{
 		ClusterRPC:   realRPC,
-		ActivePubkey: "peNgUgnzs1jGogUPW8SThXMvzNpzKSNf3om78xVPAYx", // This matches the hardcoded active pubkey in the code
+		ActivePubkey: "peNgUgnzs1jGogUPW8SThXMvzNpzKSNf3om78xVPAYx",
 		SelfIP:       "192.168.1.1",
 		ConfigPeers: map[string]config.Peer{
 			"peer1": {IP: "192.168.1.2", Name: "peer1"},
@@ -422,3 +544,480 @@ func TestState_ConcurrentAccess(t *testing.T) {
 	// If we get here without panicking, the methods are thread-safe
 	assert.True(t, true)
 }
+
+func TestAddressIP(t *testing.T) {
+	assert.Equal(t, "192.168.1.10", AddressIP("192.168.1.10:8001"))
+	assert.Equal(t, "2001:db8::1", AddressIP("[2001:db8::1]:8001"))
+	assert.Equal(t, "192.168.1.10", AddressIP("192.168.1.10"))
+
+	// unbracketed, bracketed without a port and uncompressed IPv6 addresses are canonical
+	assert.Equal(t, "2001:db8::1", AddressIP("2001:db8::1"))
+	assert.Equal(t, "2001:db8::1", AddressIP("[2001:db8::1]"))
+	assert.Equal(t, "2001:db8::1", AddressIP("[2001:0DB8:0:0:0:0:0:1]:8001"))
+	assert.Equal(t, "192.168.1.10", AddressIP("[::ffff:192.168.1.10]:8001"))
+	assert.Equal(t, "validator.example.com", AddressIP("validator.example.com:8001"))
+}
+
+func TestRefresh_IPv6Peers(t *testing.T) {
+	activePubkey := solana.NewWallet().PublicKey()
+	active := testutil.GossipNode(t, "::1", activePubkey)
+	passive := testutil.GossipNode(t, "127.0.0.2", solana.NewWallet().PublicKey())
+	require.Equal(t, "[::1]", (*active.Gossip)[:5])
+
+	fake := testutil.NewFakeRPC()
+	fake.SetClusterNodes(active, passive)
+	fake.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(activePubkey)}, nil)
+	state := NewState(Options{
+		ClusterRPC:   fake,
+		ActivePubkey: activePubkey.String(),
+		SelfIP:       "127.0.0.2",
+		ConfigPeers: config.Peers{
+			"active":  {Name: "active", IP: "::1"},
+			"passive": {Name: "passive", IP: "127.0.0.2"},
+		},
+	})
+	state.Refresh()
+
+	// the bracketed gossip address matches the peer's IPv6 address
+	activePeer, err := state.GetActivePeer()
+	require.NoError(t, err)
+	assert.Equal(t, "active", activePeer.Name)
+	assert.Equal(t, "::1", activePeer.GossipIP)
+	assert.True(t, state.HasIP("::1"))
+}
+
+// peerRPCServer is a fake peer rpc reporting identity, answering getHealth with an error when not healthy
+func peerRPCServer(t *testing.T, identity string, healthy bool) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     any    `json:"id"`
+			Method string `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		response := map[string]any{"jsonrpc": "2.0", "id": request.ID}
+		switch {
+		case request.Method == "getIdentity":
+			response["result"] = map[string]any{"identity": identity}
+		case request.Method == "getHealth" && healthy:
+			response["result"] = "ok"
+		default:
+			response["error"] = map[string]any{"code": -32005, "message": "Node is behind by 150 slots"}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// clusterRPCServer is a fake cluster rpc whose gossip has a node for pubkey, reachable on a local gossip address,
+// whose vote account is current when voting
+func clusterRPCServer(t *testing.T, pubkey string, voting bool) *httptest.Server {
+	t.Helper()
+
+	gossipListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { gossipListener.Close() })
+
+	current := []map[string]any{}
+	if voting {
+		current = append(current, map[string]any{
+			"votePubkey":       solana.NewWallet().PublicKey().String(),
+			"nodePubkey":       pubkey,
+			"activatedStake":   1,
+			"epochVoteAccount": true,
+			"commission":       0,
+			"lastVote":         100,
+			"rootSlot":         68,
+			"epochCredits":     [][]uint64{},
+		})
+	}
+	results := map[string]any{
+		"getClusterNodes": []map[string]any{{"pubkey": pubkey, "gossip": gossipListener.Addr().String()}},
+		"getSlot":         100,
+		"getVoteAccounts": map[string]any{"current": current, "delinquent": []map[string]any{}},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     any    `json:"id"`
+			Method string `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": results[request.Method]})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRefresh_ActivePeer(t *testing.T) {
+	activePubkey := solana.NewWallet().PublicKey().String()
+	passivePubkey := solana.NewWallet().PublicKey().String()
+
+	tests := []struct {
+		name           string
+		gossipPubkey   string
+		voting         bool
+		wantInGossip   bool
+		wantActivePeer bool
+	}{
+		{
+			name:           "active peer present",
+			gossipPubkey:   activePubkey,
+			voting:         true,
+			wantInGossip:   true,
+			wantActivePeer: true,
+		},
+		{
+			name:         "active peer in gossip but not voting",
+			gossipPubkey: activePubkey,
+			voting:       false,
+		},
+		{
+			name:         "active peer absent - peer is passive",
+			gossipPubkey: passivePubkey,
+			voting:       true,
+			wantInGossip: true,
+		},
+		{
+			// the peer's key is a well known one that isn't our configured active pubkey - it must never be
+			// taken for our active peer
+			name:         "pubkey mismatch",
+			gossipPubkey: "peNgUgnzs1jGogUPW8SThXMvzNpzKSNf3om78xVPAYx",
+			voting:       true,
+			wantInGossip: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewState(Options{
+				ClusterRPC:   rpc.NewClient("test", clusterRPCServer(t, tt.gossipPubkey, tt.voting).URL),
+				ActivePubkey: activePubkey,
+				SelfIP:       "192.168.1.1",
+				ConfigPeers: map[string]config.Peer{
+					"peer1": {IP: "127.0.0.1", Name: "peer1"},
+				},
+				Clock: clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+			})
+			state.Refresh()
+
+			assert.Equal(t, tt.wantActivePeer, state.HasActivePeer())
+			assert.Equal(t, tt.wantInGossip, state.HasIP("127.0.0.1"))
+			if tt.wantActivePeer {
+				assert.Equal(t, 0, state.LeaderlessSamplesCount)
+				activePeer, err := state.GetActivePeer()
+				require.NoError(t, err)
+				assert.Equal(t, "peer1", activePeer.Name)
+				assert.Equal(t, activePubkey, activePeer.Pubkey)
+			} else {
+				assert.Equal(t, 1, state.LeaderlessSamplesCount)
+				_, err := state.GetActivePeer()
+				assert.Error(t, err)
+			}
+			if tt.wantInGossip {
+				peerState := state.GetPeerStates()["peer1"]
+				assert.Equal(t, tt.gossipPubkey, peerState.Pubkey)
+				assert.Equal(t, tt.wantActivePeer, peerState.LastSeenActive)
+			}
+		})
+	}
+}
+
+func TestRefresh_PeerRPC(t *testing.T) {
+	activePubkey := "peNgUgnzs1jGogUPW8SThXMvzNpzKSNf3om78xVPAYx"
+	passivePubkey := "11111111111111111111111111111111"
+
+	state := NewState(Options{
+		// the cluster rpc failing must not stop peers' own rpc being probed
+		ClusterRPC:   rpc.NewClient("test", "http://127.0.0.1:1"),
+		ActivePubkey: activePubkey,
+		SelfIP:       "192.168.1.1",
+		ConfigPeers: map[string]config.Peer{
+			"a-self": {IP: "192.168.1.1", Name: "a-self", RPCURL: peerRPCServer(t, activePubkey, true).URL},
+			"active": {IP: "192.168.1.2", Name: "active", RPCURL: peerRPCServer(t, activePubkey, true).URL},
+			"behind": {IP: "192.168.1.3", Name: "behind", RPCURL: peerRPCServer(t, passivePubkey, false).URL},
+			"down":   {IP: "192.168.1.4", Name: "down", RPCURL: "http://127.0.0.1:1"},
+			"no-rpc": {IP: "192.168.1.5", Name: "no-rpc"},
+		},
+		Clock: clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+	})
+	state.Refresh()
+
+	assert.Empty(t, state.GetPeerStates())
+	peerRPCStates := state.GetPeerRPCStates()
+	require.Len(t, peerRPCStates, 4)
+	assert.NotContains(t, peerRPCStates, "no-rpc")
+
+	active := peerRPCStates["active"]
+	assert.True(t, active.Reachable)
+	assert.True(t, active.Healthy)
+	assert.True(t, active.IsActive)
+	assert.Equal(t, activePubkey, active.Identity)
+	assert.Equal(t, "192.168.1.2", active.IP)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), active.CheckedAtUTC)
+
+	behind := peerRPCStates["behind"]
+	assert.True(t, behind.Reachable)
+	assert.False(t, behind.Healthy)
+	assert.False(t, behind.IsActive)
+	assert.Contains(t, behind.Error, "Node is behind")
+
+	down := peerRPCStates["down"]
+	assert.False(t, down.Reachable)
+	assert.False(t, down.IsActive)
+	assert.NotEmpty(t, down.Error)
+
+	// our own rpc reporting the active identity is not a peer being active
+	activePeerRPC, ok := state.ActivePeerRPC()
+	require.True(t, ok)
+	assert.Equal(t, "active", activePeerRPC.Name)
+}
+
+func TestActivePeerRPC_NoneActive(t *testing.T) {
+	state := NewState(Options{ClusterRPC: rpc.NewClient("test", "http://127.0.0.1:1"), SelfIP: "192.168.1.1"})
+	_, ok := state.ActivePeerRPC()
+	assert.False(t, ok)
+
+	state.peerRPCStatesByName = map[string]PeerRPCState{
+		"down":   {Name: "down", IP: "192.168.1.2", IsActive: true},
+		"self":   {Name: "self", IP: "192.168.1.1", Reachable: true, IsActive: true},
+		"behind": {Name: "behind", IP: "192.168.1.3", Reachable: true},
+	}
+	_, ok = state.ActivePeerRPC()
+	assert.False(t, ok)
+}
+
+func TestRefresh_ActivePeers(t *testing.T) {
+	activePubkey := solana.NewWallet().PublicKey().String()
+	passivePubkey := solana.NewWallet().PublicKey().String()
+
+	tests := []struct {
+		name      string
+		peer2RPC  string
+		wantNames []string
+	}{
+		{
+			name:      "peer rpc reports passive",
+			peer2RPC:  passivePubkey,
+			wantNames: []string{"peer1"},
+		},
+		{
+			// peer1 is active in gossip and peer2's own rpc runs the active identity too
+			name:      "two actives",
+			peer2RPC:  activePubkey,
+			wantNames: []string{"peer1", "peer2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := NewState(Options{
+				ClusterRPC:   rpc.NewClient("test", clusterRPCServer(t, activePubkey, true).URL),
+				ActivePubkey: activePubkey,
+				SelfIP:       "192.168.1.1",
+				ConfigPeers: map[string]config.Peer{
+					"peer1": {IP: "127.0.0.1", Name: "peer1", RPCURL: peerRPCServer(t, activePubkey, true).URL},
+					"peer2": {IP: "192.168.1.2", Name: "peer2", RPCURL: peerRPCServer(t, tt.peer2RPC, true).URL},
+				},
+				Clock: clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+			})
+			state.Refresh()
+
+			// peer1 is seen active by both gossip and its own rpc but counted once
+			names := []string{}
+			for _, peerState := range state.ActivePeers() {
+				assert.True(t, peerState.LastSeenActive)
+				assert.Equal(t, activePubkey, peerState.Pubkey)
+				names = append(names, peerState.Name)
+			}
+			assert.Equal(t, tt.wantNames, names)
+			assert.Equal(t, "127.0.0.1", state.ActivePeers()[0].GossipIP)
+		})
+	}
+}
+
+func TestRefresh_Quorum(t *testing.T) {
+	activePubkey := solana.NewWallet().PublicKey()
+	passivePubkey := solana.NewWallet().PublicKey()
+	active := testutil.GossipNode(t, "127.0.0.1", activePubkey)
+	passive := testutil.GossipNode(t, "127.0.0.2", passivePubkey)
+
+	// newQuorumState returns a state sampling a fake per endpoint, the active peer voting on all of them
+	newQuorumState := func(endpoints int, minRPCConfirmations int) (*State, []*testutil.FakeRPC) {
+		fakes := make([]*testutil.FakeRPC, endpoints)
+		quorumRPCs := make([]rpc.SolanaClient, endpoints)
+		for i := range fakes {
+			fakes[i] = testutil.NewFakeRPC()
+			fakes[i].SetClusterNodes(active, passive)
+			fakes[i].SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(activePubkey)}, nil)
+			quorumRPCs[i] = fakes[i]
+		}
+		state := NewState(Options{
+			ClusterRPC:   fakes[0],
+			ActivePubkey: activePubkey.String(),
+			SelfIP:       "127.0.0.2",
+			ConfigPeers: config.Peers{
+				"active":  {Name: "active", IP: "127.0.0.1"},
+				"passive": {Name: "passive", IP: "127.0.0.2"},
+			},
+			QuorumRPCs:          quorumRPCs,
+			MinRPCConfirmations: minRPCConfirmations,
+		})
+		return state, fakes
+	}
+
+	t.Run("every endpoint sees the active peer", func(t *testing.T) {
+		state, fakes := newQuorumState(3, 2)
+		state.Refresh()
+
+		assert.True(t, state.HasActivePeer())
+		assert.Equal(t, 3, state.GetPeerStates()["active"].SeenByEndpoints)
+		assert.Equal(t, 3, state.GetPeerStates()["passive"].SeenByEndpoints)
+		for _, fake := range fakes {
+			assert.Equal(t, 1, fake.Calls("getClusterNodes"))
+		}
+	})
+
+	t.Run("one endpoint dropping the active peer is outvoted", func(t *testing.T) {
+		state, fakes := newQuorumState(3, 2)
+		state.LeaderlessSamplesCount = 1
+		fakes[0].SetClusterNodes(passive)
+		state.Refresh()
+
+		assert.True(t, state.HasActivePeer())
+		assert.Equal(t, 0, state.LeaderlessSamplesCount)
+		assert.Equal(t, 2, state.GetPeerStates()["active"].SeenByEndpoints)
+	})
+
+	t.Run("enough endpoints agree the active peer is gone", func(t *testing.T) {
+		state, fakes := newQuorumState(3, 2)
+		fakes[0].SetClusterNodes(passive)
+		// not voting is as good as gone
+		fakes[1].SetVoteAccounts(nil, nil)
+		state.Refresh()
+
+		assert.False(t, state.HasActivePeer())
+		assert.Equal(t, 1, state.LeaderlessSamplesCount)
+		assert.NotContains(t, state.GetPeerStates(), "active")
+		assert.Equal(t, 3, state.GetPeerStates()["passive"].SeenByEndpoints)
+	})
+
+	t.Run("too few endpoints answer to confirm", func(t *testing.T) {
+		state, fakes := newQuorumState(2, 2)
+		state.LeaderlessSamplesCount = 1
+		fakes[0].SetError("getClusterNodes", assert.AnError)
+		fakes[1].SetClusterNodes(passive)
+		state.Refresh()
+
+		// neither counted nor reset
+		assert.False(t, state.HasActivePeer())
+		assert.Equal(t, 1, state.LeaderlessSamplesCount)
+		assert.Equal(t, 1, state.GetPeerStates()["passive"].SeenByEndpoints)
+	})
+
+	t.Run("no endpoint answers", func(t *testing.T) {
+		state, fakes := newQuorumState(2, 2)
+		fakes[0].SetError("getClusterNodes", assert.AnError)
+		fakes[1].SetError("getClusterNodes", assert.AnError)
+		state.Refresh()
+
+		assert.Empty(t, state.GetPeerStates())
+		assert.Equal(t, 0, state.LeaderlessSamplesCount)
+	})
+
+	t.Run("a single confirmation samples the cluster rpc alone", func(t *testing.T) {
+		state, fakes := newQuorumState(2, 1)
+		fakes[0].SetClusterNodes(passive)
+		state.Refresh()
+
+		assert.False(t, state.HasActivePeer())
+		assert.Equal(t, 1, state.LeaderlessSamplesCount)
+		assert.Equal(t, 1, state.GetPeerStates()["passive"].SeenByEndpoints)
+		assert.Zero(t, fakes[1].Calls("getClusterNodes"))
+	})
+}
+
+func TestRefresh_MatchByPubkey(t *testing.T) {
+	activePubkey := solana.NewWallet().PublicKey()
+	selfPubkey := solana.NewWallet().PublicKey()
+	natPassivePubkey := solana.NewWallet().PublicKey()
+	self := testutil.GossipNode(t, "127.0.0.2", selfPubkey)
+
+	// newPubkeyState returns a state of self and peers, the active identity voting
+	newPubkeyState := func(peers config.Peers, nodes ...*solanagorpc.GetClusterNodesResult) *State {
+		fake := testutil.NewFakeRPC()
+		fake.SetClusterNodes(nodes...)
+		fake.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(activePubkey)}, nil)
+		peers["self"] = config.Peer{Name: "self", IP: "127.0.0.2"}
+		return NewState(Options{
+			ClusterRPC:   fake,
+			ActivePubkey: activePubkey.String(),
+			SelfIP:       "127.0.0.2",
+			ConfigPeers:  peers,
+		})
+	}
+
+	t.Run("a peer behind nat is matched by pubkey", func(t *testing.T) {
+		nat := testutil.GossipNode(t, "127.0.0.3", activePubkey)
+		state := newPubkeyState(config.Peers{
+			"nat": {Name: "nat", IP: "203.0.113.10", Pubkeys: []string{activePubkey.String(), natPassivePubkey.String()}},
+		}, self, nat)
+		state.Refresh()
+
+		require.True(t, state.HasActivePeer())
+		activePeer, err := state.GetActivePeer()
+		require.NoError(t, err)
+		assert.Equal(t, "nat", activePeer.Name)
+		assert.Equal(t, "203.0.113.10", activePeer.IP)
+		assert.Equal(t, "127.0.0.3", activePeer.GossipIP)
+		assert.True(t, state.HasIP("203.0.113.10"))
+		assert.Equal(t, "127.0.0.2", state.GetPeerStates()["self"].GossipIP)
+		assert.Equal(t, 0, state.LeaderlessSamplesCount)
+	})
+
+	t.Run("a peer behind nat without pubkeys is not matched", func(t *testing.T) {
+		nat := testutil.GossipNode(t, "127.0.0.3", activePubkey)
+		state := newPubkeyState(config.Peers{"nat": {Name: "nat", IP: "203.0.113.10"}}, self, nat)
+		state.Refresh()
+
+		assert.False(t, state.HasActivePeer())
+		assert.Equal(t, 1, state.LeaderlessSamplesCount)
+	})
+
+	t.Run("match_by pubkey ignores the ip", func(t *testing.T) {
+		stranger := testutil.GossipNode(t, "127.0.0.3", activePubkey)
+		state := newPubkeyState(config.Peers{
+			"relayed": {Name: "relayed", IP: "127.0.0.3", MatchBy: config.PeerMatchByPubkey, Pubkeys: []string{natPassivePubkey.String()}},
+		}, self, stranger)
+		state.Refresh()
+
+		assert.False(t, state.HasActivePeer())
+		assert.NotContains(t, state.GetPeerStates(), "relayed")
+	})
+
+	t.Run("a peer's passive pubkey claims it before the shared active pubkey", func(t *testing.T) {
+		aPassivePubkey := solana.NewWallet().PublicKey()
+		bPassivePubkey := solana.NewWallet().PublicKey()
+		aPassive := testutil.GossipNode(t, "127.0.0.4", aPassivePubkey)
+		active := testutil.GossipNode(t, "127.0.0.5", activePubkey)
+		state := newPubkeyState(config.Peers{
+			"a": {Name: "a", IP: "203.0.113.10", MatchBy: config.PeerMatchByPubkey, Pubkeys: []string{activePubkey.String(), aPassivePubkey.String()}},
+			"b": {Name: "b", IP: "203.0.113.11", MatchBy: config.PeerMatchByPubkey, Pubkeys: []string{activePubkey.String(), bPassivePubkey.String()}},
+		}, self, active, aPassive)
+		state.Refresh()
+
+		activePeer, err := state.GetActivePeer()
+		require.NoError(t, err)
+		assert.Equal(t, "b", activePeer.Name)
+		assert.Equal(t, aPassivePubkey.String(), state.GetPeerStates()["a"].Pubkey)
+	})
+}
@@ -0,0 +1,138 @@
+package ha
+
+import (
+	"math"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+)
+
+const (
+	// rateLimitedThreshold is the number of rate limited responses within the tracker window that
+	// trigger a recommendation and, when enabled, a longer effective poll interval
+	rateLimitedThreshold = 3
+)
+
+// adaptivePoll computes the effective poll interval from the cluster RPC rate limit stats
+type adaptivePoll struct {
+	base          time.Duration
+	enabled       bool
+	maxMultiplier float64
+	window        time.Duration
+	logger        *log.Logger
+	clock         clock.Clock
+
+	multiplier     float64
+	lastAdjustedAt clock.Instant
+	lastWarnedAt   clock.Instant
+}
+
+// newAdaptivePoll creates an adaptive poll for the failover config
+func newAdaptivePoll(failover config.Failover, logger *log.Logger, clk clock.Clock) *adaptivePoll {
+	maxMultiplier := float64(failover.AdaptivePollMaxMultiplier)
+	if maxMultiplier < 1 {
+		maxMultiplier = 1
+	}
+	return &adaptivePoll{
+		base:          failover.PollIntervalDuration,
+		enabled:       failover.AdaptivePoll,
+		maxMultiplier: maxMultiplier,
+		window:        rpc.DefaultRateLimitWindow,
+		logger:        logger,
+		clock:         clk,
+		multiplier:    1,
+	}
+}
+
+// effective returns the current effective poll interval
+func (a *adaptivePoll) effective() time.Duration {
+	return time.Duration(float64(a.base) * a.multiplier)
+}
+
+// relaxesAt returns when a stretched interval relaxes a step if not rate limited by then, zero if not stretched
+func (a *adaptivePoll) relaxesAt() clock.Instant {
+	if a.multiplier <= 1 {
+		return clock.Instant{}
+	}
+	return a.lastAdjustedAt.Add(a.window)
+}
+
+// warningRepeatsAt returns when the rate limited recommendation can next be logged, zero if never logged
+func (a *adaptivePoll) warningRepeatsAt() clock.Instant {
+	if a.lastWarnedAt.IsZero() {
+		return clock.Instant{}
+	}
+	return a.lastWarnedAt.Add(a.window)
+}
+
+// update evaluates the latest rate limit stats, warning with a recommended interval when rate limited
+// and stretching or relaxing the effective interval when adaptive polling is enabled
+func (a *adaptivePoll) update(stats rpc.RateLimitStats) time.Duration {
+	now := a.clock.Now()
+
+	if stats.RateLimitedInWindow >= rateLimitedThreshold {
+		sustainable := a.sustainableInterval(stats)
+
+		// warn at most once per window so the recommendation doesn't drown the logs
+		if a.lastWarnedAt.IsZero() || now.Sub(a.lastWarnedAt) >= a.window {
+			a.lastWarnedAt = now
+			a.logger.Warn("cluster rpc is rate limiting requests - consider raising failover.poll_interval_duration, enabling failover.adaptive_poll or using a dedicated rpc",
+				"rate_limited", stats.RateLimitedInWindow,
+				"requests", stats.RequestsInWindow,
+				"window", a.window,
+				"poll_interval", a.effective(),
+				"recommended_poll_interval", sustainable,
+			)
+		}
+
+		if !a.enabled || a.multiplier >= a.maxMultiplier {
+			return a.effective()
+		}
+
+		// stretch to at least double or the sustainable interval, bounded by the max multiplier
+		multiplier := math.Max(a.multiplier*2, float64(sustainable)/float64(a.base))
+		a.setMultiplier(math.Min(multiplier, a.maxMultiplier), now, "rate limited - stretching effective poll interval")
+		return a.effective()
+	}
+
+	// relax one step at a time once a full window has passed without rate limiting since the last change
+	if a.multiplier > 1 && stats.RateLimitedInWindow == 0 && now.Sub(a.lastAdjustedAt) >= a.window {
+		a.setMultiplier(math.Max(a.multiplier/2, 1), now, "no longer rate limited - relaxing effective poll interval")
+	}
+
+	return a.effective()
+}
+
+// setMultiplier sets the multiplier logging the change
+func (a *adaptivePoll) setMultiplier(multiplier float64, now clock.Instant, message string) {
+	previous := a.effective()
+	a.multiplier = multiplier
+	a.lastAdjustedAt = now
+	a.logger.Warn(message,
+		"previous_poll_interval", previous,
+		"effective_poll_interval", a.effective(),
+		"max_poll_interval", time.Duration(float64(a.base)*a.maxMultiplier),
+	)
+}
+
+// sustainableInterval estimates the poll interval at which requests would stop being rate limited by scaling
+// the current interval by the ratio of requests sent to requests that succeeded, rounded up to the second
+// and never below the provider's retry hint
+func (a *adaptivePoll) sustainableInterval(stats rpc.RateLimitStats) time.Duration {
+	current := a.effective()
+
+	succeeded := stats.RequestsInWindow - stats.RateLimitedInWindow
+	ratio := 2.0
+	if succeeded > 0 {
+		ratio = float64(stats.RequestsInWindow) / float64(succeeded)
+	}
+
+	sustainable := time.Duration(math.Ceil(float64(current)*ratio/float64(time.Second))) * time.Second
+	if stats.RetryAfter > sustainable {
+		sustainable = stats.RetryAfter
+	}
+	return sustainable
+}
@@ -0,0 +1,136 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+)
+
+// rateLimitingServer answers getSlot, returning 429 while rateLimiting is set
+func rateLimitingServer(t *testing.T, rateLimiting *atomic.Bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rateLimiting.Load() {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		var request struct {
+			ID int `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "result": 1, "id": request.ID})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// adaptivePollHarness drives poll cycles against a rate limiting server with a shared fake clock
+type adaptivePollHarness struct {
+	t            *testing.T
+	clock        *clock.Fake
+	client       *rpc.Client
+	poll         *adaptivePoll
+	rateLimiting atomic.Bool
+}
+
+func newAdaptivePollHarness(t *testing.T, enabled bool, maxMultiplier int) *adaptivePollHarness {
+	h := &adaptivePollHarness{
+		t:     t,
+		clock: clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+	server := rateLimitingServer(t, &h.rateLimiting)
+
+	h.client = rpc.NewClient("test", server.URL)
+	h.client.RateLimits().SetClock(func() time.Time { return h.clock.Now().Time() })
+
+	h.poll = newAdaptivePoll(config.Failover{
+		PollIntervalDuration:      5 * time.Second,
+		AdaptivePoll:              enabled,
+		AdaptivePollMaxMultiplier: maxMultiplier,
+	}, log.WithPrefix("test"), h.clock)
+
+	return h
+}
+
+// cycle sends requests rpc calls, updates the adaptive poll and advances the clock by the effective interval
+func (h *adaptivePollHarness) cycle(requests int) time.Duration {
+	for i := 0; i < requests; i++ {
+		_, err := h.client.GetSlot(context.Background())
+		if !h.rateLimiting.Load() {
+			require.NoError(h.t, err)
+		}
+	}
+	effective := h.poll.update(h.client.RateLimits().Stats())
+	h.clock.Advance(effective)
+	return effective
+}
+
+func TestAdaptivePoll_StretchesAndRelaxes(t *testing.T) {
+	h := newAdaptivePollHarness(t, true, 4)
+
+	// not rate limited - configured interval
+	assert.Equal(t, 5*time.Second, h.cycle(2))
+
+	// rate limited - stretched to the sustainable interval (5 requests, 3 limited: 5s * 5/2 rounded up) then up to the max multiplier
+	h.rateLimiting.Store(true)
+	assert.Equal(t, 13*time.Second, h.cycle(3))
+	assert.Equal(t, 20*time.Second, h.cycle(3))
+	assert.Equal(t, 20*time.Second, h.cycle(3), "bounded by the max multiplier")
+
+	// rate limiting stops - stays stretched until a full window passes without 429s since the last change
+	h.rateLimiting.Store(false)
+	assert.Equal(t, 20*time.Second, h.cycle(1))
+	assert.Equal(t, 20*time.Second, h.cycle(1))
+
+	// now relaxes one step at a time, a window apart
+	h.clock.Advance(time.Minute)
+	assert.Equal(t, 10*time.Second, h.cycle(1))
+	h.clock.Advance(time.Minute)
+	assert.Equal(t, 5*time.Second, h.cycle(1))
+	h.clock.Advance(time.Minute)
+	assert.Equal(t, 5*time.Second, h.cycle(1), "never below the configured interval")
+}
+
+func TestAdaptivePoll_Disabled(t *testing.T) {
+	h := newAdaptivePollHarness(t, false, 4)
+
+	h.rateLimiting.Store(true)
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, 5*time.Second, h.cycle(3))
+	}
+	assert.False(t, h.poll.lastWarnedAt.IsZero(), "recommendation is still logged")
+}
+
+func TestAdaptivePoll_BelowThreshold(t *testing.T) {
+	h := newAdaptivePollHarness(t, true, 4)
+
+	h.rateLimiting.Store(true)
+	assert.Equal(t, 5*time.Second, h.cycle(rateLimitedThreshold-1))
+	assert.True(t, h.poll.lastWarnedAt.IsZero())
+}
+
+func TestAdaptivePoll_SustainableInterval(t *testing.T) {
+	poll := newAdaptivePoll(config.Failover{PollIntervalDuration: 5 * time.Second}, log.WithPrefix("test"), clock.System)
+
+	// half the requests rate limited - double the interval
+	assert.Equal(t, 10*time.Second, poll.sustainableInterval(rpc.RateLimitStats{RequestsInWindow: 10, RateLimitedInWindow: 5}))
+	// a third rate limited - rounded up to the second
+	assert.Equal(t, 8*time.Second, poll.sustainableInterval(rpc.RateLimitStats{RequestsInWindow: 9, RateLimitedInWindow: 3}))
+	// all rate limited - double
+	assert.Equal(t, 10*time.Second, poll.sustainableInterval(rpc.RateLimitStats{RequestsInWindow: 3, RateLimitedInWindow: 3}))
+	// provider hint wins when longer
+	assert.Equal(t, 30*time.Second, poll.sustainableInterval(rpc.RateLimitStats{RequestsInWindow: 3, RateLimitedInWindow: 3, RetryAfter: 30 * time.Second}))
+}
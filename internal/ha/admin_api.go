@@ -0,0 +1,312 @@
+package ha
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+)
+
+// adminAPIUser is who the manual_transition event records as asking for a transition over the admin api
+const adminAPIUser = "admin_api"
+
+// TransitionResult is what a promote or demote requested over the admin api did
+type TransitionResult struct {
+	Role   string `json:"role"`
+	DryRun bool   `json:"dry_run"`
+	// Confirmed is true once local rpc reports the role, already held or switched to - never in dry run
+	Confirmed bool   `json:"confirmed"`
+	Error     string `json:"error,omitempty"`
+	// Commands are the rendered hooks and role command of the transition in the order they run
+	Commands []RenderedCommand `json:"commands"`
+	// Command is the role command's outcome, omitted when it never ran
+	Command *CommandOutcome `json:"command,omitempty"`
+	// Hooks are the outcomes of the hooks that ran in order - a post hook retried in the background is reported
+	// with its first failure
+	Hooks []HookOutcome `json:"hooks"`
+	// Events are the events recorded during the transition
+	Events []events.Event `json:"events"`
+}
+
+// RenderedCommand is a hook or role command as it runs, templates rendered
+type RenderedCommand struct {
+	// Stage is pre-<role>, <role> for the role command or post-<role>
+	Stage   string   `json:"stage"`
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// HookOutcome is how a hook run went
+type HookOutcome struct {
+	Name            string  `json:"name"`
+	Phase           string  `json:"phase"`
+	ExitCode        int     `json:"exit_code"`
+	Attempts        int     `json:"attempts"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	DryRun          bool    `json:"dry_run"`
+	Output          string  `json:"output,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	// Retrying is true when a failed post hook goes on being retried in the background
+	Retrying bool `json:"retrying,omitempty"`
+}
+
+// CommandOutcome is how the role command run went
+type CommandOutcome struct {
+	ExitCode        int     `json:"exit_code"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	DryRun          bool    `json:"dry_run"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// transitionReport collects the outcomes of a transition as it runs, for its history entry and when requested over the
+// admin api its result - post hooks retried in the background report to it from their own goroutine
+type transitionReport struct {
+	mu      sync.Mutex
+	command *CommandOutcome
+	hooks   []HookOutcome
+	events  []events.Event
+}
+
+// addHook adds a hook's outcome
+func (r *transitionReport) addHook(result config.HookResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	outcome := HookOutcome{
+		Name:            result.Hook.Name,
+		Phase:           result.HookType,
+		ExitCode:        result.ExitCode,
+		Attempts:        result.Attempts,
+		DurationSeconds: result.Duration.Seconds(),
+		DryRun:          result.DryRun,
+		Output:          result.Output,
+		Retrying:        result.Retrying,
+	}
+	if result.Err != nil {
+		outcome.Error = result.Err.Error()
+	}
+	r.hooks = append(r.hooks, outcome)
+}
+
+// setCommand sets the role command's outcome - a rollback's command that follows is told by its events
+func (r *transitionReport) setCommand(result config.RoleCommandResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.command != nil {
+		return
+	}
+	r.command = &CommandOutcome{ExitCode: result.ExitCode, DurationSeconds: result.Duration.Seconds(), DryRun: result.DryRun}
+	if err != nil {
+		r.command.Error = err.Error()
+	}
+}
+
+// addEvent adds a recorded event
+func (r *transitionReport) addEvent(event events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+// recordedEvents returns the events recorded so far
+func (r *transitionReport) recordedEvents() []events.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]events.Event{}, r.events...)
+}
+
+// fill copies the collected outcomes into result
+func (r *transitionReport) fill(result *TransitionResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result.Command = r.command
+	result.Hooks = append([]HookOutcome{}, r.hooks...)
+	result.Events = append([]events.Event{}, r.events...)
+}
+
+// renderedCommands returns the hooks and role command of the transition to role in the order they run
+func (m *Manager) renderedCommands(role constants.Role) []RenderedCommand {
+	roleCfg := m.cfg.Failover.Passive
+	if role == constants.RoleActive {
+		roleCfg = m.cfg.Failover.Active
+	}
+
+	commands := []RenderedCommand{}
+	for _, hook := range roleCfg.Hooks.Pre {
+		commands = append(commands, m.renderedHook("pre-"+role.String(), hook))
+	}
+	if roleCfg.UsesAdminRPC() {
+		commands = append(commands, RenderedCommand{
+			Stage:   role.String(),
+			Name:    "command",
+			Command: config.RoleMethodAgaveAdminRPC,
+			Args:    []string{roleCfg.AdminRPC.Socket(), "setIdentity", roleCfg.IdentityKeypairFile},
+		})
+	} else {
+		commands = append(commands, RenderedCommand{Stage: role.String(), Name: "command", Command: roleCfg.Command, Args: roleCfg.Args})
+	}
+	for _, hook := range roleCfg.Hooks.Post {
+		commands = append(commands, m.renderedHook("post-"+role.String(), hook))
+	}
+	return commands
+}
+
+// renderedHook returns hook at stage as it runs, built in slack and webhook hooks with their channel or method
+// and redacted url
+func (m *Manager) renderedHook(stage string, hook config.Hook) RenderedCommand {
+	switch {
+	case hook.Slack != nil:
+		return RenderedCommand{Stage: stage, Name: hook.Name, Command: "slack", Args: []string{hook.Slack.Channel}}
+	case hook.Webhook != nil:
+		return RenderedCommand{Stage: stage, Name: hook.Name, Command: "webhook", Args: []string{hook.Webhook.Method, m.cfg.Redact.String(hook.Webhook.URL)}}
+	}
+	return RenderedCommand{Stage: stage, Name: hook.Name, Command: hook.Command, Args: hook.Args}
+}
+
+// adminTransition runs a transition to role requested over the admin api between HA monitor cycles, through the
+// same checks as promote and demote and the same ensureActive and ensurePassive as a failover. It is refused while
+// another transition is in progress.
+func (m *Manager) adminTransition(role constants.Role, opts ManualTransitionOptions) (TransitionResult, error) {
+	result := TransitionResult{
+		Role:     role.String(),
+		DryRun:   m.cfg.Failover.DryRun,
+		Commands: m.renderedCommands(role),
+		Hooks:    []HookOutcome{},
+		Events:   []events.Event{},
+	}
+
+	// nothing new is started once shutting down
+	if m.shuttingDown() {
+		err := &transitionRefusedError{reason: "the agent is shutting down"}
+		result.Error = err.Error()
+		return result, err
+	}
+
+	// a running cycle that isn't transitioning is waited for, one in the middle of a transition is not - nor is
+	// another request
+	if m.transitioning.Load() || !m.adminTransitionRunning.CompareAndSwap(false, true) {
+		err := &transitionRefusedError{reason: "a transition is already in progress"}
+		result.Error = err.Error()
+		return result, err
+	}
+	defer m.adminTransitionRunning.Store(false)
+	m.cycleMu.Lock()
+	defer m.cycleMu.Unlock()
+
+	report := &transitionReport{}
+	m.transitionReport.Store(report)
+	defer m.transitionReport.Store(nil)
+
+	// a fresh snapshot of gossip so we know who else is active
+	m.gossipState.RefreshContext(m.ctx)
+
+	err := m.runManualTransition(role, opts, report.recordedEvents)
+	report.fill(&result)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Confirmed = err == nil && !result.DryRun
+	return result, err
+}
+
+// adminAPIHandler returns the admin api's routes, every one requiring the admin_api.auth bearer token
+func (m *Manager) adminAPIHandler() http.Handler {
+	token := m.cfg.AdminAPI.Auth.Token()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /state", m.handleStatus)
+	mux.HandleFunc("POST /promote", m.handleAdminTransition(constants.RoleActive))
+	mux.HandleFunc("POST /demote", m.handleAdminTransition(constants.RolePassive))
+	mux.HandleFunc("POST /pause", m.handlePause)
+	mux.HandleFunc("POST /resume", m.handleResume)
+	mux.HandleFunc("GET /log-levels", m.handleLogLevels)
+	mux.HandleFunc("POST /log-levels", m.handleSetLogLevel)
+
+	return httpauth.RequireBearerToken(token, mux)
+}
+
+// handleAdminTransition serves a transition to role as a TransitionResult - 409 Conflict when refused before
+// anything ran, such as while another transition is in progress, and 500 when it wasn't confirmed. Promotions take
+// a force query parameter to promote while gossip shows another active peer.
+func (m *Manager) handleAdminTransition(role constants.Role) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts := ManualTransitionOptions{User: adminAPIUser}
+		if value := r.URL.Query().Get("force"); value != "" && role == constants.RoleActive {
+			force, err := strconv.ParseBool(value)
+			if err != nil {
+				http.Error(w, "invalid force: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			opts.Force = force
+		}
+
+		result, err := m.adminTransition(role, opts)
+		statusCode := http.StatusOK
+		var refused *transitionRefusedError
+		if errors.As(err, &refused) {
+			statusCode = http.StatusConflict
+		} else if err != nil {
+			statusCode = http.StatusInternalServerError
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// startAdminAPI binds admin_api.port and serves the admin api in the background when admin_api.enabled, a port
+// that can't be bound or a certificate that can't be loaded is logged and the agent carries on without it
+func (m *Manager) startAdminAPI() {
+	if !m.cfg.AdminAPI.Enabled {
+		return
+	}
+
+	address := m.cfg.AdminAPI.ListenAddress()
+	end := m.beginStartupStep(StartupStepAdminAPIBind)
+	listener, err := m.listen("tcp", address)
+	end()
+	if err != nil {
+		m.logger.Error("admin api server error", "error", err)
+		return
+	}
+	tlsConfig, err := m.serverTLSConfig(m.cfg.AdminAPI.TLS)
+	if err != nil {
+		listener.Close()
+		m.logger.Error("admin api server error", "error", err)
+		return
+	}
+
+	adminServer := &http.Server{
+		Addr:      address,
+		Handler:   m.adminAPIHandler(),
+		TLSConfig: tlsConfig,
+	}
+	m.adminServer = adminServer
+
+	go func() {
+		m.logger.Info("starting admin api server", "address", listener.Addr().String(), "tls", tlsConfig != nil)
+
+		var err error
+		if tlsConfig != nil {
+			// the certificate comes from tlsConfig
+			err = adminServer.ServeTLS(listener, "", "")
+		} else {
+			err = adminServer.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			m.logger.Error("admin api server error", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,241 @@
+package ha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminAPIToken = "s3cr3t"
+
+// adminAPIHarness is a passive manager on 127.0.0.1 promoted over the admin rpc, with pre and post active hooks
+type adminAPIHarness struct {
+	manager *Manager
+	cluster *testutil.FakeRPC
+	admin   *testutil.FakeAdminRPC
+	handler http.Handler
+}
+
+func newAdminAPIHarness(t *testing.T) *adminAPIHarness {
+	t.Helper()
+
+	h := &adminAPIHarness{cluster: testutil.NewFakeRPC(), admin: testutil.NewFakeAdminRPC(t)}
+	cfg := createLiveTestConfig()
+	cfg.AdminAPI = config.AdminAPI{Enabled: true, Port: 9092, Auth: &config.Auth{BearerToken: testAdminAPIToken}}
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
+	cfg.Failover.Active = config.Role{
+		Name:                "active",
+		Method:              config.RoleMethodAgaveAdminRPC,
+		AdminRPC:            config.AdminRPC{LedgerPath: h.admin.LedgerPath},
+		IdentityKeypairFile: "/keys/active.json",
+		Hooks: config.Hooks{
+			Pre: []config.Hook{{Name: "pre-active", Command: "sh", Args: []string{"-c", "echo pre"}}},
+			// waited for so the result reports it
+			Post: []config.Hook{{Name: "post-active", Command: "true", Async: new(bool)}},
+		},
+	}
+	h.manager = newTakeoverManager(t, cfg, h.cluster, h.cluster)
+	h.admin.OnSetIdentity(func(string) {
+		h.manager.localRPC.(*testutil.FakeRPC).SetIdentity(cfg.Validator.Identities.ActiveKeyPair.PublicKey())
+	})
+	h.handler = h.manager.adminAPIHandler()
+	return h
+}
+
+// post posts to path with the admin api token, returning the response and its decoded TransitionResult
+func (h *adminAPIHarness) post(t *testing.T, path string) (*httptest.ResponseRecorder, TransitionResult) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	httpauth.SetBearerToken(req, testAdminAPIToken)
+	recorder := httptest.NewRecorder()
+	h.handler.ServeHTTP(recorder, req)
+
+	var result TransitionResult
+	if recorder.Header().Get("Content-Type") == "application/json" {
+		require.NoError(t, json.NewDecoder(recorder.Body).Decode(&result))
+	}
+	return recorder, result
+}
+
+func TestAdminAPI_RequiresToken(t *testing.T) {
+	h := newAdminAPIHarness(t)
+
+	for _, path := range []string{"/state", "/promote", "/demote", "/pause", "/resume", "/log-levels"} {
+		recorder := httptest.NewRecorder()
+		h.handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, nil))
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, path)
+	}
+	assert.Empty(t, h.admin.Calls())
+
+	h.manager.refreshMetrics()
+	req := httptest.NewRequest(http.MethodGet, "/state", nil)
+	httpauth.SetBearerToken(req, testAdminAPIToken)
+	recorder := httptest.NewRecorder()
+	h.handler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var status Status
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&status))
+	assert.Equal(t, "test-validator", status.ValidatorName)
+}
+
+func TestAdminAPI_Promote(t *testing.T) {
+	h := newAdminAPIHarness(t)
+
+	recorder, result := h.post(t, "/promote")
+
+	require.Equal(t, http.StatusOK, recorder.Code, result.Error)
+	assert.True(t, result.Confirmed)
+	assert.Equal(t, "active", result.Role)
+	assert.Equal(t, []RenderedCommand{
+		{Stage: "pre-active", Name: "pre-active", Command: "sh", Args: []string{"-c", "echo pre"}},
+		{Stage: "active", Name: "command", Command: config.RoleMethodAgaveAdminRPC, Args: []string{h.admin.LedgerPath + "/admin.rpc", "setIdentity", "/keys/active.json"}},
+		{Stage: "post-active", Name: "post-active", Command: "true"},
+	}, result.Commands)
+	require.NotNil(t, result.Command)
+	assert.Empty(t, result.Command.Error)
+	require.Len(t, result.Hooks, 2)
+	assert.Equal(t, "pre-active", result.Hooks[0].Name)
+	assert.Equal(t, "pre\n", result.Hooks[0].Output)
+	assert.Equal(t, "post-active", result.Hooks[1].Name)
+
+	types := []string{}
+	for _, event := range result.Events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{events.TypeManualTransition, events.TypeBecomingActive, events.TypeActive}, types)
+	assert.Equal(t, adminAPIUser, result.Events[0].Fields["user"])
+	assert.Len(t, h.admin.Calls(), 1)
+}
+
+func TestAdminAPI_Promote_DryRun(t *testing.T) {
+	h := newAdminAPIHarness(t)
+	h.manager.cfg.Failover.DryRun = true
+
+	recorder, result := h.post(t, "/promote")
+
+	require.Equal(t, http.StatusOK, recorder.Code, result.Error)
+	assert.True(t, result.DryRun)
+	assert.False(t, result.Confirmed)
+	require.NotNil(t, result.Command)
+	assert.True(t, result.Command.DryRun)
+	assert.Empty(t, h.admin.Calls())
+}
+
+func TestAdminAPI_Promote_ActivePeer(t *testing.T) {
+	h := newAdminAPIHarness(t)
+	activePubkey := h.manager.cfg.Validator.Identities.ActiveKeyPair.PublicKey()
+	h.cluster.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(activePubkey)}, nil)
+	h.cluster.SetClusterNodes(
+		testutil.GossipNode(t, "127.0.0.1", h.manager.cfg.Validator.Identities.PassiveKeyPair.PublicKey()),
+		testutil.GossipNode(t, "127.0.0.2", activePubkey),
+	)
+
+	// refused before anything runs unless forced
+	recorder, result := h.post(t, "/promote")
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Contains(t, result.Error, "peer peer1 (127.0.0.2) is active in gossip")
+	assert.Empty(t, result.Events)
+	assert.Empty(t, h.admin.Calls())
+
+	recorder, result = h.post(t, "/promote?force=true")
+	assert.Equal(t, http.StatusOK, recorder.Code, result.Error)
+	assert.True(t, result.Confirmed)
+	assert.Equal(t, "true", result.Events[0].Fields["force"])
+}
+
+func TestAdminAPI_Conflicts(t *testing.T) {
+	h := newAdminAPIHarness(t)
+
+	// a transition already in progress
+	h.manager.transitioning.Store(true)
+	recorder, result := h.post(t, "/demote")
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Equal(t, "a transition is already in progress", result.Error)
+	h.manager.transitioning.Store(false)
+
+	// paused for maintenance
+	h.manager.cfg.Failover.MaxPauseDuration = time.Hour
+	pauseRecorder, _ := h.post(t, "/pause")
+	require.Equal(t, http.StatusOK, pauseRecorder.Code)
+	recorder, result = h.post(t, "/promote")
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Equal(t, "failover is paused for maintenance - resume it first", result.Error)
+
+	resumeRecorder, _ := h.post(t, "/resume")
+	require.Equal(t, http.StatusOK, resumeRecorder.Code)
+	recorder, _ = h.post(t, "/promote")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Len(t, h.admin.Calls(), 1)
+}
+
+func TestAdminAPI_Demote_Failure(t *testing.T) {
+	h := newAdminAPIHarness(t)
+	h.manager.localRPC.(*testutil.FakeRPC).SetIdentity(h.manager.cfg.Validator.Identities.ActiveKeyPair.PublicKey())
+	h.manager.cfg.Failover.Passive = config.Role{Name: "passive", Command: "false"}
+
+	recorder, result := h.post(t, "/demote")
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	assert.False(t, result.Confirmed)
+	assert.Contains(t, result.Error, "failed to become passive")
+	require.NotNil(t, result.Command)
+	assert.Equal(t, 1, result.Command.ExitCode)
+	assert.NotEmpty(t, result.Command.Error)
+}
+
+func TestAdminAPI_LogLevels(t *testing.T) {
+	h := newAdminAPIHarness(t)
+	t.Cleanup(func() { require.NoError(t, loglevel.Set(log.InfoLevel, nil)) })
+	require.NoError(t, loglevel.Set(log.InfoLevel, nil))
+
+	// gossip debug without the manager's
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/log-levels?module=gossip_state&level=debug", nil)
+	httpauth.SetBearerToken(req, testAdminAPIToken)
+	h.handler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var levels LogLevels
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&levels))
+	assert.Equal(t, "info", levels.Level)
+	assert.Equal(t, "debug", levels.Levels["gossip_state"])
+	assert.Equal(t, "info", levels.Levels["ha_manager"])
+	assert.Equal(t, log.DebugLevel, loglevel.WithPrefix(loglevel.GossipState, "[test gossip_state]").GetLevel())
+	assert.Equal(t, log.InfoLevel, h.manager.logger.GetLevel())
+
+	// back to the global level
+	recorder = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/log-levels?module=gossip_state&level=default", nil)
+	httpauth.SetBearerToken(req, testAdminAPIToken)
+	h.handler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, map[string]log.Level{}, loglevel.Overrides())
+
+	for _, path := range []string{"/log-levels?module=gossip&level=debug", "/log-levels?level=verbose", "/log-levels?level=default", "/log-levels"} {
+		recorder = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, path, nil)
+		httpauth.SetBearerToken(req, testAdminAPIToken)
+		h.handler.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, path)
+	}
+
+	recorder = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/log-levels", nil)
+	httpauth.SetBearerToken(req, testAdminAPIToken)
+	h.handler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&levels))
+	assert.Equal(t, "info", levels.Levels["gossip_state"])
+}
@@ -0,0 +1,46 @@
+package ha
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+)
+
+// failoverCooldown holds back transitions for failover.cooldown after the last one completed, so a flapping
+// cluster rpc view can't flip this node between roles every few polls
+type failoverCooldown struct {
+	duration time.Duration
+
+	mu sync.Mutex
+	// lastTransitionAt is when the last transition was confirmed by local rpc, zero if none has been
+	lastTransitionAt clock.Instant
+}
+
+// start starts the cooldown from a transition confirmed at now
+func (c *failoverCooldown) start(now clock.Instant) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastTransitionAt = now
+}
+
+// remaining returns how long is left of the cooldown at now, 0 once it has elapsed or if it never started
+func (c *failoverCooldown) remaining(now clock.Instant) time.Duration {
+	expiresAt := c.expiresAt()
+	if expiresAt.IsZero() || !expiresAt.After(now) {
+		return 0
+	}
+	return expiresAt.Sub(now)
+}
+
+// expiresAt returns when the cooldown expires, zero if it never started or is disabled
+func (c *failoverCooldown) expiresAt() clock.Instant {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.duration <= 0 || c.lastTransitionAt.IsZero() {
+		return clock.Instant{}
+	}
+	return c.lastTransitionAt.Add(c.duration)
+}
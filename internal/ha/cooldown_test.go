@@ -0,0 +1,83 @@
+package ha
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverCooldown(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// disabled never holds back
+	disabled := &failoverCooldown{}
+	disabled.start(fake.Now())
+	assert.Zero(t, disabled.remaining(fake.Now()))
+	assert.True(t, disabled.expiresAt().IsZero())
+
+	// nothing to hold back before the first transition
+	cooldown := &failoverCooldown{duration: time.Minute}
+	assert.Zero(t, cooldown.remaining(fake.Now()))
+	assert.True(t, cooldown.expiresAt().IsZero())
+
+	cooldown.start(fake.Now())
+	fake.Advance(20 * time.Second)
+	assert.Equal(t, 40*time.Second, cooldown.remaining(fake.Now()))
+
+	fake.Advance(40 * time.Second)
+	assert.Zero(t, cooldown.remaining(fake.Now()))
+}
+
+func TestManager_CooldownStartsOnConfirmedTransition(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Cooldown = time.Minute
+	manager, _, _ := newFakeRPCManager(t, cfg)
+	assert.Zero(t, manager.cooldown.remaining(manager.clock.Now()))
+
+	manager.ensurePassive(DecisionReasonSelfNotInGossip)
+	assert.Greater(t, manager.cooldown.remaining(manager.clock.Now()), 50*time.Second)
+
+	// the remaining cooldown is a timer
+	timers := manager.timers.snapshot(manager.clock.Now())
+	names := []string{}
+	for _, timer := range timers {
+		names = append(names, timer.Name)
+		if timer.Name == TimerFailoverCooldown {
+			assert.Greater(t, timer.Remaining, 50*time.Second)
+		}
+	}
+	assert.Contains(t, names, TimerFailoverCooldown)
+}
+
+func TestManager_EnsureHAState_HeldBackByCooldown(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
+	cfg.Failover.Cooldown = time.Minute
+	fake := testutil.NewFakeRPC()
+	manager := newTakeoverManager(t, cfg, fake, fake)
+	manager.cooldown.start(manager.clock.Now())
+
+	manager.ensureHAState()
+	assert.Equal(t, DecisionActionNone, manager.decision.Action)
+	assert.Equal(t, DecisionReasonCooldown, manager.decision.Reason)
+	assert.Empty(t, recordedTypes(manager))
+}
+
+func TestManager_EnsureHAState_CooldownNeverHoldsBackDemotion(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Cooldown = time.Minute
+	cfg.Failover.LeaderlessSamplesThreshold = 1
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
+	localRPC.SetIdentity(cfg.Validator.Identities.ActiveKeyPair.PublicKey())
+	manager.cooldown.start(manager.clock.Now())
+
+	// we are active but out of gossip
+	manager.ensureHAState()
+	assert.Equal(t, DecisionReasonSelfNotInGossip, manager.decision.Reason)
+	require.NotEmpty(t, recordedTypes(manager))
+}
@@ -0,0 +1,274 @@
+package ha
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/etcd"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+)
+
+// election is the election a coordinator campaigns in, an etcd.Election outside of tests
+type election interface {
+	Key() string
+	Holding() bool
+	KeepAlive(ctx context.Context) (leaseExpired bool, err error)
+	Campaign(ctx context.Context) (holder string, leaseExpired bool, err error)
+	Leader(ctx context.Context) (string, error)
+	Resign(ctx context.Context) error
+}
+
+// CoordinationStatus is what we last saw of the failover.coordination.etcd key
+type CoordinationStatus struct {
+	Backend string `json:"backend"`
+	Key     string `json:"key"`
+	// Holder is who held the key when etcd last answered, omitted when no one did
+	Holder    string `json:"holder,omitempty"`
+	Reachable bool   `json:"reachable"`
+	// Degraded is true once etcd has been unreachable for longer than its unreachable_grace, failovers being
+	// coordinated by gossip alone until it answers again
+	Degraded bool   `json:"degraded"`
+	Error    string `json:"error,omitempty"`
+}
+
+// coordinator holds the failover.coordination.etcd key on a lease while we are active - a passive only takes over
+// once it has expired and the gossip conditions hold. Once etcd has been unreachable for longer than its
+// unreachable_grace it degrades to gossip alone.
+type coordinator struct {
+	election election
+	name     string
+	grace    time.Duration
+	leaseTTL time.Duration
+	logger   *log.Logger
+	clock    clock.Clock
+
+	mu sync.Mutex
+	// reachableAt is when etcd last answered, or when we started
+	reachableAt clock.Instant
+	// unreachable is true since etcd last failed to answer, degraded once it has for longer than grace
+	unreachable bool
+	degraded    bool
+	holder      string
+	lastError   string
+}
+
+// newCoordinator creates the coordinator of failover.coordination.etcd for name, returning nil when it is not set
+func newCoordinator(cfg *config.Etcd, name string, logger *log.Logger, clk clock.Clock) (*coordinator, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	tlsConfig, err := cfg.TLS.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failover.coordination.etcd.tls: %w", err)
+	}
+	return &coordinator{
+		election:    etcd.NewElection(etcd.NewClient(cfg.Endpoints, tlsConfig), cfg.Key(), name, cfg.LeaseTTL),
+		name:        name,
+		grace:       cfg.UnreachableGrace,
+		leaseTTL:    cfg.LeaseTTL,
+		logger:      logger,
+		clock:       clk,
+		reachableAt: clk.Now(),
+	}, nil
+}
+
+// observe takes or keeps the key while selfActive, or while it is neither active nor passive and already held,
+// releases it once selfPassive and otherwise reads who holds it - returning the holder, and false when etcd
+// didn't answer
+func (c *coordinator) observe(ctx context.Context, selfActive bool, selfPassive bool) (string, bool) {
+	var holder string
+	var err error
+	switch {
+	case selfActive || (!selfPassive && c.election.Holding()):
+		var leaseExpired bool
+		holder, leaseExpired, err = c.election.Campaign(ctx)
+		if leaseExpired {
+			c.logger.Error("etcd lease expired while active - checking whether a peer holds the key", "key", c.election.Key())
+		}
+	case c.election.Holding():
+		// a passive must not keep peers waiting out our lease
+		if err = c.election.Resign(ctx); err == nil {
+			c.logger.Info("we are passive - released the etcd key", "key", c.election.Key())
+			holder, err = c.election.Leader(ctx)
+		}
+	default:
+		holder, err = c.election.Leader(ctx)
+	}
+
+	c.record(holder, err)
+	return holder, err == nil
+}
+
+// keepLeaseAlive renews the lease we hold every interval until ctx is done, so the key outlasts polls that overrun
+// and transitions the monitor loop is waiting on - unreachability is reported by the polls themselves
+func (c *coordinator) keepLeaseAlive(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !c.election.Holding() {
+				continue
+			}
+			leaseExpired, err := c.election.KeepAlive(ctx)
+			switch {
+			case err != nil:
+				c.logger.Debug("failed to keep the etcd lease alive", "key", c.election.Key(), "error", err)
+			case leaseExpired:
+				c.logger.Error("etcd lease expired - the next poll checks whether a peer holds the key", "key", c.election.Key())
+			}
+		}
+	}
+}
+
+// claim takes the key ahead of a takeover, or only reads who holds it when readOnly, returning the decision reason
+// the takeover is held back for - empty when it may go ahead, also once degraded to gossip alone
+func (c *coordinator) claim(ctx context.Context, readOnly bool) string {
+	var holder string
+	var err error
+	if readOnly {
+		holder, err = c.election.Leader(ctx)
+	} else {
+		holder, _, err = c.election.Campaign(ctx)
+	}
+	c.record(holder, err)
+
+	switch {
+	case err != nil && c.isDegraded():
+		c.logger.Error("etcd unreachable for longer than failover.coordination.etcd.unreachable_grace - taking over on gossip alone", "error", err)
+		return ""
+	case err != nil:
+		c.logger.Error("etcd unreachable - not taking over until it answers or failover.coordination.etcd.unreachable_grace has elapsed", "error", err, "unreachable_grace", c.grace)
+		return DecisionReasonCoordinationUnavailable
+	case holder != "" && holder != c.name:
+		c.logger.Warn(fmt.Sprintf("peer %s holds the etcd key - not taking over", holder), "key", c.election.Key())
+		return DecisionReasonCoordinationKeyHeld
+	}
+	return ""
+}
+
+// record records what etcd answered, degrading to gossip alone once it has been unreachable for longer than grace
+func (c *coordinator) record(holder string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if err != nil {
+		if !c.unreachable {
+			c.logger.Error("etcd unreachable", "key", c.election.Key(), "error", err, "unreachable_grace", c.grace)
+		}
+		c.unreachable = true
+		c.lastError = err.Error()
+		c.degraded = now.Sub(c.reachableAt) >= c.grace
+		return
+	}
+
+	if c.unreachable {
+		c.logger.Info("etcd reachable again", "key", c.election.Key(), "unreachable_for", now.Sub(c.reachableAt))
+	}
+	if holder != c.holder {
+		c.logger.Info("etcd key holder changed", "key", c.election.Key(), "holder", holder, "previous_holder", c.holder)
+	}
+	c.reachableAt = now
+	c.unreachable = false
+	c.degraded = false
+	c.holder = holder
+	c.lastError = ""
+}
+
+// isDegraded returns true once etcd has been unreachable for longer than grace
+func (c *coordinator) isDegraded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.degraded
+}
+
+// status returns what we last saw of the key, nil when not coordinating through etcd
+func (c *coordinator) status() *CoordinationStatus {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return &CoordinationStatus{
+		Backend:   "etcd",
+		Key:       c.election.Key(),
+		Holder:    c.holder,
+		Reachable: !c.unreachable,
+		Degraded:  c.degraded,
+		Error:     c.lastError,
+	}
+}
+
+// startLeaseKeepAlive keeps the lease of the failover.coordination.etcd key alive every third of its ttl until we
+// stop, apart from the monitor loop
+func (m *Manager) startLeaseKeepAlive() {
+	if m.coordinator == nil {
+		return
+	}
+	go m.coordinator.keepLeaseAlive(m.ctx, m.coordinator.leaseTTL/3)
+}
+
+// handleCoordination keeps the failover.coordination.etcd key while we are active, alarming once etcd has been
+// unreachable for longer than its unreachable_grace, and demotes us when a peer holds it while we are active - the
+// peer only took it taking over. Returns true when the cycle's decision was made here.
+func (m *Manager) handleCoordination(decision *Decision) bool {
+	if m.coordinator == nil {
+		return false
+	}
+
+	key := m.coordinator.election.Key()
+	selfActive := m.isSelfActive()
+	holder, ok := m.coordinator.observe(m.pollContext(), selfActive, !selfActive && m.isSelfPassive())
+	if !m.coordinator.isDegraded() {
+		m.coordinationAlarmed = false
+	} else {
+		message := "etcd unreachable for longer than failover.coordination.etcd.unreachable_grace - coordinating failovers by gossip alone"
+		m.logger.Error("‼️ "+message, "unreachable_grace", m.cfg.Failover.Coordination.Etcd.UnreachableGrace)
+		if !m.coordinationAlarmed {
+			m.coordinationAlarmed = true
+			m.recordEvent(events.TypeCoordinationDegraded, message, "key", key)
+			m.runAlertHooks(events.TypeCoordinationDegraded, message, map[string]string{
+				"key":       key,
+				"public_ip": m.peerSelf.IP,
+			})
+		}
+	}
+
+	if !ok || !selfActive || holder == "" || holder == m.peerSelf.Name {
+		return false
+	}
+
+	// never held back by failover.cooldown - the peer holding the key is or is becoming active
+	m.logger.Error(fmt.Sprintf("peer %s holds the etcd key while we are active - ensuring we are passive", holder), "key", key)
+	m.decide(decision, DecisionActionBecomePassive, DecisionReasonCoordinationKeyLost)
+	m.ensurePassive(DecisionReasonCoordinationKeyLost)
+	return true
+}
+
+// holdForCoordination claims the failover.coordination.etcd key ahead of a takeover within ctx, only reading it in
+// dry run or while paused, and decides on no action and returns true when the takeover is held back
+func (m *Manager) holdForCoordination(ctx context.Context, decision *Decision) bool {
+	if m.coordinator == nil {
+		return false
+	}
+
+	readOnly := m.cfg.Failover.DryRun || !m.pause.expiresAt().IsZero()
+	if reason := m.coordinator.claim(ctx, readOnly); reason != "" {
+		m.decide(decision, DecisionActionNone, reason)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package ha
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+)
+
+// fakeElection is an election whose key holder and reachability tests set
+type fakeElection struct {
+	name       string
+	holder     string
+	lease      bool
+	err        error
+	campaigns  int
+	resigns    int
+	keepAlives int
+}
+
+func (f *fakeElection) Key() string   { return "/svha/active" }
+func (f *fakeElection) Holding() bool { return f.lease }
+
+func (f *fakeElection) KeepAlive(ctx context.Context) (bool, error) {
+	f.keepAlives++
+	return false, f.err
+}
+
+func (f *fakeElection) Campaign(ctx context.Context) (string, bool, error) {
+	if f.err != nil {
+		return "", false, f.err
+	}
+	f.campaigns++
+	f.lease = true
+	if f.holder == "" {
+		f.holder = f.name
+	}
+	return f.holder, false, nil
+}
+
+func (f *fakeElection) Leader(ctx context.Context) (string, error) {
+	return f.holder, f.err
+}
+
+func (f *fakeElection) Resign(ctx context.Context) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.resigns++
+	f.lease = false
+	if f.holder == f.name {
+		f.holder = ""
+	}
+	return nil
+}
+
+// newCoordinationManager returns a dry run manager at 127.0.0.1, alone in gossip with the passive identity, coordinating
+// through a fake election
+func newCoordinationManager(t *testing.T) (*Manager, *fakeElection, *testutil.FakeRPC, *clock.Fake) {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Failover.LeaderlessSamplesThreshold = 1
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
+	cfg.Failover.Coordination.Etcd = &config.Etcd{Endpoints: []string{"http://127.0.0.1:1"}}
+	cfg.Failover.Coordination.SetDefaults()
+
+	fakeClock := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	clusterRPC := testutil.NewFakeRPC()
+	localRPC := testutil.NewFakeRPC()
+	localRPC.SetIdentity(cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: func() (string, error) { return "127.0.0.1", nil },
+		Clock:           fakeClock,
+		ClusterRPC:      clusterRPC,
+		LocalRPC:        localRPC,
+	})
+	require.NoError(t, manager.initialize())
+	require.NotNil(t, manager.coordinator)
+	election := &fakeElection{name: "test-validator"}
+	manager.coordinator.election = election
+	clusterRPC.SetClusterNodes(testutil.GossipNode(t, "127.0.0.1", cfg.Validator.Identities.PassiveKeyPair.PublicKey()))
+	clusterRPC.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(cfg.Validator.Identities.PassiveKeyPair.PublicKey())}, nil)
+
+	return manager, election, localRPC, fakeClock
+}
+
+func TestManager_EnsureHAState_CoordinationKeyHeld(t *testing.T) {
+	manager, election, _, _ := newCoordinationManager(t)
+
+	// the active peer is gone from gossip but its key has not expired
+	election.holder = "peer1"
+	manager.ensureHAState()
+	assert.Equal(t, DecisionActionNone, manager.decision.Action)
+	assert.Equal(t, DecisionReasonCoordinationKeyHeld, manager.decision.Reason)
+	assert.Equal(t, &CoordinationStatus{Backend: "etcd", Key: "/svha/active", Holder: "peer1", Reachable: true}, manager.status().Coordination)
+
+	// once it has the takeover goes ahead - only reading the key in dry run
+	election.holder = ""
+	manager.ensureHAState()
+	assert.Equal(t, DecisionActionBecomeActive, manager.decision.Action)
+	assert.Equal(t, DecisionReasonNoActivePeer, manager.decision.Reason)
+	assert.Zero(t, election.campaigns)
+}
+
+func TestManager_EnsureHAState_CoordinationUnreachable(t *testing.T) {
+	manager, election, _, fakeClock := newCoordinationManager(t)
+
+	// within unreachable_grace no takeover is made
+	election.err = errors.New("connection refused")
+	manager.ensureHAState()
+	assert.Equal(t, DecisionReasonCoordinationUnavailable, manager.decision.Reason)
+	status := manager.status().Coordination
+	assert.False(t, status.Reachable)
+	assert.False(t, status.Degraded)
+	assert.Equal(t, "connection refused", status.Error)
+	assert.NotContains(t, recordedTypes(manager), events.TypeCoordinationDegraded)
+
+	// past it failovers are coordinated by gossip alone, alarmed once
+	fakeClock.Advance(time.Minute)
+	manager.ensureHAState()
+	assert.Equal(t, DecisionActionBecomeActive, manager.decision.Action)
+	assert.Equal(t, DecisionReasonNoActivePeer, manager.decision.Reason)
+	assert.True(t, manager.status().Coordination.Degraded)
+	manager.ensureHAState()
+	degraded := 0
+	for _, eventType := range recordedTypes(manager) {
+		if eventType == events.TypeCoordinationDegraded {
+			degraded++
+		}
+	}
+	assert.Equal(t, 1, degraded)
+
+	// etcd answering again ends it
+	election.err = nil
+	election.holder = "peer1"
+	manager.ensureHAState()
+	assert.Equal(t, DecisionReasonCoordinationKeyHeld, manager.decision.Reason)
+	assert.False(t, manager.status().Coordination.Degraded)
+	assert.False(t, manager.coordinationAlarmed)
+}
+
+func TestManager_EnsureHAState_CoordinationKeyLost(t *testing.T) {
+	manager, election, localRPC, _ := newCoordinationManager(t)
+	localRPC.SetIdentity(manager.cfg.Validator.Identities.ActiveKeyPair.PublicKey())
+
+	// we keep the key while active
+	manager.ensureHAState()
+	assert.NotEqual(t, DecisionReasonCoordinationKeyLost, manager.decision.Reason)
+	assert.Equal(t, 1, election.campaigns)
+	assert.Equal(t, "test-validator", election.holder)
+
+	// a peer holding it has taken over
+	election.holder = "peer1"
+	manager.ensureHAState()
+	assert.Equal(t, DecisionActionBecomePassive, manager.decision.Action)
+	assert.Equal(t, DecisionReasonCoordinationKeyLost, manager.decision.Reason)
+}
+
+func TestCoordinator_Observe_ResignsWhenPassive(t *testing.T) {
+	manager, election, _, _ := newCoordinationManager(t)
+	election.lease = true
+	election.holder = "test-validator"
+
+	holder, ok := manager.coordinator.observe(context.Background(), false, true)
+	assert.True(t, ok)
+	assert.Empty(t, holder)
+	assert.Equal(t, 1, election.resigns)
+	assert.False(t, election.Holding())
+
+	// with an identity that is neither the lease is kept while held
+	election.lease = true
+	manager.coordinator.observe(context.Background(), false, false)
+	assert.Equal(t, 1, election.campaigns)
+}
+
+func TestCoordinator_KeepLeaseAlive(t *testing.T) {
+	manager, election, _, _ := newCoordinationManager(t)
+
+	// nothing is kept alive while we hold no lease
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	manager.coordinator.keepLeaseAlive(ctx, 5*time.Millisecond)
+	assert.Zero(t, election.keepAlives)
+
+	// a held lease is kept alive every interval without a poll
+	election.lease = true
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	manager.coordinator.keepLeaseAlive(ctx, 5*time.Millisecond)
+	assert.Greater(t, election.keepAlives, 1)
+	assert.Zero(t, election.campaigns)
+	assert.Equal(t, 15*time.Second, manager.coordinator.leaseTTL)
+}
+
+func TestCoordinator_Claim(t *testing.T) {
+	manager, election, _, _ := newCoordinationManager(t)
+
+	// a takeover out of dry run campaigns for the key
+	assert.Empty(t, manager.coordinator.claim(context.Background(), false))
+	assert.Equal(t, 1, election.campaigns)
+	assert.Equal(t, "test-validator", election.holder)
+
+	election.holder = "peer1"
+	assert.Equal(t, DecisionReasonCoordinationKeyHeld, manager.coordinator.claim(context.Background(), false))
+}
+
+func TestNewCoordinator_NotConfigured(t *testing.T) {
+	manager, _, _ := newFakeRPCManager(t, createTestConfig())
+	assert.Nil(t, manager.coordinator)
+	assert.Nil(t, manager.status().Coordination)
+}
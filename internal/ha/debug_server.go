@@ -0,0 +1,61 @@
+package ha
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+)
+
+const (
+	// pprofPath is where net/http/pprof is served on the debug server
+	pprofPath = "/debug/pprof/"
+	// expvarPath is where the expvars are served on the debug server with debug.expvar
+	expvarPath = "/debug/vars"
+)
+
+// debugHandler returns the debug server's handler - net/http/pprof, and the expvars with debug.expvar
+func (m *Manager) debugHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pprofPath, pprof.Index)
+	mux.HandleFunc(pprofPath+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(pprofPath+"profile", pprof.Profile)
+	mux.HandleFunc(pprofPath+"symbol", pprof.Symbol)
+	mux.HandleFunc(pprofPath+"trace", pprof.Trace)
+	if m.cfg.Debug.Expvar {
+		mux.Handle(expvarPath, expvar.Handler())
+	}
+	return mux
+}
+
+// startDebugServer serves the debug server on debug.pprof_port when set, a port that can't be bound is logged and
+// the agent carries on without it
+func (m *Manager) startDebugServer() {
+	if !m.cfg.Debug.Enabled() {
+		return
+	}
+
+	address := m.cfg.Debug.ListenAddress()
+	listener, err := m.listen("tcp", address)
+	if err != nil {
+		m.logger.Error("debug server error", "error", err)
+		return
+	}
+
+	debugServer := &http.Server{
+		Addr:    address,
+		Handler: m.debugHandler(),
+	}
+	m.debugServer = debugServer
+
+	go func() {
+		paths := []string{pprofPath}
+		if m.cfg.Debug.Expvar {
+			paths = append(paths, expvarPath)
+		}
+		m.logger.Warn("starting debug server - profiles expose the agent's memory, keep it off once done", "address", listener.Addr().String(), "paths", paths)
+
+		if err := debugServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			m.logger.Error("debug server error", "error", err)
+		}
+	}()
+}
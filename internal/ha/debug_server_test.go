@@ -0,0 +1,48 @@
+package ha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/debugvars"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugHandler(t *testing.T) {
+	manager := &Manager{cfg: &config.Config{Debug: config.Debug{PprofPort: 6060}}}
+
+	rec := httptest.NewRecorder()
+	manager.debugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine profile")
+
+	// the expvars are only served with debug.expvar
+	rec = httptest.NewRecorder()
+	manager.debugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	manager.cfg.Debug.Expvar = true
+	debugvars.ObservePollIteration(false)
+	debugvars.ObserveRPCRequest("getSlot", "rpc.example.com", false, 0, nil)
+
+	rec = httptest.NewRecorder()
+	manager.debugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var vars struct {
+		Agent struct {
+			PollIterations int64            `json:"poll_iterations"`
+			RPCCalls       map[string]int64 `json:"rpc_calls"`
+			Goroutines     int              `json:"goroutines"`
+		} `json:"solana_validator_ha"`
+		MemStats map[string]any `json:"memstats"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &vars))
+	assert.GreaterOrEqual(t, vars.Agent.PollIterations, int64(1))
+	assert.GreaterOrEqual(t, vars.Agent.RPCCalls["getSlot"], int64(1))
+	assert.Positive(t, vars.Agent.Goroutines)
+	assert.NotEmpty(t, vars.MemStats)
+}
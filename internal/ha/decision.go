@@ -0,0 +1,194 @@
+package ha
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/gates"
+)
+
+const (
+	// DecisionActionNone means the cycle ended without a role change
+	DecisionActionNone = "none"
+	// DecisionActionBecomeActive means the cycle ended by ensuring we are active
+	DecisionActionBecomeActive = "become_active"
+	// DecisionActionBecomePassive means the cycle ended by ensuring we are passive
+	DecisionActionBecomePassive = "become_passive"
+
+	// DecisionReasonActivePeerPresent - an active peer was seen within the leaderless samples threshold
+	DecisionReasonActivePeerPresent = "active_peer_present"
+	// DecisionReasonSelfNotInGossip - failover was required but we do not appear in gossip
+	DecisionReasonSelfNotInGossip = "self_not_in_gossip"
+	// DecisionReasonSelfUnhealthy - failover was required but we are not healthy
+	DecisionReasonSelfUnhealthy = "self_unhealthy"
+	// DecisionReasonSelfAlreadyActive - failover was required but we are already active
+	DecisionReasonSelfAlreadyActive = "self_already_active"
+	// DecisionReasonPeerTookOver - a peer became active while we were delaying takeover
+	DecisionReasonPeerTookOver = "peer_took_over"
+	// DecisionReasonShutdown - failover was required but the agent was stopped, or asked to shut down, before taking over
+	DecisionReasonShutdown = "shutdown"
+	// DecisionReasonGateBlocked - failover was required but an enforced safety gate did not pass
+	DecisionReasonGateBlocked = "safety_gate_blocked"
+	// DecisionReasonUnknownIdentity - the local validator reports neither the active nor a passive identity
+	DecisionReasonUnknownIdentity = "unknown_identity"
+	// DecisionReasonStartupGrace - failover was required but failover.startup_grace_period since startup has not elapsed
+	DecisionReasonStartupGrace = "startup_grace_period"
+	// DecisionReasonCooldown - failover was required but failover.cooldown since the last transition has not elapsed
+	DecisionReasonCooldown = "failover_cooldown"
+	// DecisionReasonPeerRPCActive - failover was required but a peer's own rpc reports the active identity
+	DecisionReasonPeerRPCActive = "peer_rpc_reports_active"
+	// DecisionReasonPeerHeartbeatActive - failover was required but a peer's heartbeat reports the active role
+	DecisionReasonPeerHeartbeatActive = "peer_heartbeat_reports_active"
+	// DecisionReasonSplitBrain - more than one node, possibly us, was seen with the active identity
+	DecisionReasonSplitBrain = "split_brain"
+	// DecisionReasonClusterRPCOutage - gossip could not be refreshed from the cluster rpc and failover.on_rpc_outage is hold
+	DecisionReasonClusterRPCOutage = "cluster_rpc_outage"
+	// DecisionReasonCoordinationKeyHeld - failover was required but a peer holds the failover.coordination.etcd key
+	DecisionReasonCoordinationKeyHeld = "coordination_key_held"
+	// DecisionReasonCoordinationUnavailable - failover was required but etcd is unreachable, within its unreachable_grace
+	DecisionReasonCoordinationUnavailable = "coordination_unavailable"
+	// DecisionReasonCoordinationKeyLost - we are active but a peer holds the failover.coordination.etcd key
+	DecisionReasonCoordinationKeyLost = "coordination_key_lost"
+	// DecisionReasonNoActivePeer - no active peer was found so we took over
+	DecisionReasonNoActivePeer = "no_active_peer"
+)
+
+// Decision is the outcome of a single HA monitor cycle and the inputs it was based on
+type Decision struct {
+	Time                       time.Time `json:"time"`
+	Action                     string    `json:"action"`
+	Reason                     string    `json:"reason"`
+	ActivePeerPresent          bool      `json:"active_peer_present"`
+	ActivePeerName             string    `json:"active_peer_name,omitempty"`
+	LeaderlessSamples          int       `json:"leaderless_samples"`
+	LeaderlessSamplesThreshold int       `json:"leaderless_samples_threshold"`
+	SelfInGossip               bool      `json:"self_in_gossip"`
+	PeersInGossip              int       `json:"peers_in_gossip"`
+	// PeersSeen are the names of the peers in gossip, ourselves included, ordered by name
+	PeersSeen []string `json:"peers_seen"`
+	DryRun    bool     `json:"dry_run"`
+	// CooldownRemainingSeconds is how long is left of failover.cooldown, 0 when not holding back transitions
+	CooldownRemainingSeconds float64 `json:"cooldown_remaining_seconds"`
+	// Gates are the safety gate results when they were checked before a promotion
+	Gates []gates.Result `json:"gates,omitempty"`
+	// SnapshotAt is when the gossip snapshot the decision was based on was taken
+	SnapshotAt clock.Instant `json:"snapshot_at"`
+	// DecidedAt is when the action was decided
+	DecidedAt clock.Instant `json:"decided_at"`
+	// DecisionLagSeconds is the time between SnapshotAt and DecidedAt
+	DecisionLagSeconds float64 `json:"decision_lag_seconds"`
+}
+
+// newDecision captures the current gossip state as the inputs of a decision
+func (m *Manager) newDecision() *Decision {
+	decision := &Decision{
+		Time:                       m.clock.Now().Time(),
+		Action:                     DecisionActionNone,
+		LeaderlessSamples:          m.gossipState.LeaderlessSamplesCount,
+		LeaderlessSamplesThreshold: m.cfg.Failover.LeaderlessSamplesThreshold,
+		SelfInGossip:               m.isSelfInGossip(),
+		DryRun:                     m.cfg.Failover.DryRun,
+		CooldownRemainingSeconds:   m.cooldown.remaining(m.clock.Now()).Seconds(),
+		PeersSeen:                  []string{},
+	}
+
+	// peer states only hold peers currently in gossip
+	peerStates := m.gossipState.GetPeerStates()
+	decision.PeersInGossip = len(peerStates)
+	for name := range peerStates {
+		decision.PeersSeen = append(decision.PeersSeen, name)
+	}
+	sort.Strings(decision.PeersSeen)
+
+	if activePeerState, err := m.gossipState.GetActivePeer(); err == nil {
+		decision.ActivePeerPresent = true
+		decision.ActivePeerName = activePeerState.Name
+	}
+
+	return decision
+}
+
+// decide sets the action and reason of the decision, measuring the lag since the gossip snapshot it was based on
+func (m *Manager) decide(decision *Decision, action string, reason string) {
+	decision.Action = action
+	decision.Reason = reason
+	decision.SnapshotAt = m.gossipState.PeerStatesRefreshedAt
+	decision.DecidedAt = m.clock.Now()
+
+	lag := decision.DecidedAt.Sub(decision.SnapshotAt)
+	decision.DecisionLagSeconds = lag.Seconds()
+	m.metrics.ObserveDecisionLag(lag)
+
+	// dry_run never transitions so record what we would have done as shadow evidence of the decision
+	if decision.DryRun && action != DecisionActionNone {
+		m.metrics.ObserveWouldHave(action, reason)
+		m.logger.Info("dry_run - would have acted on decision", "action", action, "reason", reason)
+	}
+
+	if m.cfg.Failover.DecisionLagWarnThreshold > 0 && lag > m.cfg.Failover.DecisionLagWarnThreshold {
+		m.logger.Warn("slow stage: gossip snapshot to decision took longer than failover.decision_lag_warn_threshold - the decision may be based on stale state",
+			"stage", "snapshot_to_decision",
+			"lag", lag,
+			"threshold", m.cfg.Failover.DecisionLagWarnThreshold,
+			"action", action,
+			"reason", reason,
+		)
+	}
+}
+
+// traceDecision logs the cycle's decision and the inputs it was based on as a single line, appending it as a JSON
+// record to failover.decision_log.path if set, so why a node did or didn't take over can be answered after the fact
+func (m *Manager) traceDecision(decision *Decision) {
+	m.logger.Info("decision",
+		"action", decision.Action,
+		"reason", decision.Reason,
+		"peers_seen", strings.Join(decision.PeersSeen, ","),
+		"active_peer_present", decision.ActivePeerPresent,
+		"active_peer", decision.ActivePeerName,
+		"leaderless_samples", fmt.Sprintf("%d/%d", decision.LeaderlessSamples, decision.LeaderlessSamplesThreshold),
+		"self_in_gossip", decision.SelfInGossip,
+		"dry_run", decision.DryRun,
+		"cooldown_remaining", time.Duration(decision.CooldownRemainingSeconds*float64(time.Second)).Round(time.Second),
+	)
+
+	if m.decisionLog == nil {
+		return
+	}
+	record, err := json.Marshal(decision)
+	if err == nil {
+		_, err = m.decisionLog.Write(append(record, '\n'))
+	}
+	if err != nil {
+		m.logger.Warn("failed to append decision to failover.decision_log.path", "path", m.decisionLog.Path(), "error", err)
+	}
+}
+
+// transitionLagFields measures the lag between the current cycle's decision and a transition starting now and
+// returns the decision and action lags as event fields, none if there is no decision to measure against
+func (m *Manager) transitionLagFields() []string {
+	if m.decision == nil || m.decision.DecidedAt.IsZero() {
+		return nil
+	}
+
+	lag := m.clock.Now().Sub(m.decision.DecidedAt)
+	m.metrics.ObserveActionLag(lag)
+
+	if m.cfg.Failover.ActionLagWarnThreshold > 0 && lag > m.cfg.Failover.ActionLagWarnThreshold {
+		m.logger.Warn("slow stage: decision to transition start took longer than failover.action_lag_warn_threshold",
+			"stage", "decision_to_transition",
+			"lag", lag,
+			"threshold", m.cfg.Failover.ActionLagWarnThreshold,
+			"action", m.decision.Action,
+		)
+	}
+
+	return []string{
+		"decision_lag_seconds", strconv.FormatFloat(m.decision.DecisionLagSeconds, 'f', 3, 64),
+		"action_lag_seconds", strconv.FormatFloat(lag.Seconds(), 'f', 3, 64),
+	}
+}
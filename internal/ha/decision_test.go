@@ -0,0 +1,205 @@
+package ha
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/rotatefile"
+)
+
+// newFakeClockManager returns an initialized manager whose clock is controlled by the returned fake
+func newFakeClockManager(t *testing.T) (*Manager, *clock.Fake) {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Failover.DecisionLagWarnThreshold = 2 * time.Second
+	cfg.Failover.ActionLagWarnThreshold = time.Second
+
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager := NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc, Clock: fake})
+	require.NoError(t, manager.initialize())
+	manager.gossipState.PeerStatesRefreshedAt = fake.Now()
+
+	return manager, fake
+}
+
+// histogramSampleCount returns the sample count and sum of a histogram metric
+func histogramSampleCount(t *testing.T, manager *Manager, name string) (uint64, float64) {
+	t.Helper()
+
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+
+	var family *dto.MetricFamily
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() == name {
+			family = metricFamily
+		}
+	}
+	if family == nil {
+		return 0, 0
+	}
+	require.Len(t, family.Metric, 1)
+	return family.Metric[0].Histogram.GetSampleCount(), family.Metric[0].Histogram.GetSampleSum()
+}
+
+func TestManager_Decide_MeasuresDecisionLag(t *testing.T) {
+	manager, now := newFakeClockManager(t)
+
+	decision := manager.newDecision()
+	now.Advance(3 * time.Second)
+	manager.decide(decision, DecisionActionNone, DecisionReasonActivePeerPresent)
+
+	assert.Equal(t, DecisionActionNone, decision.Action)
+	assert.Equal(t, DecisionReasonActivePeerPresent, decision.Reason)
+	assert.Equal(t, manager.gossipState.PeerStatesRefreshedAt, decision.SnapshotAt)
+	assert.Equal(t, now.Now(), decision.DecidedAt)
+	assert.Equal(t, 3.0, decision.DecisionLagSeconds)
+
+	count, sum := histogramSampleCount(t, manager, "solana_validator_ha_decision_lag_seconds")
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 3.0, sum)
+}
+
+func TestManager_Decide_ClockStepDoesNotSkewLag(t *testing.T) {
+	manager, now := newFakeClockManager(t)
+
+	// the wall clock steps back a minute mid cycle - the lag is what elapsed, the decision time is the new wall time
+	decision := manager.newDecision()
+	now.Advance(time.Second)
+	now.Step(-time.Minute)
+	manager.decide(decision, DecisionActionNone, DecisionReasonActivePeerPresent)
+
+	assert.Equal(t, 1.0, decision.DecisionLagSeconds)
+	assert.True(t, decision.DecidedAt.Time().Before(decision.SnapshotAt.Time()))
+
+	count, sum := histogramSampleCount(t, manager, "solana_validator_ha_decision_lag_seconds")
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 1.0, sum)
+}
+
+func TestManager_TransitionLagFields(t *testing.T) {
+	manager, now := newFakeClockManager(t)
+
+	// no decision this cycle - nothing to measure
+	assert.Nil(t, manager.transitionLagFields())
+
+	manager.decision = manager.newDecision()
+	now.Advance(500 * time.Millisecond)
+	manager.decide(manager.decision, DecisionActionBecomeActive, DecisionReasonNoActivePeer)
+	now.Advance(1500 * time.Millisecond)
+
+	assert.Equal(t, []string{
+		"decision_lag_seconds", "0.500",
+		"action_lag_seconds", "1.500",
+	}, manager.transitionLagFields())
+
+	count, sum := histogramSampleCount(t, manager, "solana_validator_ha_action_lag_seconds")
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 1.5, sum)
+}
+
+func TestManager_EnsureActive_RecordsLagsInEvent(t *testing.T) {
+	manager, now := newFakeClockManager(t)
+
+	var err error
+	manager.events, err = events.New(events.Options{})
+	require.NoError(t, err)
+
+	manager.decision = manager.newDecision()
+	now.Advance(250 * time.Millisecond)
+	manager.decide(manager.decision, DecisionActionBecomeActive, DecisionReasonNoActivePeer)
+	now.Advance(time.Second)
+
+	manager.ensureActive(DecisionReasonNoActivePeer)
+
+	recorded := manager.events.Events()
+	require.NotEmpty(t, recorded)
+	assert.Equal(t, events.TypeBecomingActive, recorded[0].Type)
+	assert.Equal(t, "0.250", recorded[0].Fields["decision_lag_seconds"])
+	assert.Equal(t, "1.000", recorded[0].Fields["action_lag_seconds"])
+}
+
+func TestManager_Decide_RecordsWouldHaveWhenDryRun(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+	manager.cfg.Failover.DryRun = true
+
+	// no action - nothing would have happened
+	manager.decide(manager.newDecision(), DecisionActionNone, DecisionReasonActivePeerPresent)
+	manager.decide(manager.newDecision(), DecisionActionBecomeActive, DecisionReasonNoActivePeer)
+
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+
+	var family *dto.MetricFamily
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() == "solana_validator_ha_would_have_total" {
+			family = metricFamily
+		}
+	}
+	require.NotNil(t, family)
+	require.Len(t, family.GetMetric(), 1)
+
+	labels := map[string]string{}
+	for _, label := range family.GetMetric()[0].GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	assert.Equal(t, DecisionActionBecomeActive, labels["action"])
+	assert.Equal(t, DecisionReasonNoActivePeer, labels["reason"])
+	assert.Equal(t, 1.0, family.GetMetric()[0].GetCounter().GetValue())
+}
+
+func TestManager_Decide_NoWouldHaveWhenLive(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+	manager.cfg.Failover.DryRun = false
+
+	manager.decide(manager.newDecision(), DecisionActionBecomeActive, DecisionReasonNoActivePeer)
+
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		assert.NotEqual(t, "solana_validator_ha_would_have_total", metricFamily.GetName())
+	}
+}
+
+func TestManager_TraceDecision_AppendsToDecisionLog(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	manager.cfg.Failover.Cooldown = time.Hour
+	manager.cooldown = failoverCooldown{duration: time.Hour}
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	decisionLog, err := rotatefile.Open(rotatefile.Options{Path: path, MaxBytes: 1024 * 1024})
+	require.NoError(t, err)
+	manager.decisionLog = decisionLog
+
+	// takes over, then sits out the cooldown it started
+	manager.ensureHAState()
+	manager.ensureHAState()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var first, second Decision
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, DecisionActionBecomeActive, first.Action)
+	assert.Equal(t, DecisionReasonNoActivePeer, first.Reason)
+	assert.Equal(t, []string{"test-validator"}, first.PeersSeen)
+	assert.False(t, first.ActivePeerPresent)
+	assert.Equal(t, 1, first.LeaderlessSamplesThreshold)
+	assert.True(t, first.SelfInGossip)
+	assert.Zero(t, first.CooldownRemainingSeconds)
+	assert.Equal(t, DecisionReasonSelfAlreadyActive, second.Reason)
+	assert.Equal(t, time.Hour.Seconds(), second.CooldownRemainingSeconds)
+}
@@ -0,0 +1,243 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+)
+
+const (
+	// demotionWatchAlarmSamples is how many consecutive samples must see the active identity's votes advance
+	// from our IP before alarming - one advancing sample can be votes that landed just before the demotion
+	demotionWatchAlarmSamples = 2
+
+	// alertEnvVar is the name of the alarm passed to alert hooks
+	alertEnvVar = "SVHA_ALERT"
+	// alertMessageEnvVar is the alarm's human readable message
+	alertMessageEnvVar = "SVHA_ALERT_MESSAGE"
+	// alertFieldsEnvVar is the alarm's fields as a JSON object
+	alertFieldsEnvVar = "SVHA_ALERT_FIELDS"
+)
+
+// demotionWatch is the state of one post demotion watch, only touched by the goroutine running it
+type demotionWatch struct {
+	activePubkey solana.PublicKey
+	deadline     clock.Instant
+	// lastVote is the active identity's last vote at the previous sample, known once it has been seen
+	lastVote      uint64
+	lastVoteKnown bool
+	// selfVotingSamples is the number of consecutive samples the active identity voted from our IP
+	selfVotingSamples int
+}
+
+// startDemotionWatch watches the active identity's votes for failover.post_demotion_watch after we demoted,
+// alarming if they keep advancing from this host - the validator may still be signing with the active identity
+// while another node takes over. Any previous watch is stopped.
+func (m *Manager) startDemotionWatch() {
+	if m.cfg.Failover.PostDemotionWatch <= 0 {
+		return
+	}
+	m.stopDemotionWatch()
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.cancelDemotionWatch = cancel
+
+	watch := m.newDemotionWatch()
+	m.logger.Info("watching the active identity's votes after demotion",
+		"active_pubkey", watch.activePubkey.String(),
+		"post_demotion_watch", m.cfg.Failover.PostDemotionWatch,
+	)
+
+	go func() {
+		ticker := time.NewTicker(m.cfg.Failover.PollIntervalDuration)
+		defer ticker.Stop()
+		for {
+			if m.sampleDemotionWatch(watch) {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopDemotionWatch stops any running post demotion watch - we now vote with the active identity legitimately
+func (m *Manager) stopDemotionWatch() {
+	if m.cancelDemotionWatch != nil {
+		m.cancelDemotionWatch()
+		m.cancelDemotionWatch = nil
+	}
+}
+
+// newDemotionWatch returns a watch of the active identity ending failover.post_demotion_watch from now
+func (m *Manager) newDemotionWatch() *demotionWatch {
+	return &demotionWatch{
+		activePubkey: m.cfg.Validator.Identities.ActiveKeyPair.PublicKey(),
+		deadline:     m.clock.Now().Add(m.cfg.Failover.PostDemotionWatch),
+	}
+}
+
+// sampleDemotionWatch takes one sample of the watch, returning true when the watch is over. Votes advancing are
+// only alarming while gossip attributes the active identity to our IP and local rpc says we are passive - the
+// new active peer voting is told apart by its gossip IP.
+func (m *Manager) sampleDemotionWatch(watch *demotionWatch) (done bool) {
+	if !m.clock.Now().Before(watch.deadline) {
+		m.logger.Info("post demotion watch finished - the active identity did not vote from this host", "active_pubkey", watch.activePubkey.String())
+		return true
+	}
+
+	lastVote, ok := m.activeIdentityLastVote(watch.activePubkey)
+	if !ok {
+		watch.selfVotingSamples = 0
+		return false
+	}
+	advanced := watch.lastVoteKnown && lastVote > watch.lastVote
+	watch.lastVote = lastVote
+	watch.lastVoteKnown = true
+	if !advanced {
+		watch.selfVotingSamples = 0
+		return false
+	}
+
+	gossipIP, ok := m.activeIdentityGossipIP(watch.activePubkey)
+	if !ok || gossipIP != m.selfGossipIP() {
+		m.logger.Debug("active identity voting from a peer after demotion", "active_pubkey", watch.activePubkey.String(), "gossip_ip", gossipIP, "last_vote", lastVote)
+		watch.selfVotingSamples = 0
+		return false
+	}
+
+	// we only claim to be the cause if local rpc says we are passive - if we are active again the demotion didn't stick
+	identity, err := m.localRPC.GetIdentity(m.ctx)
+	if err != nil {
+		m.logger.Warn("failed to get local identity for post demotion watch", "error", err)
+		return false
+	}
+	localPubkey := identity.Identity.String()
+	if !m.cfg.Validator.Identities.IsPassivePubkey(localPubkey) {
+		watch.selfVotingSamples = 0
+		return false
+	}
+
+	watch.selfVotingSamples++
+	m.logger.Warn("active identity voted from this host after demotion",
+		"active_pubkey", watch.activePubkey.String(),
+		"local_pubkey", localPubkey,
+		"last_vote", lastVote,
+		"samples", watch.selfVotingSamples,
+	)
+	if watch.selfVotingSamples < demotionWatchAlarmSamples {
+		return false
+	}
+
+	m.alarmPossibleDuplicateSigning(watch.activePubkey.String(), localPubkey, lastVote)
+	return true
+}
+
+// activeIdentityLastVote returns the last vote of the active identity's vote account, current or delinquent
+func (m *Manager) activeIdentityLastVote(activePubkey solana.PublicKey) (uint64, bool) {
+	voteAccounts, err := m.clusterRPC.GetVoteAccounts(m.ctx)
+	if err != nil {
+		m.logger.Warn("failed to get vote accounts for post demotion watch", "error", err)
+		return 0, false
+	}
+
+	for _, voteAccount := range append(voteAccounts.Current, voteAccounts.Delinquent...) {
+		if voteAccount.NodePubkey.Equals(activePubkey) {
+			return voteAccount.LastVote, true
+		}
+	}
+	m.logger.Debug("active identity has no vote account", "active_pubkey", activePubkey.String())
+	return 0, false
+}
+
+// activeIdentityGossipIP returns the IP gossip attributes the active identity to
+func (m *Manager) activeIdentityGossipIP(activePubkey solana.PublicKey) (string, bool) {
+	nodes, err := m.clusterRPC.GetClusterNodes(m.ctx)
+	if err != nil {
+		m.logger.Warn("failed to get cluster nodes for post demotion watch", "error", err)
+		return "", false
+	}
+
+	for _, node := range nodes {
+		if node.Pubkey.Equals(activePubkey) && node.Gossip != nil {
+			return gossip.AddressIP(*node.Gossip), true
+		}
+	}
+	return "", false
+}
+
+// alarmPossibleDuplicateSigning raises the critical alarm that the active identity is still voting from this host
+func (m *Manager) alarmPossibleDuplicateSigning(activePubkey string, localPubkey string, lastVote uint64) {
+	message := "active identity still voting from this host after demotion - possible duplicate signing"
+	fields := map[string]string{
+		"active_pubkey": activePubkey,
+		"local_pubkey":  localPubkey,
+		"public_ip":     m.peerSelf.IP,
+		"last_vote":     strconv.FormatUint(lastVote, 10),
+	}
+
+	m.logger.Error("‼️ "+message+" - check the validator on this host NOW",
+		"active_pubkey", activePubkey,
+		"local_pubkey", localPubkey,
+		"public_ip", m.peerSelf.IP,
+		"last_vote", lastVote,
+	)
+	m.recordEvent(events.TypePossibleDuplicateSigning, message,
+		"active_pubkey", activePubkey,
+		"local_pubkey", localPubkey,
+		"public_ip", m.peerSelf.IP,
+		"last_vote", fields["last_vote"],
+	)
+	m.metrics.ObservePossibleDuplicateSigning()
+	m.runAlertHooks(events.TypePossibleDuplicateSigning, message, fields)
+}
+
+// runAlertHooks runs every failover.alert_hooks hook for the alert - they are observational so run regardless
+// of failover.dry_run and a failing hook doesn't stop the others
+func (m *Manager) runAlertHooks(alert string, message string, fields map[string]string) {
+	if len(m.cfg.Failover.AlertHooks) == 0 {
+		return
+	}
+
+	fieldsJSON, err := json.Marshal(fields)
+	if err != nil {
+		m.logger.Warn("failed to serialize alert fields", "error", err)
+	}
+	env := map[string]string{
+		alertEnvVar:        alert,
+		alertMessageEnvVar: message,
+		alertFieldsEnvVar:  string(fieldsJSON),
+	}
+
+	loggerArgs := []any{
+		"hook_type", constants.HookTypeAlert,
+		"alert", alert,
+	}
+	for _, hook := range m.cfg.Failover.AlertHooks {
+		result, err := hook.Run(config.HookRunOptions{
+			HookType:     constants.HookTypeAlert,
+			Env:          env,
+			DryRun:       false,
+			LoggerPrefix: m.logPrefix,
+			LoggerArgs:   loggerArgs,
+			Audit:        m.auditLog,
+		})
+		// alerts are raised by the post demotion watch so always while passive
+		m.metrics.ObserveHook(hook.Name, constants.HookTypeAlert, constants.RolePassive.String(), result.DryRun, result.Duration, err != nil)
+		if err != nil {
+			m.logger.Error("hook failed", append(loggerArgs, "hook_name", hook.Name, "error", err)...)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package ha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+)
+
+// votingClusterServer is a fake cluster rpc where pubkey is in gossip at gossipIP and its last vote advances
+// by one on every getVoteAccounts call
+func votingClusterServer(t *testing.T, pubkey solana.PublicKey, gossipIP string) *httptest.Server {
+	t.Helper()
+
+	var lastVote atomic.Uint64
+	lastVote.Store(100)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     any    `json:"id"`
+			Method string `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		var result any
+		switch request.Method {
+		case "getClusterNodes":
+			result = []map[string]any{{"pubkey": pubkey.String(), "gossip": gossipIP + ":8001"}}
+		case "getVoteAccounts":
+			result = map[string]any{
+				"current": []map[string]any{{
+					"votePubkey":       solana.NewWallet().PublicKey().String(),
+					"nodePubkey":       pubkey.String(),
+					"activatedStake":   1,
+					"epochVoteAccount": true,
+					"commission":       0,
+					"lastVote":         lastVote.Add(1),
+					"rootSlot":         68,
+					"epochCredits":     [][]uint64{},
+				}},
+				"delinquent": []map[string]any{},
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// demotionWatchHarness is a manager that has just demoted, watching the active identity vote from gossipIP
+type demotionWatchHarness struct {
+	manager  *Manager
+	clock    *clock.Fake
+	identity *atomic.Value
+	alertLog string
+}
+
+func newDemotionWatchHarness(t *testing.T, gossipIP string) *demotionWatchHarness {
+	t.Helper()
+
+	h := &demotionWatchHarness{
+		clock:    clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+		identity: &atomic.Value{},
+		alertLog: filepath.Join(t.TempDir(), "alert.log"),
+	}
+
+	cfg := createTestConfig()
+	cfg.Cluster.RPCURLs = []string{votingClusterServer(t, cfg.Validator.Identities.ActiveKeyPair.PublicKey(), gossipIP).URL}
+	cfg.Validator.RPCURL = identityServer(t, h.identity).URL
+	cfg.Failover.PostDemotionWatch = 5 * time.Minute
+	cfg.Failover.AlertHooks = []config.Hook{{
+		Name:    "page-oncall",
+		Command: "sh",
+		Args:    []string{"-c", `echo "$SVHA_ALERT $SVHA_ALERT_FIELDS" >> ` + h.alertLog},
+	}}
+	h.identity.Store(cfg.Validator.Identities.PassiveKeyPair.PublicKey().String())
+
+	h.manager = NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc, Clock: h.clock})
+	require.NoError(t, h.manager.initialize())
+
+	var err error
+	h.manager.events, err = events.New(events.Options{})
+	require.NoError(t, err)
+
+	return h
+}
+
+// alerts returns the alert hook log lines
+func (h *demotionWatchHarness) alerts(t *testing.T) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(h.alertLog)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// duplicateSigningAlarms returns the possible_duplicate_signing_total value
+func duplicateSigningAlarms(t *testing.T, manager *Manager) float64 {
+	t.Helper()
+
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() == "solana_validator_ha_possible_duplicate_signing_total" {
+			return metricFamily.Metric[0].Counter.GetValue()
+		}
+	}
+	return 0
+}
+
+func TestManager_SampleDemotionWatch_NewActivePeerVotingIsBenign(t *testing.T) {
+	// the new active peer votes with the active identity from its own IP
+	h := newDemotionWatchHarness(t, "192.168.1.101")
+	watch := h.manager.newDemotionWatch()
+
+	for i := 0; i < 5; i++ {
+		assert.False(t, h.manager.sampleDemotionWatch(watch))
+		h.clock.Advance(h.manager.cfg.Failover.PollIntervalDuration)
+	}
+	assert.Equal(t, 0, watch.selfVotingSamples)
+
+	// the watch ends at failover.post_demotion_watch
+	h.clock.Advance(h.manager.cfg.Failover.PostDemotionWatch)
+	assert.True(t, h.manager.sampleDemotionWatch(watch))
+
+	assert.Nil(t, h.alerts(t))
+	assert.Equal(t, float64(0), duplicateSigningAlarms(t, h.manager))
+	for _, event := range h.manager.events.Events() {
+		assert.NotEqual(t, events.TypePossibleDuplicateSigning, event.Type)
+	}
+}
+
+func TestManager_SampleDemotionWatch_SelfVotingAlarms(t *testing.T) {
+	// gossip still attributes the active identity to our IP while local rpc says we are passive
+	h := newDemotionWatchHarness(t, "192.168.1.100")
+	watch := h.manager.newDemotionWatch()
+
+	// the first sample only learns the last vote, the second sees it advance once
+	assert.False(t, h.manager.sampleDemotionWatch(watch))
+	assert.False(t, h.manager.sampleDemotionWatch(watch))
+	assert.Equal(t, 1, watch.selfVotingSamples)
+	assert.Equal(t, float64(0), duplicateSigningAlarms(t, h.manager))
+
+	// advancing again alarms and ends the watch
+	assert.True(t, h.manager.sampleDemotionWatch(watch))
+	assert.Equal(t, float64(1), duplicateSigningAlarms(t, h.manager))
+
+	recorded := h.manager.events.Events()
+	require.NotEmpty(t, recorded)
+	last := recorded[len(recorded)-1]
+	assert.Equal(t, events.TypePossibleDuplicateSigning, last.Type)
+	assert.Equal(t, h.manager.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String(), last.Fields["active_pubkey"])
+	assert.Equal(t, "192.168.1.100", last.Fields["public_ip"])
+
+	alerts := h.alerts(t)
+	require.Len(t, alerts, 1)
+	assert.True(t, strings.HasPrefix(alerts[0], "possible_duplicate_signing "), alerts[0])
+	assert.Contains(t, alerts[0], `"last_vote":"`+last.Fields["last_vote"]+`"`)
+}
+
+func TestManager_SampleDemotionWatch_LocalActiveIsNotDuplicateSigning(t *testing.T) {
+	// local rpc reports the active identity - the demotion did not stick, which ensurePassive already reported
+	h := newDemotionWatchHarness(t, "192.168.1.100")
+	h.identity.Store(h.manager.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String())
+	watch := h.manager.newDemotionWatch()
+
+	for i := 0; i < 5; i++ {
+		assert.False(t, h.manager.sampleDemotionWatch(watch))
+	}
+	assert.Equal(t, float64(0), duplicateSigningAlarms(t, h.manager))
+	assert.Nil(t, h.alerts(t))
+}
+
+func TestManager_StartDemotionWatch_Disabled(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+	manager.cfg.Failover.PostDemotionWatch = -1
+
+	manager.startDemotionWatch()
+	assert.Nil(t, manager.cancelDemotionWatch)
+
+	manager.cfg.Failover.PostDemotionWatch = time.Minute
+	manager.startDemotionWatch()
+	assert.NotNil(t, manager.cancelDemotionWatch)
+	manager.stopDemotionWatch()
+	assert.Nil(t, manager.cancelDemotionWatch)
+}
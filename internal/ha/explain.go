@@ -0,0 +1,299 @@
+package ha
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+)
+
+const (
+	// GateResultPass means the gate let the cycle continue
+	GateResultPass = "pass"
+	// GateResultFail means the gate ended the cycle with a decision or, for the leaderless gate, required a failover
+	GateResultFail = "fail"
+	// GateResultSkipped means the gate can't be evaluated remotely
+	GateResultSkipped = "skipped"
+	// GateResultNotReached means an earlier gate decided the cycle
+	GateResultNotReached = "not_reached"
+
+	// GateActivePeerPresent - an active peer is in gossip
+	GateActivePeerPresent = "active_peer_present"
+	// GateSelfInGossip - we appear in gossip
+	GateSelfInGossip = "self_in_gossip"
+	// GateSelfHealthy - the local validator reports healthy
+	GateSelfHealthy = "self_healthy"
+	// GateSelfNotActive - the local validator is not already running the active identity
+	GateSelfNotActive = "self_not_active"
+	// GateSafetyGates - the configured safety gates pass, or are not enforced
+	GateSafetyGates = "safety_gates"
+	// GateTakeoverRank - our claim order among the peers
+	GateTakeoverRank = "takeover_rank"
+	// GateNoPeerTookOver - no peer became active during our takeover delay
+	GateNoPeerTookOver = "no_peer_took_over"
+	// GatePeerRPCNotActive - no peer's own rpc reports the active identity, when failover.confirm_with_peer_rpc is on
+	GatePeerRPCNotActive = "peer_rpc_not_active"
+	// GatePeerHeartbeatNotActive - no peer's heartbeat reports the active role, when
+	// failover.require_heartbeat_confirmation is on
+	GatePeerHeartbeatNotActive = "peer_heartbeat_not_active"
+
+	// skippedRequiresLocalAccess is the detail of gates that need the local validator rpc
+	skippedRequiresLocalAccess = "skipped: requires local access"
+)
+
+// Gate is one check of the monitor cycle and the values that fed it
+type Gate struct {
+	Name   string            `json:"name"`
+	Result string            `json:"result"`
+	Detail string            `json:"detail,omitempty"`
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// Explanation is what the agent would decide for a validator right now and the gates that led there
+type Explanation struct {
+	ValidatorName string   `json:"validator_name"`
+	PublicIP      string   `json:"public_ip"`
+	Decision      Decision `json:"decision"`
+	Gates         []Gate   `json:"gates"`
+}
+
+// WouldTransition returns true if the decision would become active or passive
+func (e *Explanation) WouldTransition() bool {
+	return e.Decision.Action != DecisionActionNone
+}
+
+// ExplainOptions are the inputs to a one-off decision explanation
+type ExplainOptions struct {
+	Cfg *config.Config
+	// ClusterRPC is only used for read-only calls
+	ClusterRPC rpc.SolanaClient
+	// PublicIP is the public IP of the validator being explained
+	PublicIP string
+	// Clock defaults to clock.System
+	Clock clock.Clock
+	// NewPeerRPC returns the client for a peer's rpc_url, defaults to rpc.NewClient
+	NewPeerRPC func(logPrefix string, url string) rpc.SolanaClient
+	// FetchHeartbeat fetches a peer's heartbeat, defaults to gossip.NewHeartbeatFetcher
+	FetchHeartbeat gossip.FetchHeartbeatFunc
+}
+
+// Explain takes one gossip snapshot and evaluates the monitor cycle gates against it as the agent on
+// PublicIP would, without running any command or hook. Gates needing the local validator rpc are skipped
+// and assumed to pass, and a single snapshot is treated as having reached the leaderless samples threshold
+// so the rest of the cycle can be shown.
+func Explain(opts ExplainOptions) (*Explanation, error) {
+	clk := opts.Clock
+	if clk == nil {
+		clk = clock.System
+	}
+	// however --public-ip was written, e.g. a bracketed or uncompressed IPv6 address
+	opts.PublicIP = gossip.AddressIP(opts.PublicIP)
+
+	if opts.FetchHeartbeat == nil {
+		opts.FetchHeartbeat = gossip.NewHeartbeatFetcher(opts.Cfg)
+	}
+
+	// the same peer set the agent would use - never mutate the loaded config
+	if opts.Cfg.Failover.Peers.HasIP(opts.PublicIP) {
+		return nil, fmt.Errorf("failover.peers must not reference ourselves, found %s in failover.peers", opts.PublicIP)
+	}
+	peers := config.Peers{}
+	for name, peer := range opts.Cfg.Failover.Peers {
+		peers[name] = peer
+	}
+	peers.Add(opts.Cfg.Validator.SelfPeer(opts.PublicIP))
+
+	gossipState := gossip.NewState(gossip.Options{
+		ClusterRPC:   opts.ClusterRPC,
+		ActivePubkey: opts.Cfg.Validator.Identities.ActiveKeyPair.PublicKey().String(),
+		SelfIP:       opts.PublicIP,
+		ConfigPeers:  peers,
+		LogPrefix:    opts.Cfg.Validator.Name,
+		Clock:        clk,
+		NewPeerRPC:   opts.NewPeerRPC,
+
+		FetchHeartbeat: opts.FetchHeartbeat,
+	})
+	gossipState.Refresh()
+
+	explanation := &Explanation{
+		ValidatorName: opts.Cfg.Validator.Name,
+		PublicIP:      opts.PublicIP,
+		Decision: Decision{
+			Time:                       clk.Now().Time(),
+			Action:                     DecisionActionNone,
+			LeaderlessSamples:          gossipState.LeaderlessSamplesCount,
+			LeaderlessSamplesThreshold: opts.Cfg.Failover.LeaderlessSamplesThreshold,
+			SelfInGossip:               gossipState.HasIP(opts.PublicIP),
+			PeersInGossip:              len(gossipState.GetPeerStates()),
+			DryRun:                     opts.Cfg.Failover.DryRun,
+		},
+	}
+	decision := &explanation.Decision
+
+	decided := false
+	decide := func(action string, reason string) {
+		decided = true
+		decision.Action = action
+		decision.Reason = reason
+		decision.SnapshotAt = gossipState.PeerStatesRefreshedAt
+		decision.DecidedAt = clk.Now()
+		decision.DecisionLagSeconds = decision.DecidedAt.Sub(decision.SnapshotAt).Seconds()
+	}
+	gate := func(g Gate) {
+		if decided {
+			g.Result, g.Detail = GateResultNotReached, ""
+		}
+		explanation.Gates = append(explanation.Gates, g)
+	}
+
+	// active peer in gossip - the agent needs failover.leaderless_samples_threshold samples without one to fail over
+	activePeer := Gate{
+		Name:   GateActivePeerPresent,
+		Result: GateResultFail,
+		Values: map[string]string{
+			"peers_in_gossip":              strconv.Itoa(decision.PeersInGossip),
+			"leaderless_samples":           strconv.Itoa(decision.LeaderlessSamples),
+			"leaderless_samples_threshold": strconv.Itoa(decision.LeaderlessSamplesThreshold),
+		},
+		Detail: fmt.Sprintf("no active peer in this snapshot - the agent fails over after %d consecutive leaderless samples, evaluating as if reached",
+			decision.LeaderlessSamplesThreshold),
+	}
+	if activePeerState, err := gossipState.GetActivePeer(); err == nil {
+		decision.ActivePeerPresent = true
+		decision.ActivePeerName = activePeerState.Name
+		activePeer.Result, activePeer.Detail = GateResultPass, ""
+		activePeer.Values["active_peer_name"] = activePeerState.Name
+		activePeer.Values["active_peer_ip"] = activePeerState.IP
+		activePeer.Values["active_peer_pubkey"] = activePeerState.Pubkey
+	}
+	gate(activePeer)
+	if decision.ActivePeerPresent {
+		decide(DecisionActionNone, DecisionReasonActivePeerPresent)
+	}
+
+	// self in gossip - not appearing means we'd ensure we are passive
+	selfInGossip := Gate{
+		Name:   GateSelfInGossip,
+		Result: GateResultPass,
+		Values: map[string]string{"public_ip": opts.PublicIP},
+	}
+	for _, peerState := range gossipState.GetPeerStates() {
+		if peerState.IP == opts.PublicIP {
+			selfInGossip.Values["gossip_pubkey"] = peerState.Pubkey
+		}
+	}
+	if !decision.SelfInGossip {
+		selfInGossip.Result = GateResultFail
+		selfInGossip.Detail = "we do not appear in gossip - the agent would ensure it is passive"
+	}
+	gate(selfInGossip)
+	if !decided && !decision.SelfInGossip {
+		decide(DecisionActionBecomePassive, DecisionReasonSelfNotInGossip)
+	}
+
+	// local rpc gates are assumed to pass
+	gate(Gate{
+		Name:   GateSelfHealthy,
+		Result: GateResultSkipped,
+		Detail: skippedRequiresLocalAccess,
+		Values: map[string]string{"validator_rpc_url": opts.Cfg.Validator.RPCURL},
+	})
+	gate(Gate{
+		Name:   GateSelfNotActive,
+		Result: GateResultSkipped,
+		Detail: skippedRequiresLocalAccess,
+		Values: map[string]string{"validator_rpc_url": opts.Cfg.Validator.RPCURL},
+	})
+
+	// safety gates measure the local validator, only their modes can be shown
+	safetyGates := Gate{
+		Name:   GateSafetyGates,
+		Result: GateResultSkipped,
+		Detail: skippedRequiresLocalAccess,
+		Values: map[string]string{},
+	}
+	for name, mode := range opts.Cfg.Gates.Modes() {
+		safetyGates.Values[name] = mode.Effective().String()
+	}
+	// the vote account gate is only checked with validator.vote_account set
+	if opts.Cfg.Validator.VoteAccount == "" {
+		delete(safetyGates.Values, config.GateVoteAccount)
+	}
+	gate(safetyGates)
+
+	// claim order - fitness arbitration needs our own fitness so only the static rank is shown
+	takeoverRank := Gate{
+		Name:   GateTakeoverRank,
+		Result: GateResultPass,
+		Values: map[string]string{
+			"static_rank": strconv.Itoa(peers.GetRankedIPs()[opts.PublicIP]),
+			"peers":       strconv.Itoa(len(peers)),
+		},
+	}
+	if peers.HasPriorities() {
+		takeoverRank.Values["priority_rank"] = strconv.Itoa(priorityRank(peers, gossipState.GetPeerStates(), opts.Cfg.Validator.Name))
+	}
+	if opts.Cfg.Fitness.Enabled {
+		takeoverRank.Detail = "fitness arbitration " + skippedRequiresLocalAccess
+	}
+	gate(takeoverRank)
+
+	// the agent refreshes gossip after its takeover delay, a single snapshot can't show that
+	gate(Gate{
+		Name:   GateNoPeerTookOver,
+		Result: GateResultSkipped,
+		Detail: "skipped: re-checked by the agent after its takeover delay",
+	})
+
+	// peers' own rpc - only consulted when failover.confirm_with_peer_rpc is on
+	peerRPC := Gate{
+		Name:   GatePeerRPCNotActive,
+		Result: GateResultPass,
+		Values: map[string]string{},
+	}
+	for name, rpcState := range gossipState.GetPeerRPCStates() {
+		peerRPC.Values[name] = fmt.Sprintf("reachable=%t healthy=%t identity=%s", rpcState.Reachable, rpcState.Healthy, rpcState.Identity)
+	}
+	if !opts.Cfg.Failover.ConfirmWithPeerRPC {
+		peerRPC.Result = GateResultSkipped
+		peerRPC.Detail = "skipped: failover.confirm_with_peer_rpc is off"
+	} else if peerRPCState, ok := gossipState.ActivePeerRPC(); ok {
+		peerRPC.Result = GateResultFail
+		peerRPC.Detail = fmt.Sprintf("peer %s's rpc reports the active identity - the agent would not take over", peerRPCState.Name)
+	}
+	gate(peerRPC)
+	if !decided && peerRPC.Result == GateResultFail {
+		decide(DecisionActionNone, DecisionReasonPeerRPCActive)
+	}
+
+	// peers' heartbeats - only consulted when failover.require_heartbeat_confirmation is on
+	peerHeartbeat := Gate{
+		Name:   GatePeerHeartbeatNotActive,
+		Result: GateResultPass,
+		Values: map[string]string{},
+	}
+	for name, heartbeatState := range gossipState.GetPeerHeartbeats() {
+		peerHeartbeat.Values[name] = fmt.Sprintf("reachable=%t role=%s healthy=%t slot=%d", heartbeatState.Reachable, heartbeatState.Heartbeat.Role, heartbeatState.Heartbeat.Healthy, heartbeatState.Heartbeat.Slot)
+	}
+	if !opts.Cfg.Failover.RequireHeartbeatConfirmation {
+		peerHeartbeat.Result = GateResultSkipped
+		peerHeartbeat.Detail = "skipped: failover.require_heartbeat_confirmation is off"
+	} else if heartbeatState, ok := gossipState.ActivePeerHeartbeat(); ok {
+		peerHeartbeat.Result = GateResultFail
+		peerHeartbeat.Detail = fmt.Sprintf("peer %s's heartbeat reports the active role - the agent would not take over", heartbeatState.Name)
+	}
+	gate(peerHeartbeat)
+	if !decided && peerHeartbeat.Result == GateResultFail {
+		decide(DecisionActionNone, DecisionReasonPeerHeartbeatActive)
+	}
+
+	if !decided {
+		decide(DecisionActionBecomeActive, DecisionReasonNoActivePeer)
+	}
+
+	return explanation, nil
+}
@@ -0,0 +1,261 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+)
+
+// clusterServer is a fake cluster rpc with a single node in gossip on a live local gossip address, voting
+func clusterServer(t *testing.T, pubkey solana.PublicKey) *httptest.Server {
+	t.Helper()
+
+	gossipListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { gossipListener.Close() })
+
+	results := map[string]any{
+		"getClusterNodes": []map[string]any{{"pubkey": pubkey.String(), "gossip": gossipListener.Addr().String()}},
+		"getSlot":         100,
+		"getVoteAccounts": map[string]any{
+			"current": []map[string]any{{
+				"votePubkey":       solana.NewWallet().PublicKey().String(),
+				"nodePubkey":       pubkey.String(),
+				"activatedStake":   1,
+				"epochVoteAccount": true,
+				"commission":       0,
+				"lastVote":         100,
+				"rootSlot":         68,
+				"epochCredits":     [][]uint64{},
+			}},
+			"delinquent": []map[string]any{},
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     any    `json:"id"`
+			Method string `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": results[request.Method]})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// gateResults returns the name to result of each gate
+func gateResults(explanation *Explanation) map[string]string {
+	results := map[string]string{}
+	for _, gate := range explanation.Gates {
+		results[gate.Name] = gate.Result
+	}
+	return results
+}
+
+func TestExplain_ActivePeerPresent(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.Peers["peer1"] = config.Peer{Name: "peer1", IP: "127.0.0.1"}
+	server := clusterServer(t, cfg.Validator.Identities.ActiveKeyPair.PublicKey())
+
+	explanation, err := Explain(ExplainOptions{
+		Cfg:        cfg,
+		ClusterRPC: rpc.NewClient("test", server.URL),
+		PublicIP:   "192.168.1.100",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, DecisionActionNone, explanation.Decision.Action)
+	assert.Equal(t, DecisionReasonActivePeerPresent, explanation.Decision.Reason)
+	assert.Equal(t, "peer1", explanation.Decision.ActivePeerName)
+	assert.False(t, explanation.WouldTransition())
+
+	require.Equal(t, GateActivePeerPresent, explanation.Gates[0].Name)
+	assert.Equal(t, "127.0.0.1", explanation.Gates[0].Values["active_peer_ip"])
+	assert.Equal(t, map[string]string{
+		GateActivePeerPresent:      GateResultPass,
+		GateSelfInGossip:           GateResultNotReached,
+		GateSelfHealthy:            GateResultNotReached,
+		GateSelfNotActive:          GateResultNotReached,
+		GateSafetyGates:            GateResultNotReached,
+		GateTakeoverRank:           GateResultNotReached,
+		GateNoPeerTookOver:         GateResultNotReached,
+		GatePeerRPCNotActive:       GateResultNotReached,
+		GatePeerHeartbeatNotActive: GateResultNotReached,
+	}, gateResults(explanation))
+
+	// the loaded config is left as it was
+	assert.Len(t, cfg.Failover.Peers, 2)
+}
+
+func TestExplain_NoActivePeer_WouldBecomeActive(t *testing.T) {
+	cfg := createTestConfig()
+	server := clusterServer(t, cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+
+	explanation, err := Explain(ExplainOptions{
+		Cfg:        cfg,
+		ClusterRPC: rpc.NewClient("test", server.URL),
+		PublicIP:   "127.0.0.1",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, DecisionActionBecomeActive, explanation.Decision.Action)
+	assert.Equal(t, DecisionReasonNoActivePeer, explanation.Decision.Reason)
+	assert.True(t, explanation.Decision.SelfInGossip)
+	assert.True(t, explanation.WouldTransition())
+
+	assert.Equal(t, map[string]string{
+		GateActivePeerPresent:      GateResultFail,
+		GateSelfInGossip:           GateResultPass,
+		GateSelfHealthy:            GateResultSkipped,
+		GateSelfNotActive:          GateResultSkipped,
+		GateSafetyGates:            GateResultSkipped,
+		GateTakeoverRank:           GateResultPass,
+		GateNoPeerTookOver:         GateResultSkipped,
+		GatePeerRPCNotActive:       GateResultSkipped,
+		GatePeerHeartbeatNotActive: GateResultSkipped,
+	}, gateResults(explanation))
+	for _, gate := range explanation.Gates {
+		if gate.Name == GateSelfHealthy || gate.Name == GateSelfNotActive {
+			assert.Equal(t, skippedRequiresLocalAccess, gate.Detail)
+		}
+		if gate.Name == GateTakeoverRank {
+			assert.Equal(t, "1", gate.Values["static_rank"])
+		}
+		if gate.Name == GateSafetyGates {
+			assert.Equal(t, map[string]string{config.GateHealth: "off", config.GateSlotLag: "off", config.GateDiskSpace: "off"}, gate.Values)
+		}
+	}
+}
+
+func TestExplain_PeerRPCReportsActive(t *testing.T) {
+	cfg := createTestConfig()
+	server := clusterServer(t, cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+
+	// peer1 is missing from gossip but its own rpc still reports the active identity
+	identity := &atomic.Value{}
+	identity.Store(cfg.Validator.Identities.ActiveKeyPair.PublicKey().String())
+	cfg.Failover.Peers["peer1"] = config.Peer{Name: "peer1", IP: "192.168.1.101", RPCURL: identityServer(t, identity).URL}
+
+	explain := func() *Explanation {
+		explanation, err := Explain(ExplainOptions{
+			Cfg:        cfg,
+			ClusterRPC: rpc.NewClient("test", server.URL),
+			PublicIP:   "127.0.0.1",
+		})
+		require.NoError(t, err)
+		return explanation
+	}
+
+	// off by default - the peer rpc is only shown
+	explanation := explain()
+	assert.Equal(t, DecisionReasonNoActivePeer, explanation.Decision.Reason)
+	assert.Equal(t, GateResultSkipped, gateResults(explanation)[GatePeerRPCNotActive])
+	peerRPCGate := explanation.Gates[len(explanation.Gates)-2]
+	require.Equal(t, GatePeerRPCNotActive, peerRPCGate.Name)
+	assert.Equal(t, "reachable=true healthy=true identity="+identity.Load().(string), peerRPCGate.Values["peer1"])
+
+	cfg.Failover.ConfirmWithPeerRPC = true
+	explanation = explain()
+	assert.Equal(t, DecisionActionNone, explanation.Decision.Action)
+	assert.Equal(t, DecisionReasonPeerRPCActive, explanation.Decision.Reason)
+	assert.False(t, explanation.WouldTransition())
+	assert.Equal(t, GateResultFail, gateResults(explanation)[GatePeerRPCNotActive])
+
+	// a peer rpc reporting a passive identity confirms the failover
+	identity.Store(cfg.Validator.Identities.PassiveKeyPair.PublicKey().String())
+	explanation = explain()
+	assert.Equal(t, DecisionReasonNoActivePeer, explanation.Decision.Reason)
+	assert.Equal(t, GateResultPass, gateResults(explanation)[GatePeerRPCNotActive])
+}
+
+func TestExplain_PeerHeartbeatReportsActive(t *testing.T) {
+	cfg := createTestConfig()
+	server := clusterServer(t, cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+
+	// peer1 is missing from gossip but its agent still reports the active role
+	role := constants.RoleActive
+	explain := func() *Explanation {
+		explanation, err := Explain(ExplainOptions{
+			Cfg:        cfg,
+			ClusterRPC: rpc.NewClient("test", server.URL),
+			PublicIP:   "127.0.0.1",
+			FetchHeartbeat: func(ctx context.Context, peer config.Peer) (gossip.Heartbeat, error) {
+				if peer.Name != "peer1" {
+					return gossip.Heartbeat{}, errors.New("connection refused")
+				}
+				return gossip.Heartbeat{Name: peer.Name, Role: role, Healthy: true, Slot: 100}, nil
+			},
+		})
+		require.NoError(t, err)
+		return explanation
+	}
+
+	// off by default - the heartbeats are only shown
+	explanation := explain()
+	assert.Equal(t, DecisionReasonNoActivePeer, explanation.Decision.Reason)
+	heartbeatGate := explanation.Gates[len(explanation.Gates)-1]
+	require.Equal(t, GatePeerHeartbeatNotActive, heartbeatGate.Name)
+	assert.Equal(t, GateResultSkipped, heartbeatGate.Result)
+	assert.Equal(t, "reachable=true role=active healthy=true slot=100", heartbeatGate.Values["peer1"])
+	assert.Equal(t, "reachable=false role= healthy=false slot=0", heartbeatGate.Values["peer2"])
+
+	cfg.Failover.RequireHeartbeatConfirmation = true
+	explanation = explain()
+	assert.Equal(t, DecisionActionNone, explanation.Decision.Action)
+	assert.Equal(t, DecisionReasonPeerHeartbeatActive, explanation.Decision.Reason)
+	assert.Equal(t, GateResultFail, gateResults(explanation)[GatePeerHeartbeatNotActive])
+
+	// a heartbeat reporting passive confirms the failover
+	role = constants.RolePassive
+	explanation = explain()
+	assert.Equal(t, DecisionReasonNoActivePeer, explanation.Decision.Reason)
+	assert.Equal(t, GateResultPass, gateResults(explanation)[GatePeerHeartbeatNotActive])
+}
+
+func TestExplain_OfflineStub_WouldBecomePassive(t *testing.T) {
+	cfg := createTestConfig()
+
+	explanation, err := Explain(ExplainOptions{
+		Cfg:        cfg,
+		ClusterRPC: rpc.NewOfflineClient("test", cfg.Cluster.RPCURLs...),
+		PublicIP:   "192.168.1.100",
+	})
+	require.NoError(t, err)
+
+	// the stub has nothing in gossip so we'd bow out as passive
+	assert.Equal(t, DecisionActionBecomePassive, explanation.Decision.Action)
+	assert.Equal(t, DecisionReasonSelfNotInGossip, explanation.Decision.Reason)
+	assert.Equal(t, 1, explanation.Decision.LeaderlessSamples)
+	assert.True(t, explanation.WouldTransition())
+	assert.Equal(t, GateResultFail, gateResults(explanation)[GateSelfInGossip])
+	assert.Equal(t, GateResultNotReached, gateResults(explanation)[GateSelfHealthy])
+}
+
+func TestExplain_RejectsSelfInPeers(t *testing.T) {
+	cfg := createTestConfig()
+
+	_, err := Explain(ExplainOptions{
+		Cfg:        cfg,
+		ClusterRPC: rpc.NewOfflineClient("test", cfg.Cluster.RPCURLs...),
+		PublicIP:   "192.168.1.101",
+	})
+	assert.ErrorContains(t, err, "failover.peers must not reference ourselves")
+}
@@ -0,0 +1,54 @@
+package ha
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/charmbracelet/log"
+)
+
+// failoverIDField is the log, event and notification field the running transition's failover id is in
+const failoverIDField = "failover_id"
+
+// newFailoverID returns a short random id correlating everything one transition logs, records and notifies
+func newFailoverID() string {
+	id := make([]byte, 4)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// beginFailoverID generates the failover id of the transition starting, returning the func that ends it once the
+// transition is done - it is kept as the last failover id
+func (m *Manager) beginFailoverID() (end func()) {
+	id := newFailoverID()
+	m.failoverID.Store(&id)
+	m.lastFailoverID.Store(&id)
+	return func() {
+		m.failoverID.Store(nil)
+	}
+}
+
+// currentFailoverID returns the running transition's failover id, empty between transitions
+func (m *Manager) currentFailoverID() string {
+	if id := m.failoverID.Load(); id != nil {
+		return *id
+	}
+	return ""
+}
+
+// lastFailoverIDString returns the failover id of the running or last transition, empty if none has run
+func (m *Manager) lastFailoverIDString() string {
+	if id := m.lastFailoverID.Load(); id != nil {
+		return *id
+	}
+	return ""
+}
+
+// transitionLogger returns the manager's logger with the running transition's failover id, as is between
+// transitions
+func (m *Manager) transitionLogger() *log.Logger {
+	if id := m.currentFailoverID(); id != "" {
+		return m.logger.With(failoverIDField, id)
+	}
+	return m.logger
+}
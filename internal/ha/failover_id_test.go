@@ -0,0 +1,55 @@
+package ha
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_FailoverID_ThreadedThroughTransition(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	var logged bytes.Buffer
+	manager.logger = log.New(&logged)
+	hookOutput := filepath.Join(t.TempDir(), "failover-id")
+	manager.cfg.Failover.Active.Hooks = config.Hooks{
+		Pre: []config.Hook{{Name: "record-id", Command: "sh", Args: []string{"-c", "printf %s \"$SVHA_FAILOVER_ID\" > " + hookOutput}}},
+	}
+	assert.Empty(t, manager.status().LastFailoverID)
+
+	manager.ensureActive("leaderless")
+
+	entries := manager.history.Entries()
+	require.Len(t, entries, 1)
+	id := entries[0].FailoverID
+	assert.Regexp(t, "^[0-9a-f]{8}$", id)
+
+	// the hooks, events and log lines of the transition all carry it
+	hookID, err := os.ReadFile(hookOutput)
+	require.NoError(t, err)
+	assert.Equal(t, id, string(hookID))
+	recorded := manager.events.Events()
+	require.NotEmpty(t, recorded)
+	for _, event := range recorded {
+		assert.Equal(t, id, event.Fields[failoverIDField], event.Type)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(logged.String()), "\n") {
+		assert.Contains(t, line, "failover_id="+id)
+	}
+
+	// kept as the last once done
+	status := manager.status()
+	assert.Empty(t, status.FailoverID)
+	assert.Equal(t, id, status.LastFailoverID)
+	assert.Equal(t, manager.logger, manager.transitionLogger())
+
+	// every transition gets its own
+	manager.ensurePassive("unhealthy")
+	assert.NotEqual(t, id, manager.status().LastFailoverID)
+}
@@ -0,0 +1,338 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/sol-strategies/solana-validator-ha/internal/health"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+)
+
+const (
+	// ArbitrationModeFitness means takeover claims were ordered by advertised fitness score
+	ArbitrationModeFitness = "fitness"
+	// ArbitrationModeStaticPriority means takeover claims fell back to the static peer rank
+	ArbitrationModeStaticPriority = "static_priority"
+
+	// fitnessResponseBodyLimit bounds how much of a peer's fitness response is read
+	fitnessResponseBodyLimit = 16384
+)
+
+// PeerFitness is the fitness an agent advertises to its peers on /fitness
+type PeerFitness struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+	health.Fitness
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// sanitize strips the free text fields a peer advertised of anything unfit for logs and bounds their length
+func (f *PeerFitness) sanitize() {
+	f.Name = config.SanitizeString(f.Name, config.NameMaxLength)
+	f.IP = config.SanitizeString(f.IP, config.NameMaxLength)
+	for i := range f.Components {
+		f.Components[i].Name = config.SanitizeString(f.Components[i].Name, config.NameMaxLength)
+		f.Components[i].Value = config.SanitizeString(f.Components[i].Value, config.LabelValueMaxLength)
+	}
+}
+
+// ArbitrationCandidate is an eligible passive considered in takeover arbitration
+type ArbitrationCandidate struct {
+	Name       string `json:"name"`
+	IP         string `json:"ip"`
+	Score      int    `json:"score"`
+	StaticRank int    `json:"static_rank"`
+	Reachable  bool   `json:"reachable"`
+	Error      string `json:"error,omitempty"`
+	Self       bool   `json:"self,omitempty"`
+}
+
+// Arbitration is the outcome of ordering takeover claims among eligible passives
+type Arbitration struct {
+	Time time.Time `json:"time"`
+	Mode string    `json:"mode"`
+	// Reason explains a fallback to static priority
+	Reason     string                 `json:"reason,omitempty"`
+	Rank       int                    `json:"rank"`
+	Candidates []ArbitrationCandidate `json:"candidates"`
+}
+
+// fitnessState is the latest fitness and arbitration, shared with the http handlers
+type fitnessState struct {
+	mu          sync.RWMutex
+	fitness     *PeerFitness
+	arbitration *Arbitration
+}
+
+// refreshFitness computes and stores our current fitness when fitness is enabled
+func (m *Manager) refreshFitness() {
+	if !m.cfg.Fitness.Enabled {
+		return
+	}
+
+	fitness := &PeerFitness{
+		Name:       m.peerSelf.Name,
+		IP:         m.peerSelf.IP,
+		Fitness:    health.Score(m.fitnessInputs(), health.Thresholds{MaxSlotLag: m.cfg.Fitness.MaxSlotLag, MinDiskFreePercent: m.cfg.Fitness.MinDiskFreePercent}),
+		ComputedAt: m.clock.Now().Time(),
+	}
+	m.logger.Debug("fitness computed", "score", fitness.Score, "fitness", fitness.Fitness.String())
+
+	m.fitnessState.mu.Lock()
+	m.fitnessState.fitness = fitness
+	m.fitnessState.mu.Unlock()
+}
+
+// fitnessInputs measures the slot lag, disk free space and recent restarts fitness is scored on
+func (m *Manager) fitnessInputs() (inputs health.Inputs) {
+	if lag, err := m.slotLag(); err == nil {
+		inputs.SlotLagKnown = true
+		inputs.SlotLag = lag
+	} else {
+		m.logger.Warn("failed to measure slot lag for fitness", "error", err)
+	}
+
+	if m.cfg.Fitness.DiskPath != "" {
+		inputs.DiskChecked = true
+		if freePercent, err := diskFreePercent(m.cfg.Fitness.DiskPath); err == nil {
+			inputs.DiskFreeKnown = true
+			inputs.DiskFreePercent = freePercent
+		} else {
+			m.logger.Warn("failed to measure disk free space for fitness", "disk_path", m.cfg.Fitness.DiskPath, "error", err)
+		}
+	}
+
+	if m.events != nil {
+		// persisted events span restarts so only their wall clock time can be compared
+		since := m.clock.Now().Time().Add(-m.cfg.Fitness.RestartWindow)
+		for _, event := range m.events.Events() {
+			if event.Type == events.TypeAgentRestarted && event.Time.After(since) {
+				inputs.RecentRestarts++
+			}
+		}
+	}
+
+	return inputs
+}
+
+// currentFitness returns our latest fitness, nil if not computed
+func (m *Manager) currentFitness() *PeerFitness {
+	m.fitnessState.mu.RLock()
+	defer m.fitnessState.mu.RUnlock()
+	return m.fitnessState.fitness
+}
+
+// currentArbitration returns the latest takeover arbitration, nil if there hasn't been one
+func (m *Manager) currentArbitration() *Arbitration {
+	m.fitnessState.mu.RLock()
+	defer m.fitnessState.mu.RUnlock()
+	return m.fitnessState.arbitration
+}
+
+// takeoverRank returns our claim order among eligible passives and whether it came from fitness arbitration
+func (m *Manager) takeoverRank() (rank int, byFitness bool) {
+	if !m.cfg.Fitness.Enabled {
+		return m.staticTakeoverRank(m.peerSelf.IP), false
+	}
+
+	// eligible peers are the ones in gossip other than us - the active peer is gone or we wouldn't be here
+	var peers []config.Peer
+	for _, peerState := range m.gossipState.GetPeerStates() {
+		if !peerState.IPEquals(m.peerSelf.IP) {
+			peers = append(peers, config.Peer{Name: peerState.Name, IP: peerState.IP})
+		}
+	}
+
+	arbitration := m.arbitrateTakeover(peers)
+	m.fitnessState.mu.Lock()
+	m.fitnessState.arbitration = arbitration
+	m.fitnessState.mu.Unlock()
+
+	return arbitration.Rank, arbitration.Mode == ArbitrationModeFitness
+}
+
+// staticTakeoverRank returns the rank of ip among the config peers ordered by IP, last if not found
+func (m *Manager) staticTakeoverRank(ip string) int {
+	// artificial ordering of peers by IP so that it is common across all nodes running this function
+	if rank, ok := m.cfg.Failover.Peers.GetRankedIPs()[ip]; ok {
+		return rank
+	}
+	return len(m.cfg.Failover.Peers) + 1
+}
+
+// priorityTakeoverRank returns our rank among the config peers ordered by failover.peers priority
+func (m *Manager) priorityTakeoverRank() int {
+	return priorityRank(m.cfg.Failover.Peers, m.gossipState.GetPeerStates(), m.peerSelf.Name)
+}
+
+// priorityRank returns the rank of selfName among peers ordered by priority, counting only the peers in gossip
+// ahead of it - a higher priority peer that is gone must not hold back our claim
+func priorityRank(peers config.Peers, peerStates map[string]gossip.PeerState, selfName string) int {
+	rank := 1
+	for _, name := range peers.GetPriorityRankedNames() {
+		if name == selfName {
+			return rank
+		}
+		if _, inGossip := peerStates[name]; inGossip {
+			rank++
+		}
+	}
+	return rank
+}
+
+// arbitrateTakeover orders the eligible passives - us and peers - by advertised fitness score, ties broken
+// by IP so the order is common across all nodes. If any peer's fitness can't be fetched everyone falls back to static rank as
+// a peer we can't see may be ordering itself differently.
+func (m *Manager) arbitrateTakeover(peers []config.Peer) *Arbitration {
+	arbitration := &Arbitration{
+		Time: m.clock.Now().Time(),
+		Mode: ArbitrationModeFitness,
+	}
+
+	self := ArbitrationCandidate{
+		Name:       m.peerSelf.Name,
+		IP:         m.peerSelf.IP,
+		StaticRank: m.staticTakeoverRank(m.peerSelf.IP),
+		Reachable:  true,
+		Self:       true,
+	}
+	if fitness := m.currentFitness(); fitness != nil {
+		self.Score = fitness.Score
+	}
+
+	// fetch every eligible peer's advertised fitness concurrently
+	peerCandidates := make([]ArbitrationCandidate, len(peers))
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func(i int, peer config.Peer) {
+			defer wg.Done()
+			candidate := ArbitrationCandidate{Name: peer.Name, IP: peer.IP, StaticRank: m.staticTakeoverRank(peer.IP)}
+			fitness, err := m.getPeerFitness(peer)
+			if err != nil {
+				candidate.Error = err.Error()
+			} else {
+				candidate.Reachable = true
+				candidate.Score = fitness.Score
+			}
+			peerCandidates[i] = candidate
+		}(i, peer)
+	}
+	wg.Wait()
+
+	arbitration.Candidates = append([]ArbitrationCandidate{self}, peerCandidates...)
+	sort.SliceStable(arbitration.Candidates, func(i, j int) bool {
+		a, b := arbitration.Candidates[i], arbitration.Candidates[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.IP < b.IP
+	})
+
+	for _, candidate := range arbitration.Candidates {
+		if !candidate.Reachable {
+			arbitration.Mode = ArbitrationModeStaticPriority
+			arbitration.Reason = fmt.Sprintf("peer %s fitness unavailable: %s", candidate.Name, candidate.Error)
+			break
+		}
+	}
+
+	if arbitration.Mode == ArbitrationModeStaticPriority {
+		arbitration.Rank = self.StaticRank
+	} else {
+		for i, candidate := range arbitration.Candidates {
+			if candidate.Self {
+				arbitration.Rank = i + 1
+			}
+		}
+	}
+
+	logArgs := []any{
+		"mode", arbitration.Mode,
+		"rank", arbitration.Rank,
+		"self_score", self.Score,
+		"candidates", arbitration.candidatesString(),
+	}
+	if fitness := m.currentFitness(); fitness != nil {
+		logArgs = append(logArgs, "self_fitness", fitness.Fitness.String())
+	}
+	if arbitration.Mode == ArbitrationModeStaticPriority {
+		m.logger.Warn("takeover arbitration falling back to static priority", append(logArgs, "reason", arbitration.Reason)...)
+	} else {
+		m.logger.Info("takeover arbitration", logArgs...)
+	}
+
+	return arbitration
+}
+
+// candidatesString returns the candidates in claim order for logging
+func (a *Arbitration) candidatesString() string {
+	candidates := make([]string, 0, len(a.Candidates))
+	for _, candidate := range a.Candidates {
+		score := strconv.Itoa(candidate.Score)
+		if !candidate.Reachable {
+			score = "unreachable"
+		}
+		candidates = append(candidates, fmt.Sprintf("%s:%s", candidate.Name, score))
+	}
+	return "[" + strings.Join(candidates, " ") + "]"
+}
+
+// fetchPeerFitness fetches a peer's advertised fitness from its /fitness endpoint, served on the
+// health port - prometheus.port + 1 - which must be the same across peers, over https if healthcheck.tls is set
+func (m *Manager) fetchPeerFitness(peer config.Peer) (fitness PeerFitness, err error) {
+	ctx, cancel := context.WithTimeout(m.ctx, m.cfg.Fitness.PeerTimeout)
+	defer cancel()
+
+	url := fmt.Sprintf("%s://%s:%d/fitness", m.cfg.HealthCheck.TLS.Scheme(), peer.IP, m.cfg.Prometheus.HealthCheckPort())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fitness, err
+	}
+	// peers share healthcheck.auth
+	httpauth.SetBearerToken(req, m.cfg.HealthCheck.Auth.Token())
+
+	resp, err := (&http.Client{Timeout: m.cfg.Fitness.PeerTimeout}).Do(req)
+	if err != nil {
+		return fitness, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fitness, fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, fitnessResponseBodyLimit)).Decode(&fitness); err != nil {
+		return fitness, fmt.Errorf("failed to decode fitness from %s: %w", url, err)
+	}
+	fitness.sanitize()
+	return fitness, nil
+}
+
+// handleFitness serves our advertised fitness to peers
+func (m *Manager) handleFitness(w http.ResponseWriter, r *http.Request) {
+	if !m.cfg.Fitness.Enabled {
+		http.Error(w, "fitness is not enabled", http.StatusNotFound)
+		return
+	}
+
+	fitness := m.currentFitness()
+	if fitness == nil {
+		http.Error(w, "fitness not computed yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fitness)
+}
@@ -0,0 +1,231 @@
+package ha
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/sol-strategies/solana-validator-ha/internal/health"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+)
+
+// newFitnessManager returns an initialized manager with fitness enabled, its own score set and peer
+// fitness served from scores - a peer missing from scores is unreachable
+func newFitnessManager(t *testing.T, selfScore int, scores map[string]int) *Manager {
+	t.Helper()
+
+	manager, _ := newFakeClockManager(t)
+	manager.cfg.Fitness.Enabled = true
+	manager.fitnessState.fitness = &PeerFitness{Name: manager.peerSelf.Name, IP: manager.peerSelf.IP, Fitness: health.Fitness{Score: selfScore}}
+	manager.getPeerFitness = func(peer config.Peer) (PeerFitness, error) {
+		score, ok := scores[peer.Name]
+		if !ok {
+			return PeerFitness{}, errors.New("connection refused")
+		}
+		return PeerFitness{Name: peer.Name, IP: peer.IP, Fitness: health.Fitness{Score: score}}, nil
+	}
+
+	return manager
+}
+
+func TestManager_ArbitrateTakeover(t *testing.T) {
+	// self is 192.168.1.100, ranked first statically
+	peers := []config.Peer{
+		{Name: "peer1", IP: "192.168.1.101"},
+		{Name: "peer2", IP: "192.168.1.102"},
+	}
+
+	tests := []struct {
+		name   string
+		self   int
+		scores map[string]int
+		mode   string
+		rank   int
+		order  []string
+	}{
+		{
+			name:   "fittest claims first",
+			self:   100,
+			scores: map[string]int{"peer1": 60, "peer2": 90},
+			mode:   ArbitrationModeFitness,
+			rank:   1,
+			order:  []string{"test-validator", "peer2", "peer1"},
+		},
+		{
+			name:   "lagging self claims last",
+			self:   50,
+			scores: map[string]int{"peer1": 100, "peer2": 90},
+			mode:   ArbitrationModeFitness,
+			rank:   3,
+			order:  []string{"peer1", "peer2", "test-validator"},
+		},
+		{
+			name:   "ties broken by ip",
+			self:   80,
+			scores: map[string]int{"peer1": 80, "peer2": 100},
+			mode:   ArbitrationModeFitness,
+			rank:   2,
+			order:  []string{"peer2", "test-validator", "peer1"},
+		},
+		{
+			name:   "unreachable peer falls back to static priority",
+			self:   50,
+			scores: map[string]int{"peer1": 60},
+			mode:   ArbitrationModeStaticPriority,
+			rank:   1,
+			order:  []string{"peer1", "test-validator", "peer2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newFitnessManager(t, tt.self, tt.scores)
+
+			arbitration := manager.arbitrateTakeover(peers)
+			assert.Equal(t, tt.mode, arbitration.Mode)
+			assert.Equal(t, tt.rank, arbitration.Rank)
+
+			order := make([]string, 0, len(arbitration.Candidates))
+			for _, candidate := range arbitration.Candidates {
+				order = append(order, candidate.Name)
+			}
+			assert.Equal(t, tt.order, order)
+
+			if tt.mode == ArbitrationModeStaticPriority {
+				assert.Contains(t, arbitration.Reason, "peer2")
+			} else {
+				assert.Empty(t, arbitration.Reason)
+			}
+		})
+	}
+}
+
+func TestManager_TakeoverRank_StaticWhenDisabled(t *testing.T) {
+	manager := newFitnessManager(t, 0, map[string]int{"peer1": 100, "peer2": 100})
+	manager.cfg.Fitness.Enabled = false
+
+	rank, byFitness := manager.takeoverRank()
+	assert.False(t, byFitness)
+	assert.Equal(t, 1, rank)
+	assert.Nil(t, manager.currentArbitration())
+}
+
+func TestPriorityRank(t *testing.T) {
+	peers := config.Peers{
+		"self":  {Name: "self", IP: "192.168.1.100", Priority: 5},
+		"peer1": {Name: "peer1", IP: "192.168.1.101", Priority: 10},
+		"peer2": {Name: "peer2", IP: "192.168.1.102", Priority: 5},
+		"peer3": {Name: "peer3", IP: "192.168.1.103", Priority: 1},
+	}
+	inGossip := map[string]gossip.PeerState{"self": {}, "peer1": {}, "peer2": {}, "peer3": {}}
+
+	// peer1 has a higher priority, peer2 the same but sorts before us by name
+	assert.Equal(t, 3, priorityRank(peers, inGossip, "self"))
+	assert.Equal(t, 1, priorityRank(peers, inGossip, "peer1"))
+	assert.Equal(t, 2, priorityRank(peers, inGossip, "peer2"))
+	assert.Equal(t, 4, priorityRank(peers, inGossip, "peer3"))
+
+	// a higher priority peer out of gossip doesn't hold us back
+	delete(inGossip, "peer1")
+	assert.Equal(t, 2, priorityRank(peers, inGossip, "self"))
+}
+
+func TestManager_FetchPeerFitness_SendsBearerToken(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+	manager.cfg.HealthCheck.Auth = &config.Auth{BearerToken: "s3cr3t"}
+	manager.cfg.Fitness.PeerTimeout = time.Second
+
+	peer := httptest.NewServer(httpauth.RequireBearerToken("s3cr3t", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(PeerFitness{Name: "peer1", Fitness: health.Fitness{Score: 90}})
+	})))
+	t.Cleanup(peer.Close)
+	// peers serve fitness on prometheus.port + 1
+	manager.cfg.Prometheus.Port = peer.Listener.Addr().(*net.TCPAddr).Port - 1
+
+	fitness, err := manager.fetchPeerFitness(config.Peer{Name: "peer1", IP: "127.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, 90, fitness.Fitness.Score)
+
+	manager.cfg.HealthCheck.Auth = &config.Auth{BearerToken: "wrong"}
+	_, err = manager.fetchPeerFitness(config.Peer{Name: "peer1", IP: "127.0.0.1"})
+	assert.ErrorContains(t, err, "returned status 401")
+}
+
+func TestManager_FetchPeerFitness_UntrustedResponse(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+	manager.cfg.Fitness.PeerTimeout = time.Second
+
+	var body string
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(peer.Close)
+	manager.cfg.Prometheus.Port = peer.Listener.Addr().(*net.TCPAddr).Port - 1
+
+	// control characters are stripped and overlong fields capped before the fitness is logged or stored
+	body = `{"name":"peer1\nlevel=ERROR","ip":"127.0.0.1","score":80,"components":[{"name":"slot_lag\u001b[2J","value":"` + strings.Repeat("x", 200) + `","penalty":20}]}`
+	fitness, err := manager.fetchPeerFitness(config.Peer{Name: "peer1", IP: "127.0.0.1"})
+	require.NoError(t, err)
+	assert.Equal(t, "peer1level=ERROR", fitness.Name)
+	require.Len(t, fitness.Components, 1)
+	assert.Equal(t, "slot_lag[2J", fitness.Components[0].Name)
+	assert.Len(t, fitness.Components[0].Value, config.LabelValueMaxLength)
+
+	// and no more than the limit of a response is read
+	body = `{"name":"` + strings.Repeat("x", fitnessResponseBodyLimit) + `"}`
+	_, err = manager.fetchPeerFitness(config.Peer{Name: "peer1", IP: "127.0.0.1"})
+	assert.ErrorContains(t, err, "failed to decode fitness")
+}
+
+func TestManager_HandleFitness(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+
+	get := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		manager.handleFitness(recorder, httptest.NewRequest(http.MethodGet, "/fitness", nil))
+		return recorder
+	}
+
+	// disabled
+	assert.Equal(t, http.StatusNotFound, get().Code)
+
+	// enabled but not computed yet
+	manager.cfg.Fitness.Enabled = true
+	assert.Equal(t, http.StatusServiceUnavailable, get().Code)
+
+	// computed
+	manager.fitnessState.fitness = &PeerFitness{Name: "test-validator", IP: "192.168.1.100", Fitness: health.Fitness{Score: 75}}
+	recorder := get()
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var fitness PeerFitness
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&fitness))
+	assert.Equal(t, 75, fitness.Score)
+	assert.Equal(t, "test-validator", fitness.Name)
+}
+
+func TestManager_HandleStatus(t *testing.T) {
+	manager := newFitnessManager(t, 90, map[string]int{"peer1": 100, "peer2": 80})
+	manager.takeoverRank()
+
+	recorder := httptest.NewRecorder()
+	manager.handleStatus(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var status Status
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&status))
+	require.NotNil(t, status.Fitness)
+	assert.Equal(t, 90, status.Fitness.Score)
+	require.NotNil(t, status.Arbitration)
+	assert.Equal(t, ArbitrationModeFitness, status.Arbitration.Mode)
+}
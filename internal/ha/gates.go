@@ -0,0 +1,196 @@
+package ha
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/gates"
+)
+
+// GateEvaluation is the outcome of checking the safety gates before a promotion
+type GateEvaluation struct {
+	Time    time.Time      `json:"time"`
+	Results []gates.Result `json:"results"`
+	// Blocked is true when an enforced gate prevented the promotion
+	Blocked bool `json:"blocked"`
+}
+
+// gateState is the latest gate evaluation, shared with the http handlers
+type gateState struct {
+	mu         sync.RWMutex
+	evaluation *GateEvaluation
+}
+
+// configuredGates returns every safety gate in the order they are checked - a new gate only needs adding here
+// and to config.Gates and its Modes to be evaluated in its mode, shown by explain, reported on /status and counted in gate_violations_total
+func (m *Manager) configuredGates() []gates.Gate {
+	configured := []gates.Gate{
+		{
+			Name:  config.GateHealth,
+			Mode:  m.cfg.Gates.Health.Mode,
+			Check: m.checkHealthGate,
+		},
+		{
+			Name:  config.GateSlotLag,
+			Mode:  m.cfg.Gates.SlotLag.Mode,
+			Check: m.checkSlotLagGate,
+		},
+		{
+			Name:  config.GateDiskSpace,
+			Mode:  m.cfg.Gates.DiskSpace.Mode,
+			Check: m.checkDiskSpaceGate,
+		},
+	}
+	// the vote account gate needs validator.vote_account to check against
+	if m.voteAccount != nil {
+		configured = append(configured, gates.Gate{
+			Name:  config.GateVoteAccount,
+			Mode:  m.cfg.Gates.VoteAccount.Mode,
+			Check: m.checkVoteAccountGate,
+		})
+	}
+	return configured
+}
+
+// checkHealthGate passes while the local validator reports itself healthy
+func (m *Manager) checkHealthGate() (string, error) {
+	status, err := m.localRPC.GetHealth(m.ctx)
+	if err != nil {
+		return "", fmt.Errorf("unable to get local validator health: %w", err)
+	}
+
+	detail := fmt.Sprintf("local validator health %s", status)
+	if status != solanagorpc.HealthOk {
+		return "", fmt.Errorf("%s - local validator is not healthy", detail)
+	}
+	return detail, nil
+}
+
+// checkSlotLagGate passes while the local validator is at most gates.slot_lag.max_slot_lag behind the cluster
+func (m *Manager) checkSlotLagGate() (string, error) {
+	lag, err := m.slotLag()
+	if err != nil {
+		return "", fmt.Errorf("unable to measure slot lag: %w", err)
+	}
+
+	detail := fmt.Sprintf("slot lag %d, max %d", lag, m.cfg.Gates.SlotLag.MaxSlotLag)
+	if lag > m.cfg.Gates.SlotLag.MaxSlotLag {
+		return "", fmt.Errorf("%s - local validator is too far behind the cluster", detail)
+	}
+	return detail, nil
+}
+
+// checkDiskSpaceGate passes while free space on gates.disk_space.path is at least gates.disk_space.min_free_percent
+func (m *Manager) checkDiskSpaceGate() (string, error) {
+	freePercent, err := diskFreePercent(m.cfg.Gates.DiskSpace.Path)
+	if err != nil {
+		return "", fmt.Errorf("unable to measure free space on %s: %w", m.cfg.Gates.DiskSpace.Path, err)
+	}
+
+	detail := fmt.Sprintf("%.1f%% free on %s, min %v%%", freePercent, m.cfg.Gates.DiskSpace.Path, m.cfg.Gates.DiskSpace.MinFreePercent)
+	if freePercent < m.cfg.Gates.DiskSpace.MinFreePercent {
+		return "", fmt.Errorf("%s - not enough free disk space", detail)
+	}
+	return detail, nil
+}
+
+// slotLag returns how many slots the local validator is behind the cluster
+func (m *Manager) slotLag() (uint64, error) {
+	clusterSlot, err := m.clusterRPC.GetSlot(m.ctx)
+	if err != nil {
+		return 0, fmt.Errorf("cluster rpc: %w", err)
+	}
+
+	localSlot, err := m.localRPC.GetSlot(m.ctx)
+	if err != nil {
+		return 0, fmt.Errorf("local rpc: %w", err)
+	}
+
+	if clusterSlot > localSlot {
+		return clusterSlot - localSlot, nil
+	}
+	return 0, nil
+}
+
+// diskFreePercent returns the percentage of free space available to unprivileged users on path's filesystem
+func diskFreePercent(path string) (float64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	if stat.Blocks == 0 {
+		return 0, fmt.Errorf("filesystem reports no blocks")
+	}
+	return float64(stat.Bavail) / float64(stat.Blocks) * 100, nil
+}
+
+// checkGates evaluates the safety gates before a promotion, logging and counting every violation. Warn mode
+// violations are reported as what would have blocked the promotion, only enforced gates block it.
+func (m *Manager) checkGates() *GateEvaluation {
+	return m.evaluateGates(m.gates())
+}
+
+// checkEnforcedGates evaluates only the enforced safety gates like checkGates, for checking again right before
+// the active command without counting warn mode violations twice
+func (m *Manager) checkEnforcedGates() *GateEvaluation {
+	enforced := []gates.Gate{}
+	for _, gate := range m.gates() {
+		if gate.Mode == gates.ModeEnforce {
+			enforced = append(enforced, gate)
+		}
+	}
+	return m.evaluateGates(enforced)
+}
+
+// evaluateGates evaluates gateList, logging and counting every violation and reporting failover status blocked
+// when an enforced gate did not pass
+func (m *Manager) evaluateGates(gateList []gates.Gate) *GateEvaluation {
+	results := gates.Evaluate(gateList)
+	evaluation := &GateEvaluation{
+		Time:    m.clock.Now().Time(),
+		Results: results,
+		Blocked: len(gates.Blocked(results)) > 0,
+	}
+
+	for _, result := range results {
+		if !result.Checked {
+			continue
+		}
+		if !result.Violated() {
+			m.logger.Debug("safety gate passed", "gate", result.Name, "mode", result.Mode, "detail", result.Detail)
+			continue
+		}
+
+		m.metrics.ObserveGateViolation(result.Name, result.Mode.String())
+		if result.Blocked {
+			m.logger.Error("safety gate blocked promotion", "gate", result.Name, "mode", result.Mode, "detail", result.Detail)
+		} else {
+			m.logger.Warn("safety gate would have blocked promotion - not enforced, carrying on", "gate", result.Name, "mode", result.Mode, "detail", result.Detail)
+		}
+	}
+
+	m.gateState.mu.Lock()
+	m.gateState.evaluation = evaluation
+	m.gateState.mu.Unlock()
+
+	if evaluation.Blocked {
+		m.promotionBlocked = true
+		state := m.cache.GetState()
+		state.FailoverStatus = constants.FailoverStatusBlocked
+		m.cache.UpdateState(state)
+	}
+
+	return evaluation
+}
+
+// currentGateEvaluation returns the latest gate evaluation, nil if there hasn't been one
+func (m *Manager) currentGateEvaluation() *GateEvaluation {
+	m.gateState.mu.RLock()
+	defer m.gateState.mu.RUnlock()
+	return m.gateState.evaluation
+}
@@ -0,0 +1,221 @@
+package ha
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/gates"
+)
+
+// gateViolations returns the gate_violations_total value for the gate and mode labels
+func gateViolations(t *testing.T, manager *Manager, gate string, mode gates.Mode) float64 {
+	t.Helper()
+
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "solana_validator_ha_gate_violations_total" {
+			continue
+		}
+		for _, metric := range metricFamily.Metric {
+			labels := map[string]string{}
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["gate"] == gate && labels["mode"] == mode.String() {
+				return metric.Counter.GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+// slotServer is a fake local validator rpc at slot
+func slotServer(t *testing.T, slot uint64) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID any `json:"id"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": slot})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestManager_CheckGates_SampleGateModes(t *testing.T) {
+	tests := []struct {
+		name           string
+		mode           gates.Mode
+		violated       bool
+		wantBlocked    bool
+		wantViolations float64
+	}{
+		{name: "off passing", mode: gates.ModeOff},
+		{name: "off violating", mode: gates.ModeOff, violated: true},
+		{name: "warn passing", mode: gates.ModeWarn},
+		{name: "warn violating counts but does not block", mode: gates.ModeWarn, violated: true, wantViolations: 1},
+		{name: "enforce passing", mode: gates.ModeEnforce},
+		{name: "enforce violating blocks", mode: gates.ModeEnforce, violated: true, wantBlocked: true, wantViolations: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager, _ := newFakeClockManager(t)
+			manager.gates = func() []gates.Gate {
+				return []gates.Gate{{
+					Name: "sample",
+					Mode: tt.mode,
+					Check: func() (string, error) {
+						if tt.violated {
+							return "", errors.New("sample violated")
+						}
+						return "sample ok", nil
+					},
+				}}
+			}
+
+			evaluation := manager.checkGates()
+
+			assert.Equal(t, tt.wantBlocked, evaluation.Blocked)
+			assert.Equal(t, tt.wantBlocked, manager.promotionBlocked)
+			assert.Equal(t, tt.wantViolations, gateViolations(t, manager, "sample", tt.mode))
+			require.Len(t, evaluation.Results, 1)
+			assert.Equal(t, tt.mode, evaluation.Results[0].Mode)
+
+			// shown on /status whatever the outcome
+			status := manager.status()
+			require.NotNil(t, status.Gates)
+			assert.Equal(t, evaluation.Results, status.Gates.Results)
+
+			assert.Equal(t, tt.wantBlocked, status.FailoverStatus == constants.FailoverStatusBlocked.String())
+		})
+	}
+}
+
+func TestManager_CheckSlotLagGate(t *testing.T) {
+	tests := []struct {
+		name      string
+		localSlot uint64
+		wantErr   string
+		wantOK    string
+	}{
+		{name: "within max", localSlot: 95, wantOK: "slot lag 5, max 10"},
+		{name: "ahead of cluster", localSlot: 105, wantOK: "slot lag 0, max 10"},
+		{name: "too far behind", localSlot: 80, wantErr: "slot lag 20, max 10 - local validator is too far behind the cluster"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createTestConfig()
+			// the cluster is at slot 100
+			cfg.Cluster.RPCURLs = []string{clusterServer(t, solana.NewWallet().PublicKey()).URL}
+			cfg.Validator.RPCURL = slotServer(t, tt.localSlot).URL
+			cfg.Gates.SlotLag.MaxSlotLag = 10
+			manager := NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc})
+			require.NoError(t, manager.initialize())
+
+			detail, err := manager.checkSlotLagGate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOK, detail)
+		})
+	}
+}
+
+func TestManager_CheckSlotLagGate_UnmeasurableFailsClosed(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+	manager.cfg.Gates.SlotLag.Mode = gates.ModeEnforce
+	manager.cfg.Gates.SlotLag.MaxSlotLag = 10
+	manager.gates = func() []gates.Gate { return manager.configuredGates()[1:2] }
+
+	// no rpc is listening
+	evaluation := manager.checkGates()
+	assert.True(t, evaluation.Blocked)
+	assert.Contains(t, evaluation.Results[0].Detail, "unable to measure slot lag")
+}
+
+func TestManager_CheckDiskSpaceGate(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+	manager.cfg.Gates.DiskSpace.Path = t.TempDir()
+
+	manager.cfg.Gates.DiskSpace.MinFreePercent = 0.0001
+	detail, err := manager.checkDiskSpaceGate()
+	require.NoError(t, err)
+	assert.Contains(t, detail, "free on "+manager.cfg.Gates.DiskSpace.Path)
+
+	manager.cfg.Gates.DiskSpace.MinFreePercent = 100
+	_, err = manager.checkDiskSpaceGate()
+	assert.ErrorContains(t, err, "not enough free disk space")
+
+	manager.cfg.Gates.DiskSpace.Path = filepath.Join(t.TempDir(), "missing")
+	_, err = manager.checkDiskSpaceGate()
+	assert.ErrorContains(t, err, "unable to measure free space")
+}
+
+func TestManager_ConfiguredGates(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+	manager.cfg.Gates.SlotLag.Mode = gates.ModeWarn
+
+	names := []string{}
+	for _, gate := range manager.configuredGates() {
+		names = append(names, gate.Name)
+		assert.Equal(t, manager.cfg.Gates.Modes()[gate.Name], gate.Mode, gate.Name)
+	}
+	assert.Equal(t, []string{config.GateHealth, config.GateSlotLag, config.GateDiskSpace}, names)
+}
+
+func TestManager_CheckHealthGate(t *testing.T) {
+	manager, _, localRPC := newFakeRPCManager(t, createTestConfig())
+
+	detail, err := manager.checkHealthGate()
+	require.NoError(t, err)
+	assert.Equal(t, "local validator health ok", detail)
+
+	localRPC.SetHealth("behind")
+	_, err = manager.checkHealthGate()
+	assert.EqualError(t, err, "local validator health behind - local validator is not healthy")
+
+	localRPC.SetError("getHealth", assert.AnError)
+	_, err = manager.checkHealthGate()
+	assert.ErrorContains(t, err, "unable to get local validator health")
+}
+
+func TestManager_EnsureActive_RechecksEnforcedGates(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Gates.Health.Mode = gates.ModeEnforce
+	cfg.Gates.SlotLag.Mode = gates.ModeWarn
+	cfg.Gates.SlotLag.MaxSlotLag = 10
+	manager, clusterRPC, localRPC := newFakeRPCManager(t, cfg)
+	clusterRPC.SetSlot(100)
+	localRPC.SetSlot(50)
+	localRPC.SetHealth("behind")
+
+	manager.ensureActive(DecisionReasonNoActivePeer)
+
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeTransitionFailed}, recordedTypes(manager))
+	assert.Equal(t, "safety gates blocked promotion", manager.events.Events()[1].Message)
+	assert.Equal(t, constants.FailoverStatusBlocked, manager.cache.GetState().FailoverStatus)
+	assert.Equal(t, 1.0, gateViolations(t, manager, config.GateHealth, gates.ModeEnforce))
+	// warn mode gates are not checked again
+	assert.Zero(t, gateViolations(t, manager, config.GateSlotLag, gates.ModeWarn))
+}
@@ -0,0 +1,44 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+)
+
+// heartbeat asks the local validator for its identity, health and slot, reporting the role by its identity - what
+// the local validator says now rather than the last poll, as peers decide whether to take over on it
+func (m *Manager) heartbeat(ctx context.Context) gossip.Heartbeat {
+	heartbeat := gossip.Heartbeat{
+		Name:   m.peerSelf.Name,
+		Role:   constants.RoleUnknown,
+		SentAt: m.clock.Now().Time(),
+	}
+
+	if identity, err := m.localRPC.GetIdentity(ctx); err == nil {
+		heartbeat.Identity = identity.Identity.String()
+		if heartbeat.Identity == m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String() {
+			heartbeat.Role = constants.RoleActive
+		} else if m.cfg.Validator.Identities.IsPassivePubkey(heartbeat.Identity) {
+			heartbeat.Role = constants.RolePassive
+		}
+	}
+	if health, err := m.localRPC.GetHealth(ctx); err == nil {
+		heartbeat.Healthy = health == solanagorpc.HealthOk
+	}
+	if slot, err := m.localRPC.GetSlot(ctx); err == nil {
+		heartbeat.Slot = slot
+	}
+
+	return heartbeat
+}
+
+// handleHeartbeat serves our heartbeat to peers
+func (m *Manager) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m.heartbeat(r.Context()))
+}
@@ -0,0 +1,133 @@
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+)
+
+func TestManager_Heartbeat(t *testing.T) {
+	cfg := createTestConfig()
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
+	localRPC.SetSlot(100)
+
+	heartbeat := manager.heartbeat(context.Background())
+	assert.Equal(t, "test-validator", heartbeat.Name)
+	assert.Equal(t, constants.RolePassive, heartbeat.Role)
+	assert.Equal(t, cfg.Validator.Identities.PassiveKeyPair.PublicKey().String(), heartbeat.Identity)
+	assert.True(t, heartbeat.Healthy)
+	assert.Equal(t, uint64(100), heartbeat.Slot)
+
+	localRPC.SetIdentity(cfg.Validator.Identities.ActiveKeyPair.PublicKey())
+	localRPC.SetHealth("behind")
+	heartbeat = manager.heartbeat(context.Background())
+	assert.Equal(t, constants.RoleActive, heartbeat.Role)
+	assert.False(t, heartbeat.Healthy)
+
+	// the role can't be told without the local validator's identity
+	localRPC.SetError("getIdentity", assert.AnError)
+	heartbeat = manager.heartbeat(context.Background())
+	assert.Equal(t, constants.RoleUnknown, heartbeat.Role)
+	assert.Empty(t, heartbeat.Identity)
+}
+
+func TestManager_HandleHeartbeat(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.HealthCheck.Auth = &config.Auth{BearerToken: "healthcheck"}
+	manager, _, _ := newFakeRPCManager(t, cfg)
+
+	get := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, gossip.HeartbeatPath, nil)
+		httpauth.SetBearerToken(req, token)
+		recorder := httptest.NewRecorder()
+		manager.healthCheckHandler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// not served unless failover.heartbeat is enabled
+	assert.Equal(t, http.StatusNotFound, get("healthcheck").Code)
+
+	// authenticated with the secret peers share, not healthcheck.auth
+	cfg.Failover.Heartbeat = config.Heartbeat{Enabled: true, Auth: &config.Auth{BearerToken: "shared"}}
+	assert.Equal(t, http.StatusUnauthorized, get("").Code)
+	assert.Equal(t, http.StatusUnauthorized, get("healthcheck").Code)
+
+	recorder := get("shared")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var heartbeat gossip.Heartbeat
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&heartbeat))
+	assert.Equal(t, constants.RolePassive, heartbeat.Role)
+}
+
+func TestManager_EnsureHAState_HeartbeatConfirmation(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.LeaderlessSamplesThreshold = 1
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
+	cfg.Failover.Heartbeat = config.Heartbeat{Enabled: true, Auth: &config.Auth{BearerToken: "shared"}}
+	cfg.Failover.RequireHeartbeatConfirmation = true
+
+	// peer1 is missing from gossip but its agent still reports the active role
+	role := constants.RoleActive
+	clusterRPC := testutil.NewFakeRPC()
+	localRPC := testutil.NewFakeRPC()
+	localRPC.SetIdentity(cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	localRPC.SetSlot(100)
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: func() (string, error) { return "127.0.0.1", nil },
+		ClusterRPC:      clusterRPC,
+		LocalRPC:        localRPC,
+		FetchHeartbeat: func(ctx context.Context, peer config.Peer) (gossip.Heartbeat, error) {
+			if peer.Name != "peer1" {
+				return gossip.Heartbeat{}, errors.New("unexpected peer " + peer.Name)
+			}
+			return gossip.Heartbeat{Name: peer.Name, Role: role, Healthy: true, Slot: 99}, nil
+		},
+	})
+	require.NoError(t, manager.initialize())
+	clusterRPC.SetClusterNodes(testutil.GossipNode(t, "127.0.0.1", cfg.Validator.Identities.PassiveKeyPair.PublicKey()))
+	clusterRPC.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(cfg.Validator.Identities.PassiveKeyPair.PublicKey())}, nil)
+
+	manager.ensureHAState()
+	assert.Equal(t, DecisionActionNone, manager.decision.Action)
+	assert.Equal(t, DecisionReasonPeerHeartbeatActive, manager.decision.Reason)
+
+	// the heartbeat is in the peer's status and metrics
+	peer := manager.cache.GetState().Peers["peer1"]
+	require.NotNil(t, peer.Heartbeat)
+	assert.True(t, peer.Heartbeat.Reachable)
+	assert.Equal(t, constants.RoleActive, peer.Heartbeat.Role)
+	assert.Equal(t, uint64(99), peer.Heartbeat.Slot)
+	status := manager.status()
+	require.Len(t, status.Peers, 1)
+	require.NotNil(t, status.Peers[0].Heartbeat)
+	assert.Equal(t, "active", status.Peers[0].Heartbeat.Role)
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	var heartbeatActive float64
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() == "solana_validator_ha_peer_heartbeat_active" {
+			heartbeatActive = metricFamily.Metric[0].Gauge.GetValue()
+		}
+	}
+	assert.Equal(t, 1.0, heartbeatActive)
+
+	// a heartbeat reporting passive confirms the failover
+	role = constants.RolePassive
+	manager.ensureHAState()
+	assert.Equal(t, DecisionActionBecomeActive, manager.decision.Action)
+	assert.Equal(t, DecisionReasonNoActivePeer, manager.decision.Reason)
+}
@@ -0,0 +1,202 @@
+package ha
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
+)
+
+const (
+	// HistoryOutcomeConfirmed is a transition confirmed by local rpc
+	HistoryOutcomeConfirmed = "confirmed"
+	// HistoryOutcomeFailed is a transition that failed or was refused once begun
+	HistoryOutcomeFailed = "failed"
+	// HistoryOutcomeDryRun is a transition run in dry run, nothing ran so nothing was confirmed
+	HistoryOutcomeDryRun = "dry_run"
+)
+
+// HistoryEntry is a transition recorded in the failover history, served on /history
+type HistoryEntry struct {
+	// FailoverID is the id the transition logged, recorded and notified with
+	FailoverID string    `json:"failover_id,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	// From is the role we had when the transition began, To the role transitioned to
+	From            string  `json:"from"`
+	To              string  `json:"to"`
+	Reason          string  `json:"reason"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	DryRun          bool    `json:"dry_run"`
+	// Outcome is confirmed, failed or dry_run
+	Outcome string `json:"outcome"`
+	// Error is why a failed transition failed
+	Error string `json:"error,omitempty"`
+	// Command is the role command's outcome, omitted when it never ran
+	Command *CommandOutcome `json:"command,omitempty"`
+	// Hooks are the outcomes of the hooks that ran in order - a post hook retried in the background is recorded
+	// with its first failure
+	Hooks []HookOutcome `json:"hooks"`
+}
+
+// failoverHistory is a fixed-size ring buffer of the most recent transitions, rewritten to file on every one so it
+// survives restarts
+type failoverHistory struct {
+	mu      sync.Mutex
+	entries []HistoryEntry
+	size    int
+	// file is where the history is persisted, empty disables persistence
+	file   string
+	logger *log.Logger
+}
+
+// newFailoverHistory creates a failover history of size entries, loading any persisted to file by a previous run. A
+// file that can't be read or parsed is logged and the history starts empty - it is never a reason not to start.
+func newFailoverHistory(size int, file string, logPrefix string) *failoverHistory {
+	h := &failoverHistory{
+		size:   size,
+		file:   file,
+		logger: loglevel.WithPrefix(loglevel.History, fmt.Sprintf("[%s history]", logPrefix)),
+	}
+	if h.size <= 0 {
+		h.size = config.DefaultHistorySize
+	}
+	if h.file == "" {
+		return h
+	}
+
+	data, err := os.ReadFile(h.file)
+	if os.IsNotExist(err) {
+		return h
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &h.entries)
+	}
+	if err != nil {
+		h.logger.Warn("failed to load failover history - starting empty", "file", h.file, "error", err)
+		h.entries = nil
+		return h
+	}
+	h.trim()
+	h.logger.Debug("loaded failover history", "file", h.file, "entries", len(h.entries))
+	return h
+}
+
+// record adds entry dropping the oldest when full and persists the history if a file is configured
+func (h *failoverHistory) record(entry HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append(h.entries, entry)
+	h.trim()
+
+	if h.file == "" {
+		return
+	}
+	if err := h.persist(); err != nil {
+		h.logger.Warn("failed to persist failover history", "file", h.file, "error", err)
+	}
+}
+
+// Entries returns a copy of the entries, oldest first
+func (h *failoverHistory) Entries() []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]HistoryEntry{}, h.entries...)
+}
+
+// trim drops the oldest entries beyond size - caller must hold the lock
+func (h *failoverHistory) trim() {
+	if len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+	}
+}
+
+// persist writes the history to file - caller must hold the lock
+func (h *failoverHistory) persist() error {
+	return writeFileAtomic(h.file, h.entries)
+}
+
+// beginHistoryEntry starts recording the transition to role for reason in the failover history, returning the func
+// that records it once the transition is done. The hooks, role command and events come from the running admin api
+// transition's report, else from one collected for this transition alone.
+func (m *Manager) beginHistoryEntry(role constants.Role, reason string) (end func()) {
+	startedAt := m.clock.Now()
+	entry := HistoryEntry{
+		FailoverID: m.currentFailoverID(),
+		StartedAt:  startedAt.Time().UTC(),
+		From:       m.cache.GetState().Role.String(),
+		To:         role.String(),
+		Reason:     reason,
+		DryRun:     m.cfg.Failover.DryRun,
+	}
+
+	report := m.transitionReport.Load()
+	owned := report == nil
+	if owned {
+		report = &transitionReport{}
+		m.transitionReport.Store(report)
+	}
+
+	return func() {
+		// the admin api transition's report is still being filled once we are done
+		if owned {
+			m.transitionReport.Store(nil)
+		}
+
+		entry.DurationSeconds = m.clock.Now().Sub(startedAt).Seconds()
+		report.fillHistoryEntry(&entry)
+		if m.history != nil {
+			m.history.record(entry)
+		}
+	}
+}
+
+// fillHistoryEntry copies the collected outcomes into entry, its outcome and error from the events recorded
+func (r *transitionReport) fillHistoryEntry(entry *HistoryEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.Command = r.command
+	entry.Hooks = append([]HookOutcome{}, r.hooks...)
+	entry.Outcome = HistoryOutcomeFailed
+	if entry.DryRun {
+		entry.Outcome = HistoryOutcomeDryRun
+	}
+	for _, event := range r.events {
+		switch event.Type {
+		case events.TypeTransitionFailed:
+			// the first failure is what stopped the transition, a rollback's come after it
+			if entry.Error == "" {
+				entry.Outcome = HistoryOutcomeFailed
+				entry.Error = event.Message
+				if eventErr := event.Fields["error"]; eventErr != "" {
+					entry.Error += ": " + eventErr
+				}
+			}
+		case events.TypeActive, events.TypePassive:
+			if entry.Error == "" {
+				entry.Outcome = HistoryOutcomeConfirmed
+			}
+		}
+	}
+}
+
+// handleHistory serves the failover history, oldest first
+func (m *Manager) handleHistory(w http.ResponseWriter, r *http.Request) {
+	entries := []HistoryEntry{}
+	if m.history != nil {
+		entries = m.history.Entries()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,129 @@
+package ha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverHistory_PersistsAcrossRestarts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state", config.HistoryFileName)
+
+	history := newFailoverHistory(2, file, "test")
+	assert.Empty(t, history.Entries())
+	for _, reason := range []string{"first", "second", "third"} {
+		history.record(HistoryEntry{To: "active", Reason: reason, Hooks: []HookOutcome{}})
+	}
+	assert.Equal(t, []string{"second", "third"}, historyReasons(history.Entries()))
+
+	// a restart loads what was recorded, trimmed to the new size
+	assert.Equal(t, []string{"second", "third"}, historyReasons(newFailoverHistory(2, file, "test").Entries()))
+	assert.Equal(t, []string{"third"}, historyReasons(newFailoverHistory(1, file, "test").Entries()))
+}
+
+func TestFailoverHistory_CorruptFileStartsEmpty(t *testing.T) {
+	file := filepath.Join(t.TempDir(), config.HistoryFileName)
+	require.NoError(t, os.WriteFile(file, []byte("[{not json"), 0o640))
+
+	history := newFailoverHistory(10, file, "test")
+	assert.Empty(t, history.Entries())
+
+	// the next transition replaces it
+	history.record(HistoryEntry{To: "passive", Reason: "recovered"})
+	assert.Equal(t, []string{"recovered"}, historyReasons(newFailoverHistory(10, file, "test").Entries()))
+}
+
+func TestManager_History_RecordsTransitions(t *testing.T) {
+	manager, now := newPauseManager(t)
+
+	manager.ensureActive("leaderless")
+	manager.cfg.Failover.Passive = config.Role{Name: "passive", Command: "false"}
+	now.Advance(time.Minute)
+	manager.ensurePassive("unhealthy")
+
+	entries := manager.history.Entries()
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "active", entries[0].To)
+	assert.Equal(t, "leaderless", entries[0].Reason)
+	assert.Equal(t, HistoryOutcomeConfirmed, entries[0].Outcome)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), entries[0].StartedAt)
+	assert.False(t, entries[0].DryRun)
+	require.NotNil(t, entries[0].Command)
+	assert.Zero(t, entries[0].Command.ExitCode)
+	assert.Empty(t, entries[0].Error)
+
+	assert.Equal(t, "passive", entries[1].To)
+	assert.Equal(t, HistoryOutcomeFailed, entries[1].Outcome)
+	assert.Contains(t, entries[1].Error, "failed to run passive command")
+	require.NotNil(t, entries[1].Command)
+	assert.Equal(t, 1, entries[1].Command.ExitCode)
+
+	// no report is left behind for what runs next
+	assert.Nil(t, manager.transitionReport.Load())
+}
+
+func TestManager_History_DryRunAndHooks(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	manager.cfg.Failover.DryRun = true
+	manager.cfg.Failover.Active.Hooks = config.Hooks{
+		Pre: []config.Hook{{Name: "pre-active", Command: "true"}},
+	}
+
+	manager.ensureActive("leaderless")
+
+	entries := manager.history.Entries()
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].DryRun)
+	assert.Equal(t, HistoryOutcomeDryRun, entries[0].Outcome)
+	require.Len(t, entries[0].Hooks, 1)
+	assert.Equal(t, "pre-active", entries[0].Hooks[0].Name)
+	assert.True(t, entries[0].Hooks[0].DryRun)
+}
+
+func TestManager_History_SkippedWhilePaused(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	_, err := manager.Pause(0)
+	require.NoError(t, err)
+
+	manager.ensureActive("leaderless")
+	assert.Empty(t, manager.history.Entries())
+	assert.Equal(t, []string{events.TypePaused}, recordedTypes(manager))
+}
+
+func TestManager_HandleHistory(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	handler := manager.healthCheckHandler()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/history", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, "[]", recorder.Body.String())
+
+	manager.ensureActive("leaderless")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/history", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var entries []HistoryEntry
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, HistoryOutcomeConfirmed, entries[0].Outcome)
+}
+
+// historyReasons returns the reason of each entry in order
+func historyReasons(entries []HistoryEntry) []string {
+	reasons := []string{}
+	for _, entry := range entries {
+		reasons = append(reasons, entry.Reason)
+	}
+	return reasons
+}
@@ -0,0 +1,91 @@
+package ha
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
+)
+
+const (
+	// logLevelModuleParam is the module whose level POST /log-levels sets, the global level if not given
+	logLevelModuleParam = "module"
+	// logLevelParam is the level POST /log-levels sets
+	logLevelParam = "level"
+	// logLevelDefault resets a module to log at the global level
+	logLevelDefault = "default"
+)
+
+// LogLevels are the global log level and the level of every module, served on /log-levels
+type LogLevels struct {
+	Level  string            `json:"level"`
+	Levels map[string]string `json:"levels"`
+}
+
+// currentLogLevels returns the levels in effect
+func currentLogLevels() LogLevels {
+	global, levels := loglevel.Levels()
+	current := LogLevels{Level: global.String(), Levels: make(map[string]string, len(levels))}
+	for module, level := range levels {
+		current.Levels[module] = level.String()
+	}
+	return current
+}
+
+// setLogLevel sets module's level, the global level when module is empty - a module set to default logs at the
+// global level again. It lasts until the agent restarts, log.levels is where levels are kept.
+func (m *Manager) setLogLevel(module string, level string) (LogLevels, error) {
+	global, _ := loglevel.Levels()
+	overrides := loglevel.Overrides()
+
+	switch {
+	case module == "" && level == logLevelDefault:
+		return LogLevels{}, fmt.Errorf("%s %s is only allowed with a %s", logLevelParam, logLevelDefault, logLevelModuleParam)
+	case level == logLevelDefault:
+		delete(overrides, module)
+	default:
+		parsed, err := log.ParseLevel(level)
+		if err != nil {
+			return LogLevels{}, fmt.Errorf("%s must be one of debug, info, warn, error, fatal, %s - got: %s", logLevelParam, logLevelDefault, level)
+		}
+		if module == "" {
+			global = parsed
+		} else {
+			overrides[module] = parsed
+		}
+	}
+
+	if err := loglevel.Set(global, overrides); err != nil {
+		return LogLevels{}, err
+	}
+	m.logger.Info("log level changed over the admin api", "module", module, "level", level)
+	return currentLogLevels(), nil
+}
+
+// handleLogLevels serves the log levels in effect
+func (m *Manager) handleLogLevels(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currentLogLevels())
+}
+
+// handleSetLogLevel sets the level query parameter as the module query parameter's level, the global level if
+// not given
+func (m *Manager) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	level := query.Get(logLevelParam)
+	if level == "" {
+		http.Error(w, logLevelParam+" is required", http.StatusBadRequest)
+		return
+	}
+
+	levels, err := m.setLogLevel(query.Get(logLevelModuleParam), level)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(levels)
+}
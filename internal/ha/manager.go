@@ -2,49 +2,182 @@ package ha
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net"
 	"net/http"
+	"os"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/charmbracelet/log"
 	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/audit"
 	"github.com/sol-strategies/solana-validator-ha/internal/cache"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
 	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/debugvars"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/gates"
 	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+	"github.com/sol-strategies/solana-validator-ha/internal/lock"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
+	"github.com/sol-strategies/solana-validator-ha/internal/logthrottle"
+	"github.com/sol-strategies/solana-validator-ha/internal/notify"
 	"github.com/sol-strategies/solana-validator-ha/internal/prometheus"
+	"github.com/sol-strategies/solana-validator-ha/internal/rotatefile"
 	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/sdnotify"
+	"github.com/sol-strategies/solana-validator-ha/internal/tlscert"
 )
 
-// RPCClient interface for RPC operations
-type RPCClient interface {
-	GetClusterNodes(ctx context.Context) ([]*solanagorpc.GetClusterNodesResult, error)
-	GetIdentity(ctx context.Context) (*solanagorpc.GetIdentityResult, error)
-}
+const (
+	// commandResultEnvVar is passed to post hooks with the role command's result
+	commandResultEnvVar  = "SVHA_COMMAND_RESULT"
+	commandResultSuccess = "success"
+	commandResultFailure = "failure"
+)
 
 // NewManagerOptions is a struct that contains the configuration for the manager
 type NewManagerOptions struct {
 	Cfg             *config.Config
 	GetPublicIPFunc func() (string, error)
+	// Clock defaults to clock.System
+	Clock clock.Clock
+	// ClusterRPC defaults to a client of cluster.rpc_urls reporting its requests to the rpc metrics and retrying as
+	// cluster.rpc configures
+	ClusterRPC rpc.SolanaClient
+	// LocalRPC defaults to a client of validator.rpc_url like ClusterRPC
+	LocalRPC rpc.SolanaClient
+	// QuorumRPCs default, with failover.min_rpc_confirmations above 1, to a client per cluster.rpc_urls url
+	QuorumRPCs []rpc.SolanaClient
+	// NewPeerRPC returns the client for a peer's rpc_url, defaults to rpc.NewClient
+	NewPeerRPC func(logPrefix string, url string) rpc.SolanaClient
+	// FetchHeartbeat fetches a peer's heartbeat, defaults to gossip.NewHeartbeatFetcher - nil unless
+	// failover.heartbeat is enabled
+	FetchHeartbeat gossip.FetchHeartbeatFunc
 }
 
 // Manager handles high availability logic
 type Manager struct {
-	cfg             *config.Config
-	metrics         *prometheus.Metrics
-	cache           *cache.Cache
-	logger          *log.Logger
-	ctx             context.Context
-	peerSelf        *config.Peer
-	cancel          context.CancelFunc
-	gossipState     *gossip.State
-	getPublicIPFunc func() (string, error)
-	localRPC        *rpc.Client
+	cfg          *config.Config
+	metrics      *prometheus.Metrics
+	cache        *cache.Cache
+	logger       *log.Logger
+	ctx          context.Context
+	peerSelf     *config.Peer
+	cancel       context.CancelFunc
+	gossipState  *gossip.State
+	events       *events.Log
+	history      *failoverHistory
+	sampleHooks  *sampleHookRunner
+	publicIP     *publicIPRefresher
+	clusterRPC   rpc.SolanaClient
+	quorumRPCs   []rpc.SolanaClient
+	newPeerRPC   func(logPrefix string, url string) rpc.SolanaClient
+	lock         *lock.Lock
+	decision     *Decision
+	clock        clock.Clock
+	pollInterval *adaptivePoll
+	timers       *timerRegistry
+	startupSteps *startupTracker
+	// listen binds the server ports, net.Listen outside of tests
+	listen       func(network string, address string) (net.Listener, error)
+	fitnessState fitnessState
+	cooldown     failoverCooldown
+	pause        maintenancePause
+	startupGrace startupGrace
+	// savedState is the state last written to state.file, savedStateAt when - zero if it never was
+	savedState   persistedState
+	savedStateAt clock.Instant
+	// unknownIdentity is true while the local validator reports an identity that is neither active nor passive
+	unknownIdentity bool
+	// identityUnverified is true once local rpc never reported the identity a role command should have switched to
+	identityUnverified bool
+	// voteAccount checks validator.vote_account's node identity, nil when it is not set
+	voteAccount *voteAccountChecker
+	// passiveIdentities are the passive identities already found unstaked
+	passiveIdentities passiveIdentityCheck
+	// fetchHeartbeat fetches a peer's heartbeat, nil when failover.heartbeat is not enabled
+	fetchHeartbeat gossip.FetchHeartbeatFunc
+	// coordinator holds the failover.coordination.etcd key while we are active, nil when it is not set
+	coordinator *coordinator
+	// coordinationAlarmed is true once etcd being unreachable for longer than its unreachable_grace has been alarmed
+	coordinationAlarmed bool
+	// splitBrain are the nodes seen with the active identity in the last refresh when more than one is
+	splitBrain []activeHolder
+	// splitBrainAlarmed is true once the current split brain has been alarmed
+	splitBrainAlarmed bool
+	// rpcOutageAlarmed is true once the current cluster rpc outage has been alarmed
+	rpcOutageAlarmed bool
+	// decisionLog is the failover.decision_log.path file every cycle's decision is appended to, nil when not set
+	decisionLog *rotatefile.File
+	// auditLog is the audit.path file every command run is appended to, nil without a run.state_dir
+	auditLog *audit.Log
+	// hookPool runs post hooks not set async: false after a transition, drained on shutdown
+	hookPool *config.HookPool
+	// logThrottle summarizes warnings repeated every poll while their condition lasts, such as local rpc failures
+	logThrottle *logthrottle.Throttle
+	// shutdownRequested is closed on the first SIGTERM or SIGINT, stopping new HA monitor cycles
+	shutdownRequested chan struct{}
+	shutdownOnce      sync.Once
+	// exit exits the process on a second shutdown signal, os.Exit outside of tests
+	exit func(code int)
+	// systemd is notified of readiness, status and watchdog keep-alives, nil when not run by systemd
+	systemd *sdnotify.Notifier
+	// healthServer and adminServer are the running health check and admin api servers, nil when not serving
+	healthServer *http.Server
+	adminServer  *http.Server
+	// debugServer is the running debug.pprof_port server, nil when not serving
+	debugServer *http.Server
+	// cycleMu serializes HA monitor cycles with transitions requested over the admin api
+	cycleMu sync.Mutex
+	// transitioning is true while ensureActive or ensurePassive runs
+	transitioning atomic.Bool
+	// adminTransitionRunning is true while a transition requested over the admin api runs or waits for the cycle
+	adminTransitionRunning atomic.Bool
+	// transitionReport collects what the running transition runs for its history entry and admin api result, nil
+	// between transitions
+	transitionReport atomic.Pointer[transitionReport]
+	// failoverID is the running transition's id correlating its logs, events and notifications, nil between
+	// transitions - lastFailoverID is the running or last transition's
+	failoverID     atomic.Pointer[string]
+	lastFailoverID atomic.Pointer[string]
+
+	gateState  gateState
+	probeState probeState
+	// gates returns the safety gates checked before a promotion, configuredGates outside of tests
+	gates            func() []gates.Gate
+	promotionBlocked bool
+	// cancelDemotionWatch stops the running post demotion watch, nil when none is running
+	cancelDemotionWatch context.CancelFunc
+	rollbackFailed      bool
+	getPeerFitness      func(peer config.Peer) (PeerFitness, error)
+	getPublicIPFunc     func() (string, error)
+	// publicIPClient asks the public IP services, kept so re-resolving reuses its connections
+	publicIPClient *http.Client
+	// rpcTransport is the keep-alive transport every rpc client the manager creates shares
+	rpcTransport http.RoundTripper
+	// pollCtx is the context of the running HA monitor cycle, cancelled at its deadline and nil between cycles
+	pollCtx context.Context
+	// pollCtxInterval is the poll interval of the running HA monitor cycle, 0 between cycles
+	pollCtxInterval time.Duration
+	localRPC        rpc.SolanaClient
 	peerCount       int
 	initialized     bool
 	logPrefix       string
+	// roleCommandFailed is true from a role command failing until a later transition is confirmed by local rpc
+	roleCommandFailed bool
+	// pagerDuty pages failovers, nil when notifications.pagerduty is not configured
+	pagerDuty *notify.PagerDuty
+	// failoverPaged is true from paging a failover until gossip sees an active peer and it is resolved
+	failoverPaged bool
 }
 
 // NewManager creates a new HA manager from options
@@ -57,41 +190,104 @@ func NewManager(opts NewManagerOptions) *Manager {
 	// Create metrics with cache
 	metrics := prometheus.New(prometheus.Options{
 		Config: opts.Cfg,
-		Logger: log.WithPrefix("metrics"),
+		Logger: loglevel.WithPrefix(loglevel.Metrics, "metrics"),
 		Cache:  cache,
 	})
 
 	manager := &Manager{
-		cfg:       opts.Cfg,
-		metrics:   metrics,
-		cache:     cache,
-		logger:    log.WithPrefix(fmt.Sprintf("[%s ha_manager]", opts.Cfg.Validator.Name)),
-		localRPC:  rpc.NewClient(opts.Cfg.Validator.Name, opts.Cfg.Validator.RPCURL),
-		ctx:       ctx,
-		cancel:    cancel,
-		peerCount: len(opts.Cfg.Failover.Peers),
+		cfg:          opts.Cfg,
+		metrics:      metrics,
+		cache:        cache,
+		logger:       loglevel.WithPrefix(loglevel.HAManager, fmt.Sprintf("[%s ha_manager]", opts.Cfg.Validator.Name)),
+		localRPC:     opts.LocalRPC,
+		clusterRPC:   opts.ClusterRPC,
+		quorumRPCs:   opts.QuorumRPCs,
+		newPeerRPC:   opts.NewPeerRPC,
+		ctx:          ctx,
+		cancel:       cancel,
+		peerCount:    len(opts.Cfg.Failover.Peers),
+		timers:       newTimerRegistry(),
+		startupSteps: &startupTracker{},
+		listen:       net.Listen,
+		exit:         os.Exit,
+		clock:        clock.System,
+		cooldown:     failoverCooldown{duration: opts.Cfg.Failover.Cooldown},
+		startupGrace: startupGrace{duration: opts.Cfg.Failover.StartupGracePeriod},
+		hookPool:     config.NewHookPool(opts.Cfg.Failover.PostHookWorkers),
+
+		shutdownRequested: make(chan struct{}),
+		systemd:           sdnotify.FromEnv(),
+
+		publicIPClient: opts.Cfg.Validator.NewPublicIPHTTPClient(),
+		rpcTransport:   rpc.NewTransport(),
 	}
 
+	if opts.Clock != nil {
+		manager.clock = opts.Clock
+	}
+	manager.logThrottle = logthrottle.New(logthrottle.Options{Clock: manager.clock})
+	if manager.localRPC == nil {
+		manager.localRPC = manager.newRetryingRPC(opts.Cfg.Validator.Name, opts.Cfg.Validator.RPCURL)
+	}
 	if opts.GetPublicIPFunc != nil {
 		manager.getPublicIPFunc = opts.GetPublicIPFunc
 	}
+	manager.getPeerFitness = manager.fetchPeerFitness
+	manager.fetchHeartbeat = opts.FetchHeartbeat
+	if manager.fetchHeartbeat == nil {
+		manager.fetchHeartbeat = gossip.NewHeartbeatFetcher(opts.Cfg)
+	}
+	manager.gates = manager.configuredGates
 
 	return manager
 }
 
+// observeRPCRequest reports an rpc request to metrics and the debug server's expvars
+func (m *Manager) observeRPCRequest(method string, endpoint string, retried bool, duration time.Duration, err error) {
+	m.metrics.ObserveRPCRequest(method, endpoint, retried, duration, err)
+	debugvars.ObserveRPCRequest(method, endpoint, retried, duration, err)
+}
+
+// newRetryingRPC returns an rpc client of urls over the shared transport, reporting its requests to metrics and
+// retrying as cluster.rpc configures, its retries bounded to a poll interval
+func (m *Manager) newRetryingRPC(logPrefix string, urls ...string) *rpc.Client {
+	client := rpc.NewObservedClient(logPrefix, m.rpcTransport, m.observeRPCRequest, urls...)
+	client.SetRetryOptions(rpc.RetryOptions{
+		Retries:        m.cfg.Cluster.RPC.GetRetries(),
+		InitialBackoff: m.cfg.Cluster.RPC.InitialBackoff,
+		MaxBackoff:     m.cfg.Cluster.RPC.MaxBackoff,
+		Jitter:         m.cfg.Cluster.RPC.Jitter,
+		Budget:         m.cfg.Failover.PollIntervalDuration,
+	})
+	return client
+}
+
 // Run starts the HA manager
 func (m *Manager) Run() error {
-	// initialize
-	err := m.initialize()
+	// take the single instance lock first so a second agent on this host fails fast
+	err := m.acquireLock()
 	if err != nil {
 		return err
 	}
+	defer m.releaseLock()
 
-	// start metrics server
-	go m.startMetricsServer()
+	// SIGTERM and SIGINT let an in-progress transition finish, a second one exits at once
+	stopSignals := m.handleShutdownSignals()
+	defer stopSignals()
 
-	// start monitoring loop
-	return m.haMonitorLoop()
+	// initialize, take the first gossip refresh and start the servers within run.startup_timeout
+	err = m.startup()
+	if err != nil {
+		return err
+	}
+	m.notifySystemdReady()
+	m.startLeaseKeepAlive()
+
+	// start monitoring loop, once it stops the transition and post hooks still running get to finish before we
+	// let go of the lock
+	err = m.haMonitorLoop()
+	m.shutdown()
+	return err
 }
 
 // initialize initializes the manager
@@ -105,14 +301,16 @@ func (m *Manager) initialize() error {
 	}
 
 	// get public IP
+	end := m.beginStartupStep(StartupStepPublicIP)
 	publicIP, err := m.getPublicIP()
+	end()
 	if err != nil {
 		return err
 	}
 
 	// set global log prefix to pass everywhere
 	m.logPrefix = m.cfg.Validator.Name
-	m.logger = log.WithPrefix(fmt.Sprintf("[%s ha_manager]", m.logPrefix))
+	m.logger = loglevel.WithPrefix(loglevel.HAManager, fmt.Sprintf("[%s ha_manager]", m.logPrefix))
 
 	// peers config file must not declare ourselves
 	if m.cfg.Failover.Peers.HasIP(publicIP) {
@@ -121,37 +319,148 @@ func (m *Manager) initialize() error {
 
 	// now we can set ourselves as a peer and continue
 	m.logger.Debug("adding us to config peers", "name", m.cfg.Validator.Name, "ip", publicIP)
-	m.peerSelf = &config.Peer{
-		Name: m.cfg.Validator.Name,
-		IP:   publicIP,
-	}
+	peerSelf := m.cfg.Validator.SelfPeer(publicIP)
+	m.peerSelf = &peerSelf
 	m.cfg.Failover.Peers.Add(*m.peerSelf)
 
 	// initialize
 	m.logger.Info("initializing",
 		"public_ip", publicIP,
+		"public_ip_source", m.cfg.Validator.PublicIPSource(),
 		"cluster_rpc_urls", m.cfg.Cluster.RPCURLs,
 		"validator_rpc_url", m.cfg.Validator.RPCURL,
 		"active_pubkey", m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String(),
-		"passive_pubkey", m.cfg.Validator.Identities.PassiveKeyPair.PublicKey().String(),
+		"passive_pubkeys", m.cfg.Validator.Identities.PassivePubkeys(),
 		"peers", m.cfg.Failover.Peers.String(),
 	)
 
+	// create event log - loading any persisted events from a previous run
+	end = m.beginStartupStep(StartupStepEvents)
+	m.events, err = events.New(events.Options{
+		Size:         m.cfg.Events.Size,
+		File:         m.cfg.Events.File,
+		MaxFileBytes: m.cfg.Events.MaxFileBytes,
+		LogPrefix:    m.logPrefix,
+	})
+	end()
+	if err != nil {
+		return err
+	}
+
+	// open the decisions file - only when failover.decision_log.path is set
+	if m.cfg.Failover.DecisionLog.Enabled() {
+		m.decisionLog, err = rotatefile.Open(rotatefile.Options{
+			Path:       m.cfg.Failover.DecisionLog.Path,
+			MaxBytes:   m.cfg.Failover.DecisionLog.MaxFileBytes,
+			MaxBackups: m.cfg.Failover.DecisionLog.MaxBackups,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// open the audit log - carrying on the chain of the commands audited by a previous run
+	if m.cfg.Audit.Enabled() {
+		// compile the redact patterns now, commands are audited from several goroutines
+		m.cfg.Redact.IsSecret("")
+		m.auditLog, err = audit.Open(audit.Options{
+			Path:     m.cfg.Audit.Path,
+			IsSecret: m.cfg.Redact.IsSecret,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// create failover history - loading the transitions recorded by a previous run
+	m.history = newFailoverHistory(m.cfg.History.Size, m.cfg.Run.HistoryFile(), m.logPrefix)
+
 	// create gossip state
 	m.logger.Debug("creating gossip state")
+	if m.clusterRPC == nil {
+		m.clusterRPC = m.newRetryingRPC(m.logPrefix, m.cfg.Cluster.RPCURLs...)
+	}
+	if m.quorumRPCs == nil && m.cfg.Failover.MinRPCConfirmations > 1 {
+		for _, rpcURL := range m.cfg.Cluster.RPCURLs {
+			m.quorumRPCs = append(m.quorumRPCs, m.newRetryingRPC(m.logPrefix, rpcURL))
+		}
+	}
 	m.gossipState = gossip.NewState(gossip.Options{
-		ClusterRPC:   rpc.NewClient(m.logPrefix, m.cfg.Cluster.RPCURLs...),
+		ClusterRPC:   m.clusterRPC,
 		ActivePubkey: m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String(),
+		SelfIP:       m.peerSelf.IP,
 		ConfigPeers:  m.cfg.Failover.Peers,
 		LogPrefix:    m.logPrefix,
+		Clock:        m.clock,
+		NewPeerRPC:   m.newPeerRPC,
+
+		FetchHeartbeat: m.fetchHeartbeat,
+
+		QuorumRPCs:          m.quorumRPCs,
+		MinRPCConfirmations: m.cfg.Failover.MinRPCConfirmations,
+
+		RPCErrorsCountAsLeaderless: m.cfg.Failover.OnRPCOutage == config.RPCOutagePolicyProceed,
 	})
 
+	// restore the counters and last failover of a previous run - only when state.enabled
+	m.loadState()
+
+	// create adaptive poll interval - only stretches when failover.adaptive_poll is enabled
+	m.pollInterval = newAdaptivePoll(m.cfg.Failover, m.logger, m.clock)
+
+	// create public ip refresher - nil when validator.public_ip is static
+	m.publicIP = newPublicIPRefresher(m.cfg.Validator, m.getPublicIP, m.logPrefix, m.clock)
+
+	// create vote account checker - nil when validator.vote_account is not set
+	m.voteAccount = newVoteAccountChecker(m.cfg.Validator, m.checkVoteAccount, m.logger, m.clock)
+
+	// create etcd coordinator - nil when failover.coordination.etcd is not set
+	m.coordinator, err = newCoordinator(m.cfg.Failover.Coordination.Etcd, m.peerSelf.Name, m.logger, m.clock)
+	if err != nil {
+		return err
+	}
+
+	// create sample hook runner - nil when no failover.sample_hooks are configured
+	m.sampleHooks = newSampleHookRunner(m.cfg.Failover, m.logPrefix, m.clock, m.auditLog)
+
+	// create pagerduty client - nil when notifications.pagerduty is not configured
+	m.pagerDuty = m.newPagerDuty()
+
+	// register the timers of the features above so they show up in the timers view
+	m.registerTimers()
+
 	m.logger.Debug("initialized")
 	m.initialized = true
 	return nil
 }
 
-// getPublicIP returns the public IPv4 address using external services.
+// acquireLock takes the run.lock_file flock if configured
+func (m *Manager) acquireLock() (err error) {
+	if m.cfg.Run.LockFile == "" {
+		m.logger.Debug("run.lock_file not set - not taking single instance lock")
+		return nil
+	}
+
+	m.lock, err = lock.Acquire(m.cfg.Run.LockFile)
+	if err != nil {
+		return err
+	}
+
+	if stalePID := m.lock.StalePID(); stalePID != 0 {
+		m.logger.Warn("reclaimed single instance lock left behind by an agent that did not shut down cleanly", "lock_file", m.lock.Path(), "stale_pid", stalePID)
+	}
+	m.logger.Info("acquired single instance lock", "lock_file", m.lock.Path(), "pid", os.Getpid())
+	return nil
+}
+
+// releaseLock releases the single instance lock if held
+func (m *Manager) releaseLock() {
+	if err := m.lock.Release(); err != nil {
+		m.logger.Warn("failed to release single instance lock", "error", err)
+	}
+}
+
+// getPublicIP returns validator.public_ip or else the public IP address using external services.
 // It tries multiple services in order and returns the first successful result.
 func (m *Manager) getPublicIP() (string, error) {
 	// Use override if provided
@@ -159,88 +468,264 @@ func (m *Manager) getPublicIP() (string, error) {
 		return m.getPublicIPFunc()
 	}
 
-	return m.cfg.Validator.PublicIP()
+	return m.cfg.Validator.ResolvePublicIPWith(m.publicIPClient)
 }
 
-// startMetricsServer starts the Prometheus metrics server
-func (m *Manager) startMetricsServer() {
-	// Start the Prometheus metrics server
-	go func() {
-		if err := m.metrics.StartServer(m.cfg.Prometheus.Port); err != nil && err != http.ErrServerClosed {
+// startServers binds the Prometheus metrics and health check server ports and serves them in the background,
+// a port that can't be bound or a certificate that can't be loaded is logged and the agent carries on without
+// that server
+func (m *Manager) startServers() {
+	// Start the Prometheus metrics server unless disabled - metrics may only be mirrored to prometheus.textfile_path
+	if m.cfg.Prometheus.IsEnabled() {
+		end := m.beginStartupStep(StartupStepMetricsBind)
+		listener, err := m.listen("tcp", m.cfg.Prometheus.ListenAddress())
+		end()
+		var tlsConfig *tls.Config
+		if err == nil {
+			tlsConfig, err = m.serverTLSConfig(m.cfg.Prometheus.TLS)
+			if err != nil {
+				listener.Close()
+			}
+		}
+		if err != nil {
 			m.logger.Error("metrics server error", "error", err)
+		} else {
+			go func() {
+				if err := m.metrics.Serve(listener, tlsConfig); err != nil && err != http.ErrServerClosed {
+					m.logger.Error("metrics server error", "error", err)
+				}
+			}()
 		}
-	}()
+	} else {
+		m.logger.Info("prometheus metrics server disabled", "textfile_path", m.cfg.Prometheus.TextfilePath)
+	}
 
-	// Start health check server on a different port
-	go func() {
-		mux := http.NewServeMux()
-		mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("healthy"))
-		})
+	// the admin api has a port of its own so it can be firewalled apart from the health check server
+	m.startAdminAPI()
 
-		port := strconv.Itoa(m.cfg.Prometheus.Port + 1) // Use next port for health check
-		healthServer := &http.Server{
-			Addr:    ":" + port,
-			Handler: mux,
-		}
+	// profiling is opt-in and loopback only unless debug.allow_non_loopback is set
+	m.startDebugServer()
 
-		m.logger.Debug("starting health check server", "port", port)
+	// Start health check server on a different port
+	address := m.cfg.HealthCheck.ListenAddress(m.cfg.Prometheus.HealthCheckPort())
+	end := m.beginStartupStep(StartupStepHealthBind)
+	listener, err := m.listen("tcp", address)
+	end()
+	if err != nil {
+		m.logger.Error("health check server error", "error", err)
+		return
+	}
+	tlsConfig, err := m.serverTLSConfig(m.cfg.HealthCheck.TLS)
+	if err != nil {
+		listener.Close()
+		m.logger.Error("health check server error", "error", err)
+		return
+	}
+
+	healthServer := &http.Server{
+		Addr:      address,
+		Handler:   m.healthCheckHandler(),
+		TLSConfig: tlsConfig,
+	}
+	m.healthServer = healthServer
 
-		if err := healthServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	go func() {
+		m.logger.Info("starting health check server", "address", listener.Addr().String(), "paths", []string{healthPath, livezPath, readyzPath}, "auth", m.cfg.HealthCheck.Auth != nil, "tls", tlsConfig != nil)
+
+		var err error
+		if tlsConfig != nil {
+			// the certificate comes from tlsConfig
+			err = healthServer.ServeTLS(listener, "", "")
+		} else {
+			err = healthServer.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			m.logger.Error("health check server error", "error", err)
 		}
 	}()
 }
 
-// haMonitorLoop runs the main ha monitoring loop
-func (m *Manager) haMonitorLoop() error {
-	m.logger.Info("monitoring HA state", "poll_interval", m.cfg.Failover.PollIntervalDuration)
+// serverTLSConfig returns the server tls.Config of tlsCfg, nil to serve plain http if it is nil. The certificate
+// is reloaded on SIGHUP for as long as the manager runs.
+func (m *Manager) serverTLSConfig(tlsCfg *config.TLS) (*tls.Config, error) {
+	if tlsCfg == nil {
+		return nil, nil
+	}
 
-	// initial gossip state population
-	m.gossipState.Refresh()
+	certificate, err := tlscert.NewReloader(tlsCfg.CertFile, tlsCfg.KeyFile, m.logPrefix)
+	if err != nil {
+		return nil, err
+	}
+	stop := certificate.ReloadOnSIGHUP()
+	go func() {
+		<-m.ctx.Done()
+		stop()
+	}()
 
-	// check for active peer in state and log if found
-	m.checkForActivePeer()
+	return certificate.TLSConfig(), nil
+}
 
-	// start the monitor loop with ticker aligned to interval boundaries
-	ticker := time.NewTicker(m.cfg.Failover.PollIntervalDuration)
-	defer ticker.Stop()
+// healthCheckHandler returns the health check server's routes. Everything but the probes requires the
+// healthcheck.auth bearer token if one is configured, load balancers probe without one.
+func (m *Manager) healthCheckHandler() http.Handler {
+	token := m.cfg.HealthCheck.Auth.Token()
 
-	interval := m.cfg.Failover.PollIntervalDuration
-	intervalNanos := int64(interval)
+	mux := http.NewServeMux()
+	// kept for compatibility - always healthy while serving, see /livez and /readyz
+	mux.HandleFunc(healthPath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("healthy"))
+	})
+	mux.HandleFunc(livezPath, m.handleLivez)
+	mux.HandleFunc(readyzPath, m.handleReadyz)
+	mux.Handle("/events", httpauth.RequireBearerToken(token, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m.events.Events())
+	})))
+	mux.Handle("/status", httpauth.RequireBearerToken(token, http.HandlerFunc(m.handleStatus)))
+	mux.Handle("/history", httpauth.RequireBearerToken(token, http.HandlerFunc(m.handleHistory)))
+	// peer api - peers fetch our advertised fitness during takeover arbitration
+	mux.Handle("/fitness", httpauth.RequireBearerToken(token, http.HandlerFunc(m.handleFitness)))
+	// maintenance mode - solana-validator-ha pause and resume
+	mux.Handle("POST /admin/pause", httpauth.RequireBearerToken(token, http.HandlerFunc(m.handlePause)))
+	mux.Handle("POST /admin/resume", httpauth.RequireBearerToken(token, http.HandlerFunc(m.handleResume)))
+	// peer heartbeat - authenticated with the secret peers share rather than healthcheck.auth
+	if m.cfg.Failover.Heartbeat.Enabled {
+		mux.Handle(gossip.HeartbeatPath, httpauth.RequireBearerToken(m.cfg.Failover.Heartbeat.Auth.Token(), http.HandlerFunc(m.handleHeartbeat)))
+	}
+
+	return mux
+}
+
+// haMonitorLoop runs the main ha monitoring loop on a ticker aligned to poll interval boundaries - a cycle that
+// overruns the interval skips the ticks it missed rather than running them late, so samples stay one interval apart
+func (m *Manager) haMonitorLoop() error {
+	m.logger.Info("monitoring HA state", "poll_interval", m.cfg.Failover.PollIntervalDuration)
+	m.markLoopProgress()
+	m.notifySystemd(sdnotify.Watchdog)
+
+	interval := m.pollInterval.effective()
+	ticker, ok := m.newAlignedTicker(interval)
+	if !ok {
+		return m.stopMonitorLoop()
+	}
+	defer func() { ticker.Stop() }()
 
 	for {
 		select {
 		case <-m.ctx.Done():
-			m.logger.Info("HA monitor loop done")
-			return nil
+			return m.stopMonitorLoop()
+		case <-m.shutdownRequested:
+			return m.stopMonitorLoop()
 		case <-ticker.C:
-			// Wait until the next aligned interval before running
-			// This ensures all nodes run at the same synchronized times
-			// For example, with 5s interval: all nodes run at 12:01:05, 12:01:10, etc.
-			now := time.Now()
-			nanosSinceEpoch := now.UnixNano()
-			remainder := nanosSinceEpoch % intervalNanos
-
-			if remainder != 0 {
-				// Not aligned yet, wait until the next interval boundary
-				waitDuration := interval - time.Duration(remainder)
-				m.logger.Debug(fmt.Sprintf("synchronization, ensuring HA monitor loop runs at %s", now.Add(waitDuration).Format(time.RFC3339)))
+			if m.runPollIteration(interval) {
+				// drop the tick that fell due while overrunning so the next cycle runs on the next boundary
 				select {
-				case <-m.ctx.Done():
-					m.logger.Info("HA monitor loop done")
-					return nil
-				case <-time.After(waitDuration):
-					// Now we're at the aligned time
+				case <-ticker.C:
+				default:
+				}
+			}
+			m.markLoopProgress()
+			m.notifySystemd(sdnotify.Watchdog)
+
+			// pick up any change to the effective poll interval from rate limiting, aligned to its own boundaries
+			if effective := m.pollInterval.effective(); effective != interval {
+				interval = effective
+				ticker.Stop()
+				if ticker, ok = m.newAlignedTicker(interval); !ok {
+					return m.stopMonitorLoop()
 				}
 			}
-			// Run at the aligned interval
-			m.ensureHAState()
 		}
 	}
 }
 
+// newAlignedTicker waits until the next interval boundary and starts a ticker of interval from it, so all nodes
+// run at the same synchronized times - e.g. with a 5s interval at 12:01:05, 12:01:10, etc. Alignment is across
+// hosts so it deliberately uses the wall clock, not m.clock. It returns false if the manager stops while waiting
+func (m *Manager) newAlignedTicker(interval time.Duration) (*time.Ticker, bool) {
+	now := time.Now()
+	waitDuration := interval - time.Duration(now.UnixNano()%int64(interval))
+	m.logger.Debug(fmt.Sprintf("synchronization, ensuring HA monitor loop runs at %s", now.Add(waitDuration).Format(time.RFC3339)))
+
+	select {
+	case <-m.ctx.Done():
+		return nil, false
+	case <-m.shutdownRequested:
+		return nil, false
+	case <-time.After(waitDuration):
+		return time.NewTicker(interval), true
+	}
+}
+
+// runPollIteration runs an HA monitor cycle whose gossip sample must finish within interval, returning true if
+// the cycle overran it
+func (m *Manager) runPollIteration(interval time.Duration) (overran bool) {
+	startedAt := m.clock.Now()
+	ctx, cancel := context.WithTimeout(m.ctx, interval)
+	m.pollCtx = ctx
+	m.pollCtxInterval = interval
+	defer func() {
+		m.pollCtx = nil
+		m.pollCtxInterval = 0
+		cancel()
+	}()
+
+	// a transition requested over the admin api runs between cycles
+	m.cycleMu.Lock()
+	m.ensureHAState()
+	m.saveState()
+	m.cycleMu.Unlock()
+
+	took := m.clock.Now().Sub(startedAt)
+	overran = took > interval
+	m.metrics.ObservePollIteration(overran)
+	debugvars.ObservePollIteration(overran)
+	if overran {
+		m.logger.Warn("HA monitor cycle took longer than the poll interval - skipping the samples it overran",
+			"took", took,
+			"poll_interval", interval,
+			"skipped_samples", int(took/interval),
+		)
+	}
+	return overran
+}
+
+// pollContext returns the context of the running HA monitor cycle, cancelled at its deadline, else the manager's -
+// it bounds the sample a cycle decides on
+func (m *Manager) pollContext() context.Context {
+	if m.pollCtx != nil {
+		return m.pollCtx
+	}
+	return m.ctx
+}
+
+// takeoverContext returns a context cancelled one poll interval from now, for the steps after the takeover delay -
+// the delay may outlast the cycle's deadline, which would fail them at once
+func (m *Manager) takeoverContext() (context.Context, context.CancelFunc) {
+	if m.pollCtxInterval <= 0 {
+		return context.WithCancel(m.ctx)
+	}
+	return context.WithTimeout(m.ctx, m.pollCtxInterval)
+}
+
+// stopMonitorLoop logs the monitor loop stopping and resets its leaderless samples
+func (m *Manager) stopMonitorLoop() error {
+	m.logger.Info("HA monitor loop done")
+	m.resetLeaderlessSamples()
+	return nil
+}
+
+// resetLeaderlessSamples zeroes the leaderless samples count and its metric once the monitor loop stops, so a
+// stopped agent never leaves a count approaching the threshold behind in its metrics
+func (m *Manager) resetLeaderlessSamples() {
+	m.gossipState.LeaderlessSamplesCount = 0
+	state := m.cache.GetState()
+	state.LeaderlessSamples = 0
+	m.cache.UpdateState(state)
+	m.metrics.RefreshMetrics()
+}
+
 // checkForActivePeer checks for an active peer in the gossip state
 func (m *Manager) checkForActivePeer() {
 	if m.gossipState.LeaderlessSamplesExceedsThreshold(m.cfg.Failover.LeaderlessSamplesThreshold) {
@@ -260,23 +745,78 @@ func (m *Manager) checkForActivePeer() {
 		activePeerFoundMessage += " (us)"
 	}
 
-	m.logger.Info(activePeerFoundMessage, "name", activePeerState.Name, "public_ip", activePeerState.IP, "pubkey", activePeerState.Pubkey)
+	m.logger.Info(activePeerFoundMessage,
+		"name", activePeerState.Name,
+		"public_ip", activePeerState.IP,
+		"pubkey", activePeerState.Pubkey,
+		"last_seen_ago", activePeerState.SinceLastSeen(m.clock.Now()),
+	)
 }
 
 // ensureHAState implements basic HA logic
 func (m *Manager) ensureHAState() {
 	m.logger.Debug("ensuring HA")
 
-	// refresh gossip state
-	m.gossipState.Refresh()
+	if m.cfg.Failover.ForcedDryRun {
+		m.logger.Warn("DRY RUN FORCED with --dry-run - role transitions will not run commands or hooks regardless of failover.dry_run")
+	}
+
+	// a maintenance pause resumes on its own once its duration has elapsed
+	m.expirePause()
+
+	// pick up any change of our public ip before matching ourselves in gossip
+	m.refreshPublicIP()
+
+	// check the vote account's node identity in the background when due
+	m.voteAccount.observe()
+
+	// refresh gossip state - bounded by the cycle's deadline so a slow rpc can't push the next sample back
+	m.gossipState.RefreshContext(m.pollContext())
+
+	// adapt the poll interval to any cluster rpc rate limiting seen while refreshing
+	m.pollInterval.update(m.clusterRPC.RateLimits().Stats())
 
 	// refresh metrics
 	m.refreshMetrics()
 
+	// a blocked promotion is reported until the next cycle decides otherwise
+	m.promotionBlocked = false
+
+	// refresh the fitness we advertise to peers
+	m.refreshFitness()
+
+	// capture the decision for this cycle and hand it to any sample hooks once made
+	decision := m.newDecision()
+	m.decision = decision
+	defer m.sampleHooks.observe(decision)
+	defer m.traceDecision(decision)
+
+	// make no decisions while the local validator runs with an identity we don't know
+	if m.holdForUnknownIdentity(decision) {
+		return
+	}
+
+	// an unreachable cluster rpc is not the active peer being gone - failover.on_rpc_outage says whether to decide on
+	if m.holdForRPCOutage(decision) {
+		return
+	}
+
+	// more than one node with the active identity is alarmed and, by failover.split_brain_policy, resolved first
+	if m.handleSplitBrain(decision) {
+		return
+	}
+
+	// with failover.coordination.etcd we keep its key while active and step down once a peer holds it
+	if m.handleCoordination(decision) {
+		return
+	}
+
 	// if there is an active peer found in the last failover.leaderless_samples_threshold - we are good
 	// having a lookback grace period is important to allow for RPC glitches and other issues
 	if !m.gossipState.LeaderlessSamplesExceedsThreshold(m.cfg.Failover.LeaderlessSamplesThreshold) {
 		m.logger.Debug("active peer found - no failover required")
+		m.decide(decision, DecisionActionNone, DecisionReasonActivePeerPresent)
+		m.resolveFailoverPage()
 		return
 	}
 
@@ -285,220 +825,626 @@ func (m *Manager) ensureHAState() {
 
 	// if we don't see ourselves in gossip - bow out of the failover process and make sure we are passive - disconnection or starting up
 	if m.isSelfNotInGossip() {
+		// never held back by failover.cooldown - peers that can't see us may take over while we stay active
 		m.logger.Error("we do not appear in gossip - unable to become active in failover, ensuring we are passive")
-		m.ensurePassive()
+		m.decide(decision, DecisionActionBecomePassive, DecisionReasonSelfNotInGossip)
+		m.ensurePassive(DecisionReasonSelfNotInGossip)
 		// m.gossipState.Refresh() // refresh gossip state for clean next run
 		return
 	}
 	m.logger.Debug("we are in gossip", "pubkey", m.selfGossipPubkey(), "public_ip", m.peerSelf.IP)
+	m.warnIfUnknownGossipIdentity()
 
 	// to participate in failover we must be healthy
 	if m.isSelfUnhealthy() {
 		m.logger.Error("we are not healthy - unable to become active in failover")
+		m.decide(decision, DecisionActionNone, DecisionReasonSelfUnhealthy)
 		return
 	}
 
 	// one last check to ensure we are NOT already active
 	if m.isSelfActive() {
 		m.logger.Warn("we are already active - nothing to do")
+		m.decide(decision, DecisionActionNone, DecisionReasonSelfAlreadyActive)
+		return
+	}
+
+	// never flip back within failover.cooldown of the last transition
+	if m.holdBackTransition(decision, "becoming active") {
+		return
+	}
+
+	// safety gates in warn mode only report what they would have blocked, enforced ones block the promotion
+	gateEvaluation := m.checkGates()
+	decision.Gates = gateEvaluation.Results
+	if gateEvaluation.Blocked {
+		m.logger.Error("safety gates blocked promotion - unable to become active in failover", "gates", gates.Names(gates.Blocked(gateEvaluation.Results)))
+		m.decide(decision, DecisionActionNone, DecisionReasonGateBlocked)
+		return
+	}
+
+	// observe only until failover.startup_grace_period has elapsed - a cluster rpc glitch right after a restart
+	// must not hand us the active identity
+	if remaining := m.startupGrace.remaining(m.clock.Now()); remaining > 0 {
+		m.logger.Warn("in failover.startup_grace_period - not becoming active", "remaining", remaining, "startup_grace_period", m.cfg.Failover.StartupGracePeriod)
+		m.decide(decision, DecisionActionNone, DecisionReasonStartupGrace)
 		return
 	}
 
 	// at this point we know we are in gossip, healthy, and passive
 	// so we begin checks to make sure none of our peers have already taken over as active
 
-	// introduce a delay based on IP to safeguard against multiple nodes trying to become active at the same time
-	m.delayTakeover()
+	// introduce a delay based on our claim order to safeguard against multiple nodes trying to become active at the same time,
+	// never taking over once we are stopped during it
+	if !m.delayTakeover() {
+		m.logger.Warn("stopped while delaying takeover - not taking over")
+		m.decide(decision, DecisionActionNone, DecisionReasonShutdown)
+		return
+	}
 
-	// refresh the peers state to ensure no one else has taken over already if we know
-	// there are at least 2 possible peers other than ourselves - this will reset the leaderless samples count
-	// if a new leader is found
-	m.gossipState.Refresh()
+	// the gossip we decided on is from before the delay - refresh it to ensure no one else has taken over already,
+	// this will reset the leaderless samples count if a new leader is found
+	ctx, cancel := m.takeoverContext()
+	defer cancel()
+	m.gossipState.RefreshContext(ctx)
+
+	// a refresh that failed kept the gossip from before the delay - failover.on_rpc_outage says whether to take over
+	// on it
+	if m.holdForRPCOutage(decision) {
+		return
+	}
 
 	// if someone has already taken over as active - say so and return
 	if m.gossipState.LeaderlessSamplesBelowThreshold(m.cfg.Failover.LeaderlessSamplesThreshold) {
+		m.decide(decision, DecisionActionNone, DecisionReasonPeerTookOver)
+		m.metrics.ObserveTakeoverAborted()
 		activePeerState, err := m.gossipState.GetActivePeer()
 		if err != nil {
-			m.logger.Warn("failed to get active peer from state, but we know someone else already assumed active role", "error", err)
+			m.logger.Warn("takeover aborted - failed to get active peer from state, but we know someone else already assumed active role", "error", err)
 			return
 		}
-		m.logger.Warn(fmt.Sprintf("peer %s is active, seen at %s - noting to do", activePeerState.Name, activePeerState.LastSeenAtString()),
+		m.logger.Warn(fmt.Sprintf("takeover aborted - peer %s became active during the takeover delay, seen at %s", activePeerState.Name, activePeerState.LastSeenAtString()),
 			"ip", activePeerState.IP,
 			"pubkey", activePeerState.Pubkey,
+			"priority", m.cfg.Failover.Peers[activePeerState.Name].Priority,
 		)
 		return
 	}
 
+	// a peer missing from gossip may still be running the active identity - its own rpc is the second signal
+	if m.cfg.Failover.ConfirmWithPeerRPC {
+		if peerRPCState, ok := m.gossipState.ActivePeerRPC(); ok {
+			m.logger.Warn(fmt.Sprintf("peer %s is not active in gossip but its rpc reports the active identity - not taking over", peerRPCState.Name),
+				"ip", peerRPCState.IP,
+				"rpc_url", peerRPCState.RPCURL,
+				"identity", peerRPCState.Identity,
+				"healthy", peerRPCState.Healthy,
+			)
+			m.decide(decision, DecisionActionNone, DecisionReasonPeerRPCActive)
+			return
+		}
+	}
+
+	// gossip lags by 10-30s - a peer's own agent reporting the active role means it hasn't gone yet
+	if m.cfg.Failover.RequireHeartbeatConfirmation {
+		if heartbeatState, ok := m.gossipState.ActivePeerHeartbeat(); ok {
+			m.logger.Warn(fmt.Sprintf("peer %s is not active in gossip but its heartbeat reports the active role - not taking over", heartbeatState.Name),
+				"ip", heartbeatState.IP,
+				"identity", heartbeatState.Heartbeat.Identity,
+				"healthy", heartbeatState.Heartbeat.Healthy,
+				"slot", heartbeatState.Heartbeat.Slot,
+			)
+			m.decide(decision, DecisionActionNone, DecisionReasonPeerHeartbeatActive)
+			return
+		}
+	}
+
+	// with failover.coordination.etcd the active peer's key must have expired and be ours to take over
+	if m.holdForCoordination(ctx, decision) {
+		return
+	}
+
+	// a shutdown requested since the takeover delay ended leaves the active identity to a peer that keeps running
+	if m.shuttingDown() {
+		m.logger.Warn("shutdown requested - not taking over")
+		m.decide(decision, DecisionActionNone, DecisionReasonShutdown)
+		return
+	}
+
 	// now we know we are healthy, passive, and none of our peers have assumed active role
 	// we can take over as active - this should be idempotent in setting the active role
-	m.ensureActive()
+	m.decide(decision, DecisionActionBecomeActive, DecisionReasonNoActivePeer)
+	m.ensureActive(DecisionReasonNoActivePeer)
+}
+
+// holdBackTransition decides on no action and returns true while failover.cooldown since the last transition
+// has not elapsed - transition describes what is being held back
+func (m *Manager) holdBackTransition(decision *Decision, transition string) bool {
+	remaining := m.cooldown.remaining(m.clock.Now())
+	if remaining == 0 {
+		return false
+	}
+
+	m.logger.Warn("in failover.cooldown since the last transition - not "+transition, "remaining", remaining, "cooldown", m.cfg.Failover.Cooldown)
+	m.decide(decision, DecisionActionNone, DecisionReasonCooldown)
+	return true
 }
 
 // ensurePassive calls a user-specified command that should be idempotent in setting the passive role
 // safest thing would be to to ensure validator service always starts with passive identity
-// and the failover.passive.command simply retsarts the validator service or waits for it to start up
-func (m *Manager) ensurePassive() {
+// and the failover.passive.command simply retsarts the validator service or waits for it to start up.
+// reason is passed to the role's hooks as SVHA_REASON.
+func (m *Manager) ensurePassive(reason string) {
+	// in maintenance mode we only say what we would have done
+	if m.skipWhilePaused(constants.RolePassive, reason) {
+		return
+	}
+	m.transitioning.Store(true)
+	defer m.transitioning.Store(false)
+	endFailoverID := m.beginFailoverID()
+	defer endFailoverID()
+	endHistoryEntry := m.beginHistoryEntry(constants.RolePassive, reason)
+	defer endHistoryEntry()
+	// every line the transition logs carries its failover id
+	logger := m.transitionLogger()
+
 	var err error
 	passivePubkey := m.cfg.Validator.Identities.PassiveKeyPair.PublicKey().String()
-	m.logger.Info("becoming passive", "pubkey", passivePubkey)
+	logger.Info("becoming passive", "pubkey", passivePubkey)
+	m.recordEvent(events.TypeBecomingPassive, "becoming passive", append([]string{"pubkey", passivePubkey}, m.transitionLagFields()...)...)
+	wasActive := m.cache.GetState().Role == constants.RoleActive
+	// the cached role is only as fresh as the last monitor cycle, a manual demotion may come before any
+	demoting := wasActive || m.isSelfActive()
+
+	// never transition without the single instance lock - the handle may have been lost since startup
+	if err = m.lock.Check(); err != nil {
+		logger.Error("refusing to become passive", "error", err)
+		m.recordEvent(events.TypeTransitionFailed, "instance lock not held", "role", constants.RolePassive.String(), "error", err.Error())
+		return
+	}
+
+	// never transition once a failed rollback has left the identity in doubt
+	if err = m.checkTransitionsHalted(); err != nil {
+		logger.Error("refusing to become passive", "error", err)
+		m.recordEvent(events.TypeTransitionFailed, "transitions halted", "role", constants.RolePassive.String(), "error", err.Error())
+		return
+	}
 
 	// Update failover status in cache
 	state := m.cache.GetState()
-	state.FailoverStatus = constants.StatusBecomingPassive
+	state.FailoverStatus = constants.FailoverStatusBecomingPassive
 	m.cache.UpdateState(state)
 
+	// captured once so pre and post hooks see the same peer
+	hookContext := m.hookContext(constants.RolePassive, reason)
+
 	// run pre hooks
 	if len(m.cfg.Failover.Passive.Hooks.Pre) > 0 {
-		m.logger.Debug("running pre-passive hooks")
+		logger.Debug("running pre-passive hooks")
 		err = m.cfg.Failover.Passive.Hooks.RunPre(config.HooksRunOptions{
+			Context:      hookContext,
 			DryRun:       m.cfg.Failover.DryRun,
 			LoggerPrefix: m.logPrefix,
+			OnResult:     m.observeHookResult(constants.RolePassive),
 			LoggerArgs: []any{
 				"failover_stage", "pre-passive",
 			},
+			Audit: m.auditLog,
 		})
 	}
 	if err != nil {
-		m.logger.Error("failed to run pre-passive hooks", "error", err)
+		logger.Error("failed to run pre-passive hooks", "error", err)
+		m.recordEvent(events.TypeTransitionFailed, "failed to run pre-passive hooks", "role", constants.RolePassive.String(), "error", err.Error())
 		return
 	}
 
 	// run passive command
-	m.logger.Debug("running passive command")
-	err = m.cfg.Failover.Passive.RunCommand(config.RoleCommandRunOptions{
+	logger.Debug("running passive command")
+	roleCommandResult, err := m.cfg.Failover.Passive.RunCommand(config.RoleCommandRunOptions{
 		DryRun:       m.cfg.Failover.DryRun,
 		LoggerPrefix: m.logPrefix,
+		FailoverID:   m.currentFailoverID(),
 		LoggerArgs: []any{
-			"failover_stage", constants.RoleNamePassive,
+			"failover_stage", constants.RolePassive,
 			"passive_pubkey", passivePubkey,
 		},
+		Audit:              m.auditLog,
+		WriteKeypairFiles:  m.cfg.Validator.Identities.WriteVaultKeyPairFiles,
+		RemoveKeypairFiles: m.cfg.Validator.Identities.RemoveVaultKeyPairFiles,
 	})
+	m.observeRoleCommand(constants.RolePassive, roleCommandResult, err)
+	commandResult := commandResultSuccess
 	if err != nil {
-		m.logger.Warn("failed to run passive command", "error", err)
-		return
+		logger.Warn("failed to run passive command", "error", err)
+		m.recordEvent(events.TypeTransitionFailed, "failed to run passive command", "role", constants.RolePassive.String(), "error", err.Error())
+		m.failRoleCommand()
+		commandResult = commandResultFailure
 	}
 
-	// run post hooks
-	if len(m.cfg.Failover.Passive.Hooks.Post) > 0 {
-		m.logger.Debug("running post-passive hooks")
+	// wait for local rpc to report the passive identity so post hooks know whether the command took effect
+	verified := false
+	if err == nil {
+		verified = m.verifyIdentity(constants.RolePassive, passivePubkey)
+	}
+
+	// run post hooks - after a failed command too so they can alert, unless told not to
+	if len(m.cfg.Failover.Passive.Hooks.Post) > 0 && (err == nil || !m.cfg.Failover.Passive.SkipPostOnFailure) {
+		logger.Debug("running post-passive hooks", "command_result", commandResult, "verified", verified)
 		m.cfg.Failover.Passive.Hooks.RunPost(config.HooksRunOptions{
+			Env:          map[string]string{commandResultEnvVar: commandResult, verifiedEnvVar: strconv.FormatBool(verified)},
+			Context:      hookContext,
 			DryRun:       m.cfg.Failover.DryRun,
 			LoggerPrefix: m.logPrefix,
+			OnResult:     m.observeHookResult(constants.RolePassive),
+			Pool:         m.hookPool,
 			LoggerArgs: []any{
 				"failover_stage", "post-passive",
 			},
+			Audit: m.auditLog,
 		})
 	}
+	if err != nil {
+		return
+	}
+
+	// in dry run the command never ran so the identity never switched
+	if m.cfg.Failover.DryRun {
+		return
+	}
 
 	// check to ensure the call to the failover.passive.command was successful
-	if m.isNotSelfPassive() {
-		m.logger.Error("we are not passive as reported by local rpc - unable to become active in failover",
+	if !verified {
+		logger.Error("we are not passive as reported by local rpc within failover.identity_verify_timeout - unable to become active in failover",
 			"passive_pubkey", passivePubkey,
+			"timeout", m.cfg.Failover.IdentityVerifyTimeout,
 		)
+		m.recordEvent(events.TypeTransitionFailed, "not passive as reported by local rpc", "role", constants.RolePassive.String(), "pubkey", passivePubkey)
+		m.failVerification()
+		m.rollbackTransition(constants.RolePassive)
 		return
 	}
 
-	m.logger.Debug("we are confirmed to be passive as reported by local rpc", "passive_pubkey", passivePubkey)
+	logger.Debug("we are confirmed to be passive as reported by local rpc", "passive_pubkey", passivePubkey)
+	m.confirmTransition()
+	m.cooldown.start(m.clock.Now())
+	// ensuring we stay passive while out of gossip is not a failover
+	if demoting {
+		m.countFailover(constants.RolePassive, reason)
+	}
+	m.recordEvent(events.TypePassive, "confirmed passive by local rpc", "pubkey", passivePubkey)
+
+	// a demotion from active is only trusted once the active identity stops voting from this host
+	if wasActive && !m.cfg.Failover.DryRun {
+		m.startDemotionWatch()
+	}
 
 	// refresh gossip state to warn if we are in gossip but not passive
 	m.gossipState.Refresh()
 
 	// if we are not in gossip, warn - we may be starting up or dropped from the network
 	if m.isSelfNotInGossip() {
-		m.logger.Warn("we are not in gossip after becoming passive", "passive_pubkey", passivePubkey)
+		logger.Warn("we are not in gossip after becoming passive", "passive_pubkey", passivePubkey)
 		return
 	}
 
+	m.warnIfUnknownGossipIdentity()
+
 	// if we are in gossip but not passive, show error - failover.passive.command has likely fucked up
 	if m.isNotSelfPassive() {
-		m.logger.Error("we are in gossip but not passive - this should not happen check failover.passive.command logic", "passive_pubkey", passivePubkey)
+		logger.Error("we are in gossip but not passive - this should not happen check failover.passive.command logic", "passive_pubkey", passivePubkey)
 		return
 	}
 
 	// we are passive by local rpc and in gossip
-	m.logger.Info("we are confirmed to be passive", "passive_pubkey", passivePubkey)
+	logger.Info("we are confirmed to be passive", "passive_pubkey", passivePubkey)
 }
 
 // ensureActive makes the node active - this should be idempotent in setting the  active role
 // safest thing would be to to ensure validator service alywas starts with passive identity
-// and the failover.passive.command simply retsarts the validator service
-func (m *Manager) ensureActive() {
+// and the failover.passive.command simply retsarts the validator service.
+// reason is passed to the role's hooks as SVHA_REASON.
+func (m *Manager) ensureActive(reason string) {
+	// in maintenance mode we only say what we would have done
+	if m.skipWhilePaused(constants.RoleActive, reason) {
+		return
+	}
+	m.transitioning.Store(true)
+	defer m.transitioning.Store(false)
+	endFailoverID := m.beginFailoverID()
+	defer endFailoverID()
+	endHistoryEntry := m.beginHistoryEntry(constants.RoleActive, reason)
+	defer endHistoryEntry()
+	// every line the transition logs carries its failover id
+	logger := m.transitionLogger()
+
 	var err error
 	activePubkey := m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
-	m.logger.Info("becoming active", "pubkey", activePubkey)
+	logger.Info("becoming active", "pubkey", activePubkey)
+	m.recordEvent(events.TypeBecomingActive, "becoming active", append([]string{"pubkey", activePubkey}, m.transitionLagFields()...)...)
+
+	// we are about to vote with the active identity ourselves
+	m.stopDemotionWatch()
+
+	// never transition without the single instance lock - the handle may have been lost since startup
+	if err = m.lock.Check(); err != nil {
+		logger.Error("refusing to become active", "error", err)
+		m.recordEvent(events.TypeTransitionFailed, "instance lock not held", "role", constants.RoleActive.String(), "error", err.Error())
+		return
+	}
+
+	// never transition once a failed rollback has left the identity in doubt
+	if err = m.checkTransitionsHalted(); err != nil {
+		logger.Error("refusing to become active", "error", err)
+		m.recordEvent(events.TypeTransitionFailed, "transitions halted", "role", constants.RoleActive.String(), "error", err.Error())
+		return
+	}
+
+	// enforced safety gates are checked again right before the active command - we may have fallen behind during
+	// the takeover delay, and manual promotions never went through them
+	if evaluation := m.checkEnforcedGates(); evaluation.Blocked {
+		blocked := gates.Names(gates.Blocked(evaluation.Results))
+		logger.Error("safety gates blocked promotion - staying passive", "gates", blocked)
+		m.recordEvent(events.TypeTransitionFailed, "safety gates blocked promotion", "role", constants.RoleActive.String(), "gates", blocked)
+		return
+	}
 
 	// Update failover status in cache
 	state := m.cache.GetState()
-	state.FailoverStatus = constants.StatusBecomingActive
+	state.FailoverStatus = constants.FailoverStatusBecomingActive
 	m.cache.UpdateState(state)
 
+	// page that we are failing over before running anything that could hang
+	m.pageFailover(reason)
+
+	// captured once so pre and post hooks see the same peer
+	hookContext := m.hookContext(constants.RoleActive, reason)
+
 	// run pre hooks
 	if len(m.cfg.Failover.Active.Hooks.Pre) > 0 {
-		m.logger.Debug("running pre-active hooks")
+		logger.Debug("running pre-active hooks")
 		err = m.cfg.Failover.Active.Hooks.RunPre(config.HooksRunOptions{
+			Context:      hookContext,
 			DryRun:       m.cfg.Failover.DryRun,
 			LoggerPrefix: m.logPrefix,
+			OnResult:     m.observeHookResult(constants.RoleActive),
 			LoggerArgs: []any{
 				"failover_stage", "pre-active",
 			},
+			Audit: m.auditLog,
 		})
 	}
 	if err != nil {
-		m.logger.Error("failed to run pre-active hooks", "error", err)
+		logger.Error("failed to run pre-active hooks", "error", err)
+		m.recordEvent(events.TypeTransitionFailed, "failed to run pre-active hooks", "role", constants.RoleActive.String(), "error", err.Error())
+		return
+	}
+
+	// copy the tower file from the last known active peer before taking its identity
+	if err = m.syncTower(); err != nil {
+		logger.Error("failed to sync tower", "error", err)
+		m.recordEvent(events.TypeTransitionFailed, "failed to sync tower", "role", constants.RoleActive.String(), "error", err.Error())
 		return
 	}
 
 	// run active command
-	m.logger.Debug("running active command")
-	err = m.cfg.Failover.Active.RunCommand(config.RoleCommandRunOptions{
+	logger.Debug("running active command")
+	roleCommandResult, err := m.cfg.Failover.Active.RunCommand(config.RoleCommandRunOptions{
 		DryRun:       m.cfg.Failover.DryRun,
 		LoggerPrefix: m.logPrefix,
+		FailoverID:   m.currentFailoverID(),
 		LoggerArgs: []any{
-			"failover_stage", constants.RoleNameActive,
+			"failover_stage", constants.RoleActive,
 			"active_pubkey", activePubkey,
 		},
+		Audit:              m.auditLog,
+		WriteKeypairFiles:  m.cfg.Validator.Identities.WriteVaultKeyPairFiles,
+		RemoveKeypairFiles: m.cfg.Validator.Identities.RemoveVaultKeyPairFiles,
 	})
+	m.observeRoleCommand(constants.RoleActive, roleCommandResult, err)
+	commandResult := commandResultSuccess
 	if err != nil {
-		m.logger.Warn("failed to run active command", "error", err)
-		return
+		logger.Warn("failed to run active command", "error", err)
+		m.recordEvent(events.TypeTransitionFailed, "failed to run active command", "role", constants.RoleActive.String(), "error", err.Error())
+		m.failRoleCommand()
+		commandResult = commandResultFailure
 	}
 
-	// run post hooks
-	if len(m.cfg.Failover.Active.Hooks.Post) > 0 {
-		m.logger.Debug("running post-active hooks")
+	// wait for local rpc to report the active identity so post hooks know whether the command took effect
+	verified := false
+	if err == nil {
+		verified = m.verifyIdentity(constants.RoleActive, activePubkey)
+	}
+
+	// run post hooks - after a failed command too so they can alert, unless told not to
+	if len(m.cfg.Failover.Active.Hooks.Post) > 0 && (err == nil || !m.cfg.Failover.Active.SkipPostOnFailure) {
+		logger.Debug("running post-active hooks", "command_result", commandResult, "verified", verified)
 		m.cfg.Failover.Active.Hooks.RunPost(config.HooksRunOptions{
+			Env:          map[string]string{commandResultEnvVar: commandResult, verifiedEnvVar: strconv.FormatBool(verified)},
+			Context:      hookContext,
 			DryRun:       m.cfg.Failover.DryRun,
 			LoggerPrefix: m.logPrefix,
+			OnResult:     m.observeHookResult(constants.RoleActive),
+			Pool:         m.hookPool,
 			LoggerArgs: []any{
 				"failover_stage", "post-active",
 			},
+			Audit: m.auditLog,
 		})
 	}
+	if err != nil {
+		return
+	}
+
+	// in dry run the command never ran so the identity never switched
+	if m.cfg.Failover.DryRun {
+		return
+	}
 
 	// check to ensure the call to the failover.active.command was successful
-	if !m.isSelfActive() {
-		m.logger.Error("this node is not active as reported by local rpc - unable to become active in failover",
+	if !verified {
+		logger.Error("this node is not active as reported by local rpc within failover.identity_verify_timeout - unable to become active in failover",
 			"active_pubkey", activePubkey,
+			"timeout", m.cfg.Failover.IdentityVerifyTimeout,
 		)
+		m.recordEvent(events.TypeTransitionFailed, "not active as reported by local rpc", "role", constants.RoleActive.String(), "pubkey", activePubkey)
+		m.failVerification()
+		m.rollbackTransition(constants.RoleActive)
 		return
 	}
 
-	m.logger.Info("we are confirmed to be active", "active_pubkey", activePubkey)
+	logger.Info("we are confirmed to be active", "active_pubkey", activePubkey)
+	m.confirmTransition()
+	m.cooldown.start(m.clock.Now())
+	m.countFailover(constants.RoleActive, reason)
+	m.recordEvent(events.TypeActive, "confirmed active by local rpc", "pubkey", activePubkey)
+}
+
+// failRoleCommand records a role command failing - failover status is failed until a later transition is confirmed
+func (m *Manager) failRoleCommand() {
+	m.roleCommandFailed = true
+	state := m.cache.GetState()
+	state.FailoverStatus = constants.FailoverStatusFailed
+	m.cache.UpdateState(state)
+}
+
+// peerVisibility returns the gossip visibility and heartbeat of every configured peer but ourselves, keeping when
+// peers that have since left gossip were last seen from previous
+func (m *Manager) peerVisibility(previous map[string]cache.PeerVisibility) map[string]cache.PeerVisibility {
+	peerStates := m.gossipState.GetPeerStates()
+	heartbeats := m.gossipState.GetPeerHeartbeats()
+	peers := make(map[string]cache.PeerVisibility, len(m.cfg.Failover.Peers))
+	for name, peer := range m.cfg.Failover.Peers {
+		if peer.IP == m.peerSelf.IP {
+			continue
+		}
+
+		visibility := cache.PeerVisibility{IP: peer.IP}
+		if peerState, ok := peerStates[name]; ok {
+			visibility.InGossip = true
+			visibility.LastSeenAt = peerState.LastSeenAtUTC
+		} else if last, ok := previous[name]; ok && last.IP == peer.IP {
+			visibility.LastSeenAt = last.LastSeenAt
+		}
+		if heartbeatState, ok := heartbeats[name]; ok {
+			visibility.Heartbeat = &cache.PeerHeartbeat{
+				Reachable: heartbeatState.Reachable,
+				Role:      heartbeatState.Heartbeat.Role,
+				Identity:  heartbeatState.Heartbeat.Identity,
+				Healthy:   heartbeatState.Heartbeat.Healthy,
+				Slot:      heartbeatState.Heartbeat.Slot,
+				Error:     heartbeatState.Error,
+				CheckedAt: heartbeatState.CheckedAtUTC,
+			}
+		}
+		peers[name] = visibility
+	}
+	return peers
+}
+
+// observeHookResult returns a hooks OnResult recording the duration and outcome of role's hooks - a post hook
+// retried in the background is recorded once, when its retries are done. Results go to the report of the transition
+// running when it is called, never a later one a background retry outlives it into.
+func (m *Manager) observeHookResult(role constants.Role) func(config.HookResult) {
+	report := m.transitionReport.Load()
+	return func(result config.HookResult) {
+		if report != nil {
+			report.addHook(result)
+		}
+		if result.Retrying {
+			return
+		}
+		m.metrics.ObserveHook(result.Hook.Name, result.HookType, role.String(), result.DryRun, result.Duration, result.Err != nil)
+	}
+}
+
+// observeRoleCommand records the duration and outcome of role's command
+func (m *Manager) observeRoleCommand(role constants.Role, result config.RoleCommandResult, err error) {
+	m.metrics.ObserveRoleCommand(role.String(), result.DryRun, result.Duration, err != nil)
+	if report := m.transitionReport.Load(); report != nil {
+		report.setCommand(result, err)
+	}
+}
+
+// countFailover counts a completed transition to role for reason in failovers_total and last_failover_timestamp_seconds
+func (m *Manager) countFailover(role constants.Role, reason string) {
+	state := m.cache.GetState()
+	m.cache.UpdateState(state.WithFailover(role, reason, m.clock.Now().Time()))
+	m.metrics.RefreshMetrics()
+}
+
+// hookContext returns the context role hooks run in when transitioning to role for reason - the peer is the one
+// active in gossip, or else the last one seen active, never ourselves
+func (m *Manager) hookContext(role constants.Role, reason string) *config.HookContext {
+	hookContext := &config.HookContext{
+		Role:          role.String(),
+		Reason:        reason,
+		ValidatorName: m.cfg.Validator.Name,
+		ActivePubkey:  m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String(),
+		PassivePubkey: m.cfg.Validator.Identities.PassiveKeyPair.PublicKey().String(),
+		FailoverID:    m.currentFailoverID(),
+	}
+	if m.gossipState == nil {
+		return hookContext
+	}
+
+	if peerState, ok := m.lastKnownActivePeer(); ok {
+		hookContext.PeerName = peerState.Name
+	}
+	return hookContext
+}
+
+// lastKnownActivePeer returns the peer active in gossip, else the last peer seen active - never ourselves
+func (m *Manager) lastKnownActivePeer() (gossip.PeerState, bool) {
+	if activePeerState, err := m.gossipState.GetActivePeer(); err == nil && !activePeerState.IPEquals(m.peerSelf.IP) {
+		return activePeerState, true
+	}
+	if lastActivePeerState, ok := m.gossipState.LastActivePeer(); ok && !lastActivePeerState.IPEquals(m.peerSelf.IP) {
+		return lastActivePeerState, true
+	}
+	return gossip.PeerState{}, false
+}
+
+// recordEvent records an event in the event log, fields are key/value string pairs
+func (m *Manager) recordEvent(eventType string, message string, fields ...string) {
+	if m.events == nil {
+		return
+	}
+
+	event := events.Event{
+		Time:    time.Now().UTC(),
+		Type:    eventType,
+		Message: message,
+		Fields:  map[string]string{},
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		event.Fields[fields[i]] = fields[i+1]
+	}
+	if id := m.currentFailoverID(); id != "" {
+		event.Fields[failoverIDField] = id
+	}
+
+	m.events.Record(event)
+	if report := m.transitionReport.Load(); report != nil {
+		report.addEvent(event)
+	}
 }
 
 // isSelfHealthy checks if the validator is healthy by calling the local RPC client
 func (m *Manager) isSelfHealthy() (isHealthy bool) {
 	healthStatus, err := m.localRPC.GetHealth(m.ctx)
 	if err != nil {
-		m.logger.Error(err.Error())
+		m.logThrottle.Error(m.logger, "local_rpc_health", err.Error())
 		return false
 	}
+	m.logThrottle.Clear(m.logger, "local_rpc_health")
 
 	isHealthy = healthStatus == solanagorpc.HealthOk
 	m.logger.Debug("health status", "status", healthStatus, "is_healthy", isHealthy)
 
 	if !isHealthy {
-		m.logger.Warn("this node is unhealthy", "status", healthStatus)
+		m.logThrottle.Warn(m.logger, "unhealthy", "this node is unhealthy", "status", healthStatus)
+	} else {
+		m.logThrottle.Clear(m.logger, "unhealthy")
 	}
 
 	return isHealthy
@@ -513,25 +1459,29 @@ func (m *Manager) isSelfUnhealthy() (isUnhealthy bool) {
 func (m *Manager) isSelfActive() (isActive bool) {
 	identity, err := m.localRPC.GetIdentity(m.ctx)
 	if err != nil {
-		m.logger.Error(err.Error())
+		m.logThrottle.Error(m.logger, "local_rpc_identity", err.Error())
 		return false
 	}
+	m.logThrottle.Clear(m.logger, "local_rpc_identity")
 
 	return identity.Identity.String() == m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
 }
 
-// isSelfPassive checks if the validator is passive by checking the local RPC client getIdentity response to confirm it is not the active identity
+// isSelfPassive checks if the validator is passive by checking the local RPC client getIdentity response to confirm it is
+// one of the passive identities
 func (m *Manager) isSelfPassive() bool {
 	identity, err := m.localRPC.GetIdentity(m.ctx)
 	if err != nil {
-		m.logger.Error(err.Error())
+		m.logThrottle.Error(m.logger, "local_rpc_identity", err.Error())
 		return false
 	}
+	m.logThrottle.Clear(m.logger, "local_rpc_identity")
 
-	return identity.Identity.String() != m.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
+	return m.cfg.Validator.Identities.IsPassivePubkey(identity.Identity.String())
 }
 
-// isNotSelfPassive checks if the validator is not passive by checking the local RPC client getIdentity response to confirm it is not the active identity
+// isNotSelfPassive checks if the validator is not passive by checking the local RPC client getIdentity response to confirm it is
+// not one of the passive identities
 func (m *Manager) isNotSelfPassive() (isNotPassive bool) {
 	return !m.isSelfPassive()
 }
@@ -556,21 +1506,49 @@ func (m *Manager) selfGossipPubkey() (pubkey string) {
 	return ""
 }
 
+// selfGossipIP returns the IP of the validator's gossip address, its public IP until it is seen in gossip - they
+// differ on hosts matched in gossip by pubkey
+func (m *Manager) selfGossipIP() string {
+	for _, peer := range m.gossipState.GetPeerStates() {
+		if peer.IP == m.peerSelf.IP && peer.GossipIP != "" {
+			return peer.GossipIP
+		}
+	}
+	return m.peerSelf.IP
+}
+
+// warnIfUnknownGossipIdentity warns when gossip shows us with neither the active nor any passive identity
+func (m *Manager) warnIfUnknownGossipIdentity() {
+	pubkey := m.selfGossipPubkey()
+	identities := m.cfg.Validator.Identities
+	if pubkey == "" || pubkey == identities.ActiveKeyPair.PublicKey().String() || identities.IsPassivePubkey(pubkey) {
+		return
+	}
+	m.logger.Warn("we appear in gossip with an identity that is neither validator.identities.active nor any of validator.identities.passive",
+		"pubkey", pubkey,
+		"public_ip", m.peerSelf.IP,
+		"passive_pubkeys", identities.PassivePubkeys(),
+	)
+}
+
 // refreshMetrics updates the cache with current state
 func (m *Manager) refreshMetrics() {
 	m.logger.Debug("refreshing metrics")
 
 	// Determine role and status
-	var role, status string
+	var role constants.Role
+	var status constants.Status
 	if m.isSelfActive() {
-		role = constants.RoleNameActive
+		role = constants.RoleActive
 	} else if m.isSelfPassive() {
-		role = constants.RoleNamePassive
+		role = constants.RolePassive
 	} else {
-		role = constants.RoleNameUnknown
+		role = constants.RoleUnknown
 	}
 
-	if m.isSelfHealthy() {
+	if m.unknownIdentity || m.identityUnverified || m.voteAccount.mismatched() {
+		status = constants.StatusUnhealthy
+	} else if m.isSelfHealthy() {
 		status = constants.StatusHealthy
 	} else {
 		status = constants.StatusUnhealthy
@@ -580,7 +1558,32 @@ func (m *Manager) refreshMetrics() {
 	peerCount := len(m.gossipState.GetPeerStates())
 	selfInGossip := m.gossipState.HasIP(m.peerSelf.IP)
 
-	// Update cache with current state
+	// more than one node with the active identity is a split brain
+	m.splitBrain = nil
+	var splitBrainPeers []string
+	if holders := m.activeHolders(role == constants.RoleActive); len(holders) > 1 {
+		m.splitBrain = holders
+		splitBrainPeers = activeHolderNames(holders)
+	}
+
+	pauseStatus := m.pauseStatus()
+	var pausedUntil time.Time
+	if pauseStatus.ResumesAt != nil {
+		pausedUntil = *pauseStatus.ResumesAt
+	}
+
+	// a failed rollback is terminal until restart - keep it alarmed rather than reporting idle
+	failoverStatus := constants.FailoverStatusIdle
+	if m.rollbackFailed {
+		failoverStatus = constants.FailoverStatusRollbackFailed
+	} else if m.promotionBlocked {
+		failoverStatus = constants.FailoverStatusBlocked
+	} else if m.roleCommandFailed {
+		failoverStatus = constants.FailoverStatusFailed
+	}
+
+	// Update cache with current state - failover counts are only ever added to by countFailover
+	previous := m.cache.GetState()
 	state := cache.State{
 		ValidatorName:  m.cfg.Validator.Name,
 		PublicIP:       m.peerSelf.IP,
@@ -588,12 +1591,33 @@ func (m *Manager) refreshMetrics() {
 		Status:         status,
 		PeerCount:      peerCount,
 		SelfInGossip:   selfInGossip,
-		FailoverStatus: constants.StatusIdle,
+		Peers:          m.peerVisibility(previous.Peers),
+		FailoverStatus: failoverStatus,
+		FailoverCount:  previous.FailoverCount,
+		LastFailoverAt: previous.LastFailoverAt,
+
+		SplitBrainPeers:       splitBrainPeers,
+		Paused:                pauseStatus.Paused,
+		PausedUntil:           pausedUntil,
+		ClusterRPCErrors:      m.gossipState.ConsecutiveRPCErrors,
+		LeaderlessSamples:     m.gossipState.LeaderlessSamplesCount,
+		EffectivePollInterval: m.pollInterval.effective(),
+		RPCRateLimitedTotal:   m.clusterRPC.RateLimits().Stats().Total,
+		ClusterRPCEndpoint:    m.clusterRPC.ActiveEndpoint(),
+		Timers:                m.timers.snapshot(m.clock.Now()),
+		VoteAccountMismatch:   m.voteAccount.mismatched(),
 	}
 
-	m.cache.UpdateState(state)
+	if m.cache.UpdateState(state) {
+		m.logger.Debug("state changed",
+			"role", role,
+			"status", status,
+			"peer_count", peerCount,
+			"self_in_gossip", selfInGossip,
+		)
+	}
 
-	// Refresh metrics from cache
+	// Refresh metrics from cache - gauges are cheap to re-set so this runs every cycle
 	m.metrics.RefreshMetrics()
 
 	m.logger.Debug("metrics refreshed",
@@ -605,23 +1629,33 @@ func (m *Manager) refreshMetrics() {
 }
 
 // delayTakeover introduces a delay when there are multiple peers
-// to safeguard against multiple nodes trying to become active at the same time
-func (m *Manager) delayTakeover() {
+// to safeguard against multiple nodes trying to become active at the same time, returning false if the manager
+// is stopped or a shutdown is requested during it
+func (m *Manager) delayTakeover() bool {
 	if m.peerCount <= 1 {
-		return
+		return true
 	}
 
-	// get the peer rank - artificial ordering of peers by IP so that it is common across all nodes
-	// running this function
-	selfPeerRank := len(m.cfg.Failover.Peers) + 1
-
-	// if find yourself in the ranked list, use that rank
-	if rank, ok := m.cfg.Failover.Peers.GetRankedIPs()[m.peerSelf.IP]; ok {
-		selfPeerRank = rank
+	// get our claim order - by advertised fitness when enabled, otherwise by peer priority if any is set or else
+	// the static peer rank
+	selfPeerRank, byFitness := m.takeoverRank()
+	byPriority := !byFitness && m.cfg.Failover.Peers.HasPriorities()
+	if byPriority {
+		selfPeerRank = m.priorityTakeoverRank()
 	}
 
-	// set delay seconds based on rank
-	delay := time.Duration(selfPeerRank) * time.Second
+	var delay time.Duration
+	switch {
+	case byPriority:
+		// the highest priority passive in gossip waits only the jitter, each rank below it a stagger more
+		delay = time.Duration(selfPeerRank-1) * m.cfg.Failover.TakeoverPriorityStagger
+	case byFitness:
+		// fitness ordered claims get non-overlapping windows so jitter can't let a less fit peer claim first
+		delay = time.Duration(selfPeerRank)*time.Second + time.Duration(selfPeerRank-1)*m.cfg.Failover.TakeoverJitterDuration
+	default:
+		// set delay seconds based on rank
+		delay = time.Duration(selfPeerRank) * time.Second
+	}
 
 	// add random jitter to the delay to safeguard against multiple nodes trying to become active at the same time
 	// generate a random delay between 0 and TakeoverJitterDuration (inclusive)
@@ -632,7 +1666,14 @@ func (m *Manager) delayTakeover() {
 		delay += time.Duration(randomJitterNanos)
 	}
 
-	m.logger.Debug("delaying takeover to avoid race conditions", "delay", delay, "self_peer_rank", selfPeerRank)
-	time.Sleep(delay)
+	m.logger.Debug("delaying takeover to avoid race conditions", "delay", delay, "self_peer_rank", selfPeerRank, "by_priority", byPriority)
+	select {
+	case <-m.ctx.Done():
+		return false
+	case <-m.shutdownRequested:
+		return false
+	case <-time.After(delay):
+	}
 	m.logger.Debug("takeover delay complete", "self_peer_rank", selfPeerRank)
+	return true
 }
@@ -1,12 +1,32 @@
 package ha
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/charmbracelet/log"
 	solanago "github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/cache"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/sol-strategies/solana-validator-ha/internal/lock"
+	"github.com/sol-strategies/solana-validator-ha/internal/logthrottle"
+	"github.com/sol-strategies/solana-validator-ha/internal/logwriter"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -513,6 +533,35 @@ func TestManager_Run_WithMetricsServer(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestManager_Run_WithMetricsServerDisabled(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Prometheus.Port = 9094
+	enabled := false
+	cfg.Prometheus.Enabled = &enabled
+	cfg.Prometheus.TextfilePath = filepath.Join(t.TempDir(), "solana_validator_ha.prom")
+	cfg.Failover.PollIntervalDuration = 10 * time.Millisecond
+
+	manager := NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Run()
+	}()
+
+	// metrics are still mirrored to the textfile
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(cfg.Prometheus.TextfilePath)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// but nothing serves them over http
+	_, err := http.Get("http://localhost:9094/metrics")
+	assert.Error(t, err)
+
+	manager.cancel()
+	assert.NoError(t, <-done)
+}
+
 func TestManager_Run_WithContextCancellation(t *testing.T) {
 	cfg := createTestConfig()
 
@@ -593,6 +642,58 @@ func TestManager_Run_WithLongRunning(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// blockingWriter blocks every write until unblocked is closed, like a log pipe nobody is reading
+type blockingWriter struct {
+	unblocked chan struct{}
+}
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.unblocked
+	return len(p), nil
+}
+
+func TestManager_Run_ContinuesWhenLogSinkBlocks(t *testing.T) {
+	unblocked := make(chan struct{})
+	defer close(unblocked)
+
+	// route every logger created from here on to a sink that never returns
+	sink := logwriter.New(logwriter.Options{Name: "stderr", Out: blockingWriter{unblocked: unblocked}, QueueSize: 4, WriteTimeout: 10 * time.Millisecond})
+	log.SetOutput(sink)
+	log.SetLevel(log.DebugLevel)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetLevel(log.InfoLevel)
+	}()
+
+	cfg := createTestConfig()
+	cfg.Failover.PollIntervalDuration = 10 * time.Millisecond
+	manager := NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- manager.Run()
+	}()
+
+	// every cycle observes a decision lag - the loop must keep cycling while logging is stuck
+	cycles := func() uint64 {
+		metricFamilies, err := manager.metrics.GetRegistry().Gather()
+		if err != nil {
+			return 0
+		}
+		for _, metricFamily := range metricFamilies {
+			if metricFamily.GetName() == "solana_validator_ha_decision_lag_seconds" {
+				return metricFamily.GetMetric()[0].GetHistogram().GetSampleCount()
+			}
+		}
+		return 0
+	}
+	assert.Eventually(t, func() bool { return cycles() >= 5 }, 5*time.Second, 10*time.Millisecond)
+	assert.Greater(t, sink.Failures(), uint64(0))
+
+	manager.cancel()
+	assert.NoError(t, <-done)
+}
+
 func TestManager_Run_WithGossipStateIntegration(t *testing.T) {
 	cfg := createTestConfig()
 	// Set a short poll interval for testing
@@ -611,7 +712,12 @@ func TestManager_Run_WithGossipStateIntegration(t *testing.T) {
 		done <- manager.Run()
 	}()
 
-	// Let it run for a few poll cycles to test gossip state integration
+	// Let it start up and run for a few poll cycles to test gossip state integration
+	require.Eventually(t, func() bool {
+		manager.probeState.mu.Lock()
+		defer manager.probeState.mu.Unlock()
+		return manager.probeState.ready
+	}, 5*time.Second, 10*time.Millisecond)
 	time.Sleep(100 * time.Millisecond)
 
 	// Verify that the manager was properly initialized
@@ -660,302 +766,682 @@ func TestManager_Run_WithMetricsIntegration(t *testing.T) {
 	assert.NoError(t, err)
 }
 
-func TestManager_EnsurePassive_Success(t *testing.T) {
-	cfg := createTestConfig()
+// newFakeRPCManager returns an initialized manager of cfg whose cluster and local rpc are fakes, the local
+// validator reporting the passive identity until a test says otherwise
+func newFakeRPCManager(t *testing.T, cfg *config.Config) (*Manager, *testutil.FakeRPC, *testutil.FakeRPC) {
+	t.Helper()
 
-	opts := NewManagerOptions{
+	clusterRPC := testutil.NewFakeRPC()
+	localRPC := testutil.NewFakeRPC()
+	localRPC.SetIdentity(cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+
+	manager := NewManager(NewManagerOptions{
 		Cfg:             cfg,
 		GetPublicIPFunc: mockPublicIPFunc,
-	}
+		ClusterRPC:      clusterRPC,
+		LocalRPC:        localRPC,
+	})
+	require.NoError(t, manager.initialize())
 
-	manager := NewManager(opts)
+	return manager, clusterRPC, localRPC
+}
 
-	// Initialize the manager
-	err := manager.initialize()
-	require.NoError(t, err)
+// createLiveTestConfig returns the test config out of dry run, with role commands and hooks that succeed
+func createLiveTestConfig() *config.Config {
+	cfg := createTestConfig()
+	cfg.Failover.DryRun = false
+	cfg.Failover.Active = config.Role{Command: "true", RollbackOnFailure: true}
+	cfg.Failover.Passive = config.Role{Command: "true", RollbackOnFailure: true}
+	return cfg
+}
 
-	// Call ensurePassive - this will use the real RPC client but with dry run
-	manager.ensurePassive()
+func TestManager_EnsurePassive_Success(t *testing.T) {
+	manager, clusterRPC, _ := newFakeRPCManager(t, createLiveTestConfig())
+
+	manager.ensurePassive(DecisionReasonSelfNotInGossip)
 
-	// Verify that cache was updated with becoming_passive status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_passive", state.FailoverStatus)
+	// once verified by local rpc the failover status is back to idle
+	assert.Equal(t, constants.FailoverStatusIdle, manager.cache.GetState().FailoverStatus)
+	assert.Equal(t, []string{events.TypeBecomingPassive, events.TypePassive}, recordedTypes(manager))
+	// gossip is refreshed once confirmed passive
+	assert.Equal(t, 1, clusterRPC.Calls("getClusterNodes"))
 }
 
 func TestManager_EnsurePassive_WithPreHookError(t *testing.T) {
-	cfg := createTestConfig()
-	// Set up a failing pre hook
-	cfg.Failover.Passive.Hooks.Pre = []config.Hook{
-		{Command: "exit 1"}, // This will fail
-	}
+	cfg := createLiveTestConfig()
+	cfg.Failover.Passive.Hooks.Pre = []config.Hook{{Name: "fail", Command: "false", MustSucceed: true}}
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
 
-	opts := NewManagerOptions{
-		Cfg:             cfg,
-		GetPublicIPFunc: mockPublicIPFunc,
-	}
+	manager.ensurePassive(DecisionReasonSelfNotInGossip)
 
-	manager := NewManager(opts)
+	assert.Equal(t, []string{events.TypeBecomingPassive, events.TypeTransitionFailed}, recordedTypes(manager))
+	// the command never ran, so the identity was only read to tell if we were demoting
+	assert.Equal(t, 1, localRPC.Calls("getIdentity"))
+}
 
-	// Initialize the manager
-	err := manager.initialize()
-	require.NoError(t, err)
+func TestManager_EnsurePassive_WithCommandError(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Passive.Command = "false"
+	manager, _, _ := newFakeRPCManager(t, cfg)
 
-	// Call ensurePassive - should handle pre hook error gracefully
-	manager.ensurePassive()
+	manager.ensurePassive(DecisionReasonSelfNotInGossip)
 
-	// Verify that cache was updated with becoming_passive status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_passive", state.FailoverStatus)
+	assert.Equal(t, constants.FailoverStatusFailed, manager.cache.GetState().FailoverStatus)
+	assert.Equal(t, []string{events.TypeBecomingPassive, events.TypeTransitionFailed}, recordedTypes(manager))
 }
 
-func TestManager_EnsurePassive_WithCommandError(t *testing.T) {
-	cfg := createTestConfig()
-	// Set up a failing command
-	cfg.Failover.Passive.Command = "exit 1"
+func TestManager_EnsurePassive_WithRPCError(t *testing.T) {
+	manager, _, localRPC := newFakeRPCManager(t, createLiveTestConfig())
+	localRPC.SetError("getIdentity", assert.AnError)
+
+	manager.ensurePassive(DecisionReasonSelfNotInGossip)
+
+	// the identity can't be confirmed, so the transition fails and is rolled back to active - which can't be
+	// confirmed either
+	assert.Equal(t, constants.FailoverStatusRollbackFailed, manager.cache.GetState().FailoverStatus)
+	assert.Equal(t, []string{
+		events.TypeBecomingPassive,
+		events.TypeTransitionFailed,
+		events.TypeRollingBack,
+		events.TypeRollbackFailed,
+	}, recordedTypes(manager))
+}
 
-	opts := NewManagerOptions{
-		Cfg:             cfg,
-		GetPublicIPFunc: mockPublicIPFunc,
-	}
+func TestManager_EnsurePassive_WithNotPassiveAfterCommand(t *testing.T) {
+	cfg := createLiveTestConfig()
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
+	localRPC.SetIdentity(cfg.Validator.Identities.ActiveKeyPair.PublicKey())
+
+	manager.ensurePassive(DecisionReasonSelfNotInGossip)
+
+	// still active after the command, so rolling back to active is confirmed
+	assert.Equal(t, []string{
+		events.TypeBecomingPassive,
+		events.TypeTransitionFailed,
+		events.TypeRollingBack,
+		events.TypeRolledBack,
+	}, recordedTypes(manager))
+}
 
-	manager := NewManager(opts)
+func TestManager_EnsurePassive_WithNotInGossip(t *testing.T) {
+	cfg := createLiveTestConfig()
+	manager, clusterRPC, _ := newFakeRPCManager(t, cfg)
+
+	// a passive node out of gossip is still confirmed passive
+	manager.ensurePassive(DecisionReasonSelfNotInGossip)
+	assert.Equal(t, 1, clusterRPC.Calls("getClusterNodes"))
+	assert.True(t, manager.isSelfNotInGossip())
+	assert.Equal(t, []string{events.TypeBecomingPassive, events.TypePassive}, recordedTypes(manager))
+}
 
-	// Initialize the manager
-	err := manager.initialize()
-	require.NoError(t, err)
+func TestManager_EnsureActive_Success(t *testing.T) {
+	cfg := createLiveTestConfig()
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
+	// the active command switched the identity
+	localRPC.SetIdentity(cfg.Validator.Identities.ActiveKeyPair.PublicKey())
 
-	// Call ensurePassive - should handle command error gracefully
-	manager.ensurePassive()
+	manager.ensureActive(DecisionReasonNoActivePeer)
 
-	// Verify that cache was updated with becoming_passive status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_passive", state.FailoverStatus)
+	assert.Equal(t, constants.FailoverStatusIdle, manager.cache.GetState().FailoverStatus)
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeActive}, recordedTypes(manager))
+	assert.Equal(t, uint64(1), manager.cache.GetState().FailoverCount[cache.FailoverKey{Direction: constants.RoleActive, Reason: DecisionReasonNoActivePeer}])
 }
 
-func TestManager_EnsurePassive_WithRPCError(t *testing.T) {
-	cfg := createTestConfig()
+func TestManager_EnsureActive_WithPreHookError(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Active.Hooks.Pre = []config.Hook{{Name: "fail", Command: "false", MustSucceed: true}}
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
 
-	opts := NewManagerOptions{
-		Cfg:             cfg,
-		GetPublicIPFunc: mockPublicIPFunc,
-	}
+	manager.ensureActive(DecisionReasonNoActivePeer)
 
-	manager := NewManager(opts)
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeTransitionFailed}, recordedTypes(manager))
+	assert.Zero(t, localRPC.Calls("getIdentity"))
+}
 
-	// Initialize the manager
-	err := manager.initialize()
-	require.NoError(t, err)
+func TestManager_EnsureActive_WithCommandError(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Active.Command = "false"
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
 
-	// Call ensurePassive - will fail due to RPC errors but should handle gracefully
-	manager.ensurePassive()
+	manager.ensureActive(DecisionReasonNoActivePeer)
 
-	// Verify that cache was updated with becoming_passive status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_passive", state.FailoverStatus)
+	assert.Equal(t, constants.FailoverStatusFailed, manager.cache.GetState().FailoverStatus)
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeTransitionFailed}, recordedTypes(manager))
+	assert.Zero(t, localRPC.Calls("getIdentity"))
 }
 
-func TestManager_EnsurePassive_WithNotPassiveAfterCommand(t *testing.T) {
-	cfg := createTestConfig()
+func TestManager_EnsureActive_WithRPCError(t *testing.T) {
+	manager, _, localRPC := newFakeRPCManager(t, createLiveTestConfig())
+	localRPC.SetError("getIdentity", assert.AnError)
+
+	manager.ensureActive(DecisionReasonNoActivePeer)
+
+	assert.Equal(t, constants.FailoverStatusRollbackFailed, manager.cache.GetState().FailoverStatus)
+	assert.Equal(t, []string{
+		events.TypeBecomingActive,
+		events.TypeTransitionFailed,
+		events.TypeRollingBack,
+		events.TypeRollbackFailed,
+	}, recordedTypes(manager))
+}
 
-	opts := NewManagerOptions{
-		Cfg:             cfg,
-		GetPublicIPFunc: mockPublicIPFunc,
+func TestManager_EnsureActive_WithNotActiveAfterCommand(t *testing.T) {
+	manager, _, _ := newFakeRPCManager(t, createLiveTestConfig())
+
+	// still passive after the command, so rolling back to passive is confirmed
+	manager.ensureActive(DecisionReasonNoActivePeer)
+
+	assert.Equal(t, []string{
+		events.TypeBecomingActive,
+		events.TypeTransitionFailed,
+		events.TypeRollingBack,
+		events.TypeRolledBack,
+	}, recordedTypes(manager))
+}
+
+func TestManager_EnsureActive_AdminRPC(t *testing.T) {
+	cfg := createLiveTestConfig()
+	admin := testutil.NewFakeAdminRPC(t)
+	cfg.Failover.Active = config.Role{
+		Name:                "active",
+		Method:              config.RoleMethodAgaveAdminRPC,
+		AdminRPC:            config.AdminRPC{LedgerPath: admin.LedgerPath, RequireTower: true},
+		IdentityKeypairFile: "/keys/active.json",
 	}
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
+	admin.OnSetIdentity(func(string) {
+		localRPC.SetIdentity(cfg.Validator.Identities.ActiveKeyPair.PublicKey())
+	})
 
-	manager := NewManager(opts)
+	manager.ensureActive(DecisionReasonNoActivePeer)
 
-	// Initialize the manager
-	err := manager.initialize()
-	require.NoError(t, err)
+	assert.Equal(t, []testutil.AdminRPCCall{{Method: "setIdentity", Params: []any{"/keys/active.json", true}}}, admin.Calls())
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeActive}, recordedTypes(manager))
+}
+
+func TestManager_EnsureActive_AdminRPCError(t *testing.T) {
+	cfg := createLiveTestConfig()
+	admin := testutil.NewFakeAdminRPC(t)
+	admin.SetError(-32603, "tower not found")
+	cfg.Failover.Active = config.Role{
+		Name:                "active",
+		Method:              config.RoleMethodAgaveAdminRPC,
+		AdminRPC:            config.AdminRPC{SocketPath: admin.SocketPath, RequireTower: true},
+		IdentityKeypairFile: "/keys/active.json",
+	}
+	manager, _, _ := newFakeRPCManager(t, cfg)
 
-	// Call ensurePassive - will fail due to RPC errors but should handle gracefully
-	manager.ensurePassive()
+	manager.ensureActive(DecisionReasonNoActivePeer)
 
-	// Verify that cache was updated with becoming_passive status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_passive", state.FailoverStatus)
+	// a failed call is a failed role command
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeTransitionFailed}, recordedTypes(manager))
+	assert.Equal(t, constants.FailoverStatusFailed, manager.cache.GetState().FailoverStatus)
 }
 
-func TestManager_EnsurePassive_WithNotInGossip(t *testing.T) {
+func TestManager_EnsureActive_WithDryRun(t *testing.T) {
 	cfg := createTestConfig()
+	cfg.Failover.DryRun = true
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
 
-	opts := NewManagerOptions{
-		Cfg:             cfg,
-		GetPublicIPFunc: mockPublicIPFunc,
-	}
+	manager.ensureActive(DecisionReasonNoActivePeer)
 
-	manager := NewManager(opts)
+	// the command never ran, so the identity is not verified and nothing is confirmed, failed or rolled back
+	assert.Equal(t, constants.FailoverStatusBecomingActive, manager.cache.GetState().FailoverStatus)
+	assert.Equal(t, []string{events.TypeBecomingActive}, recordedTypes(manager))
+	assert.Zero(t, localRPC.Calls("getIdentity"))
+}
 
-	// Initialize the manager
-	err := manager.initialize()
-	require.NoError(t, err)
+func TestManager_EnsurePassive_WithDryRun(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.DryRun = true
+	manager, _, _ := newFakeRPCManager(t, cfg)
 
-	// Call ensurePassive - will fail due to RPC errors but should handle gracefully
-	manager.ensurePassive()
+	manager.ensurePassive(DecisionReasonSelfNotInGossip)
 
-	// Verify that cache was updated with becoming_passive status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_passive", state.FailoverStatus)
+	assert.Equal(t, constants.FailoverStatusBecomingPassive, manager.cache.GetState().FailoverStatus)
+	assert.Equal(t, []string{events.TypeBecomingPassive}, recordedTypes(manager))
 }
 
-func TestManager_EnsureActive_Success(t *testing.T) {
-	cfg := createTestConfig()
+func TestManager_EnsureHAState_ActivePeerDisappears(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.LeaderlessSamplesThreshold = 3
+	// a single peer so the takeover isn't delayed
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
 
-	opts := NewManagerOptions{
+	clusterRPC := testutil.NewFakeRPC()
+	localRPC := testutil.NewFakeRPC()
+	localRPC.SetIdentity(cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	manager := NewManager(NewManagerOptions{
 		Cfg:             cfg,
-		GetPublicIPFunc: mockPublicIPFunc,
+		GetPublicIPFunc: func() (string, error) { return "127.0.0.1", nil },
+		ClusterRPC:      clusterRPC,
+		LocalRPC:        localRPC,
+	})
+	require.NoError(t, manager.initialize())
+
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey()
+	self := testutil.GossipNode(t, "127.0.0.1", cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	peer := testutil.GossipNode(t, "127.0.0.2", activePubkey)
+	clusterRPC.SetClusterNodes(self, peer)
+	clusterRPC.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(activePubkey)}, nil)
+
+	// the active peer is voting - nothing to do
+	manager.ensureHAState()
+	assert.Equal(t, DecisionReasonActivePeerPresent, manager.decision.Reason)
+
+	// the active peer leaves gossip - no takeover until it has been missing for threshold samples
+	clusterRPC.SetClusterNodes(self)
+	for sample := 1; sample < cfg.Failover.LeaderlessSamplesThreshold; sample++ {
+		manager.ensureHAState()
+		assert.Equal(t, DecisionReasonActivePeerPresent, manager.decision.Reason, "sample %d", sample)
+		assert.NotContains(t, recordedTypes(manager), events.TypeBecomingActive)
 	}
 
-	manager := NewManager(opts)
+	// on the threshold-th leaderless sample we take over - the fake local rpc still reports the passive identity after
+	// the active command, so the transition fails to confirm and is rolled back
+	manager.ensureHAState()
+	assert.Equal(t, DecisionActionBecomeActive, manager.decision.Action)
+	assert.Equal(t, DecisionReasonNoActivePeer, manager.decision.Reason)
+	assert.Equal(t, []string{
+		events.TypeBecomingActive,
+		events.TypeTransitionFailed,
+		events.TypeRollingBack,
+		events.TypeRolledBack,
+	}, recordedTypes(manager))
+}
 
-	// Initialize the manager
-	err := manager.initialize()
-	require.NoError(t, err)
+func TestManager_SelfInGossip_MatchByPubkey(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Validator.GossipMatchBy = config.PeerMatchByPubkey
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
 
-	// Call ensureActive - this will use the real RPC client but with dry run
-	manager.ensureActive()
+	clusterRPC := testutil.NewFakeRPC()
+	localRPC := testutil.NewFakeRPC()
+	localRPC.SetIdentity(cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: func() (string, error) { return "203.0.113.10", nil },
+		ClusterRPC:      clusterRPC,
+		LocalRPC:        localRPC,
+	})
+	require.NoError(t, manager.initialize())
+
+	// behind nat our gossip address isn't our public ip, we are found by our passive identity
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey()
+	self := testutil.GossipNode(t, "127.0.0.1", cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	peer := testutil.GossipNode(t, "127.0.0.2", activePubkey)
+	clusterRPC.SetClusterNodes(self, peer)
+	clusterRPC.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(activePubkey)}, nil)
+
+	manager.ensureHAState()
+	assert.True(t, manager.isSelfInGossip())
+	assert.Equal(t, "127.0.0.1", manager.selfGossipIP())
+	assert.Equal(t, cfg.Validator.Identities.PassiveKeyPair.PublicKey().String(), manager.selfGossipPubkey())
+	assert.Equal(t, DecisionReasonActivePeerPresent, manager.decision.Reason)
+}
 
-	// Verify that cache was updated with becoming_active status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_active", state.FailoverStatus)
+// reappearingPeerRPC is a cluster rpc whose getClusterNodes starts listing nodes from its nth call, like an active
+// peer reappearing in gossip during the takeover delay
+type reappearingPeerRPC struct {
+	*testutil.FakeRPC
+	nth   int
+	nodes []*solanagorpc.GetClusterNodesResult
 }
 
-func TestManager_EnsureActive_WithPreHookError(t *testing.T) {
-	cfg := createTestConfig()
-	// Set up a failing pre hook
-	cfg.Failover.Active.Hooks.Pre = []config.Hook{
-		{Command: "exit 1"}, // This will fail
+func (r *reappearingPeerRPC) GetClusterNodes(ctx context.Context) ([]*solanagorpc.GetClusterNodesResult, error) {
+	if r.Calls("getClusterNodes")+1 == r.nth {
+		r.SetClusterNodes(r.nodes...)
 	}
+	return r.FakeRPC.GetClusterNodes(ctx)
+}
 
-	opts := NewManagerOptions{
+// newTakeoverManager returns an initialized manager at 127.0.0.1 whose first sample finds no active peer
+func newTakeoverManager(t *testing.T, cfg *config.Config, clusterRPC rpc.SolanaClient, fake *testutil.FakeRPC) *Manager {
+	t.Helper()
+
+	cfg.Failover.LeaderlessSamplesThreshold = 1
+	localRPC := testutil.NewFakeRPC()
+	localRPC.SetIdentity(cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	manager := NewManager(NewManagerOptions{
 		Cfg:             cfg,
-		GetPublicIPFunc: mockPublicIPFunc,
+		GetPublicIPFunc: func() (string, error) { return "127.0.0.1", nil },
+		ClusterRPC:      clusterRPC,
+		LocalRPC:        localRPC,
+	})
+	require.NoError(t, manager.initialize())
+	fake.SetClusterNodes(testutil.GossipNode(t, "127.0.0.1", cfg.Validator.Identities.PassiveKeyPair.PublicKey()))
+
+	return manager
+}
+
+func TestManager_TakeoverAbortedWhenActivePeerReappears(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey()
+	fake := testutil.NewFakeRPC()
+	fake.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(activePubkey)}, nil)
+	clusterRPC := &reappearingPeerRPC{FakeRPC: fake, nth: 2}
+	manager := newTakeoverManager(t, cfg, clusterRPC, fake)
+	clusterRPC.nodes = []*solanagorpc.GetClusterNodesResult{
+		testutil.GossipNode(t, "127.0.0.1", cfg.Validator.Identities.PassiveKeyPair.PublicKey()),
+		testutil.GossipNode(t, "127.0.0.2", activePubkey),
 	}
 
-	manager := NewManager(opts)
+	// the refresh after the takeover delay sees the active peer again
+	manager.ensureHAState()
+	assert.Equal(t, DecisionReasonPeerTookOver, manager.decision.Reason)
+	assert.Equal(t, 2, fake.Calls("getClusterNodes"))
+	assert.Equal(t, 1.0, counterMetric(t, manager, "solana_validator_ha_takeovers_aborted_total"))
+	assert.Empty(t, recordedTypes(manager))
+}
 
-	// Initialize the manager
-	err := manager.initialize()
-	require.NoError(t, err)
+func TestManager_TakeoverDelayInterruptedByShutdown(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Peers = config.Peers{
+		"peer1": {Name: "peer1", IP: "127.0.0.2"},
+		"peer2": {Name: "peer2", IP: "127.0.0.3"},
+	}
+	cfg.Failover.TakeoverJitterDuration = time.Hour
+	fake := testutil.NewFakeRPC()
+	manager := newTakeoverManager(t, cfg, fake, fake)
+
+	// stopped before the takeover delay is over - we never take over on the gossip from before it
+	time.AfterFunc(50*time.Millisecond, manager.cancel)
+	startedAt := time.Now()
+	manager.ensureHAState()
+	assert.Less(t, time.Since(startedAt), 5*time.Second)
+	assert.Equal(t, DecisionReasonShutdown, manager.decision.Reason)
+	assert.Equal(t, 1, fake.Calls("getClusterNodes"))
+	assert.Empty(t, recordedTypes(manager))
+}
 
-	// Call ensureActive - should handle pre hook error gracefully
-	manager.ensureActive()
+func TestManager_TakeoverDelayInterruptedByShutdownRequest(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Peers = config.Peers{
+		"peer1": {Name: "peer1", IP: "127.0.0.2"},
+		"peer2": {Name: "peer2", IP: "127.0.0.3"},
+	}
+	cfg.Failover.TakeoverJitterDuration = time.Hour
+	cfg.Run.ShutdownTimeout = time.Minute
+	marker := filepath.Join(t.TempDir(), "active")
+	cfg.Failover.Active.Command = "touch " + marker
+	fake := testutil.NewFakeRPC()
+	manager := newTakeoverManager(t, cfg, fake, fake)
+
+	// SIGTERM during the takeover delay leaves the manager's context live until run.shutdown_timeout, yet the
+	// delay ends with no role command run
+	time.AfterFunc(50*time.Millisecond, func() { manager.requestShutdown("terminated") })
+	startedAt := time.Now()
+	manager.ensureHAState()
+	assert.Less(t, time.Since(startedAt), 5*time.Second)
+	assert.NoError(t, manager.ctx.Err())
+	assert.Equal(t, DecisionReasonShutdown, manager.decision.Reason)
+	assert.Empty(t, recordedTypes(manager))
+	assert.NoFileExists(t, marker)
+}
 
-	// Verify that cache was updated with becoming_active status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_active", state.FailoverStatus)
+func TestManager_NoTakeoverOnceShutdownRequested(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
+	cfg.Run.ShutdownTimeout = time.Minute
+	marker := filepath.Join(t.TempDir(), "active")
+	cfg.Failover.Active.Command = "touch " + marker
+	fake := testutil.NewFakeRPC()
+	manager := newTakeoverManager(t, cfg, fake, fake)
+
+	// a shutdown requested by the time the takeover would start never flips the identity
+	manager.requestShutdown("terminated")
+	manager.ensureHAState()
+	assert.Equal(t, DecisionReasonShutdown, manager.decision.Reason)
+	assert.Empty(t, recordedTypes(manager))
+	assert.NoFileExists(t, marker)
 }
 
-func TestManager_EnsureActive_WithCommandError(t *testing.T) {
-	cfg := createTestConfig()
-	// Set up a failing command
-	cfg.Failover.Active.Command = "exit 1"
+func TestManager_Run_SecondInstanceFailsOnLock(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "config.yaml.lock")
 
-	opts := NewManagerOptions{
-		Cfg:             cfg,
-		GetPublicIPFunc: mockPublicIPFunc,
-	}
+	cfg := createTestConfig()
+	cfg.Run.LockFile = lockFile
+	first := NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc})
 
-	manager := NewManager(opts)
+	done := make(chan error, 1)
+	go func() {
+		done <- first.Run()
+	}()
 
-	// Initialize the manager
-	err := manager.initialize()
+	// wait for the first instance to record its pid in the lock file
+	require.Eventually(t, func() bool {
+		content, err := os.ReadFile(lockFile)
+		return err == nil && strings.TrimSpace(string(content)) == strconv.Itoa(os.Getpid())
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// a second in-process instance with the same lock file fails fast naming the holder
+	secondCfg := createTestConfig()
+	secondCfg.Run.LockFile = lockFile
+	second := NewManager(NewManagerOptions{Cfg: secondCfg, GetPublicIPFunc: mockPublicIPFunc})
+	err := second.Run()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "lock file "+lockFile+" is held by another solana-validator-ha instance (pid "+strconv.Itoa(os.Getpid())+")")
+
+	var exitCoder interface{ ExitCode() int }
+	require.True(t, errors.As(err, &exitCoder))
+	assert.Equal(t, lock.ExitCodeLocked, exitCoder.ExitCode())
+
+	// the lock is released on shutdown so the next instance can start
+	first.cancel()
+	require.NoError(t, <-done)
+
+	l, err := lock.Acquire(lockFile)
 	require.NoError(t, err)
-
-	// Call ensureActive - should handle command error gracefully
-	manager.ensureActive()
-
-	// Verify that cache was updated with becoming_active status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_active", state.FailoverStatus)
+	require.NoError(t, l.Release())
 }
 
-func TestManager_EnsureActive_WithRPCError(t *testing.T) {
-	cfg := createTestConfig()
+func TestManager_EnsureActive_RefusesWhenLockLost(t *testing.T) {
+	lockFile := filepath.Join(t.TempDir(), "config.yaml.lock")
+	markerFile := filepath.Join(t.TempDir(), "active-command-ran")
 
-	opts := NewManagerOptions{
-		Cfg:             cfg,
-		GetPublicIPFunc: mockPublicIPFunc,
-	}
+	cfg := createTestConfig()
+	cfg.Run.LockFile = lockFile
+	cfg.Failover.DryRun = false
+	cfg.Failover.Active = config.Role{Command: "touch", Args: []string{markerFile}}
 
-	manager := NewManager(opts)
+	manager := NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc})
+	require.NoError(t, manager.acquireLock())
+	defer manager.releaseLock()
 
-	// Initialize the manager
-	err := manager.initialize()
+	var err error
+	manager.events, err = events.New(events.Options{})
 	require.NoError(t, err)
 
-	// Call ensureActive - will fail due to RPC errors but should handle gracefully
-	manager.ensureActive()
+	// the lock file is removed from under us - another instance could now lock a new one
+	require.NoError(t, os.Remove(lockFile))
+
+	manager.ensureActive(DecisionReasonNoActivePeer)
 
-	// Verify that cache was updated with becoming_active status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_active", state.FailoverStatus)
+	assert.NoFileExists(t, markerFile, "active command must not run without the lock")
+	recorded := manager.events.Events()
+	require.NotEmpty(t, recorded)
+	last := recorded[len(recorded)-1]
+	assert.Equal(t, events.TypeTransitionFailed, last.Type)
+	assert.Equal(t, "instance lock not held", last.Message)
 }
 
-func TestManager_EnsureActive_WithNotActiveAfterCommand(t *testing.T) {
-	cfg := createTestConfig()
+func TestManager_EnsureHAState_LogsForcedDryRun(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+	var logged bytes.Buffer
+	manager.logger = log.New(&logged)
 
-	opts := NewManagerOptions{
-		Cfg:             cfg,
+	manager.ensureHAState()
+	assert.NotContains(t, logged.String(), "DRY RUN FORCED")
+
+	// every poll is loud once forced
+	manager.cfg.ForceDryRun()
+	manager.ensureHAState()
+	manager.ensureHAState()
+	assert.Equal(t, 2, strings.Count(logged.String(), "DRY RUN FORCED"))
+}
+
+func TestManager_IsSelfHealthy_ThrottlesRepeatedRPCErrors(t *testing.T) {
+	fake := testutil.NewFakeRPC()
+	now := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager := NewManager(NewManagerOptions{
+		Cfg:             createLiveTestConfig(),
 		GetPublicIPFunc: mockPublicIPFunc,
+		ClusterRPC:      fake,
+		LocalRPC:        fake,
+		Clock:           now,
+	})
+	var logged bytes.Buffer
+	manager.logger = log.New(&logged)
+
+	// a local rpc down for a weekend is logged for the first few polls, then summarized
+	fake.SetError("getHealth", errors.New("connection refused"))
+	for range 20 {
+		assert.False(t, manager.isSelfHealthy())
+		now.Advance(5 * time.Second)
 	}
+	assert.Equal(t, logthrottle.DefaultBurst, strings.Count(logged.String(), "connection refused"))
 
-	manager := NewManager(opts)
+	// and its recovery is clear
+	fake.SetError("getHealth", nil)
+	assert.True(t, manager.isSelfHealthy())
+	assert.Contains(t, logged.String(), "repeated warning cleared")
+	assert.Contains(t, logged.String(), "occurrences=20")
+}
 
-	// Initialize the manager
-	err := manager.initialize()
-	require.NoError(t, err)
+func TestManager_IsSelfPassive_RotatedPassiveIdentity(t *testing.T) {
+	cfg := createTestConfig()
+	rotated := createTestPrivateKey("rotated")
+	cfg.Validator.Identities.PassiveKeyPairs = []*solanago.PrivateKey{cfg.Validator.Identities.PassiveKeyPair, rotated}
+
+	identity := &atomic.Value{}
+	cfg.Validator.RPCURL = identityServer(t, identity).URL
+	manager := NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc})
+
+	// the second passive identity is verified as passive
+	identity.Store(rotated.PublicKey().String())
+	assert.True(t, manager.isSelfPassive())
+	assert.False(t, manager.isSelfActive())
 
-	// Call ensureActive - will fail due to RPC errors but should handle gracefully
-	manager.ensureActive()
+	identity.Store(cfg.Validator.Identities.PassiveKeyPair.PublicKey().String())
+	assert.True(t, manager.isSelfPassive())
 
-	// Verify that cache was updated with becoming_active status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_active", state.FailoverStatus)
+	// neither active nor passive
+	identity.Store(solanago.NewWallet().PublicKey().String())
+	assert.False(t, manager.isSelfPassive())
+	assert.False(t, manager.isSelfActive())
+
+	identity.Store(cfg.Validator.Identities.ActiveKeyPair.PublicKey().String())
+	assert.False(t, manager.isSelfPassive())
 }
 
-func TestManager_EnsureActive_WithDryRun(t *testing.T) {
-	cfg := createTestConfig()
-	cfg.Failover.DryRun = true
+func TestManager_ObservesRPCRequests(t *testing.T) {
+	manager, _ := newManualManager(t, false)
+	require.NoError(t, manager.initialize())
+	manager.gossipState.Refresh()
+	manager.isSelfActive()
 
-	opts := NewManagerOptions{
-		Cfg:             cfg,
-		GetPublicIPFunc: mockPublicIPFunc,
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	methods := map[string]uint64{}
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "solana_validator_ha_rpc_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range metricFamily.Metric {
+			for _, label := range metric.Label {
+				if label.GetName() == "method" {
+					methods[label.GetValue()] += metric.Histogram.GetSampleCount()
+				}
+			}
+		}
 	}
+	assert.Positive(t, methods["getClusterNodes"], "cluster rpc requests are observed")
+	assert.Positive(t, methods["getIdentity"], "local rpc requests are observed")
+}
 
-	manager := NewManager(opts)
+func TestManager_LeaderlessSamples_ResetWhenMonitorLoopStops(t *testing.T) {
+	manager, _ := newManualManager(t, false)
+	require.NoError(t, manager.initialize())
 
-	// Initialize the manager
-	err := manager.initialize()
-	require.NoError(t, err)
+	// no active peer in gossip - each sample counts
+	manager.gossipState.Refresh()
+	manager.gossipState.Refresh()
+	manager.refreshMetrics()
+	assert.Equal(t, 2, manager.cache.GetState().LeaderlessSamples)
+	assert.Equal(t, float64(2), leaderlessSamplesMetric(t, manager))
 
-	// Call ensureActive
-	manager.ensureActive()
+	// the count never outlives the loop counting it
+	manager.cancel()
+	require.NoError(t, manager.haMonitorLoop())
+	assert.Equal(t, 0, manager.gossipState.LeaderlessSamplesCount)
+	assert.Equal(t, 0, manager.cache.GetState().LeaderlessSamples)
+	assert.Equal(t, float64(0), leaderlessSamplesMetric(t, manager))
+}
 
-	// Verify that cache was updated with becoming_active status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_active", state.FailoverStatus)
+// stalledClusterRPC answers every getClusterNodes once its context is done, like a cluster rpc that hangs
+type stalledClusterRPC struct {
+	*testutil.FakeRPC
 }
 
-func TestManager_EnsurePassive_WithDryRun(t *testing.T) {
-	cfg := createTestConfig()
-	cfg.Failover.DryRun = true
+func (s stalledClusterRPC) GetClusterNodes(ctx context.Context) ([]*solanagorpc.GetClusterNodesResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
 
-	opts := NewManagerOptions{
-		Cfg:             cfg,
-		GetPublicIPFunc: mockPublicIPFunc,
+// counterMetric returns the value of the counter called name
+func counterMetric(t *testing.T, manager *Manager, name string) float64 {
+	t.Helper()
+
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() == name {
+			return metricFamily.Metric[0].Counter.GetValue()
+		}
 	}
+	return 0
+}
 
-	manager := NewManager(opts)
+func TestManager_RunPollIteration(t *testing.T) {
+	manager, _, _ := newFakeRPCManager(t, createTestConfig())
 
-	// Initialize the manager
-	err := manager.initialize()
-	require.NoError(t, err)
+	assert.False(t, manager.runPollIteration(time.Minute))
+	assert.Nil(t, manager.pollCtx)
+	assert.Equal(t, 1.0, counterMetric(t, manager, "solana_validator_ha_poll_iterations_total"))
+	assert.Equal(t, 0.0, counterMetric(t, manager, "solana_validator_ha_poll_overruns_total"))
+}
+
+func TestManager_RunPollIteration_Overrun(t *testing.T) {
+	manager, clusterRPC, _ := newFakeRPCManager(t, createTestConfig())
+	manager.gossipState = gossip.NewState(gossip.Options{
+		ClusterRPC:   stalledClusterRPC{clusterRPC},
+		ActivePubkey: manager.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String(),
+		SelfIP:       manager.peerSelf.IP,
+		ConfigPeers:  manager.cfg.Failover.Peers,
+		LogPrefix:    manager.logPrefix,
+	})
+
+	// the hung sample is cut short at the poll interval, fails and is not counted as leaderless
+	startedAt := time.Now()
+	assert.True(t, manager.runPollIteration(50*time.Millisecond))
+	assert.Less(t, time.Since(startedAt), 5*time.Second)
+	assert.Equal(t, 0, manager.gossipState.LeaderlessSamplesCount)
+	assert.Equal(t, 1.0, counterMetric(t, manager, "solana_validator_ha_poll_iterations_total"))
+	assert.Equal(t, 1.0, counterMetric(t, manager, "solana_validator_ha_poll_overruns_total"))
+}
 
-	// Call ensurePassive
-	manager.ensurePassive()
+// leaderlessSamplesMetric returns the exported solana_validator_ha_leaderless_samples value
+func leaderlessSamplesMetric(t *testing.T, manager *Manager) float64 {
+	t.Helper()
 
-	// Verify that cache was updated with becoming_passive status
-	state := manager.cache.GetState()
-	assert.Equal(t, "becoming_passive", state.FailoverStatus)
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() == "solana_validator_ha_leaderless_samples" {
+			return metricFamily.Metric[0].Gauge.GetValue()
+		}
+	}
+	t.Fatal("solana_validator_ha_leaderless_samples not found")
+	return 0
 }
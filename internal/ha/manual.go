@@ -0,0 +1,139 @@
+package ha
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+)
+
+// manualHookReason is the SVHA_REASON role hooks see for an operator initiated promote or demote
+const manualHookReason = "manual"
+
+// ManualTransitionOptions are the options for an operator initiated promote or demote
+type ManualTransitionOptions struct {
+	// Force promotes even though gossip still shows another active peer
+	Force bool
+	// User is recorded as who asked for the transition, $USER if empty
+	User string
+}
+
+// user returns who asked for the transition
+func (o ManualTransitionOptions) user() string {
+	if o.User != "" {
+		return o.User
+	}
+	return os.Getenv("USER")
+}
+
+// transitionRefusedError is a manual transition refused before anything ran
+type transitionRefusedError struct {
+	reason string
+}
+
+// Error implements error
+func (e *transitionRefusedError) Error() string {
+	return e.reason
+}
+
+// Promote makes this node active on demand through the same ensureActive flow, hooks and dry run handling as a
+// failover. It takes the single instance lock so it can't race a running agent, and refuses while gossip shows
+// another active peer unless opts.Force is set.
+func (m *Manager) Promote(opts ManualTransitionOptions) error {
+	return m.manualTransition(constants.RoleActive, opts)
+}
+
+// Demote makes this node passive on demand through the same ensurePassive flow, hooks and dry run handling as a
+// failover. It takes the single instance lock so it can't race a running agent.
+func (m *Manager) Demote() error {
+	return m.manualTransition(constants.RolePassive, ManualTransitionOptions{})
+}
+
+// manualTransition runs the transition to role once and returns an error unless it was confirmed by local rpc
+func (m *Manager) manualTransition(role constants.Role, opts ManualTransitionOptions) error {
+	err := m.acquireLock()
+	if err != nil {
+		return err
+	}
+	defer m.releaseLock()
+
+	err = m.initialize()
+	if err != nil {
+		return err
+	}
+
+	// a snapshot of gossip so we know who else is active
+	m.gossipState.Refresh()
+
+	return m.runManualTransition(role, opts, func() []events.Event { return m.events.Events() })
+}
+
+// runManualTransition runs the transition to role through ensureActive or ensurePassive on the current gossip
+// snapshot, returning an error unless it was confirmed by the last of recorded - a transitionRefusedError if it
+// never started
+func (m *Manager) runManualTransition(role constants.Role, opts ManualTransitionOptions, recorded func() []events.Event) error {
+	// a maintenance pause would skip the transition we were asked for
+	if m.pauseStatus().Paused {
+		return &transitionRefusedError{reason: "failover is paused for maintenance - resume it first"}
+	}
+
+	if role == constants.RoleActive {
+		if m.isSelfActive() {
+			m.logger.Info("already active as reported by local rpc - nothing to do")
+			return nil
+		}
+		if activePeerState, err := m.gossipState.GetActivePeer(); err == nil && !activePeerState.IPEquals(m.peerSelf.IP) {
+			if !opts.Force {
+				return &transitionRefusedError{reason: fmt.Sprintf("peer %s (%s) is active in gossip - demote it first or pass --force to promote anyway", activePeerState.Name, activePeerState.IP)}
+			}
+			m.logger.Warn("forcing promotion while a peer is active in gossip", "name", activePeerState.Name, "ip", activePeerState.IP, "pubkey", activePeerState.Pubkey)
+		}
+	}
+	if role == constants.RolePassive && m.isSelfPassive() {
+		m.logger.Info("already passive as reported by local rpc - nothing to do")
+		return nil
+	}
+
+	m.recordEvent(events.TypeManualTransition, "manual transition requested",
+		"role", role.String(),
+		"force", strconv.FormatBool(opts.Force),
+		"user", opts.user(),
+	)
+
+	if role == constants.RoleActive {
+		m.ensureActive(manualHookReason)
+	} else {
+		m.ensurePassive(manualHookReason)
+	}
+
+	// in dry run nothing ran so local rpc can't confirm the role
+	if m.cfg.Failover.DryRun {
+		m.logger.Info("dry_run - commands and hooks were logged but not run", "role", role)
+		return nil
+	}
+
+	return manualTransitionResult(role, recorded())
+}
+
+// manualTransitionResult returns an error from the last of recorded unless it confirmed role
+func manualTransitionResult(role constants.Role, recorded []events.Event) error {
+	if len(recorded) == 0 {
+		return fmt.Errorf("no events recorded for transition to %s", role)
+	}
+
+	last := recorded[len(recorded)-1]
+	confirmed := events.TypeActive
+	if role == constants.RolePassive {
+		confirmed = events.TypePassive
+	}
+	if last.Type == confirmed {
+		return nil
+	}
+
+	if cause, ok := last.Fields["error"]; ok {
+		return fmt.Errorf("failed to become %s: %s: %s", role, last.Message, cause)
+	}
+	return fmt.Errorf("failed to become %s: %s", role, last.Message)
+}
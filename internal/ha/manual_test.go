@@ -0,0 +1,203 @@
+package ha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/cache"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+)
+
+// newManualManager returns a live (not dry run) manager whose local rpc reports the active identity while the
+// marker file exists - the active command creates it and the passive command removes it. peer1 is in gossip,
+// as the active identity if peerActive.
+func newManualManager(t *testing.T, peerActive bool) (*Manager, string) {
+	t.Helper()
+
+	marker := filepath.Join(t.TempDir(), "active")
+	cfg := createTestConfig()
+	cfg.Failover.DryRun = false
+	cfg.Failover.Peers["peer1"] = config.Peer{Name: "peer1", IP: "127.0.0.1"}
+	gossipPubkey := solana.NewWallet().PublicKey()
+	if peerActive {
+		gossipPubkey = cfg.Validator.Identities.ActiveKeyPair.PublicKey()
+	}
+	cfg.Cluster.RPCURLs = []string{clusterServer(t, gossipPubkey).URL}
+
+	localRPC := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     any    `json:"id"`
+			Method string `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		identity := cfg.Validator.Identities.PassiveKeyPair.PublicKey().String()
+		if _, err := os.Stat(marker); err == nil {
+			identity = cfg.Validator.Identities.ActiveKeyPair.PublicKey().String()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": map[string]string{"identity": identity}})
+	}))
+	t.Cleanup(localRPC.Close)
+	cfg.Validator.RPCURL = localRPC.URL
+
+	cfg.Failover.Active = config.Role{Name: "active", Command: "touch", Args: []string{marker}}
+	cfg.Failover.Passive = config.Role{Name: "passive", Command: "rm", Args: []string{"-f", marker}}
+
+	return NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc}), marker
+}
+
+// recordedTypes returns the recorded event types in order
+func recordedTypes(manager *Manager) []string {
+	types := []string{}
+	for _, event := range manager.events.Events() {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func TestManager_Promote(t *testing.T) {
+	manager, marker := newManualManager(t, false)
+
+	require.NoError(t, manager.Promote(ManualTransitionOptions{}))
+
+	assert.FileExists(t, marker)
+	assert.Equal(t, []string{events.TypeManualTransition, events.TypeBecomingActive, events.TypeActive}, recordedTypes(manager))
+	assert.Equal(t, "active", manager.events.Events()[0].Fields["role"])
+}
+
+func TestManager_Promote_RefusesWhilePeerActive(t *testing.T) {
+	manager, marker := newManualManager(t, true)
+
+	err := manager.Promote(ManualTransitionOptions{})
+	assert.ErrorContains(t, err, "peer peer1 (127.0.0.1) is active in gossip")
+	assert.NoFileExists(t, marker)
+	assert.Empty(t, manager.events.Events())
+}
+
+func TestManager_Promote_Force(t *testing.T) {
+	manager, marker := newManualManager(t, true)
+
+	require.NoError(t, manager.Promote(ManualTransitionOptions{Force: true}))
+	assert.FileExists(t, marker)
+	assert.Equal(t, "true", manager.events.Events()[0].Fields["force"])
+}
+
+func TestManager_Promote_ReturnsTransitionFailure(t *testing.T) {
+	manager, marker := newManualManager(t, false)
+	manager.cfg.Failover.Active.Command = "false"
+
+	err := manager.Promote(ManualTransitionOptions{})
+	assert.ErrorContains(t, err, "failed to become active: failed to run active command")
+	assert.NoFileExists(t, marker)
+}
+
+func TestManager_Promote_DryRun(t *testing.T) {
+	manager, marker := newManualManager(t, false)
+	manager.cfg.Failover.DryRun = true
+
+	require.NoError(t, manager.Promote(ManualTransitionOptions{}))
+	assert.NoFileExists(t, marker)
+}
+
+func TestManager_Demote(t *testing.T) {
+	manager, marker := newManualManager(t, false)
+	require.NoError(t, os.WriteFile(marker, nil, 0o600))
+
+	require.NoError(t, manager.Demote())
+
+	assert.NoFileExists(t, marker)
+	assert.Equal(t, []string{events.TypeManualTransition, events.TypeBecomingPassive, events.TypePassive}, recordedTypes(manager))
+}
+
+func TestManager_Demote_AlreadyPassive(t *testing.T) {
+	manager, _ := newManualManager(t, false)
+
+	require.NoError(t, manager.Demote())
+	assert.Empty(t, manager.events.Events())
+}
+
+func TestManager_PromoteDemote_CountsFailovers(t *testing.T) {
+	manager, _ := newManualManager(t, false)
+
+	require.NoError(t, manager.Promote(ManualTransitionOptions{}))
+	state := manager.cache.GetState()
+	assert.Equal(t, map[cache.FailoverKey]uint64{{Direction: constants.RoleActive, Reason: manualHookReason}: 1}, state.FailoverCount)
+	assert.False(t, state.LastFailoverAt.IsZero())
+
+	require.NoError(t, manager.Demote())
+	assert.Equal(t, map[cache.FailoverKey]uint64{
+		{Direction: constants.RoleActive, Reason: manualHookReason}:  1,
+		{Direction: constants.RolePassive, Reason: manualHookReason}: 1,
+	}, manager.cache.GetState().FailoverCount)
+
+	// the counts survive the next monitor cycle rebuilding the cached state
+	manager.refreshMetrics()
+	assert.Len(t, manager.cache.GetState().FailoverCount, 2)
+}
+
+func TestManager_RefreshMetrics_PeerVisibility(t *testing.T) {
+	manager, _ := newManualManager(t, false)
+
+	// only peer1 is in gossip
+	require.NoError(t, manager.initialize())
+	manager.gossipState.Refresh()
+	manager.refreshMetrics()
+	peers := manager.cache.GetState().Peers
+	require.Len(t, peers, 2)
+	assert.True(t, peers["peer1"].InGossip)
+	assert.Equal(t, "127.0.0.1", peers["peer1"].IP)
+	assert.False(t, peers["peer1"].LastSeenAt.IsZero())
+	assert.Equal(t, cache.PeerVisibility{IP: "192.168.1.102"}, peers["peer2"])
+
+	// a peer that left gossip keeps when it was last seen, unless its ip changed
+	seenAt := time.Now().Add(-time.Minute)
+	peers = manager.peerVisibility(map[string]cache.PeerVisibility{"peer2": {IP: "192.168.1.102", LastSeenAt: seenAt}})
+	assert.Equal(t, cache.PeerVisibility{IP: "192.168.1.102", LastSeenAt: seenAt}, peers["peer2"])
+	peers = manager.peerVisibility(map[string]cache.PeerVisibility{"peer2": {IP: "192.168.1.12", LastSeenAt: seenAt}})
+	assert.Equal(t, cache.PeerVisibility{IP: "192.168.1.102"}, peers["peer2"])
+}
+
+func TestManager_Promote_ObservesHooksAndRoleCommand(t *testing.T) {
+	manager, _ := newManualManager(t, false)
+	manager.cfg.Failover.Active.Hooks.Pre = []config.Hook{{Name: "pre-fails", Command: "false", Timeout: time.Second}}
+
+	require.NoError(t, manager.Promote(ManualTransitionOptions{}))
+
+	// sampleCounts returns the histogram sample counts of name by their labels other than the common ones
+	sampleCounts := func(name string) map[string]uint64 {
+		metricFamilies, err := manager.metrics.GetRegistry().Gather()
+		require.NoError(t, err)
+		counts := map[string]uint64{}
+		for _, metricFamily := range metricFamilies {
+			if metricFamily.GetName() != name {
+				continue
+			}
+			for _, metric := range metricFamily.Metric {
+				labels := []string{}
+				for _, label := range metric.Label {
+					if label.GetName() != "validator_name" && label.GetName() != "public_ip" {
+						labels = append(labels, label.GetName()+"="+label.GetValue())
+					}
+				}
+				counts[strings.Join(labels, ",")] = metric.Histogram.GetSampleCount()
+			}
+		}
+		return counts
+	}
+	assert.Equal(t, map[string]uint64{"dry_run=false,hook_name=pre-fails,phase=pre,role=active": 1}, sampleCounts("solana_validator_ha_hook_duration_seconds"))
+	assert.Equal(t, map[string]uint64{"dry_run=false,role=active": 1}, sampleCounts("solana_validator_ha_role_command_duration_seconds"))
+}
@@ -0,0 +1,79 @@
+package ha
+
+import (
+	"fmt"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/notify"
+)
+
+const (
+	// notifierPagerDuty is the notifier label of pagerduty notifications
+	notifierPagerDuty = "pagerduty"
+
+	notifyResultSuccess = "success"
+	notifyResultFailure = "failure"
+)
+
+// newPagerDuty returns the pagerduty client of notifications.pagerduty, nil when it is not configured
+func (m *Manager) newPagerDuty() *notify.PagerDuty {
+	pagerDuty := m.cfg.Notifications.PagerDuty
+	if pagerDuty == nil {
+		return nil
+	}
+
+	return notify.NewPagerDuty(notify.PagerDutyOptions{
+		URL:        pagerDuty.URL,
+		RoutingKey: pagerDuty.RoutingKey,
+		Severity:   pagerDuty.Severity,
+		Source:     m.cfg.Validator.Name,
+		Timeout:    pagerDuty.Timeout,
+		Retries:    pagerDuty.GetRetries(),
+		RetryDelay: pagerDuty.RetryDelay,
+		DryRun:     m.cfg.Failover.DryRun,
+		LogPrefix:  m.logPrefix,
+		OnResult: func(action string, err error) {
+			result := notifyResultSuccess
+			if err != nil {
+				result = notifyResultFailure
+			}
+			m.metrics.ObserveNotification(notifierPagerDuty, action, result)
+		},
+	})
+}
+
+// pageFailover triggers a pagerduty incident as we start becoming active because no active peer was seen -
+// manual promotions and rollbacks are not failovers and don't page
+func (m *Manager) pageFailover(reason string) {
+	if m.pagerDuty == nil || reason != DecisionReasonNoActivePeer {
+		return
+	}
+
+	hookContext := m.hookContext(constants.RoleActive, reason)
+	details := map[string]string{
+		"validator_name": m.cfg.Validator.Name,
+		"public_ip":      m.peerSelf.IP,
+		"reason":         reason,
+		"active_pubkey":  hookContext.ActivePubkey,
+		"passive_pubkey": hookContext.PassivePubkey,
+		"previous_peer":  hookContext.PeerName,
+		failoverIDField:  hookContext.FailoverID,
+	}
+	summary := fmt.Sprintf("solana-validator-ha: %s is becoming active - no active peer seen in %d samples", m.cfg.Validator.Name, m.gossipState.LeaderlessSamplesCount)
+	if hookContext.PeerName != "" {
+		summary += fmt.Sprintf(", %s was last active", hookContext.PeerName)
+	}
+
+	m.pagerDuty.Trigger(m.cfg.Notifications.PagerDuty.DedupKey, summary, details)
+	m.failoverPaged = true
+}
+
+// resolveFailoverPage resolves the incident pageFailover triggered once gossip sees an active peer again
+func (m *Manager) resolveFailoverPage() {
+	if !m.failoverPaged || !m.gossipState.HasActivePeer() {
+		return
+	}
+
+	m.pagerDuty.Resolve(m.cfg.Notifications.PagerDuty.DedupKey)
+	m.failoverPaged = false
+}
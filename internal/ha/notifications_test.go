@@ -0,0 +1,144 @@
+package ha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/notify"
+)
+
+// pagerDutyHarness is a manager paging a fake PagerDuty, with the active identity in gossip at peer3 once refreshed
+type pagerDutyHarness struct {
+	manager *Manager
+	mu      sync.Mutex
+	events  []map[string]any
+}
+
+func newPagerDutyHarness(t *testing.T, dryRun bool) *pagerDutyHarness {
+	t.Helper()
+
+	h := &pagerDutyHarness{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		h.mu.Lock()
+		h.events = append(h.events, event)
+		h.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := createTestConfig()
+	cfg.Failover.DryRun = dryRun
+	cfg.Cluster.RPCURLs = []string{clusterServer(t, cfg.Validator.Identities.ActiveKeyPair.PublicKey()).URL}
+	cfg.Failover.Peers["peer3"] = config.Peer{Name: "peer3", IP: "127.0.0.1"}
+	cfg.Notifications.PagerDuty = &config.PagerDuty{
+		RoutingKey: "routing-key",
+		Severity:   "critical",
+		DedupKey:   "svha-failover",
+		URL:        server.URL,
+		Timeout:    time.Second,
+		RetryDelay: 10 * time.Millisecond,
+	}
+
+	h.manager = NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc})
+	require.NoError(t, h.manager.initialize())
+	require.NotNil(t, h.manager.pagerDuty)
+
+	return h
+}
+
+// sent waits for queued events then returns every event PagerDuty received
+func (h *pagerDutyHarness) sent() []map[string]any {
+	h.manager.pagerDuty.Wait()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]map[string]any{}, h.events...)
+}
+
+// notifications returns the notifications_total value for action and result
+func notifications(t *testing.T, manager *Manager, action string, result string) float64 {
+	t.Helper()
+
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "solana_validator_ha_notifications_total" {
+			continue
+		}
+		for _, metric := range metricFamily.Metric {
+			labels := map[string]string{}
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["notifier"] == notifierPagerDuty && labels["action"] == action && labels["result"] == result {
+				return metric.Counter.GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestManager_PageFailover_TriggersAndResolves(t *testing.T) {
+	h := newPagerDutyHarness(t, false)
+
+	// manual promotions are not failovers
+	h.manager.pageFailover(manualHookReason)
+	assert.Empty(t, h.sent())
+	assert.False(t, h.manager.failoverPaged)
+
+	h.manager.pageFailover(DecisionReasonNoActivePeer)
+	events := h.sent()
+	require.Len(t, events, 1)
+	assert.Equal(t, notify.ActionTrigger, events[0]["event_action"])
+	assert.Equal(t, "svha-failover", events[0]["dedup_key"])
+	payload := events[0]["payload"].(map[string]any)
+	assert.Equal(t, "test-validator", payload["source"])
+	assert.Contains(t, payload["summary"], "test-validator is becoming active")
+	assert.Equal(t, DecisionReasonNoActivePeer, payload["custom_details"].(map[string]any)["reason"])
+	assert.True(t, h.manager.failoverPaged)
+
+	// nothing is resolved while there is still no active peer
+	h.manager.resolveFailoverPage()
+	assert.Len(t, h.sent(), 1)
+
+	// the cluster has an active peer again
+	h.manager.gossipState.Refresh()
+	h.manager.resolveFailoverPage()
+	events = h.sent()
+	require.Len(t, events, 2)
+	assert.Equal(t, notify.ActionResolve, events[1]["event_action"])
+	assert.Equal(t, "svha-failover", events[1]["dedup_key"])
+	assert.False(t, h.manager.failoverPaged)
+
+	// resolved once only
+	h.manager.resolveFailoverPage()
+	assert.Len(t, h.sent(), 2)
+
+	assert.Equal(t, float64(1), notifications(t, h.manager, notify.ActionTrigger, notifyResultSuccess))
+	assert.Equal(t, float64(1), notifications(t, h.manager, notify.ActionResolve, notifyResultSuccess))
+}
+
+func TestManager_PageFailover_DryRun(t *testing.T) {
+	h := newPagerDutyHarness(t, true)
+
+	h.manager.pageFailover(DecisionReasonNoActivePeer)
+	assert.Empty(t, h.sent())
+	assert.Equal(t, float64(0), notifications(t, h.manager, notify.ActionTrigger, notifyResultSuccess))
+}
+
+func TestManager_PageFailover_NotConfigured(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+	assert.Nil(t, manager.pagerDuty)
+
+	manager.pageFailover(DecisionReasonNoActivePeer)
+	assert.False(t, manager.failoverPaged)
+}
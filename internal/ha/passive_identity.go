@@ -0,0 +1,96 @@
+package ha
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+)
+
+// passiveIdentityCheck remembers the passive identities already found unstaked, so loading the config again only
+// queries the cluster for the ones added since
+type passiveIdentityCheck struct {
+	mu       sync.Mutex
+	unstaked map[solana.PublicKey]bool
+}
+
+// isUnstaked returns true if pubkey was already found unstaked
+func (c *passiveIdentityCheck) isUnstaked(pubkey solana.PublicKey) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.unstaked[pubkey]
+}
+
+// markUnstaked remembers pubkey was found unstaked
+func (c *passiveIdentityCheck) markUnstaked(pubkey solana.PublicKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.unstaked == nil {
+		c.unstaked = map[solana.PublicKey]bool{}
+	}
+	c.unstaked[pubkey] = true
+}
+
+// checkPassiveIdentities returns an error if a passive identity is the node identity of a vote account or has more
+// than validator.identities.passive_max_stake delegated to it - demoting to a staked identity leaves it voting, so a
+// failover could vote twice. A check the cluster rpc can't answer is logged and tried again the next time.
+func (m *Manager) checkPassiveIdentities() error {
+	identities := m.cfg.Validator.Identities
+	if identities.SkipPassiveStakeCheck {
+		m.logger.Warn("--skip-passive-stake-check is set - not checking the passive identities are unstaked")
+		return nil
+	}
+
+	unchecked := []solana.PublicKey{}
+	for _, passivePubkey := range identities.PassivePubkeys() {
+		if pubkey := solana.MustPublicKeyFromBase58(passivePubkey); !m.passiveIdentities.isUnstaked(pubkey) {
+			unchecked = append(unchecked, pubkey)
+		}
+	}
+	if len(unchecked) == 0 {
+		return nil
+	}
+
+	voteAccounts, err := m.clusterRPC.GetVoteAccounts(m.ctx)
+	if err != nil {
+		m.logger.Warn("unable to get vote accounts - not checking the passive identities are unstaked", "error", err)
+		return nil
+	}
+
+	maxStake := uint64(identities.PassiveMaxStake * float64(solana.LAMPORTS_PER_SOL))
+	for _, pubkey := range unchecked {
+		if err := passiveIdentityStaked(voteAccounts, pubkey, maxStake); err != nil {
+			return fmt.Errorf("validator.identities.passive %s must be an unstaked identity: %w - use run --skip-passive-stake-check to start anyway", pubkey, err)
+		}
+		m.passiveIdentities.markUnstaked(pubkey)
+	}
+	m.logger.Debug("passive identities are unstaked", "passive_pubkeys", identities.PassivePubkeys())
+	return nil
+}
+
+// passiveIdentityStaked returns an error if pubkey is the node identity of any current or delinquent vote account,
+// or the activated stake delegated to it is more than maxStake lamports
+func passiveIdentityStaked(voteAccounts *solanagorpc.GetVoteAccountsResult, pubkey solana.PublicKey, maxStake uint64) error {
+	var delegated uint64
+	for _, voteAccount := range append(voteAccounts.Current, voteAccounts.Delinquent...) {
+		if voteAccount.NodePubkey.Equals(pubkey) {
+			return fmt.Errorf("it is the node identity of vote account %s with %s SOL activated stake", voteAccount.VotePubkey, formatSOL(voteAccount.ActivatedStake))
+		}
+		if voteAccount.VotePubkey.Equals(pubkey) {
+			delegated += voteAccount.ActivatedStake
+		}
+	}
+	if delegated > maxStake {
+		return fmt.Errorf("it has %s SOL activated stake delegated to it, more than validator.identities.passive_max_stake %s SOL", formatSOL(delegated), formatSOL(maxStake))
+	}
+	return nil
+}
+
+// formatSOL returns lamports in SOL
+func formatSOL(lamports uint64) string {
+	return strconv.FormatFloat(float64(lamports)/float64(solana.LAMPORTS_PER_SOL), 'f', -1, 64)
+}
@@ -0,0 +1,69 @@
+package ha
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_CheckPassiveIdentities(t *testing.T) {
+	cfg := createTestConfig()
+	manager, clusterRPC, _ := newFakeRPCManager(t, cfg)
+	passivePubkey := cfg.Validator.Identities.PassiveKeyPair.PublicKey()
+	clusterRPC.SetVoteAccounts([]solanagorpc.VoteAccountsResult{
+		{VotePubkey: solana.NewWallet().PublicKey(), NodePubkey: cfg.Validator.Identities.ActiveKeyPair.PublicKey(), ActivatedStake: 1000 * solana.LAMPORTS_PER_SOL},
+	}, nil)
+
+	require.NoError(t, manager.checkPassiveIdentities())
+	assert.Equal(t, 1, clusterRPC.Calls("getVoteAccounts"))
+
+	// an identity found unstaked is not checked again
+	require.NoError(t, manager.checkPassiveIdentities())
+	assert.Equal(t, 1, clusterRPC.Calls("getVoteAccounts"))
+	assert.True(t, manager.passiveIdentities.isUnstaked(passivePubkey))
+}
+
+func TestManager_CheckPassiveIdentities_NodeIdentity(t *testing.T) {
+	cfg := createTestConfig()
+	manager, clusterRPC, _ := newFakeRPCManager(t, cfg)
+	votePubkey := solana.NewWallet().PublicKey()
+	// a delinquent vote account with the passive identity is refused too
+	clusterRPC.SetVoteAccounts(nil, []solanagorpc.VoteAccountsResult{
+		{VotePubkey: votePubkey, NodePubkey: cfg.Validator.Identities.PassiveKeyPair.PublicKey(), ActivatedStake: 1500 * solana.LAMPORTS_PER_SOL},
+	})
+
+	err := manager.checkPassiveIdentities()
+	assert.EqualError(t, err, "validator.identities.passive "+cfg.Validator.Identities.PassiveKeyPair.PublicKey().String()+
+		" must be an unstaked identity: it is the node identity of vote account "+votePubkey.String()+
+		" with 1500 SOL activated stake - use run --skip-passive-stake-check to start anyway")
+
+	cfg.Validator.Identities.SkipPassiveStakeCheck = true
+	assert.NoError(t, manager.checkPassiveIdentities())
+}
+
+func TestManager_CheckPassiveIdentities_DelegatedStake(t *testing.T) {
+	cfg := createTestConfig()
+	manager, clusterRPC, _ := newFakeRPCManager(t, cfg)
+	clusterRPC.SetVoteAccounts([]solanagorpc.VoteAccountsResult{
+		{VotePubkey: cfg.Validator.Identities.PassiveKeyPair.PublicKey(), NodePubkey: solana.NewWallet().PublicKey(), ActivatedStake: solana.LAMPORTS_PER_SOL / 2},
+	}, nil)
+
+	err := manager.checkPassiveIdentities()
+	assert.ErrorContains(t, err, "it has 0.5 SOL activated stake delegated to it, more than validator.identities.passive_max_stake 0 SOL")
+
+	cfg.Validator.Identities.PassiveMaxStake = 1
+	assert.NoError(t, manager.checkPassiveIdentities())
+}
+
+func TestManager_CheckPassiveIdentities_Inconclusive(t *testing.T) {
+	cfg := createTestConfig()
+	manager, clusterRPC, _ := newFakeRPCManager(t, cfg)
+	clusterRPC.SetError("getVoteAccounts", assert.AnError)
+
+	// an unreachable cluster rpc doesn't stop startup, the identity is checked again next time
+	require.NoError(t, manager.checkPassiveIdentities())
+	assert.False(t, manager.passiveIdentities.isUnstaked(cfg.Validator.Identities.PassiveKeyPair.PublicKey()))
+}
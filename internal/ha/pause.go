@@ -0,0 +1,173 @@
+package ha
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+)
+
+// maxDurationParam is the /admin/pause query parameter a pause's duration is read from
+const maxDurationParam = "max_duration"
+
+// PauseStatus is whether failover is paused for maintenance, served by /admin/pause and /admin/resume
+type PauseStatus struct {
+	Paused bool `json:"paused"`
+	// ResumesAt is omitted when not paused
+	ResumesAt *time.Time `json:"resumes_at,omitempty"`
+}
+
+// maintenancePause holds back role transitions while an operator works on the validator - gossip, metrics and
+// decisions carry on. It resumes on its own once its duration has elapsed so a forgotten pause can't leave the
+// cluster unprotected.
+type maintenancePause struct {
+	mu sync.Mutex
+	// resumesAt is when the pause ends, zero while not paused
+	resumesAt clock.Instant
+}
+
+// pause pauses from now for duration, replacing any running pause
+func (p *maintenancePause) pause(now clock.Instant, duration time.Duration) clock.Instant {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.resumesAt = now.Add(duration)
+	return p.resumesAt
+}
+
+// resume ends the pause, returning false if there was none
+func (p *maintenancePause) resume() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	wasPaused := !p.resumesAt.IsZero()
+	p.resumesAt = clock.Instant{}
+	return wasPaused
+}
+
+// expire ends the pause once its duration has elapsed at now, returning true if it did
+func (p *maintenancePause) expire(now clock.Instant) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.resumesAt.IsZero() || p.resumesAt.After(now) {
+		return false
+	}
+	p.resumesAt = clock.Instant{}
+	return true
+}
+
+// expiresAt returns when the pause ends, zero while not paused
+func (p *maintenancePause) expiresAt() clock.Instant {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.resumesAt
+}
+
+// Pause pauses failover for duration, failover.max_pause_duration if zero and never longer - role transitions are
+// skipped and logged with what would have happened until Resume or the duration has elapsed
+func (m *Manager) Pause(duration time.Duration) (PauseStatus, error) {
+	maxDuration := m.cfg.Failover.MaxPauseDuration
+	if duration == 0 {
+		duration = maxDuration
+	}
+	if duration < 0 || duration > maxDuration {
+		return PauseStatus{}, fmt.Errorf("pause duration must be positive and at most failover.max_pause_duration %s, got %s", maxDuration, duration)
+	}
+
+	resumesAt := m.pause.pause(m.clock.Now(), duration).Time()
+	m.logger.Warn("failover paused for maintenance - role transitions are skipped until resumed", "duration", duration, "resumes_at", resumesAt.Format(time.RFC3339))
+	m.recordEvent(events.TypePaused, "failover paused for maintenance", "duration", duration.String(), "resumes_at", resumesAt.Format(time.RFC3339))
+	m.refreshPauseState()
+	return m.pauseStatus(), nil
+}
+
+// Resume ends a maintenance pause, doing nothing if failover isn't paused
+func (m *Manager) Resume() PauseStatus {
+	if m.pause.resume() {
+		m.logger.Info("failover resumed")
+		m.recordEvent(events.TypeResumed, "failover resumed", "expired", "false")
+		m.refreshPauseState()
+	}
+	return m.pauseStatus()
+}
+
+// expirePause resumes failover once a maintenance pause's duration has elapsed
+func (m *Manager) expirePause() {
+	if !m.pause.expire(m.clock.Now()) {
+		return
+	}
+	m.logger.Warn("maintenance pause reached its duration - failover resumed")
+	m.recordEvent(events.TypeResumed, "maintenance pause reached its duration - failover resumed", "expired", "true")
+}
+
+// skipWhilePaused logs the transition to role for reason that would have run and returns true while failover is
+// paused for maintenance
+func (m *Manager) skipWhilePaused(role constants.Role, reason string) bool {
+	resumesAt := m.pause.expiresAt()
+	if resumesAt.IsZero() {
+		return false
+	}
+
+	m.logger.Warn(fmt.Sprintf("failover paused - would have become %s", role),
+		"reason", reason,
+		"resumes_at", resumesAt.Time().Format(time.RFC3339),
+	)
+	return true
+}
+
+// pauseStatus returns whether failover is paused and until when
+func (m *Manager) pauseStatus() PauseStatus {
+	resumesAt := m.pause.expiresAt()
+	if resumesAt.IsZero() {
+		return PauseStatus{}
+	}
+	at := resumesAt.Time().UTC()
+	return PauseStatus{Paused: true, ResumesAt: &at}
+}
+
+// refreshPauseState updates the cached pause state and its metric between monitor cycles
+func (m *Manager) refreshPauseState() {
+	status := m.pauseStatus()
+	state := m.cache.GetState()
+	state.Paused = status.Paused
+	state.PausedUntil = time.Time{}
+	if status.ResumesAt != nil {
+		state.PausedUntil = *status.ResumesAt
+	}
+	m.cache.UpdateState(state)
+	m.metrics.RefreshMetrics()
+}
+
+// handlePause pauses failover for the max_duration query parameter, failover.max_pause_duration if not given
+func (m *Manager) handlePause(w http.ResponseWriter, r *http.Request) {
+	var duration time.Duration
+	if value := r.URL.Query().Get(maxDurationParam); value != "" {
+		var err error
+		if duration, err = time.ParseDuration(value); err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s: %s", maxDurationParam, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	status, err := m.Pause(duration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleResume ends a maintenance pause
+func (m *Manager) handleResume(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m.Resume())
+}
@@ -0,0 +1,125 @@
+package ha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPauseManager is a passive manager alone in gossip, so every cycle decides to take over over the admin rpc, on
+// a fake clock
+func newPauseManager(t *testing.T) (*Manager, *clock.Fake) {
+	t.Helper()
+
+	admin := testutil.NewFakeAdminRPC(t)
+	cfg := createLiveTestConfig()
+	cfg.Failover.MaxPauseDuration = time.Hour
+	// alone in failover.peers so there is no takeover delay
+	cfg.Failover.Peers = config.Peers{}
+	cfg.Failover.Active = config.Role{
+		Name:                "active",
+		Method:              config.RoleMethodAgaveAdminRPC,
+		AdminRPC:            config.AdminRPC{LedgerPath: admin.LedgerPath},
+		IdentityKeypairFile: "/keys/active.json",
+	}
+	fake := testutil.NewFakeRPC()
+	manager := newTakeoverManager(t, cfg, fake, fake)
+	admin.OnSetIdentity(func(string) {
+		manager.localRPC.(*testutil.FakeRPC).SetIdentity(cfg.Validator.Identities.ActiveKeyPair.PublicKey())
+	})
+	now := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager.clock = now
+	return manager, now
+}
+
+func TestManager_Pause_SkipsTransitions(t *testing.T) {
+	manager, _ := newPauseManager(t)
+
+	status, err := manager.Pause(30 * time.Minute)
+	require.NoError(t, err)
+	assert.True(t, status.Paused)
+	require.NotNil(t, status.ResumesAt)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 30, 0, 0, time.UTC), *status.ResumesAt)
+	assert.True(t, manager.cache.GetState().Paused)
+
+	// the decision is still made but the transition is skipped
+	manager.ensureHAState()
+	assert.Equal(t, DecisionActionBecomeActive, manager.decision.Action)
+	assert.Equal(t, []string{events.TypePaused}, recordedTypes(manager))
+	assert.True(t, manager.cache.GetState().Paused)
+	assert.True(t, manager.status().Paused)
+
+	// resuming lets the next cycle take over
+	assert.False(t, manager.Resume().Paused)
+	assert.False(t, manager.cache.GetState().Paused)
+	manager.ensureHAState()
+	assert.Equal(t, []string{events.TypePaused, events.TypeResumed, events.TypeBecomingActive, events.TypeActive}, recordedTypes(manager))
+}
+
+func TestManager_Pause_ResumesAfterDuration(t *testing.T) {
+	manager, now := newPauseManager(t)
+
+	// no duration pauses for failover.max_pause_duration
+	status, err := manager.Pause(0)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC), *status.ResumesAt)
+
+	now.Advance(59 * time.Minute)
+	manager.ensureHAState()
+	assert.Equal(t, []string{events.TypePaused}, recordedTypes(manager))
+
+	now.Advance(time.Minute)
+	manager.ensureHAState()
+	assert.Equal(t, []string{events.TypePaused, events.TypeResumed, events.TypeBecomingActive, events.TypeActive}, recordedTypes(manager))
+	assert.Equal(t, "true", manager.events.Events()[1].Fields["expired"])
+	assert.False(t, manager.cache.GetState().Paused)
+}
+
+func TestManager_Pause_RejectsDurationOverMax(t *testing.T) {
+	manager, _ := newPauseManager(t)
+
+	_, err := manager.Pause(2 * time.Hour)
+	assert.EqualError(t, err, "pause duration must be positive and at most failover.max_pause_duration 1h0m0s, got 2h0m0s")
+	assert.False(t, manager.pauseStatus().Paused)
+	assert.Empty(t, recordedTypes(manager))
+}
+
+func TestManager_HandlePause(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	handler := manager.healthCheckHandler()
+
+	// only posts pause
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/pause", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/pause?max_duration=soon", nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.False(t, manager.pauseStatus().Paused)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/pause?max_duration=10m", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var status PauseStatus
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&status))
+	assert.True(t, status.Paused)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 10, 0, 0, time.UTC), *status.ResumesAt)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/resume", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var resumed PauseStatus
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&resumed))
+	assert.False(t, resumed.Paused)
+	assert.Nil(t, resumed.ResumesAt)
+}
@@ -0,0 +1,191 @@
+package ha
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/cache"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+)
+
+// persistedState is the agent state written to state.file when it changes and loaded on startup, so a crash
+// looping agent keeps counting leaderless samples and the cooldown stays anchored to the last failover
+type persistedState struct {
+	// SavedAt is when the state was written, state older than state.max_age is not loaded
+	SavedAt time.Time      `json:"saved_at"`
+	Role    constants.Role `json:"role,omitempty"`
+	// LastFailoverAt is when the last transition was confirmed by local rpc, zero if none has been
+	LastFailoverAt    time.Time                `json:"last_failover_at"`
+	LeaderlessSamples int                      `json:"leaderless_samples"`
+	FailoverCounts    []persistedFailoverCount `json:"failover_counts"`
+}
+
+// persistedFailoverCount is the number of transitions completed to a role for a reason
+type persistedFailoverCount struct {
+	Direction constants.Role `json:"direction"`
+	Reason    string         `json:"reason"`
+	Count     uint64         `json:"count"`
+}
+
+// newPersistedState returns the persisted part of state, its failover counts ordered so states compare equal
+func newPersistedState(state cache.State) persistedState {
+	persisted := persistedState{
+		Role:              state.Role,
+		LastFailoverAt:    state.LastFailoverAt,
+		LeaderlessSamples: state.LeaderlessSamples,
+		FailoverCounts:    []persistedFailoverCount{},
+	}
+	for key, count := range state.FailoverCount {
+		persisted.FailoverCounts = append(persisted.FailoverCounts, persistedFailoverCount{Direction: key.Direction, Reason: key.Reason, Count: count})
+	}
+	sort.Slice(persisted.FailoverCounts, func(i, j int) bool {
+		a, b := persisted.FailoverCounts[i], persisted.FailoverCounts[j]
+		if a.Direction != b.Direction {
+			return a.Direction < b.Direction
+		}
+		return a.Reason < b.Reason
+	})
+	return persisted
+}
+
+// equal returns true if s and other hold the same state, whenever they were saved
+func (s persistedState) equal(other persistedState) bool {
+	s.SavedAt, other.SavedAt = time.Time{}, time.Time{}
+	return reflect.DeepEqual(s, other)
+}
+
+// loadState restores the state persisted to state.file by a previous run when state.enabled and it is no older
+// than state.max_age. A missing, unreadable or stale file is logged and the agent starts memoryless.
+func (m *Manager) loadState() {
+	if !m.cfg.State.Enabled {
+		return
+	}
+
+	file := m.cfg.State.File
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		m.logger.Debug("no persisted state to load", "file", file)
+		return
+	}
+	var persisted persistedState
+	if err == nil {
+		err = json.Unmarshal(data, &persisted)
+	}
+	if err != nil {
+		m.logger.Warn("failed to load persisted state - starting without it", "file", file, "error", err)
+		return
+	}
+
+	// the wall clock is all there is to compare with across restarts
+	now := m.clock.Now()
+	age := max(now.Time().Sub(persisted.SavedAt), 0)
+	if age > m.cfg.State.MaxAge {
+		m.logger.Info("ignoring persisted state older than state.max_age", "file", file, "saved_at", persisted.SavedAt.Format(time.RFC3339), "max_age", m.cfg.State.MaxAge)
+		return
+	}
+
+	state := m.cache.GetState()
+	if persisted.Role.Valid() {
+		state.Role = persisted.Role
+	}
+	state.LastFailoverAt = persisted.LastFailoverAt
+	state.LeaderlessSamples = persisted.LeaderlessSamples
+	state.FailoverCount = map[cache.FailoverKey]uint64{}
+	for _, count := range persisted.FailoverCounts {
+		state.FailoverCount[cache.FailoverKey{Direction: count.Direction, Reason: count.Reason}] = count.Count
+	}
+	m.cache.UpdateState(state)
+	m.gossipState.LeaderlessSamplesCount = persisted.LeaderlessSamples
+
+	// the cooldown runs from the last failover as if we had never restarted
+	if !persisted.LastFailoverAt.IsZero() {
+		m.cooldown.start(now.Add(-max(now.Time().Sub(persisted.LastFailoverAt), 0)))
+	}
+
+	m.savedState = persisted
+	m.savedStateAt = now
+	m.logger.Info("restored persisted state",
+		"file", file,
+		"saved_at", persisted.SavedAt.Format(time.RFC3339),
+		"role", persisted.Role,
+		"leaderless_samples", persisted.LeaderlessSamples,
+		"last_failover_at", formatOptionalTime(persisted.LastFailoverAt),
+	)
+}
+
+// saveState writes the state to state.file when state.enabled and it changed since last written, or its file is
+// half way to state.max_age so a running agent's state is never too stale to load
+func (m *Manager) saveState() {
+	if !m.cfg.State.Enabled {
+		return
+	}
+
+	now := m.clock.Now()
+	persisted := newPersistedState(m.cache.GetState())
+	if persisted.equal(m.savedState) && !m.savedStateAt.IsZero() && now.Sub(m.savedStateAt) < m.cfg.State.MaxAge/2 {
+		return
+	}
+
+	persisted.SavedAt = now.Time().UTC()
+	if err := writeFileAtomic(m.cfg.State.File, persisted); err != nil {
+		m.logger.Warn("failed to persist state", "file", m.cfg.State.File, "error", err)
+		return
+	}
+	m.savedState = persisted
+	m.savedStateAt = now
+}
+
+// writeFileAtomic writes value as JSON to a temporary file synced to disk and renamed over file, then syncs the
+// directory, so neither a crash nor a power loss ever leaves it half written
+func writeFileAtomic(file string, value any) error {
+	dir := filepath.Dir(file)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", filepath.Base(file), err)
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpFile := file + ".tmp"
+	f, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, file); err != nil {
+		return err
+	}
+
+	// the rename is only durable once the directory entry is
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// formatOptionalTime formats t in RFC3339, never if zero
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
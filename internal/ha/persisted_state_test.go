@@ -0,0 +1,140 @@
+package ha
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/cache"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStateManager is an initialized manager persisting its state to file on a fake clock at now
+func newStateManager(t *testing.T, file string, now *clock.Fake) *Manager {
+	t.Helper()
+
+	cfg := createLiveTestConfig()
+	cfg.State = config.State{Enabled: true, File: file, MaxAge: 10 * time.Minute}
+	cfg.Failover.Cooldown = time.Hour
+	fake := testutil.NewFakeRPC()
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: func() (string, error) { return "127.0.0.1", nil },
+		ClusterRPC:      fake,
+		LocalRPC:        fake,
+		Clock:           now,
+	})
+	require.NoError(t, manager.initialize())
+	return manager
+}
+
+// readPersistedState reads the state persisted to file
+func readPersistedState(t *testing.T, file string) persistedState {
+	t.Helper()
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	var persisted persistedState
+	require.NoError(t, json.Unmarshal(data, &persisted))
+	return persisted
+}
+
+func TestManager_SaveState(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state", config.StateFileName)
+	now := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager := newStateManager(t, file, now)
+
+	// written on the first cycle
+	state := manager.cache.GetState()
+	state.Role = constants.RolePassive
+	state.LeaderlessSamples = 2
+	manager.cache.UpdateState(state.WithFailover(constants.RolePassive, "unhealthy", now.Now().Time()))
+	manager.saveState()
+	persisted := readPersistedState(t, file)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), persisted.SavedAt)
+	assert.Equal(t, constants.RolePassive, persisted.Role)
+	assert.Equal(t, 2, persisted.LeaderlessSamples)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), persisted.LastFailoverAt)
+	assert.Equal(t, []persistedFailoverCount{{Direction: constants.RolePassive, Reason: "unhealthy", Count: 1}}, persisted.FailoverCounts)
+
+	// not rewritten while unchanged
+	now.Advance(time.Minute)
+	manager.saveState()
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), readPersistedState(t, file).SavedAt)
+
+	// rewritten once changed
+	state = manager.cache.GetState()
+	state.LeaderlessSamples = 3
+	manager.cache.UpdateState(state)
+	manager.saveState()
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 1, 0, 0, time.UTC), readPersistedState(t, file).SavedAt)
+
+	// and unchanged once half way to state.max_age so it never goes stale
+	now.Advance(5 * time.Minute)
+	manager.saveState()
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 6, 0, 0, time.UTC), readPersistedState(t, file).SavedAt)
+}
+
+func TestManager_LoadState(t *testing.T) {
+	file := filepath.Join(t.TempDir(), config.StateFileName)
+	lastFailoverAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, writeFileAtomic(file, persistedState{
+		SavedAt:           lastFailoverAt.Add(10 * time.Minute),
+		Role:              constants.RoleActive,
+		LastFailoverAt:    lastFailoverAt,
+		LeaderlessSamples: 2,
+		FailoverCounts:    []persistedFailoverCount{{Direction: constants.RoleActive, Reason: "leaderless", Count: 3}},
+	}))
+
+	// restarted 5 minutes after the state was saved, 15 minutes after the last failover
+	manager := newStateManager(t, file, clock.NewFake(lastFailoverAt.Add(15*time.Minute)))
+
+	state := manager.cache.GetState()
+	assert.Equal(t, constants.RoleActive, state.Role)
+	assert.Equal(t, lastFailoverAt, state.LastFailoverAt)
+	assert.Equal(t, map[cache.FailoverKey]uint64{{Direction: constants.RoleActive, Reason: "leaderless"}: 3}, state.FailoverCount)
+	assert.Equal(t, 2, manager.gossipState.LeaderlessSamplesCount)
+	assert.Equal(t, 45*time.Minute, manager.cooldown.remaining(manager.clock.Now()))
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "state", config.StateFileName)
+	require.NoError(t, writeFileAtomic(file, persistedState{LeaderlessSamples: 1}))
+	require.NoError(t, writeFileAtomic(file, persistedState{LeaderlessSamples: 2}))
+
+	// the second write replaced the first without leaving its temporary file behind
+	assert.Equal(t, 2, readPersistedState(t, file).LeaderlessSamples)
+	_, err := os.Stat(file + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestManager_LoadState_IgnoresStaleOrCorrupt(t *testing.T) {
+	file := filepath.Join(t.TempDir(), config.StateFileName)
+	savedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, writeFileAtomic(file, persistedState{SavedAt: savedAt, LeaderlessSamples: 2}))
+
+	// older than state.max_age
+	manager := newStateManager(t, file, clock.NewFake(savedAt.Add(11*time.Minute)))
+	assert.Zero(t, manager.gossipState.LeaderlessSamplesCount)
+	assert.Zero(t, manager.cooldown.remaining(manager.clock.Now()))
+
+	require.NoError(t, os.WriteFile(file, []byte("{not json"), 0o640))
+	manager = newStateManager(t, file, clock.NewFake(savedAt))
+	assert.Zero(t, manager.gossipState.LeaderlessSamplesCount)
+}
+
+func TestManager_SaveState_Disabled(t *testing.T) {
+	file := filepath.Join(t.TempDir(), config.StateFileName)
+	manager := newStateManager(t, file, clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	manager.cfg.State.Enabled = false
+
+	manager.saveState()
+	assert.NoFileExists(t, file)
+}
@@ -0,0 +1,96 @@
+package ha
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+)
+
+const (
+	// livezStallPollIntervals is how many poll intervals the monitor loop may go without progress before /livez fails
+	livezStallPollIntervals = 3
+
+	healthPath = "/health"
+	livezPath  = "/livez"
+	readyzPath = "/readyz"
+)
+
+// probeState is what the liveness and readiness probes report on, shared with the http handlers
+type probeState struct {
+	mu sync.Mutex
+	// ready is set once initialize completed and the first gossip refresh happened
+	ready bool
+	// lastProgressAt is when the monitor loop last completed a cycle, or started, zero before then
+	lastProgressAt clock.Instant
+}
+
+// markReady marks the agent ready to serve, once initialized with a first gossip refresh
+func (m *Manager) markReady() {
+	m.probeState.mu.Lock()
+	defer m.probeState.mu.Unlock()
+	m.probeState.ready = true
+}
+
+// markLoopProgress records that the monitor loop is making progress
+func (m *Manager) markLoopProgress() {
+	m.probeState.mu.Lock()
+	defer m.probeState.mu.Unlock()
+	m.probeState.lastProgressAt = m.clock.Now()
+}
+
+// handleLivez serves 200 while the monitor loop is making progress and 500 once it has stalled for
+// livezStallPollIntervals poll intervals - a role transition in progress counts as progress so a supervisor
+// never restarts the agent mid failover, and being unhealthy or out of gossip never fails it
+func (m *Manager) handleLivez(w http.ResponseWriter, r *http.Request) {
+	m.probeState.mu.Lock()
+	lastProgressAt := m.probeState.lastProgressAt
+	m.probeState.mu.Unlock()
+
+	// not monitoring yet - startup is bounded by run.startup_timeout instead
+	if lastProgressAt.IsZero() {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok: starting"))
+		return
+	}
+
+	state := m.cache.GetState()
+	if state.FailoverStatus == constants.FailoverStatusBecomingActive || state.FailoverStatus == constants.FailoverStatusBecomingPassive {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok: " + state.FailoverStatus.String()))
+		return
+	}
+
+	pollInterval := state.EffectivePollInterval
+	if pollInterval <= 0 {
+		pollInterval = m.cfg.Failover.PollIntervalDuration
+	}
+	limit := livezStallPollIntervals * pollInterval
+	if sinceProgress := m.clock.Now().Sub(lastProgressAt); sinceProgress > limit {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "stalled: no monitor loop progress in %s, limit %s", sinceProgress.Round(time.Second), limit)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// handleReadyz serves 200 once initialize completed and the first gossip refresh happened, 503 before then
+func (m *Manager) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	m.probeState.mu.Lock()
+	ready := m.probeState.ready
+	m.probeState.mu.Unlock()
+
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("not ready: starting up"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ready"))
+}
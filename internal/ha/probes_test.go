@@ -0,0 +1,116 @@
+package ha
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+	"github.com/stretchr/testify/assert"
+)
+
+// probe calls handler and returns the response status code and body
+func probe(handler http.HandlerFunc, path string) (int, string) {
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder.Code, recorder.Body.String()
+}
+
+func TestManager_HandleLivez(t *testing.T) {
+	manager, fake := newFakeClockManager(t)
+	manager.cfg.Failover.PollIntervalDuration = 5 * time.Second
+
+	// alive while starting up
+	code, body := probe(manager.handleLivez, livezPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok: starting", body)
+
+	// alive while the loop makes progress, even when unhealthy and out of gossip
+	manager.markLoopProgress()
+	fake.Advance(15 * time.Second)
+	code, body = probe(manager.handleLivez, livezPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body)
+
+	// stalled for more than 3 poll intervals
+	fake.Advance(time.Second)
+	code, body = probe(manager.handleLivez, livezPath)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, "stalled: no monitor loop progress in 16s, limit 15s", body)
+
+	// the limit follows the effective poll interval
+	state := manager.cache.GetState()
+	state.EffectivePollInterval = 10 * time.Second
+	manager.cache.UpdateState(state)
+	code, _ = probe(manager.handleLivez, livezPath)
+	assert.Equal(t, http.StatusOK, code)
+
+	// a role transition in progress is never stalled
+	fake.Advance(time.Minute)
+	state.FailoverStatus = constants.FailoverStatusBecomingActive
+	manager.cache.UpdateState(state)
+	code, body = probe(manager.handleLivez, livezPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok: becoming_active", body)
+
+	// progress again
+	state.FailoverStatus = constants.FailoverStatusIdle
+	manager.cache.UpdateState(state)
+	manager.markLoopProgress()
+	code, _ = probe(manager.handleLivez, livezPath)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestManager_HandleReadyz(t *testing.T) {
+	manager, _ := newManualManager(t, false)
+
+	code, body := probe(manager.handleReadyz, readyzPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not ready: starting up", body)
+
+	// ready once initialized with a first gossip refresh
+	manager.listen = func(string, string) (net.Listener, error) { return nil, errors.New("not listening in tests") }
+	assert.NoError(t, manager.runStartupSteps())
+	code, body = probe(manager.handleReadyz, readyzPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ready", body)
+}
+
+func TestManager_HealthCheckHandler_Auth(t *testing.T) {
+	manager, _ := newFakeClockManager(t)
+	manager.cfg.HealthCheck.Auth = &config.Auth{BearerToken: "s3cr3t"}
+	manager.markReady()
+	handler := manager.healthCheckHandler()
+
+	get := func(path string, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		httpauth.SetBearerToken(req, token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	// probes stay open so load balancers keep working
+	assert.Equal(t, http.StatusOK, get(healthPath, ""))
+	assert.Equal(t, http.StatusOK, get(livezPath, ""))
+	assert.Equal(t, http.StatusOK, get(readyzPath, ""))
+
+	for _, path := range []string{"/status", "/events", "/fitness"} {
+		assert.Equal(t, http.StatusUnauthorized, get(path, ""), path)
+		assert.Equal(t, http.StatusUnauthorized, get(path, "wrong"), path)
+	}
+	assert.Equal(t, http.StatusOK, get("/status", "s3cr3t"))
+	assert.Equal(t, http.StatusOK, get("/events", "s3cr3t"))
+	// authenticated, fitness is just not enabled
+	assert.Equal(t, http.StatusNotFound, get("/fitness", "s3cr3t"))
+
+	// no auth configured
+	manager.cfg.HealthCheck.Auth = nil
+	handler = manager.healthCheckHandler()
+	assert.Equal(t, http.StatusOK, get("/status", ""))
+}
@@ -0,0 +1,136 @@
+package ha
+
+import (
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
+)
+
+// publicIPRefresher resolves the public IP again in the background at most once per interval, keeping the
+// last successful result until the monitor loop takes it
+type publicIPRefresher struct {
+	resolve  func() (string, error)
+	interval time.Duration
+	logger   *log.Logger
+	clock    clock.Clock
+
+	mu        sync.Mutex
+	lastRunAt clock.Instant
+	running   bool
+	resolved  string
+	wg        sync.WaitGroup
+}
+
+// newPublicIPRefresher creates a refresher for a public IP resolved at startup, returning nil when
+// validator.public_ip is static or validator.public_ip_refresh_interval is not set
+func newPublicIPRefresher(validator config.Validator, resolve func() (string, error), logPrefix string, clk clock.Clock) *publicIPRefresher {
+	if validator.PublicIPSource() == config.PublicIPSourceConfig || validator.PublicIPRefreshInterval <= 0 {
+		return nil
+	}
+
+	return &publicIPRefresher{
+		resolve:   resolve,
+		interval:  validator.PublicIPRefreshInterval,
+		logger:    loglevel.WithPrefix(loglevel.PublicIP, "["+logPrefix+" public_ip]"),
+		clock:     clk,
+		lastRunAt: clk.Now(),
+	}
+}
+
+// observe resolves the public IP if the interval has elapsed since the last lookup and the previous lookup
+// has finished - it never blocks the HA monitor loop. A failed lookup is logged and leaves the IP as it was
+func (r *publicIPRefresher) observe() {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	now := r.clock.Now()
+	if r.running || now.Sub(r.lastRunAt) < r.interval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastRunAt = now
+	r.running = true
+	r.wg.Add(1)
+	r.mu.Unlock()
+
+	go func() {
+		defer r.wg.Done()
+
+		ip, err := r.resolve()
+		if err != nil {
+			r.logger.Warn("failed to resolve public ip - keeping the last known one", "error", err)
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.running = false
+		if err == nil {
+			r.resolved = ip
+		}
+	}()
+}
+
+// take returns the public IP the last lookup resolved, empty if none has since the last take
+func (r *publicIPRefresher) take() string {
+	if r == nil {
+		return ""
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ip := r.resolved
+	r.resolved = ""
+	return ip
+}
+
+// nextRunAt returns when the public IP is next resolved
+func (r *publicIPRefresher) nextRunAt() clock.Instant {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.lastRunAt.Add(r.interval)
+}
+
+// wait blocks until any in-flight lookup has finished
+func (r *publicIPRefresher) wait() {
+	if r == nil {
+		return
+	}
+	r.wg.Wait()
+}
+
+// refreshPublicIP applies the public IP the background lookup last resolved and starts the next lookup when due
+func (m *Manager) refreshPublicIP() {
+	if ip := m.publicIP.take(); ip != "" && ip != m.peerSelf.IP {
+		m.changePublicIP(ip)
+	}
+	m.publicIP.observe()
+}
+
+// changePublicIP moves ourselves to a new public IP - in the config peers we match gossip against, in the gossip
+// state and in the cache the metrics and status are labelled from
+func (m *Manager) changePublicIP(ip string) {
+	oldIP := m.peerSelf.IP
+	if name, ok := m.cfg.Failover.Peers.NameFromIP(ip); ok && name != m.peerSelf.Name {
+		m.logger.Error("resolved public ip belongs to a configured peer - keeping the current one", "public_ip", oldIP, "resolved_ip", ip, "peer_name", name)
+		return
+	}
+
+	m.peerSelf.IP = ip
+	m.cfg.Failover.Peers.Add(*m.peerSelf)
+	m.gossipState.SetSelfIP(ip)
+
+	state := m.cache.GetState()
+	state.PublicIP = ip
+	m.cache.UpdateState(state)
+
+	m.logger.Warn("public ip changed", "old_public_ip", oldIP, "public_ip", ip)
+	m.metrics.ObservePublicIPChange()
+}
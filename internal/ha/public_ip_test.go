@@ -0,0 +1,149 @@
+package ha
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+)
+
+// fakePublicIP is a public IP lookup answering with whatever it was last set to
+type fakePublicIP struct {
+	mu  sync.Mutex
+	ip  string
+	err error
+}
+
+func (f *fakePublicIP) set(ip string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ip, f.err = ip, err
+}
+
+func (f *fakePublicIP) resolve() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ip, f.err
+}
+
+// publicIPChanges returns the public_ip_changes_total value
+func publicIPChanges(t *testing.T, manager *Manager) float64 {
+	t.Helper()
+
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	total := 0.0
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() == "solana_validator_ha_public_ip_changes_total" {
+			for _, metric := range metricFamily.Metric {
+				total += metric.Counter.GetValue()
+			}
+		}
+	}
+	return total
+}
+
+func TestNewPublicIPRefresher(t *testing.T) {
+	// a static public ip is never resolved again
+	refresher := newPublicIPRefresher(config.Validator{PublicIP: "203.0.113.10", PublicIPRefreshInterval: time.Hour}, nil, "test", clock.System)
+	assert.Nil(t, refresher)
+
+	// a nil refresher is safe to use
+	refresher.observe()
+	assert.Empty(t, refresher.take())
+	refresher.wait()
+}
+
+func TestPublicIPRefresher_Interval(t *testing.T) {
+	now := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	lookup := &fakePublicIP{ip: "203.0.113.20"}
+	refresher := newPublicIPRefresher(config.Validator{PublicIPRefreshInterval: time.Hour}, lookup.resolve, "test", now)
+	require.NotNil(t, refresher)
+
+	// the startup lookup counts as the first, so nothing is resolved within the interval
+	now.Advance(59 * time.Minute)
+	refresher.observe()
+	refresher.wait()
+	assert.Empty(t, refresher.take())
+	assert.Equal(t, now.Now().Add(time.Minute), refresher.nextRunAt())
+
+	now.Advance(time.Minute)
+	refresher.observe()
+	refresher.wait()
+	assert.Equal(t, "203.0.113.20", refresher.take())
+	assert.Empty(t, refresher.take())
+
+	// a failed lookup resolves nothing
+	lookup.set("", assert.AnError)
+	now.Advance(time.Hour)
+	refresher.observe()
+	refresher.wait()
+	assert.Empty(t, refresher.take())
+}
+
+func TestManager_RefreshPublicIP(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Validator.PublicIPRefreshInterval = time.Hour
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
+
+	now := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	lookup := &fakePublicIP{ip: "127.0.0.1"}
+	clusterRPC := testutil.NewFakeRPC()
+	localRPC := testutil.NewFakeRPC()
+	localRPC.SetIdentity(cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: lookup.resolve,
+		ClusterRPC:      clusterRPC,
+		LocalRPC:        localRPC,
+		Clock:           now,
+	})
+	require.NoError(t, manager.initialize())
+
+	// our public ip has moved and gossip has followed
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey()
+	clusterRPC.SetClusterNodes(
+		testutil.GossipNode(t, "127.0.0.3", cfg.Validator.Identities.PassiveKeyPair.PublicKey()),
+		testutil.GossipNode(t, "127.0.0.2", activePubkey),
+	)
+	clusterRPC.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(activePubkey)}, nil)
+	lookup.set("127.0.0.3", nil)
+
+	// cycle runs the lookup once due and applies its result on the next cycle
+	cycle := func() {
+		now.Advance(time.Hour)
+		manager.ensureHAState()
+		manager.publicIP.wait()
+		manager.ensureHAState()
+	}
+
+	manager.ensureHAState()
+	assert.False(t, manager.isSelfInGossip())
+
+	cycle()
+	assert.Equal(t, "127.0.0.3", manager.peerSelf.IP)
+	assert.Equal(t, "127.0.0.3", manager.cfg.Failover.Peers[cfg.Validator.Name].IP)
+	assert.Equal(t, "127.0.0.3", manager.cache.GetState().PublicIP)
+	assert.True(t, manager.isSelfInGossip())
+	assert.Equal(t, 1.0, publicIPChanges(t, manager))
+
+	// a failed lookup keeps the last known good ip
+	lookup.set("", assert.AnError)
+	cycle()
+	assert.Equal(t, "127.0.0.3", manager.peerSelf.IP)
+	assert.True(t, manager.isSelfInGossip())
+
+	// an ip of another peer is never taken
+	lookup.set("127.0.0.2", nil)
+	cycle()
+	assert.Equal(t, "127.0.0.3", manager.peerSelf.IP)
+	assert.Equal(t, "127.0.0.2", manager.cfg.Failover.Peers["peer1"].IP)
+	assert.Equal(t, 1.0, publicIPChanges(t, manager))
+}
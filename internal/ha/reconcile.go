@@ -0,0 +1,81 @@
+package ha
+
+import (
+	"fmt"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+)
+
+// detectRole returns the role of the identity the local validator reports and that identity
+func (m *Manager) detectRole() (role constants.Role, pubkey string, err error) {
+	identity, err := m.localRPC.GetIdentity(m.ctx)
+	if err != nil {
+		return constants.RoleUnknown, "", err
+	}
+
+	pubkey = identity.Identity.String()
+	identities := m.cfg.Validator.Identities
+	switch {
+	case pubkey == identities.ActiveKeyPair.PublicKey().String():
+		return constants.RoleActive, pubkey, nil
+	case identities.IsPassivePubkey(pubkey):
+		return constants.RolePassive, pubkey, nil
+	default:
+		return constants.RoleUnknown, pubkey, nil
+	}
+}
+
+// reconcileRole sets the cached role from the identity the local validator is already running with, so the first
+// poll decides knowing it - an identity that is neither active nor passive holds back every failover decision
+// unless failover.allow_unknown_identity is set
+func (m *Manager) reconcileRole() {
+	role, pubkey, err := m.detectRole()
+	if err != nil {
+		m.logger.Warn("unable to detect existing role - local rpc getIdentity failed", "error", err)
+		return
+	}
+
+	state := m.cache.GetState()
+	state.Role = role
+	if role != constants.RoleUnknown {
+		m.cache.UpdateState(state)
+		m.logger.Info(fmt.Sprintf("detected existing role: %s", role), "pubkey", pubkey)
+		return
+	}
+
+	if m.cfg.Failover.AllowUnknownIdentity {
+		m.cache.UpdateState(state)
+		m.logger.Warn("detected existing role: unknown - failover.allow_unknown_identity is set, carrying on",
+			"pubkey", pubkey,
+			"passive_pubkeys", m.cfg.Validator.Identities.PassivePubkeys(),
+		)
+		return
+	}
+
+	m.unknownIdentity = true
+	state.Status = constants.StatusUnhealthy
+	m.cache.UpdateState(state)
+	m.logger.Error("detected existing role: unknown - local validator identity is neither validator.identities.active nor any of validator.identities.passive, making no failover decisions until it is",
+		"pubkey", pubkey,
+		"passive_pubkeys", m.cfg.Validator.Identities.PassivePubkeys(),
+	)
+}
+
+// holdForUnknownIdentity decides on no action and returns true while the local validator still reports the unknown
+// identity reconcileRole found, resuming decisions once an operator has set a known one
+func (m *Manager) holdForUnknownIdentity(decision *Decision) bool {
+	if !m.unknownIdentity {
+		return false
+	}
+
+	role, pubkey, err := m.detectRole()
+	if err == nil && role != constants.RoleUnknown {
+		m.logger.Info(fmt.Sprintf("detected existing role: %s - resuming failover decisions", role), "pubkey", pubkey)
+		m.unknownIdentity = false
+		return false
+	}
+
+	m.logger.Error("local validator identity is unknown - making no failover decisions", "pubkey", pubkey)
+	m.decide(decision, DecisionActionNone, DecisionReasonUnknownIdentity)
+	return true
+}
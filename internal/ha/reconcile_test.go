@@ -0,0 +1,66 @@
+package ha
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_ReconcileRole(t *testing.T) {
+	cfg := createTestConfig()
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
+
+	manager.reconcileRole()
+	assert.Equal(t, constants.RolePassive, manager.cache.GetState().Role)
+	assert.False(t, manager.unknownIdentity)
+
+	localRPC.SetIdentity(cfg.Validator.Identities.ActiveKeyPair.PublicKey())
+	manager.reconcileRole()
+	assert.Equal(t, constants.RoleActive, manager.cache.GetState().Role)
+	assert.False(t, manager.unknownIdentity)
+
+	// an rpc error leaves the role undetected without holding back decisions
+	localRPC.SetError("getIdentity", assert.AnError)
+	manager.reconcileRole()
+	assert.False(t, manager.unknownIdentity)
+}
+
+func TestManager_ReconcileRole_UnknownIdentity(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.LeaderlessSamplesThreshold = 1
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
+	localRPC.SetIdentity(solana.NewWallet().PublicKey())
+
+	manager.reconcileRole()
+	state := manager.cache.GetState()
+	assert.Equal(t, constants.RoleUnknown, state.Role)
+	assert.Equal(t, constants.StatusUnhealthy, state.Status)
+
+	// no decisions are made and we stay unhealthy
+	manager.ensureHAState()
+	assert.Equal(t, DecisionReasonUnknownIdentity, manager.decision.Reason)
+	assert.Equal(t, constants.StatusUnhealthy, manager.cache.GetState().Status)
+	assert.Empty(t, recordedTypes(manager))
+
+	// until an operator sets a known identity
+	localRPC.SetIdentity(cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	manager.ensureHAState()
+	assert.NotEqual(t, DecisionReasonUnknownIdentity, manager.decision.Reason)
+	assert.False(t, manager.unknownIdentity)
+}
+
+func TestManager_ReconcileRole_AllowUnknownIdentity(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.AllowUnknownIdentity = true
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
+	localRPC.SetIdentity(solana.NewWallet().PublicKey())
+
+	manager.reconcileRole()
+	assert.Equal(t, constants.RoleUnknown, manager.cache.GetState().Role)
+	assert.False(t, manager.unknownIdentity)
+
+	manager.ensureHAState()
+	assert.NotEqual(t, DecisionReasonUnknownIdentity, manager.decision.Reason)
+}
@@ -0,0 +1,133 @@
+package ha
+
+import (
+	"fmt"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+)
+
+const (
+	// rollbackTriggerEnvVar is set to rollbackTrigger in the env of commands and hooks run by a rollback
+	rollbackTriggerEnvVar = "SVHA_TRIGGER"
+	rollbackTrigger       = "rollback"
+	// rollbackFromRoleEnvVar is the role whose failed transition is being rolled back
+	rollbackFromRoleEnvVar = "SVHA_ROLLBACK_FROM_ROLE"
+
+	rollbackResultSuccess = "success"
+	rollbackResultFailure = "failure"
+)
+
+// rollbackTransition returns to the previous role after a transition to failedRole failed once its command
+// had run, if failover.<role>.rollback_on_failure is set. The opposite role's hooks and command are run
+// exactly once - a failed rollback is never retried or rolled back itself, it halts further transitions
+// until the agent is restarted as the identity the validator is running with can no longer be trusted.
+func (m *Manager) rollbackTransition(failedRole constants.Role) {
+	failed, previous, previousRole := &m.cfg.Failover.Active, &m.cfg.Failover.Passive, constants.RolePassive
+	if failedRole == constants.RolePassive {
+		failed, previous, previousRole = &m.cfg.Failover.Passive, &m.cfg.Failover.Active, constants.RoleActive
+	}
+
+	if !failed.RollbackOnFailure {
+		return
+	}
+
+	// in dry run the command never ran so there is nothing to roll back
+	if m.cfg.Failover.DryRun {
+		m.transitionLogger().Info("dry_run - would have rolled back failed transition", "from_role", failedRole, "to_role", previousRole)
+		return
+	}
+
+	m.transitionLogger().Warn("rolling back failed transition", "from_role", failedRole, "to_role", previousRole)
+	m.recordEvent(events.TypeRollingBack, "rolling back failed transition", "from_role", failedRole.String(), "to_role", previousRole.String())
+
+	err := m.runRollback(previous, previousRole, map[string]string{
+		rollbackTriggerEnvVar:  rollbackTrigger,
+		rollbackFromRoleEnvVar: failedRole.String(),
+	})
+	if err != nil {
+		m.rollbackFailed = true
+		state := m.cache.GetState()
+		state.FailoverStatus = constants.FailoverStatusRollbackFailed
+		m.cache.UpdateState(state)
+
+		m.transitionLogger().Error("‼️ rollback failed - manual intervention required, no further transitions will be attempted until restart",
+			"from_role", failedRole,
+			"to_role", previousRole,
+			"error", err,
+		)
+		m.recordEvent(events.TypeRollbackFailed, "rollback failed - manual intervention required", "from_role", failedRole.String(), "to_role", previousRole.String(), "error", err.Error())
+		m.metrics.ObserveRollback(failedRole.String(), rollbackResultFailure)
+		return
+	}
+
+	// the rolled back identity was confirmed by local rpc so it is no longer in doubt
+	m.identityUnverified = false
+	m.transitionLogger().Info("rolled back failed transition", "from_role", failedRole, "to_role", previousRole)
+	m.recordEvent(events.TypeRolledBack, "rolled back failed transition, confirmed by local rpc", "from_role", failedRole.String(), "to_role", previousRole.String())
+	m.metrics.ObserveRollback(failedRole.String(), rollbackResultSuccess)
+}
+
+// runRollback runs role's pre hooks, command and post hooks with env and confirms the role by local rpc
+func (m *Manager) runRollback(role *config.Role, roleName constants.Role, env map[string]string) error {
+	stage := fmt.Sprintf("rollback-%s", roleName)
+	hookContext := m.hookContext(roleName, rollbackTrigger)
+
+	if err := role.Hooks.RunPre(config.HooksRunOptions{
+		Env:          env,
+		Context:      hookContext,
+		LoggerPrefix: m.logPrefix,
+		OnResult:     m.observeHookResult(roleName),
+		LoggerArgs: []any{
+			"failover_stage", "pre-" + stage,
+		},
+		Audit: m.auditLog,
+	}); err != nil {
+		return fmt.Errorf("failed to run pre-%s hooks: %w", roleName, err)
+	}
+
+	result, err := role.RunCommand(config.RoleCommandRunOptions{
+		Env:          env,
+		LoggerPrefix: m.logPrefix,
+		FailoverID:   m.currentFailoverID(),
+		LoggerArgs: []any{
+			"failover_stage", stage,
+		},
+		Audit:              m.auditLog,
+		WriteKeypairFiles:  m.cfg.Validator.Identities.WriteVaultKeyPairFiles,
+		RemoveKeypairFiles: m.cfg.Validator.Identities.RemoveVaultKeyPairFiles,
+	})
+	m.observeRoleCommand(roleName, result, err)
+	if err != nil {
+		return fmt.Errorf("failed to run %s command: %w", roleName, err)
+	}
+
+	role.Hooks.RunPost(config.HooksRunOptions{
+		Env:          env,
+		Context:      hookContext,
+		LoggerPrefix: m.logPrefix,
+		OnResult:     m.observeHookResult(roleName),
+		LoggerArgs: []any{
+			"failover_stage", "post-" + stage,
+		},
+		Audit: m.auditLog,
+	})
+
+	if roleName == constants.RoleActive && !m.isSelfActive() {
+		return fmt.Errorf("not active as reported by local rpc")
+	}
+	if roleName == constants.RolePassive && m.isNotSelfPassive() {
+		return fmt.Errorf("not passive as reported by local rpc")
+	}
+
+	return nil
+}
+
+// checkTransitionsHalted returns an error if a failed rollback has halted transitions
+func (m *Manager) checkTransitionsHalted() error {
+	if m.rollbackFailed {
+		return fmt.Errorf("transitions halted after a failed rollback - manual intervention required, restart the agent once resolved")
+	}
+	return nil
+}
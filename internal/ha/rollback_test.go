@@ -0,0 +1,335 @@
+package ha
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+)
+
+// identityServer is a fake local validator rpc reporting whatever identity is stored
+func identityServer(t *testing.T, identity *atomic.Value) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     any    `json:"id"`
+			Method string `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		var result any = "ok"
+		if request.Method == "getIdentity" {
+			result = map[string]string{"identity": identity.Load().(string)}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": request.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// rollbackHarness is a live (not dry run) manager whose role commands and hooks append to a run log
+type rollbackHarness struct {
+	manager  *Manager
+	identity *atomic.Value
+	runLog   string
+}
+
+// newRollbackHarness returns a harness where promoting never takes - the local rpc keeps reporting the
+// passive identity - and the passive command runs passiveCommand
+func newRollbackHarness(t *testing.T, rollbackOnFailure bool, passiveCommand string) *rollbackHarness {
+	t.Helper()
+
+	h := &rollbackHarness{
+		identity: &atomic.Value{},
+		runLog:   filepath.Join(t.TempDir(), "run.log"),
+	}
+
+	cfg := createTestConfig()
+	cfg.Failover.DryRun = false
+	cfg.Validator.RPCURL = identityServer(t, h.identity).URL
+	h.identity.Store(cfg.Validator.Identities.PassiveKeyPair.PublicKey().String())
+
+	record := func(name string) []string {
+		return []string{"-c", `echo "` + name + ` trigger=$SVHA_TRIGGER from=$SVHA_ROLLBACK_FROM_ROLE" >> ` + h.runLog}
+	}
+	cfg.Failover.Active = config.Role{
+		Name:              "active",
+		Command:           "sh",
+		Args:              record("active-command"),
+		RollbackOnFailure: rollbackOnFailure,
+	}
+	cfg.Failover.Passive = config.Role{
+		Name:    "passive",
+		Command: "sh",
+		Args:    []string{"-c", passiveCommand + ` && echo "passive-command trigger=$SVHA_TRIGGER from=$SVHA_ROLLBACK_FROM_ROLE" >> ` + h.runLog},
+		Hooks: config.Hooks{
+			Pre:  []config.Hook{{Name: "pre-passive", Command: "sh", Args: record("pre-passive")}},
+			Post: []config.Hook{{Name: "post-passive", Command: "sh", Args: record("post-passive")}},
+		},
+	}
+
+	h.manager = NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc})
+	require.NoError(t, h.manager.initialize())
+
+	var err error
+	h.manager.events, err = events.New(events.Options{})
+	require.NoError(t, err)
+
+	return h
+}
+
+// runs returns the run log lines once the async post hooks still running are done
+func (h *rollbackHarness) runs(t *testing.T) []string {
+	t.Helper()
+
+	require.True(t, h.manager.hookPool.Drain(5*time.Second))
+	data, err := os.ReadFile(h.runLog)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// eventTypes returns the recorded event types in order
+func (h *rollbackHarness) eventTypes() []string {
+	types := []string{}
+	for _, event := range h.manager.events.Events() {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+// rollbacks returns the rollbacks_total value for the from_role and result labels
+func (h *rollbackHarness) rollbacks(t *testing.T, fromRole string, result string) float64 {
+	t.Helper()
+
+	metricFamilies, err := h.manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "solana_validator_ha_rollbacks_total" {
+			continue
+		}
+		for _, metric := range metricFamily.Metric {
+			labels := map[string]string{}
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["from_role"] == fromRole && labels["result"] == result {
+				return metric.Counter.GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestManager_EnsureActive_RollsBackWhenNotActiveAfterCommand(t *testing.T) {
+	h := newRollbackHarness(t, true, "true")
+
+	h.manager.ensureActive(DecisionReasonNoActivePeer)
+
+	// the passive hooks and command run once, flagged as a rollback
+	assert.Equal(t, []string{
+		"active-command trigger= from=",
+		"pre-passive trigger=rollback from=active",
+		"passive-command trigger=rollback from=active",
+		"post-passive trigger=rollback from=active",
+	}, h.runs(t))
+	assert.Equal(t, []string{
+		events.TypeBecomingActive,
+		events.TypeTransitionFailed,
+		events.TypeRollingBack,
+		events.TypeRolledBack,
+	}, h.eventTypes())
+	assert.Equal(t, float64(1), h.rollbacks(t, "active", "success"))
+	assert.Equal(t, float64(0), h.rollbacks(t, "active", "failure"))
+
+	// a successful rollback doesn't halt later transitions
+	assert.False(t, h.manager.rollbackFailed)
+	assert.NoError(t, h.manager.checkTransitionsHalted())
+}
+
+func TestManager_EnsureActive_RollbackFailureHaltsTransitions(t *testing.T) {
+	h := newRollbackHarness(t, true, "exit 1")
+
+	h.manager.ensureActive(DecisionReasonNoActivePeer)
+
+	// the rollback is attempted exactly once
+	assert.Equal(t, []string{
+		"active-command trigger= from=",
+		"pre-passive trigger=rollback from=active",
+	}, h.runs(t))
+	assert.Equal(t, []string{
+		events.TypeBecomingActive,
+		events.TypeTransitionFailed,
+		events.TypeRollingBack,
+		events.TypeRollbackFailed,
+	}, h.eventTypes())
+	assert.Equal(t, float64(0), h.rollbacks(t, "active", "success"))
+	assert.Equal(t, float64(1), h.rollbacks(t, "active", "failure"))
+	assert.Equal(t, constants.FailoverStatusRollbackFailed, h.manager.cache.GetState().FailoverStatus)
+
+	// the alarmed status survives the next refresh
+	h.manager.refreshMetrics()
+	assert.Equal(t, constants.FailoverStatusRollbackFailed, h.manager.cache.GetState().FailoverStatus)
+
+	// and no further transition is attempted in either direction
+	h.manager.ensureActive(DecisionReasonNoActivePeer)
+	h.manager.ensurePassive(DecisionReasonSelfNotInGossip)
+	assert.Len(t, h.runs(t), 2)
+	recorded := h.manager.events.Events()
+	require.Len(t, recorded, 8)
+	assert.Equal(t, "transitions halted", recorded[5].Message)
+	assert.Equal(t, "transitions halted", recorded[7].Message)
+}
+
+func TestManager_EnsureActive_NoRollbackUnlessConfigured(t *testing.T) {
+	h := newRollbackHarness(t, false, "true")
+
+	h.manager.ensureActive(DecisionReasonNoActivePeer)
+
+	assert.Equal(t, []string{"active-command trigger= from="}, h.runs(t))
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeTransitionFailed}, h.eventTypes())
+	assert.Equal(t, float64(0), h.rollbacks(t, "active", "success"))
+}
+
+func TestManager_EnsureActive_NoRollbackWhenDryRun(t *testing.T) {
+	h := newRollbackHarness(t, true, "true")
+	h.manager.cfg.Failover.DryRun = true
+
+	h.manager.ensureActive(DecisionReasonNoActivePeer)
+
+	assert.Empty(t, h.runs(t))
+	assert.Equal(t, []string{events.TypeBecomingActive}, h.eventTypes())
+}
+
+func TestManager_EnsurePassive_PassesHookContext(t *testing.T) {
+	h := &rollbackHarness{identity: &atomic.Value{}, runLog: filepath.Join(t.TempDir(), "run.log")}
+
+	// peer1 is active in gossip
+	cfg := createTestConfig()
+	cfg.Failover.DryRun = false
+	cfg.Cluster.RPCURLs = []string{clusterServer(t, cfg.Validator.Identities.ActiveKeyPair.PublicKey()).URL}
+	cfg.Failover.Peers["peer1"] = config.Peer{Name: "peer1", IP: "127.0.0.1"}
+	cfg.Validator.RPCURL = identityServer(t, h.identity).URL
+	h.identity.Store(cfg.Validator.Identities.PassiveKeyPair.PublicKey().String())
+
+	record := []string{"-c", `echo "$SVHA_ROLE $SVHA_PHASE $SVHA_REASON $SVHA_PEER_NAME $SVHA_VALIDATOR_NAME $SVHA_ACTIVE_PUBKEY $SVHA_PASSIVE_PUBKEY" >> ` + h.runLog}
+	cfg.Failover.Passive = config.Role{
+		Name:    "passive",
+		Command: "true",
+		Hooks: config.Hooks{
+			Pre:  []config.Hook{{Name: "pre-passive", Command: "sh", Args: record}},
+			Post: []config.Hook{{Name: "post-passive", Command: "sh", Args: record}},
+		},
+	}
+
+	h.manager = NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc})
+	require.NoError(t, h.manager.initialize())
+	h.manager.gossipState.Refresh()
+
+	h.manager.ensurePassive(manualHookReason)
+
+	suffix := " manual peer1 " + cfg.Validator.Name + " " + cfg.Validator.Identities.ActiveKeyPair.PublicKey().String() + " " +
+		cfg.Validator.Identities.PassiveKeyPair.PublicKey().String()
+	assert.Equal(t, []string{"passive pre" + suffix, "passive post" + suffix}, h.runs(t))
+}
+
+func TestManager_EnsureActive_AsyncPostHooks(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	runLog := filepath.Join(t.TempDir(), "run.log")
+	manager.cfg.Failover.PostHookDrainTimeout = 5 * time.Second
+	manager.cfg.Failover.Active.Hooks.Post = []config.Hook{
+		{Name: "slow", Command: "sh", Args: []string{"-c", "sleep 0.3 && echo slow >> " + runLog}},
+		{Name: "sync", Command: "sh", Args: []string{"-c", "echo sync >> " + runLog}, Async: new(bool)},
+	}
+
+	// the slow notification never holds up the transition
+	startedAt := time.Now()
+	manager.ensureActive(DecisionReasonNoActivePeer)
+	assert.Less(t, time.Since(startedAt), 300*time.Millisecond)
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeActive}, recordedTypes(manager))
+	assert.Equal(t, 1, manager.hookPool.InFlight())
+
+	// and is waited for on shutdown
+	manager.drainPostHooks()
+	data, err := os.ReadFile(runLog)
+	require.NoError(t, err)
+	assert.Equal(t, "sync\nslow\n", string(data))
+}
+
+func TestManager_EnsureRole_PostHooksRunAfterFailedCommand(t *testing.T) {
+	for _, role := range []constants.Role{constants.RoleActive, constants.RolePassive} {
+		for _, skipPostOnFailure := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/skip_post_on_failure=%t", role, skipPostOnFailure), func(t *testing.T) {
+				h := newRollbackHarness(t, true, "true")
+				record := []string{"-c", `echo "$SVHA_PHASE result=$SVHA_COMMAND_RESULT" >> ` + h.runLog}
+				failing := config.Role{
+					Name:              role.String(),
+					Command:           "false",
+					RollbackOnFailure: true,
+					SkipPostOnFailure: skipPostOnFailure,
+					Hooks: config.Hooks{
+						Pre:  []config.Hook{{Name: "pre", Command: "sh", Args: record}},
+						Post: []config.Hook{{Name: "post", Command: "sh", Args: record}},
+					},
+				}
+				ensure := h.manager.ensureActive
+				if role == constants.RoleActive {
+					h.manager.cfg.Failover.Active = failing
+				} else {
+					h.manager.cfg.Failover.Passive = failing
+					ensure = h.manager.ensurePassive
+				}
+
+				ensure(manualHookReason)
+
+				// the command never takes so nothing is rolled back
+				if skipPostOnFailure {
+					assert.Equal(t, []string{"pre result="}, h.runs(t))
+				} else {
+					assert.Equal(t, []string{"pre result=", "post result=failure"}, h.runs(t))
+				}
+				assert.Equal(t, []string{events.TypeTransitionFailed}, h.eventTypes()[1:])
+				assert.Equal(t, constants.FailoverStatusFailed, h.manager.cache.GetState().FailoverStatus)
+
+				// failed until a later transition is confirmed
+				h.manager.refreshMetrics()
+				assert.Equal(t, constants.FailoverStatusFailed, h.manager.cache.GetState().FailoverStatus)
+			})
+		}
+	}
+}
+
+func TestManager_EnsurePassive_PostHooksSeeCommandSuccess(t *testing.T) {
+	h := newRollbackHarness(t, false, "true")
+	h.manager.roleCommandFailed = true
+	h.manager.cfg.Failover.Passive.Hooks.Post = []config.Hook{{
+		Name:    "post-passive",
+		Command: "sh",
+		Args:    []string{"-c", `echo "post-passive result=$SVHA_COMMAND_RESULT" >> ` + h.runLog},
+	}}
+
+	h.manager.ensurePassive(manualHookReason)
+
+	assert.Equal(t, "post-passive result=success", h.runs(t)[len(h.runs(t))-1])
+	h.manager.refreshMetrics()
+	assert.Equal(t, constants.FailoverStatusIdle, h.manager.cache.GetState().FailoverStatus)
+}
@@ -0,0 +1,47 @@
+package ha
+
+import (
+	"strconv"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+)
+
+// holdForRPCOutage logs while gossip can't be refreshed from the cluster rpc, alarming once it has failed for
+// failover.leaderless_samples_threshold refreshes in a row. With failover.on_rpc_outage hold it decides on no action
+// and returns true - with proceed the failed refreshes were counted as leaderless samples and the cycle carries on.
+func (m *Manager) holdForRPCOutage(decision *Decision) bool {
+	rpcErrors := m.gossipState.ConsecutiveRPCErrors
+	if rpcErrors == 0 {
+		if m.rpcOutageAlarmed {
+			m.logger.Info("cluster rpc reachable again - gossip state is fresh")
+			m.rpcOutageAlarmed = false
+		}
+		return false
+	}
+
+	m.logger.Error("cluster rpc unreachable - gossip state is stale",
+		"consecutive_rpc_errors", rpcErrors,
+		"on_rpc_outage", m.cfg.Failover.OnRPCOutage,
+	)
+	if rpcErrors >= m.cfg.Failover.LeaderlessSamplesThreshold && !m.rpcOutageAlarmed {
+		m.rpcOutageAlarmed = true
+		message := "gossip could not be refreshed from the cluster rpc - cluster rpc outage"
+		m.recordEvent(events.TypeClusterRPCOutage, message,
+			"consecutive_rpc_errors", strconv.Itoa(rpcErrors),
+			"on_rpc_outage", m.cfg.Failover.OnRPCOutage,
+		)
+		m.runAlertHooks(events.TypeClusterRPCOutage, message, map[string]string{
+			"consecutive_rpc_errors": strconv.Itoa(rpcErrors),
+			"on_rpc_outage":          m.cfg.Failover.OnRPCOutage,
+			"public_ip":              m.peerSelf.IP,
+		})
+	}
+
+	if m.cfg.Failover.OnRPCOutage == config.RPCOutagePolicyProceed {
+		return false
+	}
+
+	m.decide(decision, DecisionActionNone, DecisionReasonClusterRPCOutage)
+	return true
+}
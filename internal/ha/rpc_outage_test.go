@@ -0,0 +1,131 @@
+package ha
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRPCOutageManager is a passive manager on 127.0.0.1 that has seen peer1 active on 127.0.0.2 before the cluster
+// rpc starts failing
+func newRPCOutageManager(t *testing.T, onRPCOutage string) (*Manager, *testutil.FakeRPC) {
+	t.Helper()
+
+	cfg := createLiveTestConfig()
+	cfg.Failover.OnRPCOutage = onRPCOutage
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey()
+	fake := testutil.NewFakeRPC()
+	fake.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(activePubkey)}, nil)
+	manager := newTakeoverManager(t, cfg, fake, fake)
+	fake.SetClusterNodes(
+		testutil.GossipNode(t, "127.0.0.1", cfg.Validator.Identities.PassiveKeyPair.PublicKey()),
+		testutil.GossipNode(t, "127.0.0.2", activePubkey),
+	)
+
+	manager.ensureHAState()
+	require.Equal(t, DecisionReasonActivePeerPresent, manager.decision.Reason)
+
+	fake.SetError("getClusterNodes", errors.New("connection refused"))
+	return manager, fake
+}
+
+func TestManager_RPCOutage_Hold(t *testing.T) {
+	manager, fake := newRPCOutageManager(t, config.RPCOutagePolicyHold)
+	manager.cfg.Failover.LeaderlessSamplesThreshold = 2
+
+	manager.ensureHAState()
+
+	// the peers are kept stale and no decision is made on them
+	assert.Equal(t, DecisionActionNone, manager.decision.Action)
+	assert.Equal(t, DecisionReasonClusterRPCOutage, manager.decision.Reason)
+	assert.Equal(t, 0, manager.gossipState.LeaderlessSamplesCount)
+	assert.True(t, manager.gossipState.GetPeerStates()["peer1"].Stale)
+	assert.Equal(t, 1, manager.cache.GetState().ClusterRPCErrors)
+	assert.Empty(t, recordedTypes(manager))
+
+	// alarmed once the outage lasts the leaderless samples threshold
+	manager.ensureHAState()
+	manager.ensureHAState()
+	assert.Equal(t, DecisionReasonClusterRPCOutage, manager.decision.Reason)
+	assert.Equal(t, []string{events.TypeClusterRPCOutage}, recordedTypes(manager))
+	assert.Equal(t, "2", manager.events.Events()[0].Fields["consecutive_rpc_errors"])
+	assert.Equal(t, 3, manager.cache.GetState().ClusterRPCErrors)
+
+	// the cluster rpc answering again resumes decisions
+	fake.SetError("getClusterNodes", nil)
+	manager.ensureHAState()
+	assert.Equal(t, DecisionReasonActivePeerPresent, manager.decision.Reason)
+	assert.False(t, manager.rpcOutageAlarmed)
+	assert.Equal(t, 0, manager.cache.GetState().ClusterRPCErrors)
+}
+
+// failingRefreshRPC is a FakeRPC whose nth getClusterNodes call fails
+type failingRefreshRPC struct {
+	*testutil.FakeRPC
+	nth int
+}
+
+func (r *failingRefreshRPC) GetClusterNodes(ctx context.Context) ([]*solanagorpc.GetClusterNodesResult, error) {
+	if r.Calls("getClusterNodes")+1 == r.nth {
+		r.SetError("getClusterNodes", errors.New("connection refused"))
+		defer r.SetError("getClusterNodes", nil)
+	}
+	return r.FakeRPC.GetClusterNodes(ctx)
+}
+
+func TestManager_RPCOutage_HoldAfterTakeoverDelay(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
+	fake := testutil.NewFakeRPC()
+	manager := newTakeoverManager(t, cfg, &failingRefreshRPC{FakeRPC: fake, nth: 2}, fake)
+
+	// the refresh after the takeover delay failing is no confirmation that no one took over
+	manager.ensureHAState()
+	assert.Equal(t, 2, fake.Calls("getClusterNodes"))
+	assert.Equal(t, DecisionActionNone, manager.decision.Action)
+	assert.Equal(t, DecisionReasonClusterRPCOutage, manager.decision.Reason)
+	assert.NotContains(t, recordedTypes(manager), events.TypeBecomingActive)
+}
+
+func TestManager_TakeoverDelayLongerThanPollInterval(t *testing.T) {
+	cfg := createLiveTestConfig()
+	// ranked second behind a peer that is gone from gossip, we wait 2s before taking over
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "10.0.0.2"}}
+	cfg.Failover.TakeoverJitterDuration = 0
+	fake := testutil.NewFakeRPC()
+	manager := newTakeoverManager(t, cfg, fake, fake)
+	manager.peerCount = 2
+	require.Equal(t, 2, manager.staticTakeoverRank(manager.peerSelf.IP))
+
+	// the refresh after a delay outlasting the cycle's deadline gets one of its own rather than failing on it
+	manager.runPollIteration(200 * time.Millisecond)
+	assert.Equal(t, 2, fake.Calls("getClusterNodes"))
+	assert.Zero(t, manager.gossipState.ConsecutiveRPCErrors)
+	assert.Equal(t, DecisionActionBecomeActive, manager.decision.Action)
+	assert.Contains(t, recordedTypes(manager), events.TypeBecomingActive)
+}
+
+func TestManager_RPCOutage_Proceed(t *testing.T) {
+	manager, _ := newRPCOutageManager(t, config.RPCOutagePolicyProceed)
+
+	manager.ensureHAState()
+
+	// the failed refreshes, before and after the takeover delay, counted as leaderless samples so the outage drives
+	// a takeover
+	assert.Equal(t, 2, manager.gossipState.LeaderlessSamplesCount)
+	assert.Equal(t, DecisionActionBecomeActive, manager.decision.Action)
+	assert.Equal(t, DecisionReasonNoActivePeer, manager.decision.Reason)
+	types := recordedTypes(manager)
+	require.NotEmpty(t, types)
+	assert.Equal(t, events.TypeClusterRPCOutage, types[0])
+	assert.Contains(t, types, events.TypeBecomingActive)
+}
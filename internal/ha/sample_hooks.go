@@ -0,0 +1,127 @@
+package ha
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/audit"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
+)
+
+// sampleHookRunner runs failover.sample_hooks in the background at most once per interval
+type sampleHookRunner struct {
+	hooks     []config.Hook
+	interval  time.Duration
+	logPrefix string
+	logger    *log.Logger
+	clock     clock.Clock
+	audit     *audit.Log
+
+	mu        sync.Mutex
+	lastRunAt clock.Instant
+	running   bool
+	wg        sync.WaitGroup
+}
+
+// newSampleHookRunner creates a runner for the given hooks, returning nil when there are none
+func newSampleHookRunner(failover config.Failover, logPrefix string, clk clock.Clock, auditLog *audit.Log) *sampleHookRunner {
+	if len(failover.SampleHooks) == 0 {
+		return nil
+	}
+
+	return &sampleHookRunner{
+		hooks:     failover.SampleHooks,
+		interval:  failover.SampleHookInterval,
+		logPrefix: logPrefix,
+		logger:    loglevel.WithPrefix(loglevel.Hooks, "["+logPrefix+" sample_hooks]"),
+		clock:     clk,
+		audit:     auditLog,
+	}
+}
+
+// observe runs the sample hooks with the given decision if the interval has elapsed since the last run
+// and the previous run has finished - it never blocks the HA monitor loop
+func (r *sampleHookRunner) observe(decision *Decision) {
+	if r == nil || decision == nil {
+		return
+	}
+
+	r.mu.Lock()
+	now := r.clock.Now()
+	if r.running {
+		r.mu.Unlock()
+		r.logger.Debug("previous sample hook run still in progress - skipping")
+		return
+	}
+	if !r.lastRunAt.IsZero() && now.Sub(r.lastRunAt) < r.interval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastRunAt = now
+	r.running = true
+	r.wg.Add(1)
+	r.mu.Unlock()
+
+	decisionJSON, err := json.Marshal(decision)
+	if err != nil {
+		r.logger.Warn("failed to serialize decision", "error", err)
+	}
+	env := map[string]string{
+		"SVHA_DECISION":        string(decisionJSON),
+		"SVHA_DECISION_ACTION": decision.Action,
+		"SVHA_DECISION_REASON": decision.Reason,
+	}
+
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			r.running = false
+			r.mu.Unlock()
+			r.wg.Done()
+		}()
+
+		loggerArgs := []any{
+			"hook_type", constants.HookTypeSample,
+			"decision_action", decision.Action,
+			"decision_reason", decision.Reason,
+		}
+		for _, hook := range r.hooks {
+			// sample hooks are observational - they run regardless of failover.dry_run
+			_, err := hook.Run(config.HookRunOptions{
+				HookType:     constants.HookTypeSample,
+				Env:          env,
+				DryRun:       false,
+				LoggerPrefix: r.logPrefix,
+				LoggerArgs:   loggerArgs,
+				Audit:        r.audit,
+			})
+			if err != nil {
+				r.logger.Error("hook failed", append(loggerArgs, "hook_name", hook.Name, "error", err)...)
+			}
+		}
+	}()
+}
+
+// nextRunAt returns when the sample hooks can next run, zero if they have never run
+func (r *sampleHookRunner) nextRunAt() clock.Instant {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.lastRunAt.IsZero() {
+		return clock.Instant{}
+	}
+	return r.lastRunAt.Add(r.interval)
+}
+
+// wait blocks until any in-flight sample hook run has finished
+func (r *sampleHookRunner) wait() {
+	if r == nil {
+		return
+	}
+	r.wg.Wait()
+}
@@ -0,0 +1,125 @@
+package ha
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+)
+
+// newTestSampleHookRunner creates a runner whose hook appends the decision env vars to a file
+func newTestSampleHookRunner(t *testing.T, interval time.Duration) (*sampleHookRunner, string, *clock.Fake) {
+	t.Helper()
+
+	now := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	outFile := filepath.Join(t.TempDir(), "decisions")
+	runner := newSampleHookRunner(config.Failover{
+		SampleHooks: []config.Hook{{
+			Name:    "record-decision",
+			Command: "sh",
+			Args:    []string{"-c", `printf '%s|%s|%s\n' "$SVHA_DECISION_ACTION" "$SVHA_DECISION_REASON" "$SVHA_DECISION" >> ` + outFile},
+		}},
+		SampleHookInterval: interval,
+	}, "test", now, nil)
+	require.NotNil(t, runner)
+
+	return runner, outFile, now
+}
+
+func readSampleHookLines(t *testing.T, file string) []string {
+	t.Helper()
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
+
+func TestNewSampleHookRunner_NoHooks(t *testing.T) {
+	runner := newSampleHookRunner(config.Failover{}, "test", clock.System, nil)
+	assert.Nil(t, runner)
+
+	// a nil runner is safe to use
+	runner.observe(&Decision{})
+	runner.wait()
+}
+
+func TestSampleHookRunner_IntervalEnforcement(t *testing.T) {
+	runner, outFile, now := newTestSampleHookRunner(t, time.Minute)
+
+	// first observation always runs
+	runner.observe(&Decision{Action: DecisionActionNone, Reason: DecisionReasonActivePeerPresent})
+	runner.wait()
+	assert.Len(t, readSampleHookLines(t, outFile), 1)
+
+	// observations within the interval are skipped
+	now.Advance(5 * time.Second)
+	runner.observe(&Decision{Action: DecisionActionNone, Reason: DecisionReasonActivePeerPresent})
+	runner.wait()
+	now.Advance(54 * time.Second)
+	runner.observe(&Decision{Action: DecisionActionNone, Reason: DecisionReasonActivePeerPresent})
+	runner.wait()
+	assert.Len(t, readSampleHookLines(t, outFile), 1)
+
+	// once the interval has elapsed since the last run it runs again
+	now.Advance(time.Second)
+	runner.observe(&Decision{Action: DecisionActionNone, Reason: DecisionReasonActivePeerPresent})
+	runner.wait()
+	assert.Len(t, readSampleHookLines(t, outFile), 2)
+}
+
+func TestSampleHookRunner_SkipsWhileRunning(t *testing.T) {
+	runner, _, now := newTestSampleHookRunner(t, time.Minute)
+	runner.hooks[0].Args = []string{"-c", "sleep 0.2"}
+
+	runner.observe(&Decision{})
+	runner.mu.Lock()
+	lastRunAt := runner.lastRunAt
+	runner.mu.Unlock()
+
+	// interval elapsed but the previous run is still in flight
+	now.Advance(time.Hour)
+	runner.observe(&Decision{})
+	runner.mu.Lock()
+	assert.Equal(t, lastRunAt, runner.lastRunAt)
+	runner.mu.Unlock()
+
+	runner.wait()
+}
+
+func TestSampleHookRunner_DecisionPassedInEnv(t *testing.T) {
+	runner, outFile, _ := newTestSampleHookRunner(t, time.Minute)
+
+	decision := &Decision{
+		Time:                       time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Action:                     DecisionActionBecomeActive,
+		Reason:                     DecisionReasonNoActivePeer,
+		LeaderlessSamples:          4,
+		LeaderlessSamplesThreshold: 3,
+		SelfInGossip:               true,
+		PeersInGossip:              2,
+		DryRun:                     true,
+	}
+	runner.observe(decision)
+	runner.wait()
+
+	lines := readSampleHookLines(t, outFile)
+	require.Len(t, lines, 1)
+	parts := strings.SplitN(lines[0], "|", 3)
+	require.Len(t, parts, 3)
+	assert.Equal(t, DecisionActionBecomeActive, parts[0])
+	assert.Equal(t, DecisionReasonNoActivePeer, parts[1])
+
+	var got Decision
+	require.NoError(t, json.Unmarshal([]byte(parts[2]), &got))
+	assert.Equal(t, *decision, got)
+}
@@ -0,0 +1,177 @@
+package ha
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/sdnotify"
+)
+
+// serverShutdownTimeout bounds how long the servers get to finish the requests they are serving on shutdown
+const serverShutdownTimeout = 5 * time.Second
+
+// ExitCodeForcedShutdown is the exit code when a second SIGTERM or SIGINT cuts a graceful shutdown short
+const ExitCodeForcedShutdown = 1
+
+// handleShutdownSignals requests a graceful shutdown on the first SIGTERM or SIGINT and exits at once on the next,
+// returning the func that stops handling them
+func (m *Manager) handleShutdownSignals() (stop func()) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				if !m.shuttingDown() {
+					m.requestShutdown(sig.String())
+					continue
+				}
+				m.logger.Error("received a second shutdown signal - exiting immediately", "signal", sig.String())
+				m.exit(ExitCodeForcedShutdown)
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// requestShutdown stops new HA monitor cycles and gives an in-progress transition run.shutdown_timeout to finish
+// before cancelling the manager's context, which stops the steps of a transition waiting on it such as identity
+// verification
+func (m *Manager) requestShutdown(signal string) {
+	m.shutdownOnce.Do(func() {
+		m.logger.Info("shutdown requested - no new HA monitor cycles will run",
+			"signal", signal,
+			"transitioning", m.transitioning.Load() || m.adminTransitionRunning.Load(),
+			"timeout", m.cfg.Run.ShutdownTimeout,
+		)
+		close(m.shutdownRequested)
+		m.notifySystemd(sdnotify.Stopping)
+
+		time.AfterFunc(m.cfg.Run.ShutdownTimeout, func() {
+			if m.ctx.Err() != nil {
+				return
+			}
+			if m.transitioning.Load() {
+				m.logger.Warn("transition still running at run.shutdown_timeout - cancelling it", "timeout", m.cfg.Run.ShutdownTimeout)
+			}
+			m.cancel()
+		})
+	})
+}
+
+// shuttingDown returns true once a shutdown has been requested
+func (m *Manager) shuttingDown() bool {
+	select {
+	case <-m.shutdownRequested:
+		return true
+	default:
+		return false
+	}
+}
+
+// shutdown runs once the HA monitor loop has stopped, waiting in turn for an admin api transition still running,
+// the async post hooks and the requests the servers are serving before cancelling the manager's context. Each phase
+// logs when it begins and ends so the journal shows where the time went.
+func (m *Manager) shutdown() {
+	startedAt := m.clock.Now()
+
+	end := m.beginShutdownPhase("transition")
+	m.waitForTransition()
+	end()
+
+	end = m.beginShutdownPhase("post_hooks")
+	m.drainPostHooks()
+	end()
+
+	end = m.beginShutdownPhase("servers")
+	m.stopServers()
+	end()
+
+	// a role command cut short may have left identities read from vault on tmpfs
+	if err := m.cfg.Validator.Identities.RemoveVaultKeyPairFiles(); err != nil {
+		m.logger.Warn("failed to remove identity keypair files", "error", err)
+	}
+
+	m.cancel()
+	m.logger.Info("shutdown complete", "duration", m.clock.Now().Sub(startedAt))
+}
+
+// beginShutdownPhase logs the shutdown phase beginning, returning the func that logs it ending with its duration
+func (m *Manager) beginShutdownPhase(phase string) (end func()) {
+	startedAt := m.clock.Now()
+	m.logger.Info("shutdown phase begin", "phase", phase)
+	return func() {
+		m.logger.Info("shutdown phase end", "phase", phase, "duration", m.clock.Now().Sub(startedAt))
+	}
+}
+
+// waitForTransition waits for a transition requested over the admin api to finish, or the manager's context to be
+// cancelled at run.shutdown_timeout - one run by an HA monitor cycle has finished once the loop stops
+func (m *Manager) waitForTransition() {
+	idle := make(chan struct{})
+	go func() {
+		m.cycleMu.Lock()
+		defer m.cycleMu.Unlock()
+		close(idle)
+	}()
+
+	select {
+	case <-idle:
+	case <-m.ctx.Done():
+		if m.transitioning.Load() {
+			m.logger.Warn("abandoning the transition still running")
+		}
+	}
+}
+
+// drainPostHooks waits up to failover.post_hook_drain_timeout for async post hooks still running
+func (m *Manager) drainPostHooks() {
+	inFlight := m.hookPool.InFlight()
+	if inFlight == 0 {
+		return
+	}
+
+	m.logger.Info("waiting for post hooks to finish", "in_flight", inFlight, "timeout", m.cfg.Failover.PostHookDrainTimeout)
+	if !m.hookPool.Drain(m.cfg.Failover.PostHookDrainTimeout) {
+		m.logger.Warn("post hooks still running at shutdown - abandoning them", "in_flight", m.hookPool.InFlight())
+	}
+}
+
+// stopServers shuts the metrics, health check and admin api servers down, letting the requests they are serving
+// finish for up to serverShutdownTimeout
+func (m *Manager) stopServers() {
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+
+	if err := m.metrics.ShutdownServer(ctx); err != nil {
+		m.logger.Warn("failed to stop metrics server", "error", err)
+	}
+	for _, server := range []struct {
+		name   string
+		server *http.Server
+	}{
+		{name: "health check", server: m.healthServer},
+		{name: "admin api", server: m.adminServer},
+		{name: "debug", server: m.debugServer},
+	} {
+		if server.server == nil {
+			continue
+		}
+		if err := server.server.Shutdown(ctx); err != nil {
+			m.logger.Warn("failed to stop "+server.name+" server", "error", err)
+		}
+	}
+}
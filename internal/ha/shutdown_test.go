@@ -0,0 +1,118 @@
+package ha
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runShutdown runs shutdown in the background, returning a channel closed once it is done
+func runShutdown(manager *Manager) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		manager.shutdown()
+		close(done)
+	}()
+	return done
+}
+
+func TestManager_Shutdown_WaitsForInProgressTransition(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	manager.cfg.Run.ShutdownTimeout = 5 * time.Second
+
+	// an admin api transition is running
+	manager.cycleMu.Lock()
+	manager.transitioning.Store(true)
+	manager.requestShutdown("terminated")
+	assert.True(t, manager.shuttingDown())
+
+	done := runShutdown(manager)
+	select {
+	case <-done:
+		t.Fatal("shutdown did not wait for the transition")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.NoError(t, manager.ctx.Err(), "the transition is not cancelled before run.shutdown_timeout")
+
+	manager.transitioning.Store(false)
+	manager.cycleMu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not finish once the transition did")
+	}
+	assert.Error(t, manager.ctx.Err())
+}
+
+func TestManager_Shutdown_CancelsTransitionAtTimeout(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	manager.cfg.Run.ShutdownTimeout = 50 * time.Millisecond
+
+	manager.cycleMu.Lock()
+	t.Cleanup(manager.cycleMu.Unlock)
+	manager.transitioning.Store(true)
+	manager.requestShutdown("terminated")
+
+	select {
+	case <-runShutdown(manager):
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown was not bounded by run.shutdown_timeout")
+	}
+	assert.Error(t, manager.ctx.Err())
+}
+
+func TestManager_HAMonitorLoop_StopsOnShutdown(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	manager.cfg.Run.ShutdownTimeout = time.Minute
+
+	done := make(chan error, 1)
+	go func() { done <- manager.haMonitorLoop() }()
+	manager.requestShutdown("interrupt")
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("HA monitor loop did not stop")
+	}
+	// in-flight work such as identity verification is left to finish
+	assert.NoError(t, manager.ctx.Err())
+}
+
+func TestManager_AdminTransition_RefusedWhileShuttingDown(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	manager.cfg.Run.ShutdownTimeout = time.Minute
+	manager.requestShutdown("terminated")
+
+	_, err := manager.adminTransition(constants.RoleActive, ManualTransitionOptions{})
+	assert.EqualError(t, err, "the agent is shutting down")
+	assert.Empty(t, recordedTypes(manager))
+}
+
+func TestManager_HandleShutdownSignals(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	manager.cfg.Run.ShutdownTimeout = time.Minute
+	exitCodes := make(chan int, 1)
+	manager.exit = func(code int) { exitCodes <- code }
+	stop := manager.handleShutdownSignals()
+	defer stop()
+
+	// the first signal shuts down gracefully
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+	require.Eventually(t, manager.shuttingDown, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, exitCodes)
+
+	// the second exits at once
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGINT))
+	select {
+	case code := <-exitCodes:
+		assert.Equal(t, ExitCodeForcedShutdown, code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("second signal did not exit")
+	}
+}
@@ -0,0 +1,93 @@
+package ha
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+)
+
+// activeHolder is a node seen with the active identity - us or a peer
+type activeHolder struct {
+	name     string
+	priority int
+	self     bool
+}
+
+// activeHolders returns us when selfActive and every peer seen with the active identity in gossip or by its own rpc,
+// ordered like takeover claims - by failover.peers priority highest first, ties broken by name
+func (m *Manager) activeHolders(selfActive bool) []activeHolder {
+	holders := []activeHolder{}
+	if selfActive {
+		holders = append(holders, activeHolder{name: m.peerSelf.Name, priority: m.peerSelf.Priority, self: true})
+	}
+	for _, peerState := range m.gossipState.ActivePeers() {
+		// we may be in failover.peers when it is shared between nodes - our local rpc already said if we are active
+		if peerState.IP == m.peerSelf.IP || peerState.Name == m.peerSelf.Name {
+			continue
+		}
+		holders = append(holders, activeHolder{name: peerState.Name, priority: m.cfg.Failover.Peers[peerState.Name].Priority})
+	}
+
+	sort.Slice(holders, func(i, j int) bool {
+		if holders[i].priority != holders[j].priority {
+			return holders[i].priority > holders[j].priority
+		}
+		return holders[i].name < holders[j].name
+	})
+	return holders
+}
+
+// activeHolderNames returns the names of holders in order
+func activeHolderNames(holders []activeHolder) []string {
+	names := make([]string, 0, len(holders))
+	for _, holder := range holders {
+		names = append(names, holder.name)
+	}
+	return names
+}
+
+// handleSplitBrain alarms once when more than one node is seen with the active identity and, with
+// failover.split_brain_policy demote_self_if_lower_priority, demotes us when a peer also seen active outranks us.
+// Returns true when the cycle's decision was made here.
+func (m *Manager) handleSplitBrain(decision *Decision) bool {
+	holders := m.splitBrain
+	if len(holders) == 0 {
+		if m.splitBrainAlarmed {
+			m.logger.Info("split brain resolved - only one node seen with the active identity")
+			m.splitBrainAlarmed = false
+		}
+		return false
+	}
+
+	peers := strings.Join(activeHolderNames(holders), ",")
+	message := "more than one node seen with the active identity - split brain"
+	m.logger.Error("‼️ "+message, "peers", peers, "split_brain_policy", m.cfg.Failover.SplitBrainPolicy)
+	if !m.splitBrainAlarmed {
+		m.splitBrainAlarmed = true
+		m.recordEvent(events.TypeSplitBrain, message, "peers", peers)
+		m.runAlertHooks(events.TypeSplitBrain, message, map[string]string{
+			"peers":     peers,
+			"public_ip": m.peerSelf.IP,
+		})
+	}
+
+	// only the lower priority side steps down so a split brain never leaves no one active
+	selfRank := slices.IndexFunc(holders, func(holder activeHolder) bool { return holder.self })
+	if m.cfg.Failover.SplitBrainPolicy != config.SplitBrainPolicyDemoteSelfIfLowerPriority || selfRank <= 0 {
+		m.decide(decision, DecisionActionNone, DecisionReasonSplitBrain)
+		return true
+	}
+
+	// never held back by failover.cooldown - every cycle both stay active risks duplicate votes
+	m.logger.Error(fmt.Sprintf("peer %s outranks us with the active identity - ensuring we are passive", holders[0].name),
+		"priority", m.peerSelf.Priority,
+		"peer_priority", holders[0].priority,
+	)
+	m.decide(decision, DecisionActionBecomePassive, DecisionReasonSplitBrain)
+	m.ensurePassive(DecisionReasonSplitBrain)
+	return true
+}
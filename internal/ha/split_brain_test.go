@@ -0,0 +1,149 @@
+package ha
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// splitBrainHarness is an active manager of priority 5 on 127.0.0.1 that sees peer1 active in gossip on 127.0.0.2
+type splitBrainHarness struct {
+	manager *Manager
+	cluster *testutil.FakeRPC
+	admin   *testutil.FakeAdminRPC
+	hookLog string
+}
+
+func newSplitBrainHarness(t *testing.T, policy string, peerPriority int) *splitBrainHarness {
+	t.Helper()
+
+	h := &splitBrainHarness{
+		cluster: testutil.NewFakeRPC(),
+		admin:   testutil.NewFakeAdminRPC(t),
+		hookLog: filepath.Join(t.TempDir(), "hooks.log"),
+	}
+
+	cfg := createLiveTestConfig()
+	cfg.Validator.Priority = 5
+	cfg.Failover.SplitBrainPolicy = policy
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2", Priority: peerPriority}}
+	// demoting switches the identity over the admin rpc, running the passive hooks around it
+	cfg.Failover.Passive = config.Role{
+		Name:                "passive",
+		Method:              config.RoleMethodAgaveAdminRPC,
+		AdminRPC:            config.AdminRPC{LedgerPath: h.admin.LedgerPath},
+		IdentityKeypairFile: "/keys/passive.json",
+		Hooks: config.Hooks{
+			Pre: []config.Hook{{Name: "pre-passive", Command: "sh", Args: []string{"-c", `echo "pre-passive reason=$SVHA_REASON" >> ` + h.hookLog}}},
+		},
+	}
+
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey()
+	h.cluster.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(activePubkey)}, nil)
+	h.manager = newTakeoverManager(t, cfg, h.cluster, h.cluster)
+	h.cluster.SetClusterNodes(
+		testutil.GossipNode(t, "127.0.0.1", cfg.Validator.Identities.PassiveKeyPair.PublicKey()),
+		testutil.GossipNode(t, "127.0.0.2", activePubkey),
+	)
+
+	localRPC := h.manager.localRPC.(*testutil.FakeRPC)
+	localRPC.SetIdentity(activePubkey)
+	h.admin.OnSetIdentity(func(string) {
+		localRPC.SetIdentity(cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	})
+	return h
+}
+
+// hookRuns returns the hook log lines
+func (h *splitBrainHarness) hookRuns(t *testing.T) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(h.hookLog)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+// splitBrainGauge returns the solana_validator_ha_split_brain gauge value
+func (h *splitBrainHarness) splitBrainGauge(t *testing.T) float64 {
+	t.Helper()
+
+	metricFamilies, err := h.manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() == "solana_validator_ha_split_brain" {
+			return metricFamily.Metric[0].Gauge.GetValue()
+		}
+	}
+	return 0
+}
+
+func TestManager_SplitBrain_AlertOnly(t *testing.T) {
+	h := newSplitBrainHarness(t, config.SplitBrainPolicyAlertOnly, 10)
+
+	h.manager.ensureHAState()
+
+	// alarmed but never demoted, even though peer1 outranks us
+	assert.Equal(t, DecisionActionNone, h.manager.decision.Action)
+	assert.Equal(t, DecisionReasonSplitBrain, h.manager.decision.Reason)
+	assert.Equal(t, []string{events.TypeSplitBrain}, recordedTypes(h.manager))
+	assert.Equal(t, "peer1,test-validator", h.manager.events.Events()[0].Fields["peers"])
+	assert.Empty(t, h.admin.Calls())
+	assert.Equal(t, []string{"peer1", "test-validator"}, h.manager.cache.GetState().SplitBrainPeers)
+	assert.Equal(t, 1.0, h.splitBrainGauge(t))
+
+	// the alarm is raised once per split brain
+	h.manager.ensureHAState()
+	assert.Equal(t, []string{events.TypeSplitBrain}, recordedTypes(h.manager))
+
+	// peer1 goes passive and the split brain resolves
+	passivePubkey := h.manager.cfg.Validator.Identities.PassiveKeyPair.PublicKey()
+	h.cluster.SetClusterNodes(testutil.GossipNode(t, "127.0.0.1", passivePubkey), testutil.GossipNode(t, "127.0.0.2", passivePubkey))
+	h.manager.ensureHAState()
+	assert.Equal(t, DecisionReasonSelfAlreadyActive, h.manager.decision.Reason)
+	assert.False(t, h.manager.splitBrainAlarmed)
+	assert.Empty(t, h.manager.cache.GetState().SplitBrainPeers)
+	assert.Equal(t, 0.0, h.splitBrainGauge(t))
+}
+
+func TestManager_SplitBrain_DemoteSelfIfLowerPriority(t *testing.T) {
+	h := newSplitBrainHarness(t, config.SplitBrainPolicyDemoteSelfIfLowerPriority, 10)
+
+	h.manager.ensureHAState()
+
+	// peer1 outranks us so we demote through ensurePassive, hooks included
+	assert.Equal(t, DecisionActionBecomePassive, h.manager.decision.Action)
+	assert.Equal(t, DecisionReasonSplitBrain, h.manager.decision.Reason)
+	assert.Equal(t, []string{events.TypeSplitBrain, events.TypeBecomingPassive, events.TypePassive}, recordedTypes(h.manager))
+	assert.Equal(t, []testutil.AdminRPCCall{{Method: "setIdentity", Params: []any{"/keys/passive.json", false}}}, h.admin.Calls())
+	assert.Equal(t, []string{"pre-passive reason=split_brain"}, h.hookRuns(t))
+
+	h.manager.refreshMetrics()
+	assert.Equal(t, constants.RolePassive, h.manager.cache.GetState().Role)
+	assert.Equal(t, 0.0, h.splitBrainGauge(t))
+}
+
+func TestManager_SplitBrain_DemoteSelfIfLowerPriority_SelfOutranks(t *testing.T) {
+	h := newSplitBrainHarness(t, config.SplitBrainPolicyDemoteSelfIfLowerPriority, 1)
+
+	h.manager.ensureHAState()
+
+	// we outrank peer1 so it is the one to step down
+	assert.Equal(t, DecisionActionNone, h.manager.decision.Action)
+	assert.Equal(t, DecisionReasonSplitBrain, h.manager.decision.Reason)
+	assert.Equal(t, []string{events.TypeSplitBrain}, recordedTypes(h.manager))
+	assert.Equal(t, []string{"test-validator", "peer1"}, h.manager.cache.GetState().SplitBrainPeers)
+	assert.Empty(t, h.admin.Calls())
+	assert.Empty(t, h.hookRuns(t))
+}
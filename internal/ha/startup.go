@@ -0,0 +1,233 @@
+package ha
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+)
+
+// ExitCodeStartupTimeout is the exit code used when startup does not complete within run.startup_timeout
+// (EX_UNAVAILABLE from sysexits.h) so supervisors can tell a stuck start apart from other failures
+const ExitCodeStartupTimeout = 69
+
+const (
+	// StartupStepPublicIP - resolving our public IP from validator.public_ip or validator.public_ip_service_urls
+	StartupStepPublicIP = "resolve_public_ip"
+	// StartupStepPassiveIdentity - checking the passive identities are unstaked
+	StartupStepPassiveIdentity = "check_passive_identity"
+	// StartupStepEvents - loading persisted events from events.file
+	StartupStepEvents = "load_events"
+	// StartupStepReconcileRole - detecting the role the local validator is already running with
+	StartupStepReconcileRole = "reconcile_role"
+	// StartupStepVoteAccount - checking validator.vote_account's node identity, only when it is set
+	StartupStepVoteAccount = "check_vote_account"
+	// StartupStepFirstRefresh - the first gossip refresh from the cluster rpc
+	StartupStepFirstRefresh = "first_gossip_refresh"
+	// StartupStepMetricsBind - binding the prometheus metrics server port
+	StartupStepMetricsBind = "bind_metrics_server"
+	// StartupStepHealthBind - binding the health check server port
+	StartupStepHealthBind = "bind_health_server"
+	// StartupStepAdminAPIBind - binding the admin api port, only when admin_api is enabled
+	StartupStepAdminAPIBind = "bind_admin_api"
+)
+
+// StartupStep is a startup step and how long it took, or has been running for if not done
+type StartupStep struct {
+	Name     string
+	Duration time.Duration
+	Done     bool
+}
+
+// StartupTimeoutError is returned by Run when startup does not complete within run.startup_timeout
+type StartupTimeoutError struct {
+	// Timeout is run.startup_timeout
+	Timeout time.Duration
+	// Steps are the steps begun in order, the last is still pending
+	Steps []StartupStep
+}
+
+// Pending returns the name of the step still running when the timeout fired, empty if between steps
+func (e *StartupTimeoutError) Pending() string {
+	for _, step := range e.Steps {
+		if !step.Done {
+			return step.Name
+		}
+	}
+	return ""
+}
+
+// Error implements error
+func (e *StartupTimeoutError) Error() string {
+	completed := []string{}
+	pending := "none - between steps"
+	for _, step := range e.Steps {
+		if !step.Done {
+			pending = fmt.Sprintf("%s (running %s)", step.Name, step.Duration.Round(time.Millisecond))
+			continue
+		}
+		completed = append(completed, fmt.Sprintf("%s (%s)", step.Name, step.Duration.Round(time.Millisecond)))
+	}
+	if len(completed) == 0 {
+		completed = append(completed, "none")
+	}
+	return fmt.Sprintf("startup did not complete within run.startup_timeout %s - pending: %s, completed: %s",
+		e.Timeout, pending, strings.Join(completed, ", "))
+}
+
+// ExitCode returns the process exit code for this error
+func (e *StartupTimeoutError) ExitCode() int {
+	return ExitCodeStartupTimeout
+}
+
+// startupStep is a step being tracked
+type startupStep struct {
+	name      string
+	startedAt clock.Instant
+	duration  time.Duration
+	done      bool
+}
+
+// startupTracker records startup steps as they begin and end so a timeout can say where startup got stuck
+type startupTracker struct {
+	mu    sync.Mutex
+	steps []*startupStep
+}
+
+// snapshot returns every step begun so far, pending steps with how long they have been running at now
+func (t *startupTracker) snapshot(now clock.Instant) []StartupStep {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	steps := make([]StartupStep, 0, len(t.steps))
+	for _, step := range t.steps {
+		duration := step.duration
+		if !step.done {
+			duration = now.Sub(step.startedAt)
+		}
+		steps = append(steps, StartupStep{Name: step.name, Duration: duration, Done: step.done})
+	}
+	return steps
+}
+
+// beginStartupStep logs and tracks the start of a startup step, returning the func that ends it
+func (m *Manager) beginStartupStep(name string) (end func()) {
+	step := &startupStep{name: name, startedAt: m.clock.Now()}
+	m.startupSteps.mu.Lock()
+	m.startupSteps.steps = append(m.startupSteps.steps, step)
+	m.startupSteps.mu.Unlock()
+
+	m.logger.Info("startup step begin", "step", name)
+	return func() {
+		m.startupSteps.mu.Lock()
+		step.duration = m.clock.Now().Sub(step.startedAt)
+		step.done = true
+		m.startupSteps.mu.Unlock()
+
+		m.logger.Info("startup step end", "step", name, "duration", step.duration)
+	}
+}
+
+// startup initializes the manager, takes the first gossip refresh and binds the servers, all within
+// run.startup_timeout - a stuck step returns a StartupTimeoutError naming it so the supervisor restarts us
+func (m *Manager) startup() error {
+	done := make(chan error, 1)
+	go func() {
+		done <- m.runStartupSteps()
+	}()
+
+	timeout := m.cfg.Run.StartupTimeout
+	if timeout <= 0 {
+		return <-done
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return &StartupTimeoutError{Timeout: timeout, Steps: m.startupSteps.snapshot(m.clock.Now())}
+	}
+}
+
+// runStartupSteps runs every startup step in order
+func (m *Manager) runStartupSteps() error {
+	if err := m.initialize(); err != nil {
+		return err
+	}
+
+	// a staked passive identity would keep voting after a demotion
+	end := m.beginStartupStep(StartupStepPassiveIdentity)
+	err := m.checkPassiveIdentities()
+	end()
+	if err != nil {
+		return err
+	}
+
+	// the first poll must know whether we are already active or passive
+	end = m.beginStartupStep(StartupStepReconcileRole)
+	m.reconcileRole()
+	end()
+
+	// a mismatched vote account is reported unhealthy from the first poll
+	if m.voteAccount != nil {
+		end = m.beginStartupStep(StartupStepVoteAccount)
+		m.voteAccount.run()
+		end()
+	}
+
+	// initial gossip state population
+	end = m.beginStartupStep(StartupStepFirstRefresh)
+	m.gossipState.Refresh()
+	end()
+	m.markReady()
+	m.startupGrace.start(m.clock.Now())
+
+	// check for active peer in state and log if found
+	m.checkForActivePeer()
+
+	m.startServers()
+	return nil
+}
+
+// startupGrace holds back becoming active for failover.startup_grace_period once startup completed, so samples
+// taken while the cluster rpc is briefly unhappy right after a restart can't hand us the active identity
+type startupGrace struct {
+	duration time.Duration
+
+	mu sync.Mutex
+	// startedAt is when startup completed, zero before then
+	startedAt clock.Instant
+}
+
+// start starts the grace period from startup completing at now
+func (g *startupGrace) start(now clock.Instant) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.startedAt = now
+}
+
+// remaining returns how long is left of the grace period at now, 0 once it has elapsed or before startup completed
+func (g *startupGrace) remaining(now clock.Instant) time.Duration {
+	expiresAt := g.expiresAt()
+	if expiresAt.IsZero() || !expiresAt.After(now) {
+		return 0
+	}
+	return expiresAt.Sub(now)
+}
+
+// expiresAt returns when the grace period expires, zero before startup completed or if it is disabled
+func (g *startupGrace) expiresAt() clock.Instant {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.duration <= 0 || g.startedAt.IsZero() {
+		return clock.Instant{}
+	}
+	return g.startedAt.Add(g.duration)
+}
@@ -0,0 +1,239 @@
+package ha
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStartupManager returns a manager whose startup steps all complete quickly against local fakes
+func newStartupManager(t *testing.T, timeout time.Duration) *Manager {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Run.StartupTimeout = timeout
+	cfg.Cluster.RPCURLs = []string{clusterServer(t, solana.NewWallet().PublicKey()).URL}
+
+	localRPC := testutil.NewFakeRPC()
+	localRPC.SetIdentity(cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	manager := NewManager(NewManagerOptions{Cfg: cfg, GetPublicIPFunc: mockPublicIPFunc, LocalRPC: localRPC})
+	manager.listen = func(network string, address string) (net.Listener, error) {
+		listener, err := net.Listen(network, "127.0.0.1:0")
+		if err == nil {
+			t.Cleanup(func() { listener.Close() })
+		}
+		return listener, err
+	}
+	t.Cleanup(manager.cancel)
+
+	return manager
+}
+
+func TestManager_Startup_CompletesWithinTimeout(t *testing.T) {
+	manager := newStartupManager(t, 5*time.Second)
+
+	require.NoError(t, manager.startup())
+
+	names := []string{}
+	for _, step := range manager.startupSteps.snapshot(manager.clock.Now()) {
+		assert.True(t, step.Done, step.Name)
+		names = append(names, step.Name)
+	}
+	assert.Equal(t, []string{
+		StartupStepPublicIP,
+		StartupStepEvents,
+		StartupStepPassiveIdentity,
+		StartupStepReconcileRole,
+		StartupStepFirstRefresh,
+		StartupStepMetricsBind,
+		StartupStepHealthBind,
+	}, names)
+}
+
+func TestManager_StartServers_BindAddress(t *testing.T) {
+	manager := newStartupManager(t, 5*time.Second)
+	manager.cfg.Prometheus.BindAddress = "127.0.0.1"
+	manager.cfg.HealthCheck.BindAddress = "127.0.0.1"
+
+	addresses := []string{}
+	listen := manager.listen
+	manager.listen = func(network string, address string) (net.Listener, error) {
+		addresses = append(addresses, address)
+		return listen(network, address)
+	}
+
+	require.NoError(t, manager.startup())
+	assert.Equal(t, []string{"127.0.0.1:9090", "127.0.0.1:9091"}, addresses)
+}
+
+func TestManager_Startup_ReturnsStepError(t *testing.T) {
+	manager := newStartupManager(t, 5*time.Second)
+	manager.getPublicIPFunc = mockPublicIPFuncError
+
+	err := manager.startup()
+	assert.ErrorIs(t, err, assert.AnError)
+}
+
+func TestManager_Startup_TimesOutNamingPendingStep(t *testing.T) {
+	tests := []struct {
+		name string
+		// block makes the step block until release is closed
+		block func(t *testing.T, manager *Manager, release chan struct{})
+		step  string
+	}{
+		{
+			name: "public ip services black-holed",
+			block: func(t *testing.T, manager *Manager, release chan struct{}) {
+				manager.getPublicIPFunc = func() (string, error) {
+					<-release
+					return "192.168.1.100", nil
+				}
+			},
+			step: StartupStepPublicIP,
+		},
+		{
+			name: "events file never readable",
+			block: func(t *testing.T, manager *Manager, release chan struct{}) {
+				// opening a fifo for reading blocks until a writer opens it
+				fifo := filepath.Join(t.TempDir(), "events.jsonl")
+				require.NoError(t, syscall.Mkfifo(fifo, 0o600))
+				manager.cfg.Events.File = fifo
+			},
+			step: StartupStepEvents,
+		},
+		{
+			name: "cluster rpc hangs",
+			block: func(t *testing.T, manager *Manager, release chan struct{}) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					<-release
+				}))
+				t.Cleanup(server.Close)
+				manager.cfg.Cluster.RPCURLs = []string{server.URL}
+			},
+			step: StartupStepPassiveIdentity,
+		},
+		{
+			name: "cluster rpc hangs after checking passive identity",
+			block: func(t *testing.T, manager *Manager, release chan struct{}) {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					<-release
+				}))
+				t.Cleanup(server.Close)
+				manager.cfg.Cluster.RPCURLs = []string{server.URL}
+				manager.cfg.Validator.Identities.SkipPassiveStakeCheck = true
+			},
+			step: StartupStepFirstRefresh,
+		},
+		{
+			name: "metrics port bind hangs",
+			block: func(t *testing.T, manager *Manager, release chan struct{}) {
+				manager.listen = func(network string, address string) (net.Listener, error) {
+					<-release
+					return nil, errors.New("released")
+				}
+			},
+			step: StartupStepMetricsBind,
+		},
+		{
+			name: "health port bind hangs",
+			block: func(t *testing.T, manager *Manager, release chan struct{}) {
+				listen := manager.listen
+				manager.listen = func(network string, address string) (net.Listener, error) {
+					if strings.HasSuffix(address, ":9091") {
+						<-release
+						return nil, errors.New("released")
+					}
+					return listen(network, address)
+				}
+			},
+			step: StartupStepHealthBind,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newStartupManager(t, 200*time.Millisecond)
+			release := make(chan struct{})
+			// registered after the fakes' cleanups so it runs first and unblocks them
+			tt.block(t, manager, release)
+			t.Cleanup(func() { close(release) })
+
+			err := manager.startup()
+
+			var timeoutErr *StartupTimeoutError
+			require.ErrorAs(t, err, &timeoutErr)
+			assert.Equal(t, tt.step, timeoutErr.Pending())
+			assert.Equal(t, ExitCodeStartupTimeout, timeoutErr.ExitCode())
+			assert.Equal(t, 200*time.Millisecond, timeoutErr.Timeout)
+			assert.Contains(t, err.Error(), "pending: "+tt.step)
+
+			// every step before the pending one completed
+			steps := timeoutErr.Steps
+			require.NotEmpty(t, steps)
+			for _, step := range steps[:len(steps)-1] {
+				assert.True(t, step.Done, step.Name)
+				assert.Contains(t, err.Error(), step.Name)
+			}
+			assert.False(t, steps[len(steps)-1].Done)
+		})
+	}
+}
+
+func TestStartupTimeoutError_Error(t *testing.T) {
+	err := &StartupTimeoutError{
+		Timeout: 2 * time.Minute,
+		Steps: []StartupStep{
+			{Name: StartupStepPublicIP, Duration: 120 * time.Millisecond, Done: true},
+			{Name: StartupStepEvents, Duration: 2 * time.Millisecond, Done: true},
+			{Name: StartupStepFirstRefresh, Duration: 2 * time.Minute},
+		},
+	}
+
+	assert.Equal(t, "startup did not complete within run.startup_timeout 2m0s - pending: first_gossip_refresh (running 2m0s), "+
+		"completed: resolve_public_ip (120ms), load_events (2ms)", err.Error())
+	assert.Equal(t, StartupStepFirstRefresh, err.Pending())
+
+	// nothing begun yet
+	err = &StartupTimeoutError{Timeout: time.Second}
+	assert.Equal(t, "startup did not complete within run.startup_timeout 1s - pending: none - between steps, completed: none", err.Error())
+	assert.Empty(t, err.Pending())
+}
+
+func TestManager_EnsureHAState_HeldBackByStartupGrace(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
+	cfg.Failover.StartupGracePeriod = time.Minute
+	fake := testutil.NewFakeRPC()
+	manager := newTakeoverManager(t, cfg, fake, fake)
+
+	// not started until startup completes
+	assert.Zero(t, manager.startupGrace.remaining(manager.clock.Now()))
+
+	manager.startupGrace.start(manager.clock.Now())
+	manager.ensureHAState()
+	assert.Equal(t, DecisionActionNone, manager.decision.Action)
+	assert.Equal(t, DecisionReasonStartupGrace, manager.decision.Reason)
+	assert.Empty(t, recordedTypes(manager))
+
+	// the remaining grace period is a timer
+	found := false
+	for _, timer := range manager.timers.snapshot(manager.clock.Now()) {
+		if timer.Name == TimerStartupGrace {
+			found = true
+			assert.Greater(t, timer.Remaining, 50*time.Second)
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,154 @@
+package ha
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/buildinfo"
+)
+
+// Status is the agent's current view of itself served on /status
+type Status struct {
+	ValidatorName  string `json:"validator_name"`
+	PublicIP       string `json:"public_ip"`
+	Role           string `json:"role"`
+	Status         string `json:"status"`
+	FailoverStatus string `json:"failover_status"`
+	PeerCount      int    `json:"peer_count"`
+	SelfInGossip   bool   `json:"self_in_gossip"`
+	// Peers is the gossip visibility of every configured peer other than ourselves, by name
+	Peers             []PeerStatus `json:"peers"`
+	LeaderlessSamples int          `json:"leaderless_samples"`
+	// DryRun is failover.dry_run, true when role transitions don't run commands or hooks
+	DryRun bool `json:"dry_run"`
+	// Paused is true while failover is paused for maintenance, PausedUntil is omitted when it isn't
+	Paused       bool         `json:"paused"`
+	PausedUntil  *time.Time   `json:"paused_until,omitempty"`
+	Version      string       `json:"version"`
+	LastObserved time.Time    `json:"last_observed"`
+	LastChanged  time.Time    `json:"last_changed"`
+	Fitness      *PeerFitness `json:"fitness,omitempty"`
+	Arbitration  *Arbitration `json:"arbitration,omitempty"`
+	// Gates is the latest safety gate evaluation, omitted until a promotion has been considered
+	Gates  *GateEvaluation `json:"gates,omitempty"`
+	Timers []TimerStatus   `json:"timers"`
+	// Coordination is what we last saw of the failover.coordination.etcd key, omitted when it is not set
+	Coordination *CoordinationStatus `json:"coordination,omitempty"`
+	// FailoverID is the running transition's failover id, LastFailoverID the running or last one's - both omitted
+	// until a transition has run
+	FailoverID     string `json:"failover_id,omitempty"`
+	LastFailoverID string `json:"last_failover_id,omitempty"`
+}
+
+// PeerStatus is what we see of a configured peer in gossip
+type PeerStatus struct {
+	Name     string `json:"name"`
+	IP       string `json:"ip"`
+	InGossip bool   `json:"in_gossip"`
+	// LastSeenAt is omitted when the peer has not been seen in gossip since startup
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	// Heartbeat is omitted when failover.heartbeat is not enabled
+	Heartbeat *PeerHeartbeatStatus `json:"heartbeat,omitempty"`
+}
+
+// PeerHeartbeatStatus is what a peer's agent last reported on its heartbeat
+type PeerHeartbeatStatus struct {
+	Reachable bool      `json:"reachable"`
+	Role      string    `json:"role,omitempty"`
+	Identity  string    `json:"identity,omitempty"`
+	Healthy   bool      `json:"healthy"`
+	Slot      uint64    `json:"slot,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// TimerStatus is a timer governing agent behaviour and how long it has left
+type TimerStatus struct {
+	Name    string `json:"name"`
+	Purpose string `json:"purpose"`
+	// ExpiresAt is omitted when the timer is not running
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	RemainingSeconds float64    `json:"remaining_seconds"`
+}
+
+// status returns the current status and timers from the cache state and the latest fitness and arbitration
+func (m *Manager) status() Status {
+	state := m.cache.GetState()
+
+	timers := make([]TimerStatus, 0, len(state.Timers))
+	for _, timer := range state.Timers {
+		timerStatus := TimerStatus{
+			Name:             timer.Name,
+			Purpose:          timer.Purpose,
+			RemainingSeconds: timer.Remaining.Seconds(),
+		}
+		if !timer.ExpiresAt.IsZero() {
+			expiresAt := timer.ExpiresAt.UTC()
+			timerStatus.ExpiresAt = &expiresAt
+		}
+		timers = append(timers, timerStatus)
+	}
+
+	peers := make([]PeerStatus, 0, len(state.Peers))
+	for name, peer := range state.Peers {
+		peerStatus := PeerStatus{Name: name, IP: peer.IP, InGossip: peer.InGossip}
+		if !peer.LastSeenAt.IsZero() {
+			lastSeenAt := peer.LastSeenAt.UTC()
+			peerStatus.LastSeenAt = &lastSeenAt
+		}
+		if heartbeat := peer.Heartbeat; heartbeat != nil {
+			peerStatus.Heartbeat = &PeerHeartbeatStatus{
+				Reachable: heartbeat.Reachable,
+				Identity:  heartbeat.Identity,
+				Healthy:   heartbeat.Healthy,
+				Slot:      heartbeat.Slot,
+				Error:     heartbeat.Error,
+				CheckedAt: heartbeat.CheckedAt.UTC(),
+			}
+			if heartbeat.Reachable {
+				peerStatus.Heartbeat.Role = heartbeat.Role.String()
+			}
+		}
+		peers = append(peers, peerStatus)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+
+	var pausedUntil *time.Time
+	if !state.PausedUntil.IsZero() {
+		at := state.PausedUntil.UTC()
+		pausedUntil = &at
+	}
+
+	return Status{
+		ValidatorName:     state.ValidatorName,
+		PublicIP:          state.PublicIP,
+		Role:              state.Role.String(),
+		Status:            state.Status.String(),
+		FailoverStatus:    state.FailoverStatus.String(),
+		PeerCount:         state.PeerCount,
+		SelfInGossip:      state.SelfInGossip,
+		Peers:             peers,
+		LeaderlessSamples: state.LeaderlessSamples,
+		DryRun:            m.cfg.Failover.DryRun,
+		Paused:            state.Paused,
+		PausedUntil:       pausedUntil,
+		Version:           buildinfo.Get().Version,
+		LastObserved:      state.LastObserved,
+		LastChanged:       state.LastChanged,
+		Fitness:           m.currentFitness(),
+		Arbitration:       m.currentArbitration(),
+		Gates:             m.currentGateEvaluation(),
+		Timers:            timers,
+		Coordination:      m.coordinator.status(),
+		FailoverID:        m.currentFailoverID(),
+		LastFailoverID:    m.lastFailoverIDString(),
+	}
+}
+
+// handleStatus serves the current status as JSON
+func (m *Manager) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m.status())
+}
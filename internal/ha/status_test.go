@@ -0,0 +1,41 @@
+package ha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/buildinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_HandleStatus_PeersDryRunAndVersion(t *testing.T) {
+	manager, _ := newManualManager(t, false)
+	manager.cfg.Failover.DryRun = true
+	require.NoError(t, manager.initialize())
+	manager.gossipState.Refresh()
+	manager.refreshMetrics()
+
+	recorder := httptest.NewRecorder()
+	manager.handleStatus(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var status Status
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&status))
+	assert.True(t, status.DryRun)
+	assert.Equal(t, buildinfo.Get().Version, status.Version)
+	assert.Equal(t, 1, status.LeaderlessSamples)
+	assert.Equal(t, "192.168.1.100", status.PublicIP)
+	assert.False(t, status.LastObserved.IsZero())
+
+	// sorted by name, never seen peers have no last seen time
+	require.Len(t, status.Peers, 2)
+	assert.Equal(t, "peer1", status.Peers[0].Name)
+	assert.Equal(t, "127.0.0.1", status.Peers[0].IP)
+	assert.True(t, status.Peers[0].InGossip)
+	assert.NotNil(t, status.Peers[0].LastSeenAt)
+	assert.Equal(t, PeerStatus{Name: "peer2", IP: "192.168.1.102"}, status.Peers[1])
+}
@@ -0,0 +1,82 @@
+package ha
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/sdnotify"
+)
+
+// notifySystemd sends states to systemd along with the agent's status - a no-op unless run by systemd with
+// Type=notify
+func (m *Manager) notifySystemd(states ...string) {
+	if m.systemd == nil {
+		return
+	}
+
+	if err := m.systemd.Notify(append(states, sdnotify.Status(m.systemdStatus()))...); err != nil {
+		m.logger.Warn("failed to notify systemd", "error", err)
+	}
+}
+
+// systemdStatus returns the status systemctl status shows for us, our role and failover status
+func (m *Manager) systemdStatus() string {
+	if m.shuttingDown() {
+		return "shutting down"
+	}
+
+	state := m.cache.GetState()
+	status := fmt.Sprintf("role %s, %s, failover %s", state.Role, state.Status, state.FailoverStatus)
+	if state.Paused {
+		status += ", paused"
+	}
+	return status
+}
+
+// notifySystemdReady tells systemd we are started once initialized, with a first gossip refresh and the servers
+// listening, and warns when systemd's watchdog would restart an agent that is only waiting for its next cycle
+func (m *Manager) notifySystemdReady() {
+	if m.systemd == nil {
+		return
+	}
+
+	m.notifySystemd(sdnotify.Ready)
+	interval := m.systemd.WatchdogInterval()
+	if interval == 0 {
+		m.logger.Debug("notified systemd ready", "watchdog", false)
+		return
+	}
+
+	// every cycle pings the watchdog, they are up to two poll intervals apart when one overruns
+	longestPollInterval := m.cfg.Failover.PollIntervalDuration
+	if m.cfg.Failover.AdaptivePoll {
+		longestPollInterval *= time.Duration(m.cfg.Failover.AdaptivePollMaxMultiplier)
+	}
+	if interval < 2*longestPollInterval {
+		m.logger.Warn("systemd WatchdogSec is shorter than two poll intervals - systemd may restart a healthy agent",
+			"watchdog_sec", interval,
+			"poll_interval", longestPollInterval,
+		)
+	}
+	m.logger.Debug("notified systemd ready", "watchdog", true, "watchdog_sec", interval)
+
+	go m.keepWatchdogAliveWhileTransitioning(interval / 2)
+}
+
+// keepWatchdogAliveWhileTransitioning pings systemd's watchdog every interval while a role transition runs and
+// the monitor loop is waiting on it - like /livez, a supervisor never restarts the agent mid failover
+func (m *Manager) keepWatchdogAliveWhileTransitioning(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			if m.transitioning.Load() {
+				m.notifySystemd(sdnotify.Watchdog)
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ha
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/sdnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSystemdSocket points the manager at a notify socket like systemd's with WatchdogSec watchdog, returning a
+// func reading the next notification sent to it
+func newSystemdSocket(t *testing.T, manager *Manager, watchdog string) (next func() string) {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	t.Setenv("WATCHDOG_USEC", watchdog)
+	manager.systemd = sdnotify.FromEnv()
+
+	return func() string {
+		buf := make([]byte, 4096)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+}
+
+func TestManager_NotifySystemd(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	manager.cfg.Run.ShutdownTimeout = time.Minute
+	next := newSystemdSocket(t, manager, "")
+
+	manager.refreshMetrics()
+	manager.notifySystemdReady()
+	assert.Equal(t, "READY=1\nSTATUS=role passive, healthy, failover idle", next())
+
+	manager.ensureActive(DecisionReasonNoActivePeer)
+	manager.refreshMetrics()
+	manager.notifySystemd(sdnotify.Watchdog)
+	assert.Equal(t, "WATCHDOG=1\nSTATUS=role active, healthy, failover idle", next())
+
+	manager.requestShutdown("terminated")
+	assert.Equal(t, "STOPPING=1\nSTATUS=shutting down", next())
+}
+
+func TestManager_NotifySystemd_WatchdogWhileTransitioning(t *testing.T) {
+	manager, _ := newPauseManager(t)
+	manager.cfg.Failover.PollIntervalDuration = time.Second
+	next := newSystemdSocket(t, manager, "100000")
+
+	manager.notifySystemdReady()
+	assert.Contains(t, next(), "READY=1")
+
+	// the monitor loop is held up by the transition
+	manager.transitioning.Store(true)
+	assert.Contains(t, next(), "WATCHDOG=1")
+}
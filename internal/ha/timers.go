@@ -0,0 +1,101 @@
+package ha
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/cache"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+)
+
+const (
+	// TimerSampleHookInterval - sample hooks run at most once per failover.sample_hook_interval
+	TimerSampleHookInterval = "sample_hook_interval"
+	// TimerPublicIPRefresh - a discovered public IP is resolved again once per validator.public_ip_refresh_interval
+	TimerPublicIPRefresh = "public_ip_refresh"
+	// TimerVoteAccountCheck - validator.vote_account's node identity is checked once per validator.vote_account_check_interval
+	TimerVoteAccountCheck = "vote_account_check"
+	// TimerStartupGrace - no failover to active is started until failover.startup_grace_period has elapsed since startup
+	TimerStartupGrace = "startup_grace_period"
+	// TimerFailoverCooldown - no transition is started until failover.cooldown has elapsed since the last one
+	TimerFailoverCooldown = "failover_cooldown"
+	// TimerAdaptivePollRelax - a stretched poll interval relaxes a step once a window passes without rate limiting
+	TimerAdaptivePollRelax = "adaptive_poll_relax"
+	// TimerRateLimitWarning - the rate limited recommendation is repeated at most once per window
+	TimerRateLimitWarning = "rate_limit_warning_repeat"
+	// TimerMaintenancePause - a maintenance pause resumes on its own once the duration it was paused for has elapsed
+	TimerMaintenancePause = "maintenance_pause"
+)
+
+// registeredTimer is a named timer whose expiry is read from the feature that owns it
+type registeredTimer struct {
+	name      string
+	purpose   string
+	expiresAt func() clock.Instant
+}
+
+// timerRegistry holds every timer features register so they all show up in the timers view
+type timerRegistry struct {
+	mu     sync.Mutex
+	timers map[string]registeredTimer
+}
+
+// newTimerRegistry creates an empty timer registry
+func newTimerRegistry() *timerRegistry {
+	return &timerRegistry{timers: map[string]registeredTimer{}}
+}
+
+// register adds or replaces the timer called name - expiresAt returns zero while the timer is not running
+func (r *timerRegistry) register(name string, purpose string, expiresAt func() clock.Instant) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timers[name] = registeredTimer{name: name, purpose: purpose, expiresAt: expiresAt}
+}
+
+// snapshot returns every registered timer sorted by name with its remaining time at now, expired and
+// stopped timers have none remaining
+func (r *timerRegistry) snapshot(now clock.Instant) []cache.Timer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	timers := make([]cache.Timer, 0, len(r.timers))
+	for _, registered := range r.timers {
+		expiresAt := registered.expiresAt()
+		timer := cache.Timer{
+			Name:      registered.name,
+			Purpose:   registered.purpose,
+			ExpiresAt: expiresAt.Time(),
+		}
+		// remaining is monotonic so a wall clock step never stretches or cuts short a timer
+		if !expiresAt.IsZero() && expiresAt.After(now) {
+			timer.Remaining = expiresAt.Sub(now)
+		}
+		timers = append(timers, timer)
+	}
+	sort.Slice(timers, func(i, j int) bool { return timers[i].Name < timers[j].Name })
+
+	return timers
+}
+
+// registerTimers registers the timers of the features in use
+func (m *Manager) registerTimers() {
+	m.timers.register(TimerAdaptivePollRelax, "stretched poll interval relaxes a step after a window without rate limiting", m.pollInterval.relaxesAt)
+	m.timers.register(TimerRateLimitWarning, "rate limited recommendation is logged at most once per window", m.pollInterval.warningRepeatsAt)
+	m.timers.register(TimerMaintenancePause, "failover paused for maintenance resumes on its own at most failover.max_pause_duration after pausing", m.pause.expiresAt)
+	if m.sampleHooks != nil {
+		m.timers.register(TimerSampleHookInterval, "sample hooks run at most once per failover.sample_hook_interval", m.sampleHooks.nextRunAt)
+	}
+	if m.cfg.Failover.StartupGracePeriod > 0 {
+		m.timers.register(TimerStartupGrace, "no failover to active is started until failover.startup_grace_period has elapsed since startup", m.startupGrace.expiresAt)
+	}
+	if m.cfg.Failover.Cooldown > 0 {
+		m.timers.register(TimerFailoverCooldown, "no transition is started until failover.cooldown has elapsed since the last one", m.cooldown.expiresAt)
+	}
+	if m.voteAccount != nil {
+		m.timers.register(TimerVoteAccountCheck, "validator.vote_account's node identity is checked once per validator.vote_account_check_interval", m.voteAccount.nextRunAt)
+	}
+	if m.publicIP != nil {
+		m.timers.register(TimerPublicIPRefresh, "discovered public ip is resolved again once per validator.public_ip_refresh_interval", m.publicIP.nextRunAt)
+	}
+}
@@ -0,0 +1,141 @@
+package ha
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+)
+
+// timerRemainingSeconds returns the timer_remaining_seconds value for the timer, false if not exported
+func timerRemainingSeconds(t *testing.T, manager *Manager, timer string) (float64, bool) {
+	t.Helper()
+
+	metricFamilies, err := manager.metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != "solana_validator_ha_timer_remaining_seconds" {
+			continue
+		}
+		for _, metric := range metricFamily.Metric {
+			for _, label := range metric.Label {
+				if label.GetName() == "timer" && label.GetValue() == timer {
+					return metric.Gauge.GetValue(), true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestTimerRegistry_Snapshot(t *testing.T) {
+	now := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)).Now()
+	registry := newTimerRegistry()
+	registry.register("running", "a running timer", func() clock.Instant { return now.Add(90 * time.Second) })
+	registry.register("expired", "an expired timer", func() clock.Instant { return now.Add(-time.Second) })
+	registry.register("stopped", "a stopped timer", func() clock.Instant { return clock.Instant{} })
+
+	timers := registry.snapshot(now)
+	require.Len(t, timers, 3)
+
+	// sorted by name
+	assert.Equal(t, "expired", timers[0].Name)
+	assert.Equal(t, time.Duration(0), timers[0].Remaining)
+	assert.Equal(t, "running", timers[1].Name)
+	assert.Equal(t, "a running timer", timers[1].Purpose)
+	assert.Equal(t, now.Add(90*time.Second).Time(), timers[1].ExpiresAt)
+	assert.Equal(t, 90*time.Second, timers[1].Remaining)
+	assert.Equal(t, "stopped", timers[2].Name)
+	assert.True(t, timers[2].ExpiresAt.IsZero())
+	assert.Equal(t, time.Duration(0), timers[2].Remaining)
+
+	// registering a name again replaces it
+	registry.register("running", "replaced", func() clock.Instant { return clock.Instant{} })
+	timers = registry.snapshot(now)
+	require.Len(t, timers, 3)
+	assert.Equal(t, "replaced", timers[1].Purpose)
+}
+
+func TestManager_Timers_RenderedInStatusAndMetrics(t *testing.T) {
+	manager, now := newFakeClockManager(t)
+
+	// the poll interval and maintenance pause timers are always registered
+	names := []string{}
+	for _, timer := range manager.timers.snapshot(now.Now()) {
+		names = append(names, timer.Name)
+	}
+	assert.Equal(t, []string{TimerAdaptivePollRelax, TimerMaintenancePause, TimerRateLimitWarning}, names)
+
+	// a feature registering a timer shows up without any other wiring
+	expiresAt := now.Now().Add(30 * time.Second)
+	manager.timers.register("fake_cooldown", "fake cooldown for testing", func() clock.Instant { return expiresAt })
+
+	manager.refreshMetrics()
+	remaining, ok := timerRemainingSeconds(t, manager, "fake_cooldown")
+	require.True(t, ok)
+	assert.Equal(t, 30.0, remaining)
+
+	recorder := httptest.NewRecorder()
+	manager.handleStatus(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var status Status
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&status))
+	require.Len(t, status.Timers, 4)
+	timer := status.Timers[1]
+	assert.Equal(t, "fake_cooldown", timer.Name)
+	assert.Equal(t, "fake cooldown for testing", timer.Purpose)
+	require.NotNil(t, timer.ExpiresAt)
+	assert.True(t, expiresAt.Time().Equal(*timer.ExpiresAt))
+	assert.Equal(t, 30.0, timer.RemainingSeconds)
+	assert.Nil(t, status.Timers[0].ExpiresAt, "adaptive poll isn't stretched so its timer isn't running")
+
+	// counts down and stops at zero once expired
+	now.Advance(20 * time.Second)
+	manager.refreshMetrics()
+	remaining, _ = timerRemainingSeconds(t, manager, "fake_cooldown")
+	assert.Equal(t, 10.0, remaining)
+
+	now.Advance(time.Minute)
+	manager.refreshMetrics()
+	remaining, _ = timerRemainingSeconds(t, manager, "fake_cooldown")
+	assert.Equal(t, 0.0, remaining)
+	assert.Equal(t, time.Duration(0), manager.cache.GetState().Timers[1].Remaining)
+}
+
+func TestTimerRegistry_ClockStep(t *testing.T) {
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	expiresAt := fake.Now().Add(time.Minute)
+	registry := newTimerRegistry()
+	registry.register("cooldown", "a cooldown", func() clock.Instant { return expiresAt })
+
+	// stepping the wall clock either way neither stretches nor cuts short the timer
+	fake.Advance(10 * time.Second)
+	fake.Step(time.Hour)
+	assert.Equal(t, 50*time.Second, registry.snapshot(fake.Now())[0].Remaining)
+	fake.Step(-2 * time.Hour)
+	assert.Equal(t, 50*time.Second, registry.snapshot(fake.Now())[0].Remaining)
+}
+
+func TestManager_Timers_FeatureTimers(t *testing.T) {
+	manager, now := newFakeClockManager(t)
+
+	// stretching the poll interval starts the relax window and the warning repeat
+	manager.pollInterval.enabled = true
+	manager.pollInterval.maxMultiplier = 4
+	manager.pollInterval.update(rpc.RateLimitStats{RequestsInWindow: 10, RateLimitedInWindow: 5})
+
+	timers := manager.timers.snapshot(now.Now())
+	require.Len(t, timers, 3)
+	assert.Equal(t, TimerAdaptivePollRelax, timers[0].Name)
+	assert.Equal(t, manager.pollInterval.window, timers[0].Remaining)
+	assert.Equal(t, TimerRateLimitWarning, timers[2].Name)
+	assert.Equal(t, manager.pollInterval.window, timers[2].Remaining)
+}
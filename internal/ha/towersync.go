@@ -0,0 +1,103 @@
+package ha
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/command"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+)
+
+const (
+	towerSyncResultSuccess     = "success"
+	towerSyncResultFailure     = "failure"
+	towerSyncResultUnreachable = "unreachable"
+)
+
+// syncTower copies the tower file from the last known active peer with failover.tower_sync before the active command
+// runs, returning an error when the promotion must not go ahead
+func (m *Manager) syncTower() error {
+	towerSync := m.cfg.Failover.TowerSync
+	if !towerSync.Enabled() {
+		return nil
+	}
+
+	peerState, ok := m.lastKnownActivePeer()
+	if !ok {
+		return m.towerSourceUnreachable("", fmt.Errorf("no active peer has been seen in gossip"))
+	}
+
+	name, args := towerSync.Command(peerState.IP)
+	rendered := strings.Join(append([]string{name}, args...), " ")
+	if m.cfg.Failover.DryRun {
+		m.transitionLogger().Info("dry_run - would have synced tower from last known active peer", "peer", peerState.Name, "command", rendered)
+		err := command.AuditDryRun(command.RunOptions{
+			Name:       "tower-sync",
+			Command:    name,
+			Args:       args,
+			FailoverID: m.currentFailoverID(),
+			Audit:      m.auditLog,
+		})
+		if err != nil {
+			m.transitionLogger().Warn("failed to append tower sync to the audit log", "error", err)
+		}
+		return nil
+	}
+
+	// tell an unreachable source apart from a failed copy so on_unreachable only applies to the former
+	address := net.JoinHostPort(peerState.IP, strconv.Itoa(towerSync.Port))
+	conn, err := net.DialTimeout("tcp", address, towerSync.Timeout)
+	if err != nil {
+		return m.towerSourceUnreachable(peerState.Name, err)
+	}
+	conn.Close()
+
+	m.transitionLogger().Info("syncing tower from last known active peer", "peer", peerState.Name, "command", rendered)
+	startedAt := time.Now()
+	err = command.Run(command.RunOptions{
+		Name:         "tower-sync",
+		Command:      name,
+		Args:         args,
+		InheritEnv:   true,
+		Timeout:      towerSync.Timeout,
+		LoggerPrefix: m.logPrefix,
+		StreamOutput: true,
+		FailoverID:   m.currentFailoverID(),
+		Audit:        m.auditLog,
+	})
+	duration := time.Since(startedAt)
+	if err != nil {
+		m.metrics.ObserveTowerSync(towerSyncResultFailure, duration)
+		return fmt.Errorf("failed to copy tower from %s (%s): %w", peerState.Name, peerState.IP, err)
+	}
+
+	m.metrics.ObserveTowerSync(towerSyncResultSuccess, duration)
+	m.transitionLogger().Info("synced tower from last known active peer", "peer", peerState.Name, "duration", duration)
+	return nil
+}
+
+// towerSourceUnreachable applies failover.tower_sync.on_unreachable when the tower can't be copied from peerName
+// because it can't be reached, or no active peer is known
+func (m *Manager) towerSourceUnreachable(peerName string, reason error) error {
+	m.metrics.ObserveTowerSync(towerSyncResultUnreachable, 0)
+	towerSync := m.cfg.Failover.TowerSync
+
+	switch towerSync.OnUnreachable {
+	case config.TowerSyncOnUnreachableContinue:
+		m.transitionLogger().Warn("tower source unreachable - promoting without the tower", "peer", peerName, "reason", reason)
+		return nil
+	case config.TowerSyncOnUnreachableContinueAfterDelay:
+		m.transitionLogger().Warn("tower source unreachable - promoting without the tower after a delay", "peer", peerName, "reason", reason, "delay", towerSync.UnreachableDelay)
+		select {
+		case <-m.ctx.Done():
+			return fmt.Errorf("shutting down while waiting to promote without the tower")
+		case <-time.After(towerSync.UnreachableDelay):
+			return nil
+		}
+	default:
+		return fmt.Errorf("tower source %s unreachable, failover.tower_sync.on_unreachable is %s: %w", peerName, towerSync.OnUnreachable, reason)
+	}
+}
@@ -0,0 +1,157 @@
+package ha
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/gossip"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// towerSyncHarness is a manager that last saw peer1 active on 127.0.0.2, with a fake scp on PATH recording its args
+type towerSyncHarness struct {
+	manager *Manager
+	scpLog  string
+}
+
+func newTowerSyncHarness(t *testing.T, sourceReachable bool, scpExitCode int) *towerSyncHarness {
+	t.Helper()
+
+	// the source's ssh port is a live listener when reachable, else a port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	if sourceReachable {
+		t.Cleanup(func() { listener.Close() })
+	} else {
+		listener.Close()
+	}
+
+	bin := t.TempDir()
+	h := &towerSyncHarness{scpLog: filepath.Join(bin, "scp.log")}
+	script := "#!/bin/sh\necho \"$@\" >> " + h.scpLog + "\nexit " + strconv.Itoa(scpExitCode) + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "scp"), []byte(script), 0o755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := createLiveTestConfig()
+	cfg.Failover.Peers = config.Peers{"peer1": {Name: "peer1", IP: "127.0.0.2"}}
+	cfg.Failover.TowerSync = config.TowerSync{
+		Method:         config.TowerSyncMethodSSH,
+		User:           "sol",
+		Source:         "/mnt/ledger/tower-1_9-*.bin",
+		DestinationDir: "/mnt/ledger",
+	}
+	cfg.Failover.TowerSync.SetDefaults()
+	cfg.Failover.TowerSync.Port = port
+	cfg.Failover.TowerSync.Timeout = time.Second
+
+	activePubkey := cfg.Validator.Identities.ActiveKeyPair.PublicKey()
+	fake := testutil.NewFakeRPC()
+	fake.SetVoteAccounts([]solanagorpc.VoteAccountsResult{testutil.VoteAccount(activePubkey)}, nil)
+	h.manager = newTakeoverManager(t, cfg, fake, fake)
+	fake.SetClusterNodes(
+		testutil.GossipNode(t, "127.0.0.1", cfg.Validator.Identities.PassiveKeyPair.PublicKey()),
+		testutil.GossipNode(t, "127.0.0.2", activePubkey),
+	)
+	h.manager.gossipState.Refresh()
+
+	// the active command switches the identity
+	h.manager.localRPC.(*testutil.FakeRPC).SetIdentity(activePubkey)
+	return h
+}
+
+// scpRuns returns the args of every fake scp run
+func (h *towerSyncHarness) scpRuns(t *testing.T) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(h.scpLog)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestManager_EnsureActive_SyncsTower(t *testing.T) {
+	h := newTowerSyncHarness(t, true, 0)
+
+	h.manager.ensureActive(DecisionReasonNoActivePeer)
+
+	runs := h.scpRuns(t)
+	require.Len(t, runs, 1)
+	assert.Contains(t, runs[0], "sol@127.0.0.2:/mnt/ledger/tower-1_9-*.bin /mnt/ledger/")
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeActive}, recordedTypes(h.manager))
+	assert.Equal(t, 1.0, counterMetric(t, h.manager, "solana_validator_ha_tower_syncs_total"))
+}
+
+func TestManager_EnsureActive_TowerSyncFailureAborts(t *testing.T) {
+	h := newTowerSyncHarness(t, true, 1)
+
+	h.manager.ensureActive(DecisionReasonNoActivePeer)
+
+	assert.Len(t, h.scpRuns(t), 1)
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeTransitionFailed}, recordedTypes(h.manager))
+	assert.Equal(t, "failed to sync tower", h.manager.events.Events()[1].Message)
+}
+
+func TestManager_EnsureActive_TowerSourceUnreachable(t *testing.T) {
+	tests := []struct {
+		onUnreachable string
+		expected      []string
+	}{
+		{config.TowerSyncOnUnreachableAbort, []string{events.TypeBecomingActive, events.TypeTransitionFailed}},
+		{config.TowerSyncOnUnreachableContinue, []string{events.TypeBecomingActive, events.TypeActive}},
+		{config.TowerSyncOnUnreachableContinueAfterDelay, []string{events.TypeBecomingActive, events.TypeActive}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.onUnreachable, func(t *testing.T) {
+			h := newTowerSyncHarness(t, false, 0)
+			h.manager.cfg.Failover.TowerSync.OnUnreachable = tt.onUnreachable
+			h.manager.cfg.Failover.TowerSync.UnreachableDelay = 10 * time.Millisecond
+
+			h.manager.ensureActive(DecisionReasonNoActivePeer)
+
+			// nothing is copied from an unreachable source
+			assert.Empty(t, h.scpRuns(t))
+			assert.Equal(t, tt.expected, recordedTypes(h.manager))
+			assert.Equal(t, 1.0, counterMetric(t, h.manager, "solana_validator_ha_tower_syncs_total"))
+		})
+	}
+}
+
+func TestManager_EnsureActive_TowerSyncWithoutKnownActivePeer(t *testing.T) {
+	h := newTowerSyncHarness(t, true, 0)
+	// no peer has been seen active since startup
+	h.manager.gossipState = gossip.NewState(gossip.Options{
+		ClusterRPC:   h.manager.clusterRPC,
+		ActivePubkey: h.manager.cfg.Validator.Identities.ActiveKeyPair.PublicKey().String(),
+		SelfIP:       h.manager.peerSelf.IP,
+		ConfigPeers:  h.manager.cfg.Failover.Peers,
+		LogPrefix:    h.manager.logPrefix,
+	})
+
+	h.manager.ensureActive(DecisionReasonNoActivePeer)
+
+	assert.Empty(t, h.scpRuns(t))
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeTransitionFailed}, recordedTypes(h.manager))
+}
+
+func TestManager_EnsureActive_TowerSyncDryRun(t *testing.T) {
+	h := newTowerSyncHarness(t, true, 0)
+	h.manager.cfg.Failover.DryRun = true
+
+	h.manager.ensureActive(DecisionReasonNoActivePeer)
+
+	assert.Empty(t, h.scpRuns(t))
+	assert.Equal(t, 0.0, counterMetric(t, h.manager, "solana_validator_ha_tower_syncs_total"))
+}
@@ -0,0 +1,72 @@
+package ha
+
+import (
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+)
+
+const (
+	// verifiedEnvVar is passed to post hooks with whether local rpc confirmed the role command switched the identity
+	verifiedEnvVar = "SVHA_VERIFIED"
+)
+
+// verifyIdentity waits up to failover.identity_verify_timeout for the local validator to report pubkey as role's
+// identity after its command ran, returning whether it did - in dry run nothing ran so there is nothing to wait for
+func (m *Manager) verifyIdentity(role constants.Role, pubkey string) bool {
+	if m.cfg.Failover.DryRun {
+		m.transitionLogger().Info("dry_run - would have waited for local rpc to report the new identity",
+			"role", role,
+			"pubkey", pubkey,
+			"timeout", m.cfg.Failover.IdentityVerifyTimeout,
+		)
+		return false
+	}
+
+	verified := m.isSelfActive
+	if role == constants.RolePassive {
+		verified = m.isSelfPassive
+	}
+	if verified() {
+		return true
+	}
+	if m.cfg.Failover.IdentityVerifyTimeout <= 0 {
+		return false
+	}
+
+	m.transitionLogger().Info("waiting for local rpc to report the new identity", "role", role, "pubkey", pubkey, "timeout", m.cfg.Failover.IdentityVerifyTimeout)
+	timeout := time.After(m.cfg.Failover.IdentityVerifyTimeout)
+	ticker := time.NewTicker(m.cfg.Failover.IdentityVerifyInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return false
+		case <-timeout:
+			return verified()
+		case <-ticker.C:
+			if verified() {
+				return true
+			}
+		}
+	}
+}
+
+// failVerification records that local rpc never reported the identity a role command should have switched to -
+// status is unhealthy until a later transition is confirmed since the identity is in doubt
+func (m *Manager) failVerification() {
+	m.failRoleCommand()
+	m.identityUnverified = true
+	state := m.cache.GetState()
+	state.Status = constants.StatusUnhealthy
+	m.cache.UpdateState(state)
+}
+
+// confirmTransition clears any earlier failure once local rpc confirmed a transition, returning failover status to idle
+func (m *Manager) confirmTransition() {
+	m.roleCommandFailed = false
+	m.identityUnverified = false
+	state := m.cache.GetState()
+	state.FailoverStatus = constants.FailoverStatusIdle
+	m.cache.UpdateState(state)
+}
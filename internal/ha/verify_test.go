@@ -0,0 +1,73 @@
+package ha
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_EnsureActive_WaitsForIdentity(t *testing.T) {
+	cfg := createLiveTestConfig()
+	cfg.Failover.IdentityVerifyTimeout = 5 * time.Second
+	cfg.Failover.IdentityVerifyInterval = 10 * time.Millisecond
+	manager, _, localRPC := newFakeRPCManager(t, cfg)
+
+	// the validator takes a moment to answer with its new identity
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		localRPC.SetIdentity(cfg.Validator.Identities.ActiveKeyPair.PublicKey())
+	}()
+
+	manager.ensureActive(DecisionReasonNoActivePeer)
+
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeActive}, recordedTypes(manager))
+	assert.Greater(t, localRPC.Calls("getIdentity"), 1)
+	assert.Equal(t, constants.FailoverStatusIdle, manager.cache.GetState().FailoverStatus)
+}
+
+func TestManager_EnsureActive_IdentityVerifyTimeout(t *testing.T) {
+	h := newRollbackHarness(t, false, "true")
+	h.manager.cfg.Failover.IdentityVerifyTimeout = 50 * time.Millisecond
+	h.manager.cfg.Failover.IdentityVerifyInterval = 10 * time.Millisecond
+	h.manager.cfg.Failover.Active.Hooks.Post = []config.Hook{{
+		Name:    "post-active",
+		Command: "sh",
+		Args:    []string{"-c", `echo "post-active result=$SVHA_COMMAND_RESULT verified=$SVHA_VERIFIED" >> ` + h.runLog},
+	}}
+
+	// the command succeeds but the identity stays passive
+	h.manager.ensureActive(DecisionReasonNoActivePeer)
+
+	assert.Equal(t, "post-active result=success verified=false", h.runs(t)[len(h.runs(t))-1])
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeTransitionFailed}, h.eventTypes())
+	state := h.manager.cache.GetState()
+	assert.Equal(t, constants.FailoverStatusFailed, state.FailoverStatus)
+	assert.Equal(t, constants.StatusUnhealthy, state.Status)
+
+	// unhealthy until a later transition is confirmed
+	h.manager.refreshMetrics()
+	assert.Equal(t, constants.StatusUnhealthy, h.manager.cache.GetState().Status)
+
+	h.manager.ensurePassive(manualHookReason)
+	h.manager.refreshMetrics()
+	state = h.manager.cache.GetState()
+	assert.Equal(t, constants.FailoverStatusIdle, state.FailoverStatus)
+	assert.False(t, h.manager.identityUnverified)
+}
+
+func TestManager_EnsurePassive_PostHooksSeeVerified(t *testing.T) {
+	h := newRollbackHarness(t, false, "true")
+	h.manager.cfg.Failover.Passive.Hooks.Post = []config.Hook{{
+		Name:    "post-passive",
+		Command: "sh",
+		Args:    []string{"-c", `echo "post-passive verified=$SVHA_VERIFIED" >> ` + h.runLog},
+	}}
+
+	h.manager.ensurePassive(manualHookReason)
+
+	assert.Equal(t, "post-passive verified=true", h.runs(t)[len(h.runs(t))-1])
+}
@@ -0,0 +1,160 @@
+package ha
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+)
+
+// voteAccountChecker checks that validator.vote_account's node identity is the active identity - at startup, in
+// the background at most once per interval and before every promotion. A takeover with any other identity would
+// leave the vote account without a voter.
+type voteAccountChecker struct {
+	check    func() (mismatch bool, detail string, err error)
+	interval time.Duration
+	logger   *log.Logger
+	clock    clock.Clock
+
+	mu        sync.Mutex
+	lastRunAt clock.Instant
+	running   bool
+	mismatch  bool
+	wg        sync.WaitGroup
+}
+
+// newVoteAccountChecker creates a checker for validator.vote_account, returning nil when it is not set
+func newVoteAccountChecker(validator config.Validator, check func() (bool, string, error), logger *log.Logger, clk clock.Clock) *voteAccountChecker {
+	if validator.VoteAccount == "" {
+		return nil
+	}
+
+	return &voteAccountChecker{
+		check:     check,
+		interval:  validator.VoteAccountCheckInterval,
+		logger:    logger,
+		clock:     clk,
+		lastRunAt: clk.Now(),
+	}
+}
+
+// run checks the vote account now, recording the outcome - an inconclusive check is logged, leaves the last
+// outcome as it was and is returned as the error
+func (c *voteAccountChecker) run() (string, error) {
+	mismatch, detail, err := c.check()
+	if err != nil {
+		c.logger.Warn("unable to check vote account identity - keeping the last outcome", "error", err)
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if mismatch && !c.mismatch {
+		c.logger.Error("vote account identity mismatch - becoming active would not vote, check validator.identities.active and validator.vote_account", "detail", detail)
+	} else if !mismatch && c.mismatch {
+		c.logger.Info("vote account identity mismatch resolved", "detail", detail)
+	}
+	c.mismatch = mismatch
+	if mismatch {
+		return "", fmt.Errorf("%s - the active identity would not vote", detail)
+	}
+	return detail, nil
+}
+
+// observe checks the vote account in the background if the interval has elapsed since the last check and the
+// previous one has finished - it never blocks the HA monitor loop
+func (c *voteAccountChecker) observe() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	now := c.clock.Now()
+	if c.running || now.Sub(c.lastRunAt) < c.interval {
+		c.mu.Unlock()
+		return
+	}
+	c.lastRunAt = now
+	c.running = true
+	c.wg.Add(1)
+	c.mu.Unlock()
+
+	go func() {
+		defer c.wg.Done()
+
+		c.run()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.running = false
+	}()
+}
+
+// mismatched returns true while the last conclusive check found the vote account's node identity is not the
+// active identity
+func (c *voteAccountChecker) mismatched() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.mismatch
+}
+
+// nextRunAt returns when the vote account is next checked in the background
+func (c *voteAccountChecker) nextRunAt() clock.Instant {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lastRunAt.Add(c.interval)
+}
+
+// wait blocks until any in-flight check has finished
+func (c *voteAccountChecker) wait() {
+	if c == nil {
+		return
+	}
+	c.wg.Wait()
+}
+
+// checkVoteAccount returns whether validator.vote_account's node identity in getVoteAccounts is not the active
+// identity, a vote account missing from it counting as a mismatch - an error means the check was inconclusive
+func (m *Manager) checkVoteAccount() (mismatch bool, detail string, err error) {
+	voteAccounts, err := m.clusterRPC.GetVoteAccounts(m.ctx)
+	if err != nil {
+		return false, "", fmt.Errorf("unable to get vote accounts: %w", err)
+	}
+
+	voteAccount := m.cfg.Validator.VoteAccount
+	activePubkey := m.cfg.Validator.Identities.ActiveKeyPair.PublicKey()
+	result, found := findVoteAccount(voteAccounts, voteAccount)
+	if !found {
+		return true, fmt.Sprintf("vote account %s not found in getVoteAccounts", voteAccount), nil
+	}
+	if !result.NodePubkey.Equals(activePubkey) {
+		return true, fmt.Sprintf("vote account %s node identity is %s, not the active identity %s", voteAccount, result.NodePubkey, activePubkey), nil
+	}
+	return false, fmt.Sprintf("vote account %s node identity is the active identity %s", voteAccount, activePubkey), nil
+}
+
+// findVoteAccount returns the current or delinquent vote account with votePubkey
+func findVoteAccount(voteAccounts *solanagorpc.GetVoteAccountsResult, votePubkey string) (solanagorpc.VoteAccountsResult, bool) {
+	for _, result := range append(voteAccounts.Current, voteAccounts.Delinquent...) {
+		if result.VotePubkey.String() == votePubkey {
+			return result, true
+		}
+	}
+	return solanagorpc.VoteAccountsResult{}, false
+}
+
+// checkVoteAccountGate passes while validator.vote_account's node identity is the active identity - an
+// inconclusive check fails it too
+func (m *Manager) checkVoteAccountGate() (string, error) {
+	return m.voteAccount.run()
+}
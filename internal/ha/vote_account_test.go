@@ -0,0 +1,174 @@
+package ha
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/events"
+	"github.com/sol-strategies/solana-validator-ha/internal/gates"
+	"github.com/sol-strategies/solana-validator-ha/internal/testutil"
+)
+
+// newVoteAccountManager returns a manager checking a new vote account every minute on a fake clock, the vote
+// account's node identity in getVoteAccounts being the active identity
+func newVoteAccountManager(t *testing.T, cfg *config.Config) (*Manager, *testutil.FakeRPC, *clock.Fake) {
+	t.Helper()
+
+	cfg.Validator.VoteAccount = solana.NewWallet().PublicKey().String()
+	cfg.Validator.VoteAccountCheckInterval = time.Minute
+	cfg.Gates.VoteAccount.Mode = gates.ModeEnforce
+
+	clusterRPC := testutil.NewFakeRPC()
+	localRPC := testutil.NewFakeRPC()
+	localRPC.SetIdentity(cfg.Validator.Identities.PassiveKeyPair.PublicKey())
+	setVoteAccountNode(clusterRPC, cfg.Validator.VoteAccount, cfg.Validator.Identities.ActiveKeyPair.PublicKey(), false)
+
+	fake := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	manager := NewManager(NewManagerOptions{
+		Cfg:             cfg,
+		GetPublicIPFunc: mockPublicIPFunc,
+		ClusterRPC:      clusterRPC,
+		LocalRPC:        localRPC,
+		Clock:           fake,
+	})
+	require.NoError(t, manager.initialize())
+
+	return manager, clusterRPC, fake
+}
+
+// setVoteAccountNode sets getVoteAccounts to return only voteAccount with nodePubkey, current or delinquent
+func setVoteAccountNode(clusterRPC *testutil.FakeRPC, voteAccount string, nodePubkey solana.PublicKey, delinquent bool) {
+	result := []solanagorpc.VoteAccountsResult{{VotePubkey: solana.MustPublicKeyFromBase58(voteAccount), NodePubkey: nodePubkey}}
+	if delinquent {
+		clusterRPC.SetVoteAccounts(nil, result)
+		return
+	}
+	clusterRPC.SetVoteAccounts(result, nil)
+}
+
+func TestNewVoteAccountChecker(t *testing.T) {
+	// nothing is checked without validator.vote_account
+	checker := newVoteAccountChecker(config.Validator{VoteAccountCheckInterval: time.Minute}, nil, nil, clock.System)
+	assert.Nil(t, checker)
+
+	// a nil checker is safe to use
+	checker.observe()
+	checker.wait()
+	assert.False(t, checker.mismatched())
+}
+
+func TestManager_CheckVoteAccount(t *testing.T) {
+	manager, clusterRPC, _ := newVoteAccountManager(t, createTestConfig())
+	voteAccount := manager.cfg.Validator.VoteAccount
+	activePubkey := manager.cfg.Validator.Identities.ActiveKeyPair.PublicKey()
+
+	mismatch, detail, err := manager.checkVoteAccount()
+	require.NoError(t, err)
+	assert.False(t, mismatch)
+	assert.Equal(t, "vote account "+voteAccount+" node identity is the active identity "+activePubkey.String(), detail)
+
+	// a delinquent vote account is still found
+	setVoteAccountNode(clusterRPC, voteAccount, activePubkey, true)
+	mismatch, _, err = manager.checkVoteAccount()
+	require.NoError(t, err)
+	assert.False(t, mismatch)
+
+	other := solana.NewWallet().PublicKey()
+	setVoteAccountNode(clusterRPC, voteAccount, other, false)
+	mismatch, detail, err = manager.checkVoteAccount()
+	require.NoError(t, err)
+	assert.True(t, mismatch)
+	assert.Equal(t, "vote account "+voteAccount+" node identity is "+other.String()+", not the active identity "+activePubkey.String(), detail)
+
+	clusterRPC.SetVoteAccounts(nil, nil)
+	mismatch, detail, err = manager.checkVoteAccount()
+	require.NoError(t, err)
+	assert.True(t, mismatch)
+	assert.Equal(t, "vote account "+voteAccount+" not found in getVoteAccounts", detail)
+
+	clusterRPC.SetError("getVoteAccounts", assert.AnError)
+	_, _, err = manager.checkVoteAccount()
+	assert.ErrorContains(t, err, "unable to get vote accounts")
+}
+
+func TestManager_VoteAccountMismatch_Unhealthy(t *testing.T) {
+	manager, clusterRPC, fake := newVoteAccountManager(t, createTestConfig())
+	voteAccount := manager.cfg.Validator.VoteAccount
+	setVoteAccountNode(clusterRPC, voteAccount, solana.NewWallet().PublicKey(), false)
+
+	// not checked again before the interval has elapsed
+	manager.voteAccount.observe()
+	manager.voteAccount.wait()
+	assert.False(t, manager.voteAccount.mismatched())
+
+	fake.Advance(time.Minute)
+	manager.voteAccount.observe()
+	manager.voteAccount.wait()
+	assert.True(t, manager.voteAccount.mismatched())
+
+	manager.refreshMetrics()
+	state := manager.cache.GetState()
+	assert.Equal(t, constants.StatusUnhealthy, state.Status)
+	assert.True(t, state.VoteAccountMismatch)
+
+	// an inconclusive check keeps the mismatch
+	clusterRPC.SetError("getVoteAccounts", assert.AnError)
+	fake.Advance(time.Minute)
+	manager.voteAccount.observe()
+	manager.voteAccount.wait()
+	assert.True(t, manager.voteAccount.mismatched())
+
+	// resolved once the vote account's node identity is the active identity
+	clusterRPC.SetError("getVoteAccounts", nil)
+	setVoteAccountNode(clusterRPC, voteAccount, manager.cfg.Validator.Identities.ActiveKeyPair.PublicKey(), false)
+	fake.Advance(time.Minute)
+	manager.voteAccount.observe()
+	manager.voteAccount.wait()
+	assert.False(t, manager.voteAccount.mismatched())
+
+	manager.refreshMetrics()
+	assert.False(t, manager.cache.GetState().VoteAccountMismatch)
+}
+
+func TestManager_ConfiguredGates_VoteAccount(t *testing.T) {
+	manager, _, _ := newVoteAccountManager(t, createTestConfig())
+
+	names := []string{}
+	for _, gate := range manager.configuredGates() {
+		names = append(names, gate.Name)
+	}
+	assert.Equal(t, []string{config.GateHealth, config.GateSlotLag, config.GateDiskSpace, config.GateVoteAccount}, names)
+}
+
+func TestManager_EnsureActive_VoteAccountMismatchBlocks(t *testing.T) {
+	manager, clusterRPC, _ := newVoteAccountManager(t, createLiveTestConfig())
+	setVoteAccountNode(clusterRPC, manager.cfg.Validator.VoteAccount, solana.NewWallet().PublicKey(), false)
+
+	manager.ensureActive(DecisionReasonNoActivePeer)
+
+	assert.Equal(t, []string{events.TypeBecomingActive, events.TypeTransitionFailed}, recordedTypes(manager))
+	assert.Equal(t, constants.FailoverStatusBlocked, manager.cache.GetState().FailoverStatus)
+	assert.Equal(t, 1.0, gateViolations(t, manager, config.GateVoteAccount, gates.ModeEnforce))
+	assert.True(t, manager.voteAccount.mismatched())
+}
+
+func TestManager_CheckGates_VoteAccountWarnOverride(t *testing.T) {
+	cfg := createTestConfig()
+	manager, clusterRPC, _ := newVoteAccountManager(t, cfg)
+	cfg.Gates.VoteAccount.Mode = gates.ModeWarn
+
+	// an inconclusive check fails the gate too
+	clusterRPC.SetError("getVoteAccounts", assert.AnError)
+
+	evaluation := manager.checkGates()
+	assert.False(t, evaluation.Blocked)
+	assert.Equal(t, 1.0, gateViolations(t, manager, config.GateVoteAccount, gates.ModeWarn))
+}
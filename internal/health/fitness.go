@@ -0,0 +1,136 @@
+package health
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// MaxScore is the score of a node with nothing counting against it
+	MaxScore = 100
+
+	// ComponentSlotLag is the penalty for the local validator lagging the cluster slot
+	ComponentSlotLag = "slot_lag"
+	// ComponentDiskFree is the penalty for low free disk space on the configured path
+	ComponentDiskFree = "disk_free"
+	// ComponentRecentRestarts is the penalty for agent restarts within the restart window
+	ComponentRecentRestarts = "recent_restarts"
+
+	// slotLagMaxPenalty is the penalty for lagging by max slot lag or more, or an unknown lag
+	slotLagMaxPenalty = 50
+	// diskFreeMaxPenalty is the penalty for a full disk or an unknown free space
+	diskFreeMaxPenalty = 30
+	// restartPenalty is the penalty for each recent restart
+	restartPenalty = 10
+	// recentRestartsMaxPenalty bounds the penalty for recent restarts
+	recentRestartsMaxPenalty = 20
+)
+
+// Inputs are the measurements a fitness score is computed from
+type Inputs struct {
+	// SlotLag is how many slots the local validator is behind the cluster
+	SlotLag uint64
+	// SlotLagKnown is false when the slot lag could not be measured
+	SlotLagKnown bool
+	// DiskChecked is true when a disk path is configured to be checked
+	DiskChecked bool
+	// DiskFreePercent is the free space on the configured disk path
+	DiskFreePercent float64
+	// DiskFreeKnown is false when the free space could not be measured
+	DiskFreeKnown bool
+	// RecentRestarts is the number of agent restarts within the restart window
+	RecentRestarts int
+}
+
+// Thresholds bound the inputs a penalty is applied for
+type Thresholds struct {
+	// MaxSlotLag is the slot lag at which the full slot lag penalty applies
+	MaxSlotLag uint64
+	// MinDiskFreePercent is the free space below which a disk penalty applies
+	MinDiskFreePercent float64
+}
+
+// Component is a single input's contribution to a fitness score
+type Component struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Penalty int    `json:"penalty"`
+}
+
+// Fitness is a node's dynamic fitness to take over as active, 0-100 with higher being fitter
+type Fitness struct {
+	Score      int         `json:"score"`
+	Components []Component `json:"components"`
+}
+
+// String returns the score and its components for logging
+func (f Fitness) String() string {
+	s := strconv.Itoa(f.Score)
+	for _, component := range f.Components {
+		s += fmt.Sprintf(" %s=%s(-%d)", component.Name, component.Value, component.Penalty)
+	}
+	return s
+}
+
+// Score computes the fitness score for the inputs - each component subtracts a bounded penalty from MaxScore
+func Score(inputs Inputs, thresholds Thresholds) Fitness {
+	fitness := Fitness{
+		Components: []Component{
+			slotLagComponent(inputs, thresholds),
+		},
+	}
+	if inputs.DiskChecked {
+		fitness.Components = append(fitness.Components, diskFreeComponent(inputs, thresholds))
+	}
+	fitness.Components = append(fitness.Components, recentRestartsComponent(inputs))
+
+	fitness.Score = MaxScore
+	for _, component := range fitness.Components {
+		fitness.Score -= component.Penalty
+	}
+	if fitness.Score < 0 {
+		fitness.Score = 0
+	}
+
+	return fitness
+}
+
+// slotLagComponent scales the penalty linearly up to the max slot lag - an unknown lag can't be vouched for
+func slotLagComponent(inputs Inputs, thresholds Thresholds) Component {
+	component := Component{Name: ComponentSlotLag, Value: "unknown", Penalty: slotLagMaxPenalty}
+	if !inputs.SlotLagKnown {
+		return component
+	}
+
+	component.Value = strconv.FormatUint(inputs.SlotLag, 10)
+	if thresholds.MaxSlotLag == 0 || inputs.SlotLag >= thresholds.MaxSlotLag {
+		return component
+	}
+	component.Penalty = int(inputs.SlotLag * slotLagMaxPenalty / thresholds.MaxSlotLag)
+	return component
+}
+
+// diskFreeComponent scales the penalty linearly from the min free percent down to a full disk
+func diskFreeComponent(inputs Inputs, thresholds Thresholds) Component {
+	component := Component{Name: ComponentDiskFree, Value: "unknown", Penalty: diskFreeMaxPenalty}
+	if !inputs.DiskFreeKnown {
+		return component
+	}
+
+	component.Value = strconv.FormatFloat(inputs.DiskFreePercent, 'f', 1, 64) + "%"
+	component.Penalty = 0
+	if inputs.DiskFreePercent < thresholds.MinDiskFreePercent {
+		shortfall := (thresholds.MinDiskFreePercent - inputs.DiskFreePercent) / thresholds.MinDiskFreePercent
+		component.Penalty = int(shortfall * diskFreeMaxPenalty)
+	}
+	return component
+}
+
+// recentRestartsComponent penalizes each recent restart - a flapping agent shouldn't be first to claim
+func recentRestartsComponent(inputs Inputs) Component {
+	penalty := inputs.RecentRestarts * restartPenalty
+	if penalty > recentRestartsMaxPenalty {
+		penalty = recentRestartsMaxPenalty
+	}
+	return Component{Name: ComponentRecentRestarts, Value: strconv.Itoa(inputs.RecentRestarts), Penalty: penalty}
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScore(t *testing.T) {
+	thresholds := Thresholds{MaxSlotLag: 100, MinDiskFreePercent: 10}
+
+	tests := []struct {
+		name      string
+		inputs    Inputs
+		score     int
+		penalties map[string]int
+	}{
+		{
+			name:      "healthy",
+			inputs:    Inputs{SlotLagKnown: true},
+			score:     100,
+			penalties: map[string]int{ComponentSlotLag: 0, ComponentRecentRestarts: 0},
+		},
+		{
+			name:      "slot lag scales linearly",
+			inputs:    Inputs{SlotLag: 50, SlotLagKnown: true},
+			score:     75,
+			penalties: map[string]int{ComponentSlotLag: 25, ComponentRecentRestarts: 0},
+		},
+		{
+			name:      "slot lag at max takes full penalty",
+			inputs:    Inputs{SlotLag: 100, SlotLagKnown: true},
+			score:     50,
+			penalties: map[string]int{ComponentSlotLag: 50, ComponentRecentRestarts: 0},
+		},
+		{
+			name:      "slot lag beyond max is capped",
+			inputs:    Inputs{SlotLag: 5000, SlotLagKnown: true},
+			score:     50,
+			penalties: map[string]int{ComponentSlotLag: 50, ComponentRecentRestarts: 0},
+		},
+		{
+			name:      "unknown slot lag takes full penalty",
+			inputs:    Inputs{},
+			score:     50,
+			penalties: map[string]int{ComponentSlotLag: 50, ComponentRecentRestarts: 0},
+		},
+		{
+			name:      "disk above min free has no penalty",
+			inputs:    Inputs{SlotLagKnown: true, DiskChecked: true, DiskFreePercent: 40, DiskFreeKnown: true},
+			score:     100,
+			penalties: map[string]int{ComponentSlotLag: 0, ComponentDiskFree: 0, ComponentRecentRestarts: 0},
+		},
+		{
+			name:      "disk below min free scales to full",
+			inputs:    Inputs{SlotLagKnown: true, DiskChecked: true, DiskFreePercent: 5, DiskFreeKnown: true},
+			score:     85,
+			penalties: map[string]int{ComponentSlotLag: 0, ComponentDiskFree: 15, ComponentRecentRestarts: 0},
+		},
+		{
+			name:      "unknown disk free takes full penalty",
+			inputs:    Inputs{SlotLagKnown: true, DiskChecked: true},
+			score:     70,
+			penalties: map[string]int{ComponentSlotLag: 0, ComponentDiskFree: 30, ComponentRecentRestarts: 0},
+		},
+		{
+			name:      "recent restarts",
+			inputs:    Inputs{SlotLagKnown: true, RecentRestarts: 1},
+			score:     90,
+			penalties: map[string]int{ComponentSlotLag: 0, ComponentRecentRestarts: 10},
+		},
+		{
+			name:      "recent restarts are capped",
+			inputs:    Inputs{SlotLagKnown: true, RecentRestarts: 7},
+			score:     80,
+			penalties: map[string]int{ComponentSlotLag: 0, ComponentRecentRestarts: 20},
+		},
+		{
+			name:      "everything wrong",
+			inputs:    Inputs{DiskChecked: true, RecentRestarts: 3},
+			score:     0,
+			penalties: map[string]int{ComponentSlotLag: 50, ComponentDiskFree: 30, ComponentRecentRestarts: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fitness := Score(tt.inputs, thresholds)
+			assert.Equal(t, tt.score, fitness.Score)
+
+			penalties := map[string]int{}
+			for _, component := range fitness.Components {
+				penalties[component.Name] = component.Penalty
+			}
+			assert.Equal(t, tt.penalties, penalties)
+		})
+	}
+}
+
+func TestFitness_String(t *testing.T) {
+	fitness := Score(Inputs{SlotLag: 20, SlotLagKnown: true, RecentRestarts: 1}, Thresholds{MaxSlotLag: 100})
+	assert.Equal(t, "80 slot_lag=20(-10) recent_restarts=1(-10)", fitness.String())
+}
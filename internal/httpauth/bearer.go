@@ -0,0 +1,44 @@
+package httpauth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// bearerPrefix prefixes the token in an Authorization header
+const bearerPrefix = "Bearer "
+
+// RequireBearerToken returns next wrapped to answer 401 Unauthorized to requests without an Authorization: Bearer
+// header carrying token, or next itself when token is empty and authentication is off
+func RequireBearerToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasBearerToken(r, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="solana-validator-ha"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SetBearerToken sets the Authorization header of req to token, if set
+func SetBearerToken(req *http.Request, token string) {
+	if token != "" {
+		req.Header.Set("Authorization", bearerPrefix+token)
+	}
+}
+
+// hasBearerToken returns true if r's Authorization header carries token, compared in constant time. The scheme
+// is case insensitive.
+func hasBearerToken(r *http.Request, token string) bool {
+	header := r.Header.Get("Authorization")
+	if len(header) < len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(header[len(bearerPrefix):]), []byte(token)) == 1
+}
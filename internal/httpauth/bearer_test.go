@@ -0,0 +1,55 @@
+package httpauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+func serve(handler http.Handler, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRequireBearerToken(t *testing.T) {
+	handler := RequireBearerToken("s3cr3t", okHandler)
+
+	for _, authorization := range []string{"", "Bearer", "Bearer ", "Bearer wrong", "Bearer s3cr3t2", "Basic s3cr3t", "s3cr3t"} {
+		recorder := serve(handler, authorization)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, authorization)
+		assert.Equal(t, `Bearer realm="solana-validator-ha"`, recorder.Header().Get("WWW-Authenticate"))
+		assert.NotContains(t, recorder.Body.String(), "s3cr3t")
+	}
+
+	for _, authorization := range []string{"Bearer s3cr3t", "bearer s3cr3t"} {
+		recorder := serve(handler, authorization)
+		assert.Equal(t, http.StatusOK, recorder.Code, authorization)
+		assert.Equal(t, "ok", recorder.Body.String())
+	}
+}
+
+func TestRequireBearerToken_NoToken(t *testing.T) {
+	recorder := serve(RequireBearerToken("", okHandler), "")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestSetBearerToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	SetBearerToken(req, "")
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	SetBearerToken(req, "s3cr3t")
+	assert.Equal(t, "Bearer s3cr3t", req.Header.Get("Authorization"))
+	assert.Equal(t, http.StatusOK, serve(RequireBearerToken("s3cr3t", okHandler), req.Header.Get("Authorization")).Code)
+}
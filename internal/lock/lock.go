@@ -0,0 +1,141 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ExitCodeLocked is the exit code used when another instance holds the lock (EX_TEMPFAIL from sysexits.h) so
+// supervisors can tell it apart from other failures, e.g. systemd RestartPreventExitStatus=75
+const ExitCodeLocked = 75
+
+// HeldError is returned by Acquire when another process holds the lock
+type HeldError struct {
+	// Path is the lock file path
+	Path string
+	// PID is the pid recorded in the lock file by the holder, 0 if it could not be read
+	PID int
+}
+
+// Error implements error
+func (e *HeldError) Error() string {
+	holder := "unknown pid"
+	if e.PID > 0 {
+		holder = fmt.Sprintf("pid %d", e.PID)
+	}
+	return fmt.Sprintf("lock file %s is held by another solana-validator-ha instance (%s) - is a second copy of the agent running on this host?", e.Path, holder)
+}
+
+// ExitCode returns the process exit code for this error
+func (e *HeldError) ExitCode() int {
+	return ExitCodeLocked
+}
+
+// Lock is an exclusive advisory flock on a file, held for the life of the process. The kernel releases
+// flocks when the holder exits so a lock file left behind by a crash never blocks the next start.
+type Lock struct {
+	path string
+	file *os.File
+	// stalePID is the pid a previous holder left in the lock file when it exited without releasing it
+	stalePID int
+}
+
+// Acquire takes an exclusive non-blocking flock on path, creating it if needed, and records our pid in it
+func Acquire(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create lock file directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, &HeldError{Path: path, PID: readPID(file)}
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// a pid left behind is a holder that crashed, released locks are emptied
+	stalePID := readPID(file)
+
+	// record our pid so a second instance can say who holds the lock
+	if err := file.Truncate(0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to truncate lock file %s: %w", path, err)
+	}
+	if _, err := file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write pid to lock file %s: %w", path, err)
+	}
+
+	return &Lock{path: path, file: file, stalePID: stalePID}, nil
+}
+
+// Path returns the lock file path
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// StalePID returns the pid of a previous holder that exited without releasing the lock, reclaimed by Acquire - 0 if
+// the lock was released cleanly or never held
+func (l *Lock) StalePID() int {
+	return l.stalePID
+}
+
+// Check verifies the lock is still held - the handle is open and the file at path is still the one we locked.
+// If the file was deleted or replaced another instance could lock the new one, so callers must not transition.
+func (l *Lock) Check() error {
+	if l == nil {
+		return nil
+	}
+	if l.file == nil {
+		return fmt.Errorf("lock file %s is no longer held - it was released", l.path)
+	}
+
+	held, err := l.file.Stat()
+	if err != nil {
+		return fmt.Errorf("lock file %s is no longer held - failed to stat handle: %w", l.path, err)
+	}
+	current, err := os.Stat(l.path)
+	if err != nil {
+		return fmt.Errorf("lock file %s is no longer held - it was removed: %w", l.path, err)
+	}
+	if !os.SameFile(held, current) {
+		return fmt.Errorf("lock file %s is no longer held - it was replaced by another file", l.path)
+	}
+
+	return nil
+}
+
+// Release releases the lock - the file is emptied of our pid but left in place as removing it would race with
+// another instance acquiring it
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	l.file.Truncate(0)
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// readPID reads the holder's pid from the lock file, 0 if it can't be parsed
+func readPID(file *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
@@ -0,0 +1,110 @@
+package lock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "config.yaml.lock")
+
+	l, err := Acquire(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, l.Path())
+	assert.NoError(t, l.Check())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%d", os.Getpid()), strings.TrimSpace(string(content)))
+
+	assert.Zero(t, l.StalePID())
+
+	require.NoError(t, l.Release())
+	assert.Error(t, l.Check())
+
+	// released lock files are left in place, emptied, and can be acquired again without being stale
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, content)
+	l, err = Acquire(path)
+	require.NoError(t, err)
+	assert.Zero(t, l.StalePID())
+	require.NoError(t, l.Release())
+}
+
+func TestAcquire_HeldByAnotherInstance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml.lock")
+
+	first, err := Acquire(path)
+	require.NoError(t, err)
+	defer first.Release()
+
+	// flocks are per open file so a second open in this process contends just like a second process would
+	second, err := Acquire(path)
+	assert.Nil(t, second)
+	require.Error(t, err)
+
+	var heldErr *HeldError
+	require.ErrorAs(t, err, &heldErr)
+	assert.Equal(t, path, heldErr.Path)
+	assert.Equal(t, os.Getpid(), heldErr.PID)
+	assert.Equal(t, ExitCodeLocked, heldErr.ExitCode())
+	assert.Contains(t, err.Error(), fmt.Sprintf("is held by another solana-validator-ha instance (pid %d)", os.Getpid()))
+
+	// the holder's pid is not clobbered by the failed attempt
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%d", os.Getpid()), strings.TrimSpace(string(content)))
+}
+
+func TestAcquire_StaleLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml.lock")
+
+	// a lock file left behind by a crashed process holds no flock
+	require.NoError(t, os.WriteFile(path, []byte("999999\n"), 0o640))
+
+	l, err := Acquire(path)
+	require.NoError(t, err)
+	defer l.Release()
+	assert.Equal(t, 999999, l.StalePID())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%d", os.Getpid()), strings.TrimSpace(string(content)))
+}
+
+func TestHeldError_UnknownPID(t *testing.T) {
+	err := &HeldError{Path: "/tmp/x.lock"}
+	assert.Contains(t, err.Error(), "(unknown pid)")
+}
+
+func TestLock_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml.lock")
+
+	l, err := Acquire(path)
+	require.NoError(t, err)
+	defer l.Release()
+
+	// removed
+	require.NoError(t, os.Remove(path))
+	err = l.Check()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "it was removed")
+
+	// replaced - another instance could now hold a lock on the new file
+	require.NoError(t, os.WriteFile(path, []byte("1\n"), 0o640))
+	err = l.Check()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "it was replaced by another file")
+
+	// a nil lock (locking disabled) always passes
+	var disabled *Lock
+	assert.NoError(t, disabled.Check())
+	assert.NoError(t, disabled.Release())
+}
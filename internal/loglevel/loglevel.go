@@ -0,0 +1,124 @@
+// Package loglevel sets the level of each module's loggers on top of the global log level, so one module can log
+// at debug while the rest stay at info
+package loglevel
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"weak"
+
+	"github.com/charmbracelet/log"
+)
+
+// The modules log.levels is keyed by, each the component in its loggers' prefix. They are configuration so must
+// never be renamed.
+const (
+	// HAManager is the [<name> ha_manager] logger
+	HAManager = "ha_manager"
+	// GossipState is the [<name> gossip_state] logger
+	GossipState = "gossip_state"
+	// RPC is the [<name> rpc_client] loggers of every rpc client
+	RPC = "rpc"
+	// Hooks is the [<name> pre-hook <hook>], [<name> post-hook <hook>] and [<name> sample_hooks] loggers
+	Hooks = "hooks"
+	// Metrics is the metrics logger
+	Metrics = "metrics"
+	// Command is the [<name> command <role>] and [<name> admin-rpc <role>] loggers of the role commands
+	Command = "command"
+	// Events is the [<name> events] logger
+	Events = "events"
+	// History is the [<name> history] logger
+	History = "history"
+	// PublicIP is the [<name> public_ip] logger
+	PublicIP = "public_ip"
+	// TLS is the [<name> tls] loggers reloading certificates
+	TLS = "tls"
+	// Notify is the [<name> pagerduty] logger
+	Notify = "notify"
+	// Config is the config logger
+	Config = "config"
+)
+
+// Modules are the modules a level can be set for, sorted
+var Modules = []string{Command, Config, Events, GossipState, HAManager, History, Hooks, Metrics, Notify, PublicIP, RPC, TLS}
+
+// registry tracks the loggers created for each module so a level changed at runtime reaches them
+var registry = struct {
+	mu      sync.Mutex
+	levels  map[string]log.Level
+	loggers map[string][]weak.Pointer[log.Logger]
+}{
+	levels:  map[string]log.Level{},
+	loggers: map[string][]weak.Pointer[log.Logger]{},
+}
+
+// WithPrefix returns a logger with prefix like log.WithPrefix logging at module's level, kept up to date by Set
+func WithPrefix(module string, prefix string) *log.Logger {
+	logger := log.WithPrefix(prefix)
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	logger.SetLevel(levelLocked(module))
+	// drop the loggers gone since, loggers created per hook or command run would otherwise pile up
+	loggers := slices.DeleteFunc(registry.loggers[module], func(p weak.Pointer[log.Logger]) bool { return p.Value() == nil })
+	registry.loggers[module] = append(loggers, weak.Make(logger))
+	return logger
+}
+
+// Set sets the global level and the levels of the modules in levels, every other module logging at the global level
+func Set(global log.Level, levels map[string]log.Level) error {
+	for module := range levels {
+		if !slices.Contains(Modules, module) {
+			return fmt.Errorf("unknown module %s - must be one of %v", module, Modules)
+		}
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	log.SetLevel(global)
+	registry.levels = maps.Clone(levels)
+	if registry.levels == nil {
+		registry.levels = map[string]log.Level{}
+	}
+	for module, loggers := range registry.loggers {
+		level := levelLocked(module)
+		for _, p := range loggers {
+			if logger := p.Value(); logger != nil {
+				logger.SetLevel(level)
+			}
+		}
+	}
+	return nil
+}
+
+// Levels returns the global level and the level of every module
+func Levels() (global log.Level, levels map[string]log.Level) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	levels = make(map[string]log.Level, len(Modules))
+	for _, module := range Modules {
+		levels[module] = levelLocked(module)
+	}
+	return log.GetLevel(), levels
+}
+
+// Overrides returns the levels set for modules, the rest log at the global level
+func Overrides() map[string]log.Level {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	return maps.Clone(registry.levels)
+}
+
+// levelLocked returns module's level, the global level if none is set - caller must hold the lock
+func levelLocked(module string) log.Level {
+	if level, ok := registry.levels[module]; ok {
+		return level
+	}
+	return log.GetLevel()
+}
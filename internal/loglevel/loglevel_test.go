@@ -0,0 +1,56 @@
+package loglevel
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	t.Cleanup(func() { require.NoError(t, Set(log.InfoLevel, nil)) })
+	require.NoError(t, Set(log.InfoLevel, nil))
+
+	manager := WithPrefix(HAManager, "[primary ha_manager]")
+	gossip := WithPrefix(GossipState, "[primary gossip_state]")
+	assert.Equal(t, log.InfoLevel, manager.GetLevel())
+	assert.Equal(t, log.InfoLevel, gossip.GetLevel())
+
+	// loggers created before a level is set follow it, the rest keep the global level
+	require.NoError(t, Set(log.InfoLevel, map[string]log.Level{GossipState: log.DebugLevel}))
+	assert.Equal(t, log.InfoLevel, manager.GetLevel())
+	assert.Equal(t, log.DebugLevel, gossip.GetLevel())
+	assert.Equal(t, log.DebugLevel, WithPrefix(GossipState, "[primary gossip_state]").GetLevel())
+
+	// as do modules without a level when the global level changes
+	require.NoError(t, Set(log.WarnLevel, map[string]log.Level{GossipState: log.DebugLevel}))
+	assert.Equal(t, log.WarnLevel, manager.GetLevel())
+	assert.Equal(t, log.WarnLevel, log.GetLevel())
+
+	global, levels := Levels()
+	assert.Equal(t, log.WarnLevel, global)
+	assert.Len(t, levels, len(Modules))
+	assert.Equal(t, log.DebugLevel, levels[GossipState])
+	assert.Equal(t, log.WarnLevel, levels[RPC])
+	assert.Equal(t, map[string]log.Level{GossipState: log.DebugLevel}, Overrides())
+}
+
+func TestSet_UnknownModule(t *testing.T) {
+	t.Cleanup(func() { require.NoError(t, Set(log.InfoLevel, nil)) })
+
+	assert.ErrorContains(t, Set(log.InfoLevel, map[string]log.Level{"gossip": log.DebugLevel}), "unknown module gossip")
+}
+
+func TestWithPrefix_DropsCollectedLoggers(t *testing.T) {
+	for range 100 {
+		WithPrefix(Command, "[primary command active]")
+	}
+	runtime.GC()
+	WithPrefix(Command, "[primary command active]")
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	assert.Less(t, len(registry.loggers[Command]), 100)
+}
@@ -0,0 +1,152 @@
+package logthrottle
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+)
+
+const (
+	// DefaultBurst is the default number of identical warnings logged at full rate before they are suppressed
+	DefaultBurst = 5
+	// DefaultWindow is the default interval suppressed warnings are summarized at
+	DefaultWindow = 10 * time.Minute
+)
+
+// Throttle rate-limits repeated identical warnings so a condition that lasts for hours, a peer down for a weekend or
+// an unreachable rpc, doesn't drown out everything else. The first Burst occurrences of a key are logged as they
+// happen, later ones are counted and summarized once per Window. Clearing a key once its condition clears logs the
+// recovery if any were suppressed, and the next occurrence is logged at full rate again.
+type Throttle struct {
+	clock  clock.Clock
+	burst  int
+	window time.Duration
+
+	mu   sync.Mutex
+	keys map[string]*occurrences
+}
+
+// occurrences is the count of a key's warnings since its condition began
+type occurrences struct {
+	msg   string
+	count int
+	// firstAt is when the condition began
+	firstAt clock.Instant
+	// suppressed is how many were not logged since summarizedAt, summarizedAt when suppression began or the last
+	// summary was logged
+	suppressed   int
+	summarizedAt clock.Instant
+	// everSuppressed is true once any occurrence was suppressed
+	everSuppressed bool
+}
+
+// Options are the options for a throttle
+type Options struct {
+	// Clock defaults to clock.System
+	Clock clock.Clock
+	// Burst defaults to DefaultBurst
+	Burst int
+	// Window defaults to DefaultWindow
+	Window time.Duration
+}
+
+// New creates a throttle
+func New(opts Options) *Throttle {
+	if opts.Clock == nil {
+		opts.Clock = clock.System
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = DefaultBurst
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+
+	return &Throttle{
+		clock:  opts.Clock,
+		burst:  opts.Burst,
+		window: opts.Window,
+		keys:   make(map[string]*occurrences),
+	}
+}
+
+// Warn logs msg to logger at warn level unless key is being suppressed
+func (t *Throttle) Warn(logger *log.Logger, key string, msg string, keyvals ...any) {
+	if msg, ok := t.occur(key, msg); ok {
+		logger.Warn(msg, keyvals...)
+	}
+}
+
+// Error logs msg to logger at error level unless key is being suppressed
+func (t *Throttle) Error(logger *log.Logger, key string, msg string, keyvals ...any) {
+	if msg, ok := t.occur(key, msg); ok {
+		logger.Error(msg, keyvals...)
+	}
+}
+
+// Clear resets key once its condition has cleared, logging the recovery to logger if any of its warnings were
+// suppressed
+func (t *Throttle) Clear(logger *log.Logger, key string) {
+	t.mu.Lock()
+	o, ok := t.keys[key]
+	delete(t.keys, key)
+	t.mu.Unlock()
+
+	if !ok || !o.everSuppressed {
+		return
+	}
+	logger.Info("repeated warning cleared",
+		"warning", o.msg,
+		"occurrences", o.count,
+		"duration", t.clock.Now().Sub(o.firstAt).Round(time.Second),
+	)
+}
+
+// occur counts an occurrence of key, returning the message to log and true if it is to be logged
+func (t *Throttle) occur(key string, msg string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	o, ok := t.keys[key]
+	if !ok {
+		o = &occurrences{msg: msg, firstAt: now}
+		t.keys[key] = o
+	}
+	o.count++
+	o.msg = msg
+
+	if o.count <= t.burst {
+		return msg, true
+	}
+	if !o.everSuppressed {
+		o.everSuppressed = true
+		o.summarizedAt = now
+	}
+
+	if now.Sub(o.summarizedAt) < t.window {
+		o.suppressed++
+		return "", false
+	}
+
+	suppressed := o.suppressed
+	o.suppressed = 0
+	o.summarizedAt = now
+	return fmt.Sprintf("%s - suppressed %d identical warnings in the last %s", msg, suppressed, formatWindow(t.window)), true
+}
+
+// formatWindow formats d without the zero units time.Duration's String adds, 10m rather than 10m0s
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
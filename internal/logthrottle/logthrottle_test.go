@@ -0,0 +1,70 @@
+package logthrottle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottle_SuppressesAfterBurst(t *testing.T) {
+	var logged bytes.Buffer
+	logger := log.New(&logged)
+	now := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	throttle := New(Options{Clock: now, Burst: 2, Window: 10 * time.Minute})
+
+	// full rate for the burst, then suppressed
+	for range 5 {
+		throttle.Warn(logger, "peer:a", "peer not found in gossip", "name", "a")
+		now.Advance(time.Minute)
+	}
+	assert.Equal(t, 2, strings.Count(logged.String(), "peer not found in gossip"))
+
+	// other keys are throttled on their own
+	throttle.Error(logger, "peer:b", "peer not found in gossip", "name", "b")
+	assert.Equal(t, 3, strings.Count(logged.String(), "peer not found in gossip"))
+
+	// summarized once per window
+	for range 8 {
+		throttle.Warn(logger, "peer:a", "peer not found in gossip", "name", "a")
+		now.Advance(time.Minute)
+	}
+	assert.Contains(t, logged.String(), "peer not found in gossip - suppressed 10 identical warnings in the last 10m")
+	assert.Equal(t, 4, strings.Count(logged.String(), "peer not found in gossip"))
+}
+
+func TestThrottle_Clear(t *testing.T) {
+	var logged bytes.Buffer
+	logger := log.New(&logged)
+	now := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	throttle := New(Options{Clock: now, Burst: 1})
+
+	// nothing suppressed so nothing to say
+	throttle.Warn(logger, "rpc", "failed to get cluster nodes")
+	throttle.Clear(logger, "rpc")
+	assert.NotContains(t, logged.String(), "repeated warning cleared")
+
+	// the recovery is logged once any were suppressed, and the next occurrence at full rate
+	throttle.Warn(logger, "rpc", "failed to get cluster nodes")
+	throttle.Warn(logger, "rpc", "failed to get cluster nodes")
+	now.Advance(time.Hour)
+	throttle.Clear(logger, "rpc")
+	assert.Contains(t, logged.String(), "repeated warning cleared")
+	assert.Contains(t, logged.String(), "occurrences=2")
+	assert.Contains(t, logged.String(), "duration=1h0m0s")
+
+	logged.Reset()
+	throttle.Warn(logger, "rpc", "failed to get cluster nodes")
+	assert.Contains(t, logged.String(), "failed to get cluster nodes")
+}
+
+func TestFormatWindow(t *testing.T) {
+	assert.Equal(t, "10m", formatWindow(10*time.Minute))
+	assert.Equal(t, "1h", formatWindow(time.Hour))
+	assert.Equal(t, "1h30m", formatWindow(90*time.Minute))
+	assert.Equal(t, "30s", formatWindow(30*time.Second))
+}
@@ -0,0 +1,131 @@
+package logwriter
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// errPanicked is returned by write when the sink panicked
+var errPanicked = errors.New("log sink panicked")
+
+const (
+	// DefaultQueueSize is the default number of log writes buffered while the output is slow
+	DefaultQueueSize = 1024
+	// DefaultWriteTimeout is how long a log call waits for its write before giving up on it
+	DefaultWriteTimeout = 250 * time.Millisecond
+)
+
+// Writer wraps a log sink so a failing sink can never block or crash the caller. Writes are handed
+// to a background goroutine and waited on for at most the write timeout - once the sink has stalled
+// writes are queued without waiting and dropped when the queue is full. Write never returns an error,
+// failed, dropped and panicking writes are counted instead.
+//
+// Each sink must be wrapped in its own Writer so one sink failing doesn't affect another.
+type Writer struct {
+	name    string
+	out     io.Writer
+	timeout time.Duration
+	queue   chan *entry
+
+	stalled  atomic.Bool
+	failures atomic.Uint64
+}
+
+// entry is a single queued write
+type entry struct {
+	p    []byte
+	done chan struct{}
+}
+
+// Options are the options for creating a Writer
+type Options struct {
+	// Name identifies the sink, e.g. stderr - used as a metric label
+	Name string
+	// Out is the sink being wrapped
+	Out io.Writer
+	// QueueSize is the number of writes buffered while the sink is slow, defaults to DefaultQueueSize
+	QueueSize int
+	// WriteTimeout is how long a write waits for the sink, defaults to DefaultWriteTimeout
+	WriteTimeout time.Duration
+}
+
+// New creates a Writer and starts its background goroutine
+func New(opts Options) *Writer {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = DefaultWriteTimeout
+	}
+
+	w := &Writer{
+		name:    opts.Name,
+		out:     opts.Out,
+		timeout: opts.WriteTimeout,
+		queue:   make(chan *entry, opts.QueueSize),
+	}
+	go w.run()
+	return w
+}
+
+// Name returns the sink name
+func (w *Writer) Name() string {
+	return w.name
+}
+
+// Failures returns the number of writes that failed, panicked or were dropped
+func (w *Writer) Failures() uint64 {
+	return w.failures.Load()
+}
+
+// Write implements io.Writer - it always reports success so callers never see a sink failure
+func (w *Writer) Write(p []byte) (int, error) {
+	// callers may reuse p once Write returns
+	e := &entry{p: append([]byte(nil), p...), done: make(chan struct{})}
+
+	select {
+	case w.queue <- e:
+	default:
+		w.failures.Add(1)
+		return len(p), nil
+	}
+
+	// a stalled sink is not waited on so a stuck write costs the caller one timeout, not one per log line
+	if w.stalled.Load() {
+		return len(p), nil
+	}
+
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+	select {
+	case <-e.done:
+	case <-timer.C:
+		w.stalled.Store(true)
+	}
+
+	return len(p), nil
+}
+
+// run writes queued entries to the sink for the life of the process
+func (w *Writer) run() {
+	for e := range w.queue {
+		if err := w.write(e.p); err != nil {
+			w.failures.Add(1)
+		}
+		w.stalled.Store(false)
+		close(e.done)
+	}
+}
+
+// write writes p to the sink, turning a panic into an error
+func (w *Writer) write(p []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errPanicked
+		}
+	}()
+	_, err = w.out.Write(p)
+	return err
+}
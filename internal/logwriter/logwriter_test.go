@@ -0,0 +1,116 @@
+package logwriter
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errorWriter fails every write
+type errorWriter struct{}
+
+func (errorWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+// panicWriter panics on every write
+type panicWriter struct{}
+
+func (panicWriter) Write(p []byte) (int, error) {
+	panic("write on closed pipe")
+}
+
+// blockingWriter blocks every write until unblocked is closed
+type blockingWriter struct {
+	unblocked chan struct{}
+}
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.unblocked
+	return len(p), nil
+}
+
+// syncBuffer is a bytes.Buffer safe for use from the writer goroutine
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWriter_PassesThrough(t *testing.T) {
+	out := &syncBuffer{}
+	w := New(Options{Name: "test", Out: out})
+
+	p := []byte("hello\n")
+	n, err := w.Write(p)
+	require.NoError(t, err)
+	assert.Equal(t, len(p), n)
+
+	// a reused caller buffer must not change what was written
+	copy(p, "HELLO\n")
+	_, err = w.Write([]byte("world\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello\nworld\n", out.String())
+	assert.Equal(t, uint64(0), w.Failures())
+	assert.Equal(t, "test", w.Name())
+}
+
+func TestWriter_CountsErrors(t *testing.T) {
+	w := New(Options{Name: "test", Out: errorWriter{}})
+
+	for i := 0; i < 3; i++ {
+		n, err := w.Write([]byte("line\n"))
+		require.NoError(t, err)
+		assert.Equal(t, 5, n)
+	}
+
+	assert.Equal(t, uint64(3), w.Failures())
+}
+
+func TestWriter_CountsPanics(t *testing.T) {
+	w := New(Options{Name: "test", Out: panicWriter{}})
+
+	assert.NotPanics(t, func() {
+		_, err := w.Write([]byte("line\n"))
+		require.NoError(t, err)
+	})
+	assert.Equal(t, uint64(1), w.Failures())
+}
+
+func TestWriter_BlockedSinkDoesNotBlockCaller(t *testing.T) {
+	unblocked := make(chan struct{})
+	out := blockingWriter{unblocked: unblocked}
+	w := New(Options{Name: "test", Out: out, QueueSize: 2, WriteTimeout: 20 * time.Millisecond})
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		_, err := w.Write([]byte("line\n"))
+		require.NoError(t, err)
+	}
+
+	// only the first write waits - once stalled writes are queued or dropped without waiting
+	assert.Less(t, time.Since(start), time.Second)
+	// one write is stuck in the sink, two are queued and the rest are dropped
+	assert.Equal(t, uint64(7), w.Failures())
+
+	// the sink recovering clears the stall
+	close(unblocked)
+	assert.Eventually(t, func() bool { return !w.stalled.Load() && len(w.queue) == 0 }, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,222 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
+)
+
+const (
+	// DefaultPagerDutyURL is the PagerDuty Events API v2 enqueue endpoint
+	DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+	// ActionTrigger opens, or adds to, the incident with the event's dedup key
+	ActionTrigger = "trigger"
+	// ActionResolve resolves the incident with the event's dedup key
+	ActionResolve = "resolve"
+
+	// pagerDutyQueueSize is how many events may wait to be sent before new ones are dropped
+	pagerDutyQueueSize = 16
+	// pagerDutyResponseBodyLimit bounds how much of a response is read into errors
+	pagerDutyResponseBodyLimit = 512
+	// maxPagerDutyRetryDelay caps the doubling delay between retries
+	maxPagerDutyRetryDelay = time.Minute
+	// redactedRoutingKey replaces the routing key in logged payloads
+	redactedRoutingKey = "***"
+)
+
+// PagerDutyOptions configures a PagerDuty Events API v2 client
+type PagerDutyOptions struct {
+	// URL defaults to DefaultPagerDutyURL
+	URL        string
+	RoutingKey string
+	// Severity of triggered events - critical, error, warning or info
+	Severity string
+	// Source is the affected system events are reported for, e.g. the validator name
+	Source string
+	// Timeout bounds each attempt to send an event
+	Timeout time.Duration
+	// Retries is how many more times an event is sent while PagerDuty fails or can't be reached
+	Retries int
+	// RetryDelay is the delay before the first retry, doubling for each one after
+	RetryDelay time.Duration
+	// DryRun logs the events' payloads instead of sending them
+	DryRun    bool
+	LogPrefix string
+	// OnResult, if set, is called with the action and final error of every event sent, never in dry run
+	OnResult func(action string, err error)
+}
+
+// PagerDuty sends events to the PagerDuty Events API v2 in the background, one at a time and in order
+type PagerDuty struct {
+	opts    PagerDutyOptions
+	logger  *log.Logger
+	client  *http.Client
+	queue   chan pagerDutyEvent
+	pending sync.WaitGroup
+}
+
+// pagerDutyEvent is an Events API v2 event
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+// pagerDutyPayload describes a triggered event, resolves have none
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp"`
+	Component     string            `json:"component"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// NewPagerDuty returns a PagerDuty client and starts sending the events it is given
+func NewPagerDuty(opts PagerDutyOptions) *PagerDuty {
+	if opts.URL == "" {
+		opts.URL = DefaultPagerDutyURL
+	}
+
+	p := &PagerDuty{
+		opts:   opts,
+		logger: loglevel.WithPrefix(loglevel.Notify, fmt.Sprintf("[%s pagerduty]", opts.LogPrefix)),
+		client: &http.Client{Timeout: opts.Timeout},
+		queue:  make(chan pagerDutyEvent, pagerDutyQueueSize),
+	}
+	go p.sendQueued()
+
+	return p
+}
+
+// Trigger queues a trigger event opening an incident for dedupKey with summary and details
+func (p *PagerDuty) Trigger(dedupKey string, summary string, details map[string]string) {
+	p.enqueue(pagerDutyEvent{
+		RoutingKey:  p.opts.RoutingKey,
+		EventAction: ActionTrigger,
+		DedupKey:    dedupKey,
+		Payload: &pagerDutyPayload{
+			Summary:       summary,
+			Source:        p.opts.Source,
+			Severity:      p.opts.Severity,
+			Timestamp:     time.Now().UTC().Format(time.RFC3339),
+			Component:     "solana-validator-ha",
+			CustomDetails: details,
+		},
+	})
+}
+
+// Resolve queues a resolve event for the incident of dedupKey
+func (p *PagerDuty) Resolve(dedupKey string) {
+	p.enqueue(pagerDutyEvent{
+		RoutingKey:  p.opts.RoutingKey,
+		EventAction: ActionResolve,
+		DedupKey:    dedupKey,
+	})
+}
+
+// Wait blocks until every queued event has been sent or given up on
+func (p *PagerDuty) Wait() {
+	p.pending.Wait()
+}
+
+// enqueue queues event without ever blocking the caller - a full queue drops it
+func (p *PagerDuty) enqueue(event pagerDutyEvent) {
+	p.pending.Add(1)
+	select {
+	case p.queue <- event:
+	default:
+		p.pending.Done()
+		p.logger.Error("event queue full - dropping event", "event_action", event.EventAction, "dedup_key", event.DedupKey)
+	}
+}
+
+// sendQueued sends queued events in order for the life of the client
+func (p *PagerDuty) sendQueued() {
+	for event := range p.queue {
+		p.send(event)
+		p.pending.Done()
+	}
+}
+
+// send sends event, retrying while PagerDuty fails or can't be reached, and reports the outcome
+func (p *PagerDuty) send(event pagerDutyEvent) {
+	if p.opts.DryRun {
+		logged := event
+		logged.RoutingKey = redactedRoutingKey
+		payload, _ := json.Marshal(logged)
+		p.logger.Info("would send event - dry run", "event_action", event.EventAction, "dedup_key", event.DedupKey, "payload", string(payload))
+		return
+	}
+
+	var err error
+	var retryable bool
+	for attempt := 1; attempt <= p.opts.Retries+1; attempt++ {
+		if attempt > 1 {
+			delay := p.retryDelay(attempt)
+			p.logger.Warn("failed to send event - retrying", "event_action", event.EventAction, "dedup_key", event.DedupKey, "attempt", attempt, "retry_delay", delay, "error", err)
+			time.Sleep(delay)
+		}
+
+		retryable, err = p.post(event)
+		if err == nil || !retryable {
+			break
+		}
+	}
+
+	if err != nil {
+		p.logger.Error("failed to send event", "event_action", event.EventAction, "dedup_key", event.DedupKey, "error", err)
+	} else {
+		p.logger.Info("sent event", "event_action", event.EventAction, "dedup_key", event.DedupKey)
+	}
+	if p.opts.OnResult != nil {
+		p.opts.OnResult(event.EventAction, err)
+	}
+}
+
+// post posts event once. Failures to reach PagerDuty, rate limiting and server errors are retryable, any
+// other rejection is not. Errors never include the url or routing key.
+func (p *PagerDuty) post(event pagerDutyEvent) (retryable bool, err error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	resp, err := p.client.Post(p.opts.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return true, fmt.Errorf("failed to post event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return false, nil
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, pagerDutyResponseBodyLimit))
+	retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("pagerduty returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+}
+
+// retryDelay returns the delay before the given attempt, doubling from RetryDelay up to maxPagerDutyRetryDelay
+func (p *PagerDuty) retryDelay(attempt int) time.Duration {
+	delay := p.opts.RetryDelay
+	for i := 2; i < attempt && delay < maxPagerDutyRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxPagerDutyRetryDelay)
+}
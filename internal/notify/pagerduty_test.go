@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pagerDutyServer is a fake Events API v2 endpoint recording the events it accepts, answering the first len(statuses)
+// posts with statuses
+func pagerDutyServer(t *testing.T, statuses ...int) (*httptest.Server, func() []map[string]any) {
+	t.Helper()
+
+	var mu sync.Mutex
+	accepted := []map[string]any{}
+	var posts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		if n := int(posts.Add(1)); n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			w.Write([]byte(`{"status":"error"}`))
+			return
+		}
+		mu.Lock()
+		accepted = append(accepted, event)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]map[string]any{}, accepted...)
+	}
+}
+
+// results records the OnResult calls of a client
+type results struct {
+	mu      sync.Mutex
+	actions []string
+	errs    []error
+}
+
+func (r *results) record(action string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions = append(r.actions, action)
+	r.errs = append(r.errs, err)
+}
+
+func newTestPagerDuty(url string, r *results) *PagerDuty {
+	return NewPagerDuty(PagerDutyOptions{
+		URL:        url,
+		RoutingKey: "routing-key",
+		Severity:   "critical",
+		Source:     "primary",
+		Timeout:    time.Second,
+		Retries:    2,
+		RetryDelay: 10 * time.Millisecond,
+		OnResult:   r.record,
+	})
+}
+
+func TestPagerDuty_TriggerAndResolve(t *testing.T) {
+	server, accepted := pagerDutyServer(t)
+	r := &results{}
+	p := newTestPagerDuty(server.URL, r)
+
+	p.Trigger("dedup", "primary became active", map[string]string{"reason": "no_active_peer"})
+	p.Resolve("dedup")
+	p.Wait()
+
+	events := accepted()
+	require.Len(t, events, 2)
+	assert.Equal(t, "routing-key", events[0]["routing_key"])
+	assert.Equal(t, ActionTrigger, events[0]["event_action"])
+	assert.Equal(t, "dedup", events[0]["dedup_key"])
+	payload := events[0]["payload"].(map[string]any)
+	assert.Equal(t, "primary became active", payload["summary"])
+	assert.Equal(t, "primary", payload["source"])
+	assert.Equal(t, "critical", payload["severity"])
+	assert.Equal(t, map[string]any{"reason": "no_active_peer"}, payload["custom_details"])
+	_, err := time.Parse(time.RFC3339, payload["timestamp"].(string))
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]any{"routing_key": "routing-key", "event_action": ActionResolve, "dedup_key": "dedup"}, events[1])
+	assert.Equal(t, []string{ActionTrigger, ActionResolve}, r.actions)
+	assert.Equal(t, []error{nil, nil}, r.errs)
+}
+
+func TestPagerDuty_Retries(t *testing.T) {
+	// rate limiting and server errors are retried
+	server, accepted := pagerDutyServer(t, http.StatusTooManyRequests, http.StatusBadGateway)
+	r := &results{}
+	p := newTestPagerDuty(server.URL, r)
+
+	p.Trigger("dedup", "primary became active", nil)
+	p.Wait()
+	assert.Len(t, accepted(), 1)
+	assert.Equal(t, []error{nil}, r.errs)
+
+	// retries run out
+	server, accepted = pagerDutyServer(t, 500, 500, 500)
+	r = &results{}
+	p = newTestPagerDuty(server.URL, r)
+	p.Trigger("dedup", "primary became active", nil)
+	p.Wait()
+	assert.Empty(t, accepted())
+	require.Len(t, r.errs, 1)
+	assert.EqualError(t, r.errs[0], `pagerduty returned 500 Internal Server Error: {"status":"error"}`)
+}
+
+func TestPagerDuty_BadRequestIsNotRetried(t *testing.T) {
+	server, accepted := pagerDutyServer(t, http.StatusBadRequest)
+	r := &results{}
+	p := newTestPagerDuty(server.URL, r)
+
+	p.Trigger("dedup", "primary became active", nil)
+	p.Wait()
+	assert.Empty(t, accepted())
+	require.Len(t, r.errs, 1)
+	assert.ErrorContains(t, r.errs[0], "400 Bad Request")
+}
+
+func TestPagerDuty_ErrorsNeverIncludeURL(t *testing.T) {
+	r := &results{}
+	p := NewPagerDuty(PagerDutyOptions{URL: "http://127.0.0.1:1/v2/enqueue?secret=s3cr3t", RoutingKey: "routing-key", Timeout: time.Second, OnResult: r.record})
+
+	p.Resolve("dedup")
+	p.Wait()
+	require.Len(t, r.errs, 1)
+	require.Error(t, r.errs[0])
+	assert.NotContains(t, r.errs[0].Error(), "s3cr3t")
+}
+
+func TestPagerDuty_DryRun(t *testing.T) {
+	server, accepted := pagerDutyServer(t)
+	r := &results{}
+	p := NewPagerDuty(PagerDutyOptions{URL: server.URL, RoutingKey: "routing-key", DryRun: true, OnResult: r.record})
+
+	p.Trigger("dedup", "primary became active", nil)
+	p.Resolve("dedup")
+	p.Wait()
+	assert.Empty(t, accepted())
+	assert.Empty(t, r.actions)
+}
+
+func TestPagerDuty_RetryDelay(t *testing.T) {
+	p := &PagerDuty{opts: PagerDutyOptions{RetryDelay: 20 * time.Second}}
+	assert.Equal(t, 20*time.Second, p.retryDelay(2))
+	assert.Equal(t, 40*time.Second, p.retryDelay(3))
+	assert.Equal(t, time.Minute, p.retryDelay(4))
+	assert.Equal(t, time.Minute, p.retryDelay(10))
+}
@@ -1,26 +1,64 @@
 package prometheus
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/sol-strategies/solana-validator-ha/internal/buildinfo"
 	"github.com/sol-strategies/solana-validator-ha/internal/cache"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/httpauth"
+	"github.com/sol-strategies/solana-validator-ha/internal/tlscert"
 )
 
 const (
-	metricsNamespacePrefix   = "solana_validator_ha_"
-	validatorNameLabelName   = "validator_name"
-	publicIPLabelName        = "public_ip"
-	validatorRoleLabelName   = "validator_role"
-	validatorStatusLabelName = "validator_status"
-	failoverStatusLabelName  = "status"
-	peerCountLabelName       = "peer_count"
-	selfInGossipLabelName    = "self_in_gossip"
+	metricsNamespacePrefix     = "solana_validator_ha_"
+	validatorNameLabelName     = "validator_name"
+	publicIPLabelName          = "public_ip"
+	validatorRoleLabelName     = "validator_role"
+	validatorStatusLabelName   = "validator_status"
+	failoverStatusLabelName    = "status"
+	peerCountLabelName         = "peer_count"
+	selfInGossipLabelName      = "self_in_gossip"
+	warningCodeLabelName       = "code"
+	decisionActionLabelName    = "action"
+	decisionReasonLabelName    = "reason"
+	logSinkLabelName           = "sink"
+	rollbackRoleLabelName      = "from_role"
+	rollbackResultLabelName    = "result"
+	timerLabelName             = "timer"
+	versionLabelName           = "version"
+	commitLabelName            = "commit"
+	buildDateLabelName         = "build_date"
+	goVersionLabelName         = "go_version"
+	gateLabelName              = "gate"
+	gateModeLabelName          = "mode"
+	notifierLabelName          = "notifier"
+	notifyActionLabelName      = "action"
+	notifyResultLabelName      = "result"
+	failoverDirectionLabelName = "direction"
+	failoverReasonLabelName    = "reason"
+	peerNameLabelName          = "peer_name"
+	peerIPLabelName            = "peer_ip"
+	hookNameLabelName          = "hook_name"
+	hookPhaseLabelName         = "phase"
+	roleLabelName              = "role"
+	dryRunLabelName            = "dry_run"
+	rpcMethodLabelName         = "method"
+	rpcEndpointLabelName       = "endpoint"
+	rpcRetriedLabelName        = "retried"
+	towerSyncResultLabelName   = "result"
 )
 
 var (
@@ -28,6 +66,18 @@ var (
 		validatorNameLabelName,
 		publicIPLabelName,
 	}
+
+	// failoverStatusMetricValues maps each failover status to its failover_status_code metric value,
+	// values are part of the metric contract so must never be reused or renumbered
+	failoverStatusMetricValues = map[constants.FailoverStatus]float64{
+		constants.FailoverStatusIdle:            0,
+		constants.FailoverStatusBecomingActive:  1,
+		constants.FailoverStatusBecomingPassive: 2,
+		constants.FailoverStatusFailed:          3,
+		constants.FailoverStatusBlocked:         4,
+		constants.FailoverStatusDegraded:        5,
+		constants.FailoverStatusRollbackFailed:  6,
+	}
 )
 
 // Metrics manages Prometheus metrics for the HA manager
@@ -35,15 +85,70 @@ type Metrics struct {
 	config           *config.Config
 	logger           *log.Logger
 	cache            *cache.Cache
-	server           *http.Server
 	registry         *prometheus.Registry
 	commonLabelNames []string
 
+	// serverMu guards server, which Serve sets from its own goroutine, and serverStopped, which stops a Serve that
+	// starts after the server was stopped
+	serverMu      sync.Mutex
+	server        *http.Server
+	serverStopped bool
+
 	// Metrics
-	metadata       *prometheus.GaugeVec
-	peerCount      *prometheus.GaugeVec
-	selfInGossip   *prometheus.GaugeVec
-	failoverStatus *prometheus.GaugeVec
+	metadata                   *prometheus.GaugeVec
+	peerCount                  *prometheus.GaugeVec
+	selfInGossip               *prometheus.GaugeVec
+	splitBrain                 *prometheus.GaugeVec
+	voteAccountMismatch        *prometheus.GaugeVec
+	paused                     *prometheus.GaugeVec
+	leaderlessSamples          *prometheus.GaugeVec
+	leaderlessSamplesThreshold *prometheus.GaugeVec
+	clusterRPCErrors           *prometheus.GaugeVec
+	failoverStatus             *prometheus.GaugeVec
+	failoverStatusCode         *prometheus.GaugeVec
+
+	effectivePollIntervalSeconds *prometheus.GaugeVec
+	rpcRateLimitedTotal          *prometheus.CounterVec
+	clusterRPCEndpoint           *prometheus.GaugeVec
+	rpcRequestDurationSeconds    *prometheus.HistogramVec
+	rpcErrorsTotal               *prometheus.CounterVec
+	decisionLagSeconds           *prometheus.HistogramVec
+	actionLagSeconds             *prometheus.HistogramVec
+	configWarnings               *prometheus.GaugeVec
+	wouldHaveTotal               *prometheus.CounterVec
+	logWriteFailuresTotal        *prometheus.CounterVec
+	textfileGeneratedTimestamp   *prometheus.GaugeVec
+	rollbacksTotal               *prometheus.CounterVec
+	gateViolationsTotal          *prometheus.CounterVec
+	possibleDuplicateSigning     *prometheus.CounterVec
+	publicIPChangesTotal         *prometheus.CounterVec
+	pollIterationsTotal          *prometheus.CounterVec
+	pollOverrunsTotal            *prometheus.CounterVec
+	takeoversAbortedTotal        *prometheus.CounterVec
+	notificationsTotal           *prometheus.CounterVec
+	failoversTotal               *prometheus.CounterVec
+	lastFailoverTimestamp        *prometheus.GaugeVec
+	peerInGossip                 *prometheus.GaugeVec
+	peerLastSeenSeconds          *prometheus.GaugeVec
+	peerHeartbeatReachable       *prometheus.GaugeVec
+	peerHeartbeatActive          *prometheus.GaugeVec
+	peerHeartbeatSlot            *prometheus.GaugeVec
+	hookDurationSeconds          *prometheus.HistogramVec
+	hookFailuresTotal            *prometheus.CounterVec
+	roleCommandDurationSeconds   *prometheus.HistogramVec
+	roleCommandFailuresTotal     *prometheus.CounterVec
+	towerSyncDurationSeconds     *prometheus.HistogramVec
+	towerSyncsTotal              *prometheus.CounterVec
+	timerRemainingSeconds        *prometheus.GaugeVec
+	buildInfo                    *prometheus.GaugeVec
+	// rpcRateLimitedExported is the cache total already added to rpcRateLimitedTotal
+	rpcRateLimitedExported uint64
+	// logWriteFailuresExported is the per sink total already added to logWriteFailuresTotal
+	logWriteFailuresExported map[string]uint64
+	// failoversExported is the per direction and reason cache count already added to failoversTotal
+	failoversExported map[cache.FailoverKey]uint64
+	// peersExported is the ip of every peer with exported series by name, to delete them once the peer is gone
+	peersExported map[string]string
 }
 
 // Options for creating a new Metrics instance
@@ -56,10 +161,13 @@ type Options struct {
 // New creates a new Metrics instance
 func New(opts Options) *Metrics {
 	m := &Metrics{
-		config:   opts.Config,
-		logger:   opts.Logger,
-		cache:    opts.Cache,
-		registry: prometheus.NewRegistry(),
+		config:                   opts.Config,
+		logger:                   opts.Logger,
+		cache:                    opts.Cache,
+		registry:                 prometheus.NewRegistry(),
+		logWriteFailuresExported: map[string]uint64{},
+		failoversExported:        map[cache.FailoverKey]uint64{},
+		peersExported:            map[string]string{},
 		commonLabelNames: []string{
 			validatorNameLabelName,
 			publicIPLabelName,
@@ -109,6 +217,58 @@ func (m *Metrics) initMetrics() {
 		m.commonLabelNames,
 	)
 
+	// Split brain metric - alert on any value above 0, two nodes voting with the active identity is slashable
+	m.splitBrain = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "split_brain",
+			Help: "Whether more than one node, this one included, is seen with the active identity (1 = yes, 0 = no)",
+		},
+		m.commonLabelNames,
+	)
+
+	// Vote account mismatch metric - alert on any value above 0, becoming active with this identity would not vote
+	m.voteAccountMismatch = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "vote_account_identity_mismatch",
+			Help: "Whether validator.vote_account's node identity is not the active identity (1 = yes, 0 = no)",
+		},
+		m.commonLabelNames,
+	)
+
+	// Paused metric - alert on it staying 1, no role transitions are made in maintenance mode
+	m.paused = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "paused",
+			Help: "Whether failover is paused for maintenance with solana-validator-ha pause (1 = yes, 0 = no)",
+		},
+		m.commonLabelNames,
+	)
+
+	// Leaderless samples metrics - alert on the count reaching the threshold minus one before a failover
+	m.leaderlessSamples = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "leaderless_samples",
+			Help: "Number of consecutive gossip samples without an active peer, this node fails over once it exceeds the threshold",
+		},
+		m.commonLabelNames,
+	)
+	m.leaderlessSamplesThreshold = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "leaderless_samples_threshold",
+			Help: "Configured failover.leaderless_samples_threshold",
+		},
+		m.commonLabelNames,
+	)
+
+	// Cluster rpc errors metric - alert on it staying above 0, gossip is stale and by default no decisions are made
+	m.clusterRPCErrors = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "cluster_rpc_consecutive_errors",
+			Help: "Number of consecutive gossip refreshes that failed to reach the cluster rpc, see failover.on_rpc_outage",
+		},
+		m.commonLabelNames,
+	)
+
 	// Failover status metric
 	failoverLabelNames := []string{
 		failoverStatusLabelName,
@@ -117,33 +277,505 @@ func (m *Metrics) initMetrics() {
 	m.failoverStatus = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: metricsNamespacePrefix + "failover_status",
-			Help: "Current failover status of the node",
+			Help: "Current failover status of the node, 1 for the current status and 0 for all others",
 		},
 		failoverLabelNames,
 	)
 
+	// Failover status code metric
+	m.failoverStatusCode = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "failover_status_code",
+			Help: "Current failover status of the node as a code (0=idle, 1=becoming_active, 2=becoming_passive, 3=failed, 4=blocked, 5=degraded)",
+		},
+		m.commonLabelNames,
+	)
+
+	// Effective poll interval metric
+	m.effectivePollIntervalSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "effective_poll_interval_seconds",
+			Help: "Poll interval in use in seconds, above the configured interval while adapting to rpc rate limits",
+		},
+		m.commonLabelNames,
+	)
+
+	// RPC rate limited metric
+	m.rpcRateLimitedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "rpc_rate_limited_total",
+			Help: "Number of cluster rpc responses rejected with HTTP 429 Too Many Requests",
+		},
+		m.commonLabelNames,
+	)
+
+	// Cluster rpc endpoint metric - always 1 with the host of the endpoint in use as a label
+	clusterRPCEndpointLabelNames := []string{
+		rpcEndpointLabelName,
+	}
+	clusterRPCEndpointLabelNames = append(clusterRPCEndpointLabelNames, m.commonLabelNames...)
+	m.clusterRPCEndpoint = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "cluster_rpc_endpoint",
+			Help: "Cluster rpc endpoint requests are sent to first, always 1 with the endpoint host as a label",
+		},
+		clusterRPCEndpointLabelNames,
+	)
+
+	// RPC request metrics - by JSON-RPC method and endpoint host, 10ms up to ~20s
+	rpcRequestLabelNames := []string{
+		rpcMethodLabelName,
+		rpcEndpointLabelName,
+	}
+	rpcRequestLabelNames = append(rpcRequestLabelNames, m.commonLabelNames...)
+	rpcErrorLabelNames := append([]string{rpcRetriedLabelName}, rpcRequestLabelNames...)
+	m.rpcRequestDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricsNamespacePrefix + "rpc_request_duration_seconds",
+			Help:    "Time each cluster and local rpc request took, failed ones included, by method and endpoint host",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		},
+		rpcRequestLabelNames,
+	)
+	m.rpcErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "rpc_errors_total",
+			Help: "Number of cluster and local rpc requests that failed, rate limited ones included, by method, endpoint host and whether the request was a retry",
+		},
+		rpcErrorLabelNames,
+	)
+
+	// Decision and action lag metrics - 10ms up to ~20s
+	lagBuckets := prometheus.ExponentialBuckets(0.01, 2, 12)
+	m.decisionLagSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricsNamespacePrefix + "decision_lag_seconds",
+			Help:    "Time between the gossip snapshot a decision was based on and the decision being made",
+			Buckets: lagBuckets,
+		},
+		m.commonLabelNames,
+	)
+	m.actionLagSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricsNamespacePrefix + "action_lag_seconds",
+			Help:    "Time between a decision being made and the role transition it called for starting",
+			Buckets: lagBuckets,
+		},
+		m.commonLabelNames,
+	)
+
+	// Config warnings metric - one series per active warning code
+	configWarningsLabelNames := []string{
+		warningCodeLabelName,
+	}
+	configWarningsLabelNames = append(configWarningsLabelNames, m.commonLabelNames...)
+	m.configWarnings = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "config_warnings",
+			Help: "Non-fatal configuration warnings, one series with value 1 per warning code found when the config was last validated",
+		},
+		configWarningsLabelNames,
+	)
+
+	// Would have metric - decisions dry_run prevented from being acted on
+	wouldHaveLabelNames := []string{
+		decisionActionLabelName,
+		decisionReasonLabelName,
+	}
+	wouldHaveLabelNames = append(wouldHaveLabelNames, m.commonLabelNames...)
+	m.wouldHaveTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "would_have_total",
+			Help: "Number of role transitions that would have been made had failover.dry_run been false",
+		},
+		wouldHaveLabelNames,
+	)
+
+	// Log write failures metric - one series per log sink
+	logWriteFailuresLabelNames := []string{
+		logSinkLabelName,
+	}
+	logWriteFailuresLabelNames = append(logWriteFailuresLabelNames, m.commonLabelNames...)
+	m.logWriteFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "log_write_failures_total",
+			Help: "Number of log writes that failed or were dropped because the log sink was broken or blocked",
+		},
+		logWriteFailuresLabelNames,
+	)
+
+	// Textfile generated timestamp metric - only set when mirroring to prometheus.textfile_path
+	m.textfileGeneratedTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "textfile_generated_timestamp_seconds",
+			Help: "Unix time the metrics textfile was last written, a stale value means the agent stopped refreshing it",
+		},
+		m.commonLabelNames,
+	)
+
+	// Rollbacks metric - by the role whose failed transition was rolled back and the rollback result
+	rollbacksLabelNames := []string{
+		rollbackRoleLabelName,
+		rollbackResultLabelName,
+	}
+	rollbacksLabelNames = append(rollbacksLabelNames, m.commonLabelNames...)
+	m.rollbacksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "rollbacks_total",
+			Help: "Number of failed role transitions rolled back to the previous role, by result (success, failure)",
+		},
+		rollbacksLabelNames,
+	)
+
+	// Gate violations metric - by safety gate and its mode, warn mode violations did not block promotion
+	gateViolationsLabelNames := []string{
+		gateLabelName,
+		gateModeLabelName,
+	}
+	gateViolationsLabelNames = append(gateViolationsLabelNames, m.commonLabelNames...)
+	m.gateViolationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "gate_violations_total",
+			Help: "Number of promotions a safety gate blocked (mode enforce) or would have blocked (mode warn)",
+		},
+		gateViolationsLabelNames,
+	)
+
+	// Possible duplicate signing metric - alarms raised by the post demotion watch
+	m.possibleDuplicateSigning = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "possible_duplicate_signing_total",
+			Help: "Number of times the active identity kept voting from this host after it was demoted",
+		},
+		m.commonLabelNames,
+	)
+
+	// Public IP changes metric - re-resolved public IPs that differed from the one in use
+	m.publicIPChangesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "public_ip_changes_total",
+			Help: "Number of times re-resolving the public IP returned a different one, which this node then used",
+		},
+		m.commonLabelNames,
+	)
+
+	// Poll metrics - HA monitor cycles run and those that took longer than the poll interval
+	m.pollIterationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "poll_iterations_total",
+			Help: "Number of HA monitor cycles run",
+		},
+		m.commonLabelNames,
+	)
+	m.pollOverrunsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "poll_overruns_total",
+			Help: "Number of HA monitor cycles that took longer than the poll interval, the samples they overran were skipped",
+		},
+		m.commonLabelNames,
+	)
+
+	// Takeovers aborted metric - a takeover delay outlasted by the active peer reappearing
+	m.takeoversAbortedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "takeovers_aborted_total",
+			Help: "Number of takeovers aborted because the active peer reappeared in gossip during the takeover delay",
+		},
+		m.commonLabelNames,
+	)
+
+	// Notifications metric - by notifier, action and whether it was sent
+	notificationsLabelNames := []string{
+		notifierLabelName,
+		notifyActionLabelName,
+		notifyResultLabelName,
+	}
+	notificationsLabelNames = append(notificationsLabelNames, m.commonLabelNames...)
+	m.notificationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "notifications_total",
+			Help: "Number of notifications sent by the built-in integrations, by notifier, action and result (success, failure)",
+		},
+		notificationsLabelNames,
+	)
+
+	// Failovers metric - completed role transitions by the role transitioned to and why
+	failoversLabelNames := []string{
+		failoverDirectionLabelName,
+		failoverReasonLabelName,
+	}
+	failoversLabelNames = append(failoversLabelNames, m.commonLabelNames...)
+	m.failoversTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "failovers_total",
+			Help: "Number of role transitions completed by this node, by direction (active, passive) and reason",
+		},
+		failoversLabelNames,
+	)
+
+	// Last failover metric - when the last role transition completed
+	m.lastFailoverTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "last_failover_timestamp_seconds",
+			Help: "Unix time the last role transition completed on this node, 0 if none has since startup",
+		},
+		m.commonLabelNames,
+	)
+
+	// Peer in gossip metric - one series per configured peer other than self
+	peerInGossipLabelNames := []string{
+		peerNameLabelName,
+		peerIPLabelName,
+	}
+	peerInGossipLabelNames = append(peerInGossipLabelNames, m.commonLabelNames...)
+	m.peerInGossip = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "peer_in_gossip",
+			Help: "Whether this node sees each configured peer in gossip (1 = yes, 0 = no)",
+		},
+		peerInGossipLabelNames,
+	)
+
+	// Peer last seen metric - only exported for peers seen since startup
+	peerLastSeenLabelNames := []string{
+		peerNameLabelName,
+	}
+	peerLastSeenLabelNames = append(peerLastSeenLabelNames, m.commonLabelNames...)
+	m.peerLastSeenSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "peer_last_seen_seconds",
+			Help: "Seconds since each configured peer was last seen in gossip, absent for peers not seen since startup",
+		},
+		peerLastSeenLabelNames,
+	)
+
+	// Peer heartbeat metrics - only exported when failover.heartbeat is enabled, the slot only while reachable
+	m.peerHeartbeatReachable = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "peer_heartbeat_reachable",
+			Help: "Whether each configured peer's agent answered its last heartbeat (1 = yes, 0 = no)",
+		},
+		peerLastSeenLabelNames,
+	)
+	m.peerHeartbeatActive = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "peer_heartbeat_active",
+			Help: "Whether each configured peer's agent reported the active role in its last heartbeat (1 = yes, 0 = no)",
+		},
+		peerLastSeenLabelNames,
+	)
+	m.peerHeartbeatSlot = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "peer_heartbeat_slot",
+			Help: "The local validator slot each configured peer's agent reported in its last heartbeat",
+		},
+		peerLastSeenLabelNames,
+	)
+
+	// Hook and role command execution metrics - 50ms up to ~100s, hooks' timeout and retries included
+	executionBuckets := prometheus.ExponentialBuckets(0.05, 2, 12)
+	hookLabelNames := []string{
+		hookNameLabelName,
+		hookPhaseLabelName,
+		roleLabelName,
+		dryRunLabelName,
+	}
+	hookLabelNames = append(hookLabelNames, m.commonLabelNames...)
+	m.hookDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricsNamespacePrefix + "hook_duration_seconds",
+			Help:    "Time each hook took to run, retries included, by hook name, phase (pre, post, alert) and role",
+			Buckets: executionBuckets,
+		},
+		hookLabelNames,
+	)
+	m.hookFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "hook_failures_total",
+			Help: "Number of hook runs that still failed once out of retries, by hook name, phase (pre, post, alert) and role",
+		},
+		hookLabelNames,
+	)
+	roleCommandLabelNames := []string{
+		roleLabelName,
+		dryRunLabelName,
+	}
+	roleCommandLabelNames = append(roleCommandLabelNames, m.commonLabelNames...)
+	m.roleCommandDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricsNamespacePrefix + "role_command_duration_seconds",
+			Help:    "Time each role command took to run, by role",
+			Buckets: executionBuckets,
+		},
+		roleCommandLabelNames,
+	)
+	m.roleCommandFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "role_command_failures_total",
+			Help: "Number of role command runs that failed, by role",
+		},
+		roleCommandLabelNames,
+	)
+
+	// Tower sync metrics - by the result of each tower file copy before the active command
+	towerSyncLabelNames := []string{
+		towerSyncResultLabelName,
+	}
+	towerSyncLabelNames = append(towerSyncLabelNames, m.commonLabelNames...)
+	m.towerSyncDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    metricsNamespacePrefix + "tower_sync_duration_seconds",
+			Help:    "Time each tower file copy from the last known active peer took, by result",
+			Buckets: executionBuckets,
+		},
+		towerSyncLabelNames,
+	)
+	m.towerSyncsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsNamespacePrefix + "tower_syncs_total",
+			Help: "Number of tower file copies from the last known active peer, by result",
+		},
+		towerSyncLabelNames,
+	)
+
+	// Timer remaining metric - by the name of each timer governing agent behaviour
+	timerRemainingLabelNames := []string{
+		timerLabelName,
+	}
+	timerRemainingLabelNames = append(timerRemainingLabelNames, m.commonLabelNames...)
+	m.timerRemainingSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "timer_remaining_seconds",
+			Help: "Seconds until each timer governing agent behaviour expires, 0 when expired or not running",
+		},
+		timerRemainingLabelNames,
+	)
+
+	// Build info metric - always 1 with the build details as labels
+	buildInfoLabelNames := []string{
+		versionLabelName,
+		commitLabelName,
+		buildDateLabelName,
+		goVersionLabelName,
+	}
+	buildInfoLabelNames = append(buildInfoLabelNames, m.commonLabelNames...)
+	m.buildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: metricsNamespacePrefix + "build_info",
+			Help: "Build details of the running binary, always 1 with version, commit, build_date and go_version labels",
+		},
+		buildInfoLabelNames,
+	)
+
 	// Register all metrics
 	m.registry.MustRegister(m.metadata)
 	m.registry.MustRegister(m.peerCount)
 	m.registry.MustRegister(m.selfInGossip)
+	m.registry.MustRegister(m.splitBrain)
+	m.registry.MustRegister(m.voteAccountMismatch)
+	m.registry.MustRegister(m.paused)
+	m.registry.MustRegister(m.leaderlessSamples)
+	m.registry.MustRegister(m.clusterRPCErrors)
+	m.registry.MustRegister(m.leaderlessSamplesThreshold)
 	m.registry.MustRegister(m.failoverStatus)
+	m.registry.MustRegister(m.failoverStatusCode)
+	m.registry.MustRegister(m.effectivePollIntervalSeconds)
+	m.registry.MustRegister(m.rpcRateLimitedTotal)
+	m.registry.MustRegister(m.clusterRPCEndpoint)
+	m.registry.MustRegister(m.rpcRequestDurationSeconds)
+	m.registry.MustRegister(m.rpcErrorsTotal)
+	m.registry.MustRegister(m.decisionLagSeconds)
+	m.registry.MustRegister(m.actionLagSeconds)
+	m.registry.MustRegister(m.configWarnings)
+	m.registry.MustRegister(m.wouldHaveTotal)
+	m.registry.MustRegister(m.logWriteFailuresTotal)
+	m.registry.MustRegister(m.textfileGeneratedTimestamp)
+	m.registry.MustRegister(m.rollbacksTotal)
+	m.registry.MustRegister(m.gateViolationsTotal)
+	m.registry.MustRegister(m.possibleDuplicateSigning)
+	m.registry.MustRegister(m.publicIPChangesTotal)
+	m.registry.MustRegister(m.pollIterationsTotal)
+	m.registry.MustRegister(m.pollOverrunsTotal)
+	m.registry.MustRegister(m.takeoversAbortedTotal)
+	m.registry.MustRegister(m.notificationsTotal)
+	m.registry.MustRegister(m.failoversTotal)
+	m.registry.MustRegister(m.lastFailoverTimestamp)
+	m.registry.MustRegister(m.peerInGossip)
+	m.registry.MustRegister(m.peerLastSeenSeconds)
+	m.registry.MustRegister(m.peerHeartbeatReachable)
+	m.registry.MustRegister(m.peerHeartbeatActive)
+	m.registry.MustRegister(m.peerHeartbeatSlot)
+	m.registry.MustRegister(m.hookDurationSeconds)
+	m.registry.MustRegister(m.hookFailuresTotal)
+	m.registry.MustRegister(m.roleCommandDurationSeconds)
+	m.registry.MustRegister(m.roleCommandFailuresTotal)
+	m.registry.MustRegister(m.towerSyncDurationSeconds)
+	m.registry.MustRegister(m.towerSyncsTotal)
+	m.registry.MustRegister(m.timerRemainingSeconds)
+	m.registry.MustRegister(m.buildInfo)
 
 	m.logger.Debug("initialized Prometheus metrics")
 }
 
-// StartServer starts the Prometheus metrics HTTP server
-func (m *Metrics) StartServer(port int) error {
+// StartServer starts the Prometheus metrics HTTP server on bindAddress, which must be an IP address. With
+// prometheus.tls set it serves https, reloading the certificate on SIGHUP.
+func (m *Metrics) StartServer(bindAddress string, port int) error {
+	if net.ParseIP(bindAddress) == nil {
+		err := fmt.Errorf("invalid bind address %q - must be an IP address", bindAddress)
+		m.logger.Error("Prometheus metrics server failed", "error", err)
+		return err
+	}
+
+	var tlsConfig *tls.Config
+	if m.config.Prometheus.TLS != nil {
+		certificate, err := tlscert.NewReloader(m.config.Prometheus.TLS.CertFile, m.config.Prometheus.TLS.KeyFile, m.config.Validator.Name)
+		if err != nil {
+			m.logger.Error("Prometheus metrics server failed", "error", err)
+			return err
+		}
+		stop := certificate.ReloadOnSIGHUP()
+		defer stop()
+		tlsConfig = certificate.TLSConfig()
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(bindAddress, strconv.Itoa(port)))
+	if err != nil {
+		m.logger.Error("Prometheus metrics server failed", "error", err)
+		return err
+	}
+	return m.Serve(listener, tlsConfig)
+}
+
+// Serve serves the Prometheus metrics on an already bound listener, over https if tlsConfig is set
+func (m *Metrics) Serve(listener net.Listener, tlsConfig *tls.Config) error {
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	mux.Handle("/metrics", httpauth.RequireBearerToken(m.config.Prometheus.Auth.Token(), promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})))
 
-	m.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: mux,
+	server := &http.Server{
+		Addr:      listener.Addr().String(),
+		Handler:   mux,
+		TLSConfig: tlsConfig,
 	}
 
-	m.logger.Debug("starting Prometheus metrics server", "port", port)
+	// stopped before we got here - never serve on a port the agent is giving up
+	m.serverMu.Lock()
+	if m.serverStopped {
+		m.serverMu.Unlock()
+		listener.Close()
+		return http.ErrServerClosed
+	}
+	m.server = server
+	m.serverMu.Unlock()
 
-	err := m.server.ListenAndServe()
+	m.logger.Info("starting Prometheus metrics server", "address", listener.Addr().String(), "tls", tlsConfig != nil)
+
+	var err error
+	if tlsConfig != nil {
+		// the certificate comes from tlsConfig
+		err = server.ServeTLS(listener, "", "")
+	} else {
+		err = server.Serve(listener)
+	}
 	if err != nil {
 		m.logger.Error("Prometheus metrics server failed", "error", err)
 	}
@@ -152,17 +784,211 @@ func (m *Metrics) StartServer(port int) error {
 
 // StopServer stops the Prometheus metrics HTTP server
 func (m *Metrics) StopServer() error {
-	if m.server != nil {
-		return m.server.Close()
+	if server := m.stopServer(); server != nil {
+		return server.Close()
+	}
+	return nil
+}
+
+// ShutdownServer stops the Prometheus metrics HTTP server gracefully, letting scrapes in flight finish until ctx is done
+func (m *Metrics) ShutdownServer(ctx context.Context) error {
+	if server := m.stopServer(); server != nil {
+		return server.Shutdown(ctx)
 	}
 	return nil
 }
 
+// stopServer marks the server stopped so it is never served, returning it if Serve has already started it
+func (m *Metrics) stopServer() *http.Server {
+	m.serverMu.Lock()
+	defer m.serverMu.Unlock()
+	m.serverStopped = true
+	return m.server
+}
+
 // GetRegistry returns the Prometheus registry for testing
 func (m *Metrics) GetRegistry() *prometheus.Registry {
 	return m.registry
 }
 
+// ObserveRPCRequest records how long an rpc request to endpoint took and whether it, or the retry it was, failed -
+// it is an rpc.RequestObserver
+func (m *Metrics) ObserveRPCRequest(method string, endpoint string, retried bool, duration time.Duration, err error) {
+	state := m.cache.GetState()
+	labels := m.mergeLabels(
+		prometheus.Labels{
+			rpcMethodLabelName:   method,
+			rpcEndpointLabelName: endpoint,
+		},
+		m.getCommonLabels(&state),
+	)
+	m.rpcRequestDurationSeconds.With(labels).Observe(duration.Seconds())
+	counter := m.rpcErrorsTotal.With(m.mergeLabels(prometheus.Labels{rpcRetriedLabelName: strconv.FormatBool(retried)}, labels))
+	if err != nil {
+		counter.Inc()
+	}
+}
+
+// ObserveDecisionLag records the lag between a gossip snapshot and the decision based on it
+func (m *Metrics) ObserveDecisionLag(lag time.Duration) {
+	state := m.cache.GetState()
+	m.decisionLagSeconds.With(m.getCommonLabels(&state)).Observe(lag.Seconds())
+}
+
+// ObserveActionLag records the lag between a decision and the transition it called for starting
+func (m *Metrics) ObserveActionLag(lag time.Duration) {
+	state := m.cache.GetState()
+	m.actionLagSeconds.With(m.getCommonLabels(&state)).Observe(lag.Seconds())
+}
+
+// ObserveWouldHave records a role transition decision that dry_run prevented from being acted on
+func (m *Metrics) ObserveWouldHave(action string, reason string) {
+	state := m.cache.GetState()
+	m.wouldHaveTotal.
+		With(
+			m.mergeLabels(
+				prometheus.Labels{
+					decisionActionLabelName: action,
+					decisionReasonLabelName: reason,
+				},
+				m.getCommonLabels(&state),
+			),
+		).
+		Inc()
+}
+
+// ObserveRollback records a rollback of a failed transition to fromRole and its result
+func (m *Metrics) ObserveRollback(fromRole string, result string) {
+	state := m.cache.GetState()
+	m.rollbacksTotal.
+		With(
+			m.mergeLabels(
+				prometheus.Labels{
+					rollbackRoleLabelName:   fromRole,
+					rollbackResultLabelName: result,
+				},
+				m.getCommonLabels(&state),
+			),
+		).
+		Inc()
+}
+
+// ObserveGateViolation records a safety gate that blocked, or in warn mode would have blocked, a promotion
+func (m *Metrics) ObserveGateViolation(gate string, mode string) {
+	state := m.cache.GetState()
+	m.gateViolationsTotal.
+		With(
+			m.mergeLabels(
+				prometheus.Labels{
+					gateLabelName:     gate,
+					gateModeLabelName: mode,
+				},
+				m.getCommonLabels(&state),
+			),
+		).
+		Inc()
+}
+
+// ObservePossibleDuplicateSigning records a possible duplicate signing alarm
+func (m *Metrics) ObservePossibleDuplicateSigning() {
+	state := m.cache.GetState()
+	m.possibleDuplicateSigning.
+		With(m.getCommonLabels(&state)).
+		Inc()
+}
+
+// ObservePublicIPChange records a change of the public IP, labelled with the new one once it is in the cache
+func (m *Metrics) ObservePublicIPChange() {
+	state := m.cache.GetState()
+	m.publicIPChangesTotal.
+		With(m.getCommonLabels(&state)).
+		Inc()
+}
+
+// ObservePollIteration records an HA monitor cycle and whether it overran the poll interval
+func (m *Metrics) ObservePollIteration(overran bool) {
+	state := m.cache.GetState()
+	labels := m.getCommonLabels(&state)
+	m.pollIterationsTotal.With(labels).Inc()
+	if overran {
+		m.pollOverrunsTotal.With(labels).Inc()
+	}
+}
+
+// ObserveTakeoverAborted records a takeover aborted because the active peer reappeared during the takeover delay
+func (m *Metrics) ObserveTakeoverAborted() {
+	state := m.cache.GetState()
+	m.takeoversAbortedTotal.
+		With(m.getCommonLabels(&state)).
+		Inc()
+}
+
+// ObserveNotification records a notification sent by notifier, e.g. a pagerduty trigger, and its result
+func (m *Metrics) ObserveNotification(notifier string, action string, result string) {
+	state := m.cache.GetState()
+	m.notificationsTotal.
+		With(
+			m.mergeLabels(
+				prometheus.Labels{
+					notifierLabelName:     notifier,
+					notifyActionLabelName: action,
+					notifyResultLabelName: result,
+				},
+				m.getCommonLabels(&state),
+			),
+		).
+		Inc()
+}
+
+// ObserveHook records how long a hook of phase run for role took and whether it failed
+func (m *Metrics) ObserveHook(hookName string, phase string, role string, dryRun bool, duration time.Duration, failed bool) {
+	state := m.cache.GetState()
+	labels := m.mergeLabels(
+		prometheus.Labels{
+			hookNameLabelName:  hookName,
+			hookPhaseLabelName: phase,
+			roleLabelName:      role,
+			dryRunLabelName:    strconv.FormatBool(dryRun),
+		},
+		m.getCommonLabels(&state),
+	)
+	m.hookDurationSeconds.With(labels).Observe(duration.Seconds())
+	counter := m.hookFailuresTotal.With(labels)
+	if failed {
+		counter.Inc()
+	}
+}
+
+// ObserveRoleCommand records how long role's command took and whether it failed
+func (m *Metrics) ObserveRoleCommand(role string, dryRun bool, duration time.Duration, failed bool) {
+	state := m.cache.GetState()
+	labels := m.mergeLabels(
+		prometheus.Labels{
+			roleLabelName:   role,
+			dryRunLabelName: strconv.FormatBool(dryRun),
+		},
+		m.getCommonLabels(&state),
+	)
+	m.roleCommandDurationSeconds.With(labels).Observe(duration.Seconds())
+	counter := m.roleCommandFailuresTotal.With(labels)
+	if failed {
+		counter.Inc()
+	}
+}
+
+// ObserveTowerSync records a tower file copy and how long it took - result is success, failure or unreachable
+func (m *Metrics) ObserveTowerSync(result string, duration time.Duration) {
+	state := m.cache.GetState()
+	labels := m.mergeLabels(
+		prometheus.Labels{
+			towerSyncResultLabelName: result,
+		},
+		m.getCommonLabels(&state),
+	)
+	m.towerSyncDurationSeconds.With(labels).Observe(duration.Seconds())
+	m.towerSyncsTotal.With(labels).Inc()
+}
+
 // RefreshMetrics updates all metrics based on current cache state
 func (m *Metrics) RefreshMetrics() {
 	m.logger.Debug("refreshing metrics from cache")
@@ -171,7 +997,28 @@ func (m *Metrics) RefreshMetrics() {
 	m.exportMetricMetadata(&state)
 	m.exportMetricPeerCount(&state)
 	m.exportMetricSelfInGossip(&state)
+	m.exportMetricSplitBrain(&state)
+	m.exportMetricVoteAccountMismatch(&state)
+	m.exportMetricPaused(&state)
+	m.exportMetricLeaderlessSamples(&state)
+	m.exportMetricClusterRPCErrors(&state)
 	m.exportMetricFailoverStatus(&state)
+	m.exportMetricEffectivePollInterval(&state)
+	m.exportMetricRPCRateLimited(&state)
+	m.exportMetricClusterRPCEndpoint(&state)
+	m.exportMetricConfigWarnings(&state)
+	m.exportMetricLogWriteFailures(&state)
+	m.exportMetricFailovers(&state)
+	m.exportMetricPeers(&state)
+	m.exportMetricTimerRemaining(&state)
+	m.exportMetricBuildInfo(&state)
+
+	// mirror to the textfile last so it includes everything just exported
+	if m.config.Prometheus.TextfilePath != "" {
+		if err := m.writeTextfile(&state); err != nil {
+			m.logger.Error("failed to write metrics textfile", "textfile_path", m.config.Prometheus.TextfilePath, "error", err)
+		}
+	}
 
 	m.logger.Debug("metrics refreshed",
 		validatorRoleLabelName, state.Role,
@@ -191,8 +1038,8 @@ func (m *Metrics) exportMetricMetadata(state *cache.State) {
 		With(
 			m.mergeLabels(
 				prometheus.Labels{
-					validatorRoleLabelName:   state.Role,
-					validatorStatusLabelName: state.Status,
+					validatorRoleLabelName:   state.Role.String(),
+					validatorStatusLabelName: state.Status.String(),
 				},
 				m.getCommonLabels(state),
 			),
@@ -216,12 +1063,289 @@ func (m *Metrics) exportMetricSelfInGossip(state *cache.State) {
 		Set(selfInGossipValue)
 }
 
+func (m *Metrics) exportMetricSplitBrain(state *cache.State) {
+	var splitBrainValue float64
+	if len(state.SplitBrainPeers) > 1 {
+		splitBrainValue = 1
+	}
+	m.splitBrain.
+		With(m.getCommonLabels(state)).
+		Set(splitBrainValue)
+}
+
+func (m *Metrics) exportMetricVoteAccountMismatch(state *cache.State) {
+	var mismatchValue float64
+	if state.VoteAccountMismatch {
+		mismatchValue = 1
+	}
+	m.voteAccountMismatch.
+		With(m.getCommonLabels(state)).
+		Set(mismatchValue)
+}
+
+func (m *Metrics) exportMetricPaused(state *cache.State) {
+	var pausedValue float64
+	if state.Paused {
+		pausedValue = 1
+	}
+	m.paused.
+		With(m.getCommonLabels(state)).
+		Set(pausedValue)
+}
+
+func (m *Metrics) exportMetricLeaderlessSamples(state *cache.State) {
+	m.leaderlessSamples.
+		With(m.getCommonLabels(state)).
+		Set(float64(state.LeaderlessSamples))
+	m.leaderlessSamplesThreshold.
+		With(m.getCommonLabels(state)).
+		Set(float64(m.config.Failover.LeaderlessSamplesThreshold))
+}
+
+func (m *Metrics) exportMetricClusterRPCErrors(state *cache.State) {
+	m.clusterRPCErrors.
+		With(m.getCommonLabels(state)).
+		Set(float64(state.ClusterRPCErrors))
+}
+
 func (m *Metrics) exportMetricFailoverStatus(state *cache.State) {
-	m.failoverStatus.
+	// metrics refreshed before the first cycle has set a failover status are idle
+	current := state.FailoverStatus
+	if current == "" {
+		current = constants.FailoverStatusIdle
+	}
+
+	// export every known status so the previous status drops back to 0 instead of going stale
+	for _, failoverStatus := range constants.FailoverStatuses() {
+		var value float64
+		if failoverStatus == current {
+			value = 1
+		}
+		m.failoverStatus.
+			With(
+				m.mergeLabels(
+					prometheus.Labels{
+						failoverStatusLabelName: failoverStatus.String(),
+					},
+					m.getCommonLabels(state),
+				),
+			).
+			Set(value)
+	}
+
+	code, ok := failoverStatusMetricValues[current]
+	if !ok {
+		m.logger.Warn("no metric value for failover status", "failover_status", current)
+		return
+	}
+	m.failoverStatusCode.
+		With(m.getCommonLabels(state)).
+		Set(code)
+}
+
+func (m *Metrics) exportMetricEffectivePollInterval(state *cache.State) {
+	m.effectivePollIntervalSeconds.
+		With(m.getCommonLabels(state)).
+		Set(state.EffectivePollInterval.Seconds())
+}
+
+func (m *Metrics) exportMetricRPCRateLimited(state *cache.State) {
+	// the cache holds a running total so only the increase since the last export is added
+	counter := m.rpcRateLimitedTotal.With(m.getCommonLabels(state))
+	if state.RPCRateLimitedTotal > m.rpcRateLimitedExported {
+		counter.Add(float64(state.RPCRateLimitedTotal - m.rpcRateLimitedExported))
+		m.rpcRateLimitedExported = state.RPCRateLimitedTotal
+	}
+}
+
+func (m *Metrics) exportMetricClusterRPCEndpoint(state *cache.State) {
+	// Reset so the endpoint failed over from drops out
+	m.clusterRPCEndpoint.Reset()
+	if state.ClusterRPCEndpoint == "" {
+		return
+	}
+
+	m.clusterRPCEndpoint.
+		With(
+			m.mergeLabels(
+				prometheus.Labels{
+					rpcEndpointLabelName: state.ClusterRPCEndpoint,
+				},
+				m.getCommonLabels(state),
+			),
+		).
+		Set(1)
+}
+
+func (m *Metrics) exportMetricConfigWarnings(state *cache.State) {
+	// reset so warnings cleared by a config re-evaluation drop out
+	m.configWarnings.Reset()
+	for _, warning := range m.config.Warnings {
+		m.configWarnings.
+			With(
+				m.mergeLabels(
+					prometheus.Labels{
+						warningCodeLabelName: string(warning.Code),
+					},
+					m.getCommonLabels(state),
+				),
+			).
+			Set(1)
+	}
+}
+
+func (m *Metrics) exportMetricLogWriteFailures(state *cache.State) {
+	// sinks hold running totals so only the increase since the last export is added
+	for _, sink := range m.config.Log.Sinks {
+		counter := m.logWriteFailuresTotal.With(
+			m.mergeLabels(
+				prometheus.Labels{
+					logSinkLabelName: sink.Name(),
+				},
+				m.getCommonLabels(state),
+			),
+		)
+		failures := sink.Failures()
+		if failures > m.logWriteFailuresExported[sink.Name()] {
+			counter.Add(float64(failures - m.logWriteFailuresExported[sink.Name()]))
+			m.logWriteFailuresExported[sink.Name()] = failures
+		}
+	}
+}
+
+func (m *Metrics) exportMetricFailovers(state *cache.State) {
+	// the cache holds running counts so only the increase since the last export is added
+	for key, count := range state.FailoverCount {
+		if count <= m.failoversExported[key] {
+			continue
+		}
+		m.failoversTotal.
+			With(
+				m.mergeLabels(
+					prometheus.Labels{
+						failoverDirectionLabelName: key.Direction.String(),
+						failoverReasonLabelName:    key.Reason,
+					},
+					m.getCommonLabels(state),
+				),
+			).
+			Add(float64(count - m.failoversExported[key]))
+		m.failoversExported[key] = count
+	}
+
+	lastFailoverAt := 0.0
+	if !state.LastFailoverAt.IsZero() {
+		lastFailoverAt = float64(state.LastFailoverAt.Unix())
+	}
+	m.lastFailoverTimestamp.With(m.getCommonLabels(state)).Set(lastFailoverAt)
+}
+
+func (m *Metrics) exportMetricPeers(state *cache.State) {
+	// delete the series of peers no longer configured, or whose ip changed, rather than leave them stale
+	for name, ip := range m.peersExported {
+		if peer, ok := state.Peers[name]; ok && peer.IP == ip {
+			continue
+		}
+		m.peerInGossip.DeletePartialMatch(prometheus.Labels{peerNameLabelName: name})
+		m.peerLastSeenSeconds.DeletePartialMatch(prometheus.Labels{peerNameLabelName: name})
+		m.deletePeerHeartbeat(name)
+		delete(m.peersExported, name)
+	}
+
+	for name, peer := range state.Peers {
+		var inGossipValue float64
+		if peer.InGossip {
+			inGossipValue = 1
+		}
+		m.peerInGossip.
+			With(
+				m.mergeLabels(
+					prometheus.Labels{
+						peerNameLabelName: name,
+						peerIPLabelName:   peer.IP,
+					},
+					m.getCommonLabels(state),
+				),
+			).
+			Set(inGossipValue)
+		m.peersExported[name] = peer.IP
+		m.exportMetricPeerHeartbeat(state, name, peer.Heartbeat)
+
+		if peer.LastSeenAt.IsZero() {
+			continue
+		}
+		m.peerLastSeenSeconds.
+			With(
+				m.mergeLabels(
+					prometheus.Labels{
+						peerNameLabelName: name,
+					},
+					m.getCommonLabels(state),
+				),
+			).
+			Set(max(time.Since(peer.LastSeenAt).Seconds(), 0))
+	}
+}
+
+// exportMetricPeerHeartbeat exports a peer's latest heartbeat, deleting its series when it has none
+func (m *Metrics) exportMetricPeerHeartbeat(state *cache.State, name string, heartbeat *cache.PeerHeartbeat) {
+	if heartbeat == nil {
+		m.deletePeerHeartbeat(name)
+		return
+	}
+
+	labels := m.mergeLabels(prometheus.Labels{peerNameLabelName: name}, m.getCommonLabels(state))
+	var reachableValue, activeValue float64
+	if heartbeat.Reachable {
+		reachableValue = 1
+	}
+	if heartbeat.Reachable && heartbeat.Role == constants.RoleActive {
+		activeValue = 1
+	}
+	m.peerHeartbeatReachable.With(labels).Set(reachableValue)
+	m.peerHeartbeatActive.With(labels).Set(activeValue)
+	if heartbeat.Reachable {
+		m.peerHeartbeatSlot.With(labels).Set(float64(heartbeat.Slot))
+	} else {
+		m.peerHeartbeatSlot.DeletePartialMatch(prometheus.Labels{peerNameLabelName: name})
+	}
+}
+
+// deletePeerHeartbeat deletes a peer's heartbeat series
+func (m *Metrics) deletePeerHeartbeat(name string) {
+	m.peerHeartbeatReachable.DeletePartialMatch(prometheus.Labels{peerNameLabelName: name})
+	m.peerHeartbeatActive.DeletePartialMatch(prometheus.Labels{peerNameLabelName: name})
+	m.peerHeartbeatSlot.DeletePartialMatch(prometheus.Labels{peerNameLabelName: name})
+}
+
+func (m *Metrics) exportMetricTimerRemaining(state *cache.State) {
+	for _, timer := range state.Timers {
+		m.timerRemainingSeconds.
+			With(
+				m.mergeLabels(
+					prometheus.Labels{
+						timerLabelName: timer.Name,
+					},
+					m.getCommonLabels(state),
+				),
+			).
+			Set(timer.Remaining.Seconds())
+	}
+}
+
+func (m *Metrics) exportMetricBuildInfo(state *cache.State) {
+	// Reset to drop the series exported before the common labels were known
+	m.buildInfo.Reset()
+
+	info := buildinfo.Get()
+	m.buildInfo.
 		With(
 			m.mergeLabels(
 				prometheus.Labels{
-					failoverStatusLabelName: state.FailoverStatus,
+					versionLabelName:   info.Version,
+					commitLabelName:    info.Commit,
+					buildDateLabelName: info.BuildDate,
+					goVersionLabelName: info.GoVersion,
 				},
 				m.getCommonLabels(state),
 			),
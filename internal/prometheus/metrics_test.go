@@ -1,8 +1,14 @@
 package prometheus
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,6 +20,8 @@ import (
 
 	"github.com/sol-strategies/solana-validator-ha/internal/cache"
 	"github.com/sol-strategies/solana-validator-ha/internal/config"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+	"github.com/sol-strategies/solana-validator-ha/internal/logwriter"
 )
 
 func createTestConfig() *config.Config {
@@ -118,8 +126,8 @@ func TestRefreshMetrics(t *testing.T) {
 		Status:         "healthy",
 		PeerCount:      5,
 		SelfInGossip:   true,
-		FailoverStatus: "stable",
-		LastUpdated:    time.Now(),
+		FailoverStatus: "idle",
+		LastObserved:   time.Now(),
 	}
 	cacheInstance.UpdateState(state)
 
@@ -331,6 +339,41 @@ func TestExportMetricMetadata(t *testing.T) {
 	assert.Equal(t, float64(1), *metadataMetric.Metric[0].Gauge.Value)
 }
 
+func TestExportMetricBuildInfo(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	// exported before and after the public ip is known - only the latest series remains
+	metrics.exportMetricBuildInfo(&cache.State{ValidatorName: "test-validator"})
+	metrics.exportMetricBuildInfo(&cache.State{ValidatorName: "test-validator", PublicIP: "192.168.1.100"})
+
+	metricsList, err := metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+
+	var buildInfoMetric *dto.MetricFamily
+	for _, metricFamily := range metricsList {
+		if metricFamily.GetName() == "solana_validator_ha_build_info" {
+			buildInfoMetric = metricFamily
+		}
+	}
+	require.NotNil(t, buildInfoMetric)
+	require.Len(t, buildInfoMetric.Metric, 1)
+	assert.Equal(t, 1.0, buildInfoMetric.Metric[0].Gauge.GetValue())
+
+	labels := map[string]string{}
+	for _, label := range buildInfoMetric.Metric[0].Label {
+		labels[label.GetName()] = label.GetValue()
+	}
+	assert.Equal(t, "192.168.1.100", labels["public_ip"])
+	assert.Equal(t, runtime.Version(), labels["go_version"])
+	for _, name := range []string{"version", "commit", "build_date"} {
+		assert.NotEmpty(t, labels[name], name)
+	}
+}
+
 func TestExportMetricPeerCount(t *testing.T) {
 	cfg := createTestConfig()
 	cacheInstance := createTestCache()
@@ -409,6 +452,78 @@ func TestExportMetricSelfInGossip_True(t *testing.T) {
 	assert.Equal(t, float64(1), *selfInGossipMetric.Metric[0].Gauge.Value)
 }
 
+func TestExportMetricSplitBrain(t *testing.T) {
+	tests := []struct {
+		name            string
+		splitBrainPeers []string
+		expected        float64
+	}{
+		{"none", nil, 0},
+		{"two actives", []string{"peer1", "test-validator"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := New(Options{Config: createTestConfig(), Logger: createTestLogger(), Cache: createTestCache()})
+			state := cache.State{
+				ValidatorName:   "test-validator",
+				PublicIP:        "192.168.1.100",
+				SplitBrainPeers: tt.splitBrainPeers,
+			}
+
+			metrics.exportMetricSplitBrain(&state)
+
+			metricsList, err := metrics.GetRegistry().Gather()
+			require.NoError(t, err)
+			var splitBrainMetric *dto.MetricFamily
+			for _, metricFamily := range metricsList {
+				if *metricFamily.Name == "solana_validator_ha_split_brain" {
+					splitBrainMetric = metricFamily
+					break
+				}
+			}
+			require.NotNil(t, splitBrainMetric)
+			require.Len(t, splitBrainMetric.Metric, 1)
+			assert.Equal(t, tt.expected, *splitBrainMetric.Metric[0].Gauge.Value)
+		})
+	}
+}
+
+func TestExportMetricPaused(t *testing.T) {
+	tests := []struct {
+		name     string
+		paused   bool
+		expected float64
+	}{
+		{"running", false, 0},
+		{"paused", true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := New(Options{Config: createTestConfig(), Logger: createTestLogger(), Cache: createTestCache()})
+			state := cache.State{
+				ValidatorName: "test-validator",
+				PublicIP:      "192.168.1.100",
+				Paused:        tt.paused,
+			}
+
+			metrics.exportMetricPaused(&state)
+
+			metricsList, err := metrics.GetRegistry().Gather()
+			require.NoError(t, err)
+			var pausedMetric *dto.MetricFamily
+			for _, metricFamily := range metricsList {
+				if *metricFamily.Name == "solana_validator_ha_paused" {
+					pausedMetric = metricFamily
+					break
+				}
+			}
+			require.NotNil(t, pausedMetric)
+			require.Len(t, pausedMetric.Metric, 1)
+			assert.Equal(t, tt.expected, *pausedMetric.Metric[0].Gauge.Value)
+		})
+	}
+}
+
 func TestExportMetricSelfInGossip_False(t *testing.T) {
 	cfg := createTestConfig()
 	cacheInstance := createTestCache()
@@ -483,8 +598,200 @@ func TestExportMetricFailoverStatus(t *testing.T) {
 	}
 
 	require.NotNil(t, failoverStatusMetric)
-	assert.Len(t, failoverStatusMetric.Metric, 1)
-	assert.Equal(t, float64(1), *failoverStatusMetric.Metric[0].Gauge.Value)
+	assert.Len(t, failoverStatusMetric.Metric, len(constants.FailoverStatuses()))
+	for _, metric := range failoverStatusMetric.Metric {
+		expectedValue := float64(0)
+		if getLabelValue(metric, "status") == "becoming_active" {
+			expectedValue = 1
+		}
+		assert.Equal(t, expectedValue, *metric.Gauge.Value, "status %s", getLabelValue(metric, "status"))
+	}
+
+	// the code metric reflects the current status
+	var failoverStatusCodeMetric *dto.MetricFamily
+	for _, metricFamily := range metricsList {
+		if *metricFamily.Name == "solana_validator_ha_failover_status_code" {
+			failoverStatusCodeMetric = metricFamily
+			break
+		}
+	}
+	require.NotNil(t, failoverStatusCodeMetric)
+	require.Len(t, failoverStatusCodeMetric.Metric, 1)
+	assert.Equal(t, float64(1), *failoverStatusCodeMetric.Metric[0].Gauge.Value)
+}
+
+func TestExportMetricFailoverStatus_UnsetIsIdle(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	// metrics refreshed before the first cycle read as idle
+	metrics.exportMetricFailoverStatus(&cache.State{ValidatorName: "test-validator", PublicIP: "192.168.1.100"})
+
+	metricsList, err := metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	found := 0
+	for _, metricFamily := range metricsList {
+		switch *metricFamily.Name {
+		case "solana_validator_ha_failover_status":
+			found++
+			for _, metric := range metricFamily.Metric {
+				expectedValue := float64(0)
+				if getLabelValue(metric, "status") == constants.FailoverStatusIdle.String() {
+					expectedValue = 1
+				}
+				assert.Equal(t, expectedValue, *metric.Gauge.Value, "status %s", getLabelValue(metric, "status"))
+			}
+		case "solana_validator_ha_failover_status_code":
+			found++
+			require.Len(t, metricFamily.Metric, 1)
+			assert.Equal(t, float64(0), *metricFamily.Metric[0].Gauge.Value)
+		}
+	}
+	assert.Equal(t, 2, found)
+}
+
+// getLabelValue returns the value of the named label on a metric
+func getLabelValue(metric *dto.Metric, name string) string {
+	for _, label := range metric.Label {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
+func TestFailoverStatusMetricValues_Exhaustive(t *testing.T) {
+	seenValues := map[float64]constants.FailoverStatus{}
+	for _, failoverStatus := range constants.FailoverStatuses() {
+		value, ok := failoverStatusMetricValues[failoverStatus]
+		require.True(t, ok, "failover status %s has no metric mapping in failoverStatusMetricValues", failoverStatus)
+
+		other, duplicate := seenValues[value]
+		require.False(t, duplicate, "failover statuses %s and %s share metric value %v", failoverStatus, other, value)
+		seenValues[value] = failoverStatus
+	}
+	assert.Len(t, failoverStatusMetricValues, len(constants.FailoverStatuses()), "failoverStatusMetricValues maps unknown failover statuses")
+}
+
+func TestExportMetricEffectivePollIntervalAndRPCRateLimited(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	gatherValue := func(name string) float64 {
+		metricsList, err := metrics.GetRegistry().Gather()
+		require.NoError(t, err)
+		for _, metricFamily := range metricsList {
+			if *metricFamily.Name != name {
+				continue
+			}
+			require.Len(t, metricFamily.Metric, 1)
+			if metricFamily.Metric[0].Counter != nil {
+				return *metricFamily.Metric[0].Counter.Value
+			}
+			return *metricFamily.Metric[0].Gauge.Value
+		}
+		t.Fatalf("metric %s not found", name)
+		return 0
+	}
+
+	state := cache.State{
+		ValidatorName:         "test-validator",
+		PublicIP:              "192.168.1.100",
+		EffectivePollInterval: 10 * time.Second,
+		RPCRateLimitedTotal:   3,
+	}
+	metrics.exportMetricEffectivePollInterval(&state)
+	metrics.exportMetricRPCRateLimited(&state)
+	assert.Equal(t, float64(10), gatherValue("solana_validator_ha_effective_poll_interval_seconds"))
+	assert.Equal(t, float64(3), gatherValue("solana_validator_ha_rpc_rate_limited_total"))
+
+	// the counter only grows by the increase in the running total
+	metrics.exportMetricRPCRateLimited(&state)
+	assert.Equal(t, float64(3), gatherValue("solana_validator_ha_rpc_rate_limited_total"))
+	state.RPCRateLimitedTotal = 5
+	metrics.exportMetricRPCRateLimited(&state)
+	assert.Equal(t, float64(5), gatherValue("solana_validator_ha_rpc_rate_limited_total"))
+}
+
+func TestExportMetricClusterRPCEndpoint(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	endpoints := func() []string {
+		metricsList, err := metrics.GetRegistry().Gather()
+		require.NoError(t, err)
+		endpoints := []string{}
+		for _, metricFamily := range metricsList {
+			if *metricFamily.Name != "solana_validator_ha_cluster_rpc_endpoint" {
+				continue
+			}
+			for _, metric := range metricFamily.Metric {
+				assert.Equal(t, float64(1), *metric.Gauge.Value)
+				for _, label := range metric.Label {
+					if *label.Name == rpcEndpointLabelName {
+						endpoints = append(endpoints, *label.Value)
+					}
+				}
+			}
+		}
+		return endpoints
+	}
+
+	state := cache.State{ValidatorName: "test-validator", PublicIP: "192.168.1.100", ClusterRPCEndpoint: "rpc1.example.com"}
+	metrics.exportMetricClusterRPCEndpoint(&state)
+	assert.Equal(t, []string{"rpc1.example.com"}, endpoints())
+
+	// failing over replaces the series
+	state.ClusterRPCEndpoint = "rpc2.example.com"
+	metrics.exportMetricClusterRPCEndpoint(&state)
+	assert.Equal(t, []string{"rpc2.example.com"}, endpoints())
+}
+
+func TestExportMetricConfigWarnings(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Warnings = []config.Warning{
+		{Code: config.WarningDryRunEnabled, Message: "dry run"},
+		{Code: config.WarningTakeoverJitterLow, Message: "jitter"},
+	}
+	metrics := New(Options{
+		Config: cfg,
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	gatherCodes := func() []string {
+		metricsList, err := metrics.GetRegistry().Gather()
+		require.NoError(t, err)
+		codes := []string{}
+		for _, metricFamily := range metricsList {
+			if *metricFamily.Name != "solana_validator_ha_config_warnings" {
+				continue
+			}
+			for _, metric := range metricFamily.Metric {
+				assert.Equal(t, float64(1), *metric.Gauge.Value)
+				codes = append(codes, getLabelValue(metric, "code"))
+			}
+		}
+		return codes
+	}
+
+	state := cache.State{ValidatorName: "test-validator", PublicIP: "192.168.1.100"}
+	metrics.exportMetricConfigWarnings(&state)
+	assert.ElementsMatch(t, []string{"dry_run_enabled", "takeover_jitter_low"}, gatherCodes())
+
+	// re-evaluated warnings replace previous series
+	cfg.Warnings = cfg.Warnings[:1]
+	metrics.exportMetricConfigWarnings(&state)
+	assert.ElementsMatch(t, []string{"dry_run_enabled"}, gatherCodes())
 }
 
 func TestGetRegistry(t *testing.T) {
@@ -523,14 +830,16 @@ func TestStartServer(t *testing.T) {
 	// Start server in a goroutine
 	serverErr := make(chan error, 1)
 	go func() {
-		serverErr <- metrics.StartServer(0) // Use port 0 for testing
+		serverErr <- metrics.StartServer("127.0.0.1", 0) // Use port 0 for testing
 	}()
 
 	// Give the server a moment to start
 	time.Sleep(100 * time.Millisecond)
 
 	// Verify server was created
+	metrics.serverMu.Lock()
 	assert.NotNil(t, metrics.server)
+	metrics.serverMu.Unlock()
 
 	// Stop the server
 	err := metrics.StopServer()
@@ -548,6 +857,112 @@ func TestStartServer(t *testing.T) {
 	}
 }
 
+func TestServe(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	// bound by the caller so a bind failure or hang is seen before serving
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- metrics.Serve(listener, nil)
+	}()
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/metrics")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, metrics.StopServer())
+	assert.ErrorIs(t, <-serverErr, http.ErrServerClosed)
+}
+
+func TestServe_TLS(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	// borrow httptest's certificate and a client trusting it
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	certServer.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- metrics.Serve(listener, &tls.Config{Certificates: certServer.TLS.Certificates})
+	}()
+
+	url := "https://" + listener.Addr().String() + "/metrics"
+	require.Eventually(t, func() bool {
+		resp, err := certServer.Client().Get(url)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	// plain http is refused
+	resp, err := http.Get("http://" + listener.Addr().String() + "/metrics")
+	if err == nil {
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	require.NoError(t, metrics.StopServer())
+	assert.ErrorIs(t, <-serverErr, http.ErrServerClosed)
+}
+
+func TestServe_BearerToken(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Prometheus.Auth = &config.Auth{BearerToken: "s3cr3t"}
+	metrics := New(Options{
+		Config: cfg,
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- metrics.Serve(listener, nil)
+	}()
+
+	scrape := func(token string) int {
+		req, err := http.NewRequest(http.MethodGet, "http://"+listener.Addr().String()+"/metrics", nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	require.Eventually(t, func() bool { return scrape("s3cr3t") == http.StatusOK }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, http.StatusUnauthorized, scrape(""))
+	assert.Equal(t, http.StatusUnauthorized, scrape("wrong"))
+
+	require.NoError(t, metrics.StopServer())
+	assert.ErrorIs(t, <-serverErr, http.ErrServerClosed)
+}
+
 func TestStartServer_WithInvalidPort(t *testing.T) {
 	cfg := createTestConfig()
 	cacheInstance := createTestCache()
@@ -562,10 +977,47 @@ func TestStartServer_WithInvalidPort(t *testing.T) {
 	metrics := New(opts)
 
 	// Try to start server with invalid port (negative)
-	err := metrics.StartServer(-1)
+	err := metrics.StartServer("127.0.0.1", -1)
 	assert.Error(t, err)
 }
 
+func TestStartServer_BindAddress(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	for _, bindAddress := range []string{"", "not-an-ip", "localhost", "127.0.0.1:9090"} {
+		err := metrics.StartServer(bindAddress, 0)
+		assert.ErrorContains(t, err, "invalid bind address", bindAddress)
+	}
+	assert.Nil(t, metrics.server)
+
+	// a free loopback port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- metrics.StartServer("127.0.0.1", port)
+	}()
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, metrics.StopServer())
+	assert.ErrorIs(t, <-serverErr, http.ErrServerClosed)
+}
+
 func TestStopServer_WhenNotStarted(t *testing.T) {
 	cfg := createTestConfig()
 	cacheInstance := createTestCache()
@@ -582,6 +1034,14 @@ func TestStopServer_WhenNotStarted(t *testing.T) {
 	// Stop server when not started
 	err := metrics.StopServer()
 	assert.NoError(t, err) // Should not error when server is nil
+	assert.NoError(t, metrics.ShutdownServer(context.Background()))
+
+	// a server stopped before it is served never takes the port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	assert.ErrorIs(t, metrics.Serve(listener, nil), http.ErrServerClosed)
+	_, err = net.Dial("tcp", listener.Addr().String())
+	assert.Error(t, err)
 }
 
 func TestMetrics_Integration(t *testing.T) {
@@ -606,8 +1066,8 @@ func TestMetrics_Integration(t *testing.T) {
 			Status:         "healthy",
 			PeerCount:      5,
 			SelfInGossip:   true,
-			FailoverStatus: "stable",
-			LastUpdated:    time.Now(),
+			FailoverStatus: "idle",
+			LastObserved:   time.Now(),
 		},
 		{
 			ValidatorName:  "test-validator",
@@ -617,7 +1077,7 @@ func TestMetrics_Integration(t *testing.T) {
 			PeerCount:      0,
 			SelfInGossip:   false,
 			FailoverStatus: "becoming_passive",
-			LastUpdated:    time.Now(),
+			LastObserved:   time.Now(),
 		},
 	}
 
@@ -673,8 +1133,8 @@ func TestMetrics_WithHTTPClient(t *testing.T) {
 		Status:         "healthy",
 		PeerCount:      5,
 		SelfInGossip:   true,
-		FailoverStatus: "stable",
-		LastUpdated:    time.Now(),
+		FailoverStatus: "idle",
+		LastObserved:   time.Now(),
 	}
 	cacheInstance.UpdateState(state)
 	metrics.RefreshMetrics()
@@ -682,18 +1142,21 @@ func TestMetrics_WithHTTPClient(t *testing.T) {
 	// Start server
 	serverErr := make(chan error, 1)
 	go func() {
-		serverErr <- metrics.StartServer(0) // Use port 0 for testing
+		serverErr <- metrics.StartServer("127.0.0.1", 0) // Use port 0 for testing
 	}()
 
 	// Give the server a moment to start
 	time.Sleep(100 * time.Millisecond)
 
 	// Get the actual port from the server
-	port := metrics.server.Addr[1:] // Remove the colon
+	metrics.serverMu.Lock()
+	server := metrics.server
+	metrics.serverMu.Unlock()
+	port := server.Addr[1:] // Remove the colon
 	if port == "0" {
 		// Port 0 means the OS assigned a random port, we can't test HTTP in this case
 		// Just verify the server started
-		assert.NotNil(t, metrics.server)
+		assert.NotNil(t, server)
 	} else {
 		// Test HTTP endpoint
 		resp, err := http.Get(fmt.Sprintf("http://localhost:%s/metrics", port))
@@ -745,8 +1208,8 @@ func TestMetrics_ConcurrentAccess(t *testing.T) {
 				Status:         "healthy",
 				PeerCount:      id,
 				SelfInGossip:   id%2 == 0,
-				FailoverStatus: "stable",
-				LastUpdated:    time.Now(),
+				FailoverStatus: "idle",
+				LastObserved:   time.Now(),
 			}
 			cacheInstance.UpdateState(state)
 			metrics.RefreshMetrics()
@@ -765,3 +1228,333 @@ func TestMetrics_ConcurrentAccess(t *testing.T) {
 	require.NoError(t, err)
 	assert.NotEmpty(t, metricsList)
 }
+
+func TestExportMetricLogWriteFailures(t *testing.T) {
+	cfg := createTestConfig()
+	broken := logwriter.New(logwriter.Options{Name: "stderr", Out: errorWriter{}})
+	cfg.Log.Sinks = []*logwriter.Writer{broken}
+	metrics := New(Options{
+		Config: cfg,
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	gatherFailures := func() float64 {
+		metricsList, err := metrics.GetRegistry().Gather()
+		require.NoError(t, err)
+		for _, metricFamily := range metricsList {
+			if *metricFamily.Name != "solana_validator_ha_log_write_failures_total" {
+				continue
+			}
+			require.Len(t, metricFamily.Metric, 1)
+			assert.Equal(t, "stderr", getLabelValue(metricFamily.Metric[0], "sink"))
+			return *metricFamily.Metric[0].Counter.Value
+		}
+		t.Fatal("metric solana_validator_ha_log_write_failures_total not found")
+		return 0
+	}
+
+	state := cache.State{ValidatorName: "test-validator", PublicIP: "192.168.1.100"}
+	metrics.exportMetricLogWriteFailures(&state)
+	assert.Equal(t, float64(0), gatherFailures())
+
+	// the counter only grows by the increase in the sink's running total
+	broken.Write([]byte("line\n"))
+	broken.Write([]byte("line\n"))
+	metrics.exportMetricLogWriteFailures(&state)
+	metrics.exportMetricLogWriteFailures(&state)
+	assert.Equal(t, float64(2), gatherFailures())
+}
+
+// errorWriter fails every write
+type errorWriter struct{}
+
+func (errorWriter) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("broken pipe")
+}
+
+func TestExportMetricFailovers(t *testing.T) {
+	c := createTestCache()
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  c,
+	})
+
+	failovers := func() map[string]float64 {
+		metricsList, err := metrics.GetRegistry().Gather()
+		require.NoError(t, err)
+		values := map[string]float64{}
+		for _, metricFamily := range metricsList {
+			if metricFamily.GetName() != "solana_validator_ha_failovers_total" {
+				continue
+			}
+			for _, metric := range metricFamily.Metric {
+				labels := map[string]string{}
+				for _, label := range metric.Label {
+					labels[label.GetName()] = label.GetValue()
+				}
+				values[labels["direction"]+"/"+labels["reason"]] = metric.Counter.GetValue()
+			}
+		}
+		return values
+	}
+	lastFailover := func() float64 {
+		metricsList, err := metrics.GetRegistry().Gather()
+		require.NoError(t, err)
+		for _, metricFamily := range metricsList {
+			if metricFamily.GetName() == "solana_validator_ha_last_failover_timestamp_seconds" {
+				return metricFamily.Metric[0].Gauge.GetValue()
+			}
+		}
+		t.Fatal("solana_validator_ha_last_failover_timestamp_seconds not found")
+		return 0
+	}
+
+	state := cache.State{ValidatorName: "test-validator", PublicIP: "192.168.1.100"}
+	c.UpdateState(state)
+	metrics.RefreshMetrics()
+	assert.Empty(t, failovers())
+	assert.Equal(t, float64(0), lastFailover())
+
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	state = state.WithFailover(constants.RoleActive, "no_active_peer", at)
+	c.UpdateState(state)
+
+	// refreshing repeatedly never counts a failover twice
+	metrics.RefreshMetrics()
+	metrics.RefreshMetrics()
+	assert.Equal(t, map[string]float64{"active/no_active_peer": 1}, failovers())
+	assert.Equal(t, float64(at.Unix()), lastFailover())
+
+	state = state.WithFailover(constants.RolePassive, "manual", at.Add(time.Minute))
+	state = state.WithFailover(constants.RoleActive, "no_active_peer", at.Add(2*time.Minute))
+	c.UpdateState(state)
+	metrics.RefreshMetrics()
+	metrics.RefreshMetrics()
+	assert.Equal(t, map[string]float64{"active/no_active_peer": 2, "passive/manual": 1}, failovers())
+	assert.Equal(t, float64(at.Add(2*time.Minute).Unix()), lastFailover())
+}
+
+func TestExportMetricPeers(t *testing.T) {
+	c := createTestCache()
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  c,
+	})
+
+	// peerGauges returns the values of a per-peer gauge by peer_name/peer_ip, peer_ip empty if not a label
+	peerGauges := func(name string) map[string]float64 {
+		metricsList, err := metrics.GetRegistry().Gather()
+		require.NoError(t, err)
+		values := map[string]float64{}
+		for _, metricFamily := range metricsList {
+			if metricFamily.GetName() != name {
+				continue
+			}
+			for _, metric := range metricFamily.Metric {
+				labels := map[string]string{}
+				for _, label := range metric.Label {
+					labels[label.GetName()] = label.GetValue()
+				}
+				values[labels["peer_name"]+"/"+labels["peer_ip"]] = metric.Gauge.GetValue()
+			}
+		}
+		return values
+	}
+
+	now := time.Now()
+	state := cache.State{
+		ValidatorName: "test-validator",
+		PublicIP:      "192.168.1.100",
+		Peers: map[string]cache.PeerVisibility{
+			"peer1": {IP: "192.168.1.101", InGossip: true, LastSeenAt: now, Heartbeat: &cache.PeerHeartbeat{Reachable: true, Role: constants.RoleActive, Slot: 100}},
+			"peer2": {IP: "192.168.1.102", LastSeenAt: now.Add(-30 * time.Second), Heartbeat: &cache.PeerHeartbeat{Role: constants.RoleActive}},
+			"peer3": {IP: "192.168.1.103"},
+		},
+	}
+	c.UpdateState(state)
+	metrics.RefreshMetrics()
+
+	assert.Equal(t, map[string]float64{"peer1/192.168.1.101": 1, "peer2/192.168.1.102": 0, "peer3/192.168.1.103": 0}, peerGauges("solana_validator_ha_peer_in_gossip"))
+	lastSeen := peerGauges("solana_validator_ha_peer_last_seen_seconds")
+	require.Len(t, lastSeen, 2, "peers never seen have no last seen series")
+	assert.InDelta(t, 0, lastSeen["peer1/"], 5)
+	assert.InDelta(t, 30, lastSeen["peer2/"], 5)
+
+	// heartbeats are only exported for peers with one, an unreachable peer's role and slot are not known
+	assert.Equal(t, map[string]float64{"peer1/": 1, "peer2/": 0}, peerGauges("solana_validator_ha_peer_heartbeat_reachable"))
+	assert.Equal(t, map[string]float64{"peer1/": 1, "peer2/": 0}, peerGauges("solana_validator_ha_peer_heartbeat_active"))
+	assert.Equal(t, map[string]float64{"peer1/": 100}, peerGauges("solana_validator_ha_peer_heartbeat_slot"))
+
+	// peers gone from the config have their series deleted, a changed ip replaces the old series
+	state.Peers = map[string]cache.PeerVisibility{
+		"peer1": {IP: "192.168.1.111", InGossip: true, LastSeenAt: now},
+	}
+	c.UpdateState(state)
+	metrics.RefreshMetrics()
+
+	assert.Equal(t, map[string]float64{"peer1/192.168.1.111": 1}, peerGauges("solana_validator_ha_peer_in_gossip"))
+	assert.Len(t, peerGauges("solana_validator_ha_peer_last_seen_seconds"), 1)
+	assert.Empty(t, peerGauges("solana_validator_ha_peer_heartbeat_reachable"))
+	assert.Empty(t, peerGauges("solana_validator_ha_peer_heartbeat_slot"))
+}
+
+func TestObserveHookAndRoleCommand(t *testing.T) {
+	c := createTestCache()
+	c.UpdateState(cache.State{ValidatorName: "test-validator", PublicIP: "192.168.1.100"})
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  c,
+	})
+
+	// executions returns the observation count, summed duration and failures of a metric pair by joined labels
+	type execution struct {
+		count    uint64
+		sum      float64
+		failures float64
+	}
+	executions := func(durationName string, failuresName string, labelNames ...string) map[string]execution {
+		metricsList, err := metrics.GetRegistry().Gather()
+		require.NoError(t, err)
+		key := func(metric *dto.Metric) string {
+			labels := map[string]string{}
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			values := []string{}
+			for _, labelName := range labelNames {
+				values = append(values, labels[labelName])
+			}
+			return strings.Join(values, "/")
+		}
+		values := map[string]execution{}
+		for _, metricFamily := range metricsList {
+			for _, metric := range metricFamily.Metric {
+				value := values[key(metric)]
+				switch metricFamily.GetName() {
+				case durationName:
+					value.count = metric.Histogram.GetSampleCount()
+					value.sum = metric.Histogram.GetSampleSum()
+				case failuresName:
+					value.failures = metric.Counter.GetValue()
+				default:
+					continue
+				}
+				values[key(metric)] = value
+			}
+		}
+		return values
+	}
+
+	metrics.ObserveHook("notify", "pre", "active", false, 2*time.Second, false)
+	metrics.ObserveHook("notify", "pre", "active", false, time.Second, true)
+	metrics.ObserveHook("notify", "post", "active", true, 0, false)
+	assert.Equal(t, map[string]execution{
+		"notify/pre/active/false": {count: 2, sum: 3, failures: 1},
+		"notify/post/active/true": {count: 1, sum: 0, failures: 0},
+	}, executions("solana_validator_ha_hook_duration_seconds", "solana_validator_ha_hook_failures_total", "hook_name", "phase", "role", "dry_run"))
+
+	metrics.ObserveRoleCommand("passive", false, 500*time.Millisecond, true)
+	metrics.ObserveRoleCommand("active", true, 0, false)
+	assert.Equal(t, map[string]execution{
+		"passive/false": {count: 1, sum: 0.5, failures: 1},
+		"active/true":   {count: 1, sum: 0, failures: 0},
+	}, executions("solana_validator_ha_role_command_duration_seconds", "solana_validator_ha_role_command_failures_total", "role", "dry_run"))
+}
+
+func TestObserveRPCRequest(t *testing.T) {
+	c := createTestCache()
+	c.UpdateState(cache.State{ValidatorName: "test-validator", PublicIP: "192.168.1.100"})
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  c,
+	})
+
+	metrics.ObserveRPCRequest("getClusterNodes", "api.mainnet-beta.solana.com", false, 300*time.Millisecond, nil)
+	metrics.ObserveRPCRequest("getClusterNodes", "api.mainnet-beta.solana.com", false, 5*time.Second, fmt.Errorf("context deadline exceeded"))
+	metrics.ObserveRPCRequest("getClusterNodes", "api.mainnet-beta.solana.com", true, 2*time.Second, fmt.Errorf("context deadline exceeded"))
+	metrics.ObserveRPCRequest("getIdentity", "127.0.0.1:8899", false, 10*time.Millisecond, nil)
+
+	metricsList, err := metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	counts := map[string]uint64{}
+	sums := map[string]float64{}
+	errors := map[string]float64{}
+	for _, metricFamily := range metricsList {
+		for _, metric := range metricFamily.Metric {
+			labels := map[string]string{}
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			key := labels["method"] + "@" + labels["endpoint"]
+			switch metricFamily.GetName() {
+			case "solana_validator_ha_rpc_request_duration_seconds":
+				counts[key] = metric.Histogram.GetSampleCount()
+				sums[key] = metric.Histogram.GetSampleSum()
+			case "solana_validator_ha_rpc_errors_total":
+				errors[key+" retried="+labels["retried"]] = metric.Counter.GetValue()
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]uint64{"getClusterNodes@api.mainnet-beta.solana.com": 3, "getIdentity@127.0.0.1:8899": 1}, counts)
+	assert.InDelta(t, 7.3, sums["getClusterNodes@api.mainnet-beta.solana.com"], 0.001)
+	assert.Equal(t, map[string]float64{
+		"getClusterNodes@api.mainnet-beta.solana.com retried=false": 1,
+		"getClusterNodes@api.mainnet-beta.solana.com retried=true":  1,
+		"getIdentity@127.0.0.1:8899 retried=false":                  0,
+	}, errors)
+}
+
+func TestExportMetricLeaderlessSamples(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Failover.LeaderlessSamplesThreshold = 3
+	metrics := New(Options{
+		Config: cfg,
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+
+	state := cache.State{
+		ValidatorName:     "test-validator",
+		PublicIP:          "192.168.1.100",
+		LeaderlessSamples: 2,
+	}
+	metrics.exportMetricLeaderlessSamples(&state)
+
+	metricsList, err := metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	values := map[string]float64{}
+	for _, metricFamily := range metricsList {
+		if len(metricFamily.Metric) == 1 && metricFamily.Metric[0].Gauge != nil {
+			values[metricFamily.GetName()] = metricFamily.Metric[0].Gauge.GetValue()
+		}
+	}
+	assert.Equal(t, float64(2), values["solana_validator_ha_leaderless_samples"])
+	assert.Equal(t, float64(3), values["solana_validator_ha_leaderless_samples_threshold"])
+}
+
+func TestExportMetricClusterRPCErrors(t *testing.T) {
+	metrics := New(Options{Config: createTestConfig(), Logger: createTestLogger(), Cache: createTestCache()})
+
+	state := cache.State{
+		ValidatorName:    "test-validator",
+		PublicIP:         "192.168.1.100",
+		ClusterRPCErrors: 4,
+	}
+	metrics.exportMetricClusterRPCErrors(&state)
+
+	metricsList, err := metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	var value float64
+	for _, metricFamily := range metricsList {
+		if metricFamily.GetName() == "solana_validator_ha_cluster_rpc_consecutive_errors" {
+			value = metricFamily.Metric[0].Gauge.GetValue()
+		}
+	}
+	assert.Equal(t, float64(4), value)
+}
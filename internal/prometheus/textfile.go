@@ -0,0 +1,17 @@
+package prometheus
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/cache"
+)
+
+// writeTextfile atomically writes the full registry to prometheus.textfile_path for node_exporter's
+// textfile collector. The temp file is written alongside the target without the .prom suffix and then
+// renamed over it so the collector never reads a partial file.
+func (m *Metrics) writeTextfile(state *cache.State) error {
+	m.textfileGeneratedTimestamp.With(m.getCommonLabels(state)).Set(float64(time.Now().Unix()))
+	return prometheus.WriteToTextfile(m.config.Prometheus.TextfilePath, m.registry)
+}
@@ -0,0 +1,127 @@
+package prometheus
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sol-strategies/solana-validator-ha/internal/cache"
+	"github.com/sol-strategies/solana-validator-ha/internal/constants"
+)
+
+// newTextfileMetrics returns metrics mirrored to a textfile in a temp dir
+func newTextfileMetrics(t *testing.T) (*Metrics, string) {
+	t.Helper()
+
+	cfg := createTestConfig()
+	cfg.Prometheus.TextfilePath = filepath.Join(t.TempDir(), "solana_validator_ha.prom")
+
+	cacheInstance := createTestCache()
+	cacheInstance.UpdateState(cache.State{
+		ValidatorName: "test-validator",
+		PublicIP:      "192.168.1.100",
+		Role:          constants.RoleActive,
+		Status:        constants.StatusHealthy,
+		PeerCount:     2,
+	})
+
+	metrics := New(Options{
+		Config: cfg,
+		Logger: createTestLogger(),
+		Cache:  cacheInstance,
+	})
+	return metrics, cfg.Prometheus.TextfilePath
+}
+
+func TestRefreshMetrics_WritesTextfile(t *testing.T) {
+	metrics, textfilePath := newTextfileMetrics(t)
+	metrics.RefreshMetrics()
+
+	f, err := os.Open(textfilePath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var parser expfmt.TextParser
+	metricFamilies, err := parser.TextToMetricFamilies(f)
+	require.NoError(t, err)
+
+	// the full registry is mirrored
+	assert.Contains(t, metricFamilies, "solana_validator_ha_metadata")
+	assert.Contains(t, metricFamilies, "solana_validator_ha_peer_count")
+	assert.Equal(t, float64(2), metricFamilies["solana_validator_ha_peer_count"].Metric[0].Gauge.GetValue())
+
+	// and stamped with when it was generated
+	require.Contains(t, metricFamilies, "solana_validator_ha_textfile_generated_timestamp_seconds")
+	generated := metricFamilies["solana_validator_ha_textfile_generated_timestamp_seconds"].Metric[0]
+	assert.Greater(t, generated.Gauge.GetValue(), float64(0))
+	assert.Equal(t, "test-validator", getLabelValue(generated, "validator_name"))
+
+	info, err := os.Stat(textfilePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+}
+
+func TestRefreshMetrics_WritesTextfileAtomically(t *testing.T) {
+	metrics, textfilePath := newTextfileMetrics(t)
+	metrics.RefreshMetrics()
+
+	// a reader racing the writer must only ever see a complete file
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	var reads, failures atomic.Int64
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for !stop.Load() {
+			f, err := os.Open(textfilePath)
+			if err != nil {
+				failures.Add(1)
+				continue
+			}
+			var parser expfmt.TextParser
+			metricFamilies, err := parser.TextToMetricFamilies(f)
+			f.Close()
+			if err != nil || len(metricFamilies) == 0 {
+				failures.Add(1)
+			}
+			reads.Add(1)
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		metrics.RefreshMetrics()
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	assert.Greater(t, reads.Load(), int64(0))
+	assert.Equal(t, int64(0), failures.Load())
+
+	// no temp files are left behind and none end in .prom for the collector to pick up
+	entries, err := os.ReadDir(filepath.Dir(textfilePath))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, filepath.Base(textfilePath), entries[0].Name())
+}
+
+func TestRefreshMetrics_NoTextfileWhenUnset(t *testing.T) {
+	metrics := New(Options{
+		Config: createTestConfig(),
+		Logger: createTestLogger(),
+		Cache:  createTestCache(),
+	})
+	metrics.RefreshMetrics()
+
+	// the generated timestamp is only exported when a textfile is written
+	metricFamilies, err := metrics.GetRegistry().Gather()
+	require.NoError(t, err)
+	for _, metricFamily := range metricFamilies {
+		assert.NotEqual(t, "solana_validator_ha_textfile_generated_timestamp_seconds", metricFamily.GetName())
+	}
+}
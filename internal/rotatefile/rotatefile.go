@@ -0,0 +1,243 @@
+package rotatefile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// File is an append-only file rotated by size. Once a write would take it past MaxBytes it is renamed to
+// <path>.1, shifting earlier rotations up to <path>.<MaxBackups> and removing the oldest beyond that, and a new
+// file is started. A single write larger than MaxBytes is never split. Writes, rotation and reopening are
+// serialized so it is safe for concurrent use.
+type File struct {
+	mu         sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+	file       *os.File
+	size       int64
+}
+
+// Options are the options for opening a rotated file
+type Options struct {
+	// Path is the file written to
+	Path string
+	// MaxBytes is the size the file may grow to before it is rotated
+	MaxBytes int64
+	// MaxBackups is the number of rotated files kept, 0 keeps none
+	MaxBackups int
+	// MaxAge removes rotated files last written longer ago than it on rotation, 0 keeps them however old
+	MaxAge time.Duration
+	// Compress gzips rotated files to <path>.<n>.gz
+	Compress bool
+}
+
+// Open opens opts.Path for appending, creating it and its directory if needed
+func Open(opts Options) (*File, error) {
+	f := &File{
+		path:       opts.Path,
+		maxBytes:   opts.MaxBytes,
+		maxBackups: opts.MaxBackups,
+		maxAge:     opts.MaxAge,
+		compress:   opts.Compress,
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create %s directory: %w", f.path, err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Path returns the file written to
+func (f *File) Path() string {
+	return f.path
+}
+
+// Write appends p, rotating first if it would take the file past its max size
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.size > 0 && f.size+int64(len(p)) > f.maxBytes {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Reopen closes and reopens the file at its path, so one moved away by an external logrotate is recreated
+func (f *File) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	return f.open()
+}
+
+// ReopenOnSIGHUP reopens the file every time the process receives SIGHUP until the returned stop is called,
+// onError is called with any failure to reopen it
+func (f *File) ReopenOnSIGHUP(onError func(err error)) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if err := f.Reopen(); err != nil {
+					onError(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the file for appending and reads its size - caller must hold the lock
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat %s: %w", f.path, err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files up, moves the file to <path>.1 and starts a new one - caller must hold the lock
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	if f.maxBackups <= 0 {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return f.open()
+	}
+
+	// the oldest is dropped by being renamed over
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(f.backup(i), f.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if f.compress {
+		if err := compressFile(f.path, f.backup(1)); err != nil {
+			return err
+		}
+	} else if err := os.Rename(f.path, f.backup(1)); err != nil {
+		return err
+	}
+	f.removeExpiredBackups()
+	return f.open()
+}
+
+// removeExpiredBackups removes the rotated files last written longer than MaxAge ago - caller must hold the lock
+func (f *File) removeExpiredBackups() {
+	if f.maxAge <= 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(-f.maxAge)
+	for i := 1; i <= f.maxBackups; i++ {
+		if info, err := os.Stat(f.backup(i)); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(f.backup(i))
+		}
+	}
+}
+
+// backup returns the path of the nth most recent rotated file
+func (f *File) backup(n int) string {
+	if f.compress {
+		return fmt.Sprintf("%s.%d.gz", f.path, n)
+	}
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
+
+// compressFile gzips src to dst through a temporary file, removing src once dst is complete
+func compressFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compress %s: %w", src, err)
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
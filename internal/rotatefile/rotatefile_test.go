@@ -0,0 +1,191 @@
+package rotatefile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readFile returns the contents of path, empty if it doesn't exist
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestFile_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "decisions.jsonl")
+	f, err := Open(Options{Path: path, MaxBytes: 10, MaxBackups: 2})
+	require.NoError(t, err)
+	defer f.Close()
+
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n", "ffff\n", "gggg\n"} {
+		n, err := f.Write([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, len(line), n)
+	}
+
+	assert.Equal(t, "gggg\n", readFile(t, path))
+	assert.Equal(t, "eeee\nffff\n", readFile(t, path+".1"))
+	assert.Equal(t, "cccc\ndddd\n", readFile(t, path+".2"))
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestFile_AppendsToExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("aaaaaaaa\n"), 0o640))
+
+	// the existing size counts towards the first rotation
+	f, err := Open(Options{Path: path, MaxBytes: 10, MaxBackups: 1})
+	require.NoError(t, err)
+	_, err = f.Write([]byte("bbbb\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	assert.Equal(t, "bbbb\n", readFile(t, path))
+	assert.Equal(t, "aaaaaaaa\n", readFile(t, path+".1"))
+}
+
+func TestFile_NoBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	f, err := Open(Options{Path: path, MaxBytes: 4})
+	require.NoError(t, err)
+	defer f.Close()
+
+	// a write larger than the max size is never split
+	_, err = f.Write([]byte("aaaaaa\n"))
+	require.NoError(t, err)
+	_, err = f.Write([]byte("bb\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "bb\n", readFile(t, path))
+	assert.NoFileExists(t, path+".1")
+}
+
+func TestFile_WriteAfterClose(t *testing.T) {
+	f, err := Open(Options{Path: filepath.Join(t.TempDir(), "decisions.jsonl"), MaxBytes: 10})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = f.Write([]byte("a\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestFile_Compress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	f, err := Open(Options{Path: path, MaxBytes: 10, MaxBackups: 2, Compress: true})
+	require.NoError(t, err)
+	defer f.Close()
+
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "eeee\n", readFile(t, path))
+	assert.Equal(t, "cccc\ndddd\n", readGzipFile(t, path+".1.gz"))
+	assert.Equal(t, "aaaa\nbbbb\n", readGzipFile(t, path+".2.gz"))
+	assert.NoFileExists(t, path+".1")
+	assert.NoFileExists(t, path+".1.gz.tmp")
+}
+
+func TestFile_MaxAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	require.NoError(t, os.WriteFile(path+".2", []byte("old\n"), 0o640))
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(path+".2", old, old))
+
+	f, err := Open(Options{Path: path, MaxBytes: 4, MaxBackups: 3, MaxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	defer f.Close()
+	for _, line := range []string{"aaaa\n", "bbbb\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	// shifted to .3 on rotation and removed as expired, the fresh rotation is kept
+	assert.Equal(t, "aaaa\n", readFile(t, path+".1"))
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestFile_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	f, err := Open(Options{Path: path, MaxBytes: 1024})
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Write([]byte("before\n"))
+	require.NoError(t, err)
+
+	// an external logrotate moves the file away and asks us to reopen it
+	require.NoError(t, os.Rename(path, path+".rotated"))
+	require.NoError(t, f.Reopen())
+	_, err = f.Write([]byte("after\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "before\n", readFile(t, path+".rotated"))
+	assert.Equal(t, "after\n", readFile(t, path))
+
+	require.NoError(t, f.Close())
+	assert.ErrorIs(t, f.Reopen(), os.ErrClosed)
+}
+
+func TestFile_ConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.log")
+	f, err := Open(Options{Path: path, MaxBytes: 256, MaxBackups: 100})
+	require.NoError(t, err)
+	defer f.Close()
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				_, err := f.Write([]byte(fmt.Sprintf("writer-%d\n", i)))
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// every line lands whole in exactly one file
+	lines := strings.Split(strings.TrimSpace(readFile(t, path)), "\n")
+	for i := 1; i <= 100; i++ {
+		if content := readFile(t, fmt.Sprintf("%s.%d", path, i)); content != "" {
+			lines = append(lines, strings.Split(strings.TrimSpace(content), "\n")...)
+		}
+	}
+	assert.Len(t, lines, 200)
+	for _, line := range lines {
+		assert.Regexp(t, `^writer-\d$`, line)
+	}
+}
+
+// readGzipFile returns the decompressed contents of path
+func readGzipFile(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return string(data)
+}
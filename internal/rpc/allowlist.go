@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// allowedMethods are the read-only JSON-RPC methods the agent is allowed to call - getBalance is how gossip state
+// forgives a delinquent active whose identity has fallen below the rent-exempt minimum
+var allowedMethods = map[string]bool{
+	"getBalance":         true,
+	"getBlockProduction": true,
+	"getClusterNodes":    true,
+	"getEpochInfo":       true,
+	"getHealth":          true,
+	"getIdentity":        true,
+	"getLeaderSchedule":  true,
+	"getSlot":            true,
+	"getVersion":         true,
+	"getVoteAccounts":    true,
+}
+
+// AllowedMethods returns the sorted list of JSON-RPC methods the agent is allowed to call
+func AllowedMethods() []string {
+	methods := make([]string, 0, len(allowedMethods))
+	for method := range allowedMethods {
+		methods = append(methods, method)
+	}
+	slices.Sort(methods)
+	return methods
+}
+
+// checkMethodAllowed returns an error for methods not in the allowlist
+func checkMethodAllowed(method string) error {
+	if allowedMethods[method] {
+		return nil
+	}
+	return fmt.Errorf("rpc method %q is not in the allowlist %v", method, AllowedMethods())
+}
+
+// allowlistRPCClient wraps a JSON-RPC client rejecting any method not in the allowlist before it
+// reaches the wire
+type allowlistRPCClient struct {
+	next rpc.JSONRPCClient
+}
+
+// newAllowlistRPCClient wraps next with the method allowlist
+func newAllowlistRPCClient(next rpc.JSONRPCClient) *allowlistRPCClient {
+	return &allowlistRPCClient{next: next}
+}
+
+// CallForInto implements rpc.JSONRPCClient
+func (c *allowlistRPCClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	if err := checkMethodAllowed(method); err != nil {
+		return err
+	}
+	return c.next.CallForInto(ctx, out, method, params)
+}
+
+// CallWithCallback implements rpc.JSONRPCClient
+func (c *allowlistRPCClient) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	if err := checkMethodAllowed(method); err != nil {
+		return err
+	}
+	return c.next.CallWithCallback(ctx, method, params, callback)
+}
+
+// CallBatch implements rpc.JSONRPCClient
+func (c *allowlistRPCClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	for _, request := range requests {
+		if err := checkMethodAllowed(request.Method); err != nil {
+			return nil, err
+		}
+	}
+	return c.next.CallBatch(ctx, requests)
+}
@@ -0,0 +1,103 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowedMethods(t *testing.T) {
+	assert.Equal(t, []string{
+		"getBalance",
+		"getBlockProduction",
+		"getClusterNodes",
+		"getEpochInfo",
+		"getHealth",
+		"getIdentity",
+		"getLeaderSchedule",
+		"getSlot",
+		"getVersion",
+		"getVoteAccounts",
+	}, AllowedMethods())
+}
+
+func TestCheckMethodAllowed(t *testing.T) {
+	for _, method := range AllowedMethods() {
+		assert.NoError(t, checkMethodAllowed(method), method)
+	}
+
+	assert.EqualError(t, checkMethodAllowed("sendTransaction"),
+		`rpc method "sendTransaction" is not in the allowlist [getBalance getBlockProduction getClusterNodes getEpochInfo getHealth getIdentity getLeaderSchedule getSlot getVersion getVoteAccounts]`,
+	)
+}
+
+func TestClient_DisallowedMethodNeverReachesTheWire(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("test", server.URL)
+	_, err := client.clients[server.URL].RequestAirdrop(context.Background(), solana.PublicKey{}, 1, "")
+	assert.ErrorContains(t, err, `rpc method "requestAirdrop" is not in the allowlist`)
+	_, err = client.clients[server.URL].GetAccountInfo(context.Background(), solana.PublicKey{})
+	assert.ErrorContains(t, err, `rpc method "getAccountInfo" is not in the allowlist`)
+	assert.Equal(t, int32(0), requests.Load())
+}
+
+func TestClient_EveryCallSiteIsAllowed(t *testing.T) {
+	var mu sync.Mutex
+	called := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		mu.Lock()
+		called[request.Method] = true
+		mu.Unlock()
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, request.ID, offlineResults[request.Method])
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient("test", server.URL)
+	ctx := context.Background()
+	calls := map[string]func() error{
+		"GetClusterNodes": func() error { _, err := client.GetClusterNodes(ctx); return err },
+		"GetIdentity":     func() error { _, err := client.GetIdentity(ctx); return err },
+		"GetHealth":       func() error { _, err := client.GetHealth(ctx); return err },
+		"GetVoteAccounts": func() error { _, err := client.GetVoteAccounts(ctx); return err },
+		"GetSlot":         func() error { _, err := client.GetSlot(ctx); return err },
+		"GetBalance":      func() error { _, err := client.GetBalance(ctx, solana.PublicKey{}); return err },
+	}
+
+	// every method of SolanaClient sending a request is called, so a new one can't skip the allowlist unnoticed
+	solanaClient := reflect.TypeFor[SolanaClient]()
+	for i := range solanaClient.NumMethod() {
+		name := solanaClient.Method(i).Name
+		if name == "RateLimits" || name == "ActiveEndpoint" {
+			continue
+		}
+		call, ok := calls[name]
+		require.True(t, ok, "SolanaClient.%s is not called against the allowlist", name)
+		assert.NoError(t, call(), name)
+	}
+
+	for method := range called {
+		assert.True(t, allowedMethods[method], method)
+	}
+	assert.Len(t, called, len(calls))
+}
@@ -2,42 +2,149 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
+	"net/http"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
 )
 
-// Client represents an RPC client that can handle multiple URLs
+// DefaultPreferredURLCooldown is how long a client that failed over from its first url waits before trying it again
+const DefaultPreferredURLCooldown = 5 * time.Minute
+
+// SolanaClient is the Solana rpc the agent calls, implemented by Client and faked in tests
+type SolanaClient interface {
+	GetClusterNodes(ctx context.Context) ([]*rpc.GetClusterNodesResult, error)
+	GetIdentity(ctx context.Context) (*rpc.GetIdentityResult, error)
+	GetHealth(ctx context.Context) (string, error)
+	GetVoteAccounts(ctx context.Context) (*rpc.GetVoteAccountsResult, error)
+	GetSlot(ctx context.Context) (uint64, error)
+	GetBalance(ctx context.Context, pubkey solana.PublicKey) (*rpc.GetBalanceResult, error)
+	// RateLimits returns the tracker of rate limited responses the adaptive poll interval follows
+	RateLimits() *RateLimitTracker
+	// ActiveEndpoint returns the host of the endpoint requests are sent to first
+	ActiveEndpoint() string
+}
+
+// Client represents an RPC client that fails over between multiple URLs, the first being preferred
 type Client struct {
-	// urls is a slice of URLs for load balancing
+	// urls are tried in order when the active one fails
 	urls []string
 	// clients is a map of RPC clients, keyed by the rpc URL
 	clients map[string]*rpc.Client
-	// lastSuccessfulURL tracks the last URL that succeeded to avoid it for throttling protection
-	lastSuccessfulURL string
-	timeout           time.Duration
-	logger            *log.Logger
+	timeout time.Duration
+	logger  *log.Logger
+	// rateLimits tracks HTTP 429 responses across all URLs
+	rateLimits *RateLimitTracker
+	// preferredURLCooldown is how long after failing over from the preferred url it is tried again
+	preferredURLCooldown time.Duration
+	now                  func() time.Time
+	// retry is how requests of retryable methods are retried while every url fails transiently
+	retry RetryOptions
+
+	mu sync.Mutex
+	// activeURL is the url that last succeeded, tried first until it fails
+	activeURL string
+	// preferredRetryAt is when the preferred url is next tried ahead of a different active url
+	preferredRetryAt time.Time
+}
+
+// RetryOptions configures how a client retries getClusterNodes, getIdentity and getHealth while every url fails
+// transiently - JSON-RPC errors, e.g. an unhealthy node, are answers and never retried
+type RetryOptions struct {
+	// Retries is how many more times a request is sent, 0 never retries
+	Retries int
+	// InitialBackoff is the delay before the first retry, doubling for each one after up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter is the fraction of each backoff randomized either way, between 0 and 1
+	Jitter float64
+	// Budget bounds the total time of a request and its retries, e.g. to the poll interval - a retry whose
+	// backoff would overrun it isn't sent. 0 is unbounded
+	Budget time.Duration
+}
+
+// DefaultMaxIdleConnsPerHost is how many idle keep-alive connections the transport keeps to each rpc host, enough
+// for the cluster and quorum clients sampling the same host in one poll
+const DefaultMaxIdleConnsPerHost = 4
+
+// sharedTransport is the transport of clients created by NewClient
+var sharedTransport = NewTransport()
+
+// NewTransport returns a keep-alive transport for clients to share, so polls reuse connections to the rpc hosts
+// rather than handshaking each time - create it once and pass it to every client
+func NewTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	return transport
 }
 
-// NewClient creates a new RPC client with one or more URLs
+// NewClient creates a new RPC client with one or more URLs - only allowlisted methods can be called
 func NewClient(logPrefix string, urls ...string) *Client {
+	return newClient(logPrefix, func(url string, rateLimits *RateLimitTracker) rpc.JSONRPCClient {
+		return newJSONRPCClient(url, sharedTransport, rateLimits)
+	}, urls...)
+}
+
+// NewObservedClient creates a new RPC client like NewClient sending its requests over transport, reporting every
+// request it sends to observe
+func NewObservedClient(logPrefix string, transport http.RoundTripper, observe RequestObserver, urls ...string) *Client {
+	return newClient(logPrefix, func(url string, rateLimits *RateLimitTracker) rpc.JSONRPCClient {
+		return newObservedRPCClient(newJSONRPCClient(url, transport, rateLimits), url, observe)
+	}, urls...)
+}
+
+// newJSONRPCClient returns the JSON-RPC client for url sending its requests over transport, tracking its rate
+// limited responses in rateLimits
+func newJSONRPCClient(url string, transport http.RoundTripper, rateLimits *RateLimitTracker) rpc.JSONRPCClient {
+	return jsonrpc.NewClientWithOpts(url, &jsonrpc.RPCClientOpts{
+		HTTPClient: &http.Client{
+			Transport: &rateLimitTransport{next: transport, tracker: rateLimits},
+		},
+	})
+}
+
+// newClient creates a new RPC client with one or more URLs using newJSONRPCClient for the transport of each
+func newClient(logPrefix string, newJSONRPCClient func(url string, rateLimits *RateLimitTracker) rpc.JSONRPCClient, urls ...string) *Client {
+	rateLimits := NewRateLimitTracker(DefaultRateLimitWindow)
 	clients := make(map[string]*rpc.Client)
 	for _, url := range urls {
-		clients[url] = rpc.New(url)
+		clients[url] = rpc.NewWithCustomRPCClient(newAllowlistRPCClient(newJSONRPCClient(url, rateLimits)))
 	}
-	return &Client{
-		logger:            log.WithPrefix(fmt.Sprintf("[%s rpc_client]", logPrefix)),
-		urls:              urls,
-		clients:           clients,
-		lastSuccessfulURL: "",
-		timeout:           5 * time.Second, // Default timeout
+	client := &Client{
+		logger:               loglevel.WithPrefix(loglevel.RPC, fmt.Sprintf("[%s rpc_client]", logPrefix)),
+		urls:                 urls,
+		clients:              clients,
+		timeout:              5 * time.Second, // Default timeout
+		rateLimits:           rateLimits,
+		preferredURLCooldown: DefaultPreferredURLCooldown,
+		now:                  time.Now,
 	}
+	if len(urls) > 0 {
+		client.activeURL = urls[0]
+	}
+	return client
+}
+
+// SetRetryOptions sets how the client retries its retryable methods, it never retries until set
+func (c *Client) SetRetryOptions(opts RetryOptions) {
+	c.retry = opts
+}
+
+// RateLimits returns the client's rate limit tracker
+func (c *Client) RateLimits() *RateLimitTracker {
+	return c.rateLimits
 }
 
 // withTimeout executes a function with the client's timeout
@@ -49,38 +156,113 @@ func (c *Client) withTimeout(ctx context.Context, fn func(context.Context) error
 
 // rpcOperation represents a generic RPC operation
 type rpcOperation[T any] struct {
-	name    string
-	execute func(*rpc.Client, context.Context) (T, error)
+	name string
+	// retryable operations are retried with the client's RetryOptions
+	retryable bool
+	execute   func(*rpc.Client, context.Context) (T, error)
+}
+
+// ActiveEndpoint returns the host of the url requests are sent to first
+func (c *Client) ActiveEndpoint() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return endpointHost(c.activeURL)
 }
 
-// getURLsToTry returns URLs to try with lastSuccessfulURL at the end for throttling protection
+// getURLsToTry returns the active url first and the others in configured order after it - once the cooldown since
+// failing over from the preferred url has passed, it is tried again ahead of the active one
 func (c *Client) getURLsToTry() []string {
-	if len(c.urls) <= 1 || c.lastSuccessfulURL == "" {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.urls) <= 1 || c.activeURL == "" || c.activeURL == c.urls[0] {
 		return c.urls
 	}
 
-	// Build list with lastSuccessfulURL at the end
 	urlsToTry := make([]string, 0, len(c.urls))
-
-	// Add all URLs except lastSuccessfulURL first
+	if !c.now().Before(c.preferredRetryAt) {
+		urlsToTry = append(urlsToTry, c.urls[0])
+	}
+	urlsToTry = append(urlsToTry, c.activeURL)
 	for _, url := range c.urls {
-		if url != c.lastSuccessfulURL {
+		if !slices.Contains(urlsToTry, url) {
 			urlsToTry = append(urlsToTry, url)
 		}
 	}
 
-	// Add lastSuccessfulURL at the end (as fallback)
-	urlsToTry = append(urlsToTry, c.lastSuccessfulURL)
-
 	return urlsToTry
 }
 
-// executeWithRetry executes an RPC method, trying URLs in throttling-optimized order
+// useURL makes url, which just succeeded, the active url - while it isn't the preferred one, the preferred is
+// tried again once a cooldown from failing over, or from its last failed retry, has passed
+func (c *Client) useURL(method string, url string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if url != c.urls[0] && !now.Before(c.preferredRetryAt) {
+		c.preferredRetryAt = now.Add(c.preferredURLCooldown)
+	}
+	if url == c.activeURL {
+		return
+	}
+
+	c.logger.Info("switched rpc endpoint",
+		"method", method,
+		"from", endpointHost(c.activeURL),
+		"to", endpointHost(url),
+		"preferred", url == c.urls[0],
+	)
+	c.activeURL = url
+}
+
+// executeWithRetry executes an RPC method, failing over to the other URLs in order while they error - retryable
+// operations make further passes over the URLs with backoff while they fail transiently, within the retry budget
 func executeWithRetry[T any](c *Client, ctx context.Context, op rpcOperation[T]) (T, error) {
+	retries := 0
+	if op.retryable {
+		retries = c.retry.Retries
+	}
+	if retries > 0 && c.retry.Budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.retry.Budget)
+		defer cancel()
+	}
+
+	var zero T
+	for attempt := 0; ; attempt++ {
+		result, transient, err := tryURLs(c, withRetried(ctx, attempt > 0), op)
+		if err == nil {
+			return result, nil
+		}
+		if attempt >= retries || !transient || ctx.Err() != nil {
+			return zero, err
+		}
+
+		backoff := c.backoff(attempt + 1)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			c.logger.Debug("no time left to retry method call", "method", op.name, "backoff", backoff, "error", err)
+			return zero, err
+		}
+		c.logger.Debug("retrying method call", "method", op.name, "retry", attempt+1, "backoff", backoff, "error", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, err
+		case <-timer.C:
+		}
+	}
+}
+
+// tryURLs makes one pass of an RPC method over the URLs, the active one first, until one succeeds - transient
+// reports whether any URL failed other than with a JSON-RPC error
+func tryURLs[T any](c *Client, ctx context.Context, op rpcOperation[T]) (result T, transient bool, err error) {
 	attemptedURLs := []string{}
-	errors := []error{}
+	errs := []error{}
 
-	// try each URL in order, with lastSuccessfulURL at the end for throttling protection
+	// try the active URL first, then each of the others in order
 	for _, url := range c.getURLsToTry() {
 		client, exists := c.clients[url]
 		if !exists {
@@ -89,7 +271,6 @@ func executeWithRetry[T any](c *Client, ctx context.Context, op rpcOperation[T])
 
 		attemptedURLs = append(attemptedURLs, url)
 
-		var result T
 		err := c.withTimeout(ctx, func(timeoutCtx context.Context) error {
 			var err error
 			result, err = op.execute(client, timeoutCtx)
@@ -98,17 +279,37 @@ func executeWithRetry[T any](c *Client, ctx context.Context, op rpcOperation[T])
 
 		if err != nil {
 			c.logger.Debug("method call failed", "method", op.name, "error", err, "rpc_url", url)
-			errors = append(errors, err)
+			errs = append(errs, err)
+			var rpcErr *jsonrpc.RPCError
+			if !errors.As(err, &rpcErr) {
+				transient = true
+			}
 			continue
 		}
 
-		// Success! Update the last successful URL
-		c.lastSuccessfulURL = url
-		return result, nil
+		// Success! Stick with this URL until it fails
+		c.useURL(op.name, url)
+		return result, false, nil
 	}
 
 	var zero T
-	return zero, fmt.Errorf("method call failed on all RPC endpoints method: %s, attempted_urls: %v, errors: %v", op.name, attemptedURLs, errors)
+	return zero, transient, fmt.Errorf("method call failed on all RPC endpoints method: %s, attempted_urls: %v, errors: %v", op.name, attemptedURLs, errs)
+}
+
+// backoff returns the delay before the given retry, doubling from InitialBackoff up to MaxBackoff and randomized
+// by Jitter either way
+func (c *Client) backoff(retry int) time.Duration {
+	delay := c.retry.InitialBackoff
+	for i := 1; i < retry && delay < c.retry.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if c.retry.MaxBackoff > 0 {
+		delay = min(delay, c.retry.MaxBackoff)
+	}
+	if c.retry.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * c.retry.Jitter * float64(delay))
+	}
+	return delay
 }
 
 // GetSlot gets the current slot from the first working RPC client
@@ -151,27 +352,56 @@ func (c *Client) GetBalance(ctx context.Context, pubkey solana.PublicKey) (*rpc.
 // GetClusterNodes tries each RPC client in order and returns the first successful response
 func (c *Client) GetClusterNodes(ctx context.Context) ([]*rpc.GetClusterNodesResult, error) {
 	return executeWithRetry(c, ctx, rpcOperation[[]*rpc.GetClusterNodesResult]{
-		name: "GetClusterNodes",
+		name:      "GetClusterNodes",
+		retryable: true,
 		execute: func(client *rpc.Client, ctx context.Context) ([]*rpc.GetClusterNodesResult, error) {
 			return client.GetClusterNodes(ctx)
 		},
 	})
 }
 
-// GetIdentity gets the identity from the first working RPC client
+// GetIdentity gets the identity from the first working RPC client - as agave's {"identity": <pubkey>} or the bare
+// pubkey firedancer may report it as
 func (c *Client) GetIdentity(ctx context.Context) (*rpc.GetIdentityResult, error) {
 	return executeWithRetry(c, ctx, rpcOperation[*rpc.GetIdentityResult]{
-		name: "GetIdentity",
+		name:      "GetIdentity",
+		retryable: true,
 		execute: func(client *rpc.Client, ctx context.Context) (*rpc.GetIdentityResult, error) {
-			return client.GetIdentity(ctx)
+			var identity identityResult
+			if err := client.RPCCallForInto(ctx, &identity, "getIdentity", nil); err != nil {
+				return nil, err
+			}
+			return &rpc.GetIdentityResult{Identity: identity.pubkey}, nil
 		},
 	})
 }
 
+// identityResult is a getIdentity result in either shape the validator clients report it in
+type identityResult struct {
+	pubkey solana.PublicKey
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (r *identityResult) UnmarshalJSON(data []byte) error {
+	var pubkey solana.PublicKey
+	if err := json.Unmarshal(data, &pubkey); err == nil {
+		r.pubkey = pubkey
+		return nil
+	}
+
+	var result rpc.GetIdentityResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("getIdentity result is neither {\"identity\": <pubkey>} nor a pubkey: %w", err)
+	}
+	r.pubkey = result.Identity
+	return nil
+}
+
 // GetHealth gets the health from the first working RPC client
 func (c *Client) GetHealth(ctx context.Context) (string, error) {
 	result, err := executeWithRetry(c, ctx, rpcOperation[string]{
-		name: "GetHealth",
+		name:      "GetHealth",
+		retryable: true,
 		execute: func(client *rpc.Client, ctx context.Context) (string, error) {
 			return client.GetHealth(ctx)
 		},
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -130,6 +132,33 @@ func TestNewClient(t *testing.T) {
 	assert.Equal(t, 5*time.Second, client.timeout)
 }
 
+func TestClientsShareConnections(t *testing.T) {
+	server := httptest.NewUnstartedServer(mockSolanaRPCServer(t, map[string]interface{}{"getHealth": "ok"}).Config.Handler)
+	var connections atomic.Int32
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	// polls of every client over the same transport reuse one keep-alive connection
+	transport := NewTransport()
+	observe := func(string, string, bool, time.Duration, error) {}
+	clients := []*Client{
+		NewObservedClient("test", transport, observe, server.URL),
+		NewObservedClient("test", transport, observe, server.URL),
+	}
+	for i := 0; i < 3; i++ {
+		for _, client := range clients {
+			_, err := client.GetHealth(context.Background())
+			require.NoError(t, err)
+		}
+	}
+	assert.Equal(t, int32(1), connections.Load())
+}
+
 func TestGetClusterNodes(t *testing.T) {
 	// Mock response for GetClusterNodes
 	mockResponse := []map[string]interface{}{
@@ -193,6 +222,23 @@ func TestGetIdentity(t *testing.T) {
 	assert.Equal(t, "11111111111111111111111111111111", result.Identity.String())
 }
 
+func TestGetIdentity_BarePubkey(t *testing.T) {
+	// firedancer may report the identity as a bare pubkey
+	server := mockSolanaRPCServer(t, map[string]interface{}{
+		"getIdentity": "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA",
+	})
+
+	result, err := NewClient("test", server.URL).GetIdentity(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA", result.Identity.String())
+
+	server = mockSolanaRPCServer(t, map[string]interface{}{
+		"getIdentity": []string{"TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"},
+	})
+	_, err = NewClient("test", server.URL).GetIdentity(context.Background())
+	assert.ErrorContains(t, err, "getIdentity result is neither")
+}
+
 func TestGetHealth(t *testing.T) {
 	// Mock response for GetHealth
 	mockResponse := "ok"
@@ -392,48 +438,55 @@ func TestComplexClusterNodesResponse(t *testing.T) {
 }
 
 func TestGetURLsToTry(t *testing.T) {
+	now := time.Now()
 	tests := []struct {
-		name              string
-		urls              []string
-		lastSuccessfulURL string
-		expected          []string
+		name             string
+		urls             []string
+		activeURL        string
+		preferredRetryAt time.Time
+		expected         []string
 	}{
 		{
-			name:              "single URL",
-			urls:              []string{"url1"},
-			lastSuccessfulURL: "",
-			expected:          []string{"url1"},
+			name:      "single URL",
+			urls:      []string{"url1"},
+			activeURL: "url1",
+			expected:  []string{"url1"},
 		},
 		{
-			name:              "no last successful URL",
-			urls:              []string{"url1", "url2", "url3"},
-			lastSuccessfulURL: "",
-			expected:          []string{"url1", "url2", "url3"},
+			name:      "preferred URL active",
+			urls:      []string{"url1", "url2", "url3"},
+			activeURL: "url1",
+			expected:  []string{"url1", "url2", "url3"},
 		},
 		{
-			name:              "with last successful URL",
-			urls:              []string{"url1", "url2", "url3"},
-			lastSuccessfulURL: "url2",
-			expected:          []string{"url1", "url3", "url2"},
+			name:             "failed over within the cooldown",
+			urls:             []string{"url1", "url2", "url3"},
+			activeURL:        "url2",
+			preferredRetryAt: now.Add(time.Minute),
+			expected:         []string{"url2", "url1", "url3"},
 		},
 		{
-			name:              "last successful URL is first",
-			urls:              []string{"url1", "url2", "url3"},
-			lastSuccessfulURL: "url1",
-			expected:          []string{"url2", "url3", "url1"},
+			name:             "failed over to the last URL within the cooldown",
+			urls:             []string{"url1", "url2", "url3"},
+			activeURL:        "url3",
+			preferredRetryAt: now.Add(time.Minute),
+			expected:         []string{"url3", "url1", "url2"},
 		},
 		{
-			name:              "last successful URL is last",
-			urls:              []string{"url1", "url2", "url3"},
-			lastSuccessfulURL: "url3",
-			expected:          []string{"url1", "url2", "url3"},
+			name:             "failed over past the cooldown",
+			urls:             []string{"url1", "url2", "url3"},
+			activeURL:        "url3",
+			preferredRetryAt: now,
+			expected:         []string{"url1", "url3", "url2"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client := NewClient("test", tt.urls...)
-			client.lastSuccessfulURL = tt.lastSuccessfulURL
+			client.now = func() time.Time { return now }
+			client.activeURL = tt.activeURL
+			client.preferredRetryAt = tt.preferredRetryAt
 
 			result := client.getURLsToTry()
 			assert.Equal(t, tt.expected, result)
@@ -441,133 +494,214 @@ func TestGetURLsToTry(t *testing.T) {
 	}
 }
 
-func TestLastSuccessfulURLAvoidance(t *testing.T) {
-	// Create multiple mock servers that track which one was called
-	var callCounts = make(map[string]int)
+// identityServer is an rpc server answering getIdentity, counting its requests and failing while failing is set
+func identityServer(t *testing.T, failing *atomic.Bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
 
-	// Create 3 mock servers
-	servers := make([]*httptest.Server, 3)
-	urls := make([]string, 3)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing != nil && failing.Load() {
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
 
-	for i := 0; i < 3; i++ {
-		serverIndex := i
-		servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			serverURL := servers[serverIndex].URL
-			callCounts[serverURL]++
+		response := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"result": map[string]interface{}{
+				"identity": "11111111111111111111111111111111",
+			},
+			"id": 1,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
 
-			// Return a simple identity response
-			response := map[string]interface{}{
-				"jsonrpc": "2.0",
-				"result": map[string]interface{}{
-					"identity": "11111111111111111111111111111111",
-				},
-				"id": 1,
-			}
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(response)
-		}))
+	return server, &calls
+}
+
+func TestActiveURLIsSticky(t *testing.T) {
+	servers := make([]*httptest.Server, 3)
+	calls := make([]*atomic.Int32, 3)
+	urls := make([]string, 3)
+	for i := range servers {
+		servers[i], calls[i] = identityServer(t, nil)
 		urls[i] = servers[i].URL
 	}
 
-	// Clean up servers when test completes
-	for _, server := range servers {
-		defer server.Close()
+	client := NewClient("test", urls...)
+	for i := 0; i < 4; i++ {
+		_, err := client.GetIdentity(context.Background())
+		require.NoError(t, err)
 	}
 
-	// Create client with multiple URLs
-	client := NewClient("test", urls...)
+	// every request goes to the preferred url while it works
+	assert.Equal(t, int32(4), calls[0].Load())
+	assert.Zero(t, calls[1].Load())
+	assert.Zero(t, calls[2].Load())
+	assert.Equal(t, endpointHost(urls[0]), client.ActiveEndpoint())
+}
+
+func TestFailoverMidRun(t *testing.T) {
+	var preferredFailing atomic.Bool
+	preferred, preferredCalls := identityServer(t, &preferredFailing)
+	fallback, fallbackCalls := identityServer(t, nil)
 
-	// Make several calls and verify it avoids the last successful URL initially
+	now := time.Now()
+	client := NewClient("test", preferred.URL, fallback.URL)
+	client.now = func() time.Time { return now }
 	ctx := context.Background()
 
-	// First call - should succeed on first URL
+	// the preferred url is used while it works
 	_, err := client.GetIdentity(ctx)
-	require.NoError(t, err, "GetIdentity should succeed")
-	firstSuccessfulURL := client.lastSuccessfulURL
-
-	// Reset call counts to track subsequent calls
-	callCounts = make(map[string]int)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), preferredCalls.Load())
+	assert.Equal(t, endpointHost(preferred.URL), client.ActiveEndpoint())
 
-	// Make 3 more calls - should avoid the first successful URL initially
+	// once it starts failing, requests fail over to the fallback and stick with it
+	preferredFailing.Store(true)
 	for i := 0; i < 3; i++ {
-		_, err := client.GetIdentity(ctx)
-		require.NoError(t, err, "GetIdentity should succeed")
+		_, err = client.GetIdentity(ctx)
+		require.NoError(t, err)
 	}
+	assert.Equal(t, int32(2), preferredCalls.Load())
+	assert.Equal(t, int32(3), fallbackCalls.Load())
+	assert.Equal(t, endpointHost(fallback.URL), client.ActiveEndpoint())
+
+	// past the cooldown the preferred url is retried - still failing, the fallback stays active for another
+	// cooldown
+	now = now.Add(DefaultPreferredURLCooldown)
+	_, err = client.GetIdentity(ctx)
+	require.NoError(t, err)
+	_, err = client.GetIdentity(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), preferredCalls.Load())
+	assert.Equal(t, int32(5), fallbackCalls.Load())
+	assert.Equal(t, endpointHost(fallback.URL), client.ActiveEndpoint())
+
+	// recovered, the preferred url is used again after the next cooldown
+	preferredFailing.Store(false)
+	now = now.Add(DefaultPreferredURLCooldown)
+	_, err = client.GetIdentity(ctx)
+	require.NoError(t, err)
+	_, err = client.GetIdentity(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(5), preferredCalls.Load())
+	assert.Equal(t, int32(5), fallbackCalls.Load())
+	assert.Equal(t, endpointHost(preferred.URL), client.ActiveEndpoint())
+}
 
-	// Verify that other URLs were tried first (throttling protection)
-	// The first successful URL should only be used as the last option
-	totalCalls := 0
-	for _, count := range callCounts {
-		totalCalls += count
-	}
+func TestFailoverWithAllFailing(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	preferred, _ := identityServer(t, &failing)
+	fallback, _ := identityServer(t, &failing)
 
-	assert.Equal(t, 3, totalCalls, "Should have made 3 additional calls")
+	client := NewClient("test", preferred.URL, fallback.URL)
+	_, err := client.GetIdentity(context.Background())
+	require.Error(t, err)
 
-	// With 3 URLs and first successful URL being avoided initially,
-	// the pattern should try the other 2 URLs first, then fallback to the first
-	if count, exists := callCounts[firstSuccessfulURL]; exists {
-		assert.True(t, count <= 1, "First successful URL should be used minimally for throttling protection")
-	}
+	// nothing succeeded so the active endpoint is unchanged
+	assert.Equal(t, endpointHost(preferred.URL), client.ActiveEndpoint())
 }
 
-func TestLastSuccessfulURLWithFailures(t *testing.T) {
-	// Create servers where first one fails, others succeed
-	var callCounts = make(map[string]int)
+// flakyIdentityServer is an identity server answering the first failures requests with 503, counting requests
+func flakyIdentityServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var failing atomic.Bool
+	failing.Store(failures > 0)
+	server, calls := identityServer(t, &failing)
+	inner := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failing.Store(calls.Load() < failures)
+		inner.ServeHTTP(w, r)
+	})
 
-	servers := make([]*httptest.Server, 3)
-	urls := make([]string, 3)
+	return server, calls
+}
 
-	for i := 0; i < 3; i++ {
-		serverIndex := i
-		servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			serverURL := servers[serverIndex].URL
-			callCounts[serverURL]++
-
-			// First server always fails
-			if serverIndex == 0 {
-				http.Error(w, "Server error", http.StatusInternalServerError)
-				return
-			}
+func TestRetryUntilSuccess(t *testing.T) {
+	server, calls := flakyIdentityServer(t, 2)
+	client := NewClient("test", server.URL)
+	client.SetRetryOptions(RetryOptions{Retries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
 
-			// Other servers succeed
-			response := map[string]interface{}{
-				"jsonrpc": "2.0",
-				"result": map[string]interface{}{
-					"identity": "11111111111111111111111111111111",
-				},
-				"id": 1,
-			}
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(response)
-		}))
-		urls[i] = servers[i].URL
-	}
+	identity, err := client.GetIdentity(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "11111111111111111111111111111111", identity.Identity.String())
+	assert.Equal(t, int32(3), calls.Load())
+}
 
-	// Clean up servers when test completes
-	for _, server := range servers {
-		defer server.Close()
-	}
+func TestRetriesRunOut(t *testing.T) {
+	server, calls := flakyIdentityServer(t, 3)
+	client := NewClient("test", server.URL)
+	client.SetRetryOptions(RetryOptions{Retries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
 
-	// Create client with multiple URLs
-	client := NewClient("test", urls...)
+	_, err := client.GetIdentity(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+}
 
-	// Make 6 calls - should avoid lastSuccessfulURL but server 0 always fails
-	ctx := context.Background()
-	for i := 0; i < 6; i++ {
-		_, err := client.GetIdentity(ctx)
-		require.NoError(t, err, "GetIdentity should eventually succeed despite server 0 failures")
-	}
+func TestRetryOnlyRetryableMethods(t *testing.T) {
+	failingServer := mockFailingServer(t)
+	var calls atomic.Int32
+	inner := failingServer.Config.Handler
+	failingServer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		inner.ServeHTTP(w, r)
+	})
+	client := NewClient("test", failingServer.URL)
+	client.SetRetryOptions(RetryOptions{Retries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
 
-	// Verify behavior: failing server should be tried when it's not the last successful URL
-	assert.True(t, callCounts[urls[0]] >= 1, "Failing server should have been tried at least once")
-	assert.True(t, callCounts[urls[1]] > 0, "Server 1 should have handled some requests")
-	assert.True(t, callCounts[urls[2]] > 0, "Server 2 should have handled some requests")
+	_, err := client.GetSlot(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
 
-	// Total successful calls should equal the working servers' call counts
-	successfulCalls := callCounts[urls[1]] + callCounts[urls[2]]
-	assert.Equal(t, 6, successfulCalls, "All 6 calls should eventually succeed")
+func TestRetryNotOnRPCError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"Node is behind by 42 slots"}}`))
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient("test", server.URL)
+	client.SetRetryOptions(RetryOptions{Retries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+
+	// an unhealthy node answered, asking again won't change that
+	_, err := client.GetHealth(context.Background())
+	assert.EqualError(t, err, "Node is behind by 42 slots")
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestRetryBudget(t *testing.T) {
+	server, calls := flakyIdentityServer(t, 10)
+	client := NewClient("test", server.URL)
+	client.SetRetryOptions(RetryOptions{Retries: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Budget: 250 * time.Millisecond})
 
-	// The client should remember the last successful URL and avoid it for throttling protection
-	assert.True(t, client.lastSuccessfulURL == urls[1] || client.lastSuccessfulURL == urls[2],
-		"Last successful URL should be one of the working servers")
+	// the second retry's 200ms backoff would overrun the budget so it isn't sent
+	startedAt := time.Now()
+	_, err := client.GetIdentity(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Less(t, time.Since(startedAt), 250*time.Millisecond)
+}
+
+func TestBackoff(t *testing.T) {
+	client := NewClient("test")
+	client.SetRetryOptions(RetryOptions{InitialBackoff: 250 * time.Millisecond, MaxBackoff: 2 * time.Second})
+	assert.Equal(t, 250*time.Millisecond, client.backoff(1))
+	assert.Equal(t, 500*time.Millisecond, client.backoff(2))
+	assert.Equal(t, time.Second, client.backoff(3))
+	assert.Equal(t, 2*time.Second, client.backoff(4))
+	assert.Equal(t, 2*time.Second, client.backoff(10))
+
+	client.SetRetryOptions(RetryOptions{InitialBackoff: time.Second, MaxBackoff: time.Second, Jitter: 0.2})
+	for range 100 {
+		backoff := client.backoff(1)
+		assert.GreaterOrEqual(t, backoff, 800*time.Millisecond)
+		assert.LessOrEqual(t, backoff, 1200*time.Millisecond)
+	}
 }
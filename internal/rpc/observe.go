@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+const (
+	// batchMethod is the method batch calls are observed as
+	batchMethod = "batch"
+	// invalidEndpoint is the endpoint of urls that don't parse
+	invalidEndpoint = "invalid"
+)
+
+// RequestObserver is called with the JSON-RPC method, endpoint host, duration and error of every request a
+// client sends, e.g. to export metrics - retried is set for the requests of a retry
+type RequestObserver func(method string, endpoint string, retried bool, duration time.Duration, err error)
+
+// retriedKey is the context key marking requests of a retry
+type retriedKey struct{}
+
+// withRetried returns ctx marking its requests as a retry or not
+func withRetried(ctx context.Context, retried bool) context.Context {
+	return context.WithValue(ctx, retriedKey{}, retried)
+}
+
+// isRetried returns whether ctx's requests are a retry
+func isRetried(ctx context.Context) bool {
+	retried, _ := ctx.Value(retriedKey{}).(bool)
+	return retried
+}
+
+// observedRPCClient wraps a JSON-RPC client reporting every request it sends to observe
+type observedRPCClient struct {
+	next     rpc.JSONRPCClient
+	endpoint string
+	observe  RequestObserver
+}
+
+// newObservedRPCClient wraps next, the client for url, reporting its requests to observe
+func newObservedRPCClient(next rpc.JSONRPCClient, rpcURL string, observe RequestObserver) *observedRPCClient {
+	return &observedRPCClient{next: next, endpoint: endpointHost(rpcURL), observe: observe}
+}
+
+// CallForInto implements rpc.JSONRPCClient
+func (c *observedRPCClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	startedAt := time.Now()
+	err := c.next.CallForInto(ctx, out, method, params)
+	c.observe(method, c.endpoint, isRetried(ctx), time.Since(startedAt), err)
+	return err
+}
+
+// CallWithCallback implements rpc.JSONRPCClient
+func (c *observedRPCClient) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	startedAt := time.Now()
+	err := c.next.CallWithCallback(ctx, method, params, callback)
+	c.observe(method, c.endpoint, isRetried(ctx), time.Since(startedAt), err)
+	return err
+}
+
+// CallBatch implements rpc.JSONRPCClient
+func (c *observedRPCClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	startedAt := time.Now()
+	responses, err := c.next.CallBatch(ctx, requests)
+	c.observe(batchMethod, c.endpoint, isRetried(ctx), time.Since(startedAt), err)
+	return responses, err
+}
+
+// endpointHost returns the host of rpcURL - paths and query strings may embed an api key so are never observed
+func endpointHost(rpcURL string) string {
+	parsedURL, err := url.Parse(rpcURL)
+	if err != nil || parsedURL.Host == "" {
+		return invalidEndpoint
+	}
+	return parsedURL.Host
+}
@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// observed is a request reported to a RequestObserver
+type observed struct {
+	method   string
+	endpoint string
+	retried  bool
+	err      error
+}
+
+func TestNewObservedClient(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"ok"}`))
+	}))
+	t.Cleanup(healthy.Close)
+	limited := mockRateLimitedServer(t, nil)
+
+	var mu sync.Mutex
+	requests := []observed{}
+	client := NewObservedClient("test", sharedTransport, func(method string, endpoint string, retried bool, duration time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Positive(t, duration)
+		requests = append(requests, observed{method: method, endpoint: endpoint, retried: retried, err: err})
+	}, limited.URL+"/secret-api-key", healthy.URL)
+
+	health, err := client.GetHealth(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ok", health)
+
+	// each endpoint tried is observed by host only
+	require.Len(t, requests, 2)
+	assert.Equal(t, "getHealth", requests[0].method)
+	assert.Equal(t, endpointHost(limited.URL), requests[0].endpoint)
+	assert.NotContains(t, requests[0].endpoint, "secret-api-key")
+	assert.Error(t, requests[0].err)
+	assert.Equal(t, observed{method: "getHealth", endpoint: endpointHost(healthy.URL)}, requests[1])
+}
+
+func TestNewObservedClient_Retried(t *testing.T) {
+	limited := mockRateLimitedServer(t, nil)
+
+	var mu sync.Mutex
+	requests := []observed{}
+	client := NewObservedClient("test", sharedTransport, func(method string, endpoint string, retried bool, duration time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, observed{method: method, endpoint: endpoint, retried: retried})
+	}, limited.URL)
+	client.SetRetryOptions(RetryOptions{Retries: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+	_, err := client.GetIdentity(context.Background())
+	require.Error(t, err)
+
+	// the first request is not a retry, the two after it are
+	endpoint := endpointHost(limited.URL)
+	assert.Equal(t, []observed{
+		{method: "getIdentity", endpoint: endpoint},
+		{method: "getIdentity", endpoint: endpoint, retried: true},
+		{method: "getIdentity", endpoint: endpoint, retried: true},
+	}, requests)
+}
+
+func TestEndpointHost(t *testing.T) {
+	assert.Equal(t, "api.mainnet-beta.solana.com", endpointHost("https://api.mainnet-beta.solana.com"))
+	assert.Equal(t, "rpc.example.com:8899", endpointHost("http://rpc.example.com:8899/api-key?token=secret"))
+	assert.Equal(t, invalidEndpoint, endpointHost("not a url"))
+}
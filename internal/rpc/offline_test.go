@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingRoundTripper fails the test on any HTTP request
+type failingRoundTripper struct {
+	t *testing.T
+}
+
+func (f failingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.t.Errorf("unexpected network request to %s", req.URL)
+	return nil, fmt.Errorf("network access is not allowed in this test")
+}
+
+func TestNewOfflineClient_NoNetworkIO(t *testing.T) {
+	defaultTransport := http.DefaultTransport
+	http.DefaultTransport = failingRoundTripper{t: t}
+	t.Cleanup(func() { http.DefaultTransport = defaultTransport })
+
+	client := NewOfflineClient("test", "http://127.0.0.1:8899", "https://api.mainnet-beta.solana.com")
+	ctx := context.Background()
+
+	nodes, err := client.GetClusterNodes(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, nodes)
+
+	identity, err := client.GetIdentity(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, solana.SystemProgramID, identity.Identity)
+
+	health, err := client.GetHealth(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", health)
+
+	slot, err := client.GetSlot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), slot)
+
+	voteAccounts, err := client.GetVoteAccounts(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, voteAccounts.Current)
+	assert.Empty(t, voteAccounts.Delinquent)
+
+	balance, err := client.GetBalance(ctx, solana.SystemProgramID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), balance.Value)
+}
+
+func TestNewOfflineClient_CannedResultsCoverAllowlist(t *testing.T) {
+	for _, method := range AllowedMethods() {
+		assert.Contains(t, offlineResults, method)
+	}
+}
+
+func TestNewOfflineClient_AllowlistStillApplies(t *testing.T) {
+	client := NewOfflineClient("test", "offline")
+	_, err := client.clients["offline"].GetAccountInfo(context.Background(), solana.PublicKey{})
+	assert.ErrorContains(t, err, `rpc method "getAccountInfo" is not in the allowlist`)
+}
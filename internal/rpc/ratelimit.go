@@ -0,0 +1,162 @@
+package rpc
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRateLimitWindow is the window over which requests and rate limited responses are counted
+	DefaultRateLimitWindow = time.Minute
+)
+
+// rateLimitResetHeaders are provider-specific headers carrying the seconds until a rate limit resets,
+// checked in order after the standard Retry-After
+var rateLimitResetHeaders = []string{
+	"X-RateLimit-Reset-After",
+	"X-RateLimit-Reset",
+	"RateLimit-Reset",
+}
+
+// RateLimitStats is a snapshot of the rate limit tracker
+type RateLimitStats struct {
+	// Total is the number of rate limited responses since startup
+	Total uint64
+	// RequestsInWindow is the number of requests sent within the window
+	RequestsInWindow int
+	// RateLimitedInWindow is the number of rate limited responses within the window
+	RateLimitedInWindow int
+	// RetryAfter is the most recent wait hinted by the provider, zero if none
+	RetryAfter time.Duration
+}
+
+// RateLimitTracker counts requests and HTTP 429 responses within a sliding window
+type RateLimitTracker struct {
+	mu          sync.Mutex
+	window      time.Duration
+	now         func() time.Time
+	requests    []time.Time
+	rateLimited []time.Time
+	total       uint64
+	retryAfter  time.Duration
+}
+
+// NewRateLimitTracker creates a tracker with the given window, using DefaultRateLimitWindow if zero
+func NewRateLimitTracker(window time.Duration) *RateLimitTracker {
+	if window <= 0 {
+		window = DefaultRateLimitWindow
+	}
+	return &RateLimitTracker{
+		window: window,
+		now:    time.Now,
+	}
+}
+
+// SetClock overrides the tracker clock - only useful in tests
+func (t *RateLimitTracker) SetClock(now func() time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.now = now
+}
+
+// Observe records a response, counting it as rate limited if the status is 429
+func (t *RateLimitTracker) Observe(resp *http.Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.requests = append(t.requests, now)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		t.rateLimited = append(t.rateLimited, now)
+		t.total++
+		t.retryAfter = parseRetryAfter(resp.Header, now)
+	}
+
+	t.prune(now)
+}
+
+// Stats returns a snapshot of the tracker
+func (t *RateLimitTracker) Stats() RateLimitStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(t.now())
+	return RateLimitStats{
+		Total:               t.total,
+		RequestsInWindow:    len(t.requests),
+		RateLimitedInWindow: len(t.rateLimited),
+		RetryAfter:          t.retryAfter,
+	}
+}
+
+// prune drops entries older than the window - caller must hold the lock
+func (t *RateLimitTracker) prune(now time.Time) {
+	cutoff := now.Add(-t.window)
+	t.requests = pruneBefore(t.requests, cutoff)
+	t.rateLimited = pruneBefore(t.rateLimited, cutoff)
+	if len(t.rateLimited) == 0 {
+		t.retryAfter = 0
+	}
+}
+
+// pruneBefore drops the leading times not after cutoff, times are in ascending order
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// parseRetryAfter returns the wait hinted by Retry-After (seconds or HTTP date) or a provider-specific reset header
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+		if at, err := http.ParseTime(value); err == nil && at.After(now) {
+			return at.Sub(now)
+		}
+	}
+
+	for _, name := range rateLimitResetHeaders {
+		seconds, err := strconv.Atoi(header.Get(name))
+		if err != nil || seconds < 0 {
+			continue
+		}
+		// some providers send an absolute unix timestamp rather than a delta
+		if int64(seconds) > now.Unix()/2 {
+			if at := time.Unix(int64(seconds), 0); at.After(now) {
+				return at.Sub(now)
+			}
+			continue
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	return 0
+}
+
+// rateLimitTransport is an http.RoundTripper that reports every response to a tracker
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	tracker *RateLimitTracker
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.tracker.Observe(resp)
+	return resp, nil
+}
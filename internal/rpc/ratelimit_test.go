@@ -0,0 +1,114 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockRateLimitedServer creates a server that answers every request with 429 and the given headers
+func mockRateLimitedServer(t *testing.T, headers map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+
+	t.Cleanup(func() {
+		server.Close()
+	})
+
+	return server
+}
+
+func TestRateLimitTracker_Window(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewRateLimitTracker(time.Minute)
+	tracker.SetClock(func() time.Time { return now })
+
+	ok := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	limited := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"7"}}}
+
+	tracker.Observe(ok)
+	tracker.Observe(limited)
+	now = now.Add(30 * time.Second)
+	tracker.Observe(limited)
+
+	stats := tracker.Stats()
+	assert.Equal(t, uint64(2), stats.Total)
+	assert.Equal(t, 3, stats.RequestsInWindow)
+	assert.Equal(t, 2, stats.RateLimitedInWindow)
+	assert.Equal(t, 7*time.Second, stats.RetryAfter)
+
+	// the first two fall out of the window
+	now = now.Add(31 * time.Second)
+	stats = tracker.Stats()
+	assert.Equal(t, uint64(2), stats.Total)
+	assert.Equal(t, 1, stats.RequestsInWindow)
+	assert.Equal(t, 1, stats.RateLimitedInWindow)
+
+	// everything falls out of the window - the total is kept
+	now = now.Add(time.Minute)
+	stats = tracker.Stats()
+	assert.Equal(t, RateLimitStats{Total: 2}, stats)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected time.Duration
+	}{
+		{"none", http.Header{}, 0},
+		{"retry-after seconds", http.Header{"Retry-After": []string{"5"}}, 5 * time.Second},
+		{"retry-after http date", http.Header{"Retry-After": []string{now.Add(10 * time.Second).Format(http.TimeFormat)}}, 10 * time.Second},
+		{"retry-after garbage", http.Header{"Retry-After": []string{"soon"}}, 0},
+		{"x-ratelimit-reset delta", http.Header{"X-Ratelimit-Reset": []string{"3"}}, 3 * time.Second},
+		{"x-ratelimit-reset unix timestamp", http.Header{"X-Ratelimit-Reset": []string{strconv.FormatInt(now.Add(20*time.Second).Unix(), 10)}}, 20 * time.Second},
+		{"ratelimit-reset", http.Header{"Ratelimit-Reset": []string{"2"}}, 2 * time.Second},
+		{"retry-after wins", http.Header{"Retry-After": []string{"1"}, "X-Ratelimit-Reset": []string{"9"}}, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseRetryAfter(tt.header, now))
+		})
+	}
+}
+
+func TestClient_TracksRateLimitedResponses(t *testing.T) {
+	server := mockRateLimitedServer(t, map[string]string{"Retry-After": "4"})
+	client := NewClient("test", server.URL)
+
+	for i := 0; i < 3; i++ {
+		_, err := client.GetSlot(context.Background())
+		require.Error(t, err)
+	}
+
+	stats := client.RateLimits().Stats()
+	assert.Equal(t, uint64(3), stats.Total)
+	assert.Equal(t, 3, stats.RequestsInWindow)
+	assert.Equal(t, 3, stats.RateLimitedInWindow)
+	assert.Equal(t, 4*time.Second, stats.RetryAfter)
+}
+
+func TestClient_SuccessfulResponsesAreNotRateLimited(t *testing.T) {
+	server := mockSolanaRPCServer(t, map[string]interface{}{"getSlot": 1})
+	client := NewClient("test", server.URL)
+
+	_, err := client.GetSlot(context.Background())
+	require.NoError(t, err)
+
+	stats := client.RateLimits().Stats()
+	assert.Equal(t, uint64(0), stats.Total)
+	assert.Equal(t, 1, stats.RequestsInWindow)
+}
@@ -0,0 +1,91 @@
+// Package sdnotify implements the systemd service notification protocol - readiness, status and watchdog keep-alives
+// sent as datagrams to the socket systemd passes in $NOTIFY_SOCKET. Without it every call is a no-op.
+package sdnotify
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Ready tells systemd startup is complete, Type=notify units are started once it is sent
+	Ready = "READY=1"
+	// Stopping tells systemd the service is shutting down
+	Stopping = "STOPPING=1"
+	// Watchdog is the keep-alive systemd expects at least every WatchdogSec when it is set
+	Watchdog = "WATCHDOG=1"
+)
+
+// Notifier sends notifications to systemd, a nil Notifier sends nothing
+type Notifier struct {
+	socket string
+	// watchdogInterval is the unit's WatchdogSec, zero when systemd isn't watching us
+	watchdogInterval time.Duration
+}
+
+// FromEnv returns a notifier of the $NOTIFY_SOCKET and $WATCHDOG_USEC systemd sets, nil when not run by systemd
+// with Type=notify. The variables are unset so the role commands and hooks we run never notify on our behalf.
+func FromEnv() *Notifier {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	watchdogInterval := watchdogIntervalFromEnv()
+	for _, name := range []string{"NOTIFY_SOCKET", "WATCHDOG_USEC", "WATCHDOG_PID"} {
+		os.Unsetenv(name)
+	}
+	if socket == "" {
+		return nil
+	}
+
+	return &Notifier{socket: socket, watchdogInterval: watchdogInterval}
+}
+
+// watchdogIntervalFromEnv returns $WATCHDOG_USEC, zero if it isn't set, is invalid or $WATCHDOG_PID is another process
+func watchdogIntervalFromEnv() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// WatchdogInterval returns the unit's WatchdogSec, zero when the watchdog isn't enabled
+func (n *Notifier) WatchdogInterval() time.Duration {
+	if n == nil {
+		return 0
+	}
+	return n.watchdogInterval
+}
+
+// Notify sends states, e.g. Ready and Status("..."), to systemd in a single datagram
+func (n *Notifier) Notify(states ...string) error {
+	if n == nil || len(states) == 0 {
+		return nil
+	}
+
+	// a leading @ is an abstract socket
+	name := n.socket
+	if strings.HasPrefix(name, "@") {
+		name = "\x00" + name[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("failed to connect to systemd notify socket %s: %w", n.socket, err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return fmt.Errorf("failed to notify systemd: %w", err)
+	}
+	return nil
+}
+
+// Status returns the state setting the free-form status systemctl status shows, on a single line
+func Status(status string) string {
+	return "STATUS=" + strings.ReplaceAll(status, "\n", " ")
+}
@@ -0,0 +1,80 @@
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listen returns a notify socket like systemd's, in a temp dir
+func listen(t *testing.T) (string, *net.UnixConn) {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return socket, conn
+}
+
+// read returns the next datagram sent to conn
+func read(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func TestFromEnv_NotifiesSocket(t *testing.T) {
+	socket, conn := listen(t)
+	t.Setenv("NOTIFY_SOCKET", socket)
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+	notifier := FromEnv()
+	require.NotNil(t, notifier)
+	assert.Equal(t, 30*time.Second, notifier.WatchdogInterval())
+
+	// never passed on to what we run
+	for _, name := range []string{"NOTIFY_SOCKET", "WATCHDOG_USEC", "WATCHDOG_PID"} {
+		_, set := os.LookupEnv(name)
+		assert.False(t, set, name)
+	}
+
+	require.NoError(t, notifier.Notify(Ready, Status("active, healthy\nfailover idle")))
+	assert.Equal(t, "READY=1\nSTATUS=active, healthy failover idle", read(t, conn))
+	require.NoError(t, notifier.Notify(Watchdog))
+	assert.Equal(t, "WATCHDOG=1", read(t, conn))
+}
+
+func TestFromEnv_WatchdogOfAnotherProcess(t *testing.T) {
+	socket, _ := listen(t)
+	t.Setenv("NOTIFY_SOCKET", socket)
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+
+	assert.Zero(t, FromEnv().WatchdogInterval())
+}
+
+func TestFromEnv_NotRunBySystemd(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+
+	notifier := FromEnv()
+	assert.Nil(t, notifier)
+	assert.Zero(t, notifier.WatchdogInterval())
+	assert.NoError(t, notifier.Notify(Ready))
+}
+
+func TestNotifier_Notify_SocketGone(t *testing.T) {
+	notifier := &Notifier{socket: filepath.Join(t.TempDir(), "missing.sock")}
+	assert.ErrorContains(t, notifier.Notify(Ready), "failed to connect to systemd notify socket")
+}
@@ -0,0 +1,117 @@
+package testutil
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// AdminRPCCall is a call the FakeAdminRPC received
+type AdminRPCCall struct {
+	Method string
+	Params []any
+}
+
+// FakeAdminRPC serves the agave admin rpc on an admin.rpc unix socket in a temporary ledger directory, answering
+// every call with success unless set to fail - safe for concurrent use
+type FakeAdminRPC struct {
+	// LedgerPath is the directory holding the socket
+	LedgerPath string
+	// SocketPath is the admin.rpc socket in LedgerPath
+	SocketPath string
+
+	mu            sync.Mutex
+	calls         []AdminRPCCall
+	errCode       int
+	errMessage    string
+	delay         time.Duration
+	onSetIdentity func(keypairFile string)
+}
+
+// NewFakeAdminRPC returns a FakeAdminRPC listening until the test ends
+func NewFakeAdminRPC(t *testing.T) *FakeAdminRPC {
+	t.Helper()
+
+	ledgerPath := t.TempDir()
+	f := &FakeAdminRPC{LedgerPath: ledgerPath, SocketPath: filepath.Join(ledgerPath, "admin.rpc")}
+	listener, err := net.Listen("unix", f.SocketPath)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", f.SocketPath, err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+// SetError makes every call fail with the JSON-RPC error code and message, a zero code answers with success again
+func (f *FakeAdminRPC) SetError(code int, message string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errCode, f.errMessage = code, message
+}
+
+// SetDelay makes every call wait d before answering
+func (f *FakeAdminRPC) SetDelay(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delay = d
+}
+
+// OnSetIdentity calls fn with the keypair file of every successful setIdentity, e.g. to switch a FakeRPC's identity
+func (f *FakeAdminRPC) OnSetIdentity(fn func(keypairFile string)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onSetIdentity = fn
+}
+
+// Calls returns the calls received so far
+func (f *FakeAdminRPC) Calls() []AdminRPCCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]AdminRPCCall{}, f.calls...)
+}
+
+// serve answers the one request sent on conn
+func (f *FakeAdminRPC) serve(conn net.Conn) {
+	defer conn.Close()
+
+	var req struct {
+		ID     any    `json:"id"`
+		Method string `json:"method"`
+		Params []any  `json:"params"`
+	}
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, AdminRPCCall{Method: req.Method, Params: req.Params})
+	errCode, errMessage, delay, onSetIdentity := f.errCode, f.errMessage, f.delay, f.onSetIdentity
+	f.mu.Unlock()
+
+	time.Sleep(delay)
+	resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	if errCode != 0 {
+		resp["error"] = map[string]any{"code": errCode, "message": errMessage}
+	} else {
+		resp["result"] = nil
+		if req.Method == "setIdentity" && onSetIdentity != nil && len(req.Params) > 0 {
+			if keypairFile, ok := req.Params[0].(string); ok {
+				onSetIdentity(keypairFile)
+			}
+		}
+	}
+	_ = json.NewEncoder(conn).Encode(resp)
+}
@@ -0,0 +1,201 @@
+// Package testutil provides fakes for driving the agent through failover scenarios in tests
+package testutil
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/sol-strategies/solana-validator-ha/internal/rpc"
+)
+
+// FakeRPC is an in-memory rpc.SolanaClient answering with whatever it was last set to, safe for concurrent use
+// so a test can change the cluster between the agent's samples
+type FakeRPC struct {
+	mu           sync.Mutex
+	clusterNodes []*solanagorpc.GetClusterNodesResult
+	identity     solana.PublicKey
+	health       string
+	voteAccounts solanagorpc.GetVoteAccountsResult
+	slot         uint64
+	balances     map[solana.PublicKey]uint64
+	errs         map[string]error
+	calls        map[string]int
+	rateLimits   *rpc.RateLimitTracker
+}
+
+// NewFakeRPC returns a healthy FakeRPC with no cluster nodes and no vote accounts
+func NewFakeRPC() *FakeRPC {
+	return &FakeRPC{
+		health:     solanagorpc.HealthOk,
+		balances:   map[solana.PublicKey]uint64{},
+		errs:       map[string]error{},
+		calls:      map[string]int{},
+		rateLimits: rpc.NewRateLimitTracker(rpc.DefaultRateLimitWindow),
+	}
+}
+
+// SetClusterNodes sets the nodes getClusterNodes returns
+func (f *FakeRPC) SetClusterNodes(nodes ...*solanagorpc.GetClusterNodesResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clusterNodes = nodes
+}
+
+// SetIdentity sets the identity getIdentity returns
+func (f *FakeRPC) SetIdentity(pubkey solana.PublicKey) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.identity = pubkey
+}
+
+// SetHealth sets the status getHealth returns
+func (f *FakeRPC) SetHealth(status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.health = status
+}
+
+// SetVoteAccounts sets the current and delinquent vote accounts getVoteAccounts returns
+func (f *FakeRPC) SetVoteAccounts(current []solanagorpc.VoteAccountsResult, delinquent []solanagorpc.VoteAccountsResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.voteAccounts = solanagorpc.GetVoteAccountsResult{Current: current, Delinquent: delinquent}
+}
+
+// SetSlot sets the slot getSlot returns
+func (f *FakeRPC) SetSlot(slot uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.slot = slot
+}
+
+// SetBalance sets the balance getBalance returns for pubkey
+func (f *FakeRPC) SetBalance(pubkey solana.PublicKey, lamports uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balances[pubkey] = lamports
+}
+
+// SetError makes calls of the JSON-RPC method, e.g. getIdentity, fail with err until it is set to nil
+func (f *FakeRPC) SetError(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errs[method] = err
+}
+
+// Calls returns how many times the JSON-RPC method has been called
+func (f *FakeRPC) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// call counts a call of method and returns the error it is set to fail with, or ctx's once it is done - like a
+// real client, a call made past its deadline fails
+func (f *FakeRPC) call(ctx context.Context, method string) error {
+	f.calls[method]++
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.errs[method]
+}
+
+// GetClusterNodes implements rpc.SolanaClient
+func (f *FakeRPC) GetClusterNodes(ctx context.Context) ([]*solanagorpc.GetClusterNodesResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "getClusterNodes"); err != nil {
+		return nil, err
+	}
+	return append([]*solanagorpc.GetClusterNodesResult{}, f.clusterNodes...), nil
+}
+
+// GetIdentity implements rpc.SolanaClient
+func (f *FakeRPC) GetIdentity(ctx context.Context) (*solanagorpc.GetIdentityResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "getIdentity"); err != nil {
+		return nil, err
+	}
+	return &solanagorpc.GetIdentityResult{Identity: f.identity}, nil
+}
+
+// GetHealth implements rpc.SolanaClient
+func (f *FakeRPC) GetHealth(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "getHealth"); err != nil {
+		return "", err
+	}
+	return f.health, nil
+}
+
+// GetVoteAccounts implements rpc.SolanaClient
+func (f *FakeRPC) GetVoteAccounts(ctx context.Context) (*solanagorpc.GetVoteAccountsResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "getVoteAccounts"); err != nil {
+		return nil, err
+	}
+	voteAccounts := f.voteAccounts
+	return &voteAccounts, nil
+}
+
+// GetSlot implements rpc.SolanaClient
+func (f *FakeRPC) GetSlot(ctx context.Context) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "getSlot"); err != nil {
+		return 0, err
+	}
+	return f.slot, nil
+}
+
+// GetBalance implements rpc.SolanaClient
+func (f *FakeRPC) GetBalance(ctx context.Context, pubkey solana.PublicKey) (*solanagorpc.GetBalanceResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "getBalance"); err != nil {
+		return nil, err
+	}
+	return &solanagorpc.GetBalanceResult{Value: f.balances[pubkey]}, nil
+}
+
+// RateLimits implements rpc.SolanaClient
+func (f *FakeRPC) RateLimits() *rpc.RateLimitTracker {
+	return f.rateLimits
+}
+
+// ActiveEndpoint implements rpc.SolanaClient
+func (f *FakeRPC) ActiveEndpoint() string {
+	return "fake"
+}
+
+// GossipNode returns a cluster node with pubkey whose gossip address is a live listener on ip, so the agent's
+// gossip liveness probe sees it alive - the listener is closed when the test ends
+func GossipNode(t *testing.T, ip string, pubkey solana.PublicKey) *solanagorpc.GetClusterNodesResult {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(ip, "0"))
+	if err != nil {
+		t.Fatalf("failed to listen for gossip on %s: %v", ip, err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	gossip := listener.Addr().String()
+	return &solanagorpc.GetClusterNodesResult{Pubkey: pubkey, Gossip: &gossip}
+}
+
+// VoteAccount returns a vote account of nodePubkey as getVoteAccounts lists it
+func VoteAccount(nodePubkey solana.PublicKey) solanagorpc.VoteAccountsResult {
+	return solanagorpc.VoteAccountsResult{
+		VotePubkey:       solana.NewWallet().PublicKey(),
+		NodePubkey:       nodePubkey,
+		ActivatedStake:   1,
+		EpochVoteAccount: true,
+	}
+}
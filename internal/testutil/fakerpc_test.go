@@ -0,0 +1,100 @@
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	solanagorpc "github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeRPC(t *testing.T) {
+	fake := NewFakeRPC()
+	pubkey := solana.NewWallet().PublicKey()
+
+	health, err := fake.GetHealth(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ok", health)
+
+	fake.SetIdentity(pubkey)
+	identity, err := fake.GetIdentity(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, pubkey, identity.Identity)
+
+	fake.SetBalance(pubkey, 42)
+	balance, err := fake.GetBalance(context.Background(), pubkey)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), balance.Value)
+
+	fake.SetVoteAccounts(nil, []solanagorpc.VoteAccountsResult{VoteAccount(pubkey)})
+	voteAccounts, err := fake.GetVoteAccounts(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, voteAccounts.Current)
+	require.Len(t, voteAccounts.Delinquent, 1)
+	assert.Equal(t, pubkey, voteAccounts.Delinquent[0].NodePubkey)
+}
+
+func TestFakeRPC_SetError(t *testing.T) {
+	fake := NewFakeRPC()
+
+	fake.SetError("getIdentity", assert.AnError)
+	_, err := fake.GetIdentity(context.Background())
+	assert.ErrorIs(t, err, assert.AnError)
+
+	fake.SetError("getIdentity", nil)
+	_, err = fake.GetIdentity(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.Calls("getIdentity"))
+	assert.Zero(t, fake.Calls("getHealth"))
+}
+
+func TestGossipNode(t *testing.T) {
+	pubkey := solana.NewWallet().PublicKey()
+	node := GossipNode(t, "127.0.0.1", pubkey)
+
+	fake := NewFakeRPC()
+	fake.SetClusterNodes(node)
+	nodes, err := fake.GetClusterNodes(context.Background())
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, pubkey, nodes[0].Pubkey)
+
+	// the gossip address is live
+	conn, err := net.Dial("tcp", *nodes[0].Gossip)
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestFakeAdminRPC(t *testing.T) {
+	fake := NewFakeAdminRPC(t)
+	switched := ""
+	fake.OnSetIdentity(func(keypairFile string) { switched = keypairFile })
+
+	conn, err := net.Dial("unix", fake.SocketPath)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(`{"jsonrpc":"2.0","id":7,"method":"setIdentity","params":["/keys/active.json",true]}` + "\n"))
+	require.NoError(t, err)
+
+	var resp map[string]any
+	require.NoError(t, json.NewDecoder(conn).Decode(&resp))
+	assert.Equal(t, float64(7), resp["id"])
+	assert.Nil(t, resp["error"])
+	assert.Equal(t, "/keys/active.json", switched)
+	assert.Equal(t, []AdminRPCCall{{Method: "setIdentity", Params: []any{"/keys/active.json", true}}}, fake.Calls())
+}
+
+func TestFakeRPC_ContextDone(t *testing.T) {
+	fake := NewFakeRPC()
+
+	// a call past its deadline fails like a real client's would
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := fake.GetClusterNodes(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, fake.Calls("getClusterNodes"))
+}
@@ -0,0 +1,140 @@
+package tlscert
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/charmbracelet/log"
+	"github.com/sol-strategies/solana-validator-ha/internal/loglevel"
+)
+
+// Reloader serves a certificate and key pair loaded from files, reloaded on SIGHUP so renewals like Let's
+// Encrypt's don't need a restart
+type Reloader struct {
+	certFile string
+	keyFile  string
+	logger   *log.Logger
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewReloader loads the certificate and key pair of certFile and keyFile
+func NewReloader(certFile string, keyFile string, logPrefix string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   loglevel.WithPrefix(loglevel.TLS, fmt.Sprintf("[%s tls]", logPrefix)),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificate and key pair from their files again, the current pair is kept if they fail to load
+func (r *Reloader) Reload() error {
+	cert, err := Load(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+
+	r.logger.Info("loaded certificate", "cert_file", r.certFile, "subject", cert.Leaf.Subject.String(), "not_after", cert.Leaf.NotAfter)
+	return nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// TLSConfig returns a server tls.Config always serving the current certificate
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// ReloadOnSIGHUP reloads the certificate every time the process receives SIGHUP until the returned stop is called
+func (r *Reloader) ReloadOnSIGHUP() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if err := r.Reload(); err != nil {
+					r.logger.Error("failed to reload certificate - keeping the current one", "cert_file", r.certFile, "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
+
+// Load loads the certificate and key pair of certFile and keyFile, parsing the leaf certificate
+func Load(certFile string, keyFile string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return cert, fmt.Errorf("failed to load certificate %s and key %s: %w", certFile, keyFile, err)
+	}
+	if cert.Leaf == nil {
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return cert, fmt.Errorf("failed to parse certificate %s: %w", certFile, err)
+		}
+	}
+	return cert, nil
+}
+
+// PinnedClientConfig returns a client tls.Config trusting only the leaf certificate in certFile, whatever host name
+// or IP it is reached by - for reaching the agent's own servers over loopback
+func PinnedClientConfig(certFile string) (*tls.Config, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate %s: %w", certFile, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate found in %s", certFile)
+	}
+	leaf := block.Bytes
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// verified below against the pinned certificate instead of roots and host name
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 || !bytes.Equal(rawCerts[0], leaf) {
+				return errors.New("server certificate does not match " + certFile)
+			}
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,147 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self signed certificate for commonName and 127.0.0.1 and its key to dir, returning
+// their paths
+func writeCertificate(t *testing.T, dir string, commonName string) (certFile string, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, "tls.crt")
+	keyFile = filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	return cert.Leaf.Subject.CommonName
+}
+
+func TestNewReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "first")
+
+	r, err := NewReloader(certFile, keyFile, "test")
+	require.NoError(t, err)
+	assert.Equal(t, "first", commonName(t, r))
+
+	_, err = NewReloader(certFile, filepath.Join(dir, "missing.key"), "test")
+	assert.ErrorContains(t, err, "failed to load certificate")
+
+	// a key that doesn't match the certificate
+	otherCertFile, _ := writeCertificate(t, t.TempDir(), "other")
+	_, err = NewReloader(otherCertFile, keyFile, "test")
+	assert.ErrorContains(t, err, "failed to load certificate")
+}
+
+func TestReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "first")
+	r, err := NewReloader(certFile, keyFile, "test")
+	require.NoError(t, err)
+
+	// renewed
+	writeCertificate(t, dir, "renewed")
+	require.NoError(t, r.Reload())
+	assert.Equal(t, "renewed", commonName(t, r))
+
+	// a renewal caught half written keeps the current certificate
+	require.NoError(t, os.WriteFile(keyFile, []byte("partial"), 0o600))
+	assert.Error(t, r.Reload())
+	assert.Equal(t, "renewed", commonName(t, r))
+}
+
+func TestReloader_ReloadOnSIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "first")
+	r, err := NewReloader(certFile, keyFile, "test")
+	require.NoError(t, err)
+
+	stop := r.ReloadOnSIGHUP()
+	defer stop()
+
+	writeCertificate(t, dir, "renewed")
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	assert.Eventually(t, func() bool { return commonName(t, r) == "renewed" }, 5*time.Second, 10*time.Millisecond)
+
+	// stopping twice is fine
+	stop()
+	stop()
+}
+
+func TestPinnedClientConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "agent")
+	r, err := NewReloader(certFile, keyFile, "test")
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	// served with the reloader's certificate, StartTLS would serve its own
+	server.Listener = tls.NewListener(server.Listener, r.TLSConfig())
+	server.Start()
+	t.Cleanup(server.Close)
+	url := "https://" + server.Listener.Addr().String()
+
+	get := func(certFile string) error {
+		tlsConfig, err := PinnedClientConfig(certFile)
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// the pinned certificate is trusted though it is self signed
+	assert.NoError(t, get(certFile))
+
+	// any other certificate is not
+	otherCertFile, _ := writeCertificate(t, t.TempDir(), "other")
+	assert.ErrorContains(t, get(otherCertFile), "server certificate does not match")
+
+	_, err = PinnedClientConfig(keyFile)
+	assert.ErrorContains(t, err, "no certificate found")
+}
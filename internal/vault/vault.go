@@ -0,0 +1,190 @@
+// Package vault reads secrets from HashiCorp Vault over its HTTP API, authenticating with a token or an AppRole
+// from the environment like the vault CLI
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// Scheme prefixes a secret reference, vault://<path>#<field>
+	Scheme = "vault://"
+
+	// EnvAddr is the Vault server address, e.g. https://vault.example.com:8200
+	EnvAddr = "VAULT_ADDR"
+	// EnvToken is the token to authenticate with
+	EnvToken = "VAULT_TOKEN"
+	// EnvRoleID and EnvSecretID are the AppRole to log in with when EnvToken is not set
+	EnvRoleID   = "VAULT_ROLE_ID"
+	EnvSecretID = "VAULT_SECRET_ID"
+	// EnvAppRoleMount is the path the AppRole auth method is mounted at, approle if not set
+	EnvAppRoleMount = "VAULT_APPROLE_MOUNT"
+	// EnvNamespace is the Vault Enterprise namespace, if any
+	EnvNamespace = "VAULT_NAMESPACE"
+
+	// DefaultAppRoleMount is the path the AppRole auth method is mounted at by default
+	DefaultAppRoleMount = "approle"
+	// RequestTimeout bounds each request to Vault
+	RequestTimeout = 10 * time.Second
+
+	// responseBodyLimit bounds how much of an error response is read into errors
+	responseBodyLimit = 512
+)
+
+// Ref is a reference to a field of a secret in Vault
+type Ref struct {
+	// Path is the secret's API path without /v1/, e.g. secret/data/val12/active for a KV v2 secret
+	Path string
+	// Field is the secret's field holding the value
+	Field string
+}
+
+// String returns the reference as configured
+func (r Ref) String() string {
+	return Scheme + r.Path + "#" + r.Field
+}
+
+// IsRef returns true if s is a vault://<path>#<field> reference rather than a file path
+func IsRef(s string) bool {
+	return strings.HasPrefix(s, Scheme)
+}
+
+// ParseRef parses a vault://<path>#<field> reference
+func ParseRef(s string) (Ref, error) {
+	path, field, found := strings.Cut(strings.TrimPrefix(s, Scheme), "#")
+	path = strings.Trim(path, "/")
+	if !IsRef(s) || !found || path == "" || field == "" {
+		return Ref{}, fmt.Errorf("invalid vault reference %s - must be vault://<path>#<field>, e.g. vault://secret/data/validator/active#keypair", s)
+	}
+	return Ref{Path: path, Field: field}, nil
+}
+
+// Client reads secrets from Vault
+type Client struct {
+	addr       string
+	token      string
+	namespace  string
+	httpClient *http.Client
+}
+
+// NewFromEnv returns a client for the Vault at VAULT_ADDR, authenticated with VAULT_TOKEN or, if not set, by
+// logging in with VAULT_ROLE_ID and VAULT_SECRET_ID
+func NewFromEnv(ctx context.Context) (*Client, error) {
+	c := &Client{
+		addr:       strings.TrimRight(os.Getenv(EnvAddr), "/"),
+		token:      os.Getenv(EnvToken),
+		namespace:  os.Getenv(EnvNamespace),
+		httpClient: &http.Client{Timeout: RequestTimeout},
+	}
+	if c.addr == "" {
+		return nil, fmt.Errorf("%s must be set to read from vault", EnvAddr)
+	}
+	if _, err := url.ParseRequestURI(c.addr); err != nil {
+		return nil, fmt.Errorf("%s must be a valid URL: %w", EnvAddr, err)
+	}
+	if c.token != "" {
+		return c, nil
+	}
+
+	roleID, secretID := os.Getenv(EnvRoleID), os.Getenv(EnvSecretID)
+	if roleID == "" || secretID == "" {
+		return nil, fmt.Errorf("%s or both %s and %s must be set to authenticate with vault", EnvToken, EnvRoleID, EnvSecretID)
+	}
+	mount := os.Getenv(EnvAppRoleMount)
+	if mount == "" {
+		mount = DefaultAppRoleMount
+	}
+	token, err := c.appRoleLogin(ctx, strings.Trim(mount, "/"), roleID, secretID)
+	if err != nil {
+		return nil, err
+	}
+	c.token = token
+	return c, nil
+}
+
+// appRoleLogin logs in with the AppRole mounted at mount, returning the client token
+func (c *Client) appRoleLogin(ctx context.Context, mount string, roleID string, secretID string) (string, error) {
+	body, err := json.Marshal(map[string]string{"role_id": roleID, "secret_id": secretID})
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := c.do(ctx, http.MethodPost, "auth/"+mount+"/login", body, &response); err != nil {
+		return "", fmt.Errorf("failed to log in to vault with approle: %w", err)
+	}
+	if response.Auth.ClientToken == "" {
+		return "", fmt.Errorf("failed to log in to vault with approle: no client token in response")
+	}
+	return response.Auth.ClientToken, nil
+}
+
+// Read returns the raw JSON value of ref's field - of a KV v2 secret's data, or of the secret itself otherwise
+func (c *Client) Read(ctx context.Context, ref Ref) (json.RawMessage, error) {
+	var response struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, ref.Path, nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to read %s from vault: %w", ref, err)
+	}
+
+	fields := response.Data
+	// KV v2 nests the secret in data.data alongside its metadata
+	if nested, ok := fields["data"]; ok {
+		var data map[string]json.RawMessage
+		if _, hasMetadata := fields["metadata"]; hasMetadata && json.Unmarshal(nested, &data) == nil {
+			fields = data
+		}
+	}
+
+	value, ok := fields[ref.Field]
+	if !ok || string(value) == "null" {
+		return nil, fmt.Errorf("failed to read %s from vault: the secret has no %s field", ref, ref.Field)
+	}
+	return value, nil
+}
+
+// do sends a request to the Vault API at path, decoding the JSON response into v
+func (c *Client) do(ctx context.Context, method string, path string, body []byte, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.addr+"/v1/"+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, responseBodyLimit))
+		return fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeVault returns a fake vault serving a KV v2 secret at secret/data/val12/active and a KV v1 one at
+// kv/val12/passive to token, which the approle role-id/secret-id logs in as
+func newFakeVault(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/auth/approle/login", func(w http.ResponseWriter, r *http.Request) {
+		var login map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&login))
+		if login["role_id"] != "role-id" || login["secret_id"] != "secret-id" {
+			http.Error(w, `{"errors":["invalid role or secret ID"]}`, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"auth":{"client_token":"` + token + `"}}`))
+	})
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != token {
+				http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+				return
+			}
+			next(w, r)
+		}
+	}
+	mux.HandleFunc("GET /v1/secret/data/val12/active", authorized(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"data":{"keypair":[1,2,3]},"metadata":{"version":2}}}`))
+	}))
+	mux.HandleFunc("GET /v1/kv/val12/passive", authorized(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"keypair":"[4,5,6]"}}`))
+	}))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestParseRef(t *testing.T) {
+	ref, err := ParseRef("vault://secret/data/val12/active#keypair")
+	require.NoError(t, err)
+	assert.Equal(t, Ref{Path: "secret/data/val12/active", Field: "keypair"}, ref)
+	assert.Equal(t, "vault://secret/data/val12/active#keypair", ref.String())
+
+	for _, invalid := range []string{"vault://secret/data/val12/active", "vault://#keypair", "vault://secret#", "/etc/active.json"} {
+		_, err := ParseRef(invalid)
+		assert.Error(t, err, invalid)
+	}
+	assert.True(t, IsRef("vault://secret#keypair"))
+	assert.False(t, IsRef("/etc/active.json"))
+}
+
+func TestClient_Read(t *testing.T) {
+	server := newFakeVault(t, "s.token")
+	t.Setenv(EnvAddr, server.URL)
+	t.Setenv(EnvToken, "s.token")
+
+	client, err := NewFromEnv(context.Background())
+	require.NoError(t, err)
+
+	// KV v2 secrets are read from their data
+	value, err := client.Read(context.Background(), Ref{Path: "secret/data/val12/active", Field: "keypair"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[1,2,3]`, string(value))
+
+	value, err = client.Read(context.Background(), Ref{Path: "kv/val12/passive", Field: "keypair"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `"[4,5,6]"`, string(value))
+
+	_, err = client.Read(context.Background(), Ref{Path: "secret/data/val12/active", Field: "missing"})
+	assert.EqualError(t, err, "failed to read vault://secret/data/val12/active#missing from vault: the secret has no missing field")
+
+	_, err = client.Read(context.Background(), Ref{Path: "secret/data/val12/other", Field: "keypair"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404 Not Found")
+}
+
+func TestNewFromEnv_AppRole(t *testing.T) {
+	server := newFakeVault(t, "s.approle")
+	t.Setenv(EnvAddr, server.URL)
+	t.Setenv(EnvToken, "")
+	t.Setenv(EnvRoleID, "role-id")
+	t.Setenv(EnvSecretID, "secret-id")
+
+	client, err := NewFromEnv(context.Background())
+	require.NoError(t, err)
+	_, err = client.Read(context.Background(), Ref{Path: "secret/data/val12/active", Field: "keypair"})
+	require.NoError(t, err)
+
+	t.Setenv(EnvSecretID, "wrong")
+	_, err = NewFromEnv(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to log in to vault with approle: vault returned 400 Bad Request")
+}
+
+func TestNewFromEnv_Unconfigured(t *testing.T) {
+	t.Setenv(EnvAddr, "")
+	_, err := NewFromEnv(context.Background())
+	assert.EqualError(t, err, "VAULT_ADDR must be set to read from vault")
+
+	t.Setenv(EnvAddr, "http://127.0.0.1:8200")
+	t.Setenv(EnvToken, "")
+	t.Setenv(EnvRoleID, "")
+	t.Setenv(EnvSecretID, "")
+	_, err = NewFromEnv(context.Background())
+	assert.EqualError(t, err, "VAULT_TOKEN or both VAULT_ROLE_ID and VAULT_SECRET_ID must be set to authenticate with vault")
+}